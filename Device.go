@@ -11,11 +11,11 @@ package onvif
 
 import (
 	"bytes"
-	"encoding/xml"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -23,14 +23,19 @@ import (
 	"strings"
 	"time"
 
+	"github.com/PolarisM78/go-onvif/core"
 	"github.com/PolarisM78/go-onvif/soap"
 	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
 
 	"github.com/beevik/etree"
 )
 
 /* 定义设备参数结构体 */
 type DeviceParams struct {
+	/* 设备地址,优先于Ipddr */
+	Endpoint Endpoint
+	// Deprecated: use Endpoint, Ipddr is kept in sync with it for one release
 	Ipddr    string
 	Username string
 	Password string
@@ -38,14 +43,53 @@ type DeviceParams struct {
 	Types    string
 	Name     string
 	Model    string
-	MAC      string
+	MACAddr  MACAddr
+	// Deprecated: use MACAddr, MAC is kept in sync with it for one release
+	MAC string
+	/* IPv6链路本地地址的scope ID(网卡名或编号),也可写在Ipddr中如fe80::1%eth0 */
+	Zone string
+	/* 设备服务完整地址,如https://10.1.1.200:8443/onvif/device_service,设置后不再探测 */
+	ServiceURL string
+	/* 设备服务端口和路径,为空时依次探测80/8080/8000端口和常见路径 */
+	ServicePort int
+	ServicePath string
+	/* 连接时并发获取各服务能力,见ServiceCapabilities */
+	EagerCapabilities bool
+	/* 快照地址不可用时从RTSP流截取一帧,为空时不回退,见Snapshot */
+	FrameGrabber FrameGrabber
+	/* 自定义http客户端,为空时按Dial创建默认10s超时的客户端 */
+	HttpClient *http.Client
+	/* 连接超时、总超时和IPv4/IPv6优先级,设置HttpClient时仅用于证书检查等直接连接 */
+	Dial DialOptions
+	/* 记录改变设备状态的调用,为空时不记录,见OperationRecord */
+	AuditSink AuditSink
+	/* WS-Security头中加入wsu:Timestamp,部分设备要求 */
+	SecurityTimestamp bool
+	/* 请求的User-Agent,部分防火墙按客户端标识放行,为空时使用Go默认值 */
+	UserAgent string
+	/* SOAP请求以gzip压缩并接受gzip应答,设备不支持压缩请求时自动回退,见CompressesRequests */
+	Compression bool
+	/* 备用地址,如VPN地址或DDNS域名,Ipddr传输失败时依次切换,见ActiveAddress */
+	Addresses []string
 }
 
 /* 定义设备控制句柄结构体 */
 type Device struct {
-	Params     DeviceParams
-	httpClient *http.Client
-	endpoints  map[string]string
+	Params       DeviceParams
+	httpClient   *http.Client
+	endpoints    map[string]string
+	capabilities *capabilityCache
+	// versions of the services advertised by GetServices, by endpoint key
+	versions map[string]ServiceVersion
+	// role restricting the operations of the calls, see WithRole
+	role Role
+	// failover candidate addresses, nil without DeviceParams.Addresses
+	failover *addressFailover
+	// compression gzip support of the device, nil without
+	// DeviceParams.Compression
+	compression *compressionState
+	// quarantine of the device, see Quarantine
+	quarantine *quarantineState
 }
 
 // DeviceType alias for int
@@ -76,24 +120,8 @@ func (devType DeviceType) String() string {
 	}
 }
 
-// Xlmns XML Scheam
-var Xlmns = map[string]string{
-	"onvif":   "http://www.onvif.org/ver10/schema",
-	"tds":     "http://www.onvif.org/ver10/device/wsdl",
-	"trt":     "http://www.onvif.org/ver10/media/wsdl",
-	"tev":     "http://www.onvif.org/ver10/events/wsdl",
-	"tptz":    "http://www.onvif.org/ver20/ptz/wsdl",
-	"timg":    "http://www.onvif.org/ver20/imaging/wsdl",
-	"tan":     "http://www.onvif.org/ver20/analytics/wsdl",
-	"xmime":   "http://www.w3.org/2005/05/xmlmime",
-	"wsnt":    "http://docs.oasis-open.org/wsn/b-2",
-	"xop":     "http://www.w3.org/2004/08/xop/include",
-	"wsa":     "http://www.w3.org/2005/08/addressing",
-	"wstop":   "http://docs.oasis-open.org/wsn/t-1",
-	"wsntw":   "http://docs.oasis-open.org/wsn/bw-2",
-	"wsrf-rw": "http://docs.oasis-open.org/wsrf/rw-2",
-	"wsaw":    "http://www.w3.org/2006/05/addressing/wsdl",
-}
+// Xlmns XML Scheam, the namespaces of core
+var Xlmns = core.Namespaces
 
 /* 初始化函数 */
 func init() {
@@ -101,113 +129,169 @@ func init() {
 	log.SetFlags(log.Lshortfile | log.LstdFlags)
 }
 
-/* 查找指定网卡支持onvif协议的NVT设备 */
+// GetAvailableDevicesAtSpecificEthernetInterface probe the NVT devices of the
+// network of interfaceName and connect them, the errors are logged.
+//
+// Deprecated: use DiscoverDevices, or discovery.Probe to list the devices
+// without connecting them.
 func GetAvailableDevicesAtSpecificEthernetInterface(interfaceName string) []Device {
-	/* Call an ws-discovery Probe Message to Discover NVT type Devices */
-	devices := soap.SendProbe(interfaceName, nil, []string{"tds:" + NVT.String()}, map[string]string{"tds": "http://www.onvif.org/ver10/network/wsdl"})
-	/* 遍历处理返回的设备数据 */
 	nvtDevices := make([]Device, 0)
-	for _, j := range devices {
-		doc := etree.NewDocument()
-		if err := doc.ReadFromString(j); err != nil {
-			log.Printf("error:%s", err.Error())
-			return nil
-		}
-		/* 查找ws-discovery中回复的设备地址信息 */
-		endpoints := doc.Root().FindElements("./Body/ProbeMatches/ProbeMatch/XAddrs")
-		for _, xaddr := range endpoints {
-			xaddr := strings.Split(strings.Split(xaddr.Text(), " ")[0], "/")[2]
-			c := 0
-			for c = 0; c < len(nvtDevices); c++ {
-				if nvtDevices[c].Params.Ipddr == xaddr {
-					log.Printf(nvtDevices[c].Params.Ipddr, "==", xaddr)
-					break
-				}
-			}
-			if c < len(nvtDevices) {
-				continue
-			}
-			/* 与设备建立连接获取服务地址信息 */
-			dev, err := NewDevice(DeviceParams{Ipddr: strings.Split(xaddr, " ")[0]})
-			if err != nil {
-				log.Printf("error:%s", err.Error())
-				continue
-			} else {
-				/* 获取uuid */
-				endpoints = doc.Root().FindElements("./Body/ProbeMatches/ProbeMatch/EndpointReference/Address")
-				dev.Params.Uuid = endpoints[0].Text()[strings.Index(endpoints[0].Text(), "uuid:")+5:]
-				/* 获取设备基本信息 */
-				endpoints = doc.Root().FindElements("./Body/ProbeMatches/ProbeMatch/Types")
-				dev.Params.Types = endpoints[0].Text()
-				endpoints = doc.Root().FindElements("./Body/ProbeMatches/ProbeMatch/Scopes")
-				pointsString := strings.Split(endpoints[0].Text(), " ")
-				for _, value := range pointsString {
-					if strings.Contains(value, "MAC") {
-						/* 获取设备mac */
-						macString := strings.Split(value, "/")
-						dev.Params.MAC = macString[len(macString)-1]
-					} else if strings.Contains(value, "hardware") {
-						/* 获取设备型号 */
-						hardString := strings.Split(value, "/")
-						dev.Params.Model = hardString[len(hardString)-1]
-					} else if strings.Contains(value, "name") {
-						/* 获取设备名称 */
-						nameString := strings.Split(value, "/")
-						dev.Params.Name = nameString[len(nameString)-1]
-					}
-				}
-				nvtDevices = append(nvtDevices, *dev)
-			}
+	found, err := DiscoverDevices(context.Background(), DiscoveryOptions{Interfaces: []string{interfaceName}})
+	if err != nil {
+		log.Printf("error:%s", err.Error())
+		return nvtDevices
+	}
+	for discovered := range found {
+		if discovered.Err != nil {
+			log.Printf("error:%s", discovered.Err.Error())
+			continue
 		}
+		nvtDevices = append(nvtDevices, *discovered.Device)
 	}
 	return nvtDevices
 }
 
 // NewDevice function construct a ONVIF Device entity
 func NewDevice(params DeviceParams) (*Device, error) {
+	return NewDeviceContext(context.Background(), params)
+}
+
+// NewDeviceContext construct a ONVIF Device entity like NewDevice, the
+// requests to the device are bound to ctx
+func NewDeviceContext(ctx context.Context, params DeviceParams) (*Device, error) {
+	if err := params.normalize(); err != nil {
+		return nil, err
+	}
+	dev := newDevice(params)
+	/* 调用设备GetCapabilities方法获取能力合集,默认地址失败时探测常见端口和路径 */
+	getCapabilities := device.GetCapabilities{Category: onvif.CapabilityCategoryAll}
+	_, port := dev.Params.address()
+	for _, service := range dev.Params.deviceServiceURLs() {
+		dev.endpoints["device"] = service
+		resp, err := dev.callMethodDo(ctx, service, getCapabilities)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			/* 后续服务地址使用设备应答的端口 */
+			if u, err := url.Parse(service); err == nil && u.Port() != port {
+				dev.Params.setAddress(u.Host)
+			}
+			/* 提前服务地址信息 */
+			dev.getSupportedServices(resp)
+			resp.Body.Close()
+			/* GetCapabilities不包含门禁等服务,GetServices在首次使用时补充 */
+			if dev.Params.EagerCapabilities && dev.ensureServices(ctx) {
+				dev.ServiceCapabilities(ctx)
+			}
+			return dev, nil
+		}
+		if err == nil {
+			resp.Body.Close()
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		/* 连接超时说明主机不可达,不再尝试其他端口 */
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			break
+		}
+	}
+	return nil, errors.New("camera is not available at " + dev.Params.Ipddr + " or it does not support ONVIF services")
+}
+
+// newDevice return a device not connected yet
+func newDevice(params DeviceParams) *Device {
 	dev := new(Device)
+	params.normalize()
 	dev.Params = params
 	dev.endpoints = make(map[string]string)
-	dev.addEndpoint("Device", "http://"+dev.Params.Ipddr+"/onvif/device_service")
+	dev.capabilities = new(capabilityCache)
+	dev.versions = make(map[string]ServiceVersion)
+	dev.httpClient = params.HttpClient
+	dev.failover = newAddressFailover(dev.Params)
+	dev.quarantine = new(quarantineState)
+	if params.Compression {
+		dev.compression = new(compressionState)
+	}
 
 	if dev.httpClient == nil {
-		dev.httpClient = new(http.Client)
-		/* 设置默认10s超时 */
-		dev.httpClient.Timeout = time.Second * 10
+		/* 默认10s总超时,连接超时等见DialOptions */
+		dev.httpClient = params.Dial.client()
 	}
-	/* 调用设备GetCapabilities方法获取能力合集 */
-	getCapabilities := device.GetCapabilities{Category: "All"}
-	resp, err := dev.CallMethod(getCapabilities)
+	return dev
+}
 
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return nil, errors.New("camera is not available at " + dev.Params.Ipddr + " or it does not support ONVIF services")
-	}
-	/* 提前服务地址信息 */
-	dev.getSupportedServices(resp)
-	return dev, nil
+// newOfflineDevice return a device knowing only its default device service,
+// used to keep track of devices unreachable when they are loaded
+func newOfflineDevice(params DeviceParams) *Device {
+	dev := newDevice(params)
+	dev.endpoints["device"] = dev.Params.deviceServiceURLs()[0]
+	return dev
 }
 
-func readResponse(resp *http.Response) []byte {
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		panic(err)
+/* 默认地址不可用时依次尝试的端口和路径 */
+var (
+	deviceServicePorts = []string{"8080", "8000"}
+	deviceServicePaths = []string{"/onvif/device_service", "/onvif/device"}
+)
+
+// deviceServiceURLs return the device service URLs tried by NewDevice, in order
+func (params DeviceParams) deviceServiceURLs() []string {
+	if params.ServiceURL != "" {
+		return []string{params.ServiceURL}
+	}
+	scheme := params.Endpoint.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	host, port := params.address()
+	ports := []string{port}
+	if params.ServicePort > 0 {
+		ports = []string{strconv.Itoa(params.ServicePort)}
+	} else if port == "" {
+		ports = append(ports, deviceServicePorts...)
+	}
+	paths := deviceServicePaths
+	if params.ServicePath != "" {
+		paths = []string{params.ServicePath}
+	}
+	services := make([]string, 0, len(ports)*len(paths))
+	for _, port := range ports {
+		for _, path := range paths {
+			service := url.URL{Scheme: scheme, Host: joinHost(host, port), Path: path}
+			services = append(services, service.String())
+		}
 	}
-	return b
+	return services
 }
 
-// GetServices return available endpoints
+// GetServices return the endpoints of the services by lower case key.
+//
+// Deprecated: use Services, which also reports the namespace and version of
+// the services. The map is a copy, later changes of the endpoints, e.g. by
+// RefreshServices, are not reflected.
 func (dev *Device) GetServices() map[string]string {
-	return dev.endpoints
+	defer dev.readEndpoints()()
+	endpoints := make(map[string]string, len(dev.endpoints))
+	for key, xaddr := range dev.endpoints {
+		endpoints[key] = xaddr
+	}
+	return endpoints
 }
 
 func (dev *Device) getSupportedServices(resp *http.Response) {
 	doc := etree.NewDocument()
-	data, _ := ioutil.ReadAll(resp.Body)
+	data, err := soap.ReadLimited(resp.Body, soap.DefaultLimits)
+	if err != nil || soap.CheckXML(data, soap.DefaultLimits) != nil {
+		return
+	}
 	if err := doc.ReadFromBytes(data); err != nil {
 		return
 	}
-	services := doc.FindElements("./Envelope/Body/GetCapabilitiesResponse/Capabilities/*/XAddr")
-	for _, j := range services {
+	capabilities := doc.FindElement("./Envelope/Body/GetCapabilitiesResponse/Capabilities")
+	if capabilities == nil {
+		return
+	}
+	for _, j := range capabilityXAddrs(capabilities) {
 		dev.addEndpoint(j.Parent().Tag, j.Text())
 	}
 }
@@ -218,14 +302,83 @@ func (dev *Device) addEndpoint(Key, Value string) {
 	lowCaseKey := strings.ToLower(Key)
 	// Replace host with host from device params.
 	if u, err := url.Parse(Value); err == nil {
-		u.Host = dev.Params.Ipddr
+		u.Host = dev.Params.hostPort()
 		Value = u.String()
 	}
+	defer dev.writeEndpoints()()
 	dev.endpoints[lowCaseKey] = Value
 }
 
+// address split Ipddr in host and optional port. The host of an IPv6
+// literal is returned without brackets and carries its zone, e.g. fe80::1%eth0
+func (params DeviceParams) address() (host, port string) {
+	host = params.Ipddr
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		host, port = h, p
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if index := strings.LastIndex(host, "%"); index >= 0 {
+		/* 兼容URL转义形式fe80::1%25eth0 */
+		if zone, err := url.PathUnescape(host[index:]); err == nil && len(zone) > 1 && zone[0] == '%' {
+			host = host[:index] + zone
+		}
+	} else if params.Zone != "" && strings.Contains(host, ":") {
+		host += "%" + params.Zone
+	}
+	return host, port
+}
+
+// hostPort return the host of the device as used in URLs, IPv6 literals are
+// bracketed
+func (params DeviceParams) hostPort() string {
+	return joinHost(params.address())
+}
+
+// joinHost is net.JoinHostPort accepting an empty port
+func joinHost(host, port string) string {
+	if port != "" {
+		return net.JoinHostPort(host, port)
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// RewriteHost replace the host of an URI reported by the device, e.g. a
+// stream URI, with the address used to reach the device. The port of the URI
+// is kept. Devices behind NAT or reporting another interface address return
+// URIs that are not reachable otherwise. After a failover the host of
+// ActiveAddress is used.
+func (dev *Device) RewriteHost(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" {
+		return uri
+	}
+	host, _ := dev.Params.address()
+	if dev.failover != nil {
+		/* 已切换到备用地址时使用当前地址 */
+		host = dev.failover.hosts[dev.failover.current()]
+	}
+	u.Host = joinHost(host, u.Port())
+	return u.String()
+}
+
 // getEndpoint functions get the target service endpoint in a better way
 func (dev Device) getEndpoint(endpoint string) (string, error) {
+	if endpointURL, err := dev.lookupEndpoint(endpoint); err == nil || endpoint == ServiceDevice {
+		return endpointURL, err
+	}
+	/* GetCapabilities未报告的服务,首次使用时读取GetServices */
+	if !dev.ensureServices(context.Background()) {
+		return "", errors.New("target endpoint service not found")
+	}
+	return dev.lookupEndpoint(endpoint)
+}
+
+// lookupEndpoint return the endpoint of the known services
+func (dev Device) lookupEndpoint(endpoint string) (string, error) {
+	defer dev.readEndpoints()()
 
 	// common condition, endpointMark in map we use this.
 	if endpointURL, bFound := dev.endpoints[endpoint]; bFound {
@@ -257,6 +410,19 @@ func (dev Device) getEndpoint(endpoint string) (string, error) {
 */
 //调用设备方法
 func (dev Device) CallMethodInterface(method interface{}, response interface{}, RedirectURL string) error {
+	return dev.CallMethodInterfaceContext(context.Background(), method, response, RedirectURL)
+}
+
+// CallMethodInterfaceContext is CallMethodInterface bound to ctx, the request is
+// aborted when ctx is canceled or its deadline expires.
+func (dev Device) CallMethodInterfaceContext(ctx context.Context, method interface{}, response interface{}, RedirectURL string) error {
+	return dev.CallMethodHeaders(ctx, method, response, RedirectURL, nil)
+}
+
+// CallMethodHeaders is CallMethodInterfaceContext sending the extra SOAP
+// header blocks after the WS-Security header, as required when a message is
+// addressed to an endpoint reference.
+func (dev Device) CallMethodHeaders(ctx context.Context, method interface{}, response interface{}, RedirectURL string, headers [][]byte) error {
 	/* 通过反射获取带入的结构体名称 */
 	methodTypeName := reflect.TypeOf(method).String()
 	responseTypeName := reflect.TypeOf(response).String()
@@ -266,65 +432,69 @@ func (dev Device) CallMethodInterface(method interface{}, response interface{},
 	if fmt.Sprintf("%sResponse", methodTypeName) != responseTypeName {
 		return errors.New("calls or returns struct parameter errors")
 	}
+	/* 改变设备状态的调用记录到审计日志 */
+	audit := dev.auditOperation(ctx, methodTypeName, method)
 	/* 获取调用方法的包名称 */
 	pkgPath := strings.Split(reflect.TypeOf(method).PkgPath(), "/")
 	pkg := strings.ToLower(pkgPath[len(pkgPath)-1])
 	/* 获取调用方法的包对应的server地址 */
 	endpoint, err := dev.getEndpoint(pkg)
 	if err != nil {
+		audit.done(err)
 		return err
 	}
 	if RedirectURL != "" {
 		endpoint = RedirectURL
 	}
-	retResponse, err := dev.callMethodDo(endpoint, method)
+	/* 上下文带有观察者时记录状态码、响应头与耗时,见WithCallObserver */
+	recorder := newCallRecorder(ctx, methodTypeName, endpoint)
+	retBytes, err := dev.callMethodRead(withAudited(recorder.trace(ctx)), recorder, endpoint, method, headers)
+	if err == nil {
+		err = core.Decode(retBytes, response)
+	}
+	recorder.done(err)
+	audit.done(err)
+	dev.configurationChanged(methodTypeName, err)
+	return err
+}
+
+// Call send request to the service of the device, e.g. ServiceMedia. Device
+// is the core.Caller of the service packages, e.g. media.New(dev)
+func (dev Device) Call(ctx context.Context, service string, request, response interface{}) error {
+	endpoint, err := dev.getEndpoint(service)
 	if err != nil {
 		return err
 	}
-	/* 读取http返回数据 */
-	retString := string(readResponse(retResponse))
-	/* 定义处理解析的Body命名空间 */
-	spaces := []string{"env", "s"}
-	spacesIndex := -1
-	/* 遍历查找设备Body使用的命名空间 */
-	for index, value := range spaces {
-		if strings.Index(retString, fmt.Sprintf("<%s:Body>", value)) > 0 && strings.Index(retString, fmt.Sprintf("</%s:Body>", value)) > 0 {
-			spacesIndex = index
-		}
-	}
-	/* 判断和提取选中的Body数据 */
-	if spacesIndex >= 0 {
-		startBodyLabel := fmt.Sprintf("<%s:Body>", spaces[spacesIndex])
-		endBodyLabel := fmt.Sprintf("</%s:Body>", spaces[spacesIndex])
-		bodyMsg := retString[strings.Index(retString, startBodyLabel)+len(startBodyLabel) : strings.Index(retString, endBodyLabel)]
-		/* 检测设备是否发送fault信息 */
-		if err := checkFaultCode(bodyMsg); err != nil {
-			return err
-		}
-		/* 解析body中的xml信息 */
-		if err := xml.Unmarshal([]byte(bodyMsg), &response); err != nil {
-			return err
-		} else {
-			/* 成功返回 */
-			return nil
-		}
-	}
-	return errors.New("target returned an error")
+	return dev.CallMethodHeaders(ctx, request, response, endpoint, nil)
 }
 
-// 检查错误状态码
-func checkFaultCode(msg string) error {
-	fault := device.FaultResponse{}
-	xml.Unmarshal([]byte(msg), &fault)
-	if fault.Reason.Text != "" {
-		return errors.New(fault.Reason.Text)
-	} else {
-		return nil
+// CallAddress send request to address, e.g. a subscription manager, see
+// core.Caller
+func (dev Device) CallAddress(ctx context.Context, address string, request, response interface{}, headers ...[]byte) error {
+	return dev.CallMethodHeaders(ctx, request, response, address, headers)
+}
+
+// callMethodRead send method and read the whole response
+func (dev Device) callMethodRead(ctx context.Context, recorder *callRecorder, endpoint string, method interface{}, headers [][]byte) ([]byte, error) {
+	retResponse, err := dev.callMethodDo(ctx, endpoint, method, headers...)
+	if err != nil {
+		return nil, err
 	}
+	defer retResponse.Body.Close()
+	recorder.response(retResponse)
+	/* 读取http返回数据,限制大小并检查xml结构防止恶意设备 */
+	return soap.ReadLimited(retResponse.Body, soap.DefaultLimits)
 }
 
+// FaultError SOAP fault returned by a device, see core.FaultError
+type FaultError = core.FaultError
+
 // CallMethod functions call an method, defined <method> struct.
 // You should use Authenticate method to call authorized requests.
+//
+// Deprecated: use the clients of the service packages, e.g.
+// media.New(dev).GetProfiles, or CallMethodInterfaceContext, which decode
+// the response and its faults.
 func (dev Device) CallMethod(method interface{}) (*http.Response, error) {
 	pkgPath := strings.Split(reflect.TypeOf(method).PkgPath(), "/")
 	pkg := strings.ToLower(pkgPath[len(pkgPath)-1])
@@ -333,42 +503,68 @@ func (dev Device) CallMethod(method interface{}) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	return dev.callMethodDo(endpoint, method)
+	return dev.callMethodDo(context.Background(), endpoint, method)
 }
 
-// CallMethod functions call an method, defined <method> struct with authentication data
-func (dev Device) callMethodDo(endpoint string, method interface{}) (*http.Response, error) {
-	output, err := xml.Marshal(method)
-	if err != nil {
+// sendMethod functions call an method, defined <method> struct with authentication data
+func (dev Device) sendMethod(ctx context.Context, endpoint string, method interface{}, headers ...[]byte) (*http.Response, error) {
+	if err := dev.checkQuarantine(); err != nil {
 		return nil, err
 	}
-	soap, err := dev.buildMethodSOAP(string(output))
-	if err != nil {
+	if err := dev.checkPolicy(method); err != nil {
+		return nil, err
+	}
+	if err := dev.checkVersion(method); err != nil {
 		return nil, err
 	}
-	soap.AddRootNamespaces(Xlmns)
-	soap.AddAction()
+	/* 单次构建完整的soap报文,缓冲区在请求发送完成后回收 */
+	var security *soap.Security
 	if dev.Params.Username != "" && dev.Params.Password != "" {
-		soap.AddWSSecurity(dev.Params.Username, dev.Params.Password)
+		auth, err := soap.NewSecurity(dev.Params.Username, dev.Params.Password)
+		if err != nil {
+			return nil, err
+		}
+		if dev.Params.SecurityTimestamp {
+			auth.AddTimestamp(securityTimestampTTL)
+		}
+		security = &auth
 	}
-
-	return SendSoap(dev.httpClient, endpoint, soap.String())
-}
-
-func (dev Device) buildMethodSOAP(msg string) (soap.SoapMessage, error) {
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(msg); err != nil {
-		return "", err
+	var buf *bytes.Buffer
+	if raw, ok := method.(rawMessage); ok {
+		buf = soap.BuildEnvelopeRaw(Xlmns, raw.body, security, headers...)
+	} else {
+		var err error
+		if buf, err = soap.BuildEnvelopeSecurity(Xlmns, method, security, headers...); err != nil {
+			return nil, err
+		}
+	}
+	if dev.CompressesRequests() {
+		return dev.postCompressed(ctx, endpoint, buf)
 	}
-	element := doc.Root()
-	soap := soap.NewEmptySOAP()
-	soap.AddBodyContent(element)
-	return soap, nil
+	return dev.post(ctx, endpoint, buf, false)
 }
 
+const soapContentType = "application/soap+xml; charset=utf-8"
+
+// securityTimestampTTL validity of the wsu:Timestamp of the requests
+const securityTimestampTTL = time.Minute
+
 // SendSoap send soap message
 func SendSoap(httpClient *http.Client, endpoint, message string) (*http.Response, error) {
-	resp, err := httpClient.Post(endpoint, "application/soap+xml; charset=utf-8", bytes.NewBufferString(message))
+	return SendSoapContext(context.Background(), httpClient, endpoint, message)
+}
+
+// SendSoapContext send soap message, the request is bound to ctx
+func SendSoapContext(ctx context.Context, httpClient *http.Client, endpoint, message string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBufferString(message))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", soapContentType)
+	if id := RequestID(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return resp, err
 	}
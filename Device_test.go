@@ -0,0 +1,78 @@
+package onvif
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeviceAddress(t *testing.T) {
+	for _, test := range []struct {
+		params   DeviceParams
+		host     string
+		hostPort string
+		rewrite  string
+	}{
+		{DeviceParams{Ipddr: "192.168.1.10"}, "192.168.1.10", "192.168.1.10", "rtsp://192.168.1.10:554/stream"},
+		{DeviceParams{Ipddr: "192.168.1.10:8080"}, "192.168.1.10", "192.168.1.10:8080", "rtsp://192.168.1.10:554/stream"},
+		{DeviceParams{Ipddr: "2001:db8::10"}, "2001:db8::10", "[2001:db8::10]", "rtsp://[2001:db8::10]:554/stream"},
+		{DeviceParams{Ipddr: "[2001:db8::10]:8080"}, "2001:db8::10", "[2001:db8::10]:8080", "rtsp://[2001:db8::10]:554/stream"},
+		{DeviceParams{Ipddr: "fe80::10", Zone: "eth0"}, "fe80::10%eth0", "[fe80::10%eth0]", "rtsp://[fe80::10%25eth0]:554/stream"},
+		/* URL转义的zone */
+		{DeviceParams{Ipddr: "[fe80::10%25eth0]:80"}, "fe80::10%eth0", "[fe80::10%eth0]:80", "rtsp://[fe80::10%25eth0]:554/stream"},
+	} {
+		host, _ := test.params.address()
+		if host != test.host || test.params.hostPort() != test.hostPort {
+			t.Fatalf("%+v: host %q, hostPort %q", test.params, host, test.params.hostPort())
+		}
+		dev := newDevice(test.params)
+		if rewrite := dev.RewriteHost("rtsp://10.0.0.1:554/stream"); rewrite != test.rewrite {
+			t.Fatalf("%+v: rewritten %q", test.params, rewrite)
+		}
+	}
+}
+
+func TestDeviceServiceURLs(t *testing.T) {
+	for _, test := range []struct {
+		params DeviceParams
+		urls   string
+	}{
+		{DeviceParams{Ipddr: "10.1.1.200"}, "http://10.1.1.200/onvif/device_service http://10.1.1.200/onvif/device " +
+			"http://10.1.1.200:8080/onvif/device_service http://10.1.1.200:8080/onvif/device http://10.1.1.200:8000/onvif/device_service http://10.1.1.200:8000/onvif/device"},
+		/* 地址带端口时只探测路径 */
+		{DeviceParams{Ipddr: "10.1.1.200:8899"}, "http://10.1.1.200:8899/onvif/device_service http://10.1.1.200:8899/onvif/device"},
+		{DeviceParams{Ipddr: "10.1.1.200", ServicePort: 8899, ServicePath: "/device"}, "http://10.1.1.200:8899/device"},
+		{DeviceParams{Ipddr: "10.1.1.200", ServiceURL: "https://10.1.1.200:8443/onvif/device_service"}, "https://10.1.1.200:8443/onvif/device_service"},
+	} {
+		if urls := strings.Join(test.params.deviceServiceURLs(), " "); urls != test.urls {
+			t.Fatalf("%+v: urls %s", test.params, urls)
+		}
+	}
+}
+
+func TestNewDeviceProbesServicePath(t *testing.T) {
+	fake := &scriptedDevice{answers: map[string]string{
+		"GetCapabilities": `<tds:GetCapabilitiesResponse><tds:Capabilities><tt:Media><tt:XAddr>http://10.0.0.1/onvif/media</tt:XAddr></tt:Media></tds:Capabilities></tds:GetCapabilitiesResponse>`,
+	}}
+	/* 只在/onvif/device应答 */
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/onvif/device" {
+			http.NotFound(w, r)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	dev, err := NewDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint, err := dev.getEndpoint(ServiceDevice); err != nil || endpoint != server.URL+"/onvif/device" {
+		t.Fatalf("device endpoint %q, %v", endpoint, err)
+	}
+	/* 其他服务地址使用访问设备的地址 */
+	if endpoint, err := dev.getEndpoint(ServiceMedia); err != nil || endpoint != server.URL+"/onvif/media" {
+		t.Fatalf("media endpoint %q, %v", endpoint, err)
+	}
+}
@@ -0,0 +1,159 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PolarisM78/go-onvif/types/accesscontrol"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ErrAreaNotFound returned by GetAreaInfo when the device has no area of the
+// token
+var ErrAreaNotFound = errors.New("area not found")
+
+// AccessControlCapabilities return the capabilities of the access control
+// service, a NotSupportedError is returned when the device has none
+func (dev *Device) AccessControlCapabilities(ctx context.Context) (accesscontrol.Capabilities, error) {
+	if _, err := dev.getEndpoint("accesscontrol"); err != nil {
+		return accesscontrol.Capabilities{}, &NotSupportedError{Service: "accesscontrol"}
+	}
+	response := accesscontrol.GetServiceCapabilitiesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, accesscontrol.GetServiceCapabilities{}, &response, ""); err != nil {
+		return accesscontrol.Capabilities{}, err
+	}
+	return response.Capabilities, nil
+}
+
+// Areas return every area of the device, the list is read in pages of the
+// MaxLimit advertised by the service
+func (dev *Device) Areas(ctx context.Context) ([]accesscontrol.AreaInfo, error) {
+	var areas []accesscontrol.AreaInfo
+	err := dev.areaPages(ctx, func(page []accesscontrol.AreaInfo) bool {
+		areas = append(areas, page...)
+		return true
+	})
+	return areas, err
+}
+
+// GetAreaInfo return the area of token
+func (dev *Device) GetAreaInfo(ctx context.Context, token string) (accesscontrol.AreaInfo, error) {
+	if _, err := dev.getEndpoint("accesscontrol"); err != nil {
+		return accesscontrol.AreaInfo{}, &NotSupportedError{Service: "accesscontrol"}
+	}
+	request := accesscontrol.GetAreaInfo{Token: []onvif.ReferenceToken{onvif.ReferenceToken(token)}}
+	response := accesscontrol.GetAreaInfoResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return accesscontrol.AreaInfo{}, err
+	}
+	/* 未知的令牌被设备忽略,应答为空 */
+	for _, area := range response.AreaInfo {
+		if string(area.Token) == token {
+			return area, nil
+		}
+	}
+	return accesscontrol.AreaInfo{}, fmt.Errorf("%w: %q", ErrAreaNotFound, token)
+}
+
+// areaPages call page with the pages of areas of the device until it returns
+// false
+func (dev *Device) areaPages(ctx context.Context, page func([]accesscontrol.AreaInfo) bool) error {
+	capabilities, err := dev.AccessControlCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	request := accesscontrol.GetAreaInfoList{Limit: capabilities.MaxLimit}
+	for {
+		response := accesscontrol.GetAreaInfoListResponse{}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			return err
+		}
+		if !page(response.AreaInfo) {
+			return nil
+		}
+		/* 没有下一页或设备返回相同的起点时结束 */
+		if response.NextStartReference == "" || response.NextStartReference == request.StartReference {
+			return nil
+		}
+		request.StartReference = response.NextStartReference
+	}
+}
+
+// AccessPoints return every access point of the device with the areas it
+// connects, read in pages of the MaxLimit advertised by the service
+func (dev *Device) AccessPoints(ctx context.Context) ([]accesscontrol.AccessPointInfo, error) {
+	var points []accesscontrol.AccessPointInfo
+	err := dev.accessPointPages(ctx, func(page []accesscontrol.AccessPointInfo) bool {
+		points = append(points, page...)
+		return true
+	})
+	return points, err
+}
+
+// accessPointPages call page with the pages of access points of the device
+// until it returns false
+func (dev *Device) accessPointPages(ctx context.Context, page func([]accesscontrol.AccessPointInfo) bool) error {
+	capabilities, err := dev.AccessControlCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	request := accesscontrol.GetAccessPointInfoList{Limit: capabilities.MaxLimit}
+	for {
+		response := accesscontrol.GetAccessPointInfoListResponse{}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			return err
+		}
+		if !page(response.AccessPointInfo) {
+			return nil
+		}
+		if response.NextStartReference == "" || response.NextStartReference == request.StartReference {
+			return nil
+		}
+		request.StartReference = response.NextStartReference
+	}
+}
+
+func (dev *Device) requireAreaManagement(ctx context.Context) error {
+	capabilities, err := dev.AccessControlCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	if !capabilities.AreaManagementSupported {
+		return &NotSupportedError{Service: "accesscontrol", Capability: "AreaManagement"}
+	}
+	return nil
+}
+
+// CreateArea create an area and return its token, the token is chosen by the
+// device unless token is set and the device supports client supplied tokens
+func (dev *Device) CreateArea(ctx context.Context, token, name, description string) (string, error) {
+	if err := dev.requireAreaManagement(ctx); err != nil {
+		return "", err
+	}
+	area := accesscontrol.Area{Token: onvif.ReferenceToken(token), Name: onvif.Name(name), Description: xsd.String(description)}
+	response := accesscontrol.CreateAreaResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, accesscontrol.CreateArea{Area: area}, &response, ""); err != nil {
+		return "", err
+	}
+	return string(response.Token), nil
+}
+
+// ModifyArea change the name and description of an area
+func (dev *Device) ModifyArea(ctx context.Context, token, name, description string) error {
+	if err := dev.requireAreaManagement(ctx); err != nil {
+		return err
+	}
+	area := accesscontrol.Area{Token: onvif.ReferenceToken(token), Name: onvif.Name(name), Description: xsd.String(description)}
+	return dev.CallMethodInterfaceContext(ctx, accesscontrol.ModifyArea{Area: area}, &accesscontrol.ModifyAreaResponse{}, "")
+}
+
+// DeleteArea delete an area
+func (dev *Device) DeleteArea(ctx context.Context, token string) error {
+	if err := dev.requireAreaManagement(ctx); err != nil {
+		return err
+	}
+	request := accesscontrol.DeleteArea{Token: onvif.ReferenceToken(token)}
+	return dev.CallMethodInterfaceContext(ctx, request, &accesscontrol.DeleteAreaResponse{}, "")
+}
@@ -0,0 +1,40 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAreas(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tac:GetServiceCapabilitiesResponse><tac:Capabilities MaxLimit="1" AreaManagementSupported="false"/></tac:GetServiceCapabilitiesResponse>`,
+		"GetAreaInfoList":        `<tac:GetAreaInfoListResponse><tac:NextStartReference>next</tac:NextStartReference><tac:AreaInfo token="lobby"><tac:Name>lobby</tac:Name></tac:AreaInfo></tac:GetAreaInfoListResponse>`,
+		"GetAreaInfo":            `<tac:GetAreaInfoResponse><tac:AreaInfo token="lobby"><tac:Name>lobby</tac:Name></tac:AreaInfo></tac:GetAreaInfoResponse>`,
+	}, ServiceAccessControl)
+	ctx := context.Background()
+	/* 设备返回相同的起点时停止翻页 */
+	areas, err := dev.Areas(ctx)
+	if err != nil || len(areas) != 2 || areas[0].Token != "lobby" {
+		t.Fatalf("areas %+v, %v", areas, err)
+	}
+	pages := fake.sent("GetAreaInfoList")
+	if len(pages) != 2 || !strings.Contains(pages[0], "<tac:Limit>1</tac:Limit>") || strings.Contains(pages[0], "StartReference") ||
+		!strings.Contains(pages[1], "<tac:StartReference>next</tac:StartReference>") {
+		t.Fatalf("requests %q", pages)
+	}
+	if area, err := dev.GetAreaInfo(ctx, "lobby"); err != nil || area.Name != "lobby" {
+		t.Fatalf("area %+v, %v", area, err)
+	}
+	if _, err := dev.GetAreaInfo(ctx, "garage"); !errors.Is(err, ErrAreaNotFound) {
+		t.Fatalf("error %v for an unknown area", err)
+	}
+	var unsupported *NotSupportedError
+	if _, err := dev.CreateArea(ctx, "", "garage", ""); !errors.As(err, &unsupported) || unsupported.Capability != "AreaManagement" {
+		t.Fatalf("error %v", err)
+	}
+	if len(fake.sent("CreateArea")) != 0 {
+		t.Fatal("area created without area management")
+	}
+}
@@ -0,0 +1,110 @@
+package onvif
+
+import (
+	"strings"
+	"time"
+)
+
+// Topics of the door control and access control events of Profile C devices
+const (
+	TopicDoorMode      = "Door/State/DoorMode"
+	TopicAccessGranted = "AccessControl/AccessGranted"
+	TopicAccessDenied  = "AccessControl/Denied"
+)
+
+// DoorEvent mode change of a door
+type DoorEvent struct {
+	Device string
+	Door   string
+	// Mode Unknown, Locked, Unlocked, Accessed, Blocked, LockedDown,
+	// LockedOpen or DoubleLocked
+	Mode string
+	Time time.Time
+	// Initialized the mode was sent when the subscription was created
+	Initialized bool
+}
+
+// AccessEvent access decision taken on an access point
+type AccessEvent struct {
+	Device      string
+	AccessPoint string
+	Granted     bool
+	// Kind last part of the topic: Credential, Anonymous, CredentialNotFound/Card...
+	Kind                 string
+	CredentialToken      string
+	CredentialHolderName string
+	Card                 string
+	Reason               string
+	Time                 time.Time
+}
+
+// topicSuffix return the part of topic after prefix once the namespace
+// prefix is removed, ok is false when topic is not under prefix
+func topicSuffix(topic, prefix string) (string, bool) {
+	if index := strings.Index(topic, ":"); index >= 0 {
+		topic = topic[index+1:]
+	}
+	if topic == prefix {
+		return "", true
+	}
+	if strings.HasPrefix(topic, prefix+"/") {
+		return topic[len(prefix)+1:], true
+	}
+	return "", false
+}
+
+// ParseDoorEvent return the door event carried by ev
+func ParseDoorEvent(ev Event) (DoorEvent, bool) {
+	if !topicMatch(ev.Topic, TopicDoorMode) || ev.Operation == "Deleted" {
+		return DoorEvent{}, false
+	}
+	return DoorEvent{
+		Device:      ev.Device,
+		Door:        ev.Source["DoorToken"],
+		Mode:        ev.Data["State"],
+		Time:        ev.Time,
+		Initialized: ev.Operation == "Initialized",
+	}, true
+}
+
+// ParseAccessEvent return the access decision carried by ev
+func ParseAccessEvent(ev Event) (AccessEvent, bool) {
+	access := AccessEvent{
+		Device:               ev.Device,
+		AccessPoint:          ev.Source["AccessPointToken"],
+		CredentialToken:      ev.Data["CredentialToken"],
+		CredentialHolderName: ev.Data["CredentialHolderName"],
+		Card:                 ev.Data["Card"],
+		Reason:               ev.Data["Reason"],
+		Time:                 ev.Time,
+	}
+	if kind, ok := topicSuffix(ev.Topic, TopicAccessGranted); ok {
+		access.Granted = true
+		access.Kind = kind
+		return access, true
+	}
+	if kind, ok := topicSuffix(ev.Topic, TopicAccessDenied); ok {
+		access.Kind = kind
+		return access, true
+	}
+	return AccessEvent{}, false
+}
+
+// WatchDoors call onChange from the engine workers for every door mode event
+func WatchDoors(engine *EventEngine, onChange func(DoorEvent)) {
+	engine.Handle(func(ev Event) {
+		if door, ok := ParseDoorEvent(ev); ok {
+			onChange(door)
+		}
+	})
+}
+
+// WatchAccess call onDecision from the engine workers for every access
+// granted or denied event
+func WatchAccess(engine *EventEngine, onDecision func(AccessEvent)) {
+	engine.Handle(func(ev Event) {
+		if access, ok := ParseAccessEvent(ev); ok {
+			onDecision(access)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package onvif
+
+import "testing"
+
+func TestParseAccessEvents(t *testing.T) {
+	door, ok := ParseDoorEvent(Event{
+		Device:    "10.1.1.200",
+		Topic:     "tns1:Door/State/DoorMode",
+		Operation: "Initialized",
+		Source:    map[string]string{"DoorToken": "door_1"},
+		Data:      map[string]string{"State": "Locked"},
+	})
+	if !ok || door.Door != "door_1" || door.Mode != "Locked" || !door.Initialized {
+		t.Fatalf("door %+v, %v", door, ok)
+	}
+	if _, ok := ParseDoorEvent(Event{Topic: "tns1:Door/State/DoorMode", Operation: "Deleted"}); ok {
+		t.Fatal("deleted door parsed")
+	}
+
+	access, ok := ParseAccessEvent(Event{
+		Topic:  "tns1:AccessControl/AccessGranted/Credential",
+		Source: map[string]string{"AccessPointToken": "entrance"},
+		Data:   map[string]string{"CredentialToken": "credential_1", "CredentialHolderName": "holder"},
+	})
+	if !ok || !access.Granted || access.Kind != "Credential" || access.AccessPoint != "entrance" || access.CredentialToken != "credential_1" || access.CredentialHolderName != "holder" {
+		t.Fatalf("access %+v, %v", access, ok)
+	}
+	access, ok = ParseAccessEvent(Event{
+		Topic:  "tns1:AccessControl/Denied/CredentialNotFound/Card",
+		Source: map[string]string{"AccessPointToken": "entrance"},
+		Data:   map[string]string{"Card": "0A1B2C", "Reason": "CredentialNotEnabled"},
+	})
+	if !ok || access.Granted || access.Kind != "CredentialNotFound/Card" || access.Card != "0A1B2C" || access.Reason != "CredentialNotEnabled" {
+		t.Fatalf("access %+v, %v", access, ok)
+	}
+	/* 前缀相同的其他主题不是访问决定 */
+	if _, ok := ParseAccessEvent(Event{Topic: "tns1:AccessControl/DeniedAnonymous"}); ok {
+		t.Fatal("unrelated topic parsed")
+	}
+}
@@ -0,0 +1,105 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// AccessPolicy authorization policy file of the device, its format is
+// defined by the device, e.g. the XACML of the ONVIF default access policy
+type AccessPolicy struct {
+	// ContentType MIME type of Data, empty when the device gives none
+	ContentType string
+	Data        []byte
+}
+
+// requireAccessPolicy check the AccessPolicyConfig capability of the device
+// service, a device whose capabilities cannot be read is tried anyway
+func (dev *Device) requireAccessPolicy(ctx context.Context) error {
+	capabilities, err := dev.ServiceCapabilities(ctx)
+	if err == nil && capabilities.Device != nil && !bool(capabilities.Device.Security.AccessPolicyConfig) {
+		return &NotSupportedError{Service: "device", Capability: "AccessPolicyConfig"}
+	}
+	return nil
+}
+
+// AccessPolicy return the access policy file of the device, a
+// NotSupportedError is returned when the device does not advertise
+// AccessPolicyConfig
+func (dev *Device) AccessPolicy(ctx context.Context) (AccessPolicy, error) {
+	if err := dev.requireAccessPolicy(ctx); err != nil {
+		return AccessPolicy{}, err
+	}
+	response := device.GetAccessPolicyResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetAccessPolicy{}, &response, ""); err != nil {
+		return AccessPolicy{}, err
+	}
+	/* 部分设备在base64中插入换行 */
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(response.PolicyFile.Data), ""))
+	if err != nil {
+		return AccessPolicy{}, err
+	}
+	return AccessPolicy{ContentType: response.PolicyFile.ContentType, Data: data}, nil
+}
+
+// SetAccessPolicy replace the access policy file of the device, e.g. with a
+// tightened copy of the one returned by AccessPolicy. The device applies the
+// policy at once: a policy denying the account of dev locks the client out.
+func (dev *Device) SetAccessPolicy(ctx context.Context, policy AccessPolicy) error {
+	if err := dev.requireAccessPolicy(ctx); err != nil {
+		return err
+	}
+	request := device.SetAccessPolicy{PolicyFile: onvif.BinaryData{
+		X:    onvif.ContentType(policy.ContentType),
+		Data: xsd.Base64Binary(base64.StdEncoding.EncodeToString(policy.Data)),
+	}}
+	return dev.CallMethodInterfaceContext(ctx, request, &device.SetAccessPolicyResponse{}, "")
+}
+
+// Permissive report whether the policy grants access without restriction:
+// an empty policy, leaving the device to its default, or an XACML policy with
+// a Permit rule applying to every request, i.e. without Target nor
+// Condition. known is false when the policy is not XML.
+func (policy AccessPolicy) Permissive() (permissive, known bool) {
+	if len(bytes.TrimSpace(policy.Data)) == 0 {
+		return true, true
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(policy.Data))
+	/* 规则的Effect及其是否带有Target或Condition */
+	inRule, permit, restricted := false, false, false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false, known
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			known = true
+			switch {
+			case element.Name.Local == "Rule":
+				inRule, permit, restricted = true, false, false
+				for _, attr := range element.Attr {
+					if attr.Name.Local == "Effect" && attr.Value == "Permit" {
+						permit = true
+					}
+				}
+			case inRule && (element.Name.Local == "Target" || element.Name.Local == "Condition"):
+				restricted = true
+			}
+		case xml.EndElement:
+			if element.Name.Local == "Rule" {
+				if permit && !restricted {
+					return true, true
+				}
+				inRule = false
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package onvif
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const permitAllPolicy = `<Policy xmlns="urn:oasis:names:tc:xacml:2.0:policy:schema:os"><Rule RuleId="all" Effect="Permit"/></Policy>`
+
+func TestAccessPolicy(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(permitAllPolicy))
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tds:GetServiceCapabilitiesResponse><tds:Capabilities><tds:Security AccessPolicyConfig="true"/></tds:Capabilities></tds:GetServiceCapabilitiesResponse>`,
+		/* 部分设备在base64中插入换行 */
+		"GetAccessPolicy": `<tds:GetAccessPolicyResponse><tds:PolicyFile contentType="application/xml"><tt:Data>` + encoded[:20] + "\n  " + encoded[20:] + `</tt:Data></tds:PolicyFile></tds:GetAccessPolicyResponse>`,
+		"SetAccessPolicy": `<tds:SetAccessPolicyResponse/>`,
+	})
+	ctx := context.Background()
+	policy, err := dev.AccessPolicy(ctx)
+	if err != nil || policy.ContentType != "application/xml" || string(policy.Data) != permitAllPolicy {
+		t.Fatalf("policy %+v, %v", policy, err)
+	}
+	if err := dev.SetAccessPolicy(ctx, AccessPolicy{ContentType: "application/xml", Data: []byte("<Policy/>")}); err != nil {
+		t.Fatal(err)
+	}
+	sent := fake.sent("SetAccessPolicy")
+	if len(sent) != 1 || !strings.Contains(sent[0], `xmime:contentType="application/xml"`) ||
+		!strings.Contains(sent[0], "<onvif:Data>"+base64.StdEncoding.EncodeToString([]byte("<Policy/>"))+"</onvif:Data>") {
+		t.Fatalf("requests %q", sent)
+	}
+	/* 允许所有请求的策略在审计中告警 */
+	report := dev.Audit(ctx)
+	found := false
+	for _, finding := range report.Findings {
+		if finding.Check == "access-policy" {
+			found = finding.Severity == AuditWarning && finding.Detail == "access policy permits every request"
+		}
+	}
+	if !found {
+		t.Fatalf("findings %+v", report.Findings)
+	}
+
+	/* 设备未报告AccessPolicyConfig时不发送请求 */
+	fake, dev = newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tds:GetServiceCapabilitiesResponse><tds:Capabilities><tds:Security AccessPolicyConfig="false"/></tds:Capabilities></tds:GetServiceCapabilitiesResponse>`,
+	})
+	var unsupported *NotSupportedError
+	if _, err := dev.AccessPolicy(ctx); !errors.As(err, &unsupported) || unsupported.Capability != "AccessPolicyConfig" {
+		t.Fatalf("error %v", err)
+	}
+	if err := dev.SetAccessPolicy(ctx, AccessPolicy{}); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("error %v", err)
+	}
+	if len(fake.sent("GetAccessPolicy"))+len(fake.sent("SetAccessPolicy")) != 0 {
+		t.Fatal("access policy requested without AccessPolicyConfig")
+	}
+}
+
+func TestAccessPolicyPermissive(t *testing.T) {
+	for _, test := range []struct {
+		policy            string
+		permissive, known bool
+	}{
+		{"", true, true},
+		{permitAllPolicy, true, true},
+		{`<Policy><Rule Effect="Permit"><Target><Subjects/></Target></Rule><Rule Effect="Deny"/></Policy>`, false, true},
+		{`<Policy><Rule Effect="Permit"><Condition/></Rule></Policy>`, false, true},
+		{"admin:rw\noperator:r", false, false},
+	} {
+		if permissive, known := (AccessPolicy{Data: []byte(test.policy)}).Permissive(); permissive != test.permissive || known != test.known {
+			t.Errorf("%q: %v, %v", test.policy, permissive, known)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// addressFailover candidate addresses of a device, Ipddr first and then
+// DeviceParams.Addresses, shared by the copies of the Device
+type addressFailover struct {
+	addresses []string
+	// hosts and ports of the candidates, an empty port keeps the port of the
+	// URLs reported by the device
+	hosts []string
+	ports []string
+
+	mutex  sync.Mutex
+	active int
+}
+
+// newAddressFailover return the candidates of params, nil when the device
+// has a single address
+func newAddressFailover(params DeviceParams) *addressFailover {
+	if len(params.Addresses) == 0 {
+		return nil
+	}
+	failover := &addressFailover{}
+	for _, address := range append([]string{params.Ipddr}, params.Addresses...) {
+		host, port := DeviceParams{Ipddr: address, Zone: params.Zone}.address()
+		failover.addresses = append(failover.addresses, address)
+		failover.hosts = append(failover.hosts, host)
+		failover.ports = append(failover.ports, port)
+	}
+	return failover
+}
+
+// current return the index of the candidate the calls are sent to
+func (failover *addressFailover) current() int {
+	failover.mutex.Lock()
+	defer failover.mutex.Unlock()
+	return failover.active
+}
+
+// fail move the calls away from the candidate at index after a transport
+// error and return the next candidate, another call may have moved them
+// already
+func (failover *addressFailover) fail(index int) int {
+	failover.mutex.Lock()
+	defer failover.mutex.Unlock()
+	if failover.active == index {
+		failover.active = (index + 1) % len(failover.hosts)
+	}
+	return failover.active
+}
+
+// rewrite return uri addressed to the candidate at index, it reports false
+// when uri is not addressed to any candidate of the device
+func (failover *addressFailover) rewrite(uri string, index int) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" {
+		return uri, false
+	}
+	known := false
+	for _, host := range failover.hosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return uri, false
+	}
+	port := failover.ports[index]
+	if port == "" {
+		port = u.Port()
+	}
+	u.Host = joinHost(failover.hosts[index], port)
+	return u.String(), true
+}
+
+// ActiveAddress return the address the calls are sent to: Ipddr, or the
+// entry of DeviceParams.Addresses the device failed over to
+func (dev *Device) ActiveAddress() string {
+	if dev.failover == nil {
+		return dev.Params.Ipddr
+	}
+	index := dev.failover.current()
+	if index == 0 {
+		return dev.Params.Ipddr
+	}
+	return dev.failover.addresses[index]
+}
+
+// failoverCall send method to endpoint. With DeviceParams.Addresses, a call
+// failing to connect is sent again to the next address, each address at most
+// once, and the next calls stick to the address which answered. A call which
+// may have reached the device, e.g. one timing out after the request was
+// sent, is not sent again since the operation may not be idempotent.
+func (dev Device) failoverCall(ctx context.Context, endpoint string, method interface{}, headers ...[]byte) (*http.Response, error) {
+	if dev.failover == nil {
+		return dev.sendMethod(ctx, endpoint, method, headers...)
+	}
+	index := dev.failover.current()
+	for tried := 1; ; tried++ {
+		target, ok := dev.failover.rewrite(endpoint, index)
+		resp, err := dev.sendMethod(ctx, target, method, headers...)
+		if err == nil || !ok || ctx.Err() != nil || !isDialError(err) || tried >= len(dev.failover.hosts) {
+			return resp, err
+		}
+		index = dev.failover.fail(index)
+	}
+}
+
+// isDialError report whether err is a failure to resolve or connect to the
+// device, the request not being sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return (errors.As(err, &opErr) && opErr.Op == "dial") || errors.As(err, &dnsErr)
+}
+
+// isTransportError report whether err is a failure to reach the device, as
+// opposed to an error answered by the device
+func isTransportError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
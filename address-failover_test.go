@@ -0,0 +1,108 @@
+package onvif
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+func TestAddressFailoverRewrite(t *testing.T) {
+	failover := newAddressFailover(DeviceParams{Ipddr: "10.0.0.1", Addresses: []string{"vpn.example.com:8080"}})
+	if uri, ok := failover.rewrite("http://10.0.0.1/onvif/device_service", 1); !ok || uri != "http://vpn.example.com:8080/onvif/device_service" {
+		t.Fatalf("uri %q, %v", uri, ok)
+	}
+	/* 主地址未指定端口时保留设备报告的端口 */
+	if uri, ok := failover.rewrite("http://VPN.example.com:8080/onvif/media", 0); !ok || uri != "http://10.0.0.1:8080/onvif/media" {
+		t.Fatalf("uri %q, %v", uri, ok)
+	}
+	if uri, ok := failover.rewrite("http://10.9.9.9/onvif/media", 1); ok || uri != "http://10.9.9.9/onvif/media" {
+		t.Fatalf("uri %q, %v", uri, ok)
+	}
+	/* 其他调用已切换时不再切换 */
+	if next := failover.fail(0); next != 1 {
+		t.Fatalf("next %d", next)
+	}
+	if next := failover.fail(0); next != 1 {
+		t.Fatalf("next %d after a concurrent failure", next)
+	}
+	if next := failover.fail(1); next != 0 {
+		t.Fatalf("next %d", next)
+	}
+	if newAddressFailover(DeviceParams{Ipddr: "10.0.0.1"}) != nil {
+		t.Fatal("failover of a single address")
+	}
+}
+
+// closedAddress return the address of a port nothing listens on
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestAddressFailover(t *testing.T) {
+	fake := &scriptedDevice{answers: map[string]string{
+		"GetDeviceInformation": `<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer></tds:GetDeviceInformationResponse>`,
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	secondary := strings.TrimPrefix(server.URL, "http://")
+	primary := closedAddress(t)
+	dev := newDevice(DeviceParams{Ipddr: primary, Addresses: []string{secondary}})
+	dev.endpoints[ServiceDevice] = "http://" + primary + "/onvif/device_service"
+	dev.capabilities.servicesLoaded = true
+	ctx := context.Background()
+	info := device.GetDeviceInformationResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &info, ""); err != nil || info.Manufacturer != "Acme" {
+		t.Fatalf("information %+v, %v", info, err)
+	}
+	if address := dev.ActiveAddress(); address != secondary {
+		t.Fatalf("active address %q", address)
+	}
+	if requests := fake.sent("GetDeviceInformation"); len(requests) != 1 || !strings.HasPrefix(requests[0], "/onvif/device_service ") {
+		t.Fatalf("requests %q", requests)
+	}
+	/* 后续调用直接发送到可用地址 */
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &info, ""); err != nil || len(fake.sent("GetDeviceInformation")) != 2 {
+		t.Fatalf("second call %v", err)
+	}
+}
+
+func TestAddressFailoverAfterSend(t *testing.T) {
+	fake := &scriptedDevice{answers: map[string]string{"SystemReboot": `<tds:SystemRebootResponse/>`}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	var mutex sync.Mutex
+	attempts := 0
+	/* 请求已发送后连接断开,操作可能已执行,不发送到备用地址 */
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		attempts++
+		mutex.Unlock()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer down.Close()
+	primary := strings.TrimPrefix(down.URL, "http://")
+	dev := newDevice(DeviceParams{Ipddr: primary, Addresses: []string{strings.TrimPrefix(server.URL, "http://")}})
+	dev.endpoints[ServiceDevice] = down.URL + "/onvif/device_service"
+	dev.capabilities.servicesLoaded = true
+	if err := dev.CallMethodInterfaceContext(context.Background(), device.SystemReboot{}, &device.SystemRebootResponse{}, ""); err == nil {
+		t.Fatal("closed connection answered")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if attempts != 1 || len(fake.sent("SystemReboot")) != 0 || dev.ActiveAddress() != primary {
+		t.Fatalf("%d attempts, %d sent to the secondary address, active %q", attempts, len(fake.sent("SystemReboot")), dev.ActiveAddress())
+	}
+}
@@ -0,0 +1,169 @@
+package onvif
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/types/analytics"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// AnalyticsSchema generic description of a rule or an analytics module
+// type, from which a configuration UI can be generated
+type AnalyticsSchema struct {
+	// Type qualified name of the type, e.g. tt:CellMotionDetector
+	Type string `json:"type"`
+	// Fixed instances of the type can not be created nor deleted
+	Fixed bool `json:"fixed,omitempty"`
+	// MaxInstances maximum number of instances, 0 when unknown
+	MaxInstances int                  `json:"maxInstances,omitempty"`
+	Parameters   []AnalyticsParameter `json:"parameters"`
+}
+
+// AnalyticsParameter parameter of a rule or an analytics module
+type AnalyticsParameter struct {
+	Name string `json:"name"`
+	// Type XML schema type without prefix, e.g. int, float, boolean, string,
+	// Polygon or CellLayout
+	Type string `json:"type"`
+	// Element the parameter is an element item, such as a polygon, rather
+	// than a simple item
+	Element bool `json:"element,omitempty"`
+	// Min and Max range of a numeric parameter, nil when unbounded
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// Values allowed values of an enumerated parameter
+	Values []string `json:"values,omitempty"`
+	// MinOccurs and MaxOccurs number of occurrences of the parameter, 0
+	// when not stated
+	MinOccurs int `json:"minOccurs,omitempty"`
+	MaxOccurs int `json:"maxOccurs,omitempty"`
+}
+
+// localName return name without namespace prefix
+func localName(name string) string {
+	if index := strings.LastIndex(name, ":"); index >= 0 {
+		return name[index+1:]
+	}
+	return name
+}
+
+// RuleSchemas return the schemas of the rule types supported by the video
+// analytics configuration, with the options of their parameters when the
+// device answers GetRuleOptions
+func (dev *Device) RuleSchemas(ctx context.Context, configuration string) ([]AnalyticsSchema, error) {
+	if _, err := dev.getEndpoint("analytics"); err != nil {
+		return nil, &NotSupportedError{Service: "analytics"}
+	}
+	response := analytics.GetSupportedRulesResponse{}
+	request := analytics.GetSupportedRules{ConfigurationToken: onvif.ReferenceToken(configuration)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return nil, err
+	}
+	schemas := newAnalyticsSchemas(response.SupportedRules.RuleDescription)
+	for i := range schemas {
+		/* 选项可选,设备不支持时只返回参数类型 */
+		options := analytics.GetRuleOptionsResponse{}
+		request := analytics.GetRuleOptions{RuleType: xsd.QName(schemas[i].Type), ConfigurationToken: onvif.ReferenceToken(configuration)}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &options, ""); err == nil {
+			schemas[i].applyOptions(options.RuleOptions)
+		}
+	}
+	return schemas, nil
+}
+
+// AnalyticsModuleSchemas return the schemas of the analytics module types
+// supported by the video analytics configuration, with the options of their
+// parameters when the device answers GetAnalyticsModuleOptions
+func (dev *Device) AnalyticsModuleSchemas(ctx context.Context, configuration string) ([]AnalyticsSchema, error) {
+	if _, err := dev.getEndpoint("analytics"); err != nil {
+		return nil, &NotSupportedError{Service: "analytics"}
+	}
+	response := analytics.GetSupportedAnalyticsModulesResponse{}
+	request := analytics.GetSupportedAnalyticsModules{ConfigurationToken: onvif.ReferenceToken(configuration)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return nil, err
+	}
+	schemas := newAnalyticsSchemas(response.SupportedAnalyticsModules.AnalyticsModuleDescription)
+	for i := range schemas {
+		options := analytics.GetAnalyticsModuleOptionsResponse{}
+		request := analytics.GetAnalyticsModuleOptions{Type: xsd.QName(schemas[i].Type), ConfigurationToken: onvif.ReferenceToken(configuration)}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &options, ""); err == nil {
+			schemas[i].applyOptions(options.Options)
+		}
+	}
+	return schemas, nil
+}
+
+func newAnalyticsSchemas(descriptions []onvif.ConfigDescription) []AnalyticsSchema {
+	schemas := make([]AnalyticsSchema, 0, len(descriptions))
+	for _, description := range descriptions {
+		schema := AnalyticsSchema{
+			Type:         strings.TrimSpace(string(description.Name)),
+			Fixed:        description.Fixed,
+			MaxInstances: description.MaxInstances,
+		}
+		for _, item := range description.Parameters.SimpleItemDescription {
+			schema.Parameters = append(schema.Parameters, AnalyticsParameter{Name: item.Name, Type: localName(string(item.Type))})
+		}
+		for _, item := range description.Parameters.ElementItemDescription {
+			schema.Parameters = append(schema.Parameters, AnalyticsParameter{Name: item.Name, Type: localName(string(item.Type)), Element: true})
+		}
+		for i := range schema.Parameters {
+			if schema.Parameters[i].Type == "boolean" {
+				schema.Parameters[i].Values = []string{"true", "false"}
+			}
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas
+}
+
+// applyOptions set the range and the values of the parameters from the
+// options of the type, the options of other types are ignored
+func (schema *AnalyticsSchema) applyOptions(options []onvif.ConfigOptions) {
+	for _, option := range options {
+		owner := string(option.RuleType)
+		if owner == "" {
+			owner = string(option.AnalyticsModule)
+		}
+		if owner != "" && localName(owner) != localName(schema.Type) {
+			continue
+		}
+		for i := range schema.Parameters {
+			if schema.Parameters[i].Name == option.Name {
+				schema.Parameters[i].applyOption(option)
+			}
+		}
+	}
+}
+
+// applyOption interpret the content of option: IntRange and FloatRange give
+// the range, StringList and the items of StringItems or IntItems the values
+func (parameter *AnalyticsParameter) applyOption(option onvif.ConfigOptions) {
+	parameter.MinOccurs, parameter.MaxOccurs = option.MinOccurs, option.MaxOccurs
+	var values []string
+	for _, leaf := range xmlLeaves(option.Content) {
+		switch leaf.name() {
+		case "min", "max":
+			value, err := strconv.ParseFloat(leaf.value, 64)
+			if err != nil {
+				continue
+			}
+			if leaf.name() == "min" && parameter.Min == nil {
+				parameter.Min = &value
+			} else if leaf.name() == "max" && parameter.Max == nil {
+				parameter.Max = &value
+			}
+		case "stringlist", "intlist":
+			values = append(values, strings.Fields(leaf.value)...)
+		case "item":
+			values = append(values, leaf.value)
+		}
+	}
+	if len(values) > 0 {
+		parameter.Values = values
+	}
+}
@@ -0,0 +1,55 @@
+package onvif
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyticsSchemas(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetSupportedRules": `<tan:GetSupportedRulesResponse><tan:SupportedRules>` +
+			`<tt:RuleDescription Name="tt:LineDetector"><tt:Parameters><tt:SimpleItemDescription Name="Direction" Type="xs:string"/>` +
+			`<tt:ElementItemDescription Name="Segments" Type="tt:Polyline"/></tt:Parameters></tt:RuleDescription>` +
+			`<tt:RuleDescription Name="tt:FieldDetector" maxInstances="4"><tt:Parameters><tt:SimpleItemDescription Name="Sensitivity" Type="xs:int"/>` +
+			`<tt:SimpleItemDescription Name="Enabled" Type="xs:boolean"/></tt:Parameters></tt:RuleDescription>` +
+			`</tan:SupportedRules></tan:GetSupportedRulesResponse>`,
+		/* 设备返回所有规则类型的选项,按RuleType归属 */
+		"GetRuleOptions": `<tan:GetRuleOptionsResponse>` +
+			`<tan:RuleOptions Name="Direction" RuleType="tt:LineDetector" minOccurs="1" maxOccurs="1"><tt:StringList>Any Left Right</tt:StringList></tan:RuleOptions>` +
+			`<tan:RuleOptions Name="Sensitivity" RuleType="tt:FieldDetector"><tt:IntRange><tt:Min>0</tt:Min><tt:Max>100</tt:Max></tt:IntRange></tan:RuleOptions>` +
+			`<tan:RuleOptions Name="Sensitivity" RuleType="acme:Loitering"><tt:IntRange><tt:Min>5</tt:Min><tt:Max>9</tt:Max></tt:IntRange></tan:RuleOptions>` +
+			`</tan:GetRuleOptionsResponse>`,
+		"GetSupportedAnalyticsModules": `<tan:GetSupportedAnalyticsModulesResponse><tan:SupportedAnalyticsModules>` +
+			`<tt:AnalyticsModuleDescription Name="tt:CellMotionEngine" fixed="true"><tt:Parameters><tt:SimpleItemDescription Name="Sensitivity" Type="xs:int"/>` +
+			`<tt:ElementItemDescription Name="Layout" Type="tt:CellLayout"/></tt:Parameters></tt:AnalyticsModuleDescription>` +
+			`</tan:SupportedAnalyticsModules></tan:GetSupportedAnalyticsModulesResponse>`,
+	}, ServiceAnalytics)
+	ctx := context.Background()
+	schemas, err := dev.RuleSchemas(ctx, "analytics")
+	if err != nil || len(schemas) != 2 || len(fake.sent("GetRuleOptions")) != 2 {
+		t.Fatalf("schemas %+v, %v", schemas, err)
+	}
+	min, max := 0.0, 100.0
+	expected := []AnalyticsSchema{
+		{Type: "tt:LineDetector", Parameters: []AnalyticsParameter{
+			{Name: "Direction", Type: "string", Values: []string{"Any", "Left", "Right"}, MinOccurs: 1, MaxOccurs: 1},
+			{Name: "Segments", Type: "Polyline", Element: true},
+		}},
+		{Type: "tt:FieldDetector", MaxInstances: 4, Parameters: []AnalyticsParameter{
+			{Name: "Sensitivity", Type: "int", Min: &min, Max: &max},
+			{Name: "Enabled", Type: "boolean", Values: []string{"true", "false"}},
+		}},
+	}
+	if !reflect.DeepEqual(schemas, expected) {
+		t.Fatalf("schemas %+v", schemas)
+	}
+
+	/* 设备不支持选项时只返回参数类型 */
+	schemas, err = dev.AnalyticsModuleSchemas(ctx, "analytics")
+	if err != nil || len(schemas) != 1 || !schemas[0].Fixed || !reflect.DeepEqual(schemas[0].Parameters, []AnalyticsParameter{
+		{Name: "Sensitivity", Type: "int"}, {Name: "Layout", Type: "CellLayout", Element: true},
+	}) {
+		t.Fatalf("schemas %+v, %v", schemas, err)
+	}
+}
@@ -0,0 +1,241 @@
+package onvif
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/analytics"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// TopicDetectedSound topic of the audio analytics of the ONVIF schema, the
+// class of the sound is carried by the message
+const TopicDetectedSound = "AudioAnalytics/Audio/DetectedSound"
+
+// Classes of SoundEvent
+const (
+	SoundLoudNoise  = "LoudNoise"
+	SoundScream     = "Scream"
+	SoundGlassBreak = "GlassBreak"
+)
+
+// soundKeywords class of a sound by keyword of its topic or class name,
+// lower case, the standard and the vendor names
+var soundKeywords = []struct {
+	keyword string
+	class   string
+}{
+	{"glassbreak", SoundGlassBreak},
+	{"glass", SoundGlassBreak},
+	{"scream", SoundScream},
+	{"shout", SoundScream},
+	{"aggression", SoundScream},
+	{"loud", SoundLoudNoise},
+	{"noise", SoundLoudNoise},
+	{"triggerlevel", SoundLoudNoise},
+	{"audiolevel", SoundLoudNoise},
+	{"soundlevel", SoundLoudNoise},
+	{"audioexception", SoundLoudNoise},
+	{"audiodetection", SoundLoudNoise},
+}
+
+// soundClass return the class named by value, empty when none
+func soundClass(value string) string {
+	value = strings.ToLower(value)
+	for _, item := range soundKeywords {
+		if strings.Contains(value, item.keyword) {
+			return item.class
+		}
+	}
+	return ""
+}
+
+// SoundEvent sound detected by the audio analytics of a device
+type SoundEvent struct {
+	Device string
+	// Source audio source or analytics configuration token, when stated
+	Source string
+	Rule   string
+	// Class SoundLoudNoise, SoundScream, SoundGlassBreak, or the class named
+	// by the device for the other sounds of DetectedSound
+	Class string
+	// Active the sound is detected, false when a property event reports its
+	// end
+	Active bool
+	Time   time.Time
+}
+
+// ParseSoundEvent return the sound carried by ev: DetectedSound of the ONVIF
+// schema, or an audio topic of a vendor such as tns1:AudioSource/tnsaxis:TriggerLevel
+func ParseSoundEvent(ev Event) (SoundEvent, bool) {
+	if ev.Operation == "Deleted" {
+		return SoundEvent{}, false
+	}
+	path := topicPath(ev.Topic)
+	lower := strings.ToLower(path)
+	standard := path == TopicDetectedSound
+	if !standard && !strings.Contains(lower, "audio") && !strings.Contains(lower, "sound") {
+		return SoundEvent{}, false
+	}
+	sound := SoundEvent{
+		Device: ev.Device,
+		Rule:   ev.Source["Rule"],
+		Active: true,
+		Time:   ev.Time,
+	}
+	sound.Source = itemValue(ev.Source, "AudioSourceConfigurationToken", "AudioAnalyticsConfigurationToken", "Source", "Channel")
+	/* 类别优先取消息中的声明,其次由主题推断 */
+	if value := itemValue(ev.Data, "SoundClass", "Class", "Type", "Sound"); value != "" {
+		sound.Class = soundClass(value)
+		if sound.Class == "" {
+			sound.Class = value
+		}
+	}
+	if sound.Class == "" {
+		sound.Class = soundClass(path)
+	}
+	if sound.Class == "" {
+		return SoundEvent{}, false
+	}
+	if active, ok := logicalState(itemValue(ev.Data, "IsSoundDetected", "State", "Active", "LogicalState", "Triggered")); ok {
+		sound.Active = active
+	}
+	return sound, true
+}
+
+// itemValue return the value of the first item of names found in items,
+// names compared case insensitively as vendors differ in case
+func itemValue(items map[string]string, names ...string) string {
+	for _, name := range names {
+		for item, value := range items {
+			if value != "" && strings.EqualFold(item, name) {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// SoundWatcher report the sounds detected by the devices of an engine
+type SoundWatcher struct {
+	classes []string
+	onSound func(SoundEvent)
+
+	mutex  sync.Mutex
+	active map[string]bool
+}
+
+// WatchSounds watch the sounds of the classes, every class when none, of the
+// devices of engine. onSound is called from the engine workers when a sound
+// starts, and when it ends for the devices reporting it as a property.
+func WatchSounds(engine *EventEngine, onSound func(SoundEvent), classes ...string) *SoundWatcher {
+	watcher := &SoundWatcher{classes: classes, onSound: onSound, active: make(map[string]bool)}
+	engine.Handle(watcher.handle)
+	return watcher
+}
+
+func (watcher *SoundWatcher) handle(ev Event) {
+	sound, ok := ParseSoundEvent(ev)
+	if !ok || (len(watcher.classes) > 0 && !containsString(watcher.classes, sound.Class)) {
+		return
+	}
+	key := sound.Device + "/" + sound.Source + "/" + sound.Rule + "/" + sound.Class
+	/* 属性事件只在状态变化时通知 */
+	if ev.Operation != "" {
+		watcher.mutex.Lock()
+		previous := watcher.active[key]
+		watcher.active[key] = sound.Active
+		watcher.mutex.Unlock()
+		if previous == sound.Active {
+			return
+		}
+	}
+	if watcher.onSound != nil {
+		watcher.onSound(sound)
+	}
+}
+
+// Active report whether a sound of class is in progress on device, for the
+// devices reporting the end of the sounds
+func (watcher *SoundWatcher) Active(device, class string) bool {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	for key, active := range watcher.active {
+		if active && strings.HasPrefix(key, device+"/") && strings.HasSuffix(key, "/"+class) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAnalyticsRule return a rule of ruleType named name, the parameters
+// sorted by name
+func NewAnalyticsRule(name, ruleType string, parameters map[string]string) analytics.Config {
+	rule := analytics.Config{Name: name, Type: xsd.QName(ruleType)}
+	names := make([]string, 0, len(parameters))
+	for parameter := range parameters {
+		names = append(names, parameter)
+	}
+	sort.Strings(names)
+	for _, parameter := range names {
+		rule.Parameters.SimpleItem = append(rule.Parameters.SimpleItem, analytics.SimpleItem{Name: parameter, Value: parameters[parameter]})
+	}
+	return rule
+}
+
+// AudioRuleSchemas return the schemas of the audio rule types supported by
+// the video analytics configuration, see RuleSchemas
+func (dev *Device) AudioRuleSchemas(ctx context.Context, configuration string) ([]AnalyticsSchema, error) {
+	schemas, err := dev.RuleSchemas(ctx, configuration)
+	if err != nil {
+		return nil, err
+	}
+	audio := schemas[:0]
+	for _, schema := range schemas {
+		name := strings.ToLower(localName(schema.Type))
+		if soundClass(name) != "" || strings.Contains(name, "audio") || strings.Contains(name, "sound") {
+			audio = append(audio, schema)
+		}
+	}
+	return audio, nil
+}
+
+// NewLoudNoiseRule return a rule of the audio schema triggering above level,
+// from 0 to 1, set on the first level, threshold or sensitivity parameter of
+// the schema and scaled to its range. false when the schema has no such
+// parameter.
+func NewLoudNoiseRule(schema AnalyticsSchema, name string, level float64) (analytics.Config, bool) {
+	for _, parameter := range schema.Parameters {
+		lower := strings.ToLower(parameter.Name)
+		if !strings.Contains(lower, "level") && !strings.Contains(lower, "threshold") && !strings.Contains(lower, "sensitivity") {
+			continue
+		}
+		value := level
+		if parameter.Min != nil && parameter.Max != nil {
+			value = *parameter.Min + level*(*parameter.Max-*parameter.Min)
+		}
+		formatted := strconv.FormatFloat(value, 'f', -1, 64)
+		if parameter.Type == "int" || parameter.Type == "integer" {
+			formatted = strconv.Itoa(int(value + 0.5))
+		}
+		return NewAnalyticsRule(name, schema.Type, map[string]string{parameter.Name: formatted}), true
+	}
+	return analytics.Config{}, false
+}
+
+// CreateRule create rule in the video analytics configuration
+func (dev *Device) CreateRule(ctx context.Context, configuration string, rule analytics.Config) error {
+	request := analytics.CreateRules{ConfigurationToken: onvif.ReferenceToken(configuration), Rule: []analytics.Config{rule}}
+	return dev.CallMethodInterfaceContext(ctx, request, &analytics.CreateRulesResponse{}, "")
+}
+
+// DeleteRule delete the rule named name of the video analytics configuration
+func (dev *Device) DeleteRule(ctx context.Context, configuration, name string) error {
+	request := analytics.DeleteRules{ConfigurationToken: onvif.ReferenceToken(configuration), RuleName: xsd.String(name)}
+	return dev.CallMethodInterfaceContext(ctx, request, &analytics.DeleteRulesResponse{}, "")
+}
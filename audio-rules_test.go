@@ -0,0 +1,81 @@
+package onvif
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseSoundEvent(t *testing.T) {
+	ev := Event{Device: "camera", Topic: "tns1:AudioAnalytics/Audio/DetectedSound", Operation: "Changed",
+		Source: map[string]string{"AudioSourceConfigurationToken": "as0", "Rule": "sound"}, Data: map[string]string{"SoundClass": "glass_break", "IsSoundDetected": "true"}}
+	if sound, ok := ParseSoundEvent(ev); !ok || sound.Class != SoundGlassBreak || sound.Source != "as0" || sound.Rule != "sound" || !sound.Active {
+		t.Fatalf("sound %+v, %v", sound, ok)
+	}
+	/* 未知类别保留设备的名称,厂商主题由主题推断类别 */
+	ev.Data = map[string]string{"class": "Dog"}
+	if sound, ok := ParseSoundEvent(ev); !ok || sound.Class != "Dog" {
+		t.Fatalf("sound %+v, %v", sound, ok)
+	}
+	vendor := Event{Topic: "tns1:AudioSource/tnsaxis:TriggerLevel", Operation: "Changed", Source: map[string]string{"channel": "1"}, Data: map[string]string{"triggered": "0"}}
+	if sound, ok := ParseSoundEvent(vendor); !ok || sound.Class != SoundLoudNoise || sound.Source != "1" || sound.Active {
+		t.Fatalf("sound %+v, %v", sound, ok)
+	}
+	for _, topic := range []string{"tns1:VideoSource/MotionAlarm", "tns1:AudioSource/Configuration"} {
+		if sound, ok := ParseSoundEvent(Event{Topic: topic}); ok {
+			t.Fatalf("sound %+v in %s", sound, topic)
+		}
+	}
+}
+
+func TestSoundWatcher(t *testing.T) {
+	var sounds []SoundEvent
+	watcher := WatchSounds(testEngine(1), func(sound SoundEvent) { sounds = append(sounds, sound) }, SoundScream)
+	scream := func(state string) Event {
+		return Event{Device: "camera", Topic: "tns1:AudioAnalytics/Audio/DetectedSound", Operation: "Changed", Data: map[string]string{"SoundClass": "Scream", "IsSoundDetected": state}}
+	}
+	/* 属性事件只在状态变化时通知 */
+	watcher.handle(scream("true"))
+	watcher.handle(scream("true"))
+	if len(sounds) != 1 || !watcher.Active("camera", SoundScream) {
+		t.Fatalf("sounds %+v", sounds)
+	}
+	watcher.handle(scream("false"))
+	if len(sounds) != 2 || sounds[1].Active || watcher.Active("camera", SoundScream) {
+		t.Fatalf("sounds %+v", sounds)
+	}
+	watcher.handle(Event{Device: "camera", Topic: "tns1:AudioSource/LoudNoise"})
+	if len(sounds) != 2 {
+		t.Fatalf("sound of another class reported: %+v", sounds)
+	}
+}
+
+func TestLoudNoiseRule(t *testing.T) {
+	min, max := 0.0, 100.0
+	schema := AnalyticsSchema{Type: "acme:AudioDetection", Parameters: []AnalyticsParameter{{Name: "Mode", Type: "string"}, {Name: "TriggerLevel", Type: "int", Min: &min, Max: &max}}}
+	rule, ok := NewLoudNoiseRule(schema, "loud", 0.755)
+	if !ok || rule.Name != "loud" || rule.Type != "acme:AudioDetection" || len(rule.Parameters.SimpleItem) != 1 ||
+		rule.Parameters.SimpleItem[0].Name != "TriggerLevel" || rule.Parameters.SimpleItem[0].Value != "76" {
+		t.Fatalf("rule %+v, %v", rule, ok)
+	}
+	if _, ok := NewLoudNoiseRule(AnalyticsSchema{Parameters: []AnalyticsParameter{{Name: "Mode"}}}, "loud", 0.5); ok {
+		t.Fatal("rule without level parameter")
+	}
+
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetSupportedRules": `<tan:GetSupportedRulesResponse><tan:SupportedRules><tt:RuleDescription Name="tt:LineDetector"/><tt:RuleDescription Name="acme:GlassBreakDetector"/>` +
+			`</tan:SupportedRules></tan:GetSupportedRulesResponse>`,
+		"CreateRules": `<tan:CreateRulesResponse/>`,
+	}, ServiceAnalytics)
+	ctx := context.Background()
+	schemas, err := dev.AudioRuleSchemas(ctx, "analytics")
+	if err != nil || len(schemas) != 1 || schemas[0].Type != "acme:GlassBreakDetector" {
+		t.Fatalf("schemas %+v, %v", schemas, err)
+	}
+	if err := dev.CreateRule(ctx, "analytics", rule); err != nil {
+		t.Fatal(err)
+	}
+	if sent := fake.sent("CreateRules"); len(sent) != 1 || !strings.Contains(sent[0], `<tt:SimpleItem Name="TriggerLevel" Value="76"></tt:SimpleItem>`) {
+		t.Fatalf("requests %q", sent)
+	}
+}
@@ -0,0 +1,267 @@
+package onvif
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+// AuditSeverity severity of an audit finding
+type AuditSeverity int
+
+// Severities of the audit findings, in increasing order
+const (
+	AuditInfo AuditSeverity = iota
+	AuditWarning
+	AuditCritical
+)
+
+func (severity AuditSeverity) String() string {
+	switch severity {
+	case AuditInfo:
+		return "info"
+	case AuditWarning:
+		return "warning"
+	case AuditCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// AuditFinding result of one check of the hardening audit
+type AuditFinding struct {
+	Check    string
+	Severity AuditSeverity
+	Detail   string
+}
+
+// AuditReport security report of one device
+type AuditReport struct {
+	Device   string
+	Findings []AuditFinding
+}
+
+// Worst return the highest severity of the findings
+func (report AuditReport) Worst() AuditSeverity {
+	worst := AuditInfo
+	for _, finding := range report.Findings {
+		if finding.Severity > worst {
+			worst = finding.Severity
+		}
+	}
+	return worst
+}
+
+func (report *AuditReport) add(check string, severity AuditSeverity, format string, args ...interface{}) {
+	report.Findings = append(report.Findings, AuditFinding{Check: check, Severity: severity, Detail: fmt.Sprintf(format, args...)})
+}
+
+// UserCredential username and password pair
+type UserCredential struct {
+	Username string
+	Password string
+}
+
+// DefaultCredentials factory credentials tried by Audit. Every pair is one
+// authenticated request, keep the list short on devices locking accounts.
+var DefaultCredentials = []UserCredential{
+	{"admin", "admin"},
+	{"admin", "12345"},
+	{"admin", "123456"},
+	{"root", "pass"},
+	{"service", "service"},
+}
+
+// FirmwareMaxAge age above which the firmware is reported as outdated
+var FirmwareMaxAge = 2 * 365 * 24 * time.Hour
+
+/* 固件版本中常见的构建日期格式: 2021-03-15, 20210315, build 210315 */
+var (
+	firmwareDateRegexp  = regexp.MustCompile(`(20\d{2})[-._]?(0[1-9]|1[0-2])[-._]?(0[1-9]|[12]\d|3[01])`)
+	firmwareBuildRegexp = regexp.MustCompile(`(?i)build\s*(\d{2})(0[1-9]|1[0-2])(0[1-9]|[12]\d|3[01])`)
+)
+
+// FirmwareDate extract the build date found in a firmware version string
+func FirmwareDate(version string) (time.Time, bool) {
+	if match := firmwareBuildRegexp.FindStringSubmatch(version); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		day, _ := strconv.Atoi(match[3])
+		return time.Date(2000+year, time.Month(month), day, 0, 0, 0, 0, time.UTC), true
+	}
+	if match := firmwareDateRegexp.FindStringSubmatch(version); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		day, _ := strconv.Atoi(match[3])
+		return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), true
+	}
+	return time.Time{}, false
+}
+
+/* tls.VersionName需要go1.21 */
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// withCredential return a copy of dev authenticating as credential
+func (dev *Device) withCredential(credential UserCredential) *Device {
+	probe := *dev
+	probe.Params.Username = credential.Username
+	probe.Params.Password = credential.Password
+	return &probe
+}
+
+// Audit check the hardening of the device: default or missing credentials,
+// plain HTTP, WS-Discovery, firmware age, access policy and TLS
+// configuration. Checks the device does not answer are reported as info
+// findings.
+func (dev *Device) Audit(ctx context.Context) AuditReport {
+	report := AuditReport{Device: dev.Params.Ipddr}
+	info := device.GetDeviceInformationResponse{}
+
+	/* 匿名访问 */
+	anonymous := dev.withCredential(UserCredential{})
+	if err := anonymous.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &info, ""); err == nil {
+		report.add("anonymous-access", AuditCritical, "device information readable without credentials")
+	} else {
+		report.add("anonymous-access", AuditInfo, "authentication required")
+	}
+
+	/* 默认密码 */
+	for _, credential := range DefaultCredentials {
+		if credential.Username == dev.Params.Username && credential.Password == dev.Params.Password {
+			report.add("default-credentials", AuditCritical, "configured credentials are the factory default for %s", credential.Username)
+			continue
+		}
+		probe := dev.withCredential(credential)
+		if err := probe.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &device.GetDeviceInformationResponse{}, ""); err == nil {
+			report.add("default-credentials", AuditCritical, "factory credentials accepted for user %s", credential.Username)
+		}
+	}
+
+	/* 固件版本 */
+	info = device.GetDeviceInformationResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &info, ""); err != nil {
+		report.add("firmware", AuditInfo, "device information unavailable: %v", err)
+	} else if date, ok := FirmwareDate(info.FirmwareVersion); !ok {
+		report.add("firmware", AuditInfo, "no build date in firmware %s", info.FirmwareVersion)
+	} else if age := time.Since(date); age > FirmwareMaxAge {
+		report.add("firmware", AuditWarning, "firmware %s built %s, %d days ago", info.FirmwareVersion, date.Format("2006-01-02"), int(age.Hours()/24))
+	} else {
+		report.add("firmware", AuditInfo, "firmware %s built %s", info.FirmwareVersion, date.Format("2006-01-02"))
+	}
+
+	/* WS-Discovery */
+	discovery := device.GetDiscoveryModeResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetDiscoveryMode{}, &discovery, ""); err != nil {
+		report.add("ws-discovery", AuditInfo, "discovery mode unavailable: %v", err)
+	} else if discovery.DiscoveryMode == "Discoverable" {
+		report.add("ws-discovery", AuditWarning, "device answers WS-Discovery probes")
+	} else {
+		report.add("ws-discovery", AuditInfo, "device is %s", discovery.DiscoveryMode)
+	}
+
+	/* 访问策略 */
+	if policy, err := dev.AccessPolicy(ctx); errors.Is(err, ErrNotSupported) {
+		report.add("access-policy", AuditInfo, "access policy not configurable")
+	} else if err != nil {
+		report.add("access-policy", AuditInfo, "access policy unavailable: %v", err)
+	} else if permissive, known := policy.Permissive(); permissive && len(policy.Data) == 0 {
+		report.add("access-policy", AuditWarning, "no access policy set, the device default applies")
+	} else if permissive {
+		report.add("access-policy", AuditWarning, "access policy permits every request")
+	} else if !known {
+		report.add("access-policy", AuditInfo, "access policy of %d bytes in unknown format %q", len(policy.Data), policy.ContentType)
+	} else {
+		report.add("access-policy", AuditInfo, "access policy of %d bytes restricts access", len(policy.Data))
+	}
+
+	/* HTTP/HTTPS */
+	httpsPort := 0
+	protocols := device.GetNetworkProtocolsResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetNetworkProtocols{}, &protocols, ""); err != nil {
+		report.add("transport", AuditInfo, "network protocols unavailable: %v", err)
+	} else {
+		httpEnabled := false
+		for _, protocol := range protocols.Protocols {
+			switch {
+			case protocol.Name == "HTTP" && bool(protocol.Enabled):
+				httpEnabled = true
+			case protocol.Name == "HTTPS" && bool(protocol.Enabled):
+				httpsPort = int(protocol.Port)
+			}
+		}
+		switch {
+		case httpEnabled && httpsPort == 0:
+			report.add("transport", AuditCritical, "only plain HTTP is enabled")
+		case httpEnabled:
+			report.add("transport", AuditWarning, "plain HTTP enabled next to HTTPS")
+		default:
+			report.add("transport", AuditInfo, "only HTTPS is enabled")
+		}
+	}
+	if endpoint, err := dev.getEndpoint("device"); err == nil && strings.HasPrefix(endpoint, "http:") {
+		report.add("transport", AuditWarning, "ONVIF service used over plain HTTP at %s", endpoint)
+	}
+
+	/* TLS版本和加密套件 */
+	if httpsPort > 0 {
+		dev.auditTLS(ctx, &report, httpsPort)
+	}
+	return report
+}
+
+func (dev *Device) auditTLS(ctx context.Context, report *AuditReport, port int) {
+	host, _ := dev.Params.address()
+	conn, err := dev.dialTCP(ctx, net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		report.add("tls", AuditInfo, "https port %d unreachable: %v", port, err)
+		return
+	}
+	defer conn.Close()
+	/* 仅用于审计握手参数,不校验证书 */
+	client := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS10})
+	if deadline, ok := ctx.Deadline(); ok {
+		client.SetDeadline(deadline)
+	} else {
+		client.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+	if err := client.Handshake(); err != nil {
+		report.add("tls", AuditWarning, "tls handshake failed: %v", err)
+		return
+	}
+	state := client.ConnectionState()
+	version := tlsVersionNames[state.Version]
+	cipher := tls.CipherSuiteName(state.CipherSuite)
+	if state.Version < tls.VersionTLS12 {
+		report.add("tls", AuditCritical, "negotiated %s with %s", version, cipher)
+	} else {
+		report.add("tls", AuditInfo, "negotiated %s with %s", version, cipher)
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == state.CipherSuite {
+			report.add("tls", AuditWarning, "insecure cipher suite %s", cipher)
+		}
+	}
+}
+
+// Audit run the hardening audit on every device of the fleet, a
+// PartialError is returned with the reports when ctx ended first
+func (fleet *Fleet) Audit(ctx context.Context) ([]AuditReport, error) {
+	reports := make([]AuditReport, len(fleet.Devices))
+	err := fleet.each(ctx, func(i int, dev *Device) {
+		reports[i] = dev.Audit(ctx)
+	})
+	return reports, err
+}
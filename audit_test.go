@@ -0,0 +1,62 @@
+package onvif
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFirmwareDate(t *testing.T) {
+	for version, want := range map[string]time.Time{
+		"V5.5.0 build 200115":          time.Date(2020, time.January, 15, 0, 0, 0, 0, time.UTC),
+		"2.800.0000000.9.R_2021-03-15": time.Date(2021, time.March, 15, 0, 0, 0, 0, time.UTC),
+		"IPC_20230901":                 time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		if date, ok := FirmwareDate(version); !ok || !date.Equal(want) {
+			t.Fatalf("%s: date %s, %v", version, date, ok)
+		}
+	}
+	if date, ok := FirmwareDate("1.2.3"); ok {
+		t.Fatalf("date %s in a version without date", date)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	defaults := DefaultCredentials
+	DefaultCredentials = []UserCredential{{"admin", "admin"}}
+	defer func() { DefaultCredentials = defaults }()
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetDeviceInformation": `<tds:GetDeviceInformationResponse><tds:FirmwareVersion>V5.5.0 build 200115</tds:FirmwareVersion></tds:GetDeviceInformationResponse>`,
+		"GetDiscoveryMode":     `<tds:GetDiscoveryModeResponse><tds:DiscoveryMode>Discoverable</tds:DiscoveryMode></tds:GetDiscoveryModeResponse>`,
+		"GetNetworkProtocols": `<tds:GetNetworkProtocolsResponse><tds:NetworkProtocols><tt:Name>HTTP</tt:Name><tt:Enabled>true</tt:Enabled><tt:Port>80</tt:Port></tds:NetworkProtocols>` +
+			`<tds:NetworkProtocols><tt:Name>HTTPS</tt:Name><tt:Enabled>false</tt:Enabled><tt:Port>443</tt:Port></tds:NetworkProtocols></tds:GetNetworkProtocolsResponse>`,
+	})
+	dev.Params.Username, dev.Params.Password = "admin", "admin"
+	report := dev.Audit(context.Background())
+	/* 每项检查的最高级别 */
+	worst := map[string]AuditSeverity{}
+	for _, finding := range report.Findings {
+		if severity, ok := worst[finding.Check]; !ok || finding.Severity > severity {
+			worst[finding.Check] = finding.Severity
+		}
+	}
+	for check, want := range map[string]AuditSeverity{
+		"anonymous-access":    AuditCritical,
+		"default-credentials": AuditCritical,
+		"firmware":            AuditWarning,
+		"ws-discovery":        AuditWarning,
+		"access-policy":       AuditInfo,
+		"transport":           AuditCritical,
+	} {
+		if severity, ok := worst[check]; !ok || severity != want {
+			t.Fatalf("%s %s, want %s in %+v", check, severity, want, report.Findings)
+		}
+	}
+	if _, ok := worst["tls"]; ok || report.Worst() != AuditCritical {
+		t.Fatalf("findings %+v", report.Findings)
+	}
+	/* 配置的凭据即为出厂默认时不再尝试 */
+	if requests := fake.sent("GetDeviceInformation"); len(requests) != 2 {
+		t.Fatalf("%d device information requests", len(requests))
+	}
+}
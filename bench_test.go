@@ -0,0 +1,216 @@
+package onvif
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/soap"
+	event "github.com/PolarisM78/go-onvif/types/events"
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/types/ptz"
+	tt "github.com/PolarisM78/go-onvif/xsd/onvif"
+	"github.com/beevik/etree"
+)
+
+/*
+运行: go test -run '^$' -bench . -benchmem
+修改传输/解析代码前后各运行一次对比ns/op与allocs/op,未打算改动的路径退化超过10%需在提交中说明。
+基准数据(linux/amd64):
+
+	LegacyEnvelope      637   1859643 ns/op   589116 B/op   4675 allocs/op
+	BuildEnvelope     37102     33030 ns/op    11897 B/op     64 allocs/op
+	CheckXML          20329     61197 ns/op     6912 B/op    163 allocs/op
+	DecodeProfiles     4690    268549 ns/op    43752 B/op    725 allocs/op
+	PullMessages       6775    194685 ns/op    33686 B/op    499 allocs/op
+	DiscoveryEtree    31837     37173 ns/op    14216 B/op    195 allocs/op
+	ParseProbeMatches 22284     59489 ns/op    11528 B/op    224 allocs/op
+
+TestAllocationBudget 以分配次数为回归门限,分配次数与机器无关,可在CI中稳定运行。
+*/
+
+/* 连续移动请求,模拟高频ptz控制 */
+var continuousMove = ptz.ContinuousMove{
+	ProfileToken: tt.ReferenceToken("Profile_1"),
+	Velocity:     tt.PTZSpeed{PanTilt: tt.Vector2D{X: 0.5, Y: -0.5}},
+}
+
+const profilesResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:trt="http://www.onvif.org/ver10/media/wsdl">
+<env:Body><trt:GetProfilesResponse>
+<trt:Profiles token="Profile_1" fixed="true"><tt:Name>mainStream</tt:Name>
+<tt:VideoSourceConfiguration token="VideoSourceToken"><tt:Name>VideoSourceConfig</tt:Name><tt:UseCount>2</tt:UseCount><tt:SourceToken>VideoSource_1</tt:SourceToken><tt:Bounds x="0" y="0" width="2560" height="1440"></tt:Bounds></tt:VideoSourceConfiguration>
+<tt:VideoEncoderConfiguration token="VideoEncoderToken_1"><tt:Name>VideoEncoder_1</tt:Name><tt:UseCount>1</tt:UseCount><tt:Encoding>H264</tt:Encoding><tt:Resolution><tt:Width>2560</tt:Width><tt:Height>1440</tt:Height></tt:Resolution><tt:Quality>3.0</tt:Quality><tt:RateControl><tt:FrameRateLimit>25</tt:FrameRateLimit><tt:EncodingInterval>1</tt:EncodingInterval><tt:BitrateLimit>4096</tt:BitrateLimit></tt:RateControl><tt:H264><tt:GovLength>50</tt:GovLength><tt:H264Profile>Main</tt:H264Profile></tt:H264><tt:SessionTimeout>PT5S</tt:SessionTimeout></tt:VideoEncoderConfiguration>
+</trt:Profiles>
+</trt:GetProfilesResponse></env:Body></env:Envelope>`
+
+const pullMessagesResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2" xmlns:tev="http://www.onvif.org/ver10/events/wsdl" xmlns:tns1="http://www.onvif.org/ver10/topics">
+<env:Body><tev:PullMessagesResponse><tev:CurrentTime>2022-02-10T03:00:00Z</tev:CurrentTime><tev:TerminationTime>2022-02-10T03:01:00Z</tev:TerminationTime>
+<wsnt:NotificationMessage><wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">tns1:RuleEngine/CellMotionDetector/Motion</wsnt:Topic>
+<wsnt:Message><tt:Message UtcTime="2022-02-10T03:00:00Z" PropertyOperation="Changed"><tt:Source><tt:SimpleItem Name="VideoSourceConfigurationToken" Value="VideoSourceToken"/><tt:SimpleItem Name="Rule" Value="MyMotionDetectorRule"/></tt:Source><tt:Data><tt:SimpleItem Name="IsMotion" Value="true"/></tt:Data></tt:Message></wsnt:Message>
+</wsnt:NotificationMessage></tev:PullMessagesResponse></env:Body></env:Envelope>`
+
+const probeMatches = `<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:wsadis="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:dn="http://www.onvif.org/ver10/network/wsdl">
+<env:Header><wsadis:MessageID>urn:uuid:5f5a69c2-e0ae-504f-829b-00256b1d2b6a</wsadis:MessageID></env:Header>
+<env:Body><d:ProbeMatches><d:ProbeMatch><wsadis:EndpointReference><wsadis:Address>urn:uuid:5f5a69c2-e0ae-504f-829b-00256b1d2b6a</wsadis:Address></wsadis:EndpointReference>
+<d:Types>dn:NetworkVideoTransmitter tds:Device</d:Types>
+<d:Scopes>onvif://www.onvif.org/type/video_encoder onvif://www.onvif.org/Profile/Streaming onvif://www.onvif.org/MAC/00:25:6b:1d:2b:6a onvif://www.onvif.org/hardware/DS-2CD3T45P1-I onvif://www.onvif.org/name/HIKVISION</d:Scopes>
+<d:XAddrs>http://10.1.1.200/onvif/device_service</d:XAddrs><d:MetadataVersion>10</d:MetadataVersion></d:ProbeMatch></d:ProbeMatches></env:Body></env:Envelope>`
+
+/* 固定返回报文的http传输层,排除网络耗时 */
+type cannedTransport string
+
+func (body cannedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewBufferString(string(body))),
+		Request:    req,
+	}, nil
+}
+
+/* 所有服务均返回固定报文的设备 */
+func cannedDevice(body string) *Device {
+	dev := newDevice(DeviceParams{
+		Ipddr:      "10.1.1.200",
+		HttpClient: &http.Client{Transport: cannedTransport(body)},
+	})
+	dev.endpoints["device"] = "http://10.1.1.200/onvif/device_service"
+	dev.endpoints["media"] = "http://10.1.1.200/onvif/Media"
+	dev.endpoints["events"] = "http://10.1.1.200/onvif/Events"
+	return dev
+}
+
+/* 旧的构建方式: 序列化 -> etree解析 -> 多次字符串化 */
+func legacyEnvelope(method interface{}) string {
+	output, _ := xml.Marshal(method)
+	doc := etree.NewDocument()
+	doc.ReadFromBytes(output)
+	msg := soap.NewEmptySOAP()
+	msg.AddBodyContent(doc.Root())
+	msg.AddRootNamespaces(Xlmns)
+	msg.AddWSSecurity("admin", "password")
+	return msg.String()
+}
+
+func buildEnvelope(b testing.TB) {
+	buf, err := soap.BuildEnvelope(Xlmns, continuousMove, "admin", "password")
+	if err != nil {
+		b.Fatal(err)
+	}
+	soap.PutBuffer(buf)
+}
+
+func checkXML(b testing.TB) {
+	if err := soap.CheckXML([]byte(profilesResponse), soap.DefaultLimits); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func decodeProfiles(b testing.TB, dev *Device) {
+	response := media.GetProfilesResponse{}
+	if err := dev.CallMethodInterface(media.GetProfiles{}, &response, ""); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func pullMessages(b testing.TB, dev *Device) {
+	response := event.PullMessagesResponse{}
+	if err := dev.CallMethodInterface(event.PullMessages{Timeout: "PT10S", MessageLimit: 10}, &response, ""); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func parseProbeMatches(b testing.TB) {
+	if _, err := soap.ParseProbeMatches([]byte(probeMatches)); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkLegacyEnvelope(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		legacyEnvelope(continuousMove)
+	}
+}
+
+func BenchmarkBuildEnvelope(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buildEnvelope(b)
+	}
+}
+
+func BenchmarkCheckXML(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		checkXML(b)
+	}
+}
+
+func BenchmarkDecodeProfiles(b *testing.B) {
+	dev := cannedDevice(profilesResponse)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decodeProfiles(b, dev)
+	}
+}
+
+func BenchmarkPullMessages(b *testing.B) {
+	dev := cannedDevice(pullMessagesResponse)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pullMessages(b, dev)
+	}
+}
+
+func BenchmarkDiscoveryEtree(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		doc := etree.NewDocument()
+		if err := doc.ReadFromString(probeMatches); err != nil {
+			b.Fatal(err)
+		}
+		doc.Root().FindElements("./Body/ProbeMatches/ProbeMatch/XAddrs")
+		doc.Root().FindElements("./Body/ProbeMatches/ProbeMatch/Scopes")
+	}
+}
+
+func BenchmarkParseProbeMatches(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parseProbeMatches(b)
+	}
+}
+
+// TestAllocationBudget fail when a hot path allocates more than its budget,
+// the budgets leaving about 25% over the baselines above
+func TestAllocationBudget(t *testing.T) {
+	profiles := cannedDevice(profilesResponse)
+	messages := cannedDevice(pullMessagesResponse)
+	budgets := []struct {
+		name   string
+		budget float64
+		run    func()
+	}{
+		{"BuildEnvelope", 80, func() { buildEnvelope(t) }},
+		{"CheckXML", 205, func() { checkXML(t) }},
+		{"DecodeProfiles", 900, func() { decodeProfiles(t, profiles) }},
+		{"PullMessages", 620, func() { pullMessages(t, messages) }},
+		{"ParseProbeMatches", 280, func() { parseProbeMatches(t) }},
+	}
+	for _, path := range budgets {
+		if allocs := testing.AllocsPerRun(20, path.run); allocs > path.budget {
+			t.Errorf("%s: %.0f allocs/op, budget %.0f", path.name, allocs, path.budget)
+		}
+	}
+}
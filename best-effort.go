@@ -0,0 +1,282 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/types/media2"
+)
+
+// Paths reported by the best effort helpers in Degradation.Path, the motion
+// watch reports the topic it watches
+const (
+	PathSnapshotURI    = SnapshotFromURI
+	PathRTSPFrame      = SnapshotFromRTSP
+	PathH265           = "h265"
+	PathH264           = "h264"
+	PathAnyEncoding    = "any-encoding"
+	PathAnyMotionTopic = "any-motion-topic"
+)
+
+// Motion topics, without namespace prefix, in order of preference
+const (
+	TopicCellMotion   = "RuleEngine/CellMotionDetector/Motion"
+	TopicRegionMotion = "RuleEngine/MotionRegionDetector/Motion"
+	TopicMotionAlarm  = "VideoSource/MotionAlarm"
+)
+
+// motionTopics topics tried by MotionWatchAnyTopic
+var motionTopics = []string{TopicCellMotion, TopicRegionMotion, TopicMotionAlarm}
+
+// Degradation path taken by a best effort helper among the ones it can use,
+// sparing the application the per vendor branching
+type Degradation struct {
+	Path string
+	// Fallback the preferred path was not taken
+	Fallback bool
+	// Reasons why the paths preferred to Path were not taken
+	Reasons []string
+}
+
+func (degradation *Degradation) skip(reason string) {
+	degradation.Fallback = true
+	degradation.Reasons = append(degradation.Reasons, reason)
+}
+
+// quirksOrNone return the quirks of the device, none when its manufacturer
+// cannot be read
+func (dev *Device) quirksOrNone(ctx context.Context) Quirks {
+	quirks, err := dev.Quirks(ctx)
+	if err != nil {
+		return Quirks{}
+	}
+	return quirks
+}
+
+// SnapshotOrFrame return a still image of the profile, the first one when
+// empty, from its snapshot URI, or from a frame of the RTSP stream grabbed by
+// DeviceParams.FrameGrabber when the device advertises no snapshot URI, is
+// known to serve no usable image there, or fails to.
+func (dev *Device) SnapshotOrFrame(ctx context.Context, profile string) (Snapshot, Degradation, error) {
+	degradation := Degradation{Path: PathSnapshotURI}
+	if profile == "" {
+		token, err := firstProfile(ctx, dev)
+		if err != nil {
+			return Snapshot{}, degradation, err
+		}
+		profile = string(token)
+	}
+	tryURI := true
+	if capabilities, err := dev.ServiceCapabilities(ctx); err == nil && capabilities.Media != nil && !capabilities.Media.SnapshotUri {
+		tryURI = false
+		degradation.skip("device advertises no snapshot uri")
+	} else if dev.quirksOrNone(ctx).NoSnapshotURI {
+		tryURI = false
+		degradation.skip("snapshot uri of the device is known to be unusable")
+	}
+	if tryURI {
+		snapshot, err := dev.uriSnapshot(ctx, profile)
+		if err == nil || dev.Params.FrameGrabber == nil {
+			return snapshot, degradation, err
+		}
+		degradation.skip("snapshot uri: " + err.Error())
+	}
+	degradation.Path = PathRTSPFrame
+	if dev.Params.FrameGrabber == nil {
+		return Snapshot{}, degradation, &NotSupportedError{Service: "media", Capability: "SnapshotUri"}
+	}
+	snapshot, err := dev.rtspSnapshot(ctx, profile)
+	return snapshot, degradation, err
+}
+
+// StreamChoice stream picked by StreamURIPreferH265
+type StreamChoice struct {
+	Profile string
+	URI     string
+	// Encoding of the video encoder of the profile, e.g. H265 or H264, empty
+	// when the profile has no video encoder
+	Encoding string
+}
+
+// StreamURIPreferH265 return the RTSP stream URI of the first H.265 profile
+// of the media2 service, or else of the first H.264 profile, or else of the
+// first profile whatever its encoding.
+func (dev *Device) StreamURIPreferH265(ctx context.Context) (StreamChoice, Degradation, error) {
+	degradation := Degradation{Path: PathH265}
+	if _, err := dev.getEndpoint("media2"); err != nil {
+		degradation.skip("device has no media2 service")
+	} else if choice, err := dev.media2H265Stream(ctx); err != nil {
+		degradation.skip("media2: " + err.Error())
+	} else if choice.Profile == "" {
+		degradation.skip("device has no H265 profile")
+	} else {
+		return choice, degradation, nil
+	}
+
+	profiles, err := dev.GetProfiles(ctx)
+	if err != nil {
+		return StreamChoice{}, degradation, err
+	}
+	if len(profiles) == 0 {
+		return StreamChoice{}, degradation, ErrNoProfile
+	}
+	profile := profiles[0]
+	degradation.Path = PathAnyEncoding
+	for _, candidate := range profiles {
+		if strings.EqualFold(string(candidate.VideoEncoderConfiguration.Encoding), "H264") {
+			profile = candidate
+			degradation.Path = PathH264
+			break
+		}
+	}
+	if degradation.Path == PathAnyEncoding {
+		degradation.skip("device has no H264 profile")
+	}
+	uri, err := dev.GetStreamURI(ctx, string(profile.Token), StreamRTSP)
+	if err != nil {
+		return StreamChoice{}, degradation, err
+	}
+	return StreamChoice{Profile: string(profile.Token), URI: uri, Encoding: string(profile.VideoEncoderConfiguration.Encoding)}, degradation, nil
+}
+
+// media2H265Stream return the stream of the first H.265 profile of the media2
+// service, a zero choice when there is none
+func (dev *Device) media2H265Stream(ctx context.Context) (StreamChoice, error) {
+	profiles := media2.GetProfilesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media2.GetProfiles{Type: []string{"VideoEncoder"}}, &profiles, ""); err != nil {
+		return StreamChoice{}, err
+	}
+	for _, profile := range profiles.Profiles {
+		encoder := profile.Configurations.VideoEncoder
+		if encoder == nil || !strings.EqualFold(encoder.Encoding, "H265") {
+			continue
+		}
+		response := media2.GetStreamUriResponse{}
+		request := media2.GetStreamUri{Protocol: "RTSP", ProfileToken: profile.Token}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			return StreamChoice{}, err
+		}
+		uri := dev.RewriteHost(strings.TrimSpace(response.Uri))
+		if uri == "" {
+			return StreamChoice{}, ErrNoStreamURI
+		}
+		return StreamChoice{Profile: string(profile.Token), URI: uri, Encoding: encoder.Encoding}, nil
+	}
+	return StreamChoice{}, nil
+}
+
+// MotionWatch motion events of a device, see MotionWatchAnyTopic
+type MotionWatch struct {
+	// Topic watched, empty when every motion topic is
+	Topic       string
+	Degradation Degradation
+	events      <-chan Event
+	close       func()
+}
+
+// Events return the motion events, closed by Close
+func (watch *MotionWatch) Events() <-chan Event {
+	return watch.events
+}
+
+// Close end the watch
+func (watch *MotionWatch) Close() {
+	watch.close()
+}
+
+// MotionWatchAnyTopic watch the motion events of dev through the engine,
+// under the motion topic of the quirks of the device if any, or else under
+// the first of TopicCellMotion, TopicRegionMotion and TopicMotionAlarm the
+// device advertises. When the advertised topics cannot be read or none of
+// them matches, every motion topic is watched. MotionOf reads the state of
+// the events whatever their topic.
+func (engine *EventEngine) MotionWatchAnyTopic(ctx context.Context, dev *Device, buffer int) (*MotionWatch, error) {
+	watch := &MotionWatch{Degradation: Degradation{Path: PathAnyMotionTopic}}
+	if topic := dev.quirksOrNone(ctx).MotionTopic; topic != "" {
+		watch.Topic = topic
+	} else if topics, err := dev.eventTopics(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		watch.Degradation.skip("event properties: " + err.Error())
+	} else {
+		for _, topic := range motionTopics {
+			if topics[topic] {
+				watch.Topic = topic
+				break
+			}
+			watch.Degradation.skip("device does not advertise " + topic)
+		}
+	}
+	if watch.Topic != "" {
+		watch.Degradation.Path = watch.Topic
+	}
+	watch.events, watch.close = engine.Subscribe(dev, func(ev Event) bool {
+		if watch.Topic != "" {
+			return topicMatch(ev.Topic, watch.Topic)
+		}
+		for _, topic := range motionTopics {
+			if topicMatch(ev.Topic, topic) {
+				return true
+			}
+		}
+		return false
+	}, buffer)
+	return watch, nil
+}
+
+// MotionOf return the motion state of a motion event, from its IsMotion or
+// State item; false is returned as second value when the event has neither
+func MotionOf(ev Event) (bool, bool) {
+	for _, item := range []string{"IsMotion", "State"} {
+		if value, ok := ev.Data[item]; ok {
+			return logicalState(value)
+		}
+	}
+	return false, false
+}
+
+// errNoTopicSet returned when GetEventProperties holds no topic set
+var errNoTopicSet = errors.New("event properties hold no topic set")
+
+// eventTopics return the topics advertised by the TopicSet of the event
+// properties, without namespace prefix
+func (dev *Device) eventTopics(ctx context.Context) (map[string]bool, error) {
+	body, err := dev.CallRaw(ctx, "events", `<tev:GetEventProperties xmlns:tev="http://www.onvif.org/ver10/events/wsdl"/>`, nil)
+	if err != nil {
+		return nil, err
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	/* path为TopicSet下的元素路径,nil表示尚未进入TopicSet */
+	var path []string
+	topics := make(map[string]bool)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if path == nil {
+				return nil, errNoTopicSet
+			}
+			return topics, nil
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch {
+			case path != nil:
+				path = append(path, element.Name.Local)
+				topics[strings.Join(path[1:], "/")] = true
+			case element.Name.Local == "TopicSet":
+				path = []string{element.Name.Local}
+			}
+		case xml.EndElement:
+			if len(path) == 1 {
+				return topics, nil
+			}
+			if path != nil {
+				path = path[:len(path)-1]
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const bestEffortProfiles = `<trt:GetProfilesResponse>` +
+	`<trt:Profiles token="mjpeg"><tt:Name>mjpeg</tt:Name><tt:VideoEncoderConfiguration token="encoder_1"><tt:Name>encoder_1</tt:Name><tt:Encoding>JPEG</tt:Encoding></tt:VideoEncoderConfiguration></trt:Profiles>` +
+	`<trt:Profiles token="main"><tt:Name>main</tt:Name><tt:VideoEncoderConfiguration token="encoder_2"><tt:Name>encoder_2</tt:Name><tt:Encoding>H264</tt:Encoding></tt:VideoEncoderConfiguration></trt:Profiles>` +
+	`</trt:GetProfilesResponse>`
+
+func TestStreamURIPreferH265(t *testing.T) {
+	ctx := context.Background()
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetProfiles": `<tr2:GetProfilesResponse>` +
+			`<tr2:Profiles token="main" fixed="true"><tr2:Name>main</tr2:Name><tr2:Configurations><tr2:VideoEncoder token="encoder_1"><tt:Name>encoder_1</tt:Name><tt:Encoding>H264</tt:Encoding></tr2:VideoEncoder></tr2:Configurations></tr2:Profiles>` +
+			`<tr2:Profiles token="sub" fixed="true"><tr2:Name>sub</tr2:Name><tr2:Configurations><tr2:VideoEncoder token="encoder_2"><tt:Name>encoder_2</tt:Name><tt:Encoding>H265</tt:Encoding></tr2:VideoEncoder></tr2:Configurations></tr2:Profiles>` +
+			`</tr2:GetProfilesResponse>`,
+		"GetStreamUri": `<tr2:GetStreamUriResponse><tr2:Uri>rtsp://10.0.0.1:554/sub</tr2:Uri></tr2:GetStreamUriResponse>`,
+	}, ServiceMedia2)
+	choice, degradation, err := dev.StreamURIPreferH265(ctx)
+	if err != nil || choice.Profile != "sub" || choice.Encoding != "H265" || !strings.HasSuffix(choice.URI, ":554/sub") ||
+		degradation.Path != PathH265 || degradation.Fallback {
+		t.Fatalf("choice %+v, degradation %+v, %v", choice, degradation, err)
+	}
+	if sent := fake.sent("GetStreamUri"); len(sent) != 1 || !strings.Contains(sent[0], "<tr2:ProfileToken>sub</tr2:ProfileToken>") {
+		t.Fatalf("requests %q", sent)
+	}
+
+	/* 没有media2服务时取第一个H.264配置 */
+	fake, dev = newScriptedDevice(t, map[string]string{
+		"GetProfiles":  bestEffortProfiles,
+		"GetStreamUri": `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://10.0.0.1:554/main</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`,
+	}, ServiceMedia)
+	choice, degradation, err = dev.StreamURIPreferH265(ctx)
+	if err != nil || choice.Profile != "main" || choice.Encoding != "H264" || degradation.Path != PathH264 || !degradation.Fallback ||
+		len(degradation.Reasons) != 1 || degradation.Reasons[0] != "device has no media2 service" {
+		t.Fatalf("choice %+v, degradation %+v, %v", choice, degradation, err)
+	}
+
+	/* 没有H.264配置时取第一个配置 */
+	fake.set("GetProfiles", strings.Replace(bestEffortProfiles, "H264", "MPEG4", 1))
+	choice, degradation, err = dev.StreamURIPreferH265(ctx)
+	if err != nil || choice.Profile != "mjpeg" || choice.Encoding != "JPEG" || degradation.Path != PathAnyEncoding || len(degradation.Reasons) != 2 {
+		t.Fatalf("choice %+v, degradation %+v, %v", choice, degradation, err)
+	}
+
+	fake.set("GetProfiles", `<trt:GetProfilesResponse/>`)
+	if _, _, err := dev.StreamURIPreferH265(ctx); err != ErrNoProfile {
+		t.Fatalf("error %v without profile", err)
+	}
+}
+
+func TestSnapshotOrFrame(t *testing.T) {
+	ctx := context.Background()
+	_, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<trt:GetServiceCapabilitiesResponse><trt:Capabilities SnapshotUri="false"/></trt:GetServiceCapabilitiesResponse>`,
+		"GetStreamUri":           `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://10.0.0.1:554/main</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`,
+	}, ServiceMedia)
+	/* 设备未报告快照地址且没有截帧时不支持 */
+	_, degradation, err := dev.SnapshotOrFrame(ctx, "main")
+	var notSupported *NotSupportedError
+	if !errors.As(err, &notSupported) || degradation.Path != PathRTSPFrame || len(degradation.Reasons) != 1 || degradation.Reasons[0] != "device advertises no snapshot uri" {
+		t.Fatalf("degradation %+v, %v", degradation, err)
+	}
+
+	var grabbed string
+	dev.Params.FrameGrabber = FrameGrabberFunc(func(ctx context.Context, streamURI, username, password string) ([]byte, error) {
+		grabbed = streamURI
+		return jpegImage, nil
+	})
+	snapshot, degradation, err := dev.SnapshotOrFrame(ctx, "main")
+	if err != nil || snapshot.Source != SnapshotFromRTSP || snapshot.ContentType != "image/jpeg" || !strings.HasSuffix(grabbed, ":554/main") || !degradation.Fallback {
+		t.Fatalf("snapshot %+v, degradation %+v, %v", snapshot, degradation, err)
+	}
+}
+
+func TestMotionWatchAnyTopic(t *testing.T) {
+	ctx := context.Background()
+	engine := testEngine(1)
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetEventProperties": `<tev:GetEventPropertiesResponse><wstop:TopicSet>` +
+			`<tns1:RuleEngine><MotionRegionDetector><Motion wstop:topic="true"/></MotionRegionDetector></tns1:RuleEngine>` +
+			`<tns1:VideoSource><MotionAlarm wstop:topic="true"/></tns1:VideoSource>` +
+			`</wstop:TopicSet></tev:GetEventPropertiesResponse>`,
+	}, ServiceEvents)
+	/* 取设备报告的第一个移动侦测主题 */
+	watch, err := engine.MotionWatchAnyTopic(ctx, dev, 4)
+	if err != nil || watch.Topic != TopicRegionMotion || watch.Degradation.Path != TopicRegionMotion || len(watch.Degradation.Reasons) != 1 {
+		t.Fatalf("watch %+v, %v", watch, err)
+	}
+	engine.Inject(ctx, dev, Event{Topic: "tns1:" + TopicMotionAlarm, Data: map[string]string{"State": "true"}})
+	engine.Inject(ctx, dev, Event{Topic: "tns1:" + TopicRegionMotion, Data: map[string]string{"State": "true"}})
+	if ev := <-watch.Events(); ev.Topic != "tns1:"+TopicRegionMotion {
+		t.Fatalf("event %+v", ev)
+	}
+	select {
+	case ev := <-watch.Events():
+		t.Fatalf("unexpected event %+v", ev)
+	default:
+	}
+	watch.Close()
+
+	/* 读不到事件属性时接收所有移动侦测主题 */
+	fake.set("GetEventProperties", eventFault("ter:ActionNotSupported"))
+	watch, err = engine.MotionWatchAnyTopic(ctx, dev, 4)
+	if err != nil || watch.Topic != "" || watch.Degradation.Path != PathAnyMotionTopic || !watch.Degradation.Fallback {
+		t.Fatalf("watch %+v, %v", watch, err)
+	}
+	defer watch.Close()
+	engine.Inject(ctx, dev, Event{Topic: "tns1:Device/Trigger/DigitalInput", Data: map[string]string{"LogicalState": "true"}})
+	engine.Inject(ctx, dev, Event{Topic: "tns1:" + TopicMotionAlarm, Data: map[string]string{"State": "true"}})
+	if ev := <-watch.Events(); ev.Topic != "tns1:"+TopicMotionAlarm {
+		t.Fatalf("event %+v", ev)
+	}
+
+	/* 厂商特性中的主题优先,不读取事件属性 */
+	fake.set("GetDeviceInformation", `<tds:GetDeviceInformationResponse><tds:Manufacturer>HIKVISION</tds:Manufacturer></tds:GetDeviceInformationResponse>`)
+	requests := len(fake.sent("GetEventProperties"))
+	hikvision, err := engine.MotionWatchAnyTopic(ctx, dev, 4)
+	if err != nil || hikvision.Topic != TopicCellMotion || hikvision.Degradation.Fallback || len(fake.sent("GetEventProperties")) != requests {
+		t.Fatalf("watch %+v, %v", hikvision, err)
+	}
+	hikvision.Close()
+}
+
+func TestMotionOf(t *testing.T) {
+	for _, test := range []struct {
+		data          map[string]string
+		motion, known bool
+	}{
+		{map[string]string{"IsMotion": "true"}, true, true},
+		{map[string]string{"State": "false"}, false, true},
+		{map[string]string{"IsMotion": "0", "State": "true"}, false, true},
+		{map[string]string{"LogicalState": "true"}, false, false},
+	} {
+		if motion, known := MotionOf(Event{Data: test.data}); motion != test.motion || known != test.known {
+			t.Errorf("%v: %v, %v", test.data, motion, known)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"text/template"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ConfigTemplate declarative configuration document applied by Device.Apply.
+// Every section is optional, nil sections are left untouched on the device.
+// Text values are Go templates, see TemplateData for the available variables.
+type ConfigTemplate struct {
+	Name         string                `json:"name,omitempty"`
+	VideoEncoder *VideoEncoderTemplate `json:"videoEncoder,omitempty"`
+	NTP          *NTPTemplate          `json:"ntp,omitempty"`
+	OSD          *OSDTemplate          `json:"osd,omitempty"`
+	Vars         map[string]string     `json:"vars,omitempty"`
+}
+
+// VideoEncoderTemplate encoder settings, zero values keep the device value
+type VideoEncoderTemplate struct {
+	/* 为空时应用到所有profile的编码配置 */
+	Profiles         []string `json:"profiles,omitempty"`
+	Encoding         string   `json:"encoding,omitempty"`
+	Width            int      `json:"width,omitempty"`
+	Height           int      `json:"height,omitempty"`
+	Quality          float64  `json:"quality,omitempty"`
+	FrameRateLimit   int      `json:"frameRateLimit,omitempty"`
+	EncodingInterval int      `json:"encodingInterval,omitempty"`
+	BitrateLimit     int      `json:"bitrateLimit,omitempty"`
+	GovLength        int      `json:"govLength,omitempty"`
+}
+
+// NTPTemplate NTP settings
+type NTPTemplate struct {
+	FromDHCP bool   `json:"fromDHCP"`
+	Server   string `json:"server,omitempty"`
+}
+
+// OSDTemplate text overlay, an existing OSD is updated when Token is set,
+// otherwise a new one is created on the first profile video source.
+type OSDTemplate struct {
+	Token string  `json:"token,omitempty"`
+	Text  string  `json:"text"`
+	X     float64 `json:"x,omitempty"`
+	Y     float64 `json:"y,omitempty"`
+}
+
+// TemplateData variables available to ConfigTemplate text values,
+// e.g. "{{.Name}} {{.Vars.site}}"
+type TemplateData struct {
+	Name  string
+	IP    string
+	MAC   string
+	Model string
+	UUID  string
+	Vars  map[string]string
+}
+
+// ApplyStatus result of a single setting
+type ApplyStatus int
+
+const (
+	ApplyOK ApplyStatus = iota
+	ApplyFailed
+	ApplySkipped
+)
+
+func (status ApplyStatus) String() string {
+	switch status {
+	case ApplyOK:
+		return "ok"
+	case ApplyFailed:
+		return "failed"
+	case ApplySkipped:
+		return "skipped"
+	}
+	return fmt.Sprintf("ApplyStatus(%d)", int(status))
+}
+
+// ApplyResult outcome of one setting of a ConfigTemplate
+type ApplyResult struct {
+	Setting string
+	Status  ApplyStatus
+	Err     error
+}
+
+// ApplyReport per device outcome of Device.Apply
+type ApplyReport struct {
+	Device  string
+	Results []ApplyResult
+}
+
+// Failed reports whether at least one setting failed
+func (report ApplyReport) Failed() bool {
+	for _, result := range report.Results {
+		if result.Status == ApplyFailed {
+			return true
+		}
+	}
+	return false
+}
+
+func (report *ApplyReport) add(setting string, err error) {
+	status := ApplyOK
+	if errors.Is(err, ErrNotSupported) {
+		status = ApplySkipped
+	} else if err != nil {
+		status = ApplyFailed
+	}
+	report.Results = append(report.Results, ApplyResult{Setting: setting, Status: status, Err: err})
+}
+
+// ErrNotSupported returned when the device does not advertise the capability
+// required by an operation
+var ErrNotSupported = errors.New("operation not supported by device")
+
+// NotSupportedError ErrNotSupported naming the missing service capability
+type NotSupportedError struct {
+	Service    string
+	Capability string
+}
+
+func (err *NotSupportedError) Error() string {
+	if err.Capability == "" {
+		return fmt.Sprintf("%s service not supported by device", err.Service)
+	}
+	return fmt.Sprintf("%s %s not supported by device", err.Service, err.Capability)
+}
+
+// Is report the error as ErrNotSupported
+func (err *NotSupportedError) Is(target error) bool {
+	return target == ErrNotSupported
+}
+
+// LoadConfigTemplate decode a JSON ConfigTemplate
+func LoadConfigTemplate(r io.Reader) (ConfigTemplate, error) {
+	tmpl := ConfigTemplate{}
+	err := json.NewDecoder(r).Decode(&tmpl)
+	return tmpl, err
+}
+
+// templateData variables of the device used to render template text
+func (dev *Device) templateData(vars map[string]string) TemplateData {
+	return TemplateData{
+		Name:  dev.Params.Name,
+		IP:    dev.Params.Ipddr,
+		MAC:   dev.Params.MAC,
+		Model: dev.Params.Model,
+		UUID:  dev.Params.Uuid,
+		Vars:  vars,
+	}
+}
+
+func renderTemplate(text string, data TemplateData) (string, error) {
+	t, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.Buffer{}
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Apply the configuration template to the device. Every setting is reported
+// individually, settings the device does not support are skipped.
+func (dev *Device) Apply(ctx context.Context, tmpl ConfigTemplate) ApplyReport {
+	report := ApplyReport{Device: dev.Params.Ipddr}
+	data := dev.templateData(tmpl.Vars)
+	if tmpl.VideoEncoder != nil {
+		dev.applyVideoEncoder(ctx, *tmpl.VideoEncoder, &report)
+	}
+	if tmpl.NTP != nil {
+		report.add("ntp", dev.applyNTP(ctx, *tmpl.NTP, data))
+	}
+	if tmpl.OSD != nil {
+		report.add("osd", dev.applyOSD(ctx, *tmpl.OSD, data))
+	}
+	return report
+}
+
+// Apply the configuration template to every device of the fleet, a
+// PartialError is returned with the reports when ctx ended first. The report
+// of a device not started holds a single skipped "device" result with the
+// reason.
+func (fleet *Fleet) Apply(ctx context.Context, tmpl ConfigTemplate) ([]ApplyReport, error) {
+	reports := make([]ApplyReport, len(fleet.Devices))
+	started := make([]bool, len(fleet.Devices))
+	err := fleet.each(ctx, func(i int, dev *Device) {
+		started[i] = true
+		reports[i] = dev.Apply(ctx, tmpl)
+	})
+	for i, dev := range fleet.Devices {
+		if !started[i] {
+			reports[i] = ApplyReport{Device: dev.Params.Ipddr, Results: []ApplyResult{{
+				Setting: "device", Status: ApplySkipped, Err: fmt.Errorf("device not started: %w", ctx.Err()),
+			}}}
+		}
+	}
+	return reports, err
+}
+
+func (dev *Device) applyVideoEncoder(ctx context.Context, tmpl VideoEncoderTemplate, report *ApplyReport) {
+	if _, err := dev.getEndpoint("media"); err != nil {
+		report.add("videoEncoder", ErrNotSupported)
+		return
+	}
+	profiles := media.GetProfilesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media.GetProfiles{}, &profiles, ""); err != nil {
+		report.add("videoEncoder", err)
+		return
+	}
+	/* 多个profile可能共用同一个编码配置,只设置一次 */
+	done := make(map[onvif.ReferenceToken]bool)
+	for _, profile := range profiles.Profiles {
+		if len(tmpl.Profiles) != 0 && !containsString(tmpl.Profiles, string(profile.Token)) {
+			continue
+		}
+		config := profile.VideoEncoderConfiguration
+		if config.Token == "" || done[config.Token] {
+			continue
+		}
+		done[config.Token] = true
+		if tmpl.Encoding != "" {
+			config.Encoding = onvif.VideoEncoding(tmpl.Encoding)
+		}
+		if tmpl.Width != 0 && tmpl.Height != 0 {
+			config.Resolution.Width = xsd.Int(tmpl.Width)
+			config.Resolution.Height = xsd.Int(tmpl.Height)
+		}
+		if tmpl.Quality != 0 {
+			config.Quality = tmpl.Quality
+		}
+		if tmpl.FrameRateLimit != 0 {
+			config.RateControl.FrameRateLimit = xsd.Int(tmpl.FrameRateLimit)
+		}
+		if tmpl.EncodingInterval != 0 {
+			config.RateControl.EncodingInterval = xsd.Int(tmpl.EncodingInterval)
+		}
+		if tmpl.BitrateLimit != 0 {
+			config.RateControl.BitrateLimit = xsd.Int(tmpl.BitrateLimit)
+		}
+		/* GOP长度仅适用于H.264,media服务无法设置H.265 */
+		if tmpl.GovLength != 0 && strings.EqualFold(string(config.Encoding), "H264") {
+			config.H264.GovLength = xsd.Int(tmpl.GovLength)
+		} else if tmpl.GovLength != 0 {
+			report.add("videoEncoder/"+string(config.Token)+"/govLength",
+				&NotSupportedError{Service: "media", Capability: "GovLength of " + string(config.Encoding)})
+		}
+		err := dev.CallMethodInterfaceContext(ctx, media.SetVideoEncoderConfiguration{Configuration: config, ForcePersistence: true},
+			&media.SetVideoEncoderConfigurationResponse{}, "")
+		report.add("videoEncoder/"+string(config.Token), err)
+	}
+}
+
+func (dev *Device) applyNTP(ctx context.Context, tmpl NTPTemplate, data TemplateData) error {
+	request := device.SetNTP{FromDHCP: xsd.Boolean(tmpl.FromDHCP)}
+	if !tmpl.FromDHCP {
+		server, err := renderTemplate(tmpl.Server, data)
+		if err != nil {
+			return err
+		}
+		request.NTPManual = networkHost(server)
+	}
+	return dev.CallMethodInterfaceContext(ctx, request, &device.SetNTPResponse{}, "")
+}
+
+func (dev *Device) applyOSD(ctx context.Context, tmpl OSDTemplate, data TemplateData) error {
+	if _, err := dev.getEndpoint("media"); err != nil {
+		return ErrNotSupported
+	}
+	if capabilities, _ := dev.ServiceCapabilities(ctx); capabilities.Media != nil && !capabilities.Media.OSD {
+		return ErrNotSupported
+	}
+	text, err := renderTemplate(tmpl.Text, data)
+	if err != nil {
+		return err
+	}
+	profiles := media.GetProfilesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media.GetProfiles{}, &profiles, ""); err != nil {
+		return err
+	}
+	if len(profiles.Profiles) == 0 {
+		return errors.New("device has no media profile")
+	}
+	osd := onvif.OSDConfiguration{
+		DeviceEntity:                  onvif.DeviceEntity{Token: onvif.ReferenceToken(tmpl.Token)},
+		VideoSourceConfigurationToken: onvif.OSDReference(profiles.Profiles[0].VideoSourceConfiguration.Token),
+		Type:                          "Text",
+		Position:                      onvif.OSDPosConfiguration{Type: "Custom", Pos: onvif.Vector{X: tmpl.X, Y: tmpl.Y}},
+		TextString:                    onvif.OSDTextConfiguration{Type: "Plain", PlainText: xsd.String(text)},
+	}
+	if tmpl.Token != "" {
+		return dev.CallMethodInterfaceContext(ctx, media.SetOSD{OSD: osd}, &media.SetOSDResponse{}, "")
+	}
+	return dev.CallMethodInterfaceContext(ctx, media.CreateOSD{OSD: osd}, &media.CreateOSDResponse{}, "")
+}
+
+// networkHost build an onvif NetworkHost from an IPv4, IPv6 or DNS name
+func networkHost(host string) onvif.NetworkHost {
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return onvif.NetworkHost{Type: "DNS", DNSname: onvif.DNSName(host)}
+	case ip.To4() != nil:
+		return onvif.NetworkHost{Type: "IPv4", IPv4Address: onvif.IPv4Address(host)}
+	default:
+		return onvif.NetworkHost{Type: "IPv6", IPv6Address: onvif.IPv6Address(host)}
+	}
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/soap"
+)
+
+/* 按操作名应答的设备: answers为各操作的Body内容,以<s:Fault开头时以500应答,未列出的操作应答ActionNotSupported */
+type scriptedDevice struct {
+	mutex    sync.Mutex
+	answers  map[string]string
+	requests []string
+}
+
+func (fake *scriptedDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := ioutil.ReadAll(r.Body)
+	request := string(data)
+	fake.mutex.Lock()
+	fake.requests = append(fake.requests, r.URL.Path+" "+request)
+	answer, ok := fake.answers[soap.Operation(request)]
+	fake.mutex.Unlock()
+	if !ok {
+		answer = `<s:Fault><s:Code><s:Value>s:Receiver</s:Value><s:Subcode><s:Value>ter:ActionNotSupported</s:Value></s:Subcode></s:Code>` +
+			`<s:Reason><s:Text>action not supported</s:Text></s:Reason></s:Fault>`
+	}
+	if strings.HasPrefix(answer, "<s:Fault") {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	envelope := `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"`
+	for prefix, namespace := range Xlmns {
+		envelope += ` xmlns:` + prefix + `="` + namespace + `"`
+	}
+	w.Write([]byte(envelope + `><s:Body>` + answer + `</s:Body></s:Envelope>`))
+}
+
+// set the answer of the operation
+func (fake *scriptedDevice) set(operation, answer string) {
+	fake.mutex.Lock()
+	fake.answers[operation] = answer
+	fake.mutex.Unlock()
+}
+
+// sent return the requests of the operation, prefixed with their path
+func (fake *scriptedDevice) sent(operation string) []string {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	var requests []string
+	for _, request := range fake.requests {
+		if soap.Operation(request) == operation {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// newScriptedDevice return a device serving the services at
+// /onvif/<service> of a scriptedDevice, without reading GetServices
+func newScriptedDevice(t *testing.T, answers map[string]string, services ...string) (*scriptedDevice, *Device) {
+	fake := &scriptedDevice{answers: answers}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://")})
+	for _, service := range append([]string{ServiceDevice}, services...) {
+		dev.endpoints[service] = server.URL + "/onvif/" + service
+	}
+	dev.capabilities.servicesLoaded = true
+	return fake, dev
+}
+
+const bulkProfiles = `<trt:GetProfilesResponse>` +
+	`<trt:Profiles token="main"><tt:Name>main</tt:Name><tt:VideoEncoderConfiguration token="encoder_1"><tt:Name>encoder_1</tt:Name><tt:Encoding>H264</tt:Encoding></tt:VideoEncoderConfiguration></trt:Profiles>` +
+	`<trt:Profiles token="copy"><tt:Name>copy</tt:Name><tt:VideoEncoderConfiguration token="encoder_1"><tt:Name>encoder_1</tt:Name><tt:Encoding>H264</tt:Encoding></tt:VideoEncoderConfiguration></trt:Profiles>` +
+	`<trt:Profiles token="sub"><tt:Name>sub</tt:Name><tt:VideoEncoderConfiguration token="encoder_2"><tt:Name>encoder_2</tt:Name><tt:Encoding>H265</tt:Encoding></tt:VideoEncoderConfiguration></trt:Profiles>` +
+	`</trt:GetProfilesResponse>`
+
+func TestApply(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetProfiles":                  bulkProfiles,
+		"SetVideoEncoderConfiguration": `<trt:SetVideoEncoderConfigurationResponse/>`,
+		"SetNTP":                       `<tds:SetNTPResponse/>`,
+	}, ServiceMedia)
+	dev.Params.Name = "gate"
+	tmpl, err := LoadConfigTemplate(strings.NewReader(`{"videoEncoder": {"bitrateLimit": 2048, "govLength": 50}, "ntp": {"server": "{{.Vars.ntp}}"}, "vars": {"ntp": "10.1.1.1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := dev.Apply(context.Background(), tmpl)
+	statuses := map[string]ApplyStatus{}
+	for _, result := range report.Results {
+		statuses[result.Setting] = result.Status
+	}
+	/* 共用的编码配置只设置一次,H.265不设置GOP长度 */
+	want := map[string]ApplyStatus{
+		"videoEncoder/encoder_1":           ApplyOK,
+		"videoEncoder/encoder_2/govLength": ApplySkipped,
+		"videoEncoder/encoder_2":           ApplyOK,
+		"ntp":                              ApplyOK,
+	}
+	if len(statuses) != len(want) || report.Failed() {
+		t.Fatalf("results %+v", report.Results)
+	}
+	for setting, status := range want {
+		if statuses[setting] != status {
+			t.Fatalf("%s %s, want %s", setting, statuses[setting], status)
+		}
+	}
+	encoders := fake.sent("SetVideoEncoderConfiguration")
+	if len(encoders) != 2 || !strings.Contains(encoders[0], "<H264><GovLength>50</GovLength>") || !strings.Contains(encoders[0], ">2048<") {
+		t.Fatalf("encoder requests %q", encoders)
+	}
+	if ntp := fake.sent("SetNTP"); len(ntp) != 1 || !strings.Contains(ntp[0], "10.1.1.1") || !strings.Contains(ntp[0], "IPv4") {
+		t.Fatalf("ntp requests %q", ntp)
+	}
+}
+
+func TestApplyUnsupported(t *testing.T) {
+	/* 没有media服务时跳过编码与OSD设置 */
+	_, dev := newScriptedDevice(t, map[string]string{})
+	report := dev.Apply(context.Background(), ConfigTemplate{VideoEncoder: &VideoEncoderTemplate{Quality: 4}, OSD: &OSDTemplate{Text: "{{.Name}}"}})
+	if len(report.Results) != 2 || report.Failed() {
+		t.Fatalf("results %+v", report.Results)
+	}
+	for _, result := range report.Results {
+		if result.Status != ApplySkipped || !errors.Is(result.Err, ErrNotSupported) {
+			t.Fatalf("result %+v", result)
+		}
+	}
+	/* 模板中缺失的变量是错误 */
+	if _, err := renderTemplate("{{.Vars.site}}", TemplateData{Vars: map[string]string{}}); err == nil {
+		t.Fatal("missing variable rendered")
+	}
+}
+
+func TestFleetApplyNotStarted(t *testing.T) {
+	_, dev := newScriptedDevice(t, map[string]string{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reports, err := (&Fleet{Devices: []*Device{dev}}).Apply(ctx, ConfigTemplate{})
+	if err == nil || len(reports) != 1 || len(reports[0].Results) != 1 || reports[0].Results[0].Status != ApplySkipped || !errors.Is(reports[0].Results[0].Err, context.Canceled) {
+		t.Fatalf("reports %+v, %v", reports, err)
+	}
+}
@@ -0,0 +1,138 @@
+package onvif
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// CallInfo metadata of one SOAP call to a device
+type CallInfo struct {
+	// Operation name of the method type, e.g. GetProfiles
+	Operation string
+	Endpoint  string
+	// RequestID request ID sent with the call, see WithRequestID
+	RequestID string
+	// StatusCode and Header of the HTTP response, 0 and nil when the device
+	// did not answer
+	StatusCode int
+	Header     http.Header
+	// Duration from the sending of the request to the reading of the whole
+	// response
+	Duration time.Duration
+	// Retries requests sent again for the call, by a retrying transport of
+	// DeviceParams.HttpClient, by redirects or to another address. The
+	// request answering an authentication challenge and the request sent
+	// uncompressed to a device refusing compression are not retries.
+	Retries int
+	// Err error of the call, nil on success
+	Err error
+}
+
+type callObserverKey struct{}
+
+// WithCallObserver return a context reporting to observe the metadata of
+// every call made with it, once the call returns. observe may be called
+// concurrently by the requests a method sends in parallel.
+//
+//	ctx := onvif.WithCallObserver(ctx, func(call onvif.CallInfo) {
+//		if call.Duration > time.Second {
+//			log.Printf("%s slow %s: %s", call.Endpoint, call.Operation, call.Duration)
+//		}
+//	})
+func WithCallObserver(ctx context.Context, observe func(CallInfo)) context.Context {
+	return context.WithValue(ctx, callObserverKey{}, observe)
+}
+
+// WithCallInfo return a context storing into info the metadata of the last
+// call made with it, to be read once the call returned
+//
+//	info := onvif.CallInfo{}
+//	err := dev.CallMethodInterfaceContext(onvif.WithCallInfo(ctx, &info), request, &response, "")
+//	log.Println(info.Header.Get("Server"), info.Duration)
+func WithCallInfo(ctx context.Context, info *CallInfo) context.Context {
+	var mutex sync.Mutex
+	return WithCallObserver(ctx, func(call CallInfo) {
+		mutex.Lock()
+		*info = call
+		mutex.Unlock()
+	})
+}
+
+// callRecorder collect the metadata of a call for the observer of its
+// context, nil when the context has none
+type callRecorder struct {
+	info    CallInfo
+	start   time.Time
+	observe func(CallInfo)
+
+	mutex   sync.Mutex
+	retries int
+	// written a request was written; authorized and gzipped headers of the
+	// request being written, previous ones of the last request written
+	written                     bool
+	authorized, gzipped         bool
+	prevAuthorized, prevGzipped bool
+}
+
+func newCallRecorder(ctx context.Context, operation, endpoint string) *callRecorder {
+	observe, _ := ctx.Value(callObserverKey{}).(func(CallInfo))
+	if observe == nil {
+		return nil
+	}
+	info := CallInfo{Operation: operation, Endpoint: endpoint, RequestID: RequestID(ctx)}
+	return &callRecorder{info: info, start: time.Now(), observe: observe}
+}
+
+// trace return ctx counting the requests written, to report the retries
+func (recorder *callRecorder) trace(ctx context.Context) context.Context {
+	if recorder == nil {
+		return ctx
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteHeaderField: func(key string, value []string) {
+			recorder.mutex.Lock()
+			switch http.CanonicalHeaderKey(key) {
+			case "Authorization":
+				recorder.authorized = true
+			case "Content-Encoding":
+				recorder.gzipped = true
+			}
+			recorder.mutex.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			recorder.mutex.Lock()
+			/* 应答认证质询或去掉压缩重发的请求不算重试 */
+			challenge := recorder.authorized && !recorder.prevAuthorized
+			uncompressed := recorder.prevGzipped && !recorder.gzipped
+			if recorder.written && !challenge && !uncompressed {
+				recorder.retries++
+			}
+			recorder.written = true
+			recorder.prevAuthorized, recorder.prevGzipped = recorder.authorized, recorder.gzipped
+			recorder.authorized, recorder.gzipped = false, false
+			recorder.mutex.Unlock()
+		},
+	})
+}
+
+func (recorder *callRecorder) response(resp *http.Response) {
+	if recorder != nil && resp != nil {
+		recorder.info.StatusCode, recorder.info.Header = resp.StatusCode, resp.Header
+	}
+}
+
+// done report the call to the observer
+func (recorder *callRecorder) done(err error) {
+	if recorder == nil {
+		return
+	}
+	recorder.info.Duration = time.Since(recorder.start)
+	recorder.info.Err = err
+	recorder.mutex.Lock()
+	recorder.info.Retries = recorder.retries
+	recorder.mutex.Unlock()
+	recorder.observe(recorder.info)
+}
@@ -0,0 +1,72 @@
+package onvif
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+/* 每个请求发送两次的传输层,如同响应丢失后重试; authorize时第二次带认证头,如同应答认证质询 */
+type retryingTransport struct {
+	authorize bool
+}
+
+func (transport retryingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	first, err := http.DefaultTransport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	ioutil.ReadAll(first.Body)
+	first.Body.Close()
+	again := request.Clone(request.Context())
+	if again.Body, err = request.GetBody(); err != nil {
+		return nil, err
+	}
+	if transport.authorize {
+		again.Header.Set("Authorization", "Digest response=\"0\"")
+	}
+	return http.DefaultTransport.RoundTrip(again)
+}
+
+func TestCallInfo(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetSystemDateAndTime": `<tds:GetSystemDateAndTimeResponse/>`,
+	})
+	ctx := WithRequestID(context.Background(), "request-1")
+	info := CallInfo{}
+	if err := dev.CallMethodInterfaceContext(WithCallInfo(ctx, &info), device.GetSystemDateAndTime{}, &device.GetSystemDateAndTimeResponse{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if info.Operation != "GetSystemDateAndTime" || info.Endpoint != dev.endpoints[ServiceDevice] || info.RequestID != "request-1" || info.StatusCode != http.StatusOK ||
+		info.Header.Get("Content-Type") == "" || info.Duration <= 0 || info.Retries != 0 || info.Err != nil {
+		t.Fatalf("info %+v", info)
+	}
+
+	/* fault同样报告状态码与错误 */
+	var calls []CallInfo
+	observed := WithCallObserver(ctx, func(call CallInfo) { calls = append(calls, call) })
+	if err := dev.CallMethodInterfaceContext(observed, device.GetDeviceInformation{}, &device.GetDeviceInformationResponse{}, ""); err == nil {
+		t.Fatal("fault not returned")
+	}
+	if len(calls) != 1 || calls[0].StatusCode != http.StatusInternalServerError || calls[0].Err == nil {
+		t.Fatalf("calls %+v", calls)
+	}
+
+	dev.httpClient = &http.Client{Transport: retryingTransport{}}
+	if err := dev.CallMethodInterfaceContext(observed, device.GetSystemDateAndTime{}, &device.GetSystemDateAndTimeResponse{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[1].Retries != 1 || len(fake.sent("GetSystemDateAndTime")) != 3 {
+		t.Fatalf("calls %+v", calls)
+	}
+	dev.httpClient = &http.Client{Transport: retryingTransport{authorize: true}}
+	if err := dev.CallMethodInterfaceContext(observed, device.GetSystemDateAndTime{}, &device.GetSystemDateAndTimeResponse{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 3 || calls[2].Retries != 0 {
+		t.Fatalf("calls %+v", calls)
+	}
+}
@@ -0,0 +1,206 @@
+package onvif
+
+import (
+	"context"
+	"sync"
+
+	"github.com/PolarisM78/go-onvif/types/analytics"
+	"github.com/PolarisM78/go-onvif/types/device"
+	event "github.com/PolarisM78/go-onvif/types/events"
+	"github.com/PolarisM78/go-onvif/types/imaging"
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/types/ptz"
+	"github.com/PolarisM78/go-onvif/types/recording"
+	"github.com/PolarisM78/go-onvif/types/replay"
+	"github.com/PolarisM78/go-onvif/types/search"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ServiceCapabilities capabilities of the services of the device, gating the
+// optional features; a service the device does not offer is nil
+type ServiceCapabilities struct {
+	Device    *device.DeviceServiceCapabilities
+	Media     *media.Capabilities
+	Events    *event.Capabilities
+	PTZ       *ptz.Capabilities
+	Imaging   *imaging.Capabilities
+	Analytics *analytics.Capabilities
+	Recording *recording.Capabilities
+	Search    *search.Capabilities
+	Replay    *replay.Capabilities
+}
+
+// has report whether the capabilities of the service of key were read
+func (capabilities *ServiceCapabilities) has(key string) bool {
+	switch key {
+	case ServiceDevice:
+		return capabilities.Device != nil
+	case ServiceMedia:
+		return capabilities.Media != nil
+	case ServiceEvents:
+		return capabilities.Events != nil
+	case ServicePTZ:
+		return capabilities.PTZ != nil
+	case ServiceImaging:
+		return capabilities.Imaging != nil
+	case ServiceAnalytics:
+		return capabilities.Analytics != nil
+	case ServiceRecording:
+		return capabilities.Recording != nil
+	case ServiceSearch:
+		return capabilities.Search != nil
+	case ServiceReplay:
+		return capabilities.Replay != nil
+	}
+	return false
+}
+
+// capabilityCache service capabilities and manufacturer shared by the copies
+// of a Device
+type capabilityCache struct {
+	mutex        sync.Mutex
+	loaded       bool
+	capabilities ServiceCapabilities
+	// manufacturer reported by GetDeviceInformation, selecting the Quirks
+	manufacturerLoaded bool
+	manufacturer       string
+	// engine event engine pulling the events of the device, reused by
+	// WaitForEvent; guarded by its own mutex as the other one is held during
+	// requests
+	engineMutex sync.Mutex
+	engine      *EventEngine
+	// endpointsMutex guard the endpoints and the versions of the device,
+	// updated by RefreshServices while calls read them
+	endpointsMutex sync.RWMutex
+	// servicesLoaded GetServices was requested, on the first use of a service
+	// GetCapabilities does not report or of the versions
+	servicesMutex  sync.Mutex
+	servicesLoaded bool
+	// profiles cached by CachedProfiles
+	profilesMutex  sync.Mutex
+	profilesLoaded bool
+	profiles       []onvif.Profile
+}
+
+// InvalidateCache drop the cached capabilities, manufacturer and profiles of
+// the device, read again by their next use, e.g. after the configuration of
+// the device was changed by another client. The EventEngine pulling the
+// device calls it on the configuration events of the device.
+func (dev *Device) InvalidateCache() {
+	cache := dev.capabilities
+	if cache == nil {
+		return
+	}
+	cache.mutex.Lock()
+	cache.loaded, cache.capabilities = false, ServiceCapabilities{}
+	cache.manufacturerLoaded, cache.manufacturer = false, ""
+	cache.mutex.Unlock()
+	dev.invalidateProfiles()
+}
+
+// invalidateProfiles drop the profiles cached by CachedProfiles
+func (dev *Device) invalidateProfiles() {
+	cache := dev.capabilities
+	if cache == nil {
+		return
+	}
+	cache.profilesMutex.Lock()
+	cache.profilesLoaded, cache.profiles = false, nil
+	cache.profilesMutex.Unlock()
+}
+
+// readEndpoints lock the endpoints and versions of the device for reading,
+// the returned function unlocks them
+func (dev Device) readEndpoints() func() {
+	if dev.capabilities == nil {
+		return func() {}
+	}
+	dev.capabilities.endpointsMutex.RLock()
+	return dev.capabilities.endpointsMutex.RUnlock
+}
+
+// writeEndpoints lock the endpoints and versions of the device for writing,
+// the returned function unlocks them
+func (dev Device) writeEndpoints() func() {
+	if dev.capabilities == nil {
+		return func() {}
+	}
+	dev.capabilities.endpointsMutex.Lock()
+	return dev.capabilities.endpointsMutex.Unlock
+}
+
+// ServiceCapabilities return the capabilities of the services of the device. They are fetched concurrently on the first call, or at connect
+// time with DeviceParams.EagerCapabilities, and cached afterwards. On error
+// the capabilities of the services which answered are still returned.
+func (dev *Device) ServiceCapabilities(ctx context.Context) (ServiceCapabilities, error) {
+	if dev.capabilities == nil {
+		return dev.fetchServiceCapabilities(ctx)
+	}
+	/* 持锁获取,避免并发调用重复请求 */
+	dev.capabilities.mutex.Lock()
+	defer dev.capabilities.mutex.Unlock()
+	if dev.capabilities.loaded {
+		return dev.capabilities.capabilities, nil
+	}
+	capabilities, err := dev.fetchServiceCapabilities(ctx)
+	if err == nil {
+		dev.capabilities.capabilities = capabilities
+		dev.capabilities.loaded = true
+	}
+	return capabilities, err
+}
+
+// serviceCapabilitiesCall GetServiceCapabilities request of a service, store
+// keeping the capabilities of its response
+type serviceCapabilitiesCall struct {
+	service  string
+	request  interface{}
+	response interface{}
+	store    func()
+}
+
+func (dev *Device) fetchServiceCapabilities(ctx context.Context) (ServiceCapabilities, error) {
+	capabilities := ServiceCapabilities{}
+	deviceResponse := device.GetServiceCapabilitiesResponse{}
+	mediaResponse := media.GetServiceCapabilitiesResponse{}
+	eventResponse := event.GetServiceCapabilitiesResponse{}
+	ptzResponse := ptz.GetServiceCapabilitiesResponse{}
+	imagingResponse := imaging.GetServiceCapabilitiesResponse{}
+	analyticsResponse := analytics.GetServiceCapabilitiesResponse{}
+	recordingResponse := recording.GetServiceCapabilitiesResponse{}
+	searchResponse := search.GetServiceCapabilitiesResponse{}
+	replayResponse := replay.GetServiceCapabilitiesResponse{}
+	calls := []serviceCapabilitiesCall{
+		{"device", device.GetServiceCapabilities{}, &deviceResponse, func() { capabilities.Device = &deviceResponse.Capabilities }},
+		{"media", media.GetServiceCapabilities{}, &mediaResponse, func() { capabilities.Media = &mediaResponse.Capabilities }},
+		{"events", event.GetServiceCapabilities{}, &eventResponse, func() { capabilities.Events = &eventResponse.Capabilities }},
+		{"ptz", ptz.GetServiceCapabilities{}, &ptzResponse, func() { capabilities.PTZ = &ptzResponse.Capabilities }},
+		{"imaging", imaging.GetServiceCapabilities{}, &imagingResponse, func() { capabilities.Imaging = &imagingResponse.Capabilities }},
+		{"analytics", analytics.GetServiceCapabilities{}, &analyticsResponse, func() { capabilities.Analytics = &analyticsResponse.Capabilities }},
+		{"recording", recording.GetServiceCapabilities{}, &recordingResponse, func() { capabilities.Recording = &recordingResponse.Capabilities }},
+		{"search", search.GetServiceCapabilities{}, &searchResponse, func() { capabilities.Search = &searchResponse.Capabilities }},
+		{"replay", replay.GetServiceCapabilities{}, &replayResponse, func() { capabilities.Replay = &replayResponse.Capabilities }},
+	}
+	errs := make([]error, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		/* 设备未提供的服务不请求,能力保持为nil */
+		if _, err := dev.getEndpoint(call.service); err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, call serviceCapabilitiesCall) {
+			defer wg.Done()
+			if errs[i] = dev.CallMethodInterfaceContext(ctx, call.request, call.response, ""); errs[i] == nil {
+				call.store()
+			}
+		}(i, call)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return capabilities, err
+		}
+	}
+	return capabilities, nil
+}
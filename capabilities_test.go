@@ -0,0 +1,76 @@
+package onvif
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestServiceCapabilitiesCache(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tds:GetServiceCapabilitiesResponse><tds:Capabilities/></tds:GetServiceCapabilitiesResponse>`,
+	}, ServiceMedia, ServiceEvents, ServicePTZ)
+	ctx := context.Background()
+	/* 并发的首次调用只请求一次 */
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := dev.ServiceCapabilities(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	capabilities, err := dev.ServiceCapabilities(ctx)
+	if err != nil || capabilities.Device == nil || capabilities.Media == nil || capabilities.Events == nil || capabilities.PTZ == nil || capabilities.Imaging != nil {
+		t.Fatalf("capabilities %+v, %v", capabilities, err)
+	}
+	/* 每个服务发送到自己的地址 */
+	var paths []string
+	for _, request := range fake.sent("GetServiceCapabilities") {
+		paths = append(paths, strings.Fields(request)[0])
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, " ") != "/onvif/device /onvif/events /onvif/media /onvif/ptz" {
+		t.Fatalf("requests to %v", paths)
+	}
+	dev.InvalidateCache()
+	if _, err := dev.ServiceCapabilities(ctx); err != nil || len(fake.sent("GetServiceCapabilities")) != 8 {
+		t.Fatalf("%d requests after InvalidateCache, %v", len(fake.sent("GetServiceCapabilities")), err)
+	}
+}
+
+func TestServiceCapabilitiesOfEveryService(t *testing.T) {
+	/* 各服务的能力以同一元素应答,按属性名解析 */
+	_, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tds:GetServiceCapabilitiesResponse><tds:Capabilities RuleSupport="true" MaxRecordingJobs="4" MetadataSearch="true" ReversePlayback="true" SessionTimeoutRange="10 60"/>` +
+			`</tds:GetServiceCapabilitiesResponse>`,
+	}, ServiceMedia, ServiceEvents, ServicePTZ, ServiceImaging, ServiceAnalytics, ServiceRecording, ServiceSearch, ServiceReplay)
+	capabilities, err := dev.ServiceCapabilities(context.Background())
+	if err != nil || capabilities.Device == nil || capabilities.Media == nil || capabilities.Events == nil || capabilities.PTZ == nil || capabilities.Imaging == nil {
+		t.Fatalf("capabilities %+v, %v", capabilities, err)
+	}
+	if capabilities.Analytics == nil || !capabilities.Analytics.RuleSupport || capabilities.Recording == nil || capabilities.Recording.MaxRecordingJobs != 4 ||
+		capabilities.Search == nil || !capabilities.Search.MetadataSearch || capabilities.Replay == nil || capabilities.Replay.SessionTimeoutRange != "10 60" {
+		t.Fatalf("capabilities %+v", capabilities)
+	}
+
+	/* 一个服务失败时返回其余服务的能力,且不缓存 */
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	dev.endpoints[ServiceReplay] = closed.URL
+	dev.InvalidateCache()
+	capabilities, err = dev.ServiceCapabilities(context.Background())
+	if err == nil || capabilities.Replay != nil || capabilities.Recording == nil || capabilities.Device == nil {
+		t.Fatalf("capabilities %+v, %v", capabilities, err)
+	}
+	if dev.capabilities.loaded {
+		t.Fatal("partial capabilities cached")
+	}
+}
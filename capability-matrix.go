@@ -0,0 +1,215 @@
+package onvif
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/types/imaging"
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/types/media2"
+	"github.com/PolarisM78/go-onvif/types/ptz"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// CapabilityMatrix features of a device, for the feature gating of a VMS. A
+// feature is false when the device does not offer it or did not answer the
+// request telling it, Errors then holds the failure per service.
+type CapabilityMatrix struct {
+	Device string `json:"device"`
+	// Profile media profile the PTZ, imaging and encoder features were read
+	// from, the first one of the device
+	Profile string            `json:"profile,omitempty"`
+	PTZ     PTZFeatures       `json:"ptz"`
+	Imaging ImagingFeatures   `json:"imaging"`
+	Media   MediaFeatures     `json:"media"`
+	Events  EventFeatures     `json:"events"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// PTZFeatures PTZ features of the node of the profile
+type PTZFeatures struct {
+	Supported      bool `json:"supported"`
+	AbsoluteMove   bool `json:"absoluteMove"`
+	RelativeMove   bool `json:"relativeMove"`
+	ContinuousMove bool `json:"continuousMove"`
+	Home           bool `json:"home"`
+	// MaxPresets maximum number of presets of the node, 0 without presets
+	MaxPresets int `json:"maxPresets"`
+}
+
+// ImagingFeatures imaging features of the video source of the profile
+type ImagingFeatures struct {
+	Supported bool `json:"supported"`
+	// FocusControl the focus can be moved, absolutely, relatively or
+	// continuously
+	FocusControl       bool `json:"focusControl"`
+	AutoFocus          bool `json:"autoFocus"`
+	WDR                bool `json:"wdr"`
+	IrCutFilter        bool `json:"irCutFilter"`
+	ImageStabilization bool `json:"imageStabilization"`
+	Presets            bool `json:"presets"`
+}
+
+// MediaFeatures media features of the device
+type MediaFeatures struct {
+	Snapshot bool `json:"snapshot"`
+	OSD      bool `json:"osd"`
+	Rotation bool `json:"rotation"`
+	JPEG     bool `json:"jpeg"`
+	H264     bool `json:"h264"`
+	// H265 an encoder of the device offers H.265, read from the media2
+	// service, the only one able to configure H.265 encoders
+	H265         bool `json:"h265"`
+	RTPMulticast bool `json:"rtpMulticast"`
+	RTSPOverTCP  bool `json:"rtspOverTcp"`
+	MaxProfiles  int  `json:"maxProfiles"`
+}
+
+// EventFeatures features of the event service
+type EventFeatures struct {
+	Supported     bool `json:"supported"`
+	PullPoint     bool `json:"pullPoint"`
+	MaxPullPoints int  `json:"maxPullPoints"`
+}
+
+func (matrix *CapabilityMatrix) fail(service string, err error) {
+	if matrix.Errors == nil {
+		matrix.Errors = make(map[string]string)
+	}
+	matrix.Errors[service] = err.Error()
+}
+
+// CapabilityMatrix collect the features of the device, a failing service is
+// recorded in the Errors of the matrix instead of failing the call
+func (dev *Device) CapabilityMatrix(ctx context.Context) CapabilityMatrix {
+	matrix := CapabilityMatrix{Device: dev.Params.Ipddr}
+	capabilities, err := dev.ServiceCapabilities(ctx)
+	if err != nil {
+		matrix.fail("capabilities", err)
+	}
+	if c := capabilities.Media; c != nil {
+		matrix.Media.Snapshot = c.SnapshotUri
+		matrix.Media.OSD = c.OSD
+		matrix.Media.Rotation = c.Rotation
+		matrix.Media.RTPMulticast = c.StreamingCapabilities.RTPMulticast
+		matrix.Media.RTSPOverTCP = c.StreamingCapabilities.RTP_RTSP_TCP
+		matrix.Media.MaxProfiles = c.ProfileCapabilities.MaximumNumberOfProfiles
+	}
+	if c := capabilities.Events; c != nil {
+		matrix.Events.Supported = true
+		matrix.Events.PullPoint = bool(c.WSPullPointSupport)
+		matrix.Events.MaxPullPoints = int(c.MaxPullPoints)
+	}
+	if _, err := dev.getEndpoint("media2"); err == nil {
+		dev.media2Features(ctx, &matrix)
+	}
+	if _, err := dev.getEndpoint("media"); err != nil {
+		return matrix
+	}
+
+	/* 以第一个profile读取编码、PTZ及图像能力 */
+	token, err := firstProfile(ctx, dev)
+	if err != nil {
+		matrix.fail("media", err)
+		return matrix
+	}
+	matrix.Profile = string(token)
+	profile := media.GetProfileResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media.GetProfile{ProfileToken: token}, &profile, ""); err != nil {
+		matrix.fail("media", err)
+		return matrix
+	}
+	options := media.GetVideoEncoderConfigurationOptionsResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media.GetVideoEncoderConfigurationOptions{ProfileToken: token}, &options, ""); err != nil {
+		matrix.fail("media", err)
+	} else {
+		matrix.Media.JPEG = options.Options.JPEG.ResolutionsAvailable.Width > 0
+		matrix.Media.H264 = options.Options.H264.ResolutionsAvailable.Width > 0
+	}
+	if node := profile.Profile.PTZConfiguration.NodeToken; node != "" {
+		if _, err := dev.getEndpoint("ptz"); err == nil {
+			dev.ptzFeatures(ctx, node, &matrix)
+		}
+	}
+	if source := profile.Profile.VideoSourceConfiguration.SourceToken; source != "" {
+		if _, err := dev.getEndpoint("imaging"); err == nil {
+			dev.imagingFeatures(ctx, source, &matrix)
+		}
+	}
+	return matrix
+}
+
+// media2Features read the encodings of the encoders from media2, every
+// encoder being described without a profile or configuration token
+func (dev *Device) media2Features(ctx context.Context, matrix *CapabilityMatrix) {
+	response := media2.GetVideoEncoderConfigurationOptionsResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media2.GetVideoEncoderConfigurationOptions{}, &response, ""); err != nil {
+		matrix.fail("media2", err)
+		return
+	}
+	for _, options := range response.Options {
+		/* 部分设备返回H.265而非MIME子类型名H265 */
+		if encoding := strings.ToUpper(strings.Replace(options.Encoding, ".", "", -1)); encoding == "H265" || encoding == "HEVC" {
+			matrix.Media.H265 = true
+		}
+	}
+}
+
+func (dev *Device) ptzFeatures(ctx context.Context, node onvif.ReferenceToken, matrix *CapabilityMatrix) {
+	response := ptz.GetNodeResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, ptz.GetNode{NodeToken: node}, &response, ""); err != nil {
+		matrix.fail("ptz", err)
+		return
+	}
+	spaces := response.PTZNode.SupportedPTZSpaces
+	matrix.PTZ.Supported = true
+	matrix.PTZ.AbsoluteMove = spaces.AbsolutePanTiltPositionSpace.URI != "" || spaces.AbsoluteZoomPositionSpace.URI != ""
+	matrix.PTZ.RelativeMove = spaces.RelativePanTiltTranslationSpace.URI != "" || spaces.RelativeZoomTranslationSpace.URI != ""
+	matrix.PTZ.ContinuousMove = spaces.ContinuousPanTiltVelocitySpace.URI != "" || spaces.ContinuousZoomVelocitySpace.URI != ""
+	matrix.PTZ.Home = bool(response.PTZNode.HomeSupported)
+	matrix.PTZ.MaxPresets = response.PTZNode.MaximumNumberOfPresets
+}
+
+func (dev *Device) imagingFeatures(ctx context.Context, source onvif.ReferenceToken, matrix *CapabilityMatrix) {
+	capabilities, err := dev.ImagingCapabilities(ctx)
+	if err != nil {
+		matrix.fail("imaging", err)
+		return
+	}
+	matrix.Imaging.Supported = true
+	matrix.Imaging.ImageStabilization = capabilities.ImageStabilization
+	matrix.Imaging.Presets = capabilities.Presets
+	options := imaging.GetOptionsResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, imaging.GetOptions{VideoSourceToken: source}, &options, ""); err != nil {
+		matrix.fail("imaging", err)
+	} else {
+		matrix.Imaging.AutoFocus = len(options.ImagingOptions.Focus.AutoFocusModes) > 0
+		matrix.Imaging.WDR = len(options.ImagingOptions.WideDynamicRange.Mode) > 0
+		matrix.Imaging.IrCutFilter = len(options.ImagingOptions.IrCutFilterModes) > 0
+	}
+	/* 不支持对焦的设备对GetMoveOptions返回错误或空选项 */
+	move := imaging.GetMoveOptionsResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, imaging.GetMoveOptions{VideoSourceToken: source}, &move, ""); err == nil {
+		options := move.MoveOptions
+		matrix.Imaging.FocusControl = options.Absolute != nil || options.Relative != nil || options.Continuous != nil
+	}
+}
+
+// CapabilityMatrix collect the features of every device of the fleet, a
+// PartialError is returned with the matrices when ctx ended first
+func (fleet *Fleet) CapabilityMatrix(ctx context.Context) ([]CapabilityMatrix, error) {
+	matrices := make([]CapabilityMatrix, len(fleet.Devices))
+	err := fleet.each(ctx, func(i int, dev *Device) {
+		matrices[i] = dev.CapabilityMatrix(ctx)
+	})
+	return matrices, err
+}
+
+// WriteCapabilityMatrixJSON write the matrices as an indented JSON array
+func WriteCapabilityMatrixJSON(w io.Writer, matrices []CapabilityMatrix) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(matrices)
+}
@@ -0,0 +1,50 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestCapabilityMatrix(t *testing.T) {
+	/* 各服务的能力以同一元素应答,按属性名解析 */
+	_, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tds:GetServiceCapabilitiesResponse><tds:Capabilities SnapshotUri="true" OSD="true" WSPullPointSupport="true" MaxPullPoints="2" Presets="true">` +
+			`<trt:ProfileCapabilities MaximumNumberOfProfiles="8"/><trt:StreamingCapabilities RTP_RTSP_TCP="true"/></tds:Capabilities></tds:GetServiceCapabilitiesResponse>`,
+		"GetProfiles": `<trt:GetProfilesResponse><trt:Profiles token="main"><tt:Name>main</tt:Name></trt:Profiles></trt:GetProfilesResponse>`,
+		"GetProfile": `<trt:GetProfileResponse><trt:Profile token="main"><tt:Name>main</tt:Name>` +
+			`<tt:VideoSourceConfiguration token="source"><tt:SourceToken>vs0</tt:SourceToken></tt:VideoSourceConfiguration>` +
+			`<tt:PTZConfiguration token="ptz"><tt:NodeToken>node</tt:NodeToken></tt:PTZConfiguration></trt:Profile></trt:GetProfileResponse>`,
+		/* media应答JPEG与H264的选项,media2应答编码名 */
+		"GetVideoEncoderConfigurationOptions": `<trt:GetVideoEncoderConfigurationOptionsResponse><trt:Options><tt:Encoding>H.265</tt:Encoding>` +
+			`<tt:H264><tt:ResolutionsAvailable><tt:Width>1920</tt:Width><tt:Height>1080</tt:Height></tt:ResolutionsAvailable></tt:H264></trt:Options></trt:GetVideoEncoderConfigurationOptionsResponse>`,
+		"GetNode": `<tptz:GetNodeResponse><tptz:PTZNode token="node"><tt:SupportedPTZSpaces><tt:RelativePanTiltTranslationSpace><tt:URI>http://www.onvif.org/ver10/tptz/PanTiltSpaces/TranslationGenericSpace</tt:URI></tt:RelativePanTiltTranslationSpace></tt:SupportedPTZSpaces>` +
+			`<tt:MaximumNumberOfPresets>16</tt:MaximumNumberOfPresets><tt:HomeSupported>true</tt:HomeSupported></tptz:PTZNode></tptz:GetNodeResponse>`,
+		"GetMoveOptions": `<timg:GetMoveOptionsResponse><timg:MoveOptions><tt:Continuous><tt:Speed><tt:Min>0</tt:Min><tt:Max>1</tt:Max></tt:Speed></tt:Continuous></timg:MoveOptions></timg:GetMoveOptionsResponse>`,
+	}, ServiceMedia, ServiceMedia2, ServiceEvents, ServicePTZ, ServiceImaging)
+	matrix := dev.CapabilityMatrix(context.Background())
+	if matrix.Profile != "main" || matrix.Media != (MediaFeatures{Snapshot: true, OSD: true, H264: true, H265: true, RTSPOverTCP: true, MaxProfiles: 8}) ||
+		matrix.Events != (EventFeatures{Supported: true, PullPoint: true, MaxPullPoints: 2}) {
+		t.Fatalf("matrix %+v", matrix)
+	}
+	if matrix.PTZ != (PTZFeatures{Supported: true, RelativeMove: true, Home: true, MaxPresets: 16}) {
+		t.Fatalf("ptz %+v", matrix.PTZ)
+	}
+	/* GetOptions失败记录在Errors中,其余图像能力照常读取 */
+	if matrix.Imaging != (ImagingFeatures{Supported: true, FocusControl: true, Presets: true}) || len(matrix.Errors) != 1 || matrix.Errors["imaging"] == "" {
+		t.Fatalf("imaging %+v, errors %v", matrix.Imaging, matrix.Errors)
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteCapabilityMatrixJSON(&buffer, []CapabilityMatrix{matrix}); err != nil {
+		t.Fatal(err)
+	}
+	var document []map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &document); err != nil || len(document) != 1 {
+		t.Fatalf("document %s, %v", buffer.String(), err)
+	}
+	if media, ok := document[0]["media"].(map[string]interface{}); !ok || media["h265"] != true || media["rtspOverTcp"] != true {
+		t.Fatalf("document %s", buffer.String())
+	}
+}
@@ -0,0 +1,267 @@
+package onvif
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Topics of the security relevant events reported by CEFSink
+const (
+	TopicTamper               = "RuleEngine/TamperDetector/Tamper"
+	TopicGlobalSceneChange    = "VideoSource/GlobalSceneChange/ImagingService"
+	TopicDoorAlarm            = "Door/State/DoorAlarm"
+	TopicDoorTamper           = "Door/State/DoorTamper"
+	TopicConfigurationChanged = "Media/ConfigurationChanged"
+	TopicProfileChanged       = "Media/ProfileChanged"
+)
+
+// SecurityEvent security relevant event as reported to a SIEM
+type SecurityEvent struct {
+	Device string
+	// SignatureID stable identifier of the kind of event, e.g. onvif:access-denied
+	SignatureID string
+	Name        string
+	// Severity CEF severity, 0 to 10
+	Severity int
+	Time     time.Time
+	// Extensions CEF extension fields, e.g. suser, cs1
+	Extensions map[string]string
+}
+
+// SecurityRule classify the events of a topic as security events, Classify
+// return false for the events of the topic which are not relevant, e.g. a
+// tamper alarm ending
+type SecurityRule struct {
+	Topic    string
+	Classify func(ev Event) (SecurityEvent, bool)
+}
+
+/* 属性事件在订阅建立时以Initialized发送当前状态,不作为新告警 */
+func activeState(ev Event, name string) bool {
+	if ev.Operation == "Initialized" || ev.Operation == "Deleted" {
+		return false
+	}
+	active, ok := logicalState(ev.Data[name])
+	return ok && active
+}
+
+// SecurityRules rules applied by ClassifySecurityEvent, in order
+var SecurityRules = []SecurityRule{
+	{Topic: TopicAccessDenied, Classify: func(ev Event) (SecurityEvent, bool) {
+		access, ok := ParseAccessEvent(ev)
+		if !ok || access.Granted {
+			return SecurityEvent{}, false
+		}
+		return SecurityEvent{SignatureID: "onvif:access-denied", Name: "Access denied", Severity: 5, Extensions: map[string]string{
+			"suser":    access.CredentialHolderName,
+			"reason":   access.Reason,
+			"cs1Label": "accessPoint", "cs1": access.AccessPoint,
+			"cs2Label": "credential", "cs2": access.CredentialToken,
+			"cs3Label": "card", "cs3": access.Card,
+			"cs4Label": "decision", "cs4": access.Kind,
+		}}, true
+	}},
+	{Topic: TopicDoorAlarm, Classify: func(ev Event) (SecurityEvent, bool) {
+		state := ev.Data["State"]
+		if ev.Operation == "Initialized" || state == "" || state == "Normal" {
+			return SecurityEvent{}, false
+		}
+		return SecurityEvent{SignatureID: "onvif:door-alarm", Name: "Door alarm " + state, Severity: 8, Extensions: map[string]string{
+			"cs1Label": "door", "cs1": ev.Source["DoorToken"],
+			"cs2Label": "alarm", "cs2": state,
+		}}, true
+	}},
+	{Topic: TopicDoorTamper, Classify: func(ev Event) (SecurityEvent, bool) {
+		state := ev.Data["State"]
+		if ev.Operation == "Initialized" || state != "TamperDetected" {
+			return SecurityEvent{}, false
+		}
+		return SecurityEvent{SignatureID: "onvif:door-tamper", Name: "Door tamper", Severity: 8, Extensions: map[string]string{
+			"cs1Label": "door", "cs1": ev.Source["DoorToken"],
+		}}, true
+	}},
+	{Topic: TopicTamper, Classify: func(ev Event) (SecurityEvent, bool) {
+		if !activeState(ev, "IsTamper") {
+			return SecurityEvent{}, false
+		}
+		return SecurityEvent{SignatureID: "onvif:tamper", Name: "Camera tamper", Severity: 7, Extensions: map[string]string{
+			"cs1Label": "videoSource", "cs1": ev.Source["VideoSourceConfigurationToken"],
+			"cs2Label": "rule", "cs2": ev.Source["Rule"],
+		}}, true
+	}},
+	{Topic: TopicGlobalSceneChange, Classify: func(ev Event) (SecurityEvent, bool) {
+		if !activeState(ev, "State") {
+			return SecurityEvent{}, false
+		}
+		return SecurityEvent{SignatureID: "onvif:scene-change", Name: "Global scene change", Severity: 6, Extensions: map[string]string{
+			"cs1Label": "videoSource", "cs1": ev.Source["Source"],
+		}}, true
+	}},
+	{Topic: TopicConfigurationChanged, Classify: func(ev Event) (SecurityEvent, bool) {
+		return SecurityEvent{SignatureID: "onvif:config-change", Name: "Configuration changed", Severity: 3, Extensions: map[string]string{
+			"cs1Label": "configuration", "cs1": ev.Source["ConfigurationToken"],
+			"cs2Label": "type", "cs2": ev.Source["Type"],
+		}}, true
+	}},
+	{Topic: TopicProfileChanged, Classify: func(ev Event) (SecurityEvent, bool) {
+		return SecurityEvent{SignatureID: "onvif:profile-change", Name: "Media profile changed", Severity: 3, Extensions: map[string]string{
+			"cs1Label": "profile", "cs1": ev.Source["Token"],
+		}}, true
+	}},
+}
+
+// ClassifySecurityEvent return the security event carried by ev
+func ClassifySecurityEvent(ev Event) (SecurityEvent, bool) {
+	for _, rule := range SecurityRules {
+		if _, ok := topicSuffix(ev.Topic, rule.Topic); !ok {
+			continue
+		}
+		security, ok := rule.Classify(ev)
+		if !ok {
+			return SecurityEvent{}, false
+		}
+		security.Device = ev.Device
+		security.Time = ev.Time
+		if security.Time.IsZero() {
+			security.Time = ev.ReceivedAt
+		}
+		return security, true
+	}
+	return SecurityEvent{}, false
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// CEF format the event as an ArcSight Common Event Format message
+func (security SecurityEvent) CEF(vendor, product, version string) string {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "CEF:0|%s|%s|%s|%s|%s|%d|",
+		cefHeaderEscaper.Replace(vendor), cefHeaderEscaper.Replace(product), cefHeaderEscaper.Replace(version),
+		cefHeaderEscaper.Replace(security.SignatureID), cefHeaderEscaper.Replace(security.Name), security.Severity)
+	fields := map[string]string{"dvchost": security.Device, "rt": strconv.FormatInt(security.Time.UnixNano()/int64(time.Millisecond), 10)}
+	for key, value := range security.Extensions {
+		fields[key] = value
+	}
+	keys := make([]string, 0, len(fields))
+	for key, value := range fields {
+		/* 省略空值,同时省略没有值的标签字段 */
+		if value != "" && !(strings.HasSuffix(key, "Label") && fields[strings.TrimSuffix(key, "Label")] == "") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(key + "=" + cefExtensionEscaper.Replace(fields[key]))
+	}
+	return builder.String()
+}
+
+// CEFSink send the security relevant events of an EventEngine as CEF
+// messages over syslog (RFC 5424) to a SIEM:
+//
+//	engine.Handle(onvif.NewCEFSink("udp", "siem:514").Handle)
+type CEFSink struct {
+	// Network udp or tcp, messages are newline terminated over tcp
+	Network string
+	Address string
+	// Facility syslog facility, 0 means local4 (20)
+	Facility int
+	AppName  string
+	// Vendor, Product and Version of the CEF header
+	Vendor  string
+	Product string
+	Version string
+	// OnError optional callback for the events which could not be sent
+	OnError func(Event, error)
+
+	mutex sync.Mutex
+	conn  net.Conn
+}
+
+// NewCEFSink return a sink sending to the syslog server at address
+func NewCEFSink(network, address string) *CEFSink {
+	return &CEFSink{Network: network, Address: address, AppName: "go-onvif", Vendor: "ONVIF", Product: "go-onvif", Version: "1.0"}
+}
+
+// Handle send ev when it is security relevant, to be registered with
+// EventEngine.Handle
+func (sink *CEFSink) Handle(ev Event) {
+	security, ok := ClassifySecurityEvent(ev)
+	if !ok {
+		return
+	}
+	if err := sink.Send(security); err != nil && sink.OnError != nil {
+		sink.OnError(ev, err)
+	}
+}
+
+// Send write the security event to the syslog server
+func (sink *CEFSink) Send(security SecurityEvent) error {
+	facility := sink.Facility
+	if facility == 0 {
+		facility = 20
+	}
+	severity := 6
+	switch {
+	case security.Severity >= 8:
+		severity = 2
+	case security.Severity >= 5:
+		severity = 4
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	message := fmt.Sprintf("<%d>1 %s %s %s - %s - %s", facility*8+severity, security.Time.UTC().Format(time.RFC3339Nano),
+		hostname, sink.AppName, strings.Replace(security.SignatureID, ":", "-", -1), security.CEF(sink.Vendor, sink.Product, sink.Version))
+	if sink.Network != "udp" {
+		message += "\n"
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	/* 连接断开时重连一次 */
+	for attempt := 0; ; attempt++ {
+		if sink.conn == nil {
+			conn, err := net.DialTimeout(sink.Network, sink.Address, 5*time.Second)
+			if err != nil {
+				return err
+			}
+			sink.conn = conn
+		}
+		sink.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		_, err := sink.conn.Write([]byte(message))
+		if err == nil {
+			return nil
+		}
+		sink.conn.Close()
+		sink.conn = nil
+		if attempt > 0 {
+			return err
+		}
+	}
+}
+
+// Close close the connection to the syslog server
+func (sink *CEFSink) Close() error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if sink.conn == nil {
+		return nil
+	}
+	err := sink.conn.Close()
+	sink.conn = nil
+	return err
+}
@@ -0,0 +1,71 @@
+package onvif
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifySecurityEvent(t *testing.T) {
+	at := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	denied := Event{
+		Device: "10.1.1.200",
+		Topic:  "tns1:AccessControl/Denied/Credential",
+		Time:   at,
+		Source: map[string]string{"AccessPointToken": "entrance"},
+		Data:   map[string]string{"CredentialToken": "credential_1", "CredentialHolderName": "holder=a|b", "Reason": "CredentialNotEnabled"},
+	}
+	security, ok := ClassifySecurityEvent(denied)
+	if !ok || security.SignatureID != "onvif:access-denied" || security.Device != "10.1.1.200" || !security.Time.Equal(at) {
+		t.Fatalf("security %+v, %v", security, ok)
+	}
+	/* 空字段与其标签一并省略,扩展值转义= */
+	want := `CEF:0|ONVIF|go\|onvif|1.0|onvif:access-denied|Access denied|5|cs1=entrance cs1Label=accessPoint cs2=credential_1 cs2Label=credential cs4=Credential cs4Label=decision ` +
+		`dvchost=10.1.1.200 reason=CredentialNotEnabled rt=1792144800000 suser=holder\=a|b`
+	if cef := security.CEF("ONVIF", "go|onvif", "1.0"); cef != want {
+		t.Fatalf("cef\n%s\nwant\n%s", cef, want)
+	}
+
+	for _, ev := range []Event{
+		/* 订阅建立时的当前状态和结束的告警不是安全事件 */
+		{Topic: "tns1:RuleEngine/TamperDetector/Tamper", Operation: "Initialized", Data: map[string]string{"IsTamper": "true"}},
+		{Topic: "tns1:RuleEngine/TamperDetector/Tamper", Operation: "Changed", Data: map[string]string{"IsTamper": "false"}},
+		{Topic: "tns1:AccessControl/AccessGranted/Credential"},
+		{Topic: "tns1:VideoSource/MotionAlarm", Operation: "Changed", Data: map[string]string{"State": "true"}},
+	} {
+		if security, ok := ClassifySecurityEvent(ev); ok {
+			t.Fatalf("%s classified as %+v", ev.Topic, security)
+		}
+	}
+}
+
+func TestCEFSink(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	sink := NewCEFSink("udp", listener.LocalAddr().String())
+	defer sink.Close()
+	sink.Handle(Event{Topic: "tns1:VideoSource/MotionAlarm"})
+	sink.Handle(Event{
+		Device:    "10.1.1.200",
+		Topic:     "tns1:Door/State/DoorAlarm",
+		Operation: "Changed",
+		Time:      time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+		Source:    map[string]string{"DoorToken": "door_1"},
+		Data:      map[string]string{"State": "ForcedOpen"},
+	})
+	buffer := make([]byte, 2048)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	/* local4,严重级别8映射为critical */
+	message := string(buffer[:n])
+	if !strings.HasPrefix(message, "<162>1 2026-10-16T10:00:00Z ") || !strings.Contains(message, " go-onvif - onvif-door-alarm - CEF:0|ONVIF|go-onvif|1.0|onvif:door-alarm|Door alarm ForcedOpen|8|") {
+		t.Fatalf("message %q", message)
+	}
+}
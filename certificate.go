@@ -0,0 +1,228 @@
+package onvif
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CertificateInfo summary of the TLS certificate presented by a device
+type CertificateInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	// Fingerprint SHA-256 of the DER certificate, lower case hex
+	Fingerprint string `json:"fingerprint"`
+}
+
+// NewCertificateInfo return the summary of cert
+func NewCertificateInfo(cert *x509.Certificate) CertificateInfo {
+	sum := sha256.Sum256(cert.Raw)
+	return CertificateInfo{
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}
+}
+
+// ErrNoHTTPS returned when the device exposes no HTTPS service endpoint
+var ErrNoHTTPS = errors.New("device has no https endpoint")
+
+// httpsEndpoint return the host:port of the first HTTPS service of the device
+func (dev *Device) httpsEndpoint() (string, error) {
+	defer dev.readEndpoints()()
+	for _, endpoint := range dev.endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || !strings.EqualFold(u.Scheme, "https") {
+			continue
+		}
+		port := u.Port()
+		if port == "" {
+			port = "443"
+		}
+		return net.JoinHostPort(u.Hostname(), port), nil
+	}
+	return "", ErrNoHTTPS
+}
+
+// PeerCertificates return the certificate chain presented by the HTTPS
+// service of the device, the chain is returned without being verified
+func (dev *Device) PeerCertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	address, err := dev.httpsEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dev.dialTCP(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	/* 仅读取证书,校验由CertificatePins完成 */
+	client := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	if deadline, ok := ctx.Deadline(); ok {
+		client.SetDeadline(deadline)
+	} else {
+		client.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+	if err := client.Handshake(); err != nil {
+		return nil, err
+	}
+	certificates := client.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return nil, errors.New("device presented no certificate")
+	}
+	return certificates, nil
+}
+
+// ErrCertificateChanged returned when a device presents a certificate other
+// than the pinned one
+var ErrCertificateChanged = errors.New("device certificate changed")
+
+// CertificateChangeError ErrCertificateChanged with both certificates
+type CertificateChangeError struct {
+	Device    string
+	Pinned    string
+	Presented CertificateInfo
+}
+
+func (err *CertificateChangeError) Error() string {
+	return fmt.Sprintf("certificate of %s changed from %s to %s", err.Device, err.Pinned, err.Presented.Fingerprint)
+}
+
+// Is report the error as ErrCertificateChanged
+func (err *CertificateChangeError) Is(target error) bool {
+	return target == ErrCertificateChanged
+}
+
+// CertificatePins trust-on-first-use store of device certificates. The
+// first certificate seen for a device is pinned, a different one is reported
+// as a CertificateChangeError until it is accepted with Pin. When Roots is
+// set, a certificate issued by one of the roots replaces the pin silently,
+// so devices enrolled in a private CA can renew their certificates.
+type CertificatePins struct {
+	// Roots optional CAs trusted for certificate renewals
+	Roots *x509.CertPool
+	// OnChange called for every unexpected certificate
+	OnChange func(*CertificateChangeError)
+
+	mutex sync.Mutex
+	pins  map[string]string
+}
+
+// NewCertificatePins return an empty pin store
+func NewCertificatePins() *CertificatePins {
+	return &CertificatePins{pins: make(map[string]string)}
+}
+
+// LoadCertificatePins decode the pins written by Save
+func LoadCertificatePins(r io.Reader) (*CertificatePins, error) {
+	pins := NewCertificatePins()
+	if err := json.NewDecoder(r).Decode(&pins.pins); err != nil {
+		return nil, err
+	}
+	if pins.pins == nil {
+		pins.pins = make(map[string]string)
+	}
+	return pins, nil
+}
+
+// Save write the pins as a JSON object of device to fingerprint
+func (pins *CertificatePins) Save(w io.Writer) error {
+	pins.mutex.Lock()
+	defer pins.mutex.Unlock()
+	return json.NewEncoder(w).Encode(pins.pins)
+}
+
+// Pin trust fingerprint for the device, replacing the previous pin
+func (pins *CertificatePins) Pin(device, fingerprint string) {
+	pins.mutex.Lock()
+	defer pins.mutex.Unlock()
+	if pins.pins == nil {
+		pins.pins = make(map[string]string)
+	}
+	pins.pins[device] = strings.ToLower(fingerprint)
+}
+
+// Pinned return the fingerprint pinned for the device
+func (pins *CertificatePins) Pinned(device string) (string, bool) {
+	pins.mutex.Lock()
+	defer pins.mutex.Unlock()
+	fingerprint, ok := pins.pins[device]
+	return fingerprint, ok
+}
+
+// Observe check the chain presented by the device against its pin, the leaf
+// certificate is pinned when the device has none
+func (pins *CertificatePins) Observe(device string, chain []*x509.Certificate) (CertificateInfo, error) {
+	if len(chain) == 0 {
+		return CertificateInfo{}, errors.New("empty certificate chain")
+	}
+	info := NewCertificateInfo(chain[0])
+	pins.mutex.Lock()
+	if pins.pins == nil {
+		pins.pins = make(map[string]string)
+	}
+	pinned, ok := pins.pins[device]
+	if !ok || pinned == info.Fingerprint || pins.issuedByRoots(chain) {
+		pins.pins[device] = info.Fingerprint
+		pins.mutex.Unlock()
+		return info, nil
+	}
+	onChange := pins.OnChange
+	pins.mutex.Unlock()
+	err := &CertificateChangeError{Device: device, Pinned: pinned, Presented: info}
+	if onChange != nil {
+		onChange(err)
+	}
+	return info, err
+}
+
+/* 设备证书通常签发给IP地址,只校验证书链不校验主机名 */
+func (pins *CertificatePins) issuedByRoots(chain []*x509.Certificate) bool {
+	if pins.Roots == nil {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{Roots: pins.Roots, Intermediates: intermediates})
+	return err == nil
+}
+
+// TLSConfig return a client configuration enforcing the pin of the device,
+// to be used in the transport of DeviceParams.HttpClient
+func (pins *CertificatePins) TLSConfig(device string) *tls.Config {
+	return &tls.Config{
+		/* 由VerifyConnection按固定证书校验 */
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			_, err := pins.Observe(device, state.PeerCertificates)
+			return err
+		},
+	}
+}
+
+// ObserveCertificate read the certificate of the device and check it against
+// its pin in pins
+func (dev *Device) ObserveCertificate(ctx context.Context, pins *CertificatePins) (CertificateInfo, error) {
+	chain, err := dev.PeerCertificates(ctx)
+	if err != nil {
+		return CertificateInfo{}, err
+	}
+	return pins.Observe(dev.Params.Ipddr, chain)
+}
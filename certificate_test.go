@@ -0,0 +1,67 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestObserveCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "https://")})
+	ctx := context.Background()
+	if _, err := dev.ObserveCertificate(ctx, NewCertificatePins()); !errors.Is(err, ErrNoHTTPS) {
+		t.Fatalf("error %v without https endpoint", err)
+	}
+	dev.endpoints[ServiceDevice] = server.URL + "/onvif/device_service"
+
+	/* 首次使用时固定证书 */
+	pins := NewCertificatePins()
+	info, err := dev.ObserveCertificate(ctx, pins)
+	if err != nil || info.Fingerprint != NewCertificateInfo(server.Certificate()).Fingerprint {
+		t.Fatalf("certificate %+v, %v", info, err)
+	}
+	if pinned, ok := pins.Pinned(dev.Params.Ipddr); !ok || pinned != info.Fingerprint {
+		t.Fatalf("pinned %q, %v", pinned, ok)
+	}
+	if _, err := dev.ObserveCertificate(ctx, pins); err != nil {
+		t.Fatal(err)
+	}
+
+	var changes []*CertificateChangeError
+	pins.OnChange = func(change *CertificateChangeError) { changes = append(changes, change) }
+	pins.Pin(dev.Params.Ipddr, strings.Repeat("AB", 32))
+	_, err = dev.ObserveCertificate(ctx, pins)
+	var changed *CertificateChangeError
+	if !errors.Is(err, ErrCertificateChanged) || !errors.As(err, &changed) || changed.Pinned != strings.Repeat("ab", 32) || len(changes) != 1 {
+		t.Fatalf("error %v, changes %v", err, changes)
+	}
+	/* 变化的证书在接受前一直报告 */
+	if _, err := dev.ObserveCertificate(ctx, pins); !errors.Is(err, ErrCertificateChanged) {
+		t.Fatalf("error %v", err)
+	}
+
+	/* 受信CA签发的新证书替换固定的证书 */
+	pins.Roots = x509.NewCertPool()
+	pins.Roots.AddCert(server.Certificate())
+	if _, err := dev.ObserveCertificate(ctx, pins); err != nil || len(changes) != 2 {
+		t.Fatalf("error %v, %d changes", err, len(changes))
+	}
+	var buffer bytes.Buffer
+	if err := pins.Save(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCertificatePins(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned, _ := loaded.Pinned(dev.Params.Ipddr); pinned != info.Fingerprint {
+		t.Fatalf("loaded pin %q", pinned)
+	}
+}
@@ -0,0 +1,149 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ClockSample one comparison of the device clock with the host clock
+type ClockSample struct {
+	Device     string
+	DeviceTime time.Time
+	HostTime   time.Time
+	// Drift device clock minus host clock, positive when the device is ahead
+	Drift     time.Duration
+	RoundTrip time.Duration
+	// Uptime zero when the device does not report it
+	Uptime time.Duration
+	Err    error
+}
+
+// SampleClock read the device UTC clock and compare it to the host clock.
+// The host time is taken in the middle of the request to cancel the round trip.
+func (dev *Device) SampleClock(ctx context.Context) ClockSample {
+	sample := ClockSample{Device: dev.Params.Ipddr}
+	start := time.Now()
+	response := device.GetSystemDateAndTimeResponse{}
+	err := dev.CallMethodInterfaceContext(ctx, device.GetSystemDateAndTime{}, &response, "")
+	end := time.Now()
+	if err != nil {
+		sample.Err = err
+		return sample
+	}
+	utc := response.SystemDateAndTime.UTCDateTime
+	if utc.Date.Year == 0 {
+		sample.Err = errors.New("device did not report UTC date and time")
+		return sample
+	}
+	sample.RoundTrip = end.Sub(start)
+	sample.HostTime = start.Add(sample.RoundTrip / 2).UTC()
+	sample.DeviceTime = utc.ToTime(time.UTC)
+	sample.Drift = sample.DeviceTime.Sub(sample.HostTime)
+	sample.Uptime, _ = dev.Uptime(ctx)
+	return sample
+}
+
+/* 常见厂商系统日志中的运行时间格式 */
+var (
+	uptimeSecondsRegexp = regexp.MustCompile(`(?i)up\s*time\s*[:=]?\s*(\d+)\s*(?:s|sec|seconds)?\b`)
+	uptimeDaysRegexp    = regexp.MustCompile(`(?i)\bup\s+(\d+)\s+days?,\s*(\d+):(\d+)`)
+)
+
+// Uptime read the device uptime from the system log, the ONVIF device service
+// has no dedicated operation so vendor formats are recognized best effort.
+func (dev *Device) Uptime(ctx context.Context) (time.Duration, error) {
+	response := device.GetSystemLogResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetSystemLog{LogType: onvif.SystemLogTypeSystem}, &response, ""); err != nil {
+		return 0, err
+	}
+	return parseUptime(response.SystemLog.String)
+}
+
+func parseUptime(log string) (time.Duration, error) {
+	if match := uptimeDaysRegexp.FindStringSubmatch(log); match != nil {
+		days, _ := strconv.Atoi(match[1])
+		hours, _ := strconv.Atoi(match[2])
+		minutes, _ := strconv.Atoi(match[3])
+		return time.Duration(days)*24*time.Hour + time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+	}
+	if match := uptimeSecondsRegexp.FindStringSubmatch(log); match != nil {
+		seconds, _ := strconv.Atoi(match[1])
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, errors.New("uptime not found in system log")
+}
+
+// ClockCollector periodically samples the clock of every device of a fleet
+type ClockCollector struct {
+	Fleet    *Fleet
+	Interval time.Duration
+	// OnSample optional callback called for every sample
+	OnSample func(ClockSample)
+
+	mutex   sync.Mutex
+	samples map[string]ClockSample
+}
+
+// NewClockCollector return a collector sampling the fleet every interval
+func NewClockCollector(fleet *Fleet, interval time.Duration) *ClockCollector {
+	return &ClockCollector{Fleet: fleet, Interval: interval}
+}
+
+// Run sample the fleet until ctx is done
+func (collector *ClockCollector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(collector.Interval)
+	defer ticker.Stop()
+	for {
+		collector.Collect(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect sample the fleet once
+func (collector *ClockCollector) Collect(ctx context.Context) {
+	collector.Fleet.each(ctx, func(i int, dev *Device) {
+		sample := dev.SampleClock(ctx)
+		collector.mutex.Lock()
+		if collector.samples == nil {
+			collector.samples = make(map[string]ClockSample)
+		}
+		collector.samples[sample.Device] = sample
+		collector.mutex.Unlock()
+		if collector.OnSample != nil {
+			collector.OnSample(sample)
+		}
+	})
+}
+
+// Samples return the latest sample of every device
+func (collector *ClockCollector) Samples() []ClockSample {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	samples := make([]ClockSample, 0, len(collector.samples))
+	for _, sample := range collector.samples {
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// Drifting return the latest samples whose absolute drift exceeds max
+func (collector *ClockCollector) Drifting(max time.Duration) []ClockSample {
+	var drifting []ClockSample
+	for _, sample := range collector.Samples() {
+		if sample.Err == nil && (sample.Drift > max || sample.Drift < -max) {
+			drifting = append(drifting, sample)
+		}
+	}
+	return drifting
+}
@@ -0,0 +1,63 @@
+package onvif
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseUptime(t *testing.T) {
+	for _, test := range []struct {
+		log    string
+		uptime time.Duration
+	}{
+		{"10:00:00 up 3 days, 4:05, load average: 0.10", 3*24*time.Hour + 4*time.Hour + 5*time.Minute},
+		{"boot ok\nUptime: 3600 seconds\n", time.Hour},
+		{"uptime=42s", 42 * time.Second},
+	} {
+		if uptime, err := parseUptime(test.log); err != nil || uptime != test.uptime {
+			t.Fatalf("%q: uptime %s, %v", test.log, uptime, err)
+		}
+	}
+	if _, err := parseUptime("no clock here"); err == nil {
+		t.Fatal("uptime parsed from a log without it")
+	}
+}
+
+// systemDateAndTime answer of GetSystemDateAndTime with the UTC time of at
+func systemDateAndTime(at time.Time) string {
+	number := strconv.Itoa
+	return `<tds:GetSystemDateAndTimeResponse><tds:SystemDateAndTime><tt:DateTimeType>Manual</tt:DateTimeType><tt:DaylightSavings>false</tt:DaylightSavings><tt:UTCDateTime>` +
+		`<tt:Time><tt:Hour>` + number(at.Hour()) + `</tt:Hour><tt:Minute>` + number(at.Minute()) + `</tt:Minute><tt:Second>` + number(at.Second()) + `</tt:Second></tt:Time>` +
+		`<tt:Date><tt:Year>` + number(at.Year()) + `</tt:Year><tt:Month>` + number(int(at.Month())) + `</tt:Month><tt:Day>` + number(at.Day()) + `</tt:Day></tt:Date>` +
+		`</tt:UTCDateTime></tds:SystemDateAndTime></tds:GetSystemDateAndTimeResponse>`
+}
+
+func TestClockCollector(t *testing.T) {
+	/* 时钟电池失效的设备落后一小时 */
+	_, late := newScriptedDevice(t, map[string]string{
+		"GetSystemDateAndTime": systemDateAndTime(time.Now().UTC().Add(-time.Hour)),
+		"GetSystemLog":         `<tds:GetSystemLogResponse><tds:SystemLog><tt:String>up 2 days, 1:00</tt:String></tds:SystemLog></tds:GetSystemLogResponse>`,
+	})
+	_, synced := newScriptedDevice(t, map[string]string{
+		"GetSystemDateAndTime": systemDateAndTime(time.Now().UTC()),
+	})
+	collector := NewClockCollector(&Fleet{Devices: []*Device{late, synced}}, time.Minute)
+	collector.Collect(context.Background())
+	if samples := collector.Samples(); len(samples) != 2 {
+		t.Fatalf("samples %+v", samples)
+	}
+	drifting := collector.Drifting(time.Minute)
+	if len(drifting) != 1 || drifting[0].Device != late.Params.Ipddr {
+		t.Fatalf("drifting %+v", drifting)
+	}
+	sample := drifting[0]
+	if sample.Drift > -59*time.Minute || sample.Drift < -61*time.Minute || sample.Uptime != 49*time.Hour || sample.RoundTrip <= 0 {
+		t.Fatalf("sample %+v", sample)
+	}
+	/* 设备不支持GetSystemLog时运行时间为0 */
+	if sample := synced.SampleClock(context.Background()); sample.Err != nil || sample.Uptime != 0 {
+		t.Fatalf("sample %+v", sample)
+	}
+}
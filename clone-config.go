@@ -0,0 +1,285 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/soap"
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Sections of the configuration copied by CloneConfig
+const (
+	CloneVideoEncoders = "videoEncoder"
+	CloneOSD           = "osd"
+	CloneImaging       = "imaging"
+	CloneRules         = "rules"
+)
+
+// cloneSections sections copied when none is given, in order
+var cloneSections = []string{CloneVideoEncoders, CloneOSD, CloneImaging, CloneRules}
+
+// ErrModelMismatch returned by CloneConfig when the devices are not of the
+// same manufacturer and model
+var ErrModelMismatch = errors.New("devices are not of the same model")
+
+// CloneConfig copy the configuration sections of src to dst, every section
+// when none is given, e.g. to set up a replacement camera like the one it
+// replaces. The devices must be of the same model, their profiles, video
+// sources and analytics configurations are matched by token. The imaging
+// settings, OSDs and rules are copied verbatim, vendor extensions included;
+// the multicast settings of the video encoders of dst are kept.
+// Every setting is reported individually, the sections dst does not support
+// are skipped; an error is returned when the devices cannot be compared.
+func CloneConfig(ctx context.Context, src, dst *Device, sections ...string) (ApplyReport, error) {
+	report := ApplyReport{Device: dst.Params.Ipddr}
+	if len(sections) == 0 {
+		sections = cloneSections
+	}
+	srcInfo, dstInfo := device.GetDeviceInformationResponse{}, device.GetDeviceInformationResponse{}
+	if err := src.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &srcInfo, ""); err != nil {
+		return report, err
+	}
+	if err := dst.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &dstInfo, ""); err != nil {
+		return report, err
+	}
+	if !strings.EqualFold(srcInfo.Manufacturer, dstInfo.Manufacturer) || !strings.EqualFold(srcInfo.Model, dstInfo.Model) {
+		return report, fmt.Errorf("%w: %s %s and %s %s", ErrModelMismatch, srcInfo.Manufacturer, srcInfo.Model, dstInfo.Manufacturer, dstInfo.Model)
+	}
+
+	profiles, err := src.GetProfiles(ctx)
+	if err != nil {
+		return report, err
+	}
+	dstProfiles, err := dst.GetProfiles(ctx)
+	if err != nil {
+		return report, err
+	}
+	/* 同型号设备的profile令牌一致,目标设备缺少的profile不复制 */
+	dstTokens := make(map[onvif.ReferenceToken]onvif.Profile, len(dstProfiles))
+	for _, profile := range dstProfiles {
+		dstTokens[profile.Token] = profile
+	}
+	matched := profiles[:0:0]
+	for _, profile := range profiles {
+		if _, ok := dstTokens[profile.Token]; ok {
+			matched = append(matched, profile)
+		} else {
+			report.add("profile/"+string(profile.Token), fmt.Errorf("profile %s not found on %s", profile.Token, dst.Params.Ipddr))
+		}
+	}
+
+	for _, section := range sections {
+		switch section {
+		case CloneVideoEncoders:
+			cloneVideoEncoders(ctx, dst, matched, dstTokens, &report)
+		case CloneOSD:
+			cloneOSDs(ctx, src, dst, matched, &report)
+		case CloneImaging:
+			cloneImaging(ctx, src, dst, matched, &report)
+		case CloneRules:
+			cloneRules(ctx, src, dst, matched, &report)
+		default:
+			report.add(section, fmt.Errorf("unknown configuration section %q", section))
+		}
+	}
+	return report, nil
+}
+
+func cloneVideoEncoders(ctx context.Context, dst *Device, profiles []onvif.Profile, dstProfiles map[onvif.ReferenceToken]onvif.Profile, report *ApplyReport) {
+	/* 多个profile可能共用同一个编码配置,只设置一次 */
+	done := make(map[onvif.ReferenceToken]bool)
+	for _, profile := range profiles {
+		config := profile.VideoEncoderConfiguration
+		target := dstProfiles[profile.Token].VideoEncoderConfiguration
+		if config.Token == "" || target.Token == "" || done[target.Token] {
+			continue
+		}
+		done[target.Token] = true
+		config.Token, config.Name = target.Token, target.Name
+		/* 组播地址是每台设备独有的,沿用目标设备的设置 */
+		config.Multicast = target.Multicast
+		err := dst.CallMethodInterfaceContext(ctx, media.SetVideoEncoderConfiguration{Configuration: config, ForcePersistence: true},
+			&media.SetVideoEncoderConfigurationResponse{}, "")
+		report.add(CloneVideoEncoders+"/"+string(config.Token), err)
+	}
+}
+
+func cloneOSDs(ctx context.Context, src, dst *Device, profiles []onvif.Profile, report *ApplyReport) {
+	if capabilities, _ := dst.ServiceCapabilities(ctx); capabilities.Media != nil && !capabilities.Media.OSD {
+		report.add(CloneOSD, ErrNotSupported)
+		return
+	}
+	request := `<trt:GetOSDs xmlns:trt="` + Xlmns["trt"] + `"><trt:ConfigurationToken>%s</trt:ConfigurationToken></trt:GetOSDs>`
+	as := xml.Name{Space: Xlmns["trt"], Local: "OSD"}
+	done := make(map[onvif.ReferenceToken]bool)
+	for _, profile := range profiles {
+		source := profile.VideoSourceConfiguration.Token
+		if source == "" || done[source] {
+			continue
+		}
+		done[source] = true
+		setting := CloneOSD + "/" + string(source)
+		osds, err := rawElements(ctx, src, "media", fmt.Sprintf(request, escapeXML(string(source))), "OSDs", as)
+		if err != nil {
+			report.add(setting, err)
+			continue
+		}
+		existing, err := rawElements(ctx, dst, "media", fmt.Sprintf(request, escapeXML(string(source))), "OSDs", xml.Name{})
+		if err != nil {
+			report.add(setting, err)
+			continue
+		}
+		tokens := make(map[string]bool, len(existing))
+		for _, osd := range existing {
+			tokens[elementAttr(osd, "token")] = true
+		}
+		/* 目标设备已有的OSD更新,其余新建 */
+		for _, osd := range osds {
+			token := elementAttr(osd, "token")
+			operation := "CreateOSD"
+			if tokens[token] {
+				operation = "SetOSD"
+			}
+			body := `<trt:` + operation + ` xmlns:trt="` + Xlmns["trt"] + `">` + string(osd) + `</trt:` + operation + `>`
+			report.add(setting+"/"+token, sendRaw(ctx, dst, "media", body))
+		}
+	}
+}
+
+func cloneImaging(ctx context.Context, src, dst *Device, profiles []onvif.Profile, report *ApplyReport) {
+	if _, err := dst.getEndpoint("imaging"); err != nil {
+		report.add(CloneImaging, ErrNotSupported)
+		return
+	}
+	namespace := Xlmns["timg"]
+	done := make(map[onvif.ReferenceToken]bool)
+	for _, profile := range profiles {
+		source := profile.VideoSourceConfiguration.SourceToken
+		if source == "" || done[source] {
+			continue
+		}
+		done[source] = true
+		setting := CloneImaging + "/" + string(source)
+		token := `<timg:VideoSourceToken>` + escapeXML(string(source)) + `</timg:VideoSourceToken>`
+		settings, err := rawElements(ctx, src, "imaging", `<timg:GetImagingSettings xmlns:timg="`+namespace+`">`+token+`</timg:GetImagingSettings>`,
+			"ImagingSettings", xml.Name{Space: namespace, Local: "ImagingSettings"})
+		if err == nil && len(settings) == 0 {
+			err = errors.New("imaging settings missing from the response")
+		}
+		if err != nil {
+			report.add(setting, err)
+			continue
+		}
+		body := `<timg:SetImagingSettings xmlns:timg="` + namespace + `">` + token + string(settings[0]) +
+			`<timg:ForcePersistence>true</timg:ForcePersistence></timg:SetImagingSettings>`
+		report.add(setting, sendRaw(ctx, dst, "imaging", body))
+	}
+}
+
+func cloneRules(ctx context.Context, src, dst *Device, profiles []onvif.Profile, report *ApplyReport) {
+	if _, err := dst.getEndpoint("analytics"); err != nil {
+		report.add(CloneRules, ErrNotSupported)
+		return
+	}
+	namespace := Xlmns["tan"]
+	done := make(map[onvif.ReferenceToken]bool)
+	for _, profile := range profiles {
+		configuration := profile.VideoAnalyticsConfiguration.Token
+		if configuration == "" || done[configuration] {
+			continue
+		}
+		done[configuration] = true
+		setting := CloneRules + "/" + string(configuration)
+		token := `<tan:ConfigurationToken>` + escapeXML(string(configuration)) + `</tan:ConfigurationToken>`
+		request := `<tan:GetRules xmlns:tan="` + namespace + `">` + token + `</tan:GetRules>`
+		as := xml.Name{Space: namespace, Local: "Rule"}
+		rules, err := rawElements(ctx, src, "analytics", request, "Rule", as)
+		if err != nil {
+			report.add(setting, err)
+			continue
+		}
+		existing, err := rawElements(ctx, dst, "analytics", request, "Rule", xml.Name{})
+		if err != nil {
+			report.add(setting, err)
+			continue
+		}
+		names := make(map[string]bool, len(existing))
+		for _, rule := range existing {
+			names[elementAttr(rule, "Name")] = true
+		}
+		/* 同名规则修改,其余新建 */
+		var modified, created bytes.Buffer
+		for _, rule := range rules {
+			if names[elementAttr(rule, "Name")] {
+				modified.Write(rule)
+			} else {
+				created.Write(rule)
+			}
+		}
+		if modified.Len() > 0 {
+			body := `<tan:ModifyRules xmlns:tan="` + namespace + `">` + token + modified.String() + `</tan:ModifyRules>`
+			report.add(setting+"/modify", sendRaw(ctx, dst, "analytics", body))
+		}
+		if created.Len() > 0 {
+			body := `<tan:CreateRules xmlns:tan="` + namespace + `">` + token + created.String() + `</tan:CreateRules>`
+			report.add(setting+"/create", sendRaw(ctx, dst, "analytics", body))
+		}
+	}
+}
+
+// rawElements send the raw request body to service and return the elements
+// of the response called local, renamed as when set, see soap.ElementsAs
+func rawElements(ctx context.Context, dev *Device, service, body, local string, as xml.Name) ([][]byte, error) {
+	message, err := parseRawMessage([]byte(body))
+	if err != nil {
+		return nil, err
+	}
+	response, err := dev.callRaw(ctx, service, message)
+	if err != nil {
+		return nil, err
+	}
+	return soap.ElementsAs(response, local, as)
+}
+
+// sendRaw send the raw request body to service, discarding the response
+func sendRaw(ctx context.Context, dev *Device, service, body string) error {
+	message, err := parseRawMessage([]byte(body))
+	if err != nil {
+		return err
+	}
+	_, err = dev.callRaw(ctx, service, message)
+	return err
+}
+
+// elementAttr return the attribute of the root of an element, empty when it
+// has none
+func elementAttr(element []byte, name string) string {
+	decoder := xml.NewDecoder(bytes.NewReader(element))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == name {
+					return attr.Value
+				}
+			}
+			return ""
+		}
+	}
+}
+
+func escapeXML(text string) string {
+	buf := bytes.Buffer{}
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
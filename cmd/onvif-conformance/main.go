@@ -0,0 +1,77 @@
+// Command onvif-conformance runs the read-only conformance checks of the
+// profiles claimed by a device and prints a pass/fail report per operation.
+//
+//	onvif-conformance -addr 10.1.1.200 -user admin -pass secret
+//
+// The exit status is 1 when a check fails. With -matrix the capability matrix
+// of the device is printed as JSON instead.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PolarisM78/go-onvif"
+)
+
+func main() {
+	addr := flag.String("addr", "", "device address, host or host:port")
+	user := flag.String("user", "", "username")
+	pass := flag.String("pass", "", "password")
+	serviceURL := flag.String("url", "", "device service URL, overrides -addr port probing")
+	timeout := flag.Duration("timeout", 2*time.Minute, "timeout of the whole run")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	matrix := flag.Bool("matrix", false, "print the capability matrix of the device instead of running the checks")
+	flag.Parse()
+	if *addr == "" && *serviceURL == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	/* 连接设备 */
+	dev, err := onvif.NewDevice(onvif.DeviceParams{Ipddr: *addr, ServiceURL: *serviceURL, Username: *user, Password: *pass})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if *matrix {
+		if err := onvif.WriteCapabilityMatrixJSON(os.Stdout, []onvif.CapabilityMatrix{dev.CapabilityMatrix(ctx)}); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	report := dev.Conformance(ctx)
+
+	/* 输出报告 */
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		fmt.Printf("device %s, profiles: %s\n\n", report.Device, strings.Join(report.Profiles, " "))
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PROFILE\tOPERATION\tRESULT\tTIME\tERROR")
+		for _, result := range report.Results {
+			profile, status := result.Profile, "PASS"
+			if profile == "" {
+				profile = "core"
+			}
+			if !result.Passed {
+				status = "FAIL"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", profile, result.Operation, status, result.Duration.Round(time.Millisecond), result.Error)
+		}
+		w.Flush()
+	}
+	if !report.Passed() {
+		os.Exit(1)
+	}
+}
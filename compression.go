@@ -0,0 +1,149 @@
+package onvif
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/PolarisM78/go-onvif/soap"
+)
+
+// States of the gzip support of a device, see DeviceParams.Compression
+const (
+	compressionUnknown int32 = iota
+	compressionSupported
+	compressionUnsupported
+)
+
+// compressionState gzip support of a device detected by the calls, shared by
+// the copies of the Device
+type compressionState struct {
+	state int32
+}
+
+func (compression *compressionState) load() int32 {
+	return atomic.LoadInt32(&compression.state)
+}
+
+func (compression *compressionState) store(state int32) {
+	atomic.StoreInt32(&compression.state, state)
+}
+
+// CompressesRequests report whether the requests are sent gzip compressed:
+// DeviceParams.Compression is set and the device did not reject a compressed
+// request yet
+func (dev *Device) CompressesRequests() bool {
+	return dev.compression != nil && dev.compression.load() != compressionUnsupported
+}
+
+// post send the SOAP message of buf to endpoint, buf is released once the
+// response body is closed and the transport is done with the request
+func (dev Device) post(ctx context.Context, endpoint string, buf *bytes.Buffer, gzipped bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		soap.PutBuffer(buf)
+		return nil, err
+	}
+	/* 缓冲区在响应读取完毕且传输层关闭请求体后回收 */
+	body := soap.NewBufferRequest(buf)
+	req.ContentLength = int64(buf.Len())
+	req.Body, req.GetBody = body.Body(), body.GetBody
+	req.Header.Set("Content-Type", soapContentType)
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if dev.compression != nil {
+		/* 显式声明时传输层不再自动解压,由gunzipResponse处理 */
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	dev.setRequestHeaders(ctx, req)
+	resp, err := dev.httpClient.Do(req)
+	if err != nil {
+		body.Release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: body.Release}
+	if err := gunzipResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// releaseBody response body calling release once closed
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (body *releaseBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.once.Do(body.release)
+	return err
+}
+
+// postCompressed send the SOAP message of buf gzip compressed. While the
+// support of the device is unknown, a compressed request answered with an
+// error status is sent again uncompressed, and the device is taken as not
+// supporting compression when the plain request succeeds.
+func (dev Device) postCompressed(ctx context.Context, endpoint string, buf *bytes.Buffer) (*http.Response, error) {
+	compressed := soap.GetBuffer()
+	writer := gzip.NewWriter(compressed)
+	if _, err := writer.Write(buf.Bytes()); err != nil {
+		soap.PutBuffer(compressed)
+		return dev.post(ctx, endpoint, buf, false)
+	}
+	writer.Close()
+	resp, err := dev.post(ctx, endpoint, compressed, true)
+	if err != nil || resp.StatusCode < http.StatusMultipleChoices || dev.compression.load() == compressionSupported {
+		if err == nil && resp.StatusCode < http.StatusMultipleChoices {
+			dev.compression.store(compressionSupported)
+		}
+		soap.PutBuffer(buf)
+		return resp, err
+	}
+	/* 设备可能不支持压缩请求,以原始报文重试 */
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, soap.DefaultLimits.MaxSize))
+	resp.Body.Close()
+	resp, err = dev.post(ctx, endpoint, buf, false)
+	if err == nil && resp.StatusCode < http.StatusMultipleChoices {
+		dev.compression.store(compressionUnsupported)
+	}
+	return resp, err
+}
+
+// gzipBody decompressed body of a response, closing the original body
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (body gzipBody) Close() error {
+	body.Reader.Close()
+	return body.body.Close()
+}
+
+// gunzipResponse decompress the body of a gzip encoded response, the size of
+// the decompressed body is limited by the reader of the response
+func gunzipResponse(resp *http.Response) error {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body = gzipBody{Reader: reader, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
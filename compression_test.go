@@ -0,0 +1,113 @@
+package onvif
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+/* 支持gzip时解压请求并压缩应答,否则以415拒绝压缩的请求 */
+type gzipDevice struct {
+	scriptedDevice
+	supported bool
+
+	encodings []string
+	mutex     sync.Mutex
+}
+
+func (fake *gzipDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	encoding := r.Header.Get("Content-Encoding")
+	fake.mutex.Lock()
+	fake.encodings = append(fake.encodings, encoding)
+	fake.mutex.Unlock()
+	if encoding == "gzip" {
+		if !fake.supported {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		plain, _ := ioutil.ReadAll(reader)
+		r.Body = ioutil.NopCloser(bytes.NewReader(plain))
+	}
+	recorder := httptest.NewRecorder()
+	fake.scriptedDevice.ServeHTTP(recorder, r)
+	if fake.supported && r.Header.Get("Accept-Encoding") == "gzip" {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(recorder.Code)
+		writer := gzip.NewWriter(w)
+		writer.Write(recorder.Body.Bytes())
+		writer.Close()
+		return
+	}
+	w.WriteHeader(recorder.Code)
+	w.Write(recorder.Body.Bytes())
+}
+
+func (fake *gzipDevice) sentEncodings() string {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return strings.Join(fake.encodings, ",")
+}
+
+func newGzipDevice(t *testing.T, supported, compression bool) (*gzipDevice, *Device) {
+	fake := &gzipDevice{supported: supported}
+	fake.answers = map[string]string{
+		"GetDeviceInformation": `<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer></tds:GetDeviceInformationResponse>`,
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://"), Compression: compression})
+	dev.endpoints[ServiceDevice] = server.URL + "/onvif/device_service"
+	dev.capabilities.servicesLoaded = true
+	return fake, dev
+}
+
+func TestCompression(t *testing.T) {
+	ctx := context.Background()
+	call := func(dev *Device) {
+		t.Helper()
+		info := device.GetDeviceInformationResponse{}
+		if err := dev.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &info, ""); err != nil || info.Manufacturer != "Acme" {
+			t.Fatalf("information %+v, %v", info, err)
+		}
+	}
+
+	fake, dev := newGzipDevice(t, true, true)
+	call(dev)
+	call(dev)
+	if encodings := fake.sentEncodings(); encodings != "gzip,gzip" || !dev.CompressesRequests() {
+		t.Fatalf("encodings %q", encodings)
+	}
+
+	/* 设备拒绝压缩请求时以原始报文重试,之后不再压缩 */
+	fake, dev = newGzipDevice(t, false, true)
+	call(dev)
+	if dev.CompressesRequests() {
+		t.Fatal("compressed requests after a rejection")
+	}
+	call(dev)
+	if encodings := fake.sentEncodings(); encodings != "gzip,," {
+		t.Fatalf("encodings %q", encodings)
+	}
+	if len(fake.sent("GetDeviceInformation")) != 2 {
+		t.Fatalf("%d plain requests", len(fake.sent("GetDeviceInformation")))
+	}
+
+	fake, dev = newGzipDevice(t, true, false)
+	call(dev)
+	if encodings := fake.sentEncodings(); encodings != "" || len(fake.sent("GetDeviceInformation")) != 1 || dev.CompressesRequests() {
+		t.Fatalf("encodings %q without compression", encodings)
+	}
+}
@@ -0,0 +1,147 @@
+package onvif
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Topics, without namespace prefix, reporting configuration changes next to
+// TopicProfileChanged and TopicConfigurationChanged
+const (
+	// TopicDeviceConfiguration configuration of the device, e.g. its network
+	// or its protocols, changed from its web interface
+	TopicDeviceConfiguration = "Device/Configuration"
+	// TopicConfiguration configurations changed, reported under the kind of
+	// configuration, e.g. Configuration/VideoEncoderConfiguration
+	TopicConfiguration = "Configuration"
+)
+
+// ConfigChange configuration change reported by a device, see
+// WatchConfiguration
+type ConfigChange struct {
+	Event Event
+	// Services the endpoints of the services were read again, for a change of
+	// the configuration of the device
+	Services bool
+	// Err failure to read the services again
+	Err error
+}
+
+// isConfigurationEvent report whether ev tells a configuration of the device
+// changed
+func isConfigurationEvent(ev Event) bool {
+	if ev.Operation == "Initialized" {
+		return false
+	}
+	for _, topic := range []string{TopicDeviceConfiguration, TopicProfileChanged, TopicConfigurationChanged, TopicConfiguration} {
+		if _, ok := topicSuffix(ev.Topic, topic); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchConfiguration make the engine pull the events of dev and call
+// onChange, optional, after each change of its configuration made by other
+// clients, e.g. from its web interface. The engine keeps every device it
+// pulls consistent with such changes without a watch, see
+// EventEngine.configurationChanged; the watch keeps the device pulled and
+// reports the changes. A NotSupportedError is returned when the device
+// advertises its topics and none of them reports configuration changes. The
+// watch ends with the returned function, or at the first change once ctx is
+// done.
+func (engine *EventEngine) WatchConfiguration(ctx context.Context, dev *Device, onChange func(ConfigChange)) (func(), error) {
+	if topics, err := dev.eventTopics(ctx); err == nil && !hasConfigurationTopic(topics) {
+		return nil, &NotSupportedError{Service: "events", Capability: TopicDeviceConfiguration}
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	watch := &configWatch{ctx: ctx, onChange: onChange}
+	engine.mutex.Lock()
+	if engine.watches == nil {
+		engine.watches = make(map[*Device]map[*configWatch]struct{})
+	}
+	if engine.watches[dev] == nil {
+		engine.watches[dev] = make(map[*configWatch]struct{})
+	}
+	engine.watches[dev][watch] = struct{}{}
+	engine.add(dev)
+	engine.mutex.Unlock()
+	var once sync.Once
+	return func() { once.Do(func() { engine.unwatch(dev, watch) }) }, nil
+}
+
+// configWatch watch of the configuration of a device
+type configWatch struct {
+	ctx      context.Context
+	onChange func(ConfigChange)
+}
+
+// unwatch end the watch, the device is no longer pulled when nothing else
+// uses it
+func (engine *EventEngine) unwatch(dev *Device, watch *configWatch) {
+	engine.mutex.Lock()
+	delete(engine.watches[dev], watch)
+	if len(engine.watches[dev]) == 0 {
+		delete(engine.watches, dev)
+	}
+	engine.mutex.Unlock()
+	engine.remove(dev, true)
+}
+
+// configurationChanged keep dev consistent with a configuration event,
+// whether or not it is watched: the cached capabilities and profiles are
+// dropped, see InvalidateCache, and for a change of the device configuration
+// the services are read again, see RefreshServices. It runs on the worker
+// that pulled the event, before the event is delivered, then calls the
+// watches of the device.
+func (engine *EventEngine) configurationChanged(ctx context.Context, dev *Device, ev Event) {
+	if !isConfigurationEvent(ev) {
+		return
+	}
+	change := ConfigChange{Event: ev}
+	if _, ok := topicSuffix(ev.Topic, TopicDeviceConfiguration); ok {
+		change.Services = true
+		if change.Err = dev.RefreshServices(ctx); change.Err != nil {
+			engine.reportError(dev, change.Err)
+		}
+	}
+	/* RefreshServices失败时缓存同样需要清除 */
+	dev.InvalidateCache()
+	engine.mutex.Lock()
+	watches := make([]*configWatch, 0, len(engine.watches[dev]))
+	for watch := range engine.watches[dev] {
+		watches = append(watches, watch)
+	}
+	engine.mutex.Unlock()
+	for _, watch := range watches {
+		if watch.ctx.Err() != nil {
+			engine.unwatch(dev, watch)
+		} else if watch.onChange != nil {
+			watch.onChange(change)
+		}
+	}
+}
+
+// hasConfigurationTopic report whether the advertised topics report
+// configuration changes
+func hasConfigurationTopic(topics map[string]bool) bool {
+	for topic := range topics {
+		if isConfigurationEvent(Event{Topic: topic}) {
+			return true
+		}
+	}
+	return false
+}
+
+// configurationChanged drop the cached profiles after a successful call
+// changing the profiles or the media configurations
+func (dev Device) configurationChanged(operation string, err error) {
+	if err != nil || OperationRole(operation) <= RoleReadOnly {
+		return
+	}
+	if strings.Contains(operation, "Profile") || strings.Contains(operation, "Configuration") {
+		dev.invalidateProfiles()
+	}
+}
@@ -0,0 +1,210 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/accesscontrol"
+	"github.com/PolarisM78/go-onvif/types/credential"
+	"github.com/PolarisM78/go-onvif/types/device"
+	event "github.com/PolarisM78/go-onvif/types/events"
+	"github.com/PolarisM78/go-onvif/types/imaging"
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/types/ptz"
+	"github.com/PolarisM78/go-onvif/types/schedule"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ConformanceCheck read-only operation run by Conformance
+type ConformanceCheck struct {
+	// Profile ONVIF profile requiring the operation (S, T, G, C, A...),
+	// empty for the core operations every device must answer
+	Profile string
+	// Service endpoint key of the service, a device without the service
+	// fails the check when it claims Profile
+	Service string
+	// Optional the check is skipped when the device does not offer Service,
+	// for the conditional features of a profile such as PTZ
+	Optional  bool
+	Operation string
+	Run       func(ctx context.Context, dev *Device) error
+}
+
+// ConformanceResult outcome of one check
+type ConformanceResult struct {
+	Profile   string        `json:"profile,omitempty"`
+	Operation string        `json:"operation"`
+	Passed    bool          `json:"passed"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// ConformanceReport results of the checks run against one device
+type ConformanceReport struct {
+	Device   string              `json:"device"`
+	Profiles []string            `json:"profiles"`
+	Results  []ConformanceResult `json:"results"`
+}
+
+// Passed report whether every check passed
+func (report ConformanceReport) Passed() bool {
+	for _, result := range report.Results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// readOnlyCheck return a check calling method, response is the zero value of
+// the response type
+func readOnlyCheck(profile, service string, method, response interface{}) ConformanceCheck {
+	name := service + "." + reflect.TypeOf(method).Name()
+	return ConformanceCheck{Profile: profile, Service: service, Operation: name, Run: func(ctx context.Context, dev *Device) error {
+		return dev.CallMethodInterfaceContext(ctx, method, reflect.New(reflect.TypeOf(response)).Interface(), "")
+	}}
+}
+
+func optional(check ConformanceCheck) ConformanceCheck {
+	check.Optional = true
+	return check
+}
+
+// firstProfile return the token of the first media profile, see CachedProfiles
+func firstProfile(ctx context.Context, dev *Device) (onvif.ReferenceToken, error) {
+	profiles, err := dev.CachedProfiles(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(profiles) == 0 {
+		return "", ErrNoProfile
+	}
+	return onvif.ReferenceToken(profiles[0].Token), nil
+}
+
+// ConformanceChecks battery run by Conformance, in order
+var ConformanceChecks = []ConformanceCheck{
+	readOnlyCheck("", "device", device.GetDeviceInformation{}, device.GetDeviceInformationResponse{}),
+	readOnlyCheck("", "device", device.GetSystemDateAndTime{}, device.GetSystemDateAndTimeResponse{}),
+	readOnlyCheck("", "device", device.GetCapabilities{Category: onvif.CapabilityCategoryAll}, device.GetCapabilitiesResponse{}),
+	readOnlyCheck("", "device", device.GetServices{IncludeCapability: true}, device.GetServicesResponse{}),
+	readOnlyCheck("", "device", device.GetScopes{}, device.GetScopesResponse{}),
+	readOnlyCheck("", "device", device.GetHostname{}, device.GetHostnameResponse{}),
+	readOnlyCheck("", "device", device.GetNetworkInterfaces{}, device.GetNetworkInterfacesResponse{}),
+	readOnlyCheck("", "device", device.GetNetworkProtocols{}, device.GetNetworkProtocolsResponse{}),
+	readOnlyCheck("", "device", device.GetDNS{}, device.GetDNSResponse{}),
+	readOnlyCheck("", "device", device.GetNTP{}, device.GetNTPResponse{}),
+	readOnlyCheck("", "device", device.GetDiscoveryMode{}, device.GetDiscoveryModeResponse{}),
+	optional(readOnlyCheck("", "events", event.GetEventProperties{}, event.GetEventPropertiesResponse{})),
+
+	readOnlyCheck("S", "media", media.GetServiceCapabilities{}, media.GetServiceCapabilitiesResponse{}),
+	readOnlyCheck("S", "media", media.GetProfiles{}, media.GetProfilesResponse{}),
+	readOnlyCheck("S", "media", media.GetVideoSources{}, media.GetVideoSourcesResponse{}),
+	readOnlyCheck("S", "media", media.GetVideoSourceConfigurations{}, media.GetVideoSourceConfigurationsResponse{}),
+	readOnlyCheck("S", "media", media.GetVideoEncoderConfigurations{}, media.GetVideoEncoderConfigurationsResponse{}),
+	{Profile: "S", Service: "media", Operation: "media.GetStreamUri", Run: func(ctx context.Context, dev *Device) error {
+		token, err := firstProfile(ctx, dev)
+		if err != nil {
+			return err
+		}
+		response := media.GetStreamUriResponse{}
+		request := media.GetStreamUri{
+			ProfileToken: token,
+			StreamSetup:  device.StreamSetup{Stream: "RTP-Unicast", Transport: device.Transport{Protocol: "RTSP"}},
+		}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			return err
+		}
+		if response.MediaUri.Uri == "" {
+			return errors.New("empty stream uri")
+		}
+		return nil
+	}},
+	{Profile: "S", Service: "media", Operation: "media.GetSnapshotUri", Run: func(ctx context.Context, dev *Device) error {
+		token, err := firstProfile(ctx, dev)
+		if err != nil {
+			return err
+		}
+		return dev.CallMethodInterfaceContext(ctx, media.GetSnapshotUri{ProfileToken: token}, &media.GetSnapshotUriResponse{}, "")
+	}},
+	optional(readOnlyCheck("S", "ptz", ptz.GetNodes{}, ptz.GetNodesResponse{})),
+	optional(readOnlyCheck("S", "ptz", ptz.GetConfigurations{}, ptz.GetConfigurationsResponse{})),
+
+	readOnlyCheck("T", "media", media.GetProfiles{}, media.GetProfilesResponse{}),
+	readOnlyCheck("T", "imaging", imaging.GetServiceCapabilities{}, imaging.GetServiceCapabilitiesResponse{}),
+	readOnlyCheck("T", "events", event.GetServiceCapabilities{}, event.GetServiceCapabilitiesResponse{}),
+
+	readOnlyCheck("C", "accesscontrol", accesscontrol.GetServiceCapabilities{}, accesscontrol.GetServiceCapabilitiesResponse{}),
+	readOnlyCheck("C", "accesscontrol", accesscontrol.GetAccessPointInfoList{}, accesscontrol.GetAccessPointInfoListResponse{}),
+	readOnlyCheck("C", "accesscontrol", accesscontrol.GetAreaInfoList{}, accesscontrol.GetAreaInfoListResponse{}),
+
+	readOnlyCheck("A", "credential", credential.GetServiceCapabilities{}, credential.GetServiceCapabilitiesResponse{}),
+	readOnlyCheck("A", "credential", credential.GetCredentialInfoList{}, credential.GetCredentialInfoListResponse{}),
+	readOnlyCheck("A", "schedule", schedule.GetServiceCapabilities{}, schedule.GetServiceCapabilitiesResponse{}),
+	readOnlyCheck("A", "schedule", schedule.GetScheduleList{}, schedule.GetScheduleListResponse{}),
+}
+
+// ClaimedProfiles return the ONVIF profiles the device claims in its scopes,
+// e.g. onvif://www.onvif.org/Profile/Streaming is profile S
+func (dev *Device) ClaimedProfiles(ctx context.Context) ([]string, error) {
+	response := device.GetScopesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetScopes{}, &response, ""); err != nil {
+		return nil, err
+	}
+	claimed := make(map[string]bool)
+	for _, scope := range response.Scopes {
+		item := string(scope.ScopeItem)
+		index := strings.Index(strings.ToLower(item), "/profile/")
+		if index < 0 {
+			continue
+		}
+		name := item[index+len("/profile/"):]
+		/* Profile S旧版本使用Streaming作为范围名称 */
+		if strings.EqualFold(name, "Streaming") {
+			name = "S"
+		}
+		claimed[strings.ToUpper(name)] = true
+	}
+	profiles := make([]string, 0, len(claimed))
+	for profile := range claimed {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// Conformance run the core checks and the checks of every profile claimed by
+// the device. Only read-only operations are called.
+func (dev *Device) Conformance(ctx context.Context) ConformanceReport {
+	report := ConformanceReport{Device: dev.Params.Ipddr}
+	profiles, err := dev.ClaimedProfiles(ctx)
+	if err != nil {
+		report.Results = append(report.Results, ConformanceResult{Operation: "device.GetScopes", Error: err.Error()})
+	}
+	report.Profiles = profiles
+	for _, check := range ConformanceChecks {
+		if check.Profile != "" && !containsString(profiles, check.Profile) {
+			continue
+		}
+		result := ConformanceResult{Profile: check.Profile, Operation: check.Operation}
+		start := time.Now()
+		if _, err := dev.getEndpoint(check.Service); err != nil {
+			/* 可选服务缺失时跳过 */
+			if check.Optional {
+				continue
+			}
+			result.Error = check.Service + " service not advertised"
+		} else if err := check.Run(ctx, dev); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Passed = true
+		}
+		result.Duration = time.Since(start)
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
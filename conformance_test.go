@@ -0,0 +1,53 @@
+package onvif
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/types/accesscontrol"
+	"github.com/PolarisM78/go-onvif/types/device"
+	event "github.com/PolarisM78/go-onvif/types/events"
+	"github.com/PolarisM78/go-onvif/types/imaging"
+	"github.com/PolarisM78/go-onvif/types/media"
+)
+
+func TestConformance(t *testing.T) {
+	checks := ConformanceChecks
+	ConformanceChecks = []ConformanceCheck{
+		readOnlyCheck("", "device", device.GetHostname{}, device.GetHostnameResponse{}),
+		optional(readOnlyCheck("", "events", event.GetEventProperties{}, event.GetEventPropertiesResponse{})),
+		readOnlyCheck("S", "media", media.GetProfiles{}, media.GetProfilesResponse{}),
+		readOnlyCheck("T", "imaging", imaging.GetServiceCapabilities{}, imaging.GetServiceCapabilitiesResponse{}),
+		readOnlyCheck("C", "accesscontrol", accesscontrol.GetServiceCapabilities{}, accesscontrol.GetServiceCapabilitiesResponse{}),
+	}
+	defer func() { ConformanceChecks = checks }()
+	_, dev := newScriptedDevice(t, map[string]string{
+		"GetScopes": `<tds:GetScopesResponse><tds:Scopes><tt:ScopeDef>Fixed</tt:ScopeDef><tt:ScopeItem>onvif://www.onvif.org/Profile/Streaming</tt:ScopeItem></tds:Scopes>` +
+			`<tds:Scopes><tt:ScopeDef>Fixed</tt:ScopeDef><tt:ScopeItem>onvif://www.onvif.org/profile/c</tt:ScopeItem></tds:Scopes>` +
+			`<tds:Scopes><tt:ScopeDef>Configurable</tt:ScopeDef><tt:ScopeItem>onvif://www.onvif.org/name/camera</tt:ScopeItem></tds:Scopes></tds:GetScopesResponse>`,
+		"GetProfiles": `<trt:GetProfilesResponse><trt:Profiles token="main"><tt:Name>main</tt:Name></trt:Profiles></trt:GetProfilesResponse>`,
+		"GetHostname": `<tds:GetHostnameResponse><tds:HostnameInformation><tt:FromDHCP>false</tt:FromDHCP><tt:Name>camera</tt:Name></tds:HostnameInformation></tds:GetHostnameResponse>`,
+	}, ServiceMedia)
+	report := dev.Conformance(context.Background())
+	if strings.Join(report.Profiles, ",") != "C,S" {
+		t.Fatalf("profiles %v", report.Profiles)
+	}
+	/* 缺失的可选服务和未声明的Profile不检查,声明的Profile缺少服务时失败 */
+	var results []string
+	for _, result := range report.Results {
+		outcome := result.Error
+		if result.Passed {
+			outcome = "passed"
+		}
+		results = append(results, result.Operation+" "+outcome)
+	}
+	want := []string{
+		"device.GetHostname passed",
+		"media.GetProfiles passed",
+		"accesscontrol.GetServiceCapabilities accesscontrol service not advertised",
+	}
+	if strings.Join(results, "\n") != strings.Join(want, "\n") || report.Passed() {
+		t.Fatalf("results %q", results)
+	}
+}
@@ -0,0 +1,74 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Steps of Connect, reported by ConnectError
+const (
+	ConnectDevice   = "device"
+	ConnectProfile  = "profile"
+	ConnectStream   = "stream"
+	ConnectSnapshot = "snapshot"
+)
+
+// Errors of the steps of Connect
+var (
+	ErrNoProfile     = errors.New("device has no media profile")
+	ErrNoStreamURI   = errors.New("device returned an empty stream uri")
+	ErrNoSnapshotURI = errors.New("device returned an empty snapshot uri")
+)
+
+// ConnectError returned by Connect, naming the step which failed
+type ConnectError struct {
+	Address string
+	// Step ConnectDevice, ConnectProfile, ConnectStream or ConnectSnapshot
+	Step string
+	Err  error
+}
+
+func (err *ConnectError) Error() string {
+	return fmt.Sprintf("connect %s: %s: %v", err.Address, err.Step, err.Err)
+}
+
+func (err *ConnectError) Unwrap() error {
+	return err.Err
+}
+
+// Connect return the RTSP stream URI and the snapshot URI of the first media
+// profile of the device at address, see ConnectContext
+func Connect(address, username, password string) (streamURL, snapshotURL string, err error) {
+	return ConnectContext(context.Background(), address, username, password)
+}
+
+// ConnectContext connect to the device at address and return the RTSP stream
+// URI and the snapshot URI of its first media profile, the hosts rewritten to
+// address. Errors are ConnectErrors. Many devices serve no snapshot, the
+// stream URI is then returned along with the ConnectError of the snapshot
+// step.
+func ConnectContext(ctx context.Context, address, username, password string) (streamURL, snapshotURL string, err error) {
+	fail := func(step string, err error) error {
+		return &ConnectError{Address: address, Step: step, Err: err}
+	}
+	endpoint, err := ParseEndpoint(address)
+	if err != nil {
+		return "", "", fail(ConnectDevice, err)
+	}
+	dev, err := NewDevice(DeviceParams{Endpoint: endpoint, Username: username, Password: password})
+	if err != nil {
+		return "", "", fail(ConnectDevice, err)
+	}
+	profile, err := firstProfile(ctx, dev)
+	if err != nil {
+		return "", "", fail(ConnectProfile, err)
+	}
+	if streamURL, err = dev.GetStreamURI(ctx, string(profile), StreamRTSP); err != nil {
+		return "", "", fail(ConnectStream, err)
+	}
+	if snapshotURL, err = dev.GetSnapshotURI(ctx, string(profile)); err != nil {
+		return streamURL, "", fail(ConnectSnapshot, err)
+	}
+	return streamURL, snapshotURL, nil
+}
@@ -0,0 +1,43 @@
+package onvif
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConnect(t *testing.T) {
+	fake := &scriptedDevice{answers: map[string]string{
+		"GetCapabilities": `<tds:GetCapabilitiesResponse><tds:Capabilities><tt:Media><tt:XAddr>http://10.0.0.1/onvif/media</tt:XAddr></tt:Media></tds:Capabilities></tds:GetCapabilitiesResponse>`,
+		"GetProfiles":     `<trt:GetProfilesResponse><trt:Profiles token="main"><tt:Name>main</tt:Name></trt:Profiles></trt:GetProfilesResponse>`,
+		"GetStreamUri":    `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://10.0.0.1:554/main</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`,
+		"GetSnapshotUri":  `<trt:GetSnapshotUriResponse><trt:MediaUri><tt:Uri>http://10.0.0.1/snapshot.jpg</tt:Uri></trt:MediaUri></trt:GetSnapshotUriResponse>`,
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+	/* 地址改写为访问设备的地址 */
+	stream, snapshot, err := Connect(address, "admin", "secret")
+	if err != nil || stream != "rtsp://127.0.0.1:554/main" || snapshot != "http://127.0.0.1/snapshot.jpg" {
+		t.Fatalf("stream %q, snapshot %q, %v", stream, snapshot, err)
+	}
+	if sent := fake.sent("GetStreamUri"); len(sent) != 1 || !strings.Contains(sent[0], "<trt:ProfileToken>main</trt:ProfileToken>") {
+		t.Fatalf("requests %q", sent)
+	}
+
+	/* 不提供快照的设备仍返回流地址 */
+	fake.set("GetSnapshotUri", `<trt:GetSnapshotUriResponse><trt:MediaUri><tt:Uri></tt:Uri></trt:MediaUri></trt:GetSnapshotUriResponse>`)
+	stream, _, err = Connect(address, "admin", "secret")
+	var connectErr *ConnectError
+	if !errors.As(err, &connectErr) || connectErr.Step != ConnectSnapshot || !errors.Is(err, ErrNoSnapshotURI) || stream == "" {
+		t.Fatalf("stream %q, %v", stream, err)
+	}
+	fake.set("GetProfiles", `<trt:GetProfilesResponse/>`)
+	if _, _, err := Connect(address, "admin", "secret"); !errors.As(err, &connectErr) || connectErr.Step != ConnectProfile || !errors.Is(err, ErrNoProfile) {
+		t.Fatalf("error %v", err)
+	}
+	if _, _, err := Connect("ftp://"+address, "admin", "secret"); !errors.As(err, &connectErr) || connectErr.Step != ConnectDevice {
+		t.Fatalf("error %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+// Package core sends the SOAP requests of the ONVIF services: it holds the
+// endpoints and credentials of a device, builds the envelopes with
+// WS-Security and decodes the responses and faults.
+//
+// The service packages device, media, ptz and events are typed clients over
+// a Caller, either a Client of this package or an onvif.Device, which adds
+// the capability cache, failover, audit and quarantine of the onvif package.
+package core
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/soap"
+)
+
+// Endpoint keys of the services, the lower case name of the package holding
+// the types of the service
+const (
+	ServiceDevice        = "device"
+	ServiceMedia         = "media"
+	ServiceMedia2        = "media2"
+	ServiceEvents        = "events"
+	ServicePTZ           = "ptz"
+	ServiceImaging       = "imaging"
+	ServiceAnalytics     = "analytics"
+	ServiceDeviceIO      = "deviceio"
+	ServiceRecording     = "recording"
+	ServiceSearch        = "search"
+	ServiceReplay        = "replay"
+	ServiceAccessControl = "accesscontrol"
+	ServiceCredential    = "credential"
+	ServiceSchedule      = "schedule"
+	// ServiceAdvancedSecurity keystore, certificates and media signing
+	ServiceAdvancedSecurity = "advancedsecurity"
+)
+
+// Namespaces declared on the envelope of the requests
+var Namespaces = map[string]string{
+	"onvif":   "http://www.onvif.org/ver10/schema",
+	"tt":      "http://www.onvif.org/ver10/schema",
+	"tns1":    "http://www.onvif.org/ver10/topics",
+	"tds":     "http://www.onvif.org/ver10/device/wsdl",
+	"trt":     "http://www.onvif.org/ver10/media/wsdl",
+	"tr2":     "http://www.onvif.org/ver20/media/wsdl",
+	"tev":     "http://www.onvif.org/ver10/events/wsdl",
+	"tptz":    "http://www.onvif.org/ver20/ptz/wsdl",
+	"timg":    "http://www.onvif.org/ver20/imaging/wsdl",
+	"tan":     "http://www.onvif.org/ver20/analytics/wsdl",
+	"xmime":   "http://www.w3.org/2005/05/xmlmime",
+	"wsnt":    "http://docs.oasis-open.org/wsn/b-2",
+	"xop":     "http://www.w3.org/2004/08/xop/include",
+	"wsa":     "http://www.w3.org/2005/08/addressing",
+	"wstop":   "http://docs.oasis-open.org/wsn/t-1",
+	"wsntw":   "http://docs.oasis-open.org/wsn/bw-2",
+	"wsrf-rw": "http://docs.oasis-open.org/wsrf/rw-2",
+	"wsaw":    "http://www.w3.org/2006/05/addressing/wsdl",
+	"tac":     "http://www.onvif.org/ver10/accesscontrol/wsdl",
+	"tcr":     "http://www.onvif.org/ver10/credential/wsdl",
+	"tsc":     "http://www.onvif.org/ver10/schedule/wsdl",
+	"trc":     "http://www.onvif.org/ver10/recording/wsdl",
+	"tse":     "http://www.onvif.org/ver10/search/wsdl",
+	"trp":     "http://www.onvif.org/ver10/replay/wsdl",
+	"tas":     "http://www.onvif.org/ver10/advancedsecurity/wsdl",
+}
+
+// ContentType of the SOAP 1.2 requests
+const ContentType = "application/soap+xml; charset=utf-8"
+
+// Caller sends the requests of a service package. request is a type of the
+// types/... packages and response its Response type, taken by address.
+type Caller interface {
+	// Call send request to the service of the device, e.g. ServiceMedia
+	Call(ctx context.Context, service string, request, response interface{}) error
+	// CallAddress send request to address, e.g. a subscription manager,
+	// headers are sent after the WS-Security header
+	CallAddress(ctx context.Context, address string, request, response interface{}, headers ...[]byte) error
+}
+
+// ServiceError the device serves no endpoint for Service
+type ServiceError struct {
+	Service string
+}
+
+func (err *ServiceError) Error() string {
+	return fmt.Sprintf("%s service not served by the device", err.Service)
+}
+
+// Params of a Client
+type Params struct {
+	// XAddr address of the device service, e.g.
+	// http://192.168.1.10/onvif/device_service
+	XAddr    string
+	Username string
+	Password string
+	// HTTPClient sends the requests, a client with a 10 seconds timeout
+	// when nil
+	HTTPClient *http.Client
+}
+
+// Client Caller sending the requests to the endpoints of a device, without
+// the caches and policies of onvif.Device
+type Client struct {
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mutex     sync.RWMutex
+	endpoints map[string]string
+}
+
+// NewClient return a client of the device of params, only the device
+// service is known until SetEndpoint, see device.Client.LoadEndpoints
+func NewClient(params Params) (*Client, error) {
+	if params.XAddr == "" {
+		return nil, errors.New("device service address required")
+	}
+	client := &Client{
+		username:   params.Username,
+		password:   params.Password,
+		httpClient: params.HTTPClient,
+		endpoints:  map[string]string{ServiceDevice: params.XAddr},
+	}
+	if client.httpClient == nil {
+		client.httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return client, nil
+}
+
+// SetEndpoint set the address of the service, the key is case insensitive
+func (client *Client) SetEndpoint(service, xaddr string) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.endpoints[strings.ToLower(service)] = xaddr
+}
+
+// Endpoint return the address of the service, a ServiceError when the
+// device does not serve it
+func (client *Client) Endpoint(service string) (string, error) {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+	xaddr, ok := client.endpoints[strings.ToLower(service)]
+	if !ok {
+		return "", &ServiceError{Service: service}
+	}
+	return xaddr, nil
+}
+
+// Call send request to the endpoint of service and decode the response
+func (client *Client) Call(ctx context.Context, service string, request, response interface{}) error {
+	endpoint, err := client.Endpoint(service)
+	if err != nil {
+		return err
+	}
+	return client.CallAddress(ctx, endpoint, request, response)
+}
+
+// CallAddress send request to address and decode the response, a
+// FaultError when the device answers a fault
+func (client *Client) CallAddress(ctx context.Context, address string, request, response interface{}, headers ...[]byte) error {
+	var security *soap.Security
+	if client.username != "" && client.password != "" {
+		auth, err := soap.NewSecurity(client.username, client.password)
+		if err != nil {
+			return err
+		}
+		security = &auth
+	}
+	buf, err := soap.BuildEnvelopeSecurity(Namespaces, request, security, headers...)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, nil)
+	if err != nil {
+		soap.PutBuffer(buf)
+		return err
+	}
+	/* 缓冲区在响应读取完毕后回收 */
+	requestBody := soap.NewBufferRequest(buf)
+	defer requestBody.Release()
+	req.ContentLength = int64(buf.Len())
+	req.Body, req.GetBody = requestBody.Body(), requestBody.GetBody
+	req.Header.Set("Content-Type", ContentType)
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	message, err := soap.ReadLimited(resp.Body, soap.DefaultLimits)
+	if err != nil {
+		return err
+	}
+	/* 错误状态码的应答优先返回其fault */
+	body, err := ResponseBody(message)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("response status %s", resp.Status)
+	}
+	return xml.Unmarshal(body, response)
+}
+
+// serviceKeys endpoint key of the services by WSDL namespace
+var serviceKeys = map[string]string{
+	"http://www.onvif.org/ver10/device/wsdl":           ServiceDevice,
+	"http://www.onvif.org/ver10/media/wsdl":            ServiceMedia,
+	"http://www.onvif.org/ver20/media/wsdl":            ServiceMedia2,
+	"http://www.onvif.org/ver10/events/wsdl":           ServiceEvents,
+	"http://www.onvif.org/ver20/ptz/wsdl":              ServicePTZ,
+	"http://www.onvif.org/ver20/imaging/wsdl":          ServiceImaging,
+	"http://www.onvif.org/ver20/analytics/wsdl":        ServiceAnalytics,
+	"http://www.onvif.org/ver10/deviceIO/wsdl":         ServiceDeviceIO,
+	"http://www.onvif.org/ver10/recording/wsdl":        ServiceRecording,
+	"http://www.onvif.org/ver10/search/wsdl":           ServiceSearch,
+	"http://www.onvif.org/ver10/replay/wsdl":           ServiceReplay,
+	"http://www.onvif.org/ver10/accesscontrol/wsdl":    ServiceAccessControl,
+	"http://www.onvif.org/ver10/credential/wsdl":       ServiceCredential,
+	"http://www.onvif.org/ver10/schedule/wsdl":         ServiceSchedule,
+	"http://www.onvif.org/ver10/advancedsecurity/wsdl": ServiceAdvancedSecurity,
+}
+
+// ServiceKey return the endpoint key of the service of the WSDL namespace,
+// e.g. ServiceMedia2 for http://www.onvif.org/ver20/media/wsdl
+func ServiceKey(namespace string) (string, bool) {
+	key, ok := serviceKeys[namespace]
+	return key, ok
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type getHostname struct {
+	XMLName string `xml:"tds:GetHostname"`
+}
+
+type getHostnameResponse struct {
+	HostnameInformation struct {
+		Name string
+	}
+}
+
+const envelope = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:tds="http://www.onvif.org/ver10/device/wsdl"><s:Body>%s</s:Body></s:Envelope>`
+
+func TestClientCall(t *testing.T) {
+	var request string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		request = string(body)
+		w.Write([]byte(fmt.Sprintf(envelope, `<tds:GetHostnameResponse><tds:HostnameInformation><tt:Name>camera</tt:Name></tds:HostnameInformation></tds:GetHostnameResponse>`)))
+	}))
+	defer server.Close()
+	client, err := NewClient(Params{XAddr: server.URL, Username: "admin", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := getHostnameResponse{}
+	if err := client.Call(context.Background(), "Device", getHostname{}, &response); err != nil || response.HostnameInformation.Name != "camera" {
+		t.Fatalf("hostname %q, %v", response.HostnameInformation.Name, err)
+	}
+	if !strings.Contains(request, "<tds:GetHostname>") || !strings.Contains(request, "UsernameToken") {
+		t.Fatalf("request %s", request)
+	}
+	/* 未知服务不发送请求 */
+	var missing *ServiceError
+	if err := client.Call(context.Background(), ServiceMedia, getHostname{}, &response); !errors.As(err, &missing) || missing.Service != ServiceMedia {
+		t.Fatalf("error %v for a missing service", err)
+	}
+}
+
+func TestClientFault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf(envelope, `<s:Fault><s:Code><s:Value>s:Sender</s:Value><s:Subcode><s:Value>ter:InvalidArgVal</s:Value>`+
+			`<s:Subcode><s:Value>ter:NoProfile</s:Value></s:Subcode></s:Subcode></s:Code><s:Reason><s:Text>no such profile</s:Text></s:Reason></s:Fault>`)))
+	}))
+	defer server.Close()
+	client, _ := NewClient(Params{XAddr: server.URL})
+	err := client.Call(context.Background(), ServiceDevice, getHostname{}, &getHostnameResponse{})
+	var fault *FaultError
+	if !errors.As(err, &fault) || fault.Code != "Sender" || !fault.HasSubcode("NoProfile") || fault.Error() != "no such profile" {
+		t.Fatalf("error %v", err)
+	}
+	if _, err := NewClient(Params{}); err == nil {
+		t.Fatal("client without device service address")
+	}
+}
+
+func TestServiceKey(t *testing.T) {
+	if key, ok := ServiceKey("http://www.onvif.org/ver20/media/wsdl"); !ok || key != ServiceMedia2 {
+		t.Fatalf("key %q", key)
+	}
+	if _, ok := ServiceKey("http://example.com/wsdl"); ok {
+		t.Fatal("key of an unknown namespace")
+	}
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/soap"
+)
+
+// FaultError SOAP fault returned by a device. Subcodes are the nested
+// subcodes from the outermost, without their namespace prefix, e.g.
+// InvalidArgVal then InvalidTimeZone.
+type FaultError struct {
+	Code     string
+	Subcodes []string
+	Reason   string
+}
+
+func (err *FaultError) Error() string {
+	if err.Reason == "" {
+		return "fault " + err.Code
+	}
+	return err.Reason
+}
+
+// HasSubcode report whether the fault carries one of the subcodes, compared
+// without namespace prefix
+func (err *FaultError) HasSubcode(subcodes ...string) bool {
+	for _, subcode := range err.Subcodes {
+		for _, want := range subcodes {
+			if subcode == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FaultCode SOAP 1.2 fault code with its nested subcodes
+type FaultCode struct {
+	Value   string     `xml:"Value"`
+	Subcode *FaultCode `xml:"Subcode"`
+}
+
+// Fault SOAP 1.2 Fault element
+type Fault struct {
+	XMLName xml.Name  `xml:"Fault"`
+	Code    FaultCode `xml:"Code"`
+	Reason  string    `xml:"Reason>Text"`
+}
+
+// Err return the FaultError of the fault, nil when it is empty
+func (fault Fault) Err() error {
+	if fault.Reason == "" && fault.Code.Value == "" {
+		return nil
+	}
+	err := &FaultError{Code: localName(strings.TrimSpace(fault.Code.Value)), Reason: strings.TrimSpace(fault.Reason)}
+	for subcode := fault.Code.Subcode; subcode != nil; subcode = subcode.Subcode {
+		err.Subcodes = append(err.Subcodes, localName(strings.TrimSpace(subcode.Value)))
+	}
+	return err
+}
+
+// CheckFault return the FaultError of body when it is a fault
+func CheckFault(body []byte) error {
+	fault := Fault{}
+	if xml.Unmarshal(body, &fault) != nil {
+		return nil
+	}
+	return fault.Err()
+}
+
+// ResponseBody check the response of a device and return the content of its
+// Body, or the error of the fault it holds
+func ResponseBody(message []byte) ([]byte, error) {
+	if err := soap.CheckXML(message, soap.DefaultLimits); err != nil {
+		return nil, err
+	}
+	/* 按命名空间提取Body数据,不依赖设备使用的前缀 */
+	body, err := soap.Body(message)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckFault(body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Decode check the response of a device and decode its body into response
+func Decode(message []byte, response interface{}) error {
+	body, err := ResponseBody(message)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(body, response)
+}
+
+func localName(name string) string {
+	if index := strings.LastIndex(name, ":"); index >= 0 {
+		return name[index+1:]
+	}
+	return name
+}
@@ -0,0 +1,296 @@
+package onvif
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/credential"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Identifier formats used by CardIdentifier and PINIdentifier, devices list
+// the formats they accept in the SupportedIdentifierType capability
+var (
+	CardFormatType = "SIMPLE_NUMBER32"
+	PINFormatType  = "SIMPLE_ALPHA_NUMERIC"
+)
+
+// CardImport one card to import with ImportCards
+type CardImport struct {
+	// Holder reference of the credential holder, e.g. an employee number
+	Holder string
+	Card   uint32
+	// PIN optional PIN required with the card
+	PIN string
+	// ValidFrom and ValidTo optional validity window of the credential
+	ValidFrom time.Time
+	ValidTo   time.Time
+	// AccessProfiles tokens of the access profiles granted to the card
+	AccessProfiles []string
+}
+
+// CardIdentifier return the identifier of a card number
+func CardIdentifier(card uint32) credential.CredentialIdentifier {
+	return credential.CredentialIdentifier{
+		Type:  credential.IdentifierType{Name: "pt:Card", FormatType: xsd.String(CardFormatType)},
+		Value: xsd.String(fmt.Sprintf("%08X", card)),
+	}
+}
+
+// PINIdentifier return the identifier of a PIN, the digits are sent as
+// characters so leading zeros are kept
+func PINIdentifier(pin string) credential.CredentialIdentifier {
+	return credential.CredentialIdentifier{
+		Type:  credential.IdentifierType{Name: "pt:PIN", FormatType: xsd.String(PINFormatType)},
+		Value: xsd.String(strings.ToUpper(hex.EncodeToString([]byte(pin)))),
+	}
+}
+
+/* 凭证有效期使用UTC时间 */
+func credentialTime(t time.Time) xsd.String {
+	if t.IsZero() {
+		return ""
+	}
+	return xsd.String(xsd.FormatDateTime(t.Truncate(time.Second)))
+}
+
+// CredentialCapabilities return the capabilities of the credential service, a
+// NotSupportedError is returned when the device has none
+func (dev *Device) CredentialCapabilities(ctx context.Context) (credential.Capabilities, error) {
+	if _, err := dev.getEndpoint("credential"); err != nil {
+		return credential.Capabilities{}, &NotSupportedError{Service: "credential"}
+	}
+	response := credential.GetServiceCapabilitiesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, credential.GetServiceCapabilities{}, &response, ""); err != nil {
+		return credential.Capabilities{}, err
+	}
+	return response.Capabilities, nil
+}
+
+// Credentials return every credential of the device, read in pages of the
+// MaxLimit advertised by the service
+func (dev *Device) Credentials(ctx context.Context) ([]credential.CredentialInfo, error) {
+	capabilities, err := dev.CredentialCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dev.credentials(ctx, capabilities)
+}
+
+func (dev *Device) credentials(ctx context.Context, capabilities credential.Capabilities) ([]credential.CredentialInfo, error) {
+	var credentials []credential.CredentialInfo
+	err := dev.credentialPages(ctx, capabilities, func(page []credential.CredentialInfo) bool {
+		credentials = append(credentials, page...)
+		return true
+	})
+	return credentials, err
+}
+
+// credentialPages call page with the pages of credentials of the device until
+// it returns false
+func (dev *Device) credentialPages(ctx context.Context, capabilities credential.Capabilities, page func([]credential.CredentialInfo) bool) error {
+	request := credential.GetCredentialInfoList{Limit: capabilities.MaxLimit}
+	for {
+		response := credential.GetCredentialInfoListResponse{}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			return err
+		}
+		if !page(response.CredentialInfo) {
+			return nil
+		}
+		if response.NextStartReference == "" || response.NextStartReference == request.StartReference {
+			return nil
+		}
+		request.StartReference = response.NextStartReference
+	}
+}
+
+// ImportCards create one enabled credential per card and return their tokens.
+// The credential service creates one credential per request: the cards are
+// sent in batches of the MaxLimit of the service, the requests of a batch in
+// parallel. The cards are checked against the capabilities before any is
+// created, and nothing is created when they exceed the free capacity of the
+// device. On a failure no further batch is sent and the tokens created so far
+// are returned, in the order of the cards, with the error of the first card
+// which failed.
+func (dev *Device) ImportCards(ctx context.Context, cards []CardImport) ([]string, error) {
+	capabilities, err := dev.CredentialCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]credential.CreateCredential, len(cards))
+	for i, card := range cards {
+		if (!card.ValidFrom.IsZero() || !card.ValidTo.IsZero()) && !capabilities.CredentialValiditySupported {
+			return nil, &NotSupportedError{Service: "credential", Capability: "CredentialValidity"}
+		}
+		if capabilities.MaxAccessProfilesPerCredential > 0 && len(card.AccessProfiles) > capabilities.MaxAccessProfilesPerCredential {
+			return nil, fmt.Errorf("card %d: %d access profiles exceed the maximum of %d", i, len(card.AccessProfiles), capabilities.MaxAccessProfilesPerCredential)
+		}
+		requests[i] = cardCredential(card)
+	}
+	if err := dev.checkCredentialCapacity(ctx, capabilities, len(cards)); err != nil {
+		return nil, err
+	}
+	batch := capabilities.MaxLimit
+	if batch <= 0 {
+		batch = 1
+	}
+	created := make([]string, len(cards))
+	errs := make([]error, len(cards))
+	for start := 0; start < len(cards); start += batch {
+		end := start + batch
+		if end > len(cards) {
+			end = len(cards)
+		}
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				response := credential.CreateCredentialResponse{}
+				if err := dev.CallMethodInterfaceContext(ctx, requests[i], &response, ""); err != nil {
+					errs[i] = fmt.Errorf("card %d: %w", i, err)
+					return
+				}
+				created[i] = string(response.Token)
+			}(i)
+		}
+		wg.Wait()
+		for _, err := range errs[start:end] {
+			if err != nil {
+				return createdTokens(created), err
+			}
+		}
+	}
+	return created, nil
+}
+
+// checkCredentialCapacity fail when count credentials more do not fit in the
+// MaxCredentials of the device. The credentials are counted page by page and
+// only until the free capacity is known to be too small.
+func (dev *Device) checkCredentialCapacity(ctx context.Context, capabilities credential.Capabilities, count int) error {
+	if capabilities.MaxCredentials <= 0 {
+		return nil
+	}
+	limit := capabilities.MaxCredentials - count
+	if limit < 0 {
+		return fmt.Errorf("importing %d cards exceeds the capacity of %d credentials", count, capabilities.MaxCredentials)
+	}
+	existing := 0
+	err := dev.credentialPages(ctx, capabilities, func(page []credential.CredentialInfo) bool {
+		existing += len(page)
+		return existing <= limit
+	})
+	if err != nil {
+		return err
+	}
+	if existing > limit {
+		return fmt.Errorf("importing %d cards exceeds the capacity of %d credentials, at least %d in use", count, capabilities.MaxCredentials, existing)
+	}
+	return nil
+}
+
+// cardCredential CreateCredential request of an enabled credential of the card
+func cardCredential(card CardImport) credential.CreateCredential {
+	request := credential.CreateCredential{
+		Credential: credential.Credential{
+			CredentialHolderReference: xsd.String(card.Holder),
+			ValidFrom:                 credentialTime(card.ValidFrom),
+			ValidTo:                   credentialTime(card.ValidTo),
+			CredentialIdentifier:      []credential.CredentialIdentifier{CardIdentifier(card.Card)},
+		},
+		State: credential.CredentialState{Enabled: true},
+	}
+	if card.PIN != "" {
+		request.Credential.CredentialIdentifier = append(request.Credential.CredentialIdentifier, PINIdentifier(card.PIN))
+	}
+	for _, profile := range card.AccessProfiles {
+		request.Credential.CredentialAccessProfile = append(request.Credential.CredentialAccessProfile,
+			credential.CredentialAccessProfile{AccessProfileToken: onvif.ReferenceToken(profile)})
+	}
+	return request
+}
+
+/* 去掉创建失败的卡对应的空令牌 */
+func createdTokens(created []string) []string {
+	tokens := make([]string, 0, len(created))
+	for _, token := range created {
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// SetPIN set or replace the PIN of a credential
+func (dev *Device) SetPIN(ctx context.Context, token, pin string) error {
+	request := credential.SetCredentialIdentifier{
+		CredentialToken:      onvif.ReferenceToken(token),
+		CredentialIdentifier: PINIdentifier(pin),
+	}
+	return dev.CallMethodInterfaceContext(ctx, request, &credential.SetCredentialIdentifierResponse{}, "")
+}
+
+// EnableCredentials whitelist the credentials
+func (dev *Device) EnableCredentials(ctx context.Context, reason string, tokens ...string) error {
+	for _, token := range tokens {
+		request := credential.EnableCredential{Token: onvif.ReferenceToken(token), Reason: xsd.String(reason)}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &credential.EnableCredentialResponse{}, ""); err != nil {
+			return fmt.Errorf("enable credential %s: %w", token, err)
+		}
+	}
+	return nil
+}
+
+// DisableCredentials blacklist the credentials
+func (dev *Device) DisableCredentials(ctx context.Context, reason string, tokens ...string) error {
+	for _, token := range tokens {
+		request := credential.DisableCredential{Token: onvif.ReferenceToken(token), Reason: xsd.String(reason)}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &credential.DisableCredentialResponse{}, ""); err != nil {
+			return fmt.Errorf("disable credential %s: %w", token, err)
+		}
+	}
+	return nil
+}
+
+// ApplyValidityWindows enable the credentials whose validity window holds now
+// and disable the others, for devices which do not enforce the window
+// themselves. Credentials without a window are left untouched.
+func (dev *Device) ApplyValidityWindows(ctx context.Context, now time.Time) (enabled, disabled []string, err error) {
+	credentials, err := dev.Credentials(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, info := range credentials {
+		from, fromErr := xsd.ParseDateTime(string(info.ValidFrom))
+		to, toErr := xsd.ParseDateTime(string(info.ValidTo))
+		hasFrom, hasTo := fromErr == nil, toErr == nil
+		if !hasFrom && !hasTo {
+			continue
+		}
+		valid := (!hasFrom || !now.Before(from)) && (!hasTo || now.Before(to))
+		state := credential.GetCredentialStateResponse{}
+		if err := dev.CallMethodInterfaceContext(ctx, credential.GetCredentialState{Token: info.Token}, &state, ""); err != nil {
+			return enabled, disabled, err
+		}
+		token := string(info.Token)
+		switch {
+		case valid && !bool(state.State.Enabled):
+			if err := dev.EnableCredentials(ctx, "validity window opened", token); err != nil {
+				return enabled, disabled, err
+			}
+			enabled = append(enabled, token)
+		case !valid && bool(state.State.Enabled):
+			if err := dev.DisableCredentials(ctx, "validity window closed", token); err != nil {
+				return enabled, disabled, err
+			}
+			disabled = append(disabled, token)
+		}
+	}
+	return enabled, disabled, nil
+}
@@ -0,0 +1,105 @@
+package onvif
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+/* 凭证服务: MaxLimit为2,已有existing个凭证,按页返回 */
+type credentialDevice struct {
+	mutex    sync.Mutex
+	existing int
+	max      int
+	pages    int
+	created  int
+	// fail CreateCredential of the holder fails
+	fail string
+}
+
+func (fake *credentialDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := ioutil.ReadAll(r.Body)
+	request := string(data)
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	body := ""
+	switch {
+	case strings.Contains(request, "GetServiceCapabilities"):
+		body = fmt.Sprintf(`<tcr:GetServiceCapabilitiesResponse><tcr:Capabilities MaxLimit="2" MaxCredentials="%d"/></tcr:GetServiceCapabilitiesResponse>`, fake.max)
+	case strings.Contains(request, "GetCredentialInfoList"):
+		/* 第n页从第2n个凭证开始 */
+		start := fake.pages * 2
+		fake.pages++
+		body = `<tcr:GetCredentialInfoListResponse>`
+		for i := start; i < start+2 && i < fake.existing; i++ {
+			body += fmt.Sprintf(`<tcr:CredentialInfo token="c%d"/>`, i)
+		}
+		if start+2 < fake.existing {
+			body += fmt.Sprintf(`<tcr:NextStartReference>%d</tcr:NextStartReference>`, start+2)
+		}
+		body += `</tcr:GetCredentialInfoListResponse>`
+	case strings.Contains(request, "CreateCredential"):
+		if fake.fail != "" && strings.Contains(request, ">"+fake.fail+"<") {
+			w.WriteHeader(http.StatusInternalServerError)
+			body = `<s:Fault><s:Reason><s:Text>CapacityExceeded</s:Text></s:Reason></s:Fault>`
+			break
+		}
+		fake.created++
+		body = fmt.Sprintf(`<tcr:CreateCredentialResponse><tcr:Token>new%d</tcr:Token></tcr:CreateCredentialResponse>`, fake.created)
+	}
+	w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tcr="http://www.onvif.org/ver10/credential/wsdl"><s:Body>` + body + `</s:Body></s:Envelope>`))
+}
+
+func newCredentialDevice(t *testing.T, fake *credentialDevice) *Device {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://")})
+	dev.endpoints[ServiceDevice] = server.URL
+	dev.endpoints[ServiceCredential] = server.URL
+	return dev
+}
+
+func holders(count int) []CardImport {
+	cards := make([]CardImport, count)
+	for i := range cards {
+		cards[i] = CardImport{Holder: fmt.Sprintf("holder%d", i), Card: uint32(i)}
+	}
+	return cards
+}
+
+func TestImportCardsCapacity(t *testing.T) {
+	/* 容量不足时读到超出为止,不读完整个列表 */
+	fake := &credentialDevice{existing: 100, max: 105}
+	dev := newCredentialDevice(t, fake)
+	tokens, err := dev.ImportCards(context.Background(), holders(10))
+	if err == nil || len(tokens) != 0 || fake.created != 0 {
+		t.Fatalf("tokens %v, created %d, error %v", tokens, fake.created, err)
+	}
+	if fake.pages != 48 {
+		t.Fatalf("%d pages read to find 96 credentials in use", fake.pages)
+	}
+	fake = &credentialDevice{existing: 3, max: 10}
+	dev = newCredentialDevice(t, fake)
+	tokens, err = dev.ImportCards(context.Background(), holders(5))
+	if err != nil || len(tokens) != 5 || fake.created != 5 {
+		t.Fatalf("tokens %v, created %d, error %v", tokens, fake.created, err)
+	}
+}
+
+func TestImportCardsFailure(t *testing.T) {
+	/* 第二批失败后不再发送第三批 */
+	fake := &credentialDevice{fail: "holder3"}
+	dev := newCredentialDevice(t, fake)
+	tokens, err := dev.ImportCards(context.Background(), holders(6))
+	if err == nil || !strings.HasPrefix(err.Error(), "card 3:") {
+		t.Fatalf("error %v", err)
+	}
+	if len(tokens) != 3 || fake.created != 3 {
+		t.Fatalf("tokens %v, created %d", tokens, fake.created)
+	}
+}
@@ -0,0 +1,181 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd"
+)
+
+// Date and time modes of a device
+const (
+	DateTimeManual = "Manual"
+	DateTimeNTP    = "NTP"
+)
+
+// Settings of DateTimeSettings, as reported by DateTimeResult
+const (
+	DateTimeSettingMode            = "DateTimeType"
+	DateTimeSettingDaylightSavings = "DaylightSavings"
+	DateTimeSettingTimeZone        = "TimeZone"
+	DateTimeSettingTime            = "UTCDateTime"
+)
+
+// dateTimeTolerance difference between the device clock and the time set
+// still taken as applied, the request and the read back take time
+const dateTimeTolerance = 10 * time.Second
+
+// DateTimeSettings date and time settings applied by SetDateTime, the zero
+// fields keep the value of the device
+type DateTimeSettings struct {
+	// Mode DateTimeNTP or DateTimeManual
+	Mode            string
+	DaylightSavings *bool
+	// TimeZone POSIX 1003.1 time zone, e.g. CET-1CEST,M3.5.0,M10.5.0/3
+	TimeZone string
+	// Time UTC time set in manual mode, the host time when zero
+	Time time.Time
+}
+
+// DateTimeResult outcome of SetDateTime, read back from the device
+type DateTimeResult struct {
+	// Applied and Ignored the requested settings the device took and the ones
+	// it accepted but did not apply, e.g. DateTimeSettingTimeZone
+	Applied []string
+	Ignored []string
+	// Requests SetSystemDateAndTime requests sent, mode switches included
+	Requests int
+	// Sequenced the settings were applied through a switch to manual mode
+	Sequenced bool
+	Current   device.SystemDateTime
+}
+
+// DateTimeError failure of a step of SetDateTime, the device may be left in
+// manual mode when Step is the switch back to NTP
+type DateTimeError struct {
+	Step string
+	Err  error
+}
+
+func (err *DateTimeError) Error() string {
+	return fmt.Sprintf("set date and time, %s: %v", err.Step, err.Err)
+}
+
+// Unwrap return the error of the step
+func (err *DateTimeError) Unwrap() error {
+	return err.Err
+}
+
+// SetDateTime apply the date and time settings. Many firmwares reject a time
+// zone or daylight saving change while in NTP mode, or a single request
+// changing the mode along with them; when the single request is rejected
+// with an ActionNotSupported or InvalidArgs fault the device is switched to
+// manual mode, the time zone set there, and the device switched back to NTP.
+// Other failures, e.g. an invalid time zone, are returned as they are. The
+// settings are read back to report which ones the device actually applied.
+func (dev *Device) SetDateTime(ctx context.Context, settings DateTimeSettings) (DateTimeResult, error) {
+	result := DateTimeResult{}
+	current, err := dev.systemDateAndTime(ctx)
+	if err != nil {
+		return result, err
+	}
+	mode := settings.Mode
+	if mode == "" {
+		mode = current.DateTimeType
+	}
+	daylightSavings := current.DaylightSavings
+	if settings.DaylightSavings != nil {
+		daylightSavings = *settings.DaylightSavings
+	}
+	var timeZone *device.TimeZone
+	if settings.TimeZone != "" {
+		timeZone = &device.TimeZone{TZ: settings.TimeZone}
+	}
+	now := func() *device.DateTime {
+		t := settings.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		dateTime := device.NewDateTime(t.UTC())
+		return &dateTime
+	}
+	set := func(request device.SetSystemDateAndTime) error {
+		result.Requests++
+		return dev.CallMethodInterfaceContext(ctx, request, &device.SetSystemDateAndTimeResponse{}, "")
+	}
+
+	request := device.SetSystemDateAndTime{DateTimeType: mode, DaylightSavings: xsd.Boolean(daylightSavings), TimeZone: timeZone}
+	if mode == DateTimeManual {
+		request.UTCDateTime = now()
+	}
+	err = set(request)
+	/* 仅NTP模式下的时区和夏令时变更被设备拒绝时才需要切换模式 */
+	ntp := mode == DateTimeNTP || current.DateTimeType == DateTimeNTP
+	if ntp && rejectedInNTPMode(err) && (timeZone != nil || daylightSavings != current.DaylightSavings) {
+		result.Sequenced = true
+		manual := device.SetSystemDateAndTime{DateTimeType: DateTimeManual, DaylightSavings: xsd.Boolean(daylightSavings), TimeZone: timeZone, UTCDateTime: now()}
+		if err = set(manual); rejectedInNTPMode(err) && timeZone != nil {
+			/* 部分设备切换模式时不接受时区,分两步设置 */
+			manual.TimeZone = nil
+			if err = set(manual); err == nil {
+				manual.TimeZone, manual.UTCDateTime = timeZone, now()
+				err = set(manual)
+			}
+		}
+		if err != nil {
+			return result, &DateTimeError{Step: "switch to manual mode", Err: err}
+		}
+		if mode == DateTimeNTP {
+			back := device.SetSystemDateAndTime{DateTimeType: DateTimeNTP, DaylightSavings: xsd.Boolean(daylightSavings)}
+			if err = set(back); err != nil {
+				return result, &DateTimeError{Step: "switch back to NTP mode", Err: err}
+			}
+		}
+	}
+	if err != nil {
+		return result, &DateTimeError{Step: "set " + mode + " mode", Err: err}
+	}
+
+	if result.Current, err = dev.systemDateAndTime(ctx); err != nil {
+		return result, err
+	}
+	result.check(DateTimeSettingMode, settings.Mode != "", result.Current.DateTimeType == mode)
+	result.check(DateTimeSettingDaylightSavings, settings.DaylightSavings != nil, result.Current.DaylightSavings == daylightSavings)
+	result.check(DateTimeSettingTimeZone, timeZone != nil, strings.EqualFold(strings.TrimSpace(result.Current.TimeZone.TZ), strings.TrimSpace(settings.TimeZone)))
+	if mode == DateTimeManual && !settings.Time.IsZero() {
+		deviceTime := result.Current.UTCDateTime.ToTime(time.UTC)
+		drift := deviceTime.Sub(settings.Time)
+		result.check(DateTimeSettingTime, true, drift < dateTimeTolerance && drift > -dateTimeTolerance)
+	}
+	return result, nil
+}
+
+// rejectedInNTPMode report whether err is the fault of a device refusing the
+// settings in NTP mode, as opposed to refusing their values
+func rejectedInNTPMode(err error) bool {
+	var fault *FaultError
+	return errors.As(err, &fault) && fault.HasSubcode("ActionNotSupported", "InvalidArgs") && !fault.HasSubcode("InvalidTimeZone")
+}
+
+// check record whether the setting, when requested, was applied
+func (result *DateTimeResult) check(setting string, requested, applied bool) {
+	switch {
+	case !requested:
+	case applied:
+		result.Applied = append(result.Applied, setting)
+	default:
+		result.Ignored = append(result.Ignored, setting)
+	}
+}
+
+func (dev *Device) systemDateAndTime(ctx context.Context) (device.SystemDateTime, error) {
+	response := device.GetSystemDateAndTimeResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetSystemDateAndTime{}, &response, ""); err != nil {
+		return device.SystemDateTime{}, err
+	}
+	return response.SystemDateAndTime, nil
+}
@@ -0,0 +1,151 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/soap"
+)
+
+/* 模拟时钟设置: rejectInNTP时NTP模式下拒绝时区和夏令时变更,rejectOnSwitch时切换模式的请求不接受时区,ignoreTimeZone时接受但不应用时区 */
+type clockDevice struct {
+	scriptedDevice
+	mode           string
+	daylight       bool
+	timeZone       string
+	utc            time.Time
+	rejectInNTP    bool
+	rejectOnSwitch bool
+	ignoreTimeZone bool
+
+	clockMutex sync.Mutex
+}
+
+var clockField = regexp.MustCompile(`<(?:\w+:)?(DateTimeType|DaylightSavings|TZ|Hour|Minute|Second|Year|Month|Day)>([^<]*)<`)
+
+const actionNotSupported = `<s:Fault><s:Code><s:Value>s:Receiver</s:Value><s:Subcode><s:Value>ter:ActionNotSupported</s:Value></s:Subcode></s:Code>` +
+	`<s:Reason><s:Text xml:lang="en">not supported in NTP mode</s:Text></s:Reason></s:Fault>`
+
+func (fake *clockDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	fake.clockMutex.Lock()
+	defer fake.clockMutex.Unlock()
+	if soap.Operation(string(data)) == "SetSystemDateAndTime" {
+		fake.set("SetSystemDateAndTime", fake.apply(string(data)))
+	}
+	number, at := strconv.Itoa, fake.utc
+	fake.set("GetSystemDateAndTime", `<tds:GetSystemDateAndTimeResponse><tds:SystemDateAndTime><tt:DateTimeType>`+fake.mode+`</tt:DateTimeType>`+
+		`<tt:DaylightSavings>`+strconv.FormatBool(fake.daylight)+`</tt:DaylightSavings><tt:TimeZone><tt:TZ>`+fake.timeZone+`</tt:TZ></tt:TimeZone><tt:UTCDateTime>`+
+		`<tt:Time><tt:Hour>`+number(at.Hour())+`</tt:Hour><tt:Minute>`+number(at.Minute())+`</tt:Minute><tt:Second>`+number(at.Second())+`</tt:Second></tt:Time>`+
+		`<tt:Date><tt:Year>`+number(at.Year())+`</tt:Year><tt:Month>`+number(int(at.Month()))+`</tt:Month><tt:Day>`+number(at.Day())+`</tt:Day></tt:Date>`+
+		`</tt:UTCDateTime></tds:SystemDateAndTime></tds:GetSystemDateAndTimeResponse>`)
+	fake.scriptedDevice.ServeHTTP(w, r)
+}
+
+/* 调用方持有clockMutex */
+func (fake *clockDevice) apply(request string) string {
+	fields := map[string]int{}
+	values := map[string]string{}
+	for _, match := range clockField.FindAllStringSubmatch(request, -1) {
+		values[match[1]] = match[2]
+		fields[match[1]], _ = strconv.Atoi(match[2])
+	}
+	mode, timeZone, hasTimeZone := values["DateTimeType"], values["TZ"], strings.Contains(request, "TimeZone>")
+	daylight := values["DaylightSavings"] == "true"
+	changes := hasTimeZone || daylight != fake.daylight
+	if timeZone == "bogus" {
+		return `<s:Fault><s:Code><s:Value>s:Sender</s:Value><s:Subcode><s:Value>ter:InvalidArgVal</s:Value><s:Subcode><s:Value>ter:InvalidTimeZone</s:Value></s:Subcode></s:Subcode></s:Code>` +
+			`<s:Reason><s:Text xml:lang="en">invalid time zone</s:Text></s:Reason></s:Fault>`
+	}
+	if fake.rejectInNTP && fake.mode == DateTimeNTP && mode == DateTimeNTP && changes {
+		return actionNotSupported
+	}
+	if fake.rejectOnSwitch && mode != fake.mode && hasTimeZone {
+		return actionNotSupported
+	}
+	fake.mode, fake.daylight = mode, daylight
+	if hasTimeZone && !fake.ignoreTimeZone {
+		fake.timeZone = timeZone
+	}
+	if _, ok := values["Year"]; ok {
+		fake.utc = time.Date(fields["Year"], time.Month(fields["Month"]), fields["Day"], fields["Hour"], fields["Minute"], fields["Second"], 0, time.UTC)
+	}
+	return `<tds:SetSystemDateAndTimeResponse/>`
+}
+
+func newClockDevice(t *testing.T, fake *clockDevice) *Device {
+	fake.answers = map[string]string{}
+	fake.mode, fake.timeZone, fake.utc = DateTimeNTP, "UTC0", time.Now().UTC()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://")})
+	dev.endpoints[ServiceDevice] = server.URL + "/onvif/device_service"
+	dev.capabilities.servicesLoaded = true
+	return dev
+}
+
+const parisTimeZone = "CET-1CEST,M3.5.0,M10.5.0/3"
+
+func TestSetDateTime(t *testing.T) {
+	ctx := context.Background()
+	dev := newClockDevice(t, &clockDevice{})
+	result, err := dev.SetDateTime(ctx, DateTimeSettings{TimeZone: parisTimeZone})
+	if err != nil || result.Requests != 1 || result.Sequenced || strings.Join(result.Applied, ",") != DateTimeSettingTimeZone || len(result.Ignored) != 0 {
+		t.Fatalf("result %+v, %v", result, err)
+	}
+
+	at := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	result, err = dev.SetDateTime(ctx, DateTimeSettings{Mode: DateTimeManual, Time: at})
+	if err != nil || strings.Join(result.Applied, ",") != DateTimeSettingMode+","+DateTimeSettingTime || result.Current.DateTimeType != DateTimeManual {
+		t.Fatalf("result %+v, %v", result, err)
+	}
+
+	/* 接受但未应用的设置 */
+	dev = newClockDevice(t, &clockDevice{ignoreTimeZone: true})
+	result, err = dev.SetDateTime(ctx, DateTimeSettings{TimeZone: parisTimeZone})
+	if err != nil || len(result.Applied) != 0 || strings.Join(result.Ignored, ",") != DateTimeSettingTimeZone {
+		t.Fatalf("result %+v, %v", result, err)
+	}
+}
+
+func TestSetDateTimeSequenced(t *testing.T) {
+	ctx := context.Background()
+	/* NTP模式下拒绝时区变更: 切换到手动模式设置后切回NTP */
+	fake := &clockDevice{rejectInNTP: true}
+	dev := newClockDevice(t, fake)
+	daylight := true
+	result, err := dev.SetDateTime(ctx, DateTimeSettings{TimeZone: parisTimeZone, DaylightSavings: &daylight})
+	if err != nil || !result.Sequenced || result.Requests != 3 || strings.Join(result.Applied, ",") != DateTimeSettingDaylightSavings+","+DateTimeSettingTimeZone {
+		t.Fatalf("result %+v, %v", result, err)
+	}
+	if result.Current.DateTimeType != DateTimeNTP || result.Current.TimeZone.TZ != parisTimeZone {
+		t.Fatalf("current %+v", result.Current)
+	}
+
+	/* 切换模式时也不接受时区: 先切换再设置时区 */
+	fake = &clockDevice{rejectInNTP: true, rejectOnSwitch: true}
+	dev = newClockDevice(t, fake)
+	result, err = dev.SetDateTime(ctx, DateTimeSettings{TimeZone: parisTimeZone})
+	if err != nil || !result.Sequenced || result.Requests != 5 || strings.Join(result.Applied, ",") != DateTimeSettingTimeZone || result.Current.DateTimeType != DateTimeNTP {
+		t.Fatalf("result %+v, %v", result, err)
+	}
+
+	/* 无效时区不触发模式切换 */
+	result, err = dev.SetDateTime(ctx, DateTimeSettings{TimeZone: "bogus"})
+	var dateTimeErr *DateTimeError
+	var fault *FaultError
+	if !errors.As(err, &dateTimeErr) || dateTimeErr.Step != "set NTP mode" || !errors.As(err, &fault) || !fault.HasSubcode("InvalidTimeZone") || result.Requests != 1 {
+		t.Fatalf("result %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,122 @@
+// Package device is the client of the ONVIF device management service:
+// device information, services, clock, scopes, network and reboot.
+//
+// Connect returns a core.Client knowing the endpoints of every service of
+// the device, for the clients of the media, ptz and events packages:
+//
+//	client, err := device.Connect(ctx, core.Params{XAddr: "http://192.168.1.10/onvif/device_service", Username: "admin", Password: "password"})
+//	profiles, err := media.New(client).GetProfiles(ctx)
+package device
+
+import (
+	"context"
+
+	"github.com/PolarisM78/go-onvif/core"
+	tds "github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Client of the device management service of a device
+type Client struct {
+	caller core.Caller
+}
+
+// New return the device service client sending its requests through caller
+func New(caller core.Caller) *Client {
+	return &Client{caller: caller}
+}
+
+// Connect return a client of the device of params with the endpoints of
+// the services listed by GetServices
+func Connect(ctx context.Context, params core.Params) (*core.Client, error) {
+	client, err := core.NewClient(params)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := New(client).Endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for key, xaddr := range endpoints {
+		client.SetEndpoint(key, xaddr)
+	}
+	return client, nil
+}
+
+// GetServices return the services of the device, with their capabilities
+// when includeCapability is set
+func (client *Client) GetServices(ctx context.Context, includeCapability bool) ([]tds.Service, error) {
+	response := tds.GetServicesResponse{}
+	err := client.caller.Call(ctx, core.ServiceDevice, tds.GetServices{IncludeCapability: xsd.Boolean(includeCapability)}, &response)
+	return response.Service, err
+}
+
+// Endpoints return the addresses of the services of the device listed by
+// GetServices by endpoint key, e.g. core.ServiceMedia. The services of
+// unknown namespaces are left out.
+func (client *Client) Endpoints(ctx context.Context) (map[string]string, error) {
+	services, err := client.GetServices(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make(map[string]string, len(services))
+	for _, service := range services {
+		if key, ok := core.ServiceKey(string(service.Namespace)); ok && service.XAddr != "" {
+			endpoints[key] = string(service.XAddr)
+		}
+	}
+	return endpoints, nil
+}
+
+// GetCapabilities return the capabilities of the device in category,
+// onvif.CapabilityCategoryAll for every service
+func (client *Client) GetCapabilities(ctx context.Context, category onvif.CapabilityCategory) (onvif.Capabilities, error) {
+	response := tds.GetCapabilitiesResponse{}
+	err := client.caller.Call(ctx, core.ServiceDevice, tds.GetCapabilities{Category: category}, &response)
+	return response.Capabilities, err
+}
+
+// GetDeviceInformation return the manufacturer, model, firmware and serial
+// number of the device
+func (client *Client) GetDeviceInformation(ctx context.Context) (tds.GetDeviceInformationResponse, error) {
+	response := tds.GetDeviceInformationResponse{}
+	err := client.caller.Call(ctx, core.ServiceDevice, tds.GetDeviceInformation{}, &response)
+	return response, err
+}
+
+// GetSystemDateAndTime return the clock of the device
+func (client *Client) GetSystemDateAndTime(ctx context.Context) (tds.SystemDateTime, error) {
+	response := tds.GetSystemDateAndTimeResponse{}
+	err := client.caller.Call(ctx, core.ServiceDevice, tds.GetSystemDateAndTime{}, &response)
+	return response.SystemDateAndTime, err
+}
+
+// GetHostname return the hostname of the device
+func (client *Client) GetHostname(ctx context.Context) (onvif.HostnameInformation, error) {
+	response := tds.GetHostnameResponse{}
+	err := client.caller.Call(ctx, core.ServiceDevice, tds.GetHostname{}, &response)
+	return response.HostnameInformation, err
+}
+
+// GetScopes return the scopes of the device
+func (client *Client) GetScopes(ctx context.Context) ([]onvif.Scope, error) {
+	response := tds.GetScopesResponse{}
+	err := client.caller.Call(ctx, core.ServiceDevice, tds.GetScopes{}, &response)
+	return response.Scopes, err
+}
+
+// GetNetworkInterfaces return the network interfaces of the device
+func (client *Client) GetNetworkInterfaces(ctx context.Context) ([]onvif.NetworkInterface, error) {
+	response := tds.GetNetworkInterfacesResponse{}
+	err := client.caller.Call(ctx, core.ServiceDevice, tds.GetNetworkInterfaces{}, &response)
+	return response.NetworkInterfaces, err
+}
+
+// SystemReboot reboot the device and return its message, e.g. the time the
+// reboot takes
+func (client *Client) SystemReboot(ctx context.Context) (string, error) {
+	response := tds.SystemRebootResponse{}
+	err := client.caller.Call(ctx, core.ServiceDevice, tds.SystemReboot{}, &response)
+	return response.Message, err
+}
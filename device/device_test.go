@@ -0,0 +1,55 @@
+package device
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/core"
+)
+
+const envelope = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:tds="http://www.onvif.org/ver10/device/wsdl"><s:Body>`
+
+func TestConnect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(body), "GetServices"):
+			service := func(namespace, path string) string {
+				return `<tds:Service><tds:Namespace>` + namespace + `</tds:Namespace><tds:XAddr>http://` + r.Host + path + `</tds:XAddr>` +
+					`<tds:Version><tt:Major>2</tt:Major><tt:Minor>60</tt:Minor></tds:Version></tds:Service>`
+			}
+			w.Write([]byte(envelope + `<tds:GetServicesResponse>` +
+				service("http://www.onvif.org/ver10/device/wsdl", "/onvif/device_service") +
+				service("http://www.onvif.org/ver20/media/wsdl", "/onvif/media2") +
+				service("http://www.onvif.org/ver20/ptz/wsdl", "/onvif/ptz") +
+				service("http://www.example.com/vendor/wsdl", "/onvif/vendor") +
+				`</tds:GetServicesResponse></s:Body></s:Envelope>`))
+		case strings.Contains(string(body), "GetDeviceInformation"):
+			w.Write([]byte(envelope + `<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer><tds:Model>C1</tds:Model>` +
+				`<tds:FirmwareVersion>1.0</tds:FirmwareVersion><tds:SerialNumber>42</tds:SerialNumber><tds:HardwareId>1</tds:HardwareId>` +
+				`</tds:GetDeviceInformationResponse></s:Body></s:Envelope>`))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	client, err := Connect(ctx, core.Params{XAddr: server.URL + "/onvif/device_service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, path := range map[string]string{core.ServiceMedia2: "/onvif/media2", core.ServicePTZ: "/onvif/ptz"} {
+		if xaddr, err := client.Endpoint(key); err != nil || xaddr != server.URL+path {
+			t.Fatalf("%s endpoint %q, %v", key, xaddr, err)
+		}
+	}
+	if _, err := client.Endpoint(core.ServiceEvents); err == nil {
+		t.Fatal("endpoint of a service not listed")
+	}
+	information, err := New(client).GetDeviceInformation(ctx)
+	if err != nil || information.Manufacturer != "Acme" || information.SerialNumber != "42" {
+		t.Fatalf("information %+v, %v", information, err)
+	}
+}
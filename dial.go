@@ -0,0 +1,169 @@
+package onvif
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// IP families of DialOptions.Prefer
+const (
+	PreferIPv4 = "ip4"
+	PreferIPv6 = "ip6"
+)
+
+// DialOptions connection settings of the http client created for a device
+// without DeviceParams.HttpClient. A single overall timeout conflates the
+// devices slow to answer with the dead ones, ConnectTimeout detects the
+// latter quickly.
+type DialOptions struct {
+	// ConnectTimeout limit of the TCP connection, 0 leaves the connection
+	// bounded by Timeout only
+	ConnectTimeout time.Duration
+	// Timeout of a whole call, the response included, 0 means 10s
+	Timeout time.Duration
+	// Prefer PreferIPv4 or PreferIPv6, the family connected first when the
+	// name of the device resolves to both, empty keeps the order of the
+	// resolver
+	Prefer string
+	// FallbackDelay time the first family is given before the other family
+	// is raced (happy eyeballs), 0 means 300ms, negative tries the families
+	// one after the other
+	FallbackDelay time.Duration
+	// KeepAlive period of the TCP keep-alive probes, 0 means 30s
+	KeepAlive time.Duration
+}
+
+const defaultFallbackDelay = 300 * time.Millisecond
+
+// client return the http client of a device
+func (options DialOptions) client() *http.Client {
+	client := &http.Client{Timeout: durationOr(options.Timeout, 10*time.Second)}
+	if options != (DialOptions{Timeout: options.Timeout}) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = options.DialContext
+		client.Transport = transport
+	}
+	return client
+}
+
+func (options DialOptions) dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:       options.ConnectTimeout,
+		KeepAlive:     durationOr(options.KeepAlive, 30*time.Second),
+		FallbackDelay: options.FallbackDelay,
+	}
+}
+
+// DialContext connect to address according to the options, the dial
+// function of the http transport of the device
+func (options DialOptions) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := options.dialer()
+	host, port, err := net.SplitHostPort(address)
+	if options.Prefer == "" || network != "tcp" || err != nil || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var primaries, fallbacks []string
+	for _, ip := range ips {
+		target := net.JoinHostPort(ip.String(), port)
+		if (ip.IP.To4() != nil) == (options.Prefer == PreferIPv4) {
+			primaries = append(primaries, target)
+		} else {
+			fallbacks = append(fallbacks, target)
+		}
+	}
+	if len(primaries) == 0 {
+		primaries, fallbacks = fallbacks, nil
+	}
+	if len(fallbacks) == 0 || options.FallbackDelay < 0 {
+		return dialSerial(ctx, dialer, network, append(primaries, fallbacks...))
+	}
+	return dialRace(ctx, dialer, network, primaries, fallbacks, durationOr(options.FallbackDelay, defaultFallbackDelay))
+}
+
+// dialSerial connect to the first address accepting the connection
+func dialSerial(ctx context.Context, dialer *net.Dialer, network string, addresses []string) (net.Conn, error) {
+	var lastErr error
+	for _, address := range addresses {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// dialRace connect to the primary addresses, and to the fallback ones as
+// well once delay elapsed or the primary ones failed, the first connection
+// established is returned
+func dialRace(ctx context.Context, dialer *net.Dialer, network string, primaries, fallbacks []string, delay time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type dialResult struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	results := make(chan dialResult, 2)
+	start := func(addresses []string, primary bool) {
+		go func() {
+			conn, err := dialSerial(ctx, dialer, network, addresses)
+			results <- dialResult{conn: conn, err: err, primary: primary}
+		}()
+	}
+	start(primaries, true)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	pending, fallbackStarted := 1, false
+	var primaryErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				start(fallbacks, false)
+				pending, fallbackStarted = pending+1, true
+			}
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				/* 另一族的连接可能随后建立,需关闭 */
+				if pending > 0 {
+					go func() {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}()
+				}
+				return result.conn, nil
+			}
+			if result.primary || primaryErr == nil {
+				primaryErr = result.err
+			}
+			if !fallbackStarted {
+				start(fallbacks, false)
+				pending, fallbackStarted = pending+1, true
+			} else if pending == 0 {
+				return nil, primaryErr
+			}
+		}
+	}
+}
+
+// dialTCP connect to address with the dial options of the device, used for
+// the connections made outside the http client, e.g. TLS inspection
+func (dev *Device) dialTCP(ctx context.Context, address string) (net.Conn, error) {
+	options := dev.Params.Dial
+	if options.ConnectTimeout <= 0 {
+		options.ConnectTimeout = 5 * time.Second
+	}
+	return options.DialContext(ctx, "tcp", address)
+}
@@ -0,0 +1,102 @@
+package onvif
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// acceptingAddress return the address of a listener accepting connections
+func acceptingAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestDialOptionsClient(t *testing.T) {
+	client := DialOptions{}.client()
+	if client.Timeout != 10*time.Second || client.Transport != nil {
+		t.Fatalf("client %+v", client)
+	}
+	client = DialOptions{Timeout: time.Second}.client()
+	if client.Timeout != time.Second || client.Transport != nil {
+		t.Fatalf("client %+v", client)
+	}
+	/* 设置了连接参数时使用独立的transport */
+	client = DialOptions{ConnectTimeout: time.Second}.client()
+	if transport, ok := client.Transport.(*http.Transport); !ok || transport.DialContext == nil || transport == http.DefaultTransport {
+		t.Fatalf("transport %+v", client.Transport)
+	}
+}
+
+func TestDialRace(t *testing.T) {
+	ctx := context.Background()
+	dialer := DialOptions{ConnectTimeout: time.Second}.dialer()
+	good, closed := acceptingAddress(t), closedAddress(t)
+
+	conn, err := dialSerial(ctx, dialer, "tcp", []string{closed, good})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.RemoteAddr().String() != good {
+		t.Fatalf("connected to %s", conn.RemoteAddr())
+	}
+	conn.Close()
+
+	/* 首选地址失败时不等待延迟即尝试另一族 */
+	start := time.Now()
+	conn, err = dialRace(ctx, dialer, "tcp", []string{closed}, []string{good}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("fallback after %s", elapsed)
+	}
+	conn, err = dialRace(ctx, dialer, "tcp", []string{good}, []string{closed}, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if _, err := dialRace(ctx, dialer, "tcp", []string{closed}, []string{closedAddress(t)}, time.Millisecond); err == nil || !strings.Contains(err.Error(), closed) {
+		t.Fatalf("error %v, want the error of the primary address", err)
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	good := acceptingAddress(t)
+	_, port, _ := net.SplitHostPort(good)
+	ctx := context.Background()
+	for _, options := range []DialOptions{
+		{},
+		{Prefer: PreferIPv4, FallbackDelay: -1},
+		{Prefer: PreferIPv4},
+	} {
+		conn, err := options.DialContext(ctx, "tcp", net.JoinHostPort("localhost", port))
+		if err != nil {
+			t.Fatalf("%+v: %v", options, err)
+		}
+		conn.Close()
+	}
+	/* IP地址直接连接 */
+	conn, err := DialOptions{Prefer: PreferIPv6}.DialContext(ctx, "tcp", good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,126 @@
+package onvif
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+// Enricher add facts to a device found by DiscoverDevices before it is
+// sent, the errors are collected in DiscoveredDevice.EnrichErrors
+type Enricher func(ctx context.Context, found *DiscoveredDevice) error
+
+// DefaultMACVendors manufacturers of the OUIs common among cameras, used by
+// MACVendorEnricher
+var DefaultMACVendors = map[string]string{
+	"00:40:8c": "Axis Communications",
+	"ac:cc:8e": "Axis Communications",
+	"44:19:b6": "Hikvision",
+	"c0:56:e3": "Hikvision",
+	"28:57:be": "Hikvision",
+	"bc:ad:28": "Hikvision",
+	"3c:ef:8c": "Dahua",
+	"e0:50:8b": "Dahua",
+	"90:02:a9": "Dahua",
+	"00:09:18": "Hanwha Vision",
+}
+
+// arpTable path of the ARP table of Linux
+const arpTable = "/proc/net/arp"
+
+// enrich run the enrichers on found
+func (found *DiscoveredDevice) enrich(ctx context.Context, enrichers []Enricher) {
+	for _, enricher := range enrichers {
+		if err := enricher(ctx, found); err != nil {
+			found.EnrichErrors = append(found.EnrichErrors, err)
+		}
+	}
+}
+
+// ip return the IP address of the device, without port
+func (found *DiscoveredDevice) ip() string {
+	host, _ := DeviceParams{Ipddr: found.Match.Host}.address()
+	return host
+}
+
+// ReverseDNSEnricher set Hostnames from the reverse DNS records of the address
+// of the device
+func ReverseDNSEnricher() Enricher {
+	return func(ctx context.Context, found *DiscoveredDevice) error {
+		names, err := net.DefaultResolver.LookupAddr(ctx, found.ip())
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			found.Hostnames = append(found.Hostnames, strings.TrimSuffix(name, "."))
+		}
+		return nil
+	}
+}
+
+// ARPEnricher set HardwareAddr from the ARP table of the host, Linux only,
+// the address advertised in the scopes is kept when the device is not a
+// neighbour
+func ARPEnricher() Enricher {
+	return func(ctx context.Context, found *DiscoveredDevice) error {
+		file, err := os.Open(arpTable)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		ip := found.ip()
+		scanner := bufio.NewScanner(file)
+		/* 格式: IP address, HW type, Flags, HW address, Mask, Device */
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 4 || fields[0] != ip {
+				continue
+			}
+			if mac, err := ParseMACAddr(fields[3]); err == nil && mac != "00:00:00:00:00:00" {
+				found.HardwareAddr = mac
+			}
+			break
+		}
+		return scanner.Err()
+	}
+}
+
+// MACVendorEnricher set Vendor from the OUI of HardwareAddr, vendors maps the
+// lower case OUIs, e.g. 00:40:8c, to the manufacturers, DefaultMACVendors
+// when nil. It runs after ARPEnricher.
+func MACVendorEnricher(vendors map[string]string) Enricher {
+	if vendors == nil {
+		vendors = DefaultMACVendors
+	}
+	return func(ctx context.Context, found *DiscoveredDevice) error {
+		if len(found.HardwareAddr) >= 8 {
+			found.Vendor = vendors[string(found.HardwareAddr[:8])]
+		}
+		return nil
+	}
+}
+
+// DeviceInformationEnricher set Information from GetDeviceInformation, with
+// the connected device or, when DiscoveryOptions.ProbeOnly is set, with the
+// first service address of the match and the given credentials
+func DeviceInformationEnricher(username, password string) Enricher {
+	return func(ctx context.Context, found *DiscoveredDevice) error {
+		dev := found.Device
+		if dev == nil {
+			dev = newOfflineDevice(DeviceParams{Ipddr: found.Match.Host, Username: username, Password: password})
+			if len(found.Match.XAddrs) > 0 {
+				dev.endpoints["device"] = found.Match.XAddrs[0]
+			}
+		}
+		info := device.GetDeviceInformationResponse{}
+		if err := dev.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &info, ""); err != nil {
+			return err
+		}
+		found.Information = &info
+		return nil
+	}
+}
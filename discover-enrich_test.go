@@ -0,0 +1,47 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/discovery"
+)
+
+func TestEnrichers(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetDeviceInformation": `<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer><tds:Model>C1</tds:Model></tds:GetDeviceInformationResponse>`,
+	})
+	ctx := context.Background()
+	failing := errors.New("lookup failed")
+	found := DiscoveredDevice{Match: discovery.Match{Host: dev.Params.Ipddr}, HardwareAddr: "44:19:b6:01:02:03"}
+	found.Match.XAddrs = []string{dev.endpoints[ServiceDevice]}
+	/* 探测模式下以服务地址和提供的凭据读取设备信息,出错的enricher不影响其余的 */
+	found.enrich(ctx, []Enricher{
+		func(ctx context.Context, found *DiscoveredDevice) error { return failing },
+		MACVendorEnricher(nil),
+		DeviceInformationEnricher("admin", "secret"),
+	})
+	if found.Vendor != "Hikvision" || found.Information == nil || found.Information.Manufacturer != "Acme" || found.Information.Model != "C1" {
+		t.Fatalf("found %+v", found)
+	}
+	if len(found.EnrichErrors) != 1 || found.EnrichErrors[0] != failing {
+		t.Fatalf("errors %v", found.EnrichErrors)
+	}
+	if requests := fake.sent("GetDeviceInformation"); len(requests) != 1 || !strings.Contains(requests[0], "<Username>admin</Username>") {
+		t.Fatalf("requests %q", requests)
+	}
+
+	/* 已连接的设备直接调用 */
+	connected := DiscoveredDevice{Match: discovery.Match{Host: "192.0.2.1"}, Device: dev, HardwareAddr: "00:11:22:33:44:55"}
+	connected.enrich(ctx, []Enricher{MACVendorEnricher(map[string]string{"00:11:22": "Other"}), DeviceInformationEnricher("", "")})
+	if connected.Vendor != "Other" || connected.Information == nil || len(connected.EnrichErrors) != 0 {
+		t.Fatalf("found %+v", connected)
+	}
+	unknown := DiscoveredDevice{HardwareAddr: "00:11:22:33:44:55"}
+	unknown.enrich(ctx, []Enricher{MACVendorEnricher(nil)})
+	if unknown.Vendor != "" {
+		t.Fatalf("vendor %q", unknown.Vendor)
+	}
+}
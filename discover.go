@@ -0,0 +1,180 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/discovery"
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+// ErrNoInterface returned by DiscoverDevices when no network interface can be
+// probed
+var ErrNoInterface = errors.New("no multicast network interface")
+
+// DiscoveryOptions options of DiscoverDevices
+type DiscoveryOptions struct {
+	// Interfaces names of the network interfaces probed, every interface up
+	// and multicast capable when empty
+	Interfaces []string
+	// Types device types probed, discovery.NetworkVideoTransmitter when empty
+	Types []string
+	// Scopes the devices must advertise
+	Scopes []string
+	// Timeout time the answers are collected for, soap.ProbeWindow when 0
+	Timeout time.Duration
+	// Username and Password the devices are connected with
+	Username string
+	Password string
+	// Concurrency limit of devices connected in parallel, 0 means
+	// defaultFleetConcurrency
+	Concurrency int
+	// ProbeOnly report the devices without connecting them
+	ProbeOnly bool
+	// Enrichers run in order on every device found, after its connection
+	Enrichers []Enricher
+}
+
+// DiscoveredDevice device answering the probe of DiscoverDevices
+type DiscoveredDevice struct {
+	// Interface network interface the device answered on
+	Interface string
+	Match     discovery.Match
+	// Device connected device, nil when Err or ProbeOnly is set
+	Device *Device
+	// Err error connecting the device, or error of the probe of Interface
+	// when Match is empty
+	Err error
+
+	// Hostnames reverse DNS names of the device, see ReverseDNSEnricher
+	Hostnames []string
+	// HardwareAddr MAC address of the device, advertised in its scopes or
+	// read from the ARP table, see ARPEnricher
+	HardwareAddr MACAddr
+	// Vendor manufacturer of the network interface, see MACVendorEnricher
+	Vendor string
+	// Information of the device, see DeviceInformationEnricher
+	Information *device.GetDeviceInformationResponse
+	// EnrichErrors errors of the enrichers
+	EnrichErrors []error
+}
+
+// DiscoverDevices probe the network interfaces of opts and connect the
+// devices as they answer, the enrichers then add the facts useful to the
+// operators, e.g. the host names and the manufacturer:
+//
+//	found, err := onvif.DiscoverDevices(ctx, onvif.DiscoveryOptions{ProbeOnly: true,
+//		Enrichers: []onvif.Enricher{onvif.ReverseDNSEnricher(), onvif.ARPEnricher(), onvif.MACVendorEnricher(nil)}})
+//
+// The devices are sent on the returned channel, once per host, and the
+// channel is closed when the probes ended and the devices are connected, or
+// when ctx is done.
+func DiscoverDevices(ctx context.Context, opts DiscoveryOptions) (<-chan DiscoveredDevice, error) {
+	interfaces := opts.Interfaces
+	if len(interfaces) == 0 {
+		interfaces = multicastInterfaces()
+	}
+	if len(interfaces) == 0 {
+		return nil, ErrNoInterface
+	}
+	for _, name := range interfaces {
+		if _, err := net.InterfaceByName(name); err != nil {
+			return nil, err
+		}
+	}
+	limit := opts.Concurrency
+	if limit <= 0 {
+		limit = defaultFleetConcurrency
+	}
+	probeOptions := discovery.Options{Types: opts.Types, Scopes: opts.Scopes, Timeout: opts.Timeout}
+
+	results := make(chan DiscoveredDevice)
+	send := func(found DiscoveredDevice) {
+		select {
+		case results <- found:
+		case <-ctx.Done():
+		}
+	}
+	sem := make(chan struct{}, limit)
+	var mutex sync.Mutex
+	hosts := make(map[string]bool)
+	var wg sync.WaitGroup
+	for _, name := range interfaces {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			err := discovery.ProbeFunc(ctx, name, probeOptions, func(match discovery.Match) {
+				/* 多个网卡可能收到同一设备的应答 */
+				mutex.Lock()
+				seen := hosts[match.Host]
+				hosts[match.Host] = true
+				mutex.Unlock()
+				if seen {
+					return
+				}
+				/* 连接在独立协程中进行,不阻塞应答的接收 */
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					select {
+					case sem <- struct{}{}:
+					case <-ctx.Done():
+						return
+					}
+					defer func() { <-sem }()
+					found := DiscoveredDevice{Interface: name, Match: match}
+					found.HardwareAddr, _ = ParseMACAddr(match.MAC)
+					if !opts.ProbeOnly {
+						found.Device, found.Err = connectMatch(ctx, match, opts.Username, opts.Password)
+					}
+					found.enrich(ctx, opts.Enrichers)
+					send(found)
+				}()
+			})
+			if err != nil && ctx.Err() == nil {
+				send(DiscoveredDevice{Interface: name, Err: err})
+			}
+		}(name)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results, nil
+}
+
+// multicastInterfaces return the names of the interfaces up and multicast
+// capable, loopback excluded
+func multicastInterfaces() []string {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagMulticast != 0 && iface.Flags&net.FlagLoopback == 0 {
+			names = append(names, iface.Name)
+		}
+	}
+	return names
+}
+
+// connectMatch connect the device of match and fill its parameters with the
+// values it advertised
+func connectMatch(ctx context.Context, match discovery.Match, username, password string) (*Device, error) {
+	dev, err := NewDeviceContext(ctx, DeviceParams{Ipddr: match.Host, Username: username, Password: password})
+	if err != nil {
+		return nil, err
+	}
+	dev.Params.Uuid = match.UUID
+	dev.Params.Types = strings.Join(match.Types, " ")
+	dev.Params.MAC = match.MAC
+	dev.Params.MACAddr, _ = ParseMACAddr(match.MAC)
+	dev.Params.Model = match.Hardware
+	dev.Params.Name = match.Name
+	return dev, nil
+}
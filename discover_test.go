@@ -0,0 +1,58 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/discovery"
+)
+
+func TestConnectMatchContext(t *testing.T) {
+	/* 应答前挂起的设备,取消后不再探测其他端口和路径 */
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	dev, err := connectMatch(ctx, discovery.Match{Host: strings.TrimPrefix(server.URL, "http://")}, "", "")
+	if dev != nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("device %v, error %v", dev, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("connect returned after %s", elapsed)
+	}
+}
+
+func TestConnectMatch(t *testing.T) {
+	server := httptest.NewServer(&capabilitiesDevice{deviceIO: "/onvif/DeviceIO"})
+	defer server.Close()
+	/* 设备参数取自探测应答 */
+	match := discovery.Match{Host: strings.TrimPrefix(server.URL, "http://"), UUID: "urn:uuid:1", MAC: "00-40-8C-12-34-56", Hardware: "M3045", Name: "lobby"}
+	match.Types = []string{"dn:NetworkVideoTransmitter", "tds:Device"}
+	dev, err := connectMatch(context.Background(), match, "admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.Params.Uuid != "urn:uuid:1" || dev.Params.Types != "dn:NetworkVideoTransmitter tds:Device" || dev.Params.Model != "M3045" ||
+		dev.Params.Name != "lobby" || dev.Params.MACAddr.String() != "00:40:8c:12:34:56" || dev.Params.Username != "admin" {
+		t.Fatalf("params %+v", dev.Params)
+	}
+}
+
+func TestDiscoverDevicesInterface(t *testing.T) {
+	if _, err := DiscoverDevices(context.Background(), DiscoveryOptions{Interfaces: []string{"nonexistent0"}}); err == nil {
+		t.Fatal("unknown interface probed")
+	}
+}
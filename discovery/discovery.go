@@ -0,0 +1,147 @@
+// Package discovery finds the ONVIF devices of a network with WS-Discovery.
+// It only depends on the soap package, so that tools listing the devices do
+// not import the device, media and event layers of the onvif package.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/soap"
+)
+
+// Device types of the probes
+const (
+	// NetworkVideoTransmitter cameras and encoders
+	NetworkVideoTransmitter = "NetworkVideoTransmitter"
+	// Device any ONVIF device
+	Device = "Device"
+)
+
+// Match device answering a probe
+type Match struct {
+	soap.ProbeMatch
+	// UUID of the endpoint reference, without the urn:uuid: prefix
+	UUID string
+	// Host host and port of the first transport address, the address the
+	// device is connected at
+	Host string
+	// Name, Hardware and MAC values of the onvif:// scopes of the device,
+	// empty when not advertised
+	Name     string
+	Hardware string
+	MAC      string
+}
+
+// PartialError returned by ProbeContext when ctx ended before the answers
+// were all collected, along with the devices which answered in time
+type PartialError struct {
+	// Found devices which answered before ctx ended
+	Found int
+	Err   error
+}
+
+func (err *PartialError) Error() string {
+	return fmt.Sprintf("discovery cut short after %d devices: %v", err.Found, err.Err)
+}
+
+func (err *PartialError) Unwrap() error {
+	return err.Err
+}
+
+// Probe send a probe for the devices of deviceType, NetworkVideoTransmitter
+// when empty, on the network of interfaceName and return the devices which
+// answered, one match per host
+func Probe(interfaceName, deviceType string) []Match {
+	matches, _ := ProbeContext(context.Background(), interfaceName, deviceType)
+	return matches
+}
+
+// ProbeContext probe like Probe, for soap.ProbeWindow at most. When ctx ends
+// first the devices which answered so far are returned with a PartialError.
+func ProbeContext(ctx context.Context, interfaceName, deviceType string) ([]Match, error) {
+	if deviceType == "" {
+		deviceType = NetworkVideoTransmitter
+	}
+	var matches []Match
+	err := ProbeFunc(ctx, interfaceName, Options{Types: []string{deviceType}}, func(match Match) {
+		matches = append(matches, match)
+	})
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+		return matches, &PartialError{Found: len(matches), Err: err}
+	}
+	return matches, err
+}
+
+// Options of a probe
+type Options struct {
+	// Types device types probed, NetworkVideoTransmitter when empty
+	Types []string
+	// Scopes the devices must advertise, e.g.
+	// onvif://www.onvif.org/location/city/paris
+	Scopes []string
+	// Timeout time the answers are collected for, soap.ProbeWindow when 0
+	Timeout time.Duration
+}
+
+// ProbeFunc send a probe on the network of interfaceName and call found with
+// every device as soon as it answers, once per host. When ctx ends first the
+// error of ctx is returned.
+func ProbeFunc(ctx context.Context, interfaceName string, options Options, found func(Match)) error {
+	types := options.Types
+	if len(types) == 0 {
+		types = []string{NetworkVideoTransmitter}
+	}
+	prefixed := make([]string, 0, len(types))
+	for _, deviceType := range types {
+		prefixed = append(prefixed, "dn:"+deviceType)
+	}
+	hosts := make(map[string]bool)
+	return soap.ListenProbe(ctx, interfaceName, options.Scopes, prefixed, map[string]string{"dn": "http://www.onvif.org/ver10/network/wsdl"}, options.Timeout, func(answer []byte) {
+		/* 任何主机都可应答探测,格式错误的应答直接丢弃 */
+		probeMatches, err := soap.ParseProbeMatches(answer)
+		if err != nil {
+			return
+		}
+		for _, probeMatch := range probeMatches {
+			match := NewMatch(probeMatch)
+			if match.Host == "" || hosts[match.Host] {
+				continue
+			}
+			hosts[match.Host] = true
+			found(match)
+		}
+	})
+}
+
+// NewMatch interpret the addresses and the scopes of a probe match
+func NewMatch(probeMatch soap.ProbeMatch) Match {
+	match := Match{ProbeMatch: probeMatch, UUID: probeMatch.Address}
+	if index := strings.Index(match.UUID, "uuid:"); index >= 0 {
+		match.UUID = match.UUID[index+5:]
+	}
+	if len(probeMatch.XAddrs) > 0 {
+		if address, err := url.Parse(probeMatch.XAddrs[0]); err == nil {
+			match.Host = address.Host
+		}
+	}
+	for _, scope := range probeMatch.Scopes {
+		value := scope[strings.LastIndex(scope, "/")+1:]
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			value = unescaped
+		}
+		switch {
+		case strings.Contains(scope, "MAC"):
+			match.MAC = value
+		case strings.Contains(scope, "/hardware/"):
+			match.Hardware = value
+		case strings.Contains(scope, "/name/"):
+			match.Name = value
+		}
+	}
+	return match
+}
@@ -0,0 +1,26 @@
+// Package onvif is a client of ONVIF devices: the device, media, media2, PTZ,
+// imaging, events and access control services are called through Device, the
+// requests being routed to the service of the package of their type.
+//
+// The other packages are importable without this one:
+//
+//	core         SOAP transport: endpoints, WS-Security, faults
+//	device       device management service client
+//	media        media service client
+//	ptz          PTZ service client
+//	events       event service client, subscriptions and pull points
+//	soap         SOAP envelopes, WS-Security, WS-Discovery messages
+//	discovery    WS-Discovery probes, only depending on soap
+//	types/...    request and response types of each service
+//	xsd/...      ONVIF schema types
+//
+// The service clients send their requests through a core.Caller: a
+// core.Client, e.g. from device.Connect, or a Device, which adds the
+// capability cache, address failover, audit, policy and quarantine of this
+// package:
+//
+//	profiles, err := media.New(dev).GetProfiles(ctx)
+//
+// The PTZ and subscription methods of Device are deprecated shims over the
+// ptz and events packages.
+package onvif
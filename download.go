@@ -0,0 +1,305 @@
+package onvif
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/core"
+	"github.com/PolarisM78/go-onvif/soap"
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Progress called while a download is written with the bytes written so far
+// and the total size, -1 when the device does not announce it
+type Progress func(written, total int64)
+
+// ErrNoContent returned by a download when the response holds no content
+var ErrNoContent = errors.New("response holds no content")
+
+// progressWriter writer reporting the bytes written to a Progress
+type progressWriter struct {
+	w        io.Writer
+	progress Progress
+	written  int64
+	total    int64
+}
+
+func (writer *progressWriter) Write(p []byte) (int, error) {
+	n, err := writer.w.Write(p)
+	writer.written += int64(n)
+	if writer.progress != nil {
+		writer.progress(writer.written, writer.total)
+	}
+	return n, err
+}
+
+func newProgressWriter(w io.Writer, progress Progress, total int64) *progressWriter {
+	if total <= 0 {
+		total = -1
+	}
+	return &progressWriter{w: w, progress: progress, total: total}
+}
+
+// Download write the file at uri, e.g. a SystemBackupUri, to w without
+// buffering it, answering an authentication challenge with the device
+// credentials. It returns the number of bytes written.
+func (dev *Device) Download(ctx context.Context, uri string, w io.Writer, progress Progress) (int64, error) {
+	resp, err := dev.authorizedGet(ctx, dev.RewriteHost(uri))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("download status %s", resp.Status)
+	}
+	writer := newProgressWriter(w, progress, resp.ContentLength)
+	_, err = io.Copy(writer, resp.Body)
+	return writer.written, err
+}
+
+// DownloadSystemLog write the system log of logType, System or Access, to w.
+// The log is downloaded from its system URI when the device has one, else
+// streamed out of the GetSystemLog response.
+func (dev *Device) DownloadSystemLog(ctx context.Context, logType string, w io.Writer, progress Progress) (int64, error) {
+	uris := device.GetSystemUrisResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetSystemUris{}, &uris, ""); err == nil {
+		for _, log := range uris.SystemLogUris.SystemLog {
+			if string(log.Type) == logType && log.Uri != "" {
+				return dev.Download(ctx, string(log.Uri), w, progress)
+			}
+		}
+	}
+	request := device.GetSystemLog{LogType: onvif.SystemLogType(logType)}
+	return dev.CallMethodStream(ctx, request, "String", false, w, progress)
+}
+
+// DownloadSystemBackup write the system backup to w, downloaded from the
+// system backup URI when the device has one, else streamed out of the
+// GetSystemBackup response
+func (dev *Device) DownloadSystemBackup(ctx context.Context, w io.Writer, progress Progress) (int64, error) {
+	uris := device.GetSystemUrisResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetSystemUris{}, &uris, ""); err == nil && uris.SystemBackupUri != "" {
+		return dev.Download(ctx, string(uris.SystemBackupUri), w, progress)
+	}
+	return dev.CallMethodStream(ctx, device.GetSystemBackup{}, "Data", true, w, progress)
+}
+
+// maxStreamSize largest content written by CallMethodStream, the markup
+// around it being held to soap.DefaultLimits
+const maxStreamSize = 1 << 30
+
+// CallMethodStream send method and write the content of its response to w
+// without buffering it: the attachments of an MTOM response, or else the text
+// of the first element of the body named element, base64 decoded when
+// binary. An error status is answered with the fault of the device. The
+// markup of the response is held to soap.DefaultLimits and the content to
+// maxStreamSize. It returns the number of bytes written.
+func (dev Device) CallMethodStream(ctx context.Context, method interface{}, element string, binary bool, w io.Writer, progress Progress) (int64, error) {
+	pkgPath := strings.Split(reflect.TypeOf(method).PkgPath(), "/")
+	endpoint, err := dev.getEndpoint(strings.ToLower(pkgPath[len(pkgPath)-1]))
+	if err != nil {
+		return 0, err
+	}
+	resp, err := dev.callMethodDo(ctx, endpoint, method)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		/* 错误状态按普通调用读取fault */
+		message, err := soap.ReadLimited(resp.Body, soap.DefaultLimits)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := core.ResponseBody(message); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("response status %s", resp.Status)
+	}
+	body := io.LimitReader(resp.Body, soap.DefaultLimits.MaxSize+maxStreamSize+1)
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") {
+		written, err := streamAttachments(multipart.NewReader(body, params["boundary"]), w, progress)
+		if err == nil && written > maxStreamSize {
+			err = soap.ErrMessageTooLarge
+		}
+		return written, err
+	}
+	/* 非MTOM响应,逐个解析xml标记,只写出指定元素的文本 */
+	writer := newProgressWriter(w, progress, -1)
+	decoder := xml.NewDecoder(body)
+	decoder.Strict = true
+	depth, elements := 0, 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return writer.written, ErrNoContent
+		}
+		if err != nil {
+			return writer.written, err
+		}
+		/* 指定元素之前的标记按响应的限制检查 */
+		if decoder.InputOffset() > soap.DefaultLimits.MaxSize {
+			return writer.written, soap.ErrMessageTooLarge
+		}
+		switch token := token.(type) {
+		case xml.Directive:
+			return writer.written, soap.ErrDTDNotAllowed
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			if depth++; depth > soap.DefaultLimits.MaxDepth {
+				return writer.written, soap.ErrTooDeep
+			}
+			if elements++; elements > soap.DefaultLimits.MaxElements {
+				return writer.written, soap.ErrTooManyElements
+			}
+			if token.Name.Local == "Fault" {
+				fault := core.Fault{}
+				if err := decoder.DecodeElement(&fault, &token); err != nil {
+					return 0, err
+				}
+				if err := fault.Err(); err != nil {
+					return 0, err
+				}
+				return 0, errors.New("fault without reason")
+			}
+			if token.Name.Local == element {
+				err := copyElementText(decoder, &limitedWriter{w: writer, remaining: maxStreamSize}, binary)
+				return writer.written, err
+			}
+		}
+	}
+}
+
+// limitedWriter writer failing with soap.ErrMessageTooLarge past remaining
+// bytes
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (writer *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > writer.remaining {
+		return 0, soap.ErrMessageTooLarge
+	}
+	writer.remaining -= int64(len(p))
+	return writer.w.Write(p)
+}
+
+// copyElementText write the text of the element just opened to w, up to its
+// end
+func copyElementText(decoder *xml.Decoder, w io.Writer, binary bool) error {
+	var decoded *base64Writer
+	if binary {
+		decoded = &base64Writer{w: w}
+		w = decoded
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.CharData:
+			if _, err := w.Write(token); err != nil {
+				return err
+			}
+		case xml.StartElement:
+			if err := decoder.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			if decoded != nil {
+				return decoded.Flush()
+			}
+			return nil
+		}
+	}
+}
+
+// base64Writer decode the base64 text written to it into w, whitespace
+// ignored as the devices fold long contents
+type base64Writer struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (writer *base64Writer) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			writer.pending = append(writer.pending, c)
+		}
+	}
+	/* 按4字节分组解码,剩余部分留待下次写入 */
+	if n := len(writer.pending) / 4 * 4; n > 0 {
+		if err := writer.decode(writer.pending[:n]); err != nil {
+			return 0, err
+		}
+		writer.pending = append(writer.pending[:0], writer.pending[n:]...)
+	}
+	return len(p), nil
+}
+
+// Flush decode the remaining text
+func (writer *base64Writer) Flush() error {
+	if len(writer.pending) == 0 {
+		return nil
+	}
+	return writer.decode(writer.pending)
+}
+
+func (writer *base64Writer) decode(encoded []byte) error {
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(decoded, encoded)
+	if err != nil {
+		return err
+	}
+	_, err = writer.w.Write(decoded[:n])
+	return err
+}
+
+// streamAttachments write the parts following the root SOAP part of an MTOM
+// response to w, after checking the root part for a fault
+func streamAttachments(reader *multipart.Reader, w io.Writer, progress Progress) (int64, error) {
+	root, err := reader.NextPart()
+	if err != nil {
+		return 0, err
+	}
+	message, err := soap.ReadLimited(root, soap.DefaultLimits)
+	if err != nil {
+		return 0, err
+	}
+	if body, err := soap.Body(message); err == nil {
+		if err := core.CheckFault(body); err != nil {
+			return 0, err
+		}
+	}
+	writer := newProgressWriter(w, progress, -1)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return writer.written, err
+		}
+		if _, err := io.Copy(writer, part); err != nil {
+			return writer.written, err
+		}
+	}
+	if writer.written == 0 {
+		return 0, ErrNoContent
+	}
+	return writer.written, nil
+}
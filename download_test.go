@@ -0,0 +1,74 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDownloadSystemLog(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetSystemLog": `<tds:GetSystemLogResponse><tds:SystemLog><tt:String>boot&#xA;login admin</tt:String></tds:SystemLog></tds:GetSystemLogResponse>`,
+	})
+	ctx := context.Background()
+	var buffer bytes.Buffer
+	var progress []int64
+	/* 设备没有系统URI时从响应中流式读取 */
+	written, err := dev.DownloadSystemLog(ctx, "System", &buffer, func(written, total int64) {
+		if total != -1 {
+			t.Errorf("total %d", total)
+		}
+		progress = append(progress, written)
+	})
+	if err != nil || written != 16 || buffer.String() != "boot\nlogin admin" || len(progress) == 0 || progress[len(progress)-1] != 16 {
+		t.Fatalf("log %q (%d), %v, progress %v", buffer.String(), written, err, progress)
+	}
+
+	fake.set("GetSystemLog", `<s:Fault><s:Code><s:Value>s:Receiver</s:Value></s:Code><s:Reason><s:Text>log unavailable</s:Text></s:Reason></s:Fault>`)
+	if _, err := dev.DownloadSystemLog(ctx, "System", &buffer, nil); err == nil || !strings.Contains(err.Error(), "log unavailable") {
+		t.Fatalf("error %v", err)
+	}
+	fake.set("GetSystemLog", `<tds:GetSystemLogResponse/>`)
+	if _, err := dev.DownloadSystemLog(ctx, "System", &buffer, nil); err != ErrNoContent {
+		t.Fatalf("error %v", err)
+	}
+}
+
+func TestDownloadSystemBackup(t *testing.T) {
+	backup := []byte("backup \x00\x01\x02 data")
+	encoded := base64.StdEncoding.EncodeToString(backup)
+	fake, dev := newScriptedDevice(t, map[string]string{
+		/* 设备折行的base64内容 */
+		"GetSystemBackup": `<tds:GetSystemBackupResponse><tds:BackupFiles><tt:Name>backup</tt:Name><tt:Data>` +
+			encoded[:10] + "\n  " + encoded[10:] + `</tt:Data></tds:BackupFiles></tds:GetSystemBackupResponse>`,
+	})
+	ctx := context.Background()
+	var buffer bytes.Buffer
+	if written, err := dev.DownloadSystemBackup(ctx, &buffer, nil); err != nil || written != int64(len(backup)) || !bytes.Equal(buffer.Bytes(), backup) {
+		t.Fatalf("backup %q (%d), %v", buffer.Bytes(), written, err)
+	}
+
+	/* 有系统备份URI时直接下载 */
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/backup.bin" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(backup)
+	}))
+	defer files.Close()
+	fake.set("GetSystemUris", `<tds:GetSystemUrisResponse><tds:SystemBackupUri>`+files.URL+`/backup.bin</tds:SystemBackupUri></tds:GetSystemUrisResponse>`)
+	buffer.Reset()
+	var total int64
+	written, err := dev.DownloadSystemBackup(ctx, &buffer, func(written, size int64) { total = size })
+	if err != nil || written != int64(len(backup)) || total != int64(len(backup)) || !bytes.Equal(buffer.Bytes(), backup) {
+		t.Fatalf("backup %q (%d of %d), %v", buffer.Bytes(), written, total, err)
+	}
+	if _, err := dev.Download(ctx, files.URL+"/missing", &buffer, nil); err == nil {
+		t.Fatal("missing file downloaded")
+	}
+}
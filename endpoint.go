@@ -0,0 +1,129 @@
+package onvif
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Endpoint typed address of a device
+type Endpoint struct {
+	// Scheme of the device service, http or https, http when empty
+	Scheme string
+	// Host IP address or host name, IPv6 literals without brackets and with
+	// their zone, e.g. fe80::1%eth0
+	Host string
+	// Port of the device service, 0 to probe the usual ports
+	Port int
+}
+
+// ParseEndpoint parse the address of a device: a host, a host and port, or
+// a device service URL such as https://10.1.1.200:8443/onvif/device_service
+func ParseEndpoint(address string) (Endpoint, error) {
+	endpoint := Endpoint{}
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return Endpoint{}, err
+		}
+		endpoint.Scheme, address = strings.ToLower(u.Scheme), u.Host
+	}
+	host, port := DeviceParams{Ipddr: address}.address()
+	endpoint.Host = host
+	if port != "" {
+		value, err := strconv.Atoi(port)
+		if err != nil {
+			return Endpoint{}, fmt.Errorf("invalid port %q", port)
+		}
+		endpoint.Port = value
+	}
+	if err := endpoint.Validate(); err != nil {
+		return Endpoint{}, err
+	}
+	return endpoint, nil
+}
+
+// Validate check the scheme, the host and the port of the endpoint
+func (endpoint Endpoint) Validate() error {
+	if endpoint.Scheme != "" && endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", endpoint.Scheme)
+	}
+	if endpoint.Host == "" || strings.ContainsAny(endpoint.Host, "/?#[] ") {
+		return fmt.Errorf("invalid host %q", endpoint.Host)
+	}
+	if endpoint.Port < 0 || endpoint.Port > 65535 {
+		return fmt.Errorf("invalid port %d", endpoint.Port)
+	}
+	return nil
+}
+
+// String return the host and port of the endpoint, IPv6 literals bracketed
+// when a port is given, the form of DeviceParams.Ipddr
+func (endpoint Endpoint) String() string {
+	if endpoint.Port == 0 {
+		return endpoint.Host
+	}
+	return net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))
+}
+
+// MACAddr hardware address of a device, lower case and colon separated, e.g.
+// 00:40:8c:12:34:56
+type MACAddr string
+
+// ErrInvalidMACAddr returned by ParseMACAddr
+var ErrInvalidMACAddr = errors.New("invalid mac address")
+
+// ParseMACAddr parse a 48 bit hardware address separated by colons, dashes
+// or dots, or not separated as in the scopes of some devices
+func ParseMACAddr(value string) (MACAddr, error) {
+	value = strings.TrimSpace(value)
+	if len(value) == 12 {
+		/* 无分隔符形式,如001122AABBCC */
+		parts := make([]string, 0, 6)
+		for i := 0; i < 12; i += 2 {
+			parts = append(parts, value[i:i+2])
+		}
+		value = strings.Join(parts, ":")
+	}
+	hardware, err := net.ParseMAC(value)
+	if err != nil || len(hardware) != 6 {
+		return "", ErrInvalidMACAddr
+	}
+	return MACAddr(hardware.String()), nil
+}
+
+func (mac MACAddr) String() string {
+	return string(mac)
+}
+
+// normalize fill Endpoint and MACAddr from the deprecated Ipddr and MAC
+// fields, or the other way round, and validate the endpoint
+func (params *DeviceParams) normalize() error {
+	if params.Endpoint.Host != "" {
+		if err := params.Endpoint.Validate(); err != nil {
+			return err
+		}
+		params.Ipddr = params.Endpoint.String()
+	} else if params.Ipddr != "" {
+		/* 旧字段的格式不做校验,保持原有行为 */
+		params.Endpoint, _ = ParseEndpoint(params.Ipddr)
+	}
+	if params.MACAddr != "" {
+		params.MAC = string(params.MACAddr)
+	} else if params.MAC != "" {
+		params.MACAddr, _ = ParseMACAddr(params.MAC)
+	}
+	return nil
+}
+
+// setAddress change the address the device is reached at
+func (params *DeviceParams) setAddress(address string) {
+	params.Ipddr = address
+	if endpoint, err := ParseEndpoint(address); err == nil {
+		endpoint.Scheme = params.Endpoint.Scheme
+		params.Endpoint = endpoint
+	}
+}
@@ -0,0 +1,74 @@
+package onvif
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	for address, expected := range map[string]Endpoint{
+		"10.1.1.200":        {Host: "10.1.1.200"},
+		"camera.lan:8080":   {Host: "camera.lan", Port: 8080},
+		"[fe80::1%eth0]:80": {Host: "fe80::1%eth0", Port: 80},
+		"https://10.1.1.200:8443/onvif/device_service": {Scheme: "https", Host: "10.1.1.200", Port: 8443},
+	} {
+		if endpoint, err := ParseEndpoint(address); err != nil || endpoint != expected {
+			t.Errorf("%s parsed as %+v, %v", address, endpoint, err)
+		}
+	}
+	for _, address := range []string{"", "ftp://10.1.1.200", "10.1.1.200:http", "10.1.1.200:70000", "camera lan"} {
+		if endpoint, err := ParseEndpoint(address); err == nil {
+			t.Errorf("%q parsed as %+v", address, endpoint)
+		}
+	}
+	if s := (Endpoint{Host: "fe80::1", Port: 80}).String(); s != "[fe80::1]:80" {
+		t.Fatalf("endpoint %s", s)
+	}
+}
+
+func TestParseMACAddr(t *testing.T) {
+	for _, value := range []string{"00:40:8C:12:34:56", "00-40-8c-12-34-56", "0040.8c12.3456", "00408C123456"} {
+		if mac, err := ParseMACAddr(value); err != nil || mac != "00:40:8c:12:34:56" {
+			t.Errorf("%s parsed as %s, %v", value, mac, err)
+		}
+	}
+	for _, value := range []string{"", "00:40:8c:12:34", "00408C12345G", "02:00:5e:10:00:00:00:01"} {
+		if _, err := ParseMACAddr(value); err != ErrInvalidMACAddr {
+			t.Errorf("%s accepted", value)
+		}
+	}
+}
+
+func TestDeviceParamsCompatibility(t *testing.T) {
+	/* 新旧字段互相同步 */
+	params := DeviceParams{Endpoint: Endpoint{Host: "10.1.1.200", Port: 8080}, MAC: "00-40-8C-12-34-56"}
+	if err := params.normalize(); err != nil || params.Ipddr != "10.1.1.200:8080" || params.MACAddr != "00:40:8c:12:34:56" {
+		t.Fatalf("params %+v, %v", params, err)
+	}
+	params = DeviceParams{Ipddr: "[fe80::1%eth0]:80", MACAddr: "00:40:8c:12:34:56"}
+	if err := params.normalize(); err != nil || params.Endpoint != (Endpoint{Host: "fe80::1%eth0", Port: 80}) || params.MAC != "00:40:8c:12:34:56" {
+		t.Fatalf("params %+v, %v", params, err)
+	}
+	if _, err := NewDevice(DeviceParams{Endpoint: Endpoint{Scheme: "ftp", Host: "10.1.1.200"}}); err == nil || !strings.Contains(err.Error(), "scheme") {
+		t.Fatalf("error %v", err)
+	}
+
+	/* https端点以https探测设备服务 */
+	fake := &scriptedDevice{answers: map[string]string{
+		"GetCapabilities": `<tds:GetCapabilitiesResponse><tds:Capabilities/></tds:GetCapabilitiesResponse>`,
+	}}
+	server := httptest.NewTLSServer(fake)
+	defer server.Close()
+	endpoint, err := ParseEndpoint(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := NewDevice(DeviceParams{Endpoint: endpoint, HttpClient: server.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url, err := dev.getEndpoint(ServiceDevice); err != nil || !strings.HasPrefix(url, server.URL+"/onvif/") {
+		t.Fatalf("device endpoint %q, %v", url, err)
+	}
+}
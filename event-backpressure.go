@@ -0,0 +1,175 @@
+package onvif
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backpressure policy of an event channel which is full
+type Backpressure int
+
+// Backpressure policies
+const (
+	// BackpressureBlock wait for the consumer, up to MaxBlock when set, then
+	// drop the event
+	BackpressureBlock Backpressure = iota
+	// BackpressureDropOldest drop the oldest event of the channel to make
+	// room for the new one
+	BackpressureDropOldest
+	// BackpressureDropNewest drop the new event
+	BackpressureDropNewest
+)
+
+func (policy Backpressure) String() string {
+	switch policy {
+	case BackpressureBlock:
+		return "block"
+	case BackpressureDropOldest:
+		return "drop-oldest"
+	case BackpressureDropNewest:
+		return "drop-newest"
+	}
+	return "unknown"
+}
+
+// ConsumerOptions options of an event consumer
+type ConsumerOptions struct {
+	// Filter keep the events for which it returns true, nil keeps every event
+	Filter func(Event) bool
+	// Buffer capacity of the channel of the consumer
+	Buffer int
+	// Backpressure policy when the channel is full. A blocked consumer holds
+	// the pull of its device, so the drop policies or MaxBlock keep a stuck
+	// consumer from stalling the engine and the subscription from expiring.
+	Backpressure Backpressure
+	// MaxBlock longest wait of BackpressureBlock, 0 waits until the consumer
+	// reads or is closed
+	MaxBlock time.Duration
+}
+
+// ConsumerStats counters of an event channel
+type ConsumerStats struct {
+	Delivered uint64
+	Dropped   uint64
+}
+
+// eventCounters counters updated atomically
+type eventCounters struct {
+	delivered uint64
+	dropped   uint64
+}
+
+func (counters *eventCounters) stats() ConsumerStats {
+	return ConsumerStats{Delivered: atomic.LoadUint64(&counters.delivered), Dropped: atomic.LoadUint64(&counters.dropped)}
+}
+
+// offer send ev on events following policy, false when ctx ended or done was
+// closed while blocked
+func offer(ctx context.Context, done <-chan struct{}, events chan Event, ev Event, policy Backpressure, maxBlock time.Duration, counters *eventCounters) bool {
+	select {
+	case events <- ev:
+		atomic.AddUint64(&counters.delivered, 1)
+		return true
+	default:
+	}
+	switch policy {
+	case BackpressureDropNewest:
+		atomic.AddUint64(&counters.dropped, 1)
+		return true
+	case BackpressureDropOldest:
+		/* 丢弃最早的事件腾出空间,与消费者竞争时可能仍然失败 */
+		select {
+		case <-events:
+			atomic.AddUint64(&counters.dropped, 1)
+		default:
+		}
+		select {
+		case events <- ev:
+			atomic.AddUint64(&counters.delivered, 1)
+		default:
+			atomic.AddUint64(&counters.dropped, 1)
+		}
+		return true
+	}
+	var timeout <-chan time.Time
+	if maxBlock > 0 {
+		timer := time.NewTimer(maxBlock)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case events <- ev:
+		atomic.AddUint64(&counters.delivered, 1)
+		return true
+	case <-timeout:
+		atomic.AddUint64(&counters.dropped, 1)
+		return true
+	case <-done:
+		atomic.AddUint64(&counters.dropped, 1)
+		return true
+	case <-ctx.Done():
+		atomic.AddUint64(&counters.dropped, 1)
+		return false
+	}
+}
+
+// EventConsumer channel of events of an EventEngine or an EventBroker, with
+// its own backpressure policy
+type EventConsumer struct {
+	options ConsumerOptions
+	events  chan Event
+	done    chan struct{}
+	// mutex held while sending, so that the channel is not closed under a
+	// blocked sender
+	mutex    sync.Mutex
+	closed   bool
+	counters eventCounters
+	once     sync.Once
+	onClose  func()
+}
+
+func newEventConsumer(options ConsumerOptions) *EventConsumer {
+	return &EventConsumer{options: options, events: make(chan Event, options.Buffer), done: make(chan struct{})}
+}
+
+// Events return the channel of the events, closed by Close
+func (consumer *EventConsumer) Events() <-chan Event {
+	return consumer.events
+}
+
+// Stats return the events delivered to the channel and the events dropped
+// by the backpressure policy
+func (consumer *EventConsumer) Stats() ConsumerStats {
+	return consumer.counters.stats()
+}
+
+// Close end the consumer and close its channel
+func (consumer *EventConsumer) Close() {
+	consumer.once.Do(func() {
+		/* 先唤醒阻塞中的发送方,再关闭通道 */
+		close(consumer.done)
+		consumer.mutex.Lock()
+		consumer.closed = true
+		close(consumer.events)
+		consumer.mutex.Unlock()
+		if consumer.onClose != nil {
+			consumer.onClose()
+		}
+	})
+}
+
+// send hand ev to the consumer when its filter keeps it, false when ctx
+// ended while blocked
+func (consumer *EventConsumer) send(ctx context.Context, ev Event) bool {
+	if consumer.options.Filter != nil && !consumer.options.Filter(ev) {
+		return true
+	}
+	consumer.mutex.Lock()
+	defer consumer.mutex.Unlock()
+	if consumer.closed {
+		return true
+	}
+	return offer(ctx, consumer.done, consumer.events, ev, consumer.options.Backpressure, consumer.options.MaxBlock, &consumer.counters)
+}
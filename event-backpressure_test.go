@@ -0,0 +1,111 @@
+package onvif
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func sequenceEvent(sequence int) Event {
+	return Event{Data: map[string]string{"Sequence": strconv.Itoa(sequence)}}
+}
+
+func TestOffer(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		policy Backpressure
+		kept   string
+		stats  ConsumerStats
+	}{
+		{BackpressureDropNewest, "1", ConsumerStats{Delivered: 1, Dropped: 2}},
+		{BackpressureDropOldest, "3", ConsumerStats{Delivered: 3, Dropped: 2}},
+		{BackpressureBlock, "1", ConsumerStats{Delivered: 1, Dropped: 2}},
+	} {
+		events := make(chan Event, 1)
+		counters := eventCounters{}
+		for i := 1; i <= 3; i++ {
+			if !offer(ctx, nil, events, sequenceEvent(i), test.policy, time.Millisecond, &counters) {
+				t.Fatalf("%s: event %d not offered", test.policy, i)
+			}
+		}
+		if ev := <-events; ev.Data["Sequence"] != test.kept {
+			t.Fatalf("%s: event %s kept, want %s", test.policy, ev.Data["Sequence"], test.kept)
+		}
+		if stats := counters.stats(); stats != test.stats {
+			t.Fatalf("%s: stats %+v", test.policy, stats)
+		}
+	}
+	/* 阻塞等待在ctx结束或消费者关闭时返回 */
+	events := make(chan Event, 1)
+	events <- sequenceEvent(1)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if offer(cancelled, nil, events, sequenceEvent(2), BackpressureBlock, 0, &eventCounters{}) {
+		t.Fatal("blocked offer returned true after ctx ended")
+	}
+	done := make(chan struct{})
+	close(done)
+	if !offer(ctx, done, events, sequenceEvent(2), BackpressureBlock, 0, &eventCounters{}) {
+		t.Fatal("blocked offer returned false after the consumer closed")
+	}
+}
+
+func TestEventEngineSlowConsumer(t *testing.T) {
+	/* 消费者不读取时,丢弃策略使拉取继续,订阅不会过期 */
+	fake := &eventDevice{messages: 4}
+	dev := newEventDevice(t, fake)
+	engine := testEngine(1)
+	consumer := engine.Consume(dev, ConsumerOptions{Buffer: 1, Backpressure: BackpressureDropOldest})
+	runEngine(t, engine)
+	eventually(t, "pulls past the full channel", func() bool { return fake.count(&fake.pulls) > 10 })
+	if stats := consumer.Stats(); stats.Dropped == 0 {
+		t.Fatalf("stats %+v", stats)
+	}
+	if ev := <-consumer.Events(); ev.Data["Sequence"] == "1" {
+		t.Fatal("oldest event kept")
+	}
+}
+
+func TestEventEngineBlockingConsumer(t *testing.T) {
+	/* 阻塞的消费者挂起拉取,MaxBlock后丢弃事件继续拉取 */
+	fake := &eventDevice{messages: 4}
+	dev := newEventDevice(t, fake)
+	engine := testEngine(1)
+	engine.Consume(dev, ConsumerOptions{Buffer: 1, Backpressure: BackpressureBlock})
+	stop := runEngine(t, engine)
+	eventually(t, "a pull", func() bool { return fake.count(&fake.pulls) > 0 })
+	time.Sleep(50 * time.Millisecond)
+	if pulls := fake.count(&fake.pulls); pulls != 1 {
+		t.Fatalf("%d pulls with a blocked consumer", pulls)
+	}
+	/* 阻塞中的worker在Run结束时返回 */
+	stop()
+
+	fake = &eventDevice{messages: 4}
+	dev = newEventDevice(t, fake)
+	engine = testEngine(1)
+	consumer := engine.Consume(dev, ConsumerOptions{Buffer: 1, Backpressure: BackpressureBlock, MaxBlock: time.Millisecond})
+	runEngine(t, engine)
+	eventually(t, "pulls past the blocked consumer", func() bool { return fake.count(&fake.pulls) > 3 })
+	if stats := consumer.Stats(); stats.Dropped == 0 || stats.Delivered != 1 {
+		t.Fatalf("stats %+v", stats)
+	}
+}
+
+func TestEventEngineEventsBackpressure(t *testing.T) {
+	fake := &eventDevice{messages: 32}
+	dev := newEventDevice(t, fake)
+	engine := testEngine(1)
+	engine.Backpressure = BackpressureDropNewest
+	events := engine.Events()
+	engine.Add(dev)
+	runEngine(t, engine)
+	eventually(t, "events dropped from the full channel", func() bool { return engine.EventStats().Dropped > 0 })
+	if stats := engine.EventStats(); stats.Delivered != uint64(cap(events)) {
+		t.Fatalf("stats %+v with a channel of %d", stats, cap(events))
+	}
+	if ev := <-events; ev.Data["Sequence"] != "1" {
+		t.Fatalf("first event has sequence %s", ev.Data["Sequence"])
+	}
+}
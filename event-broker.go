@@ -0,0 +1,94 @@
+package onvif
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// EventBroker fan out the events of an EventEngine to many consumers, each
+// with its own filter:
+//
+//	engine.Handle(broker.Handle)
+//
+// The consumers of Subscribe too slow to drain their channel lose events
+// instead of stalling the engine, Consume sets another backpressure policy.
+type EventBroker struct {
+	mutex     sync.Mutex
+	consumers map[*EventConsumer]struct{}
+}
+
+// NewEventBroker return a broker without consumer
+func NewEventBroker() *EventBroker {
+	return &EventBroker{consumers: make(map[*EventConsumer]struct{})}
+}
+
+// Handle dispatch ev to the consumers, to be registered with EventEngine.Handle
+func (broker *EventBroker) Handle(ev Event) {
+	broker.mutex.Lock()
+	consumers := make([]*EventConsumer, 0, len(broker.consumers))
+	for consumer := range broker.consumers {
+		consumers = append(consumers, consumer)
+	}
+	broker.mutex.Unlock()
+	for _, consumer := range consumers {
+		consumer.send(context.Background(), ev)
+	}
+}
+
+// Subscribe return a channel of the events kept by filter, nil keeping every
+// event, buffering up to buffer events and dropping the newest events when
+// full. The returned function ends the subscription and closes the channel.
+func (broker *EventBroker) Subscribe(filter func(Event) bool, buffer int) (<-chan Event, func()) {
+	consumer := broker.Consume(ConsumerOptions{Filter: filter, Buffer: buffer, Backpressure: BackpressureDropNewest})
+	return consumer.Events(), consumer.Close
+}
+
+// Consume return a consumer of the events following options. A blocking
+// consumer holds the handlers of the engine, see ConsumerOptions.MaxBlock.
+func (broker *EventBroker) Consume(options ConsumerOptions) *EventConsumer {
+	consumer := newEventConsumer(options)
+	consumer.onClose = func() {
+		broker.mutex.Lock()
+		delete(broker.consumers, consumer)
+		broker.mutex.Unlock()
+	}
+	broker.mutex.Lock()
+	if broker.consumers == nil {
+		broker.consumers = make(map[*EventConsumer]struct{})
+	}
+	broker.consumers[consumer] = struct{}{}
+	broker.mutex.Unlock()
+	return consumer
+}
+
+// topicPath return the levels of an event topic without their namespace
+// prefix, tns1:RuleEngine/tnsaxis:Motion becomes RuleEngine/Motion
+func topicPath(topic string) string {
+	levels := strings.Split(strings.TrimSpace(topic), "/")
+	for i, level := range levels {
+		if index := strings.Index(level, ":"); index >= 0 {
+			levels[i] = level[index+1:]
+		}
+	}
+	return strings.Join(levels, "/")
+}
+
+// TopicFilter return a filter keeping the events under one of the topics,
+// given without namespace prefix, e.g. RuleEngine keeps
+// tns1:RuleEngine/CellMotionDetector/Motion. No topic keeps every event.
+func TopicFilter(topics ...string) func(Event) bool {
+	return func(ev Event) bool {
+		if len(topics) == 0 {
+			return true
+		}
+		path := topicPath(ev.Topic)
+		for _, topic := range topics {
+			topic = strings.Trim(topicPath(topic), "/")
+			if path == topic || strings.HasPrefix(path, topic+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
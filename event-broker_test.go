@@ -0,0 +1,46 @@
+package onvif
+
+import "testing"
+
+func TestTopicFilter(t *testing.T) {
+	filter := TopicFilter("RuleEngine/CellMotionDetector", "tns1:Device/Trigger/")
+	for topic, want := range map[string]bool{
+		"tns1:RuleEngine/CellMotionDetector/Motion":  true,
+		"tns1:RuleEngine/tnsaxis:CellMotionDetector": true,
+		"tns1:RuleEngine/CellMotionDetectorArea":     false,
+		"tns1:Device/Trigger/DigitalInput":           true,
+		"tns1:VideoSource/MotionAlarm":               false,
+	} {
+		if filter(Event{Topic: topic}) != want {
+			t.Fatalf("%s kept %v", topic, !want)
+		}
+	}
+	if !TopicFilter()(Event{Topic: "tns1:VideoSource/MotionAlarm"}) {
+		t.Fatal("event dropped without topic")
+	}
+}
+
+func TestEventBrokerSubscribe(t *testing.T) {
+	broker := NewEventBroker()
+	motion, cancelMotion := broker.Subscribe(TopicFilter("VideoSource"), 1)
+	every, cancelEvery := broker.Subscribe(nil, 4)
+	defer cancelEvery()
+	broker.Handle(Event{Topic: "tns1:VideoSource/MotionAlarm", Device: "first"})
+	/* 缓冲已满时丢弃最新的事件,不阻塞引擎 */
+	broker.Handle(Event{Topic: "tns1:VideoSource/MotionAlarm", Device: "second"})
+	broker.Handle(Event{Topic: "tns1:Device/Trigger/DigitalInput"})
+	if ev := <-motion; ev.Device != "first" || len(motion) != 0 {
+		t.Fatalf("event %+v, %d buffered", ev, len(motion))
+	}
+	if len(every) != 3 {
+		t.Fatalf("%d events of the unfiltered subscription", len(every))
+	}
+	cancelMotion()
+	if _, ok := <-motion; ok {
+		t.Fatal("channel open after cancel")
+	}
+	broker.Handle(Event{Topic: "tns1:VideoSource/MotionAlarm"})
+	if len(every) != 4 {
+		t.Fatalf("%d events after cancelling another subscription", len(every))
+	}
+}
@@ -0,0 +1,132 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SubscriptionCapacity limits of the event service of a device
+type SubscriptionCapacity struct {
+	// MaxPullPoints maximum number of pull point subscriptions, 0 when the
+	// device states no limit
+	MaxPullPoints int
+	// MaxNotificationProducers maximum number of basic notification
+	// subscriptions, 0 when the device states no limit
+	MaxNotificationProducers int
+	PullPointSupport         bool
+}
+
+// SubscriptionCapacity return the subscription limits of the device, read
+// from the cached capabilities of its event service
+func (dev *Device) SubscriptionCapacity(ctx context.Context) (SubscriptionCapacity, error) {
+	capabilities, err := dev.ServiceCapabilities(ctx)
+	if capabilities.Events == nil {
+		if err == nil {
+			err = &NotSupportedError{Service: "events"}
+		}
+		return SubscriptionCapacity{}, err
+	}
+	return SubscriptionCapacity{
+		MaxPullPoints:            int(capabilities.Events.MaxPullPoints),
+		MaxNotificationProducers: int(capabilities.Events.MaxNotificationProducers),
+		PullPointSupport:         bool(capabilities.Events.WSPullPointSupport),
+	}, nil
+}
+
+// ErrSubscriptionLimit returned when a subscription could not be created on a
+// device limiting its number of subscriptions
+var ErrSubscriptionLimit = errors.New("device subscription limit reached")
+
+// SubscriptionLimitError ErrSubscriptionLimit carrying the limit of the device
+// and the fault of the subscription
+type SubscriptionLimitError struct {
+	Device string
+	// MaxPullPoints limit advertised by the device, 0 when it advertises none
+	MaxPullPoints int
+	Err           error
+}
+
+func (err *SubscriptionLimitError) Error() string {
+	if err.MaxPullPoints <= 0 {
+		return fmt.Sprintf("subscription on %s failed, the device is out of subscriptions: %v", err.Device, err.Err)
+	}
+	return fmt.Sprintf("subscription on %s failed, the device allows %d pull points: %v", err.Device, err.MaxPullPoints, err.Err)
+}
+
+// Is report the error as ErrSubscriptionLimit
+func (err *SubscriptionLimitError) Is(target error) bool {
+	return target == ErrSubscriptionLimit
+}
+
+// Unwrap return the error of the subscription
+func (err *SubscriptionLimitError) Unwrap() error {
+	return err.Err
+}
+
+// subscriptionLimitFaults subcodes of the faults of a device refusing a
+// subscription for lack of resources
+var subscriptionLimitFaults = []string{"TooManySubscriptions", "MaxPullPoints", "MaxNotificationProducers", "ResourceUnknown",
+	"ResourceUnknownFault", "SubscribeCreationFailedFault", "CapabilityViolated"}
+
+// subscriptionLimitFault report whether err is the fault of a device out of
+// subscriptions, not e.g. an authentication failure or a timeout
+func subscriptionLimitFault(err error) bool {
+	var fault *FaultError
+	return errors.As(err, &fault) && fault.HasSubcode(subscriptionLimitFaults...)
+}
+
+// Subscribe return a channel of the events of dev kept by filter, nil keeping
+// every event, buffering up to buffer events; the events are dropped when the
+// channel is full. Every consumer of a device shares the single subscription
+// of the engine on that device, the events being demultiplexed in process.
+// The device is added to the engine when needed, and removed when the last
+// consumer ends unless it was added with Add. The returned function ends the
+// subscription and closes the channel.
+func (engine *EventEngine) Subscribe(dev *Device, filter func(Event) bool, buffer int) (<-chan Event, func()) {
+	consumer := engine.Consume(dev, ConsumerOptions{Filter: filter, Buffer: buffer, Backpressure: BackpressureDropNewest})
+	return consumer.Events(), consumer.Close
+}
+
+// Consume return a consumer of the events of dev following options, like
+// Subscribe with the backpressure policy of options
+func (engine *EventEngine) Consume(dev *Device, options ConsumerOptions) *EventConsumer {
+	consumer := newEventConsumer(options)
+	consumer.onClose = func() {
+		engine.mutex.Lock()
+		delete(engine.consumers[dev], consumer)
+		if len(engine.consumers[dev]) == 0 {
+			delete(engine.consumers, dev)
+		}
+		engine.mutex.Unlock()
+		engine.remove(dev, true)
+	}
+	engine.mutex.Lock()
+	if engine.consumers == nil {
+		engine.consumers = make(map[*Device]map[*EventConsumer]struct{})
+	}
+	if engine.consumers[dev] == nil {
+		engine.consumers[dev] = make(map[*EventConsumer]struct{})
+	}
+	engine.consumers[dev][consumer] = struct{}{}
+	engine.add(dev)
+	engine.mutex.Unlock()
+	return consumer
+}
+
+// dispatch hand ev to the consumers of the device, false when ctx ended
+// while a consumer blocked
+func (engine *EventEngine) dispatch(ctx context.Context, dev *Device, ev Event) bool {
+	engine.mutex.Lock()
+	consumers := make([]*EventConsumer, 0, len(engine.consumers[dev]))
+	for consumer := range engine.consumers[dev] {
+		consumers = append(consumers, consumer)
+	}
+	engine.mutex.Unlock()
+	for _, consumer := range consumers {
+		if !consumer.send(ctx, ev) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestEventEngineSharedSubscription(t *testing.T) {
+	fake := &eventDevice{messages: 2}
+	dev := newEventDevice(t, fake)
+	engine := testEngine(2)
+	all := engine.Consume(dev, ConsumerOptions{Buffer: 64, Backpressure: BackpressureDropNewest})
+	odd := engine.Consume(dev, ConsumerOptions{Buffer: 64, Backpressure: BackpressureDropNewest, Filter: func(ev Event) bool {
+		sequence, _ := strconv.Atoi(ev.Data["Sequence"])
+		return sequence%2 == 1
+	}})
+	runEngine(t, engine)
+	for i := 1; i <= 10; i++ {
+		if ev := <-all.Events(); ev.Data["Sequence"] != strconv.Itoa(i) {
+			t.Fatalf("event %d has sequence %s", i, ev.Data["Sequence"])
+		}
+	}
+	for i := 1; i <= 9; i += 2 {
+		if ev := <-odd.Events(); ev.Data["Sequence"] != strconv.Itoa(i) {
+			t.Fatalf("filtered event has sequence %s, want %d", ev.Data["Sequence"], i)
+		}
+	}
+	/* 两个消费者共用一个订阅 */
+	if created := fake.count(&fake.created); created != 1 {
+		t.Fatalf("%d subscriptions created for two consumers", created)
+	}
+	all.Close()
+	if open := fake.openSubscriptions(); open != 1 {
+		t.Fatalf("%d subscriptions open with a consumer left", open)
+	}
+	/* 最后一个消费者关闭后取消订阅 */
+	odd.Close()
+	eventually(t, "the subscription cancelled", func() bool { return fake.openSubscriptions() == 0 })
+}
+
+func TestEventEngineSubscriptionLimit(t *testing.T) {
+	/* 其他客户端已占满设备的拉取点 */
+	fake := &eventDevice{maxPullPoints: 1, open: map[string]bool{"/other": true}}
+	dev := newEventDevice(t, fake)
+	capacity, err := dev.SubscriptionCapacity(context.Background())
+	if err != nil || capacity.MaxPullPoints != 1 || capacity.MaxNotificationProducers != 4 || !capacity.PullPointSupport {
+		t.Fatalf("capacity %+v, %v", capacity, err)
+	}
+	engine := testEngine(1)
+	var mutex sync.Mutex
+	var limit *SubscriptionLimitError
+	engine.OnError = func(dev *Device, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if errors.Is(err, ErrSubscriptionLimit) {
+			errors.As(err, &limit)
+		}
+	}
+	engine.Add(dev)
+	runEngine(t, engine)
+	eventually(t, "the subscription limit error", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return limit != nil
+	})
+	mutex.Lock()
+	defer mutex.Unlock()
+	if limit.MaxPullPoints != 1 || limit.Device != dev.Params.Ipddr {
+		t.Fatalf("limit error %v", limit)
+	}
+}
@@ -0,0 +1,551 @@
+package onvif
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+// EventEngine pull events from many devices over a bounded pool of workers.
+// Devices are served in the order their next pull is due, so every device
+// gets its turn whatever the number of devices; a device whose pull fails is
+// delayed with an exponential backoff and its subscription is cancelled and
+// recreated, unless the pull only timed out.
+// Subscriptions are renewed once half of their termination time has elapsed.
+// The configuration events of a device drop its cached capabilities and
+// profiles, see WatchConfiguration.
+//
+// With a Store, the subscriptions are saved and left on the devices when Run
+// returns; the next Run resumes them with Renew and SetSynchronizationPoint,
+// so devices capping their concurrent subscriptions are not filled with the
+// subscriptions of previous processes.
+type EventEngine struct {
+	// Workers number of pulls in flight, 0 means 16
+	Workers int
+	// PullTimeout long poll timeout sent with PullMessages, 0 means 5s
+	PullTimeout time.Duration
+	// MessageLimit maximum number of messages per pull, 0 means 32
+	MessageLimit int
+	// TerminationTime initial termination time requested for subscriptions, 0 means 60s
+	TerminationTime time.Duration
+	// MinBackoff and MaxBackoff bound the retry delay of failing devices,
+	// 0 means 1s and 5min
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Filter optional filter of the subscriptions
+	Filter *EventFilter
+	// OnError optional callback for pull and subscription errors
+	OnError func(dev *Device, err error)
+	// Store optional persistence of the subscriptions
+	Store SubscriptionStore
+	// Backpressure policy of the Events channel when it is full, and
+	// MaxBlock longest wait of BackpressureBlock, 0 waiting until ctx ends
+	Backpressure Backpressure
+	MaxBlock     time.Duration
+	// FloodLimit events of a device within FloodWindow beyond which the
+	// device is quarantined, see Device.Quarantine; 0 disables the limit.
+	// FloodWindow 0 means 1 minute.
+	FloodLimit  int
+	FloodWindow time.Duration
+
+	events   chan Event
+	handlers []func(Event)
+	wake     chan struct{}
+
+	mutex         sync.Mutex
+	queue         subscriptionQueue
+	subscriptions map[*Device]*pullSubscription
+	/* 上次运行保存的订阅,按设备地址索引,恢复后删除 */
+	stored map[string]SubscriptionRecord
+	/* 各设备的进程内消费者,共用设备的同一个订阅 */
+	consumers map[*Device]map[*EventConsumer]struct{}
+	/* 各设备的配置变更监视,见WatchConfiguration */
+	watches map[*Device]map[*configWatch]struct{}
+	running bool
+	// counters of the Events channel
+	counters eventCounters
+}
+
+// pullSubscription pull point subscription of one device
+type pullSubscription struct {
+	dev       *Device
+	reference event.EndpointReferenceType
+	renewed   time.Time
+	failures  int
+	due       time.Time
+	/* 由Add显式添加,否则在最后一个消费者取消时移除 */
+	explicit bool
+	/* 当前计数窗口的起点和事件数,用于检测事件洪泛 */
+	windowStart  time.Time
+	windowEvents int
+	/* 在队列中的下标, -1表示正在被worker处理或已移除 */
+	index   int
+	removed bool
+}
+
+// NewEventEngine return an engine with the given number of workers
+func NewEventEngine(workers int) *EventEngine {
+	return &EventEngine{
+		Workers:       workers,
+		wake:          make(chan struct{}, 1),
+		subscriptions: make(map[*Device]*pullSubscription),
+	}
+}
+
+// Events return the channel of the events of every device, it is closed
+// when Run returns. The channel is created by the first call, once created it
+// must be drained or the workers stall, unless Backpressure drops the events.
+func (engine *EventEngine) Events() <-chan Event {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	if engine.events == nil {
+		engine.events = make(chan Event, 1024)
+	}
+	return engine.events
+}
+
+// Handle register a handler called by the workers for every event, before
+// it is sent on the Events channel. Handlers must not block.
+func (engine *EventEngine) Handle(handler func(Event)) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	engine.handlers = append(engine.handlers, handler)
+}
+
+// Add start pulling the events of dev, the subscription is created by a worker
+func (engine *EventEngine) Add(dev *Device) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	engine.add(dev).explicit = true
+}
+
+/* 调用方持有mutex */
+func (engine *EventEngine) add(dev *Device) *pullSubscription {
+	if sub, ok := engine.subscriptions[dev]; ok {
+		return sub
+	}
+	if engine.subscriptions == nil {
+		engine.subscriptions = make(map[*Device]*pullSubscription)
+	}
+	sub := &pullSubscription{dev: dev, due: time.Now()}
+	engine.subscriptions[dev] = sub
+	heap.Push(&engine.queue, sub)
+	engine.notify()
+	dev.attachEngine(engine)
+	return sub
+}
+
+// Remove stop pulling the events of dev, a pull in progress is finished
+// before the subscription is cancelled
+func (engine *EventEngine) Remove(dev *Device) {
+	engine.remove(dev, false)
+}
+
+// remove stop pulling the events of dev; with unused, only when the device
+// was not added with Add and has no consumer left
+func (engine *EventEngine) remove(dev *Device, unused bool) {
+	engine.mutex.Lock()
+	sub, ok := engine.subscriptions[dev]
+	if ok && unused && (sub.explicit || len(engine.consumers[dev]) > 0 || len(engine.watches[dev]) > 0) {
+		ok = false
+	}
+	queued := false
+	if ok {
+		delete(engine.subscriptions, dev)
+		dev.detachEngine(engine)
+		sub.removed = true
+		if sub.index >= 0 {
+			heap.Remove(&engine.queue, sub.index)
+			queued = true
+		}
+	}
+	engine.mutex.Unlock()
+	/* 正在被worker处理的订阅由reschedule取消 */
+	if queued && sub.reference.Address != "" {
+		engine.cancel(sub)
+	}
+	if ok && engine.Store != nil {
+		if err := engine.Store.DeleteSubscription(dev.Params.Ipddr); err != nil {
+			engine.reportError(dev, err)
+		}
+	}
+}
+
+// cancel unsubscribe with a short timeout of its own
+func (engine *EventEngine) cancel(sub *pullSubscription) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	engine.unsubscribe(ctx, sub)
+}
+
+func (engine *EventEngine) notify() {
+	select {
+	case engine.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (engine *EventEngine) workers() int {
+	if engine.Workers > 0 {
+		return engine.Workers
+	}
+	return 16
+}
+
+func durationOr(value, fallback time.Duration) time.Duration {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// Run pull events until ctx is done, then unsubscribe every device and close
+// the events channel. With a Store the subscriptions are kept instead.
+func (engine *EventEngine) Run(ctx context.Context) error {
+	if engine.Store != nil {
+		records, err := engine.Store.LoadSubscriptions()
+		if err != nil {
+			return err
+		}
+		engine.mutex.Lock()
+		engine.stored = make(map[string]SubscriptionRecord, len(records))
+		for _, record := range records {
+			engine.stored[record.Device] = record
+		}
+		engine.mutex.Unlock()
+	}
+	engine.mutex.Lock()
+	engine.running = true
+	engine.mutex.Unlock()
+	work := make(chan *pullSubscription)
+	var wg sync.WaitGroup
+	for i := 0; i < engine.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sub := range work {
+				engine.reschedule(sub, engine.pull(ctx, sub))
+			}
+		}()
+	}
+	engine.schedule(ctx, work)
+	close(work)
+	wg.Wait()
+	/* 退出时取消所有订阅,持久化的订阅留待下次恢复 */
+	engine.mutex.Lock()
+	engine.running = false
+	subs := make([]*pullSubscription, 0, len(engine.subscriptions))
+	for _, sub := range engine.subscriptions {
+		subs = append(subs, sub)
+	}
+	engine.mutex.Unlock()
+	for _, sub := range subs {
+		if sub.reference.Address != "" && engine.Store == nil {
+			engine.cancel(sub)
+		}
+	}
+	engine.mutex.Lock()
+	if engine.events != nil {
+		close(engine.events)
+	}
+	engine.mutex.Unlock()
+	return ctx.Err()
+}
+
+// schedule hand the due subscriptions to the workers in due order
+func (engine *EventEngine) schedule(ctx context.Context, work chan<- *pullSubscription) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		engine.mutex.Lock()
+		var next *pullSubscription
+		wait := time.Hour
+		if engine.queue.Len() > 0 {
+			if head := engine.queue[0]; !head.due.After(time.Now()) {
+				next = heap.Pop(&engine.queue).(*pullSubscription)
+			} else {
+				wait = time.Until(head.due)
+			}
+		}
+		engine.mutex.Unlock()
+
+		if next != nil {
+			select {
+			case work <- next:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-engine.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// reschedule put the subscription back in the queue, failing devices are
+// delayed exponentially
+func (engine *EventEngine) reschedule(sub *pullSubscription, err error) {
+	/* 失败后尽力取消旧订阅,以免每次失败在设备上遗留一个拉取点;拉取超时则保留订阅重试 */
+	var timeout pullTimeoutError
+	if err != nil && !errors.As(err, &timeout) && sub.reference.Address != "" {
+		engine.cancel(sub)
+	}
+	engine.mutex.Lock()
+	if sub.removed {
+		engine.mutex.Unlock()
+		/* 在worker处理期间被移除,由该worker取消订阅 */
+		if sub.reference.Address != "" {
+			engine.cancel(sub)
+		}
+		return
+	}
+	defer engine.mutex.Unlock()
+	if err != nil {
+		sub.failures++
+		backoff := durationOr(engine.MinBackoff, time.Second)
+		for i := 1; i < sub.failures && backoff < durationOr(engine.MaxBackoff, 5*time.Minute); i++ {
+			backoff *= 2
+		}
+		if max := durationOr(engine.MaxBackoff, 5*time.Minute); backoff > max {
+			backoff = max
+		}
+		sub.due = time.Now().Add(backoff)
+	} else {
+		sub.failures = 0
+		sub.due = time.Now()
+	}
+	heap.Push(&engine.queue, sub)
+	engine.notify()
+}
+
+// pull one batch of messages of the subscription, creating it when needed
+func (engine *EventEngine) pull(ctx context.Context, sub *pullSubscription) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	/* 隔离的设备按退避重试,解除隔离后重新订阅 */
+	if err := sub.dev.checkQuarantine(); err != nil {
+		return err
+	}
+	termination := durationOr(engine.TerminationTime, time.Minute)
+	if sub.reference.Address == "" {
+		if err := engine.subscribe(ctx, sub); err != nil {
+			engine.reportError(sub.dev, err)
+			return err
+		}
+	} else if time.Since(sub.renewed) > termination/2 {
+		if _, err := sub.dev.Renew(ctx, sub.reference, termination); err != nil {
+			engine.reportError(sub.dev, err)
+			return err
+		}
+		sub.renewed = time.Now()
+		engine.save(sub)
+	}
+	timeout := durationOr(engine.PullTimeout, 5*time.Second)
+	limit := engine.MessageLimit
+	if limit <= 0 {
+		limit = 32
+	}
+	/* 请求超时需大于设备长轮询时间 */
+	pullCtx, cancel := context.WithTimeout(ctx, timeout+10*time.Second)
+	defer cancel()
+	response, err := sub.dev.PullMessages(pullCtx, sub.reference, timeout, limit)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		engine.reportError(sub.dev, err)
+		/* 网络超时不说明订阅已失效 */
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return pullTimeoutError{err}
+		}
+		return err
+	}
+	if err := engine.checkFlood(sub, len(response.NotificationMessage)); err != nil {
+		engine.reportError(sub.dev, err)
+		return err
+	}
+	for _, message := range response.NotificationMessage {
+		if !engine.deliver(ctx, sub.dev, NewEvent(sub.dev.Params.Ipddr, message)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// pullTimeoutError PullMessages timed out, the subscription is kept and pulled
+// again after the backoff
+type pullTimeoutError struct {
+	err error
+}
+
+func (err pullTimeoutError) Error() string {
+	return err.err.Error()
+}
+
+func (err pullTimeoutError) Unwrap() error {
+	return err.err
+}
+
+// checkFlood count the events pulled from the device and quarantine it when
+// they exceed FloodLimit within FloodWindow, the events are then dropped
+func (engine *EventEngine) checkFlood(sub *pullSubscription, events int) error {
+	if engine.FloodLimit <= 0 {
+		return nil
+	}
+	window := durationOr(engine.FloodWindow, time.Minute)
+	if now := time.Now(); now.Sub(sub.windowStart) > window {
+		sub.windowStart, sub.windowEvents = now, 0
+	}
+	sub.windowEvents += events
+	if sub.windowEvents <= engine.FloodLimit {
+		return nil
+	}
+	sub.dev.Quarantine(fmt.Sprintf("event flood, %d events within %s", sub.windowEvents, window))
+	sub.windowEvents = 0
+	return sub.dev.checkQuarantine()
+}
+
+// deliver hand ev to the handlers, the consumers of the device and the Events
+// channel, false when ctx ended while waiting for the channel
+func (engine *EventEngine) deliver(ctx context.Context, dev *Device, ev Event) bool {
+	engine.mutex.Lock()
+	events, handlers := engine.events, engine.handlers
+	engine.mutex.Unlock()
+	engine.configurationChanged(ctx, dev, ev)
+	for _, handler := range handlers {
+		handler(ev)
+	}
+	if !engine.dispatch(ctx, dev, ev) {
+		return false
+	}
+	if events == nil {
+		return true
+	}
+	return offer(ctx, nil, events, ev, engine.Backpressure, engine.MaxBlock, &engine.counters)
+}
+
+// EventStats return the events delivered to the Events channel and the
+// events dropped by Backpressure
+func (engine *EventEngine) EventStats() ConsumerStats {
+	return engine.counters.stats()
+}
+
+func (engine *EventEngine) subscribe(ctx context.Context, sub *pullSubscription) error {
+	if engine.resume(ctx, sub) {
+		return nil
+	}
+	response, err := sub.dev.CreatePullPointSubscription(ctx, engine.Filter, durationOr(engine.TerminationTime, time.Minute))
+	if err != nil {
+		/* 仅资源类故障说明订阅已被其他客户端占满 */
+		if !subscriptionLimitFault(err) {
+			return err
+		}
+		limit := &SubscriptionLimitError{Device: sub.dev.Params.Ipddr, Err: err}
+		if capacity, capErr := sub.dev.SubscriptionCapacity(ctx); capErr == nil {
+			limit.MaxPullPoints = capacity.MaxPullPoints
+		}
+		return limit
+	}
+	sub.reference = response.SubscriptionReference
+	sub.renewed = time.Now()
+	engine.save(sub)
+	return nil
+}
+
+// resume take over the subscription saved for the device by a previous run,
+// it reports false when there is none or the device no longer knows it
+func (engine *EventEngine) resume(ctx context.Context, sub *pullSubscription) bool {
+	engine.mutex.Lock()
+	record, ok := engine.stored[sub.dev.Params.Ipddr]
+	delete(engine.stored, sub.dev.Params.Ipddr)
+	engine.mutex.Unlock()
+	if !ok || time.Now().After(record.Expires) {
+		return false
+	}
+	termination := durationOr(engine.TerminationTime, time.Minute)
+	if _, err := sub.dev.Renew(ctx, record.Reference, termination); err != nil {
+		return false
+	}
+	sub.reference = record.Reference
+	sub.renewed = time.Now()
+	engine.save(sub)
+	/* 让设备重新发送属性当前状态,补上停机期间的变化 */
+	if err := sub.dev.SetSynchronizationPoint(ctx, sub.reference); err != nil {
+		engine.reportError(sub.dev, err)
+	}
+	return true
+}
+
+func (engine *EventEngine) save(sub *pullSubscription) {
+	if engine.Store == nil {
+		return
+	}
+	record := SubscriptionRecord{
+		Device:    sub.dev.Params.Ipddr,
+		Reference: sub.reference,
+		Expires:   sub.renewed.Add(durationOr(engine.TerminationTime, time.Minute)),
+	}
+	if err := engine.Store.SaveSubscription(record); err != nil {
+		engine.reportError(sub.dev, err)
+	}
+}
+
+func (engine *EventEngine) unsubscribe(ctx context.Context, sub *pullSubscription) {
+	if err := sub.dev.Unsubscribe(ctx, sub.reference); err != nil {
+		engine.reportError(sub.dev, err)
+	}
+	sub.reference = event.EndpointReferenceType{}
+}
+
+func (engine *EventEngine) reportError(dev *Device, err error) {
+	if engine.OnError != nil {
+		engine.OnError(dev, err)
+	}
+}
+
+// subscriptionQueue min-heap of subscriptions ordered by due time
+type subscriptionQueue []*pullSubscription
+
+func (queue subscriptionQueue) Len() int { return len(queue) }
+
+func (queue subscriptionQueue) Less(i, j int) bool { return queue[i].due.Before(queue[j].due) }
+
+func (queue subscriptionQueue) Swap(i, j int) {
+	queue[i], queue[j] = queue[j], queue[i]
+	queue[i].index = i
+	queue[j].index = j
+}
+
+func (queue *subscriptionQueue) Push(x interface{}) {
+	sub := x.(*pullSubscription)
+	sub.index = len(*queue)
+	*queue = append(*queue, sub)
+}
+
+func (queue *subscriptionQueue) Pop() interface{} {
+	old := *queue
+	sub := old[len(old)-1]
+	old[len(old)-1] = nil
+	sub.index = -1
+	*queue = old[:len(old)-1]
+	return sub
+}
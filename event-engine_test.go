@@ -0,0 +1,255 @@
+package onvif
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/* 事件服务: 每个拉取点一个路径,记录设备上仍打开的订阅 */
+type eventDevice struct {
+	mutex sync.Mutex
+	// open subscriptions by path
+	open    map[string]bool
+	created int
+	pulls   int
+	renewed int
+	synced  int
+	// failPulls PullMessages answered with a fault before the pulls succeed
+	failPulls int
+	// stallPulls PullMessages held for stall before being answered
+	stallPulls int
+	stall      time.Duration
+	// messages notification messages answered to each pull, numbered from 1
+	messages int
+	sequence int
+	// maxPullPoints subscriptions accepted at once, 0 without limit
+	maxPullPoints int
+}
+
+func (fake *eventDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := ioutil.ReadAll(r.Body)
+	request := string(data)
+	fake.mutex.Lock()
+	body, stall := fake.answer(r, request)
+	fake.mutex.Unlock()
+	if stall > 0 {
+		select {
+		case <-time.After(stall):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if strings.HasPrefix(body, "<s:Fault>") {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:ter="http://www.onvif.org/ver10/error"` +
+		` xmlns:tev="http://www.onvif.org/ver10/events/wsdl" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2" xmlns:wsa="http://www.w3.org/2005/08/addressing"><s:Body>` + body + `</s:Body></s:Envelope>`))
+}
+
+/* 调用方持有mutex */
+func (fake *eventDevice) answer(r *http.Request, request string) (string, time.Duration) {
+	if fake.open == nil {
+		fake.open = make(map[string]bool)
+	}
+	switch {
+	case strings.Contains(request, "GetServiceCapabilities"):
+		return fmt.Sprintf(`<tev:GetServiceCapabilitiesResponse><tev:Capabilities WSPullPointSupport="true" MaxPullPoints="%d" MaxNotificationProducers="4"/></tev:GetServiceCapabilitiesResponse>`, fake.maxPullPoints), 0
+	case strings.Contains(request, "CreatePullPointSubscription"):
+		if fake.maxPullPoints > 0 && len(fake.open) >= fake.maxPullPoints {
+			return eventFault("ter:MaxPullPoints"), 0
+		}
+		fake.created++
+		path := fmt.Sprintf("/subscription/%d", fake.created)
+		fake.open[path] = true
+		return `<tev:CreatePullPointSubscriptionResponse><tev:SubscriptionReference><wsa:Address>http://` + r.Host + path +
+			`</wsa:Address></tev:SubscriptionReference></tev:CreatePullPointSubscriptionResponse>`, 0
+	}
+	/* 其余请求发送至订阅地址 */
+	if !fake.open[r.URL.Path] {
+		return eventFault("wsrf-rw:ResourceUnknownFault"), 0
+	}
+	switch {
+	case strings.Contains(request, "PullMessages"):
+		fake.pulls++
+		if fake.failPulls > 0 {
+			fake.failPulls--
+			return eventFault("ter:Action"), 0
+		}
+		stall := time.Duration(0)
+		if fake.stallPulls > 0 {
+			fake.stallPulls--
+			stall = fake.stall
+		}
+		body := `<tev:PullMessagesResponse>`
+		for i := 0; i < fake.messages; i++ {
+			fake.sequence++
+			body += `<wsnt:NotificationMessage><wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">tns1:VideoSource/MotionAlarm</wsnt:Topic>` +
+				`<wsnt:Message><tt:Message UtcTime="2026-10-16T10:00:00Z" PropertyOperation="Changed"><tt:Source><tt:SimpleItem Name="Source" Value="vs0"/></tt:Source>` +
+				fmt.Sprintf(`<tt:Data><tt:SimpleItem Name="State" Value="true"/><tt:SimpleItem Name="Sequence" Value="%d"/></tt:Data></tt:Message></wsnt:Message></wsnt:NotificationMessage>`, fake.sequence)
+		}
+		return body + `</tev:PullMessagesResponse>`, stall
+	case strings.Contains(request, "Renew"):
+		fake.renewed++
+		return `<wsnt:RenewResponse><wsnt:TerminationTime>2026-10-16T10:01:00Z</wsnt:TerminationTime></wsnt:RenewResponse>`, 0
+	case strings.Contains(request, "SetSynchronizationPoint"):
+		fake.synced++
+		return `<tev:SetSynchronizationPointResponse/>`, 0
+	case strings.Contains(request, "Unsubscribe"):
+		delete(fake.open, r.URL.Path)
+		return `<wsnt:UnsubscribeResponse/>`, 0
+	}
+	return eventFault("ter:ActionNotSupported"), 0
+}
+
+func eventFault(subcode string) string {
+	return `<s:Fault><s:Code><s:Value>s:Receiver</s:Value><s:Subcode><s:Value>` + subcode + `</s:Value></s:Subcode></s:Code>` +
+		`<s:Reason><s:Text>` + subcode + `</s:Text></s:Reason></s:Fault>`
+}
+
+func (fake *eventDevice) count(counter *int) int {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return *counter
+}
+
+func (fake *eventDevice) openSubscriptions() int {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return len(fake.open)
+}
+
+func newEventDevice(t *testing.T, fake *eventDevice) *Device {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://")})
+	dev.endpoints[ServiceDevice] = server.URL
+	dev.endpoints[ServiceEvents] = server.URL
+	dev.capabilities.servicesLoaded = true
+	return dev
+}
+
+/* 引擎的短退避,测试不必等待默认的1s */
+func testEngine(workers int) *EventEngine {
+	engine := NewEventEngine(workers)
+	engine.MinBackoff = time.Millisecond
+	engine.MaxBackoff = 10 * time.Millisecond
+	engine.PullTimeout = time.Second
+	return engine
+}
+
+// runEngine run the engine until the test ends, the returned function stops
+// it and waits for Run to return
+func runEngine(t *testing.T, engine *EventEngine) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		engine.Run(ctx)
+	}()
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+// eventually wait up to 5s for condition
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestEventEngineCancelsFailedSubscriptions(t *testing.T) {
+	fake := &eventDevice{failPulls: 5}
+	dev := newEventDevice(t, fake)
+	engine := testEngine(2)
+	engine.Add(dev)
+	stop := runEngine(t, engine)
+	eventually(t, "a successful pull", func() bool { return fake.count(&fake.pulls) > 6 })
+	/* 每次失败都取消旧订阅,设备上只留一个 */
+	if open, created := fake.openSubscriptions(), fake.count(&fake.created); open != 1 || created != 6 {
+		t.Fatalf("%d subscriptions open, %d created", open, created)
+	}
+	stop()
+	if open := fake.openSubscriptions(); open != 0 {
+		t.Fatalf("%d subscriptions left open by Run", open)
+	}
+}
+
+func TestEventEngineKeepsSubscriptionOnPullTimeout(t *testing.T) {
+	fake := &eventDevice{stallPulls: 2, stall: time.Second}
+	dev := newEventDevice(t, fake)
+	dev.httpClient = &http.Client{Timeout: 100 * time.Millisecond}
+	engine := testEngine(1)
+	engine.Add(dev)
+	runEngine(t, engine)
+	eventually(t, "a pull after the timeouts", func() bool { return fake.count(&fake.pulls) > 3 })
+	if open, created := fake.openSubscriptions(), fake.count(&fake.created); open != 1 || created != 1 {
+		t.Fatalf("%d subscriptions open, %d created after pull timeouts", open, created)
+	}
+}
+
+func TestEventEngineBackoff(t *testing.T) {
+	engine := NewEventEngine(1)
+	engine.MinBackoff, engine.MaxBackoff = time.Second, 5*time.Second
+	sub := &pullSubscription{dev: newDevice(DeviceParams{Ipddr: "10.1.1.200"})}
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		engine.reschedule(sub, errors.New("pull failed"))
+		heap.Pop(&engine.queue)
+		if delay := time.Until(sub.due); delay > backoff || delay < backoff-time.Second/2 {
+			t.Fatalf("failure %d delayed %s, want %s", sub.failures, delay, backoff)
+		}
+	}
+	/* 成功后立即再次拉取 */
+	engine.reschedule(sub, nil)
+	if sub.failures != 0 || sub.due.After(time.Now()) {
+		t.Fatalf("failures %d, due in %s after a success", sub.failures, time.Until(sub.due))
+	}
+}
+
+func TestEventEngineFairness(t *testing.T) {
+	/* 单个worker时,持续失败的设备不影响其他设备的拉取 */
+	failing := &eventDevice{failPulls: 1 << 30}
+	healthy := []*eventDevice{{messages: 1}, {messages: 1}}
+	engine := testEngine(1)
+	engine.MinBackoff, engine.MaxBackoff = 50*time.Millisecond, 50*time.Millisecond
+	var mutex sync.Mutex
+	received := make(map[string]int)
+	engine.Handle(func(ev Event) {
+		mutex.Lock()
+		received[ev.Device]++
+		mutex.Unlock()
+	})
+	engine.Add(newEventDevice(t, failing))
+	var addresses []string
+	for _, fake := range healthy {
+		dev := newEventDevice(t, fake)
+		addresses = append(addresses, dev.Params.Ipddr)
+		engine.Add(dev)
+	}
+	start := time.Now()
+	runEngine(t, engine)
+	eventually(t, "the events of every healthy device", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return received[addresses[0]] >= 20 && received[addresses[1]] >= 20
+	})
+	/* 失败的设备按退避间隔重试 */
+	if pulls, most := failing.count(&failing.pulls), int(time.Since(start)/(50*time.Millisecond))+2; pulls > most {
+		t.Fatalf("failing device pulled %d times, at most %d expected", pulls, most)
+	}
+}
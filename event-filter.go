@@ -0,0 +1,88 @@
+package onvif
+
+import (
+	"fmt"
+	"strings"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+	"github.com/PolarisM78/go-onvif/xsd"
+)
+
+// Filter dialects defined by the ONVIF event service
+const (
+	TopicDialectConcreteSet  = "http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet"
+	MessageContentItemFilter = "http://www.onvif.org/ver10/tev/messageContentFilter/ItemFilter"
+)
+
+// EventFilter builder of the filter sent with a subscription. Topics are
+// alternatives, message content expressions must all hold. Message content
+// filtering is optional in the specification, devices which do not honor it
+// still deliver every message of the selected topics.
+type EventFilter struct {
+	Topics      []string
+	Expressions []string
+}
+
+// NewEventFilter return an empty filter matching every event
+func NewEventFilter() *EventFilter {
+	return &EventFilter{}
+}
+
+// Topic add a concrete topic, e.g. tns1:RuleEngine/CellMotionDetector/Motion
+func (filter *EventFilter) Topic(topic string) *EventFilter {
+	filter.Topics = append(filter.Topics, strings.TrimSpace(topic))
+	return filter
+}
+
+// Content add a raw message content expression, e.g.
+// boolean(//tt:SimpleItem[@Name="IsMotion" and @Value="true"])
+func (filter *EventFilter) Content(expression string) *EventFilter {
+	filter.Expressions = append(filter.Expressions, strings.TrimSpace(expression))
+	return filter
+}
+
+// SimpleItem keep the messages holding a simple item name with value
+func (filter *EventFilter) SimpleItem(name, value string) *EventFilter {
+	return filter.Content(fmt.Sprintf("boolean(//tt:SimpleItem[@Name=%s and @Value=%s])", xpathLiteral(name), xpathLiteral(value)))
+}
+
+// Source keep the messages whose source holds a simple item name with value,
+// e.g. Source("VideoSourceConfigurationToken", "vsconf")
+func (filter *EventFilter) Source(name, value string) *EventFilter {
+	return filter.Content(fmt.Sprintf("boolean(//tt:Source/tt:SimpleItem[@Name=%s and @Value=%s])", xpathLiteral(name), xpathLiteral(value)))
+}
+
+// FilterType return the wsnt:Filter of the subscription, nil when the
+// filter is empty
+func (filter *EventFilter) FilterType() *event.FilterType {
+	if filter == nil || (len(filter.Topics) == 0 && len(filter.Expressions) == 0) {
+		return nil
+	}
+	result := &event.FilterType{}
+	if len(filter.Topics) > 0 {
+		result.TopicExpression = event.TopicExpressionType{
+			Dialect:    TopicDialectConcreteSet,
+			TopicKinds: xsd.String(strings.Join(filter.Topics, "|")),
+		}
+	}
+	if len(filter.Expressions) > 0 {
+		result.MessageContent = event.QueryExpressionType{
+			Dialect:     MessageContentItemFilter,
+			MessageKind: xsd.String(strings.Join(filter.Expressions, " and ")),
+		}
+	}
+	return result
+}
+
+// xpathLiteral quote value as an XPath 1.0 string literal, which has no
+// escape sequence
+func xpathLiteral(value string) string {
+	if !strings.Contains(value, `"`) {
+		return `"` + value + `"`
+	}
+	if !strings.Contains(value, `'`) {
+		return `'` + value + `'`
+	}
+	parts := strings.Split(value, `"`)
+	return `concat("` + strings.Join(parts, `", '"', "`) + `")`
+}
@@ -0,0 +1,48 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+func TestXPathLiteral(t *testing.T) {
+	for value, literal := range map[string]string{
+		`IsMotion`:   `"IsMotion"`,
+		`say "hi"`:   `'say "hi"'`,
+		`it's "odd"`: `concat("it's ", '"', "odd", '"', "")`,
+	} {
+		if got := xpathLiteral(value); got != literal {
+			t.Fatalf("literal of %q is %s, want %s", value, got, literal)
+		}
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	if NewEventFilter().FilterType() != nil || (*EventFilter)(nil).FilterType() != nil {
+		t.Fatal("empty filter sent")
+	}
+	filter := NewEventFilter().Topic(" tns1:RuleEngine/CellMotionDetector/Motion").Topic("tns1:VideoSource/MotionAlarm").
+		SimpleItem("IsMotion", "true").Source("VideoSourceConfigurationToken", "vsconf")
+	output, err := xml.Marshal(event.CreatePullPointSubscription{Filter: filter.FilterType()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := string(output)
+	for _, want := range []string{
+		`<wsnt:TopicExpression Dialect="` + TopicDialectConcreteSet + `">tns1:RuleEngine/CellMotionDetector/Motion|tns1:VideoSource/MotionAlarm</wsnt:TopicExpression>`,
+		`<wsnt:MessageContent Dialect="` + MessageContentItemFilter + `">boolean(//tt:SimpleItem[@Name=&#34;IsMotion&#34; and @Value=&#34;true&#34;]) and ` +
+			`boolean(//tt:Source/tt:SimpleItem[@Name=&#34;VideoSourceConfigurationToken&#34; and @Value=&#34;vsconf&#34;])</wsnt:MessageContent>`,
+	} {
+		if !strings.Contains(request, want) {
+			t.Fatalf("request without %s: %s", want, request)
+		}
+	}
+	/* 只有内容表达式时不发送空的主题表达式 */
+	output, _ = xml.Marshal(event.CreatePullPointSubscription{Filter: NewEventFilter().Content("boolean(//tt:SimpleItem)").FilterType()})
+	if strings.Contains(string(output), "TopicExpression") || !strings.Contains(string(output), "MessageContent") {
+		t.Fatalf("request %s", output)
+	}
+}
@@ -0,0 +1,46 @@
+package onvif
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+// SimulatedEvent return an event of topic, e.g.
+// tns1:RuleEngine/CellMotionDetector/Motion, changed now, for Inject. The
+// source and data items may be nil.
+//
+//	engine.Inject(ctx, dev, onvif.SimulatedEvent("tns1:RuleEngine/CellMotionDetector/Motion",
+//		map[string]string{"VideoSourceConfigurationToken": "vsconf"}, map[string]string{"IsMotion": "true"}))
+func SimulatedEvent(topic string, source, data map[string]string) Event {
+	now := time.Now()
+	return Event{Topic: topic, Operation: "Changed", Time: now, ReceivedAt: now, Source: source, Data: data}
+}
+
+// Inject deliver ev as if it had been pulled from dev: to the handlers, to
+// the consumers of dev and to the Events channel, following the
+// backpressure policies. It lets the automation downstream of the engine,
+// e.g. webhooks and recording triggers, be tested end to end without a real
+// event in front of the camera. The device of ev is set to dev, its times
+// to now when zero, and its topic is mapped like the pulled ones, see
+// NormalizeEvent; dev needs not be added to the engine. It reports false
+// when ctx ended while a consumer blocked.
+func (engine *EventEngine) Inject(ctx context.Context, dev *Device, ev Event) bool {
+	ev.Device = dev.Params.Ipddr
+	ev.Topic = strings.TrimSpace(ev.Topic)
+	if ev.ReceivedAt.IsZero() {
+		ev.ReceivedAt = time.Now()
+	}
+	if ev.Time.IsZero() {
+		ev.Time = ev.ReceivedAt
+	}
+	return engine.deliver(ctx, dev, NormalizeEvent(ev))
+}
+
+// InjectMessage deliver a notification message as if it had been pulled
+// from dev, the message being normalized like the pulled ones, see Inject
+func (engine *EventEngine) InjectMessage(ctx context.Context, dev *Device, message event.NotificationMessage) bool {
+	return engine.deliver(ctx, dev, NewEvent(dev.Params.Ipddr, message))
+}
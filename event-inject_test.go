@@ -0,0 +1,77 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"sync"
+	"testing"
+	"time"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+func TestInject(t *testing.T) {
+	engine := testEngine(1)
+	dev := newDevice(DeviceParams{Ipddr: "192.168.1.10"})
+	var mutex sync.Mutex
+	var handled []Event
+	engine.Handle(func(ev Event) {
+		mutex.Lock()
+		handled = append(handled, ev)
+		mutex.Unlock()
+	})
+	events := engine.Events()
+	consumed, stop := engine.Subscribe(dev, nil, 4)
+	defer stop()
+	ctx := context.Background()
+
+	/* 注入的厂商事件与拉取的一样映射到ONVIF主题 */
+	if !engine.Inject(ctx, dev, Event{Topic: " tnsaxis:CameraApplicationPlatform/VMD/Camera1Profile1 ", Data: map[string]string{"active": "1"}}) {
+		t.Fatal("event not delivered")
+	}
+	for _, ev := range []Event{<-events, <-consumed} {
+		if ev.Device != "192.168.1.10" || ev.Topic != "tns1:"+TopicCellMotion || ev.VendorTopic != "tnsaxis:CameraApplicationPlatform/VMD/Camera1Profile1" ||
+			ev.Data["IsMotion"] != "1" || ev.ReceivedAt.IsZero() || !ev.Time.Equal(ev.ReceivedAt) {
+			t.Fatalf("event %+v", ev)
+		}
+	}
+	simulated := SimulatedEvent("tns1:Device/Trigger/DigitalInput", map[string]string{"InputToken": "di0"}, map[string]string{"LogicalState": "true"})
+	simulated.Time = time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	engine.Inject(ctx, dev, simulated)
+	if ev := <-events; ev.Operation != "Changed" || ev.Source["InputToken"] != "di0" || !ev.Time.Equal(simulated.Time) {
+		t.Fatalf("event %+v", ev)
+	}
+	<-consumed
+
+	message := event.NotificationMessage{}
+	if err := xml.Unmarshal([]byte(notificationMessage), &message); err != nil {
+		t.Fatal(err)
+	}
+	engine.InjectMessage(ctx, dev, message)
+	if ev := <-events; ev.Device != "192.168.1.10" || ev.Topic != "tns1:VideoSource/MotionAlarm" || ev.Data["State"] != "true" {
+		t.Fatalf("event %+v", ev)
+	}
+	<-consumed
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(handled) != 3 {
+		t.Fatalf("%d events handled", len(handled))
+	}
+}
+
+func TestInjectBlocked(t *testing.T) {
+	engine := testEngine(1)
+	dev := newDevice(DeviceParams{Ipddr: "192.168.1.10"})
+	consumer := engine.Consume(dev, ConsumerOptions{Buffer: 1, Backpressure: BackpressureBlock})
+	defer consumer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ev := SimulatedEvent("tns1:Device/Trigger/DigitalInput", nil, nil)
+	if !engine.Inject(ctx, dev, ev) {
+		t.Fatal("first event not delivered")
+	}
+	/* 消费者未读取时阻塞至ctx结束 */
+	if engine.Inject(ctx, dev, ev) {
+		t.Fatal("event delivered to a full consumer")
+	}
+}
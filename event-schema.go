@@ -0,0 +1,85 @@
+package onvif
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EventSchemaVersion major version of the JSON form of the events, fields are
+// only added within a version
+const EventSchemaVersion = 1
+
+// EventJSONSchema JSON schema of EventJSON, published for the consumers of
+// the sinks and of the gateway written in other languages
+//
+//go:embed schema/event-v1.json
+var EventJSONSchema string
+
+// EventJSON JSON form of an Event, as published by the sinks and the
+// gateway, see EventJSONSchema
+type EventJSON struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	Device        string            `json:"device"`
+	Topic         string            `json:"topic"`
+	VendorTopic   string            `json:"vendorTopic,omitempty"`
+	Operation     string            `json:"operation,omitempty"`
+	Time          time.Time         `json:"time"`
+	ReceivedAt    time.Time         `json:"receivedAt"`
+	Source        map[string]string `json:"source,omitempty"`
+	Key           map[string]string `json:"key,omitempty"`
+	Data          map[string]string `json:"data,omitempty"`
+	Elements      map[string]string `json:"elements,omitempty"`
+}
+
+// NewEventJSON return the JSON form of ev
+func NewEventJSON(ev Event) EventJSON {
+	return EventJSON{
+		SchemaVersion: EventSchemaVersion,
+		Device:        ev.Device,
+		Topic:         ev.Topic,
+		VendorTopic:   ev.VendorTopic,
+		Operation:     ev.Operation,
+		Time:          ev.Time,
+		ReceivedAt:    ev.ReceivedAt,
+		Source:        ev.Source,
+		Key:           ev.Key,
+		Data:          ev.Data,
+		Elements:      ev.Elements,
+	}
+}
+
+// Event return the event of the JSON form
+func (ev EventJSON) Event() Event {
+	return Event{
+		Device:      ev.Device,
+		Topic:       ev.Topic,
+		VendorTopic: ev.VendorTopic,
+		Operation:   ev.Operation,
+		Time:        ev.Time,
+		ReceivedAt:  ev.ReceivedAt,
+		Source:      ev.Source,
+		Key:         ev.Key,
+		Data:        ev.Data,
+		Elements:    ev.Elements,
+	}
+}
+
+// MarshalEvent encode ev in its JSON form
+func MarshalEvent(ev Event) ([]byte, error) {
+	return json.Marshal(NewEventJSON(ev))
+}
+
+// UnmarshalEvent decode an event in its JSON form, the events of another
+// major version of the schema are rejected
+func UnmarshalEvent(data []byte) (Event, error) {
+	ev := EventJSON{}
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return Event{}, err
+	}
+	if ev.SchemaVersion != EventSchemaVersion {
+		return Event{}, fmt.Errorf("unsupported event schema version %d", ev.SchemaVersion)
+	}
+	return ev.Event(), nil
+}
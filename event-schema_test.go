@@ -0,0 +1,71 @@
+package onvif
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventJSON(t *testing.T) {
+	ev := Event{
+		Device:     "192.168.1.10",
+		Topic:      "tns1:RuleEngine/CellMotionDetector/Motion",
+		Operation:  "Changed",
+		Time:       time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+		ReceivedAt: time.Date(2026, 10, 16, 10, 0, 1, 0, time.UTC),
+		Source:     map[string]string{"VideoSourceConfigurationToken": "vsc0"},
+		Data:       map[string]string{"IsMotion": "true"},
+	}
+	data, err := MarshalEvent(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"schemaVersion":1`) || strings.Contains(string(data), "vendorTopic") {
+		t.Fatalf("json %s", data)
+	}
+	decoded, err := UnmarshalEvent(data)
+	if err != nil || !reflect.DeepEqual(decoded, ev) {
+		t.Fatalf("event %+v, %v", decoded, err)
+	}
+	/* 其他主版本的事件被拒绝 */
+	if _, err := UnmarshalEvent([]byte(`{"schemaVersion":2,"device":"d","topic":"t"}`)); err == nil {
+		t.Fatal("schema version 2 accepted")
+	}
+	if _, err := UnmarshalEvent([]byte(`{"device":"d"}`)); err == nil {
+		t.Fatal("event without schema version accepted")
+	}
+}
+
+func TestEventJSONSchema(t *testing.T) {
+	schema := struct {
+		ID         string                     `json:"$id"`
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}{}
+	if err := json.Unmarshal([]byte(EventJSONSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(schema.ID, "event-v1.json") {
+		t.Fatalf("schema id %q", schema.ID)
+	}
+	/* schema与EventJSON的字段一一对应 */
+	fields := map[string]bool{}
+	kind := reflect.TypeOf(EventJSON{})
+	for i := 0; i < kind.NumField(); i++ {
+		name := strings.Split(kind.Field(i).Tag.Get("json"), ",")[0]
+		fields[name] = true
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("field %s missing from the schema", name)
+		}
+	}
+	if len(schema.Properties) != len(fields) {
+		t.Errorf("schema properties %d, fields %d", len(schema.Properties), len(fields))
+	}
+	for _, name := range schema.Required {
+		if !fields[name] {
+			t.Errorf("required property %s is no field", name)
+		}
+	}
+}
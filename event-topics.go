@@ -0,0 +1,136 @@
+package onvif
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TopicMapping map the events of a vendor topic into the ONVIF topic tree,
+// so that the typed API, e.g. MotionOf or ParseDoorEvent, and the topic
+// filters see them as the standard events they stand for
+type TopicMapping struct {
+	// Vendor topic expression of the vendor events with their namespace
+	// prefixes, e.g. tns1:VideoSource/tnsaxis:Tampering. A trailing * matches
+	// the rest of the topic.
+	Vendor string
+	// Topic ONVIF topic the events are delivered under. A trailing * is
+	// replaced by the rest of the topic matched by the * of Vendor, e.g.
+	// hik:* to tns1:* moves the Hikvision namespace into the ONVIF one.
+	Topic string
+	// Items renames of the source and data items, from the vendor name to the
+	// ONVIF one, e.g. active to IsMotion
+	Items map[string]string
+}
+
+var (
+	topicMappingsMutex sync.RWMutex
+	// topicMappings mappings by vendor topic
+	topicMappings = map[string]TopicMapping{
+		/* 海康、大华在自有命名空间下沿用ONVIF主题树 */
+		"hik:*":      {Vendor: "hik:*", Topic: "tns1:*"},
+		"tnshik:*":   {Vendor: "tnshik:*", Topic: "tns1:*"},
+		"dahua:*":    {Vendor: "dahua:*", Topic: "tns1:*"},
+		"tnsdahua:*": {Vendor: "tnsdahua:*", Topic: "tns1:*"},
+		/* Axis的移动侦测和遮挡告警 */
+		"tnsaxis:CameraApplicationPlatform/VMD/*": {
+			Vendor: "tnsaxis:CameraApplicationPlatform/VMD/*",
+			Topic:  "tns1:" + TopicCellMotion,
+			Items:  map[string]string{"active": "IsMotion"},
+		},
+		"tns1:VideoSource/tnsaxis:Tampering": {
+			Vendor: "tns1:VideoSource/tnsaxis:Tampering",
+			Topic:  "tns1:VideoSource/GlobalSceneChange/ImagingService",
+			Items:  map[string]string{"tampering": "State"},
+		},
+	}
+)
+
+// RegisterTopicMapping add a mapping of vendor events into the ONVIF topic
+// tree, replacing the mapping of the same vendor topic. A mapping with an
+// empty Topic removes the mapping of the vendor topic.
+func RegisterTopicMapping(mapping TopicMapping) {
+	topicMappingsMutex.Lock()
+	defer topicMappingsMutex.Unlock()
+	if mapping.Topic == "" {
+		delete(topicMappings, mapping.Vendor)
+		return
+	}
+	topicMappings[mapping.Vendor] = mapping
+}
+
+// TopicMappings return the registered mappings, by vendor topic
+func TopicMappings() []TopicMapping {
+	topicMappingsMutex.RLock()
+	defer topicMappingsMutex.RUnlock()
+	mappings := make([]TopicMapping, 0, len(topicMappings))
+	for _, mapping := range topicMappings {
+		mappings = append(mappings, mapping)
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Vendor < mappings[j].Vendor })
+	return mappings
+}
+
+// NormalizeEvent return ev under the ONVIF topic of the mapping of its topic,
+// its items renamed and its topic kept in VendorTopic; ev is returned
+// unchanged when no mapping matches. The events pulled, notified or injected
+// are normalized on reception.
+func NormalizeEvent(ev Event) Event {
+	if ev.VendorTopic != "" {
+		return ev
+	}
+	mapping, rest, ok := lookupTopicMapping(ev.Topic)
+	if !ok {
+		return ev
+	}
+	ev.VendorTopic = ev.Topic
+	ev.Topic = mapping.Topic
+	if strings.HasSuffix(ev.Topic, "*") {
+		ev.Topic = strings.TrimSuffix(ev.Topic, "*") + rest
+	}
+	ev.Source = renameItems(ev.Source, mapping.Items)
+	ev.Data = renameItems(ev.Data, mapping.Items)
+	return ev
+}
+
+// lookupTopicMapping return the mapping of topic and the part of the topic
+// matched by its *, the longest matching vendor topic winning
+func lookupTopicMapping(topic string) (TopicMapping, string, bool) {
+	topicMappingsMutex.RLock()
+	defer topicMappingsMutex.RUnlock()
+	if mapping, ok := topicMappings[topic]; ok {
+		return mapping, "", true
+	}
+	found, rest, matched := TopicMapping{}, "", ""
+	for vendor, mapping := range topicMappings {
+		prefix := strings.TrimSuffix(vendor, "*")
+		if prefix == vendor || len(vendor) <= len(matched) || !strings.HasPrefix(topic, prefix) {
+			continue
+		}
+		found, rest, matched = mapping, topic[len(prefix):], vendor
+	}
+	return found, rest, matched != ""
+}
+
+// renameItems return items with the names of renames replaced, a copy when
+// any is renamed
+func renameItems(items map[string]string, renames map[string]string) map[string]string {
+	copied := false
+	for from, to := range renames {
+		value, ok := items[from]
+		if !ok {
+			continue
+		}
+		if !copied {
+			/* 注入的事件可能共用调用方的map */
+			renamed := make(map[string]string, len(items))
+			for name, value := range items {
+				renamed[name] = value
+			}
+			items, copied = renamed, true
+		}
+		delete(items, from)
+		items[to] = value
+	}
+	return items
+}
@@ -0,0 +1,59 @@
+package onvif
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestNormalizeEvent(t *testing.T) {
+	/* 厂商命名空间换成ONVIF命名空间,主题树不变 */
+	ev := NormalizeEvent(Event{Topic: "hik:RuleEngine/LineDetector/Crossed", Data: map[string]string{"ObjectId": "3"}})
+	if ev.Topic != "tns1:RuleEngine/LineDetector/Crossed" || ev.VendorTopic != "hik:RuleEngine/LineDetector/Crossed" || ev.Data["ObjectId"] != "3" {
+		t.Fatalf("event %+v", ev)
+	}
+	if again := NormalizeEvent(ev); again.Topic != ev.Topic || again.VendorTopic != ev.VendorTopic {
+		t.Fatalf("event normalized twice %+v", again)
+	}
+
+	/* 改名的条目不影响调用方的map */
+	data := map[string]string{"tampering": "1"}
+	ev = NormalizeEvent(Event{Topic: "tns1:VideoSource/tnsaxis:Tampering", Data: data})
+	if ev.Topic != "tns1:VideoSource/GlobalSceneChange/ImagingService" || ev.Data["State"] != "1" || len(ev.Data) != 1 {
+		t.Fatalf("event %+v", ev)
+	}
+	if data["tampering"] != "1" || len(data) != 1 {
+		t.Fatalf("caller data changed %v", data)
+	}
+
+	if ev := NormalizeEvent(Event{Topic: "tns1:VideoSource/MotionAlarm"}); ev.VendorTopic != "" || ev.Topic != "tns1:VideoSource/MotionAlarm" {
+		t.Fatalf("event %+v", ev)
+	}
+}
+
+func TestRegisterTopicMapping(t *testing.T) {
+	mapping := TopicMapping{Vendor: "hik:RuleEngine/Tamper/*", Topic: "tns1:VideoSource/GlobalSceneChange/AnalyticsService", Items: map[string]string{"tamper": "State"}}
+	RegisterTopicMapping(mapping)
+	defer RegisterTopicMapping(TopicMapping{Vendor: mapping.Vendor})
+	/* 最长的厂商主题优先 */
+	ev := NormalizeEvent(Event{Topic: "hik:RuleEngine/Tamper/Region1", Data: map[string]string{"tamper": "true"}})
+	if ev.Topic != mapping.Topic || ev.Data["State"] != "true" {
+		t.Fatalf("event %+v", ev)
+	}
+	if ev := NormalizeEvent(Event{Topic: "hik:RuleEngine/FieldDetector/ObjectsInside"}); ev.Topic != "tns1:RuleEngine/FieldDetector/ObjectsInside" {
+		t.Fatalf("event %+v", ev)
+	}
+	mappings := TopicMappings()
+	found := false
+	for _, registered := range mappings {
+		found = found || registered.Vendor == mapping.Vendor
+	}
+	if !found || !sort.SliceIsSorted(mappings, func(i, j int) bool { return mappings[i].Vendor < mappings[j].Vendor }) {
+		t.Fatalf("mappings %+v", mappings)
+	}
+
+	/* 空主题删除映射 */
+	RegisterTopicMapping(TopicMapping{Vendor: mapping.Vendor})
+	if ev := NormalizeEvent(Event{Topic: "hik:RuleEngine/Tamper/Region1"}); ev.Topic != "tns1:RuleEngine/Tamper/Region1" {
+		t.Fatalf("event %+v", ev)
+	}
+}
@@ -0,0 +1,74 @@
+package onvif
+
+import (
+	"strings"
+	"time"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+// Event normalized notification received from a device
+type Event struct {
+	// Device address of the device which produced the event
+	Device string
+	// Topic topic expression without surrounding spaces, e.g.
+	// tns1:RuleEngine/CellMotionDetector/Motion
+	Topic string
+	// VendorTopic topic the device sent the event under when it was mapped
+	// into the ONVIF topic tree, see TopicMapping
+	VendorTopic string
+	// Operation PropertyOperation of the message: Initialized, Changed or Deleted
+	Operation string
+	// Time UtcTime of the message, ReceivedAt when the device sent none
+	Time       time.Time
+	ReceivedAt time.Time
+	Source     map[string]string
+	Key        map[string]string
+	Data       map[string]string
+	// Elements raw XML of the element items of the data, by name, e.g. the
+	// Configuration of a RecordingJobConfiguration message
+	Elements map[string]string
+}
+
+func simpleItems(list event.ItemList) map[string]string {
+	if len(list.SimpleItem) == 0 {
+		return nil
+	}
+	items := make(map[string]string, len(list.SimpleItem))
+	for _, item := range list.SimpleItem {
+		items[string(item.Name)] = string(item.Value)
+	}
+	return items
+}
+
+func elementItems(list event.ItemList) map[string]string {
+	if len(list.ElementItem) == 0 {
+		return nil
+	}
+	items := make(map[string]string, len(list.ElementItem))
+	for _, item := range list.ElementItem {
+		items[string(item.Name)] = item.Inner
+	}
+	return items
+}
+
+// NewEvent normalize a notification message received from device
+func NewEvent(device string, message event.NotificationMessage) Event {
+	description := message.Message.Message
+	ev := Event{
+		Device:     device,
+		Topic:      strings.TrimSpace(string(message.Topic.TopicKinds)),
+		Operation:  string(description.PropertyOperation),
+		ReceivedAt: time.Now(),
+		Source:     simpleItems(description.Source),
+		Key:        simpleItems(description.Key),
+		Data:       simpleItems(description.Data),
+		Elements:   elementItems(description.Data),
+	}
+	if t, err := description.UtcTime.ToTime(); err == nil {
+		ev.Time = t
+	} else {
+		ev.Time = ev.ReceivedAt
+	}
+	return NormalizeEvent(ev)
+}
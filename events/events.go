@@ -0,0 +1,141 @@
+// Package events is the client of the ONVIF event service: pull point and
+// base notification subscriptions and the messages sent to their
+// subscription manager.
+//
+// The client sends its requests through a core.Caller, a core.Client or an
+// onvif.Device:
+//
+//	subscription, err := events.New(dev).CreatePullPointSubscription(ctx, nil, time.Minute)
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/core"
+	"github.com/PolarisM78/go-onvif/soap"
+	event "github.com/PolarisM78/go-onvif/types/events"
+	"github.com/PolarisM78/go-onvif/xsd"
+)
+
+// WS-Addressing actions of the messages sent to a subscription manager
+const (
+	ActionPullMessages = "http://www.onvif.org/ver10/events/wsdl/PullPointSubscription/PullMessagesRequest"
+	ActionRenew        = "http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/RenewRequest"
+	ActionUnsubscribe  = "http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/UnsubscribeRequest"
+
+	ActionSetSynchronizationPoint = "http://www.onvif.org/ver10/events/wsdl/PullPointSubscription/SetSynchronizationPointRequest"
+)
+
+// ErrNoSubscriptionAddress returned when a subscription reference holds no address
+var ErrNoSubscriptionAddress = errors.New("subscription reference has no address")
+
+// Client of the event service of a device
+type Client struct {
+	caller core.Caller
+}
+
+// New return the event service client sending its requests through caller
+func New(caller core.Caller) *Client {
+	return &Client{caller: caller}
+}
+
+// ReferenceHeaders return the headers of a message sent to the endpoint
+// reference: wsa:Action, wsa:To and every reference parameter
+func ReferenceHeaders(reference event.EndpointReferenceType, action string) [][]byte {
+	headers := soap.AddressingHeaders(action, string(reference.Address))
+	for _, parameter := range reference.ReferenceParameters.Parameters {
+		headers = append(headers, soap.ReferenceParameterHeader(parameter.XMLName, parameter.Attrs, parameter.Inner))
+	}
+	return headers
+}
+
+// GetServiceCapabilities return the capabilities of the event service
+func (client *Client) GetServiceCapabilities(ctx context.Context) (event.Capabilities, error) {
+	response := event.GetServiceCapabilitiesResponse{}
+	err := client.caller.Call(ctx, core.ServiceEvents, event.GetServiceCapabilities{}, &response)
+	return response.Capabilities, err
+}
+
+// GetEventProperties return the topics and dialects of the device
+func (client *Client) GetEventProperties(ctx context.Context) (event.GetEventPropertiesResponse, error) {
+	response := event.GetEventPropertiesResponse{}
+	err := client.caller.Call(ctx, core.ServiceEvents, event.GetEventProperties{}, &response)
+	return response, err
+}
+
+// CreatePullPointSubscription create a pull point on the device, filter and
+// termination are optional
+func (client *Client) CreatePullPointSubscription(ctx context.Context, filter *event.FilterType, termination time.Duration) (event.CreatePullPointSubscriptionResponse, error) {
+	request := event.CreatePullPointSubscription{Filter: filter}
+	if termination > 0 {
+		request.InitialTerminationTime = xsd.String(xsd.FormatDuration(termination))
+	}
+	response := event.CreatePullPointSubscriptionResponse{}
+	if err := client.caller.Call(ctx, core.ServiceEvents, request, &response); err != nil {
+		return response, err
+	}
+	if response.SubscriptionReference.Address == "" {
+		return response, ErrNoSubscriptionAddress
+	}
+	return response, nil
+}
+
+// Subscribe create a base notification subscription sending the events of
+// the device to consumer, filter and termination are optional
+func (client *Client) Subscribe(ctx context.Context, consumer string, filter *event.FilterType, termination time.Duration) (event.SubscribeResponse, error) {
+	request := event.Subscribe{ConsumerReference: event.ConsumerReferenceType{Address: xsd.AnyURI(consumer)}, Filter: filter}
+	if termination > 0 {
+		request.InitialTerminationTime = xsd.String(xsd.FormatDuration(termination))
+	}
+	response := event.SubscribeResponse{}
+	if err := client.caller.Call(ctx, core.ServiceEvents, request, &response); err != nil {
+		return response, err
+	}
+	if response.SubscriptionReference.Address == "" {
+		return response, ErrNoSubscriptionAddress
+	}
+	return response, nil
+}
+
+// PullMessages pull the messages of the subscription, the device holds the
+// request up to timeout when no message is pending
+func (client *Client) PullMessages(ctx context.Context, reference event.EndpointReferenceType, timeout time.Duration, limit int) (event.PullMessagesResponse, error) {
+	response := event.PullMessagesResponse{}
+	if reference.Address == "" {
+		return response, ErrNoSubscriptionAddress
+	}
+	request := event.PullMessages{Timeout: xsd.FormatDuration(timeout), MessageLimit: limit}
+	err := client.caller.CallAddress(ctx, string(reference.Address), request, &response, ReferenceHeaders(reference, ActionPullMessages)...)
+	return response, err
+}
+
+// Renew extend the subscription by termination, the message is sent to the
+// subscription manager address with the reference parameters as headers
+func (client *Client) Renew(ctx context.Context, reference event.EndpointReferenceType, termination time.Duration) (event.RenewResponse, error) {
+	response := event.RenewResponse{}
+	if reference.Address == "" {
+		return response, ErrNoSubscriptionAddress
+	}
+	request := event.Renew{TerminationTime: xsd.String(xsd.FormatDuration(termination))}
+	err := client.caller.CallAddress(ctx, string(reference.Address), request, &response, ReferenceHeaders(reference, ActionRenew)...)
+	return response, err
+}
+
+// Unsubscribe cancel the subscription on the subscription manager
+func (client *Client) Unsubscribe(ctx context.Context, reference event.EndpointReferenceType) error {
+	if reference.Address == "" {
+		return ErrNoSubscriptionAddress
+	}
+	return client.caller.CallAddress(ctx, string(reference.Address), event.Unsubscribe{}, &event.UnsubscribeResponse{}, ReferenceHeaders(reference, ActionUnsubscribe)...)
+}
+
+// SetSynchronizationPoint ask the subscription to send the current state of
+// every property again, as Initialized messages
+func (client *Client) SetSynchronizationPoint(ctx context.Context, reference event.EndpointReferenceType) error {
+	if reference.Address == "" {
+		return ErrNoSubscriptionAddress
+	}
+	return client.caller.CallAddress(ctx, string(reference.Address), event.SetSynchronizationPoint{}, &event.SetSynchronizationPointResponse{}, ReferenceHeaders(reference, ActionSetSynchronizationPoint)...)
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/core"
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+const envelope = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tev="http://www.onvif.org/ver10/events/wsdl" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2" xmlns:wsa="http://www.w3.org/2005/08/addressing"><s:Body>`
+
+func TestSubscription(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.URL.Path+" "+string(body))
+		switch {
+		case strings.Contains(string(body), "CreatePullPointSubscription"):
+			w.Write([]byte(envelope + `<tev:CreatePullPointSubscriptionResponse><tev:SubscriptionReference><wsa:Address>http://` + r.Host + `/subscription/1</wsa:Address>` +
+				`<wsa:ReferenceParameters><dom0:SubscriptionId xmlns:dom0="http://www.example.com/subscription">7</dom0:SubscriptionId></wsa:ReferenceParameters></tev:SubscriptionReference>` +
+				`</tev:CreatePullPointSubscriptionResponse></s:Body></s:Envelope>`))
+		case strings.Contains(string(body), "PullMessages"):
+			w.Write([]byte(envelope + `<tev:PullMessagesResponse><tev:CurrentTime>2026-10-16T10:00:00Z</tev:CurrentTime><tev:TerminationTime>2026-10-16T10:01:00Z</tev:TerminationTime>` +
+				`<wsnt:NotificationMessage><wsnt:Topic>tns1:Device/Trigger/DigitalInput</wsnt:Topic></wsnt:NotificationMessage></tev:PullMessagesResponse></s:Body></s:Envelope>`))
+		}
+	}))
+	defer server.Close()
+	client, _ := core.NewClient(core.Params{XAddr: server.URL + "/onvif/device_service"})
+	client.SetEndpoint(core.ServiceEvents, server.URL+"/onvif/events")
+	events := New(client)
+	ctx := context.Background()
+	subscription, err := events.CreatePullPointSubscription(ctx, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reference := subscription.SubscriptionReference
+	if len(reference.ReferenceParameters.Parameters) != 1 {
+		t.Fatalf("reference %+v", reference)
+	}
+	messages, err := events.PullMessages(ctx, reference, 5*time.Second, 10)
+	if err != nil || len(messages.NotificationMessage) != 1 {
+		t.Fatalf("%d messages, %v", len(messages.NotificationMessage), err)
+	}
+	/* 拉取请求发往订阅地址,带wsa:To、Action和引用参数 */
+	pull := requests[1]
+	for _, want := range []string{"/subscription/1 ", "<tev:Timeout>PT5S</tev:Timeout>", ActionPullMessages, "/subscription/1</", "SubscriptionId", ">7</"} {
+		if !strings.Contains(pull, want) {
+			t.Fatalf("pull request without %q: %s", want, pull)
+		}
+	}
+}
+
+func TestNoSubscriptionAddress(t *testing.T) {
+	client, _ := core.NewClient(core.Params{XAddr: "http://192.0.2.1/onvif/device_service"})
+	events := New(client)
+	if _, err := events.PullMessages(context.Background(), event.EndpointReferenceType{}, time.Second, 1); !errors.Is(err, ErrNoSubscriptionAddress) {
+		t.Fatalf("error %v", err)
+	}
+	if err := events.Unsubscribe(context.Background(), event.EndpointReferenceType{}); !errors.Is(err, ErrNoSubscriptionAddress) {
+		t.Fatalf("error %v", err)
+	}
+}
+
+func TestReferenceHeaders(t *testing.T) {
+	reference := event.EndpointReferenceType{Address: "http://10.1.1.200/subscription/1"}
+	reference.ReferenceParameters.Parameters = []event.ReferenceParameter{{XMLName: xml.Name{Space: "http://www.example.com/subscription", Local: "SubscriptionId"}, Inner: "7"}}
+	headers := ReferenceHeaders(reference, ActionRenew)
+	if len(headers) != 3 || !strings.Contains(string(headers[2]), "SubscriptionId") {
+		t.Fatalf("headers %q", headers)
+	}
+}
@@ -38,8 +38,8 @@ func main() {
 		if err != nil {
 			log.Fatalf(err.Error())
 		}
-		if pull.NotificationMessage.Topic.TopicKinds != "" {
-			log.Printf("%v", pull.NotificationMessage)
+		for _, message := range pull.NotificationMessage {
+			log.Printf("%v", message)
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
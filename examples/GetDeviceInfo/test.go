@@ -24,9 +24,8 @@ func main() {
 	dev, _ := onvif.NewDevice(onvif.DeviceParams{Ipddr: "10.1.1.210", Username: "admin", Password: "123qweasdZXC"})
 
 	/* 获取能力集合 */
-	retServices := dev.GetServices()
-	for key, value := range retServices {
-		log.Printf("server key : %s value : %s\r\n", key, value)
+	for _, service := range dev.Services().Sorted() {
+		log.Printf("server key : %s value : %s version : %s\r\n", service.Key, service.XAddr, service.Version)
 	}
 
 	/* 获取设备基本信息 */
@@ -24,8 +24,8 @@ func main() {
 	err := dev.CallMethodInterface(
 		device.SetSystemDateAndTime{
 			DateTimeType: "Manual", DaylightSavings: false,
-			TimeZone:    device.TimeZone{TZ: "CST-8"},
-			UTCDateTime: device.DateTime{Time: device.Time{Hour: 12, Minute: 0, Second: 0}, Date: device.Date{Year: 2020, Month: 2, Day: 1}},
+			TimeZone:    &device.TimeZone{TZ: "CST-8"},
+			UTCDateTime: &device.DateTime{Time: device.Time{Hour: 12, Minute: 0, Second: 0}, Date: device.Date{Year: 2020, Month: 2, Day: 1}},
 		}, nil, "")
 	if err != nil {
 		log.Fatalf(err.Error())
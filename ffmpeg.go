@@ -0,0 +1,63 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// runFFmpeg run ffmpeg reading the RTSP stream over transport, an ffmpeg
+// -rtsp_transport value, and writing output to stdout. The credentials are
+// never put on the command line, which every local user can read: ffmpeg is
+// given the address of a local rtspProxy that authenticates its requests.
+func runFFmpeg(ctx context.Context, path, streamURI, username, password, transport string, output []string, stdout io.Writer) error {
+	if path == "" {
+		path = "ffmpeg"
+	}
+	u, err := url.Parse(streamURI)
+	if err != nil {
+		return err
+	}
+	if u.User != nil {
+		if username == "" {
+			username = u.User.Username()
+			password, _ = u.User.Password()
+		}
+		u.User = nil
+	}
+	input := u.String()
+	var proxy *rtspProxy
+	if username != "" {
+		/* HTTP隧道由代理建立,ffmpeg与代理之间使用TCP */
+		tunnel := transport == "http"
+		if proxy, err = startRTSPProxy(u, username, password, tunnel); err != nil {
+			return err
+		}
+		if tunnel {
+			transport = "tcp"
+		}
+		input = proxy.URL()
+	}
+	args := append([]string{"-nostdin", "-loglevel", "error", "-rtsp_transport", transport, "-i", input}, output...)
+	stderr := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout, cmd.Stderr = stdout, &stderr
+	err = cmd.Run()
+	var proxyErr error
+	if proxy != nil {
+		proxyErr = proxy.Close()
+	}
+	if err != nil {
+		/* 错误信息中显示设备地址而非代理地址 */
+		message := strings.Replace(strings.TrimSpace(stderr.String()), input, u.String(), -1)
+		if proxyErr != nil {
+			return fmt.Errorf("ffmpeg: %v: %s: %w", err, message, proxyErr)
+		}
+		return fmt.Errorf("ffmpeg: %v: %s", err, message)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/types/media2"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// View modes of the video source configurations of the panoramic cameras,
+// tt:ViewModes of the ONVIF schema
+const (
+	ViewModeFisheye     = "tt:Fisheye"
+	ViewMode360Panorama = "tt:360Panorama"
+	ViewMode180Panorama = "tt:180Panorama"
+	ViewModeQuad        = "tt:Quad"
+	ViewModeOriginal    = "tt:Original"
+	ViewModeLeftHalf    = "tt:LeftHalf"
+	ViewModeRightHalf   = "tt:RightHalf"
+	ViewModeDewarp      = "tt:Dewarp"
+)
+
+// ErrViewModeNotFound returned by SetViewMode when no video source mode of
+// the device matches the view mode
+var ErrViewModeNotFound = errors.New("no video source mode matches the view mode")
+
+// VideoSourceModes return the modes of the video source, e.g. the fisheye
+// and the dewarped outputs of a panoramic camera, from the media2 service
+// when the device offers it
+func (dev *Device) VideoSourceModes(ctx context.Context, videoSource string) ([]onvif.VideoSourceMode, error) {
+	if _, err := dev.getEndpoint("media2"); err == nil {
+		response := media2.GetVideoSourceModesResponse{}
+		request := media2.GetVideoSourceModes{VideoSourceToken: onvif.ReferenceToken(videoSource)}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			return nil, err
+		}
+		return response.VideoSourceModes, nil
+	}
+	response := media.GetVideoSourceModesResponse{}
+	request := media.GetVideoSourceModes{VideoSourceToken: onvif.ReferenceToken(videoSource)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return nil, err
+	}
+	return response.VideoSourceModes, nil
+}
+
+// SetVideoSourceMode switch the video source to the mode of token and report
+// whether the device reboots to apply it
+func (dev *Device) SetVideoSourceMode(ctx context.Context, videoSource, mode string) (bool, error) {
+	if _, err := dev.getEndpoint("media2"); err == nil {
+		response := media2.SetVideoSourceModeResponse{}
+		request := media2.SetVideoSourceMode{VideoSourceToken: onvif.ReferenceToken(videoSource), VideoSourceModeToken: onvif.ReferenceToken(mode)}
+		err := dev.CallMethodInterfaceContext(ctx, request, &response, "")
+		return response.Reboot, err
+	}
+	response := media.SetVideoSourceModeResponse{}
+	request := media.SetVideoSourceMode{VideoSourceToken: onvif.ReferenceToken(videoSource), VideoSourceModeToken: onvif.ReferenceToken(mode)}
+	err := dev.CallMethodInterfaceContext(ctx, request, &response, "")
+	return response.Reboot, err
+}
+
+// ViewModes return the view mode of the video sources used by the profiles,
+// by video source token, the sources without view mode are left out
+func (dev *Device) ViewModes(ctx context.Context) (map[string]string, error) {
+	profiles, err := dev.GetProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modes := make(map[string]string)
+	for _, profile := range profiles {
+		configuration := profile.VideoSourceConfiguration
+		if configuration.ViewMode != "" {
+			modes[string(configuration.SourceToken)] = configuration.ViewMode
+		}
+	}
+	return modes, nil
+}
+
+// FindViewMode return the mode of modes providing the view mode, e.g.
+// ViewModeQuad. The vendors name the modes after the view modes in their
+// tokens or descriptions, which are compared without case, prefix nor
+// punctuation.
+func FindViewMode(modes []onvif.VideoSourceMode, viewMode string) (onvif.VideoSourceMode, bool) {
+	key := viewModeKey(viewMode)
+	for _, mode := range modes {
+		if viewModeKey(string(mode.Token)) == key || strings.Contains(viewModeKey(mode.Description), key) {
+			return mode, true
+		}
+	}
+	return onvif.VideoSourceMode{}, false
+}
+
+// viewModeKey lower case letters and digits of a view mode, without its
+// namespace prefix
+func viewModeKey(text string) string {
+	if index := strings.LastIndex(text, ":"); index >= 0 {
+		text = text[index+1:]
+	}
+	builder := strings.Builder{}
+	for _, r := range strings.ToLower(text) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// SetViewMode switch the video source to the mode providing the view mode,
+// e.g. from ViewModeFisheye to ViewModeQuad, and report whether the device
+// reboots to apply it
+func (dev *Device) SetViewMode(ctx context.Context, videoSource, viewMode string) (bool, error) {
+	modes, err := dev.VideoSourceModes(ctx, videoSource)
+	if err != nil {
+		return false, err
+	}
+	mode, ok := FindViewMode(modes, viewMode)
+	if !ok {
+		return false, ErrViewModeNotFound
+	}
+	if mode.Enabled {
+		return false, nil
+	}
+	return dev.SetVideoSourceMode(ctx, videoSource, string(mode.Token))
+}
@@ -0,0 +1,87 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+const fisheyeModes = `<VideoSourceModes token="mode_1" Enabled="true"><tt:MaxFramerate>25</tt:MaxFramerate><tt:MaxResolution><tt:Width>2992</tt:Width><tt:Height>2992</tt:Height></tt:MaxResolution>` +
+	`<tt:Encodings>H264 H265</tt:Encodings><tt:Reboot>false</tt:Reboot><tt:Description>Fisheye</tt:Description></VideoSourceModes>` +
+	`<VideoSourceModes token="mode_2" Enabled="false"><tt:MaxFramerate>15</tt:MaxFramerate><tt:MaxResolution><tt:Width>1920</tt:Width><tt:Height>1080</tt:Height></tt:MaxResolution>` +
+	`<tt:Encodings>H264</tt:Encodings><tt:Reboot>true</tt:Reboot><tt:Description>Quad view</tt:Description></VideoSourceModes>`
+
+func TestFindViewMode(t *testing.T) {
+	modes := []onvif.VideoSourceMode{
+		{Token: "fisheye", Enabled: true},
+		{Token: "mode_2", Description: "360° Panorama"},
+		{Token: "mode_3", Description: "Dewarp (4 views)"},
+	}
+	for viewMode, token := range map[string]onvif.ReferenceToken{
+		ViewModeFisheye:     "fisheye",
+		ViewMode360Panorama: "mode_2",
+		ViewModeDewarp:      "mode_3",
+	} {
+		if mode, ok := FindViewMode(modes, viewMode); !ok || mode.Token != token {
+			t.Fatalf("%s found %+v, %v", viewMode, mode, ok)
+		}
+	}
+	if mode, ok := FindViewMode(modes, ViewModeQuad); ok {
+		t.Fatalf("quad found %+v", mode)
+	}
+}
+
+func TestSetViewMode(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetVideoSourceModes": `<tr2:GetVideoSourceModesResponse>` + strings.ReplaceAll(fisheyeModes, "VideoSourceModes", "tr2:VideoSourceModes") + `</tr2:GetVideoSourceModesResponse>`,
+		"SetVideoSourceMode":  `<tr2:SetVideoSourceModeResponse><tr2:Reboot>true</tr2:Reboot></tr2:SetVideoSourceModeResponse>`,
+		"GetProfiles": `<trt:GetProfilesResponse><trt:Profiles token="main"><tt:Name>main</tt:Name>` +
+			`<tt:VideoSourceConfiguration token="config" ViewMode="tt:Fisheye"><tt:Name>config</tt:Name><tt:SourceToken>source_1</tt:SourceToken></tt:VideoSourceConfiguration></trt:Profiles>` +
+			`<trt:Profiles token="other"><tt:Name>other</tt:Name><tt:VideoSourceConfiguration token="plain"><tt:Name>plain</tt:Name><tt:SourceToken>source_2</tt:SourceToken></tt:VideoSourceConfiguration></trt:Profiles>` +
+			`</trt:GetProfilesResponse>`,
+	}, ServiceMedia, ServiceMedia2)
+	ctx := context.Background()
+	modes, err := dev.VideoSourceModes(ctx, "source_1")
+	if err != nil || len(modes) != 2 || !modes[0].Enabled || modes[1].MaxResolution.Width != 1920 || !modes[1].Reboot || modes[1].Encodings != "H264" {
+		t.Fatalf("modes %+v, %v", modes, err)
+	}
+	if viewModes, err := dev.ViewModes(ctx); err != nil || len(viewModes) != 1 || viewModes["source_1"] != ViewModeFisheye {
+		t.Fatalf("view modes %v, %v", viewModes, err)
+	}
+
+	/* 已启用的模式不再切换 */
+	if reboot, err := dev.SetViewMode(ctx, "source_1", ViewModeFisheye); err != nil || reboot {
+		t.Fatalf("reboot %v, %v", reboot, err)
+	}
+	if len(fake.sent("SetVideoSourceMode")) != 0 {
+		t.Fatal("enabled mode set")
+	}
+	reboot, err := dev.SetViewMode(ctx, "source_1", ViewModeQuad)
+	if err != nil || !reboot {
+		t.Fatalf("reboot %v, %v", reboot, err)
+	}
+	if requests := fake.sent("SetVideoSourceMode"); len(requests) != 1 || !strings.HasPrefix(requests[0], "/onvif/"+ServiceMedia2+" ") ||
+		!strings.Contains(requests[0], "<tr2:VideoSourceModeToken>mode_2</tr2:VideoSourceModeToken>") {
+		t.Fatalf("requests %q", requests)
+	}
+	if _, err := dev.SetViewMode(ctx, "source_1", ViewModeLeftHalf); !errors.Is(err, ErrViewModeNotFound) {
+		t.Fatalf("error %v", err)
+	}
+}
+
+func TestVideoSourceModesMedia(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetVideoSourceModes": `<trt:GetVideoSourceModesResponse>` + strings.ReplaceAll(fisheyeModes, "VideoSourceModes", "trt:VideoSourceModes") + `</trt:GetVideoSourceModesResponse>`,
+	}, ServiceMedia)
+	/* 没有media2服务时使用media服务 */
+	modes, err := dev.VideoSourceModes(context.Background(), "source_1")
+	if err != nil || len(modes) != 2 || modes[1].Token != "mode_2" {
+		t.Fatalf("modes %+v, %v", modes, err)
+	}
+	if requests := fake.sent("GetVideoSourceModes"); len(requests) != 1 || !strings.HasPrefix(requests[0], "/onvif/"+ServiceMedia+" ") {
+		t.Fatalf("requests %q", requests)
+	}
+}
@@ -0,0 +1,83 @@
+package onvif
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// defaultFleetConcurrency limit of devices handled at the same time by a Fleet
+const defaultFleetConcurrency = 8
+
+// Fleet is a group of connected devices on which the same operation is run.
+type Fleet struct {
+	Devices []*Device
+	// Concurrency limit of devices handled in parallel, 0 means defaultFleetConcurrency
+	Concurrency int
+}
+
+// NewFleet return a Fleet for the given devices
+func NewFleet(devices ...*Device) *Fleet {
+	return &Fleet{Devices: devices}
+}
+
+// PartialError returned by the operations of a Fleet along with their
+// results when ctx ended before every device was started. The results keep
+// the order of the devices, those of the skipped devices are zero and those
+// of the devices cut short hold the error of ctx.
+type PartialError struct {
+	// Skipped addresses of the devices not started
+	Skipped []string
+	Total   int
+	Err     error
+}
+
+func (err *PartialError) Error() string {
+	return fmt.Sprintf("%d of %d devices skipped: %v", len(err.Skipped), err.Total, err.Err)
+}
+
+func (err *PartialError) Unwrap() error {
+	return err.Err
+}
+
+// each calls fn for every device of the fleet with bounded parallelism.
+// Devices not yet started when ctx is done are skipped, a PartialError is
+// returned when any was skipped; the devices cut short report the error of
+// ctx in their own results.
+func (fleet *Fleet) each(ctx context.Context, fn func(index int, dev *Device)) error {
+	limit := fleet.Concurrency
+	if limit <= 0 {
+		limit = defaultFleetConcurrency
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	started := 0
+loop:
+	for i, dev := range fleet.Devices {
+		/* select在两者都就绪时随机选择,ctx结束后不再启动设备 */
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- struct{}{}:
+		}
+		started++
+		wg.Add(1)
+		go func(i int, dev *Device) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i, dev)
+		}(i, dev)
+	}
+	wg.Wait()
+	if started == len(fleet.Devices) {
+		return nil
+	}
+	partial := &PartialError{Total: len(fleet.Devices), Err: ctx.Err()}
+	for _, dev := range fleet.Devices[started:] {
+		partial.Skipped = append(partial.Skipped, dev.Params.Ipddr)
+	}
+	return partial
+}
@@ -0,0 +1,46 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFleetPartialError(t *testing.T) {
+	fleet := &Fleet{Concurrency: 1}
+	for _, address := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		fleet.Devices = append(fleet.Devices, newDevice(DeviceParams{Ipddr: address}))
+	}
+	if err := fleet.each(context.Background(), func(int, *Device) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	/* 第一台设备处理期间ctx结束,其余设备不再启动 */
+	ctx, cancel := context.WithCancel(context.Background())
+	handled := make([]bool, len(fleet.Devices))
+	err := fleet.each(ctx, func(i int, dev *Device) {
+		handled[i] = true
+		cancel()
+		/* 占用并发名额直到调度循环看到ctx结束 */
+		time.Sleep(50 * time.Millisecond)
+	})
+	var partial *PartialError
+	if !errors.As(err, &partial) || !errors.Is(err, context.Canceled) || partial.Total != 3 || len(partial.Skipped) != 2 || partial.Skipped[0] != "10.0.0.2" {
+		t.Fatalf("error %v", err)
+	}
+	if !handled[0] || handled[1] || handled[2] {
+		t.Fatalf("handled %v", handled)
+	}
+
+	/* 全部设备已启动时不返回PartialError */
+	ctx, cancel = context.WithCancel(context.Background())
+	last := len(fleet.Devices) - 1
+	if err := fleet.each(ctx, func(i int, dev *Device) {
+		if i == last {
+			cancel()
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,150 @@
+package onvif
+
+import (
+	"crypto/subtle"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors of the tenant checks of the gateway
+var (
+	ErrInvalidAPIKey = errors.New("missing or invalid api key")
+	ErrRateLimited   = errors.New("rate limit exceeded")
+)
+
+// GatewayTenant user or site of a multi-tenant Gateway, identified by its API
+// key and restricted to the devices of its groups
+type GatewayTenant struct {
+	Name string
+	// Key API key of the tenant, sent in the X-API-Key header or as a Bearer
+	// token of the Authorization header
+	Key string
+	// Groups registry groups of the devices the tenant sees and controls
+	Groups []string
+	// All the tenant sees every device, whatever its groups
+	All bool
+	// RateLimit requests per second allowed to the tenant, 0 means unlimited;
+	// Burst requests allowed at once, 0 means RateLimit rounded up
+	RateLimit float64
+	Burst     int
+}
+
+// sees report whether the tenant sees the device of entry
+func (tenant *GatewayTenant) sees(entry RegistryEntry) bool {
+	if tenant == nil || tenant.All {
+		return true
+	}
+	for _, group := range entry.Groups {
+		if containsString(tenant.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenBucket rate limiter of a tenant
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take remove a token from the bucket of the tenant, false when the tenant
+// exceeded its rate
+func (bucket *tokenBucket) take(tenant *GatewayTenant, now time.Time) bool {
+	burst := float64(tenant.Burst)
+	if burst <= 0 {
+		burst = math.Ceil(tenant.RateLimit)
+	}
+	if bucket.last.IsZero() {
+		bucket.tokens = burst
+	} else {
+		bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*tenant.RateLimit)
+	}
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// requestKey return the API key of the request
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
+
+// tenantFor return the tenant of the request, comparing the keys in constant
+// time, nil when none matches
+func (gateway *Gateway) tenantFor(r *http.Request) *GatewayTenant {
+	key := requestKey(r)
+	if key == "" {
+		return nil
+	}
+	var found *GatewayTenant
+	for i := range gateway.Tenants {
+		tenant := &gateway.Tenants[i]
+		if tenant.Key != "" && subtle.ConstantTimeCompare([]byte(tenant.Key), []byte(key)) == 1 {
+			found = tenant
+		}
+	}
+	return found
+}
+
+// authorize return the tenant of the request, nil when the gateway has no
+// tenants. The error is written to w and ok is false when the key is invalid
+// or the tenant exceeded its rate.
+func (gateway *Gateway) authorize(w http.ResponseWriter, r *http.Request) (tenant *GatewayTenant, ok bool) {
+	if len(gateway.Tenants) == 0 {
+		return nil, true
+	}
+	if tenant = gateway.tenantFor(r); tenant == nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="onvif-gateway"`)
+		gatewayError(w, http.StatusUnauthorized, ErrInvalidAPIKey)
+		return nil, false
+	}
+	if tenant.RateLimit > 0 {
+		gateway.mutex.Lock()
+		if gateway.buckets == nil {
+			gateway.buckets = make(map[string]*tokenBucket)
+		}
+		/* 按API密钥区分,名称可能为空或重复 */
+		bucket, found := gateway.buckets[tenant.Key]
+		if !found {
+			bucket = &tokenBucket{}
+			gateway.buckets[tenant.Key] = bucket
+		}
+		allowed := bucket.take(tenant, time.Now())
+		gateway.mutex.Unlock()
+		if !allowed {
+			w.Header().Set("Retry-After", "1")
+			gatewayError(w, http.StatusTooManyRequests, ErrRateLimited)
+			return nil, false
+		}
+	}
+	return tenant, true
+}
+
+// tenantAddresses return the addresses of the devices the tenant sees, nil
+// for a tenant seeing every device
+func (gateway *Gateway) tenantAddresses(tenant *GatewayTenant) map[string]bool {
+	if tenant == nil || tenant.All {
+		return nil
+	}
+	addresses := make(map[string]bool)
+	for _, entry := range gateway.Registry.Entries() {
+		if entry.Device != nil && tenant.sees(entry) {
+			addresses[entry.Device.Params.Ipddr] = true
+		}
+	}
+	return addresses
+}
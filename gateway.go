@@ -0,0 +1,480 @@
+package onvif
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidSignature returned for a signed URL altered or expired
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// Gateway REST API over the devices of a registry:
+//
+//	GET /devices                          entries of the registry
+//	GET /devices/{id}                     entry of a device
+//	GET /devices/{id}/snapshot/url        signed URL of the snapshot
+//	GET /devices/{id}/stream/url          signed URL of the relayed stream
+//	GET /devices/{id}/snapshot?signature  snapshot proxied from the device
+//	GET /devices/{id}/stream?signature    RTSP stream relayed over HTTP
+//	GET /events                           live events of every device
+//	GET /devices/{id}/events              live events of a device
+//
+// The media routes take an optional profile parameter, the first profile of
+// the device by default. They are reached from browsers, e.g. in an img or
+// video element, so they are authorized by their short-lived signature
+// instead of the credentials of the API, and the camera credentials never
+// leave the gateway. The other routes are meant to sit behind the
+// authentication of the application.
+//
+// The events routes stream Server-Sent Events, or WebSocket text messages
+// when the request is a WebSocket upgrade, each event as JSON. Their topic
+// parameters, repeated or separated by commas, keep the events under one of
+// the topics, e.g. ?topic=RuleEngine/CellMotionDetector,Device/Trigger.
+//
+// With Tenants the routes other than the signed media routes require the API
+// key of a tenant, and a tenant only sees the devices of its groups, their
+// events included; the devices of other tenants answer not found.
+type Gateway struct {
+	Registry *Registry
+	// Secret key signing the media URLs, the media routes are disabled when
+	// empty
+	Secret []byte
+	// URLLifetime validity of the signed URLs, 0 means 5 minutes
+	URLLifetime time.Duration
+	// BaseURL prefix of the signed URLs, e.g. https://gateway.example.com;
+	// the URLs are relative when empty
+	BaseURL string
+	// Relay re-streams RTSP for the stream route, disabled when nil
+	Relay StreamRelay
+	// Events source of the events routes, disabled when nil
+	Events *EventBroker
+	// Tenants API key holders of the gateway, every request is allowed
+	// every device when empty
+	Tenants []GatewayTenant
+
+	mutex sync.Mutex
+	// buckets rate limiters of the tenants by API key
+	buckets map[string]*tokenBucket
+}
+
+// NewGateway return a gateway over the registry signing media URLs with secret
+func NewGateway(registry *Registry, secret []byte) *Gateway {
+	return &Gateway{Registry: registry, Secret: secret, Relay: FFmpegRelay{}}
+}
+
+// gatewayDevice JSON form of a registry entry
+type gatewayDevice struct {
+	ID      string            `json:"id"`
+	Address string            `json:"address"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Groups  []string          `json:"groups,omitempty"`
+}
+
+// signedURL JSON answer of the url routes
+type signedURL struct {
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+// ListenAndServe serve the gateway on the TCP address until ctx ends, see
+// Serve
+func (gateway *Gateway) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return gateway.Serve(ctx, listener)
+}
+
+// Serve serve the gateway on listener until ctx ends, then shut the server
+// down: the event streams end at once, the other requests in flight are
+// given 10s to complete before their context is cancelled. The error of ctx
+// is returned after a shutdown.
+func (gateway *Gateway) Serve(ctx context.Context, listener net.Listener) error {
+	/* 请求的ctx不随ctx结束,进行中的请求在关闭期间得以完成 */
+	base, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	streams, endStreams := context.WithCancel(base)
+	defer endStreams()
+	server := &http.Server{Handler: gateway, BaseContext: func(net.Listener) context.Context {
+		return context.WithValue(base, gatewayStreamsKey{}, streams)
+	}}
+	/* 事件流不会自行结束,关闭开始时立即结束 */
+	server.RegisterOnShutdown(endStreams)
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		cancelBase()
+		server.Close()
+	}
+	<-served
+	return ctx.Err()
+}
+
+// gatewayStreamsKey context key of the context of the event streams, done
+// when the server served by Serve shuts down
+type gatewayStreamsKey struct{}
+
+// streamsDone return the channel closed when the event streams of the
+// request must end, nil when the gateway is not run by Serve
+func streamsDone(r *http.Request) <-chan struct{} {
+	if streams, ok := r.Context().Value(gatewayStreamsKey{}).(context.Context); ok {
+		return streams.Done()
+	}
+	return nil
+}
+
+// ServeHTTP route the request
+func (gateway *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		gatewayError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	/* 请求ID传递到对设备的调用 */
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		r = r.WithContext(WithRequestID(r.Context(), id))
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	media := ""
+	if len(parts) > 2 {
+		media = strings.Join(parts[2:], "/")
+	}
+	/* 签名的媒体地址由签名授权,其余路由需要租户密钥 */
+	var tenant *GatewayTenant
+	if media != "snapshot" && media != "stream" {
+		var ok bool
+		if tenant, ok = gateway.authorize(w, r); !ok {
+			return
+		}
+	}
+	if len(parts) == 1 && parts[0] == "events" {
+		gateway.serveEvents(w, r, eventFilter(r, "", gateway.tenantAddresses(tenant)))
+		return
+	}
+	if parts[0] != "devices" {
+		gatewayError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if len(parts) == 1 {
+		entries := gateway.Registry.Entries()
+		devices := make([]gatewayDevice, 0, len(entries))
+		for _, entry := range entries {
+			if tenant.sees(entry) {
+				devices = append(devices, entryDevice(entry))
+			}
+		}
+		gatewayJSON(w, devices)
+		return
+	}
+	entry, ok := gateway.Registry.Get(parts[1])
+	if !ok || !tenant.sees(entry) {
+		gatewayError(w, http.StatusNotFound, ErrUnknownDevice)
+		return
+	}
+	if media != "" && entry.Device == nil {
+		gatewayError(w, http.StatusNotFound, errors.New("device has no connection"))
+		return
+	}
+	switch media {
+	case "":
+		gatewayJSON(w, entryDevice(entry))
+	case "snapshot/url", "stream/url":
+		gateway.serveSignedURL(w, r, entry.ID, parts[2])
+	case "snapshot":
+		if gateway.checkSignature(w, r) {
+			gateway.serveSnapshot(w, r, entry.Device)
+		}
+	case "stream":
+		if gateway.checkSignature(w, r) {
+			gateway.serveStream(w, r, entry.Device)
+		}
+	case "events":
+		gateway.serveEvents(w, r, eventFilter(r, entry.Device.Params.Ipddr, nil))
+	default:
+		gatewayError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func entryDevice(entry RegistryEntry) gatewayDevice {
+	device := gatewayDevice{ID: entry.ID, Labels: entry.Labels, Groups: entry.Groups}
+	if entry.Device != nil {
+		device.Address = entry.Device.Params.Ipddr
+	}
+	return device
+}
+
+// SignURL return the signed URL of a media route of the device, media being
+// snapshot or stream
+func (gateway *Gateway) SignURL(id, media, profile string) (string, time.Time, error) {
+	if len(gateway.Secret) == 0 {
+		return "", time.Time{}, errors.New("gateway has no signing secret")
+	}
+	lifetime := gateway.URLLifetime
+	if lifetime <= 0 {
+		lifetime = 5 * time.Minute
+	}
+	expires := time.Now().Add(lifetime).Truncate(time.Second)
+	query := url.Values{}
+	if profile != "" {
+		query.Set("profile", profile)
+	}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	path := "/devices/" + url.PathEscape(id) + "/" + media
+	query.Set("signature", gateway.signature(path, query))
+	return strings.TrimSuffix(gateway.BaseURL, "/") + path + "?" + query.Encode(), expires, nil
+}
+
+// signature HMAC-SHA256 of the path and of the parameters but the signature
+func (gateway *Gateway) signature(path string, query url.Values) string {
+	signed := url.Values{}
+	for key, values := range query {
+		if key != "signature" {
+			signed[key] = values
+		}
+	}
+	mac := hmac.New(sha256.New, gateway.Secret)
+	mac.Write([]byte(path + "?" + signed.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyURL check the signature and the expiry of a signed request
+func (gateway *Gateway) VerifyURL(r *http.Request) error {
+	if len(gateway.Secret) == 0 {
+		return ErrInvalidSignature
+	}
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return ErrInvalidSignature
+	}
+	expected := gateway.signature(r.URL.EscapedPath(), query)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (gateway *Gateway) checkSignature(w http.ResponseWriter, r *http.Request) bool {
+	if err := gateway.VerifyURL(r); err != nil {
+		gatewayError(w, http.StatusForbidden, err)
+		return false
+	}
+	return true
+}
+
+func (gateway *Gateway) serveSignedURL(w http.ResponseWriter, r *http.Request, id, media string) {
+	if media == "stream" && gateway.Relay == nil {
+		gatewayError(w, http.StatusNotFound, errors.New("stream relay disabled"))
+		return
+	}
+	signed, expires, err := gateway.SignURL(id, media, r.URL.Query().Get("profile"))
+	if err != nil {
+		gatewayError(w, http.StatusNotFound, err)
+		return
+	}
+	gatewayJSON(w, signedURL{URL: signed, Expires: expires})
+}
+
+// mediaProfile return the profile asked in the request, the first profile of
+// the device by default
+func mediaProfile(ctx context.Context, r *http.Request, dev *Device) (string, error) {
+	if profile := r.URL.Query().Get("profile"); profile != "" {
+		return profile, nil
+	}
+	token, err := firstProfile(ctx, dev)
+	return string(token), err
+}
+
+func (gateway *Gateway) serveSnapshot(w http.ResponseWriter, r *http.Request, dev *Device) {
+	profile, err := mediaProfile(r.Context(), r, dev)
+	if err != nil {
+		gatewayError(w, http.StatusBadGateway, err)
+		return
+	}
+	snapshot, err := dev.Snapshot(r.Context(), profile)
+	if err != nil {
+		gatewayError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.Header().Set("Content-Type", snapshot.ContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(snapshot.Image)
+}
+
+func (gateway *Gateway) serveStream(w http.ResponseWriter, r *http.Request, dev *Device) {
+	if gateway.Relay == nil {
+		gatewayError(w, http.StatusNotFound, errors.New("stream relay disabled"))
+		return
+	}
+	profile, err := mediaProfile(r.Context(), r, dev)
+	if err != nil {
+		gatewayError(w, http.StatusBadGateway, err)
+		return
+	}
+	uri, err := dev.GetStreamURI(r.Context(), profile, StreamRTSP)
+	if err != nil {
+		gatewayError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.Header().Set("Content-Type", gateway.Relay.ContentType())
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodHead {
+		return
+	}
+	writer := &flushWriter{w: w}
+	if err := gateway.Relay.Relay(r.Context(), uri, dev.Params.Username, dev.Params.Password, writer); err != nil {
+		if !writer.written {
+			gatewayError(w, http.StatusBadGateway, err)
+			return
+		}
+		/* 流开始后状态码已发送,只能中断连接,客户端不会把截断的流当作完整的 */
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// flushWriter flush every write, so the relayed stream is not buffered
+type flushWriter struct {
+	w http.ResponseWriter
+	// written the stream started, the status is sent
+	written bool
+}
+
+func (writer *flushWriter) Write(p []byte) (int, error) {
+	writer.written = true
+	n, err := writer.w.Write(p)
+	if flusher, ok := writer.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+func gatewayJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func gatewayError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// eventFilter return the filter of an events route, device being the address
+// of the device or empty for every device of addresses, every device when
+// addresses is nil
+func eventFilter(r *http.Request, device string, addresses map[string]bool) func(Event) bool {
+	var topics []string
+	for _, value := range r.URL.Query()["topic"] {
+		for _, topic := range strings.Split(value, ",") {
+			if topic = strings.TrimSpace(topic); topic != "" {
+				topics = append(topics, topic)
+			}
+		}
+	}
+	matchTopic := TopicFilter(topics...)
+	return func(ev Event) bool {
+		if addresses != nil && !addresses[ev.Device] {
+			return false
+		}
+		return (device == "" || ev.Device == device) && matchTopic(ev)
+	}
+}
+
+func (gateway *Gateway) serveEvents(w http.ResponseWriter, r *http.Request, filter func(Event) bool) {
+	if gateway.Events == nil {
+		gatewayError(w, http.StatusNotFound, errors.New("event streaming disabled"))
+		return
+	}
+	if isWebSocket(r) {
+		gateway.serveWebSocketEvents(w, r, filter)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		gatewayError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	events, cancel := gateway.Events.Subscribe(filter, 256)
+	defer cancel()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	/* 定时发送注释行,避免代理关闭空闲连接 */
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+	shutdown := streamsDone(r)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-shutdown:
+			return
+		case <-keepAlive.C:
+			w.Write([]byte(": keep-alive\n\n"))
+		case ev := <-events:
+			data, err := json.Marshal(NewEventJSON(ev))
+			if err != nil {
+				continue
+			}
+			w.Write([]byte("event: " + topicPath(ev.Topic) + "\ndata: "))
+			w.Write(data)
+			w.Write([]byte("\n\n"))
+		}
+		flusher.Flush()
+	}
+}
+
+func (gateway *Gateway) serveWebSocketEvents(w http.ResponseWriter, r *http.Request, filter func(Event) bool) {
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		gatewayError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer ws.Close()
+	events, cancel := gateway.Events.Subscribe(filter, 256)
+	defer cancel()
+	closed := make(chan struct{})
+	go func() {
+		ws.readLoop()
+		close(closed)
+	}()
+	/* 升级后的连接不受Shutdown管理 */
+	shutdown := streamsDone(r)
+	for {
+		select {
+		case <-closed:
+			return
+		case <-shutdown:
+			return
+		case ev := <-events:
+			data, err := json.Marshal(NewEventJSON(ev))
+			if err != nil {
+				continue
+			}
+			if err := ws.WriteText(data); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package onvif
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const streamUriResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:trt="http://www.onvif.org/ver10/media/wsdl">
+<env:Body><trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://10.1.1.200/stream1</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse></env:Body></env:Envelope>`
+
+/* 记录调用的中继,先写入written再返回err */
+type stubRelay struct {
+	calls   int
+	written string
+	err     error
+	// started and release, when set, hold the relay until release is closed
+	started chan struct{}
+	release chan struct{}
+}
+
+func (relay *stubRelay) ContentType() string {
+	return "video/mp2t"
+}
+
+func (relay *stubRelay) Relay(ctx context.Context, streamURI, username, password string, w io.Writer) error {
+	relay.calls++
+	if relay.started != nil {
+		close(relay.started)
+		select {
+		case <-relay.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if relay.written != "" {
+		io.WriteString(w, relay.written)
+	}
+	return relay.err
+}
+
+func streamRequest(t *testing.T, relay *stubRelay, method string) (response *httptest.ResponseRecorder, aborted bool) {
+	registry := NewRegistry(nil)
+	registry.Add("cam", cannedDevice(streamUriResponse), nil)
+	gateway := NewGateway(registry, []byte("secret"))
+	gateway.Relay = relay
+	signed, _, err := gateway.SignURL("cam", "stream", "Profile_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = httptest.NewRecorder()
+	defer func() {
+		if r := recover(); r != nil {
+			if r != http.ErrAbortHandler {
+				panic(r)
+			}
+			aborted = true
+		}
+	}()
+	gateway.ServeHTTP(response, httptest.NewRequest(method, signed, nil))
+	return response, false
+}
+
+func TestGatewayStreamHead(t *testing.T) {
+	relay := &stubRelay{}
+	response, _ := streamRequest(t, relay, http.MethodHead)
+	if response.Code != http.StatusOK || response.Header().Get("Content-Type") != "video/mp2t" || relay.calls != 0 {
+		t.Fatalf("status %d, content type %s, relay calls %d", response.Code, response.Header().Get("Content-Type"), relay.calls)
+	}
+}
+
+func TestGatewayStreamError(t *testing.T) {
+	/* 流开始前失败,返回502 */
+	response, aborted := streamRequest(t, &stubRelay{err: errors.New("ffmpeg: exit status 1: 401 Unauthorized")}, http.MethodGet)
+	if aborted || response.Code != http.StatusBadGateway || response.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %s, aborted %v", response.Code, response.Header().Get("Content-Type"), aborted)
+	}
+	/* 流开始后失败,中断连接 */
+	response, aborted = streamRequest(t, &stubRelay{written: "ts", err: errors.New("ffmpeg: exit status 1")}, http.MethodGet)
+	if !aborted || response.Body.String() != "ts" {
+		t.Fatalf("body %q, aborted %v", response.Body.String(), aborted)
+	}
+	response, aborted = streamRequest(t, &stubRelay{written: "ts"}, http.MethodGet)
+	if aborted || response.Code != http.StatusOK || response.Body.String() != "ts" {
+		t.Fatalf("status %d, body %q, aborted %v", response.Code, response.Body.String(), aborted)
+	}
+}
+
+func TestGatewayServeShutdown(t *testing.T) {
+	registry := NewRegistry(nil)
+	registry.Add("cam", cannedDevice(streamUriResponse), nil)
+	relay := &stubRelay{written: "ts", started: make(chan struct{}), release: make(chan struct{})}
+	gateway := NewGateway(registry, []byte("secret"))
+	gateway.Relay = relay
+	gateway.Events = NewEventBroker()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- gateway.Serve(ctx, listener)
+	}()
+	base := "http://" + listener.Addr().String()
+	events, err := http.Get(base + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Body.Close()
+	signed, _, _ := gateway.SignURL("cam", "stream", "Profile_1")
+	stream := make(chan string, 1)
+	go func() {
+		response, err := http.Get(base + signed)
+		if err != nil {
+			stream <- err.Error()
+			return
+		}
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		stream <- string(body)
+	}()
+	<-relay.started
+	cancel()
+	/* 事件流在关闭开始时结束 */
+	if _, err := bufio.NewReader(events.Body).ReadString('x'); err == nil {
+		t.Fatal("event stream still open")
+	}
+	/* 进行中的请求不被取消,得以完成 */
+	time.Sleep(50 * time.Millisecond)
+	close(relay.release)
+	if body := <-stream; body != "ts" {
+		t.Fatalf("stream %q", body)
+	}
+	if err := <-served; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Serve returned %v", err)
+	}
+}
+
+func TestGatewayEvents(t *testing.T) {
+	registry := NewRegistry(nil)
+	registry.Add("cam", newDevice(DeviceParams{Ipddr: "10.1.1.200"}), nil)
+	gateway := NewGateway(registry, []byte("secret"))
+	gateway.Events = NewEventBroker()
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+	input := Event{Device: "10.1.1.200", Topic: "tns1:Device/Trigger/DigitalInput", Operation: "Changed", Data: map[string]string{"LogicalState": "true"}}
+
+	response, err := http.Get(server.URL + "/devices/cam/events?topic=VideoSource,Device/Trigger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("content type %s", response.Header.Get("Content-Type"))
+	}
+	/* 其他设备和其他主题的事件被过滤 */
+	gateway.Events.Handle(Event{Device: "10.1.1.201", Topic: input.Topic})
+	gateway.Events.Handle(Event{Device: "10.1.1.200", Topic: "tns1:RuleEngine/CellMotionDetector/Motion"})
+	gateway.Events.Handle(input)
+	reader := bufio.NewReader(response.Body)
+	name, _ := reader.ReadString('\n')
+	data, _ := reader.ReadString('\n')
+	if name != "event: Device/Trigger/DigitalInput\n" || !strings.HasPrefix(data, "data: {") || !strings.Contains(data, `"device":"10.1.1.200","topic":"tns1:Device/Trigger/DigitalInput"`) {
+		t.Fatalf("event %q %q", name, data)
+	}
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET /events?topic=Device HTTP/1.1\r\nHost: gateway\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	reader = bufio.NewReader(conn)
+	upgrade, err := http.ReadResponse(reader, nil)
+	if err != nil || upgrade.StatusCode != http.StatusSwitchingProtocols || upgrade.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("upgrade %+v, %v", upgrade, err)
+	}
+	/* 升级完成后订阅才建立,等待事件到达 */
+	frames := make(chan []byte, 1)
+	go func() {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			close(frames)
+			return
+		}
+		length := int(header[1] & 0x7f)
+		if length == 126 {
+			extended := make([]byte, 2)
+			io.ReadFull(reader, extended)
+			length = int(extended[0])<<8 | int(extended[1])
+		}
+		payload := make([]byte, length)
+		io.ReadFull(reader, payload)
+		frames <- append(header[:1], payload...)
+	}()
+	for delivered := false; !delivered; {
+		gateway.Events.Handle(input)
+		select {
+		case frame := <-frames:
+			if frame == nil || frame[0] != 0x81 || !strings.Contains(string(frame[1:]), `"data":{"LogicalState":"true"}`) {
+				t.Fatalf("frame %q", frame)
+			}
+			delivered = true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -4,11 +4,10 @@ go 1.17
 
 require (
 	github.com/gofrs/uuid v4.2.0+incompatible
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/net v0.0.0-20211216030914-fe4d6282115f
 )
 
-require github.com/elgs/gostrgen v0.0.0-20161222160715-9d61ae07eeae
-
 require (
 	github.com/beevik/etree v1.1.0
 	golang.org/x/sys v0.0.0-20210423082822-04245dca01da // indirect
@@ -0,0 +1,400 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DeviceHealth last known state of a device watched by a HealthMonitor
+type DeviceHealth struct {
+	Device *Device
+	// Status HealthOnline, HealthDegraded, HealthOffline or HealthQuarantined
+	Status    string
+	Error     string
+	LastCheck time.Time
+	LastSeen  time.Time
+	Latency   time.Duration
+	// Certificate last certificate presented by the HTTPS service
+	Certificate *CertificateInfo
+	// CertificateError pin mismatch or handshake failure of the last check
+	CertificateError string
+	// Alarms storage and recording failures in progress, reported by the
+	// events of the device, see HealthMonitor.WatchEvents
+	Alarms []HealthAlarm
+	// RecordingJobs last state or mode reported for each recording job
+	RecordingJobs map[string]string
+	// Failures and Successes consecutive failed and successful probes
+	Failures  int
+	Successes int
+	// Faults consecutive probes the device answered with an error, see
+	// HealthMonitor.QuarantineAfter
+	Faults int
+	// NextCheck time before which Run does not probe the device again, set
+	// while an offline device is backed off
+	NextCheck time.Time
+}
+
+// HealthMonitor probe the devices of a fleet periodically
+type HealthMonitor struct {
+	Fleet *Fleet
+	// Registry when set, the devices registered at each round are probed
+	// instead of Fleet
+	Registry *Registry
+	// Interval between two rounds of probes, 0 means 30s
+	Interval time.Duration
+	// Timeout of one probe, 0 means 5s
+	Timeout time.Duration
+	// FailureThreshold consecutive failed probes before an online device is
+	// reported HealthOffline, 0 means 2; RecoveryThreshold consecutive
+	// successful probes before an offline device is back online, 0 means 2.
+	// A device probed for the first time takes the result of the probe.
+	FailureThreshold  int
+	RecoveryThreshold int
+	// QuarantineAfter consecutive probes answered with an error, the device
+	// being reachable, after which the device is quarantined, see
+	// Device.Quarantine; 0 never quarantines a device
+	QuarantineAfter int
+	// MaxBackoff longest delay between two probes of an offline device, the
+	// delay doubling from Interval at each failure, 0 means 10 minutes
+	MaxBackoff time.Duration
+	// Pins optional certificate store, when set the certificate of every
+	// HTTPS device is recorded and checked at each probe
+	Pins *CertificatePins
+	// OnChange called when the status of a device changes
+	OnChange func(DeviceHealth)
+	// Store optional persistence of the health of the devices, so that a
+	// restarted monitor keeps the failure counts, backoffs and alarms
+	Store KVStore
+	// OnError optional callback for the errors of Store
+	OnError func(dev *Device, err error)
+
+	mutex  sync.Mutex
+	health map[*Device]DeviceHealth
+}
+
+// NewHealthMonitor return a monitor of the fleet
+func NewHealthMonitor(fleet *Fleet) *HealthMonitor {
+	return &HealthMonitor{Fleet: fleet, health: make(map[*Device]DeviceHealth)}
+}
+
+// NewRegistryHealthMonitor return a monitor of the devices of the registry
+func NewRegistryHealthMonitor(registry *Registry) *HealthMonitor {
+	return &HealthMonitor{Registry: registry, health: make(map[*Device]DeviceHealth)}
+}
+
+// Status return the last known state of every device of the fleet
+func (monitor *HealthMonitor) Status() []DeviceHealth {
+	fleet := monitor.fleet()
+	for _, dev := range fleet.Devices {
+		monitor.restore(dev)
+	}
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	status := make([]DeviceHealth, 0, len(fleet.Devices))
+	for _, dev := range fleet.Devices {
+		health, ok := monitor.current(dev)
+		if !ok {
+			health = DeviceHealth{Device: dev}
+		}
+		status = append(status, health)
+	}
+	return status
+}
+
+// Check probe every device once, the backed off ones included, and return
+// their state
+func (monitor *HealthMonitor) Check(ctx context.Context) []DeviceHealth {
+	monitor.round(ctx, time.Time{})
+	return monitor.Status()
+}
+
+// round probe the devices whose next check is due at now, every device
+// when now is zero
+func (monitor *HealthMonitor) round(ctx context.Context, now time.Time) {
+	monitor.fleet().each(ctx, func(i int, dev *Device) {
+		monitor.restore(dev)
+		monitor.mutex.Lock()
+		health, _ := monitor.current(dev)
+		monitor.mutex.Unlock()
+		next := health.NextCheck
+		if !now.IsZero() && now.Before(next) {
+			return
+		}
+		monitor.probe(ctx, dev)
+	})
+}
+
+func (monitor *HealthMonitor) fleet() *Fleet {
+	if monitor.Registry != nil {
+		return monitor.Registry.Fleet(nil)
+	}
+	return monitor.Fleet
+}
+
+// Run probe the devices every Interval until ctx is done
+func (monitor *HealthMonitor) Run(ctx context.Context) error {
+	interval := monitor.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		monitor.round(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (monitor *HealthMonitor) probe(ctx context.Context, dev *Device) {
+	timeout := monitor.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	monitor.restore(dev)
+	monitor.mutex.Lock()
+	health, ok := monitor.current(dev)
+	monitor.mutex.Unlock()
+	previous := health.Status
+	health.Device = dev
+
+	/* 隔离中的设备不探测,解除隔离后按首次探测处理 */
+	if dev.Quarantined() != nil {
+		health.Status, health.NextCheck = HealthQuarantined, time.Time{}
+		monitor.changed(ok, previous, monitor.store(dev, health))
+		return
+	}
+	/* GetSystemDateAndTime无需认证,作为存活探测 */
+	ping, err := dev.Ping(probeCtx)
+	health.LastCheck = time.Now()
+	if err != nil {
+		health.Failures, health.Successes = health.Failures+1, 0
+		health.Error = err.Error()
+		if isTransportError(err) {
+			health.Faults = 0
+		} else {
+			health.Faults++
+		}
+	} else {
+		health.Failures, health.Successes, health.Faults = 0, health.Successes+1, 0
+		health.Error = ""
+		health.LastSeen = health.LastCheck
+		health.Latency = ping.Latency
+	}
+	health.Status = monitor.nextStatus(health)
+	health.NextCheck = monitor.nextCheck(health)
+	if monitor.QuarantineAfter > 0 && health.Faults >= monitor.QuarantineAfter {
+		dev.Quarantine(fmt.Sprintf("%d failed health probes: %s", health.Faults, health.Error))
+		health.Status, health.NextCheck, health.Faults = HealthQuarantined, time.Time{}, 0
+	}
+
+	/* 记录并校验HTTPS证书 */
+	if monitor.Pins != nil && err == nil {
+		health.CertificateError = ""
+		info, err := dev.ObserveCertificate(probeCtx, monitor.Pins)
+		switch {
+		case errors.Is(err, ErrNoHTTPS):
+		case err == nil || errors.Is(err, ErrCertificateChanged):
+			health.Certificate = &info
+			if err != nil {
+				health.CertificateError = err.Error()
+			}
+		default:
+			health.CertificateError = err.Error()
+		}
+	}
+
+	monitor.changed(ok, previous, monitor.store(dev, health))
+}
+
+// store record the health of the device after a probe, with the alarms of
+// its current state as the events may have updated them during the probe
+func (monitor *HealthMonitor) store(dev *Device, health DeviceHealth) DeviceHealth {
+	monitor.mutex.Lock()
+	current, _ := monitor.current(dev)
+	health.Alarms, health.RecordingJobs = current.Alarms, current.RecordingJobs
+	if health.Status != HealthOffline && health.Status != HealthQuarantined {
+		health.Status = onlineStatus(health.Alarms)
+	}
+	monitor.health[dev] = health
+	monitor.mutex.Unlock()
+	monitor.persist(health)
+	return health
+}
+
+// changed call OnChange when the status of the device changed, known telling
+// whether the device had a previous status
+func (monitor *HealthMonitor) changed(known bool, previous string, health DeviceHealth) {
+	if monitor.OnChange != nil && (!known || previous != health.Status) {
+		monitor.OnChange(health)
+	}
+}
+
+// nextStatus return the status of the device after its last probe: the
+// status only changes after FailureThreshold failures or RecoveryThreshold
+// successes in a row, so that a lost packet does not flap the device
+func (monitor *HealthMonitor) nextStatus(health DeviceHealth) string {
+	failures, successes := monitor.FailureThreshold, monitor.RecoveryThreshold
+	if failures <= 0 {
+		failures = 2
+	}
+	if successes <= 0 {
+		successes = 2
+	}
+	switch {
+	case health.Status == "" || health.Status == HealthQuarantined:
+		/* 首次探测及解除隔离后直接采用结果 */
+		if health.Failures > 0 {
+			return HealthOffline
+		}
+		return onlineStatus(health.Alarms)
+	case health.Status == HealthOffline:
+		if health.Successes >= successes {
+			return onlineStatus(health.Alarms)
+		}
+		return HealthOffline
+	case health.Failures >= failures:
+		return HealthOffline
+	}
+	return health.Status
+}
+
+// nextCheck return the time of the next probe of an offline device, zero
+// for the devices probed at every round
+func (monitor *HealthMonitor) nextCheck(health DeviceHealth) time.Time {
+	if health.Status != HealthOffline || health.Failures == 0 {
+		return time.Time{}
+	}
+	interval := durationOr(monitor.Interval, 30*time.Second)
+	maxBackoff := durationOr(monitor.MaxBackoff, 10*time.Minute)
+	delay := interval
+	for i := 1; i < health.Failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	/* 提前半个间隔,避免与Run的轮次错开后多等一轮 */
+	return health.LastCheck.Add(delay - interval/2)
+}
+
+// healthRecord persisted health of a device, see HealthMonitor.Store
+type healthRecord struct {
+	Status           string            `json:"status"`
+	Error            string            `json:"error,omitempty"`
+	LastCheck        time.Time         `json:"lastCheck"`
+	LastSeen         time.Time         `json:"lastSeen"`
+	Latency          time.Duration     `json:"latency"`
+	Certificate      *CertificateInfo  `json:"certificate,omitempty"`
+	CertificateError string            `json:"certificateError,omitempty"`
+	Alarms           []HealthAlarm     `json:"alarms,omitempty"`
+	RecordingJobs    map[string]string `json:"recordingJobs,omitempty"`
+	Failures         int               `json:"failures"`
+	Successes        int               `json:"successes"`
+	Faults           int               `json:"faults,omitempty"`
+	NextCheck        time.Time         `json:"nextCheck"`
+	// Quarantine reason of the quarantine of the device, restored with the
+	// health so that a restart does not release it
+	Quarantine string `json:"quarantine,omitempty"`
+}
+
+// restore read the health of dev from Store when the device was never probed
+// by this monitor, without holding the mutex during the read
+func (monitor *HealthMonitor) restore(dev *Device) {
+	if monitor.Store == nil {
+		return
+	}
+	monitor.mutex.Lock()
+	_, ok := monitor.health[dev]
+	monitor.mutex.Unlock()
+	if ok {
+		return
+	}
+	record := healthRecord{}
+	found, err := getJSON(monitor.Store, HealthBucket, dev.Params.Ipddr, &record)
+	if err != nil {
+		monitor.reportError(dev, err)
+	}
+	if !found || err != nil {
+		return
+	}
+	health := DeviceHealth{
+		Device:           dev,
+		Status:           record.Status,
+		Error:            record.Error,
+		LastCheck:        record.LastCheck,
+		LastSeen:         record.LastSeen,
+		Latency:          record.Latency,
+		Certificate:      record.Certificate,
+		CertificateError: record.CertificateError,
+		Alarms:           record.Alarms,
+		RecordingJobs:    record.RecordingJobs,
+		Failures:         record.Failures,
+		Successes:        record.Successes,
+		Faults:           record.Faults,
+		NextCheck:        record.NextCheck,
+	}
+	monitor.mutex.Lock()
+	/* 读取期间设备可能已被探测或收到事件,以内存中的状态为准 */
+	_, ok = monitor.current(dev)
+	if !ok {
+		monitor.health[dev] = health
+	}
+	monitor.mutex.Unlock()
+	if !ok && record.Quarantine != "" {
+		dev.Quarantine(record.Quarantine)
+	}
+}
+
+// current return the health of dev known by the monitor, restore reading it
+// from Store beforehand; the caller holds the mutex
+func (monitor *HealthMonitor) current(dev *Device) (DeviceHealth, bool) {
+	if monitor.health == nil {
+		monitor.health = make(map[*Device]DeviceHealth)
+	}
+	health, ok := monitor.health[dev]
+	return health, ok
+}
+
+// persist save the health of the device in Store
+func (monitor *HealthMonitor) persist(health DeviceHealth) {
+	if monitor.Store == nil {
+		return
+	}
+	record := healthRecord{
+		Status:           health.Status,
+		Error:            health.Error,
+		LastCheck:        health.LastCheck,
+		LastSeen:         health.LastSeen,
+		Latency:          health.Latency,
+		Certificate:      health.Certificate,
+		CertificateError: health.CertificateError,
+		Alarms:           health.Alarms,
+		RecordingJobs:    health.RecordingJobs,
+		Failures:         health.Failures,
+		Successes:        health.Successes,
+		Faults:           health.Faults,
+		NextCheck:        health.NextCheck,
+	}
+	if quarantine := health.Device.Quarantined(); quarantine != nil {
+		record.Quarantine = quarantine.Reason
+	}
+	if err := putJSON(monitor.Store, HealthBucket, health.Device.Params.Ipddr, record); err != nil {
+		monitor.reportError(health.Device, err)
+	}
+}
+
+func (monitor *HealthMonitor) reportError(dev *Device, err error) {
+	if monitor.OnError != nil {
+		monitor.OnError(dev, err)
+	}
+}
@@ -0,0 +1,122 @@
+package onvif
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/* 健康探测的设备: down时断开连接,fault时以fault应答 */
+type healthDevice struct {
+	mutex  sync.Mutex
+	down   bool
+	fault  bool
+	probes int
+}
+
+func (fake *healthDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.mutex.Lock()
+	fake.probes++
+	down, fault := fake.down, fake.fault
+	fake.mutex.Unlock()
+	if down {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+		return
+	}
+	body := `<tds:GetSystemDateAndTimeResponse><tds:SystemDateAndTime><tt:DateTimeType>NTP</tt:DateTimeType><tt:UTCDateTime>` +
+		`<tt:Time><tt:Hour>10</tt:Hour><tt:Minute>0</tt:Minute><tt:Second>0</tt:Second></tt:Time><tt:Date><tt:Year>2026</tt:Year><tt:Month>10</tt:Month><tt:Day>16</tt:Day></tt:Date>` +
+		`</tt:UTCDateTime></tds:SystemDateAndTime></tds:GetSystemDateAndTimeResponse>`
+	if fault {
+		w.WriteHeader(http.StatusInternalServerError)
+		body = `<s:Fault><s:Code><s:Value>s:Receiver</s:Value></s:Code><s:Reason><s:Text>internal error</s:Text></s:Reason></s:Fault>`
+	}
+	w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:tds="http://www.onvif.org/ver10/device/wsdl"><s:Body>` + body + `</s:Body></s:Envelope>`))
+}
+
+func (fake *healthDevice) set(down, fault bool) {
+	fake.mutex.Lock()
+	fake.down, fake.fault = down, fault
+	fake.mutex.Unlock()
+}
+
+func (fake *healthDevice) count() int {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return fake.probes
+}
+
+func newHealthDevice(t *testing.T, fake *healthDevice) *Device {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://")})
+	dev.endpoints[ServiceDevice] = server.URL
+	return dev
+}
+
+func TestHealthMonitorHysteresis(t *testing.T) {
+	fake := &healthDevice{}
+	dev := newHealthDevice(t, fake)
+	monitor := NewHealthMonitor(&Fleet{Devices: []*Device{dev}})
+	var changes []string
+	monitor.OnChange = func(health DeviceHealth) {
+		changes = append(changes, health.Status)
+	}
+	ctx := context.Background()
+	for i, step := range []struct {
+		down   bool
+		status string
+	}{
+		{false, HealthOnline},
+		/* 一次失败不改变状态 */
+		{true, HealthOnline},
+		{true, HealthOffline},
+		{false, HealthOffline},
+		{true, HealthOffline},
+		{false, HealthOffline},
+		{false, HealthOnline},
+	} {
+		fake.set(step.down, false)
+		if health := monitor.Check(ctx)[0]; health.Status != step.status {
+			t.Fatalf("probe %d: status %s, want %s (%d failures, %d successes)", i, health.Status, step.status, health.Failures, health.Successes)
+		}
+	}
+	if strings.Join(changes, " ") != "online offline online" {
+		t.Fatalf("changes %v", changes)
+	}
+}
+
+func TestHealthMonitorBackoff(t *testing.T) {
+	fake := &healthDevice{down: true}
+	dev := newHealthDevice(t, fake)
+	monitor := NewHealthMonitor(&Fleet{Devices: []*Device{dev}})
+	monitor.Interval, monitor.MaxBackoff = time.Second, 4*time.Second
+	ctx := context.Background()
+	/* 离线设备的探测间隔从Interval倍增至MaxBackoff */
+	for i, delay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		health := monitor.Check(ctx)[0]
+		if health.Status != HealthOffline || health.NextCheck.Sub(health.LastCheck) != delay-time.Second/2 {
+			t.Fatalf("failure %d: status %s, next check after %s", i+1, health.Status, health.NextCheck.Sub(health.LastCheck))
+		}
+	}
+	/* 退避期间Run的轮次不探测该设备 */
+	probes := fake.count()
+	monitor.round(ctx, time.Now())
+	if fake.count() != probes {
+		t.Fatal("device probed while backed off")
+	}
+	monitor.round(ctx, time.Now().Add(4*time.Second))
+	if fake.count() != probes+1 {
+		t.Fatal("device not probed after the backoff")
+	}
+	/* 恢复后每轮探测 */
+	fake.set(false, false)
+	monitor.Check(ctx)
+	if health := monitor.Check(ctx)[0]; health.Status != HealthOnline || !health.NextCheck.IsZero() {
+		t.Fatalf("status %s, next check %s after recovery", health.Status, health.NextCheck)
+	}
+}
@@ -0,0 +1,157 @@
+package onvif
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PolarisM78/go-onvif/types/imaging"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ImagingCapabilities return the capabilities of the imaging service, a
+// NotSupportedError is returned when the device has no imaging service
+func (dev *Device) ImagingCapabilities(ctx context.Context) (imaging.Capabilities, error) {
+	if _, err := dev.getEndpoint("imaging"); err != nil {
+		return imaging.Capabilities{}, &NotSupportedError{Service: "imaging"}
+	}
+	response := imaging.GetServiceCapabilitiesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, imaging.GetServiceCapabilities{}, &response, ""); err != nil {
+		return imaging.Capabilities{}, err
+	}
+	return response.Capabilities, nil
+}
+
+// requireImaging check the imaging capability named capability before a call
+// the device would certainly fault
+func (dev *Device) requireImaging(ctx context.Context, capability string, supported func(imaging.Capabilities) bool) error {
+	capabilities, err := dev.ImagingCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	if !supported(capabilities) {
+		return &NotSupportedError{Service: "imaging", Capability: capability}
+	}
+	return nil
+}
+
+func hasImagingPresets(capabilities imaging.Capabilities) bool {
+	return capabilities.Presets
+}
+
+// ImagingPresets return the imaging presets of the video source
+func (dev *Device) ImagingPresets(ctx context.Context, videoSource string) ([]imaging.ImagingPreset, error) {
+	if err := dev.requireImaging(ctx, "Presets", hasImagingPresets); err != nil {
+		return nil, err
+	}
+	response := imaging.GetPresetsResponse{}
+	request := imaging.GetPresets{VideoSourceToken: onvif.ReferenceToken(videoSource)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return nil, err
+	}
+	return response.Preset, nil
+}
+
+// CurrentImagingPreset return the preset last applied to the video source
+func (dev *Device) CurrentImagingPreset(ctx context.Context, videoSource string) (imaging.ImagingPreset, error) {
+	if err := dev.requireImaging(ctx, "Presets", hasImagingPresets); err != nil {
+		return imaging.ImagingPreset{}, err
+	}
+	response := imaging.GetCurrentPresetResponse{}
+	request := imaging.GetCurrentPreset{VideoSourceToken: onvif.ReferenceToken(videoSource)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return imaging.ImagingPreset{}, err
+	}
+	return response.Preset, nil
+}
+
+// SetImagingPreset apply the imaging preset to the video source
+func (dev *Device) SetImagingPreset(ctx context.Context, videoSource, preset string) error {
+	if err := dev.requireImaging(ctx, "Presets", hasImagingPresets); err != nil {
+		return err
+	}
+	request := imaging.SetCurrentPreset{
+		VideoSourceToken: onvif.ReferenceToken(videoSource),
+		PresetToken:      onvif.ReferenceToken(preset),
+	}
+	return dev.CallMethodInterfaceContext(ctx, request, &imaging.SetCurrentPresetResponse{}, "")
+}
+
+// ImagingOptions return the value ranges of the imaging settings of the video source
+func (dev *Device) ImagingOptions(ctx context.Context, videoSource string) (onvif.ImagingOptions20, error) {
+	response := imaging.GetOptionsResponse{}
+	request := imaging.GetOptions{VideoSourceToken: onvif.ReferenceToken(videoSource)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return onvif.ImagingOptions20{}, err
+	}
+	return response.ImagingOptions, nil
+}
+
+func hasImageStabilization(capabilities imaging.Capabilities) bool {
+	return capabilities.ImageStabilization
+}
+
+// SetImageStabilization set the image stabilization mode (OFF, ON, AUTO or
+// Extended) and level of the video source, level is optional and validated
+// against the range reported by the device
+func (dev *Device) SetImageStabilization(ctx context.Context, videoSource, mode string, level *float64) error {
+	if err := dev.requireImaging(ctx, "ImageStabilization", hasImageStabilization); err != nil {
+		return err
+	}
+	options, err := dev.ImagingOptions(ctx, videoSource)
+	if err != nil {
+		return err
+	}
+	stabilization := options.Extension.ImageStabilization
+	modes := make([]string, len(stabilization.Mode))
+	for i, value := range stabilization.Mode {
+		modes[i] = string(value)
+	}
+	if !containsString(modes, mode) {
+		return fmt.Errorf("image stabilization mode %q not in %v", mode, modes)
+	}
+	if level != nil && (*level < stabilization.Level.Min || *level > stabilization.Level.Max) {
+		return fmt.Errorf("image stabilization level %g out of range [%g, %g]", *level, stabilization.Level.Min, stabilization.Level.Max)
+	}
+	extension := imaging.ImagingExtension{ImageStabilization: &imaging.ModeLevel{Mode: mode, Level: level}}
+	return dev.setImagingExtension(ctx, videoSource, extension)
+}
+
+// SetDefogging set the defogging mode (OFF, ON or AUTO) and level of the
+// video source, the level is normalized between 0 and 1 and only accepted
+// when the device reports it as adjustable
+func (dev *Device) SetDefogging(ctx context.Context, videoSource, mode string, level *float64) error {
+	options, err := dev.ImagingOptions(ctx, videoSource)
+	if err != nil {
+		return err
+	}
+	defogging := options.Extension.Extension.Extension.DefoggingOptions
+	if len(defogging.Mode) == 0 {
+		return &NotSupportedError{Service: "imaging", Capability: "Defogging"}
+	}
+	if !containsString(defogging.Mode, mode) {
+		return fmt.Errorf("defogging mode %q not in %v", mode, defogging.Mode)
+	}
+	if level != nil {
+		if !defogging.Level {
+			return &NotSupportedError{Service: "imaging", Capability: "Defogging level"}
+		}
+		if *level < 0 || *level > 1 {
+			return fmt.Errorf("defogging level %g out of range [0, 1]", *level)
+		}
+	}
+	extension := imaging.ImagingExtension{
+		Extension: &imaging.ImagingExtension2{
+			Extension: &imaging.ImagingExtension3{Defogging: &imaging.ModeLevel{Mode: mode, Level: level}},
+		},
+	}
+	return dev.setImagingExtension(ctx, videoSource, extension)
+}
+
+func (dev *Device) setImagingExtension(ctx context.Context, videoSource string, extension imaging.ImagingExtension) error {
+	request := imaging.SetImagingSettingsExtension{
+		VideoSourceToken: onvif.ReferenceToken(videoSource),
+		ImagingSettings:  imaging.ImagingExtensionSetting{Extension: extension},
+		ForcePersistence: true,
+	}
+	return dev.CallMethodInterfaceContext(ctx, request, &imaging.SetImagingSettingsExtensionResponse{}, "")
+}
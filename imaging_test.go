@@ -0,0 +1,82 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestImagingCapabilities(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<timg:GetServiceCapabilitiesResponse><timg:Capabilities ImageStabilization="false" Presets="true"/></timg:GetServiceCapabilitiesResponse>`,
+		"GetPresets":             `<timg:GetPresetsResponse><timg:Preset token="1" type="Indoor"><tt:Name>indoor</tt:Name></timg:Preset><timg:Preset token="2" type="Outdoor"><tt:Name>outdoor</tt:Name></timg:Preset></timg:GetPresetsResponse>`,
+	}, ServiceImaging)
+	ctx := context.Background()
+	presets, err := dev.ImagingPresets(ctx, "vs0")
+	if err != nil || len(presets) != 2 || presets[1].Type != "Outdoor" {
+		t.Fatalf("presets %+v, %v", presets, err)
+	}
+	/* 设备未声明的能力不发送请求 */
+	err = dev.SetImageStabilization(ctx, "vs0", "ON", nil)
+	var unsupported *NotSupportedError
+	if !errors.Is(err, ErrNotSupported) || !errors.As(err, &unsupported) || unsupported.Capability != "ImageStabilization" {
+		t.Fatalf("error %v", err)
+	}
+	if len(fake.sent("GetOptions")) != 0 || len(fake.sent("SetImagingSettings")) != 0 {
+		t.Fatal("image stabilization requested from a device without it")
+	}
+
+	_, dev = newScriptedDevice(t, map[string]string{})
+	if _, err := dev.ImagingPresets(ctx, "vs0"); !errors.As(err, &unsupported) || unsupported.Service != "imaging" || unsupported.Capability != "" {
+		t.Fatalf("error %v without imaging service", err)
+	}
+	if unsupported.Error() != "imaging service not supported by device" {
+		t.Fatalf("message %q", unsupported.Error())
+	}
+}
+
+const imagingOptions = `<timg:GetOptionsResponse><timg:ImagingOptions><tt:Extension>` +
+	`<tt:ImageStabilization><tt:Mode>OFF</tt:Mode><tt:Mode>ON</tt:Mode><tt:Level><tt:Min>0</tt:Min><tt:Max>100</tt:Max></tt:Level></tt:ImageStabilization>` +
+	`<tt:Extension><tt:Extension><tt:DefoggingOptions><tt:Mode>OFF</tt:Mode><tt:Mode>AUTO</tt:Mode><tt:Level>false</tt:Level></tt:DefoggingOptions></tt:Extension></tt:Extension>` +
+	`</tt:Extension></timg:ImagingOptions></timg:GetOptionsResponse>`
+
+func TestImageStabilizationAndDefogging(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<timg:GetServiceCapabilitiesResponse><timg:Capabilities ImageStabilization="true"/></timg:GetServiceCapabilitiesResponse>`,
+		"GetOptions":             imagingOptions,
+		"SetImagingSettings":     `<timg:SetImagingSettingsResponse/>`,
+	}, ServiceImaging)
+	ctx := context.Background()
+	level := 50.0
+	if err := dev.SetImageStabilization(ctx, "vs0", "ON", &level); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetDefogging(ctx, "vs0", "AUTO", nil); err != nil {
+		t.Fatal(err)
+	}
+	requests := fake.sent("SetImagingSettings")
+	if len(requests) != 2 ||
+		!strings.Contains(requests[0], "<onvif:Extension><onvif:ImageStabilization><onvif:Mode>ON</onvif:Mode><onvif:Level>50</onvif:Level></onvif:ImageStabilization></onvif:Extension>") ||
+		!strings.Contains(requests[1], "<onvif:Extension><onvif:Extension><onvif:Extension><onvif:Defogging><onvif:Mode>AUTO</onvif:Mode></onvif:Defogging>") {
+		t.Fatalf("requests %q", requests)
+	}
+	/* 超出选项范围的设置不发送 */
+	level = 150
+	for _, err := range []error{
+		dev.SetImageStabilization(ctx, "vs0", "AUTO", nil),
+		dev.SetImageStabilization(ctx, "vs0", "ON", &level),
+		dev.SetDefogging(ctx, "vs0", "ON", nil),
+	} {
+		if err == nil {
+			t.Fatal("setting outside the options accepted")
+		}
+	}
+	level = 0.5
+	if err := dev.SetDefogging(ctx, "vs0", "AUTO", &level); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("error %v for a fixed defogging level", err)
+	}
+	if len(fake.sent("SetImagingSettings")) != 2 {
+		t.Fatal("invalid setting sent")
+	}
+}
@@ -0,0 +1,123 @@
+package onvif
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/types/media"
+)
+
+// Health status values of an inventory record
+const (
+	HealthOnline  = "online"
+	HealthOffline = "offline"
+)
+
+// InventoryProfile media profile of an inventory record
+type InventoryProfile struct {
+	Token     string `json:"token"`
+	Name      string `json:"name"`
+	StreamURI string `json:"streamUri,omitempty"`
+}
+
+// InventoryRecord asset information of one device
+type InventoryRecord struct {
+	IP           string             `json:"ip"`
+	MAC          string             `json:"mac"`
+	Name         string             `json:"name"`
+	Manufacturer string             `json:"manufacturer"`
+	Model        string             `json:"model"`
+	Firmware     string             `json:"firmware"`
+	Serial       string             `json:"serial"`
+	HardwareID   string             `json:"hardwareId"`
+	Profiles     []InventoryProfile `json:"profiles"`
+	Health       string             `json:"health"`
+	Error        string             `json:"error,omitempty"`
+}
+
+// Inventory collect the asset information of the device, a device that can not
+// be queried is reported offline with the error instead of failing the call.
+func (dev *Device) Inventory(ctx context.Context) InventoryRecord {
+	record := InventoryRecord{
+		IP:    dev.Params.Ipddr,
+		MAC:   dev.Params.MAC,
+		Name:  dev.Params.Name,
+		Model: dev.Params.Model,
+	}
+	/* 获取设备基本信息 */
+	info := device.GetDeviceInformationResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &info, ""); err != nil {
+		record.Health = HealthOffline
+		record.Error = err.Error()
+		return record
+	}
+	record.Health = HealthOnline
+	record.Manufacturer = info.Manufacturer
+	record.Model = info.Model
+	record.Firmware = info.FirmwareVersion
+	record.Serial = info.SerialNumber
+	record.HardwareID = info.HardwareId
+	/* 获取profile及对应的直播地址 */
+	if _, err := dev.getEndpoint("media"); err != nil {
+		return record
+	}
+	profiles := media.GetProfilesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media.GetProfiles{}, &profiles, ""); err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	for _, profile := range profiles.Profiles {
+		item := InventoryProfile{Token: string(profile.Token), Name: string(profile.Name)}
+		item.StreamURI, _ = dev.GetStreamURI(ctx, string(profile.Token), StreamRTSP)
+		record.Profiles = append(record.Profiles, item)
+	}
+	return record
+}
+
+// Inventory collect the asset information of every device of the fleet, a
+// PartialError is returned with the records when ctx ended first
+func (fleet *Fleet) Inventory(ctx context.Context) ([]InventoryRecord, error) {
+	records := make([]InventoryRecord, len(fleet.Devices))
+	err := fleet.each(ctx, func(i int, dev *Device) {
+		records[i] = dev.Inventory(ctx)
+	})
+	return records, err
+}
+
+// inventoryCSVHeader column names written by WriteInventoryCSV
+var inventoryCSVHeader = []string{"ip", "mac", "name", "manufacturer", "model", "firmware", "serial", "hardware_id", "profiles", "stream_uris", "health", "error"}
+
+// WriteInventoryCSV write the records as CSV, one device per row. Profiles and
+// stream URIs are joined with ";" in the same order.
+func WriteInventoryCSV(w io.Writer, records []InventoryRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(inventoryCSVHeader); err != nil {
+		return err
+	}
+	for _, record := range records {
+		tokens := make([]string, 0, len(record.Profiles))
+		uris := make([]string, 0, len(record.Profiles))
+		for _, profile := range record.Profiles {
+			tokens = append(tokens, profile.Token)
+			uris = append(uris, profile.StreamURI)
+		}
+		row := []string{record.IP, record.MAC, record.Name, record.Manufacturer, record.Model, record.Firmware,
+			record.Serial, record.HardwareID, strings.Join(tokens, ";"), strings.Join(uris, ";"), record.Health, record.Error}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteInventoryJSON write the records as an indented JSON array
+func WriteInventoryJSON(w io.Writer, records []InventoryRecord) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
@@ -0,0 +1,58 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const inventoryInformation = `<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer><tds:Model>C1</tds:Model>` +
+	`<tds:FirmwareVersion>1.2</tds:FirmwareVersion><tds:SerialNumber>42</tds:SerialNumber><tds:HardwareId>7</tds:HardwareId></tds:GetDeviceInformationResponse>`
+
+func TestInventory(t *testing.T) {
+	_, dev := newScriptedDevice(t, map[string]string{
+		"GetDeviceInformation": inventoryInformation,
+		"GetProfiles":          `<trt:GetProfilesResponse><trt:Profiles token="main"><tt:Name>Main</tt:Name></trt:Profiles><trt:Profiles token="sub"><tt:Name>Sub</tt:Name></trt:Profiles></trt:GetProfilesResponse>`,
+		"GetStreamUri":         `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://192.168.0.10/stream</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`,
+	}, ServiceMedia)
+	dev.Params.MAC = "00:25:6b:1d:2b:6a"
+	record := dev.Inventory(context.Background())
+	if record.Health != HealthOnline || record.Manufacturer != "Acme" || record.Firmware != "1.2" || record.Serial != "42" || record.HardwareID != "7" || record.Error != "" {
+		t.Fatalf("record %+v", record)
+	}
+	/* 直播地址的主机改写为访问设备的地址 */
+	host, _ := dev.Params.address()
+	if len(record.Profiles) != 2 || record.Profiles[1].Name != "Sub" || !strings.HasPrefix(record.Profiles[0].StreamURI, "rtsp://"+host+"/") {
+		t.Fatalf("profiles %+v", record.Profiles)
+	}
+
+	offline := newDevice(DeviceParams{Ipddr: "127.0.0.1:1", Name: "gate"})
+	offline.endpoints[ServiceDevice] = "http://127.0.0.1:1/onvif/device_service"
+	records := []InventoryRecord{record, offline.Inventory(context.Background())}
+	if records[1].Health != HealthOffline || records[1].Error == "" || records[1].Name != "gate" {
+		t.Fatalf("offline record %+v", records[1])
+	}
+
+	buf := bytes.Buffer{}
+	if err := WriteInventoryCSV(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 3 || len(rows[0]) != len(inventoryCSVHeader) {
+		t.Fatalf("rows %q, %v", rows, err)
+	}
+	if rows[1][1] != "00:25:6b:1d:2b:6a" || rows[1][8] != "main;sub" || strings.Count(rows[1][9], ";") != 1 || rows[2][10] != HealthOffline {
+		t.Fatalf("rows %q", rows)
+	}
+	buf.Reset()
+	if err := WriteInventoryJSON(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	decoded := []InventoryRecord{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[0].Profiles[0].StreamURI != record.Profiles[0].StreamURI {
+		t.Fatalf("decoded %+v, %v", decoded, err)
+	}
+}
@@ -0,0 +1,154 @@
+package onvif
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Topics of the digital input and relay output property events
+const (
+	TopicDigitalInput = "Device/Trigger/DigitalInput"
+	TopicRelay        = "Device/Trigger/Relay"
+)
+
+// IOState state of one digital input or relay output of a device
+type IOState struct {
+	Device string
+	Token  string
+	Active bool
+	// Time UtcTime of the message which set the state
+	Time time.Time
+	// Initialized the state was set by the Initialized message sent when a
+	// subscription is created, false until the first one is received
+	Initialized bool
+}
+
+// IOWatcher state machine of the digital inputs or relay outputs of the
+// devices of an engine. It relies on the property semantics of the topics:
+// the device sends an Initialized message with the current state for every
+// input when a subscription is created, again each time the engine recreates
+// a lost subscription, so the state is resynchronized after any gap and the
+// changes missed meanwhile are reported.
+type IOWatcher struct {
+	topic    string
+	item     string
+	onChange func(IOState)
+
+	mutex  sync.Mutex
+	states map[string]IOState
+}
+
+// WatchDigitalInputs watch the digital inputs of the devices of engine,
+// onChange is called from the engine workers for every change of state,
+// including the first state of an input
+func WatchDigitalInputs(engine *EventEngine, onChange func(IOState)) *IOWatcher {
+	return newIOWatcher(engine, TopicDigitalInput, "InputToken", onChange)
+}
+
+// WatchRelayState watch the relay outputs of the devices of engine, see
+// WatchDigitalInputs
+func WatchRelayState(engine *EventEngine, onChange func(IOState)) *IOWatcher {
+	return newIOWatcher(engine, TopicRelay, "RelayToken", onChange)
+}
+
+func newIOWatcher(engine *EventEngine, topic, item string, onChange func(IOState)) *IOWatcher {
+	watcher := &IOWatcher{
+		topic:    topic,
+		item:     item,
+		onChange: onChange,
+		states:   make(map[string]IOState),
+	}
+	engine.Handle(watcher.handle)
+	return watcher
+}
+
+// topicMatch compare topic without its namespace prefix, devices use
+// different prefixes for the onvif topic namespace
+func topicMatch(topic, expected string) bool {
+	if index := strings.Index(topic, ":"); index >= 0 {
+		topic = topic[index+1:]
+	}
+	return topic == expected
+}
+
+// logicalState parse the LogicalState of an input (true/false) or a relay
+// (active/inactive)
+func logicalState(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "active", "1":
+		return true, true
+	case "false", "inactive", "0":
+		return false, true
+	}
+	return false, false
+}
+
+func (watcher *IOWatcher) handle(ev Event) {
+	if !topicMatch(ev.Topic, watcher.topic) {
+		return
+	}
+	token := ev.Source[watcher.item]
+	key := ev.Device + "/" + token
+	watcher.mutex.Lock()
+	if ev.Operation == "Deleted" {
+		delete(watcher.states, key)
+		watcher.mutex.Unlock()
+		return
+	}
+	active, ok := logicalState(ev.Data["LogicalState"])
+	if !ok {
+		watcher.mutex.Unlock()
+		return
+	}
+	previous, known := watcher.states[key]
+	/* 丢弃乱序到达的旧消息,初始化消息总是用于重新同步 */
+	if known && ev.Operation != "Initialized" && ev.Time.Before(previous.Time) {
+		watcher.mutex.Unlock()
+		return
+	}
+	state := IOState{
+		Device:      ev.Device,
+		Token:       token,
+		Active:      active,
+		Time:        ev.Time,
+		Initialized: previous.Initialized || ev.Operation == "Initialized",
+	}
+	watcher.states[key] = state
+	watcher.mutex.Unlock()
+	if (!known || previous.Active != active) && watcher.onChange != nil {
+		watcher.onChange(state)
+	}
+}
+
+// State return the last known state of the input or relay token of device
+func (watcher *IOWatcher) State(device, token string) (IOState, bool) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	state, ok := watcher.states[device+"/"+token]
+	return state, ok
+}
+
+// States return the last known state of every input or relay
+func (watcher *IOWatcher) States() []IOState {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	states := make([]IOState, 0, len(watcher.states))
+	for _, state := range watcher.states {
+		states = append(states, state)
+	}
+	return states
+}
+
+// Synced report whether an Initialized message was received for device, the
+// states of a device are only complete once it is synced
+func (watcher *IOWatcher) Synced(device string) bool {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	for _, state := range watcher.states {
+		if state.Device == device && state.Initialized {
+			return true
+		}
+	}
+	return false
+}
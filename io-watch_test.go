@@ -0,0 +1,48 @@
+package onvif
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigitalInputWatcher(t *testing.T) {
+	var changes []IOState
+	watcher := WatchDigitalInputs(NewEventEngine(1), func(state IOState) { changes = append(changes, state) })
+	at := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	input := func(operation, state string, offset time.Duration) Event {
+		return Event{
+			Device:    "10.1.1.200",
+			Topic:     "tns1:Device/Trigger/DigitalInput",
+			Operation: operation,
+			Time:      at.Add(offset),
+			Source:    map[string]string{"InputToken": "gate"},
+			Data:      map[string]string{"LogicalState": state},
+		}
+	}
+	for _, ev := range []Event{
+		input("Changed", "false", 0),
+		/* 同一状态不回调,乱序的旧消息被丢弃 */
+		input("Changed", "false", time.Second),
+		input("Changed", "true", 3*time.Second),
+		input("Changed", "false", 2*time.Second),
+		{Device: "10.1.1.200", Topic: "tns1:Device/Trigger/Relay", Source: map[string]string{"RelayToken": "gate"}, Data: map[string]string{"LogicalState": "inactive"}},
+	} {
+		watcher.handle(ev)
+	}
+	if len(changes) != 2 || changes[0].Active || !changes[1].Active {
+		t.Fatalf("changes %+v", changes)
+	}
+	if watcher.Synced("10.1.1.200") {
+		t.Fatal("synced without an Initialized message")
+	}
+	/* 重新订阅后的初始化消息总是重新同步,即使时间更早 */
+	watcher.handle(input("Initialized", "false", time.Second))
+	state, ok := watcher.State("10.1.1.200", "gate")
+	if !ok || state.Active || !state.Initialized || !watcher.Synced("10.1.1.200") || len(changes) != 3 {
+		t.Fatalf("state %+v, changes %+v", state, changes)
+	}
+	watcher.handle(input("Deleted", "", 4*time.Second))
+	if states := watcher.States(); len(states) != 0 {
+		t.Fatalf("states %+v after Deleted", states)
+	}
+}
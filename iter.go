@@ -0,0 +1,145 @@
+//go:build go1.23
+
+package onvif
+
+import (
+	"context"
+	"iter"
+
+	"github.com/PolarisM78/go-onvif/types/accesscontrol"
+	"github.com/PolarisM78/go-onvif/types/credential"
+	"github.com/PolarisM78/go-onvif/types/search"
+)
+
+// Iterator forms of the collections, for range over func on Go 1.23 and
+// later. The sequences are lazy: nothing is requested before the loop starts,
+// and breaking out of the loop stops the work underneath, e.g. the discovery
+// or the paging of a list.
+
+// DiscoverDevicesSeq iterate over the devices found by DiscoverDevices, with
+// the error of each, e.g. a failed connection; a failure to start the
+// discovery is yielded alone. Breaking out of the loop ends the discovery.
+//
+//	for found, err := range onvif.DiscoverDevicesSeq(ctx, onvif.DiscoveryOptions{ProbeOnly: true}) {
+//		...
+//	}
+func DiscoverDevicesSeq(ctx context.Context, opts DiscoveryOptions) iter.Seq2[DiscoveredDevice, error] {
+	return func(yield func(DiscoveredDevice, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		results, err := DiscoverDevices(ctx, opts)
+		if err != nil {
+			yield(DiscoveredDevice{}, err)
+			return
+		}
+		for found := range results {
+			if !yield(found, found.Err) {
+				return
+			}
+		}
+	}
+}
+
+// DevicesSeq iterate over the devices of the fleet until ctx ends
+//
+//	for dev := range fleet.DevicesSeq(ctx) {
+//		...
+//	}
+func (fleet *Fleet) DevicesSeq(ctx context.Context) iter.Seq[*Device] {
+	return func(yield func(*Device) bool) {
+		for _, dev := range fleet.Devices {
+			if ctx.Err() != nil || !yield(dev) {
+				return
+			}
+		}
+	}
+}
+
+// EventsSeq iterate over the events of dev delivered by the engine following
+// options, until ctx ends. The consumer is created when the loop starts and
+// closed when it ends, see Consume.
+func (engine *EventEngine) EventsSeq(ctx context.Context, dev *Device, options ConsumerOptions) iter.Seq[Event] {
+	return func(yield func(Event) bool) {
+		consumer := engine.Consume(dev, options)
+		defer consumer.Close()
+		events := consumer.Events()
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok || !yield(ev) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// AreasSeq iterate over the areas of the device, see Areas; the pages are
+// requested as the loop advances. A failure is yielded last.
+func (dev *Device) AreasSeq(ctx context.Context) iter.Seq2[accesscontrol.AreaInfo, error] {
+	return func(yield func(accesscontrol.AreaInfo, error) bool) {
+		err := dev.areaPages(ctx, func(page []accesscontrol.AreaInfo) bool {
+			return yieldPage(page, yield)
+		})
+		if err != nil {
+			yield(accesscontrol.AreaInfo{}, err)
+		}
+	}
+}
+
+// AccessPointsSeq iterate over the access points of the device, see
+// AccessPoints and AreasSeq
+func (dev *Device) AccessPointsSeq(ctx context.Context) iter.Seq2[accesscontrol.AccessPointInfo, error] {
+	return func(yield func(accesscontrol.AccessPointInfo, error) bool) {
+		err := dev.accessPointPages(ctx, func(page []accesscontrol.AccessPointInfo) bool {
+			return yieldPage(page, yield)
+		})
+		if err != nil {
+			yield(accesscontrol.AccessPointInfo{}, err)
+		}
+	}
+}
+
+// CredentialsSeq iterate over the credentials of the device, see Credentials
+// and AreasSeq
+func (dev *Device) CredentialsSeq(ctx context.Context) iter.Seq2[credential.CredentialInfo, error] {
+	return func(yield func(credential.CredentialInfo, error) bool) {
+		capabilities, err := dev.CredentialCapabilities(ctx)
+		if err != nil {
+			yield(credential.CredentialInfo{}, err)
+			return
+		}
+		err = dev.credentialPages(ctx, capabilities, func(page []credential.CredentialInfo) bool {
+			return yieldPage(page, yield)
+		})
+		if err != nil {
+			yield(credential.CredentialInfo{}, err)
+		}
+	}
+}
+
+// RecordingSearchSeq iterate over the recordings found by a search, see
+// SearchRecordings and AreasSeq; breaking out of the loop ends the search on
+// the device
+func (dev *Device) RecordingSearchSeq(ctx context.Context, criteria RecordingSearch) iter.Seq2[search.RecordingInformation, error] {
+	return func(yield func(search.RecordingInformation, error) bool) {
+		err := dev.recordingSearchPages(ctx, criteria, func(page []search.RecordingInformation) bool {
+			return yieldPage(page, yield)
+		})
+		if err != nil {
+			yield(search.RecordingInformation{}, err)
+		}
+	}
+}
+
+// yieldPage yield the items of a page, false when the loop was broken
+func yieldPage[T any](page []T, yield func(T, error) bool) bool {
+	for _, item := range page {
+		if !yield(item, nil) {
+			return false
+		}
+	}
+	return true
+}
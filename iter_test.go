@@ -0,0 +1,116 @@
+//go:build go1.23
+
+package onvif
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDevicesSeq(t *testing.T) {
+	fleet := NewFleet(newDevice(DeviceParams{Ipddr: "10.0.0.1"}), newDevice(DeviceParams{Ipddr: "10.0.0.2"}), newDevice(DeviceParams{Ipddr: "10.0.0.3"}))
+	var visited []string
+	for dev := range fleet.DevicesSeq(context.Background()) {
+		visited = append(visited, dev.Params.Ipddr)
+		if len(visited) == 2 {
+			break
+		}
+	}
+	if strings.Join(visited, ",") != "10.0.0.1,10.0.0.2" {
+		t.Fatalf("visited %v", visited)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for dev := range fleet.DevicesSeq(ctx) {
+		t.Fatalf("device %s after cancel", dev.Params.Ipddr)
+	}
+}
+
+func TestEventsSeq(t *testing.T) {
+	engine := testEngine(1)
+	dev := newDevice(DeviceParams{Ipddr: "192.168.1.10"})
+	consumers := func() int {
+		engine.mutex.Lock()
+		defer engine.mutex.Unlock()
+		return len(engine.consumers[dev])
+	}
+	topics := make(chan string)
+	go func() {
+		defer close(topics)
+		for ev := range engine.EventsSeq(context.Background(), dev, ConsumerOptions{Buffer: 4}) {
+			topics <- ev.Topic
+			break
+		}
+	}()
+	/* 循环开始后才创建消费者,结束后关闭 */
+	eventually(t, "consumer created", func() bool { return consumers() == 1 })
+	engine.Inject(context.Background(), dev, Event{Topic: "tns1:" + TopicMotionAlarm})
+	if topic := <-topics; topic != "tns1:"+TopicMotionAlarm {
+		t.Fatalf("topic %s", topic)
+	}
+	<-topics
+	if consumers() != 0 {
+		t.Fatal("consumer left open")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range engine.EventsSeq(ctx, dev, ConsumerOptions{}) {
+		}
+	}()
+	eventually(t, "consumer created", func() bool { return consumers() == 1 })
+	cancel()
+	<-done
+	if consumers() != 0 {
+		t.Fatal("consumer left open after cancel")
+	}
+}
+
+func TestPagedSeq(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities":    `<tac:GetServiceCapabilitiesResponse><tac:Capabilities MaxLimit="1"/></tac:GetServiceCapabilitiesResponse>`,
+		"GetAreaInfoList":           `<tac:GetAreaInfoListResponse><tac:NextStartReference>next</tac:NextStartReference><tac:AreaInfo token="lobby"><tac:Name>lobby</tac:Name></tac:AreaInfo></tac:GetAreaInfoListResponse>`,
+		"FindRecordings":            `<tse:FindRecordingsResponse><tse:SearchToken>search_1</tse:SearchToken></tse:FindRecordingsResponse>`,
+		"GetRecordingSearchResults": searchResults,
+		"EndSearch":                 `<tse:EndSearchResponse/>`,
+	}, ServiceAccessControl, ServiceSearch)
+	ctx := context.Background()
+	/* 跳出循环后不再请求下一页 */
+	for area, err := range dev.AreasSeq(ctx) {
+		if err != nil || area.Token != "lobby" {
+			t.Fatalf("area %+v, %v", area, err)
+		}
+		break
+	}
+	if pages := fake.sent("GetAreaInfoList"); len(pages) != 1 {
+		t.Fatalf("requests %q", pages)
+	}
+
+	var tokens []string
+	for recording, err := range dev.RecordingSearchSeq(ctx, RecordingSearch{}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, string(recording.RecordingToken))
+		break
+	}
+	if len(tokens) != 1 || tokens[0] != "rec_1" || len(fake.sent("EndSearch")) != 1 {
+		t.Fatalf("recordings %v, end search %q", tokens, fake.sent("EndSearch"))
+	}
+
+	/* 失败最后产生 */
+	fake.set("GetRecordingSearchResults", eventFault("ter:InvalidArgVal"))
+	var errs int
+	for recording, err := range dev.RecordingSearchSeq(ctx, RecordingSearch{}) {
+		if err == nil || recording.RecordingToken != "" {
+			t.Fatalf("recording %+v, %v", recording, err)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Fatalf("%d errors yielded", errs)
+	}
+}
@@ -0,0 +1,100 @@
+//go:build bbolt
+// +build bbolt
+
+package onvif
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltKVStore KVStore keeping the values in a bbolt database, every bucket
+// of the store being a bucket of the database. It is built with the bbolt
+// tag so that programs not using it do not compile go.etcd.io/bbolt.
+type BoltKVStore struct {
+	DB *bolt.DB
+}
+
+// OpenBoltKVStore open or create the database at path, it is locked until
+// Close so a second daemon fails instead of sharing the state
+func OpenBoltKVStore(path string) (*BoltKVStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltKVStore{DB: db}, nil
+}
+
+// Close close the database
+func (store *BoltKVStore) Close() error {
+	return store.DB.Close()
+}
+
+// Get return a copy of the value of key
+func (store *BoltKVStore) Get(bucket, key string) ([]byte, error) {
+	var value []byte
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return ErrKeyNotFound
+		}
+		data := b.Get([]byte(key))
+		if data == nil {
+			return ErrKeyNotFound
+		}
+		/* 事务结束后数据不再有效,需复制 */
+		value = append([]byte(nil), data...)
+		return nil
+	})
+	return value, err
+}
+
+// Put store value, the bucket is created on first use
+func (store *BoltKVStore) Put(bucket, key string, value []byte) error {
+	return store.DB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), value)
+	})
+}
+
+// Delete remove key
+func (store *BoltKVStore) Delete(bucket, key string) error {
+	return store.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+// ForEach call fn with the keys in byte order, fn may use the store
+func (store *BoltKVStore) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	/* 先读出再回调,fn中的写操作不能在只读事务内进行 */
+	var keys []string
+	var values [][]byte
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(key, value []byte) error {
+			keys = append(keys, string(key))
+			values = append(values, append([]byte(nil), value...))
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for i, key := range keys {
+		if err := fn(key, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+//go:build bbolt
+// +build bbolt
+
+package onvif
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltKVStore(t *testing.T) {
+	store, err := OpenBoltKVStore(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := store.Get("health", "10.1.1.200"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("missing bucket: %v", err)
+	}
+	for _, key := range []string{"10.1.1.201", "10.1.1.200"} {
+		if err := store.Put("health", key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	/* 回调中可以写入同一个存储 */
+	var keys []string
+	err = store.ForEach("health", func(key string, value []byte) error {
+		keys = append(keys, key)
+		return store.Delete("health", key)
+	})
+	if err != nil || len(keys) != 2 || keys[0] != "10.1.1.200" {
+		t.Fatalf("keys %v, %v", keys, err)
+	}
+	if _, err := store.Get("health", "10.1.1.200"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("deleted key: %v", err)
+	}
+}
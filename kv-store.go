@@ -0,0 +1,155 @@
+package onvif
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrKeyNotFound returned by KVStore.Get for a missing key
+var ErrKeyNotFound = errors.New("key not found")
+
+// Buckets of the state kept in a KVStore
+const (
+	SubscriptionBucket = "subscriptions"
+	HealthBucket       = "health"
+	SnapshotBucket     = "snapshots"
+)
+
+// KVStore key-value persistence of the state of the long running components,
+// the HealthMonitor, the SnapshotPoller and the EventEngine through
+// KVSubscriptionStore, so a restarted daemon carries on where it stopped.
+// The keys are grouped in buckets, one per component. MemoryKVStore keeps
+// the values in memory, BoltKVStore in a bbolt database when built with the
+// bbolt tag.
+type KVStore interface {
+	// Get return the value of key, ErrKeyNotFound when it is missing
+	Get(bucket, key string) ([]byte, error)
+	Put(bucket, key string, value []byte) error
+	// Delete remove key, a missing key is no error
+	Delete(bucket, key string) error
+	// ForEach call fn with every key of the bucket in order, until fn fails
+	ForEach(bucket string, fn func(key string, value []byte) error) error
+}
+
+// MemoryKVStore KVStore keeping the values in memory, for the tests and the
+// processes which need no persistence
+type MemoryKVStore struct {
+	mutex   sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryKVStore return an empty store
+func NewMemoryKVStore() *MemoryKVStore {
+	return &MemoryKVStore{buckets: make(map[string]map[string][]byte)}
+}
+
+// Get return a copy of the value of key
+func (store *MemoryKVStore) Get(bucket, key string) ([]byte, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	value, ok := store.buckets[bucket][key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// Put store a copy of value
+func (store *MemoryKVStore) Put(bucket, key string, value []byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.buckets == nil {
+		store.buckets = make(map[string]map[string][]byte)
+	}
+	if store.buckets[bucket] == nil {
+		store.buckets[bucket] = make(map[string][]byte)
+	}
+	store.buckets[bucket][key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete remove key
+func (store *MemoryKVStore) Delete(bucket, key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.buckets[bucket], key)
+	return nil
+}
+
+// ForEach call fn with the keys sorted, fn may use the store
+func (store *MemoryKVStore) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	/* 复制后释放锁,允许fn修改存储 */
+	store.mutex.Lock()
+	keys := make([]string, 0, len(store.buckets[bucket]))
+	values := make(map[string][]byte, len(store.buckets[bucket]))
+	for key, value := range store.buckets[bucket] {
+		keys = append(keys, key)
+		values[key] = append([]byte(nil), value...)
+	}
+	store.mutex.Unlock()
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getJSON decode the value of key into v, it reports false when the key is
+// missing
+func getJSON(store KVStore, bucket, key string, v interface{}) (bool, error) {
+	data, err := store.Get(bucket, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func putJSON(store KVStore, bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return store.Put(bucket, key, data)
+}
+
+// KVSubscriptionStore SubscriptionStore keeping the records in the
+// SubscriptionBucket of a KVStore, keyed by device address
+type KVSubscriptionStore struct {
+	Store KVStore
+}
+
+// NewKVSubscriptionStore return a SubscriptionStore backed by store
+func NewKVSubscriptionStore(store KVStore) *KVSubscriptionStore {
+	return &KVSubscriptionStore{Store: store}
+}
+
+// LoadSubscriptions read every record
+func (store *KVSubscriptionStore) LoadSubscriptions() ([]SubscriptionRecord, error) {
+	var records []SubscriptionRecord
+	err := store.Store.ForEach(SubscriptionBucket, func(key string, value []byte) error {
+		record := SubscriptionRecord{}
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+// SaveSubscription insert or replace the record of the device
+func (store *KVSubscriptionStore) SaveSubscription(record SubscriptionRecord) error {
+	return putJSON(store.Store, SubscriptionBucket, record.Device, record)
+}
+
+// DeleteSubscription remove the record of the device
+func (store *KVSubscriptionStore) DeleteSubscription(device string) error {
+	return store.Store.Delete(SubscriptionBucket, device)
+}
@@ -0,0 +1,212 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/types/ptz"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ErrFixedProfile returned when an operation would delete or modify a profile
+// the device marks as fixed
+var ErrFixedProfile = errors.New("media profile is fixed")
+
+// FixedProfileError lists the fixed profiles an operation targeted and the
+// configurations bound to them
+type FixedProfileError struct {
+	Profiles       []string
+	Configurations []string
+}
+
+func (err *FixedProfileError) Error() string {
+	if len(err.Configurations) == 0 {
+		return fmt.Sprintf("fixed media profiles %v cannot be deleted or modified", err.Profiles)
+	}
+	return fmt.Sprintf("fixed media profiles %v (configurations %v) cannot be deleted or modified", err.Profiles, err.Configurations)
+}
+
+// Is report the error as ErrFixedProfile
+func (err *FixedProfileError) Is(target error) bool {
+	return target == ErrFixedProfile
+}
+
+// Media configuration kinds accepted by AddProfileConfiguration and
+// RemoveProfileConfiguration
+const (
+	ConfigVideoSource    = "VideoSource"
+	ConfigVideoEncoder   = "VideoEncoder"
+	ConfigAudioSource    = "AudioSource"
+	ConfigAudioEncoder   = "AudioEncoder"
+	ConfigPTZ            = "PTZ"
+	ConfigVideoAnalytics = "VideoAnalytics"
+	ConfigMetadata       = "Metadata"
+)
+
+// profileConfigurations return the tokens of the configurations bound to the profile
+func profileConfigurations(profile onvif.Profile) []string {
+	var tokens []string
+	for _, token := range []onvif.ReferenceToken{
+		profile.VideoSourceConfiguration.Token,
+		profile.AudioSourceConfiguration.Token,
+		profile.VideoEncoderConfiguration.Token,
+		profile.AudioEncoderConfiguration.Token,
+		profile.VideoAnalyticsConfiguration.Token,
+		profile.PTZConfiguration.Token,
+		profile.MetadataConfiguration.Token,
+	} {
+		if token != "" {
+			tokens = append(tokens, string(token))
+		}
+	}
+	return tokens
+}
+
+// checkFixedProfiles return a FixedProfileError when one of the profiles is fixed
+func (dev *Device) checkFixedProfiles(ctx context.Context, tokens []string) error {
+	profiles := media.GetProfilesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media.GetProfiles{}, &profiles, ""); err != nil {
+		return err
+	}
+	fixed := &FixedProfileError{}
+	for _, profile := range profiles.Profiles {
+		if profile.Fixed && containsString(tokens, string(profile.Token)) {
+			fixed.Profiles = append(fixed.Profiles, string(profile.Token))
+			fixed.Configurations = append(fixed.Configurations, profileConfigurations(profile)...)
+		}
+	}
+	if len(fixed.Profiles) > 0 {
+		return fixed
+	}
+	return nil
+}
+
+// DeleteProfiles delete the media profiles, nothing is deleted and a
+// FixedProfileError is returned when one of them is fixed
+func (dev *Device) DeleteProfiles(ctx context.Context, tokens ...string) error {
+	if err := dev.checkFixedProfiles(ctx, tokens); err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		request := media.DeleteProfile{ProfileToken: onvif.ReferenceToken(token)}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &media.DeleteProfileResponse{}, ""); err != nil {
+			return fmt.Errorf("delete profile %s: %w", token, err)
+		}
+	}
+	return nil
+}
+
+// AddProfileConfiguration bind the configuration of the given kind to the
+// profile, a FixedProfileError is returned when the profile is fixed
+func (dev *Device) AddProfileConfiguration(ctx context.Context, profile, kind, configuration string) error {
+	if err := dev.checkFixedProfiles(ctx, []string{profile}); err != nil {
+		return err
+	}
+	profileToken := onvif.ReferenceToken(profile)
+	configurationToken := onvif.ReferenceToken(configuration)
+	var request, response interface{}
+	switch kind {
+	case ConfigVideoSource:
+		request = media.AddVideoSourceConfiguration{ProfileToken: profileToken, ConfigurationToken: configurationToken}
+		response = &media.AddVideoSourceConfigurationResponse{}
+	case ConfigVideoEncoder:
+		request = media.AddVideoEncoderConfiguration{ProfileToken: profileToken, ConfigurationToken: configurationToken}
+		response = &media.AddVideoEncoderConfigurationResponse{}
+	case ConfigAudioSource:
+		request = media.AddAudioSourceConfiguration{ProfileToken: profileToken, ConfigurationToken: configurationToken}
+		response = &media.AddAudioSourceConfigurationResponse{}
+	case ConfigAudioEncoder:
+		request = media.AddAudioEncoderConfiguration{ProfileToken: profileToken, ConfigurationToken: configurationToken}
+		response = &media.AddAudioEncoderConfigurationResponse{}
+	case ConfigPTZ:
+		request = media.AddPTZConfiguration{ProfileToken: profileToken, ConfigurationToken: configurationToken}
+		response = &media.AddPTZConfigurationResponse{}
+	case ConfigVideoAnalytics:
+		request = media.AddVideoAnalyticsConfiguration{ProfileToken: profileToken, ConfigurationToken: configurationToken}
+		response = &media.AddVideoAnalyticsConfigurationResponse{}
+	case ConfigMetadata:
+		request = media.AddMetadataConfiguration{ProfileToken: profileToken, ConfigurationToken: configurationToken}
+		response = &media.AddMetadataConfigurationResponse{}
+	default:
+		return fmt.Errorf("unknown configuration kind %q", kind)
+	}
+	return dev.CallMethodInterfaceContext(ctx, request, response, "")
+}
+
+// RemoveProfileConfiguration unbind the configuration of the given kind from
+// the profile, a FixedProfileError is returned when the profile is fixed
+func (dev *Device) RemoveProfileConfiguration(ctx context.Context, profile, kind string) error {
+	if err := dev.checkFixedProfiles(ctx, []string{profile}); err != nil {
+		return err
+	}
+	profileToken := onvif.ReferenceToken(profile)
+	var request, response interface{}
+	switch kind {
+	case ConfigVideoSource:
+		request = media.RemoveVideoSourceConfiguration{ProfileToken: profileToken}
+		response = &media.RemoveVideoSourceConfigurationResponse{}
+	case ConfigVideoEncoder:
+		request = media.RemoveVideoEncoderConfiguration{ProfileToken: profileToken}
+		response = &media.RemoveVideoEncoderConfigurationResponse{}
+	case ConfigAudioSource:
+		request = media.RemoveAudioSourceConfiguration{ProfileToken: profileToken}
+		response = &media.RemoveAudioSourceConfigurationResponse{}
+	case ConfigAudioEncoder:
+		request = media.RemoveAudioEncoderConfiguration{ProfileToken: profileToken}
+		response = &media.RemoveAudioEncoderConfigurationResponse{}
+	case ConfigPTZ:
+		request = media.RemovePTZConfiguration{ProfileToken: profileToken}
+		response = &media.RemovePTZConfigurationResponse{}
+	case ConfigVideoAnalytics:
+		request = media.RemoveVideoAnalyticsConfiguration{ProfileToken: profileToken}
+		response = &media.RemoveVideoAnalyticsConfigurationResponse{}
+	case ConfigMetadata:
+		request = media.RemoveMetadataConfiguration{ProfileToken: profileToken}
+		response = &media.RemoveMetadataConfigurationResponse{}
+	default:
+		return fmt.Errorf("unknown configuration kind %q", kind)
+	}
+	return dev.CallMethodInterfaceContext(ctx, request, response, "")
+}
+
+// CompatibleVideoEncoderConfigurations return the video encoder configurations
+// which can be added to the profile
+func (dev *Device) CompatibleVideoEncoderConfigurations(ctx context.Context, profile string) ([]onvif.VideoEncoderConfiguration, error) {
+	response := media.GetCompatibleVideoEncoderConfigurationsResponse{}
+	request := media.GetCompatibleVideoEncoderConfigurations{ProfileToken: onvif.ReferenceToken(profile)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return nil, err
+	}
+	return response.Configurations, nil
+}
+
+// CompatibleAudioEncoderConfigurations return the audio encoder configurations
+// which can be added to the profile
+func (dev *Device) CompatibleAudioEncoderConfigurations(ctx context.Context, profile string) ([]onvif.AudioEncoderConfiguration, error) {
+	response := media.GetCompatibleAudioEncoderConfigurationsResponse{}
+	request := media.GetCompatibleAudioEncoderConfigurations{ProfileToken: onvif.ReferenceToken(profile)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return nil, err
+	}
+	return response.Configurations, nil
+}
+
+// CompatiblePTZConfigurations return the PTZ configurations which can be
+// added to the profile, the call is skipped with a NotSupportedError when the
+// PTZ service does not advertise GetCompatibleConfigurations
+func (dev *Device) CompatiblePTZConfigurations(ctx context.Context, profile string) ([]onvif.PTZConfiguration, error) {
+	if _, err := dev.getEndpoint("ptz"); err != nil {
+		return nil, &NotSupportedError{Service: "ptz"}
+	}
+	if capabilities, _ := dev.ServiceCapabilities(ctx); capabilities.PTZ != nil && !bool(capabilities.PTZ.GetCompatibleConfigurations) {
+		return nil, &NotSupportedError{Service: "ptz", Capability: "GetCompatibleConfigurations"}
+	}
+	response := ptz.GetCompatibleConfigurationsResponse{}
+	request := ptz.GetCompatibleConfigurations{ProfileToken: onvif.ReferenceToken(profile)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return nil, err
+	}
+	return response.PTZConfiguration, nil
+}
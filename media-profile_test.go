@@ -0,0 +1,95 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const fixedProfiles = `<trt:GetProfilesResponse>` +
+	`<trt:Profiles token="main" fixed="true"><tt:Name>main</tt:Name><tt:VideoSourceConfiguration token="source_1"><tt:Name>source_1</tt:Name></tt:VideoSourceConfiguration><tt:VideoEncoderConfiguration token="encoder_1"><tt:Name>encoder_1</tt:Name></tt:VideoEncoderConfiguration></trt:Profiles>` +
+	`<trt:Profiles token="user"><tt:Name>user</tt:Name></trt:Profiles>` +
+	`</trt:GetProfilesResponse>`
+
+func TestFixedProfiles(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetProfiles":                  fixedProfiles,
+		"DeleteProfile":                `<trt:DeleteProfileResponse/>`,
+		"AddVideoEncoderConfiguration": `<trt:AddVideoEncoderConfigurationResponse/>`,
+	}, ServiceMedia)
+	ctx := context.Background()
+	/* 包含固定配置文件时一个都不删除 */
+	err := dev.DeleteProfiles(ctx, "user", "main")
+	var fixed *FixedProfileError
+	if !errors.Is(err, ErrFixedProfile) || !errors.As(err, &fixed) {
+		t.Fatalf("error %v", err)
+	}
+	if strings.Join(fixed.Profiles, ",") != "main" || strings.Join(fixed.Configurations, ",") != "source_1,encoder_1" {
+		t.Fatalf("fixed %+v", fixed)
+	}
+	if len(fake.sent("DeleteProfile")) != 0 {
+		t.Fatal("profile deleted")
+	}
+	if err := dev.AddProfileConfiguration(ctx, "main", ConfigVideoEncoder, "encoder_2"); !errors.Is(err, ErrFixedProfile) {
+		t.Fatalf("error %v modifying a fixed profile", err)
+	}
+
+	if err := dev.DeleteProfiles(ctx, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.AddProfileConfiguration(ctx, "user", ConfigVideoEncoder, "encoder_2"); err != nil {
+		t.Fatal(err)
+	}
+	if deleted := fake.sent("DeleteProfile"); len(deleted) != 1 || !strings.Contains(deleted[0], "<trt:ProfileToken>user</trt:ProfileToken>") {
+		t.Fatalf("delete requests %q", deleted)
+	}
+	if added := fake.sent("AddVideoEncoderConfiguration"); len(added) != 1 || !strings.Contains(added[0], "<trt:ConfigurationToken>encoder_2</trt:ConfigurationToken>") {
+		t.Fatalf("add requests %q", added)
+	}
+	if err := dev.RemoveProfileConfiguration(ctx, "user", "Unknown"); err == nil {
+		t.Fatal("unknown configuration kind accepted")
+	}
+}
+
+func TestCompatibleConfigurations(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetCompatibleVideoEncoderConfigurations": `<trt:GetCompatibleVideoEncoderConfigurationsResponse>` +
+			`<trt:Configurations token="encoder_1"><tt:Name>encoder_1</tt:Name><tt:Encoding>H264</tt:Encoding></trt:Configurations>` +
+			`<trt:Configurations token="encoder_2"><tt:Name>encoder_2</tt:Name><tt:Encoding>H265</tt:Encoding></trt:Configurations>` +
+			`</trt:GetCompatibleVideoEncoderConfigurationsResponse>`,
+		"GetCompatibleAudioEncoderConfigurations": `<trt:GetCompatibleAudioEncoderConfigurationsResponse/>`,
+		"GetServiceCapabilities":                  `<tptz:GetServiceCapabilitiesResponse><tptz:Capabilities GetCompatibleConfigurations="false"/></tptz:GetServiceCapabilitiesResponse>`,
+	}, ServiceMedia, ServicePTZ)
+	ctx := context.Background()
+	encoders, err := dev.CompatibleVideoEncoderConfigurations(ctx, "main")
+	if err != nil || len(encoders) != 2 || encoders[1].Token != "encoder_2" || encoders[1].Encoding != "H265" {
+		t.Fatalf("video encoders %+v, %v", encoders, err)
+	}
+	if requests := fake.sent("GetCompatibleVideoEncoderConfigurations"); len(requests) != 1 || !strings.Contains(requests[0], "<trt:ProfileToken>main</trt:ProfileToken>") {
+		t.Fatalf("requests %q", requests)
+	}
+	if audio, err := dev.CompatibleAudioEncoderConfigurations(ctx, "main"); err != nil || len(audio) != 0 {
+		t.Fatalf("audio encoders %+v, %v", audio, err)
+	}
+	/* PTZ服务未声明GetCompatibleConfigurations时不发送请求 */
+	var unsupported *NotSupportedError
+	if _, err := dev.CompatiblePTZConfigurations(ctx, "main"); !errors.As(err, &unsupported) || unsupported.Capability != "GetCompatibleConfigurations" {
+		t.Fatalf("error %v", err)
+	}
+	if len(fake.sent("GetCompatibleConfigurations")) != 0 {
+		t.Fatal("compatible PTZ configurations requested")
+	}
+
+	fake, dev = newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities":      `<tptz:GetServiceCapabilitiesResponse><tptz:Capabilities GetCompatibleConfigurations="true"/></tptz:GetServiceCapabilitiesResponse>`,
+		"GetCompatibleConfigurations": `<tptz:GetCompatibleConfigurationsResponse><tptz:PTZConfiguration token="ptz_1"><tt:Name>ptz_1</tt:Name></tptz:PTZConfiguration></tptz:GetCompatibleConfigurationsResponse>`,
+	}, ServicePTZ)
+	configurations, err := dev.CompatiblePTZConfigurations(ctx, "main")
+	if err != nil || len(configurations) != 1 || configurations[0].Token != "ptz_1" {
+		t.Fatalf("PTZ configurations %+v, %v", configurations, err)
+	}
+	if requests := fake.sent("GetCompatibleConfigurations"); len(requests) != 1 || !strings.HasPrefix(requests[0], "/onvif/"+ServicePTZ+" ") {
+		t.Fatalf("requests %q", requests)
+	}
+}
@@ -0,0 +1,251 @@
+package onvif
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrSignatureInvalid returned when a media signature does not verify
+var ErrSignatureInvalid = errors.New("media signature invalid")
+
+// ErrNoSigningCertificate returned when no certificate is assigned to the
+// media signing of the device
+var ErrNoSigningCertificate = errors.New("no media signing certificate assigned")
+
+// SigningCertificatePath certification path assigned to the media signing of
+// a device, see Device.MediaSigningCertificates
+type SigningCertificatePath struct {
+	// ID certification path ID of the advanced security keystore
+	ID    string
+	Alias string
+	// Certificates of the path, the signing certificate first
+	Certificates []*x509.Certificate
+}
+
+// requireMediaSigning check the MediaSigningSupported capability of the
+// advanced security service
+func (dev *Device) requireMediaSigning(ctx context.Context) error {
+	supported, err := dev.MediaSigningSupported(ctx)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return &NotSupportedError{Service: ServiceAdvancedSecurity, Capability: "MediaSigning"}
+	}
+	return nil
+}
+
+// MediaSigningSupported report whether the device signs its media streams,
+// from the MediaSigningCapabilities of the advanced security service. A
+// NotSupportedError is returned when the device has no advanced security
+// service.
+func (dev *Device) MediaSigningSupported(ctx context.Context) (bool, error) {
+	if _, err := dev.getEndpoint(ServiceAdvancedSecurity); err != nil {
+		return false, &NotSupportedError{Service: ServiceAdvancedSecurity}
+	}
+	capabilities, err := rawElements(ctx, dev, ServiceAdvancedSecurity, `<tas:GetServiceCapabilities xmlns:tas="`+Xlmns["tas"]+`"/>`,
+		"MediaSigningCapabilities", xml.Name{})
+	if err != nil || len(capabilities) == 0 {
+		return false, err
+	}
+	supported, _ := logicalState(elementAttr(capabilities[0], "MediaSigningSupported"))
+	return supported, nil
+}
+
+// MediaSigningCertificates return the certification paths assigned to the
+// media signing of the device, with their certificates
+func (dev *Device) MediaSigningCertificates(ctx context.Context) ([]SigningCertificatePath, error) {
+	if err := dev.requireMediaSigning(ctx); err != nil {
+		return nil, err
+	}
+	body, err := dev.CallRaw(ctx, ServiceAdvancedSecurity, `<tas:GetAssignedMediaSigningCertificates xmlns:tas="`+Xlmns["tas"]+`"/>`, nil)
+	if err != nil {
+		return nil, err
+	}
+	assigned := struct {
+		CertificationPathID []string `xml:"CertificationPathID"`
+	}{}
+	if err := xml.Unmarshal(body, &assigned); err != nil {
+		return nil, err
+	}
+	paths := make([]SigningCertificatePath, 0, len(assigned.CertificationPathID))
+	for _, id := range assigned.CertificationPathID {
+		path, err := dev.signingCertificatePath(ctx, strings.TrimSpace(id))
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// signingCertificatePath read the certification path of id and its
+// certificates from the keystore
+func (dev *Device) signingCertificatePath(ctx context.Context, id string) (SigningCertificatePath, error) {
+	path := SigningCertificatePath{ID: id}
+	body, err := dev.CallRaw(ctx, ServiceAdvancedSecurity, `<tas:GetCertificationPath xmlns:tas="`+Xlmns["tas"]+`">`+
+		`<tas:CertificationPathID>{{.ID}}</tas:CertificationPathID></tas:GetCertificationPath>`, map[string]string{"ID": id})
+	if err != nil {
+		return path, err
+	}
+	certificationPath := struct {
+		CertificateID []string `xml:"CertificationPath>CertificateID"`
+		Alias         string   `xml:"CertificationPath>Alias"`
+	}{}
+	if err := xml.Unmarshal(body, &certificationPath); err != nil {
+		return path, err
+	}
+	path.Alias = certificationPath.Alias
+	for _, certificateID := range certificationPath.CertificateID {
+		body, err := dev.CallRaw(ctx, ServiceAdvancedSecurity, `<tas:GetCertificate xmlns:tas="`+Xlmns["tas"]+`">`+
+			`<tas:CertificateID>{{.ID}}</tas:CertificateID></tas:GetCertificate>`, map[string]string{"ID": strings.TrimSpace(certificateID)})
+		if err != nil {
+			return path, err
+		}
+		certificate := struct {
+			Content string `xml:"Certificate>CertificateContent"`
+		}{}
+		if err := xml.Unmarshal(body, &certificate); err != nil {
+			return path, err
+		}
+		/* 部分设备在base64中插入换行 */
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(certificate.Content), ""))
+		if err != nil {
+			return path, fmt.Errorf("certificate %s: %w", certificateID, err)
+		}
+		parsed, err := x509.ParseCertificate(der)
+		if err != nil {
+			return path, fmt.Errorf("certificate %s: %w", certificateID, err)
+		}
+		path.Certificates = append(path.Certificates, parsed)
+	}
+	return path, nil
+}
+
+// AssignMediaSigningCertificate assign the certification path of the keystore
+// to the media signing of the device, the device signing its streams with
+// the key of the first certificate of the path from then on
+func (dev *Device) AssignMediaSigningCertificate(ctx context.Context, certificationPathID string) error {
+	if err := dev.requireMediaSigning(ctx); err != nil {
+		return err
+	}
+	_, err := dev.CallRaw(ctx, ServiceAdvancedSecurity, `<tas:AddMediaSigningCertificateAssignment xmlns:tas="`+Xlmns["tas"]+`">`+
+		`<tas:CertificationPathID>{{.ID}}</tas:CertificationPathID></tas:AddMediaSigningCertificateAssignment>`, map[string]string{"ID": certificationPathID})
+	return err
+}
+
+// UnassignMediaSigningCertificate remove the assignment of the certification
+// path to the media signing of the device
+func (dev *Device) UnassignMediaSigningCertificate(ctx context.Context, certificationPathID string) error {
+	if err := dev.requireMediaSigning(ctx); err != nil {
+		return err
+	}
+	_, err := dev.CallRaw(ctx, ServiceAdvancedSecurity, `<tas:RemoveMediaSigningCertificateAssignment xmlns:tas="`+Xlmns["tas"]+`">`+
+		`<tas:CertificationPathID>{{.ID}}</tas:CertificationPathID></tas:RemoveMediaSigningCertificateAssignment>`, map[string]string{"ID": certificationPathID})
+	return err
+}
+
+// MediaSignatureVerifier verify the signatures carried by the signed streams
+// of a device, e.g. the SEI signatures of H.264 and H.265, for the evidence
+// integrity pipelines. Extracting the signatures and computing the digests
+// of the stream is left to the caller; the verifier checks them against the
+// signing certificate of the device.
+type MediaSignatureVerifier struct {
+	Path SigningCertificatePath
+	// Roots trusted to issue the signing certificate, nil trusting the
+	// certificate as pinned, e.g. read from the device when it was commissioned
+	Roots *x509.CertPool
+}
+
+// MediaSignatureVerifier return a verifier of the signatures of dev, for its
+// first assigned signing certificate
+func (dev *Device) MediaSignatureVerifier(ctx context.Context, roots *x509.CertPool) (*MediaSignatureVerifier, error) {
+	paths, err := dev.MediaSigningCertificates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 || len(paths[0].Certificates) == 0 {
+		return nil, ErrNoSigningCertificate
+	}
+	return &MediaSignatureVerifier{Path: paths[0], Roots: roots}, nil
+}
+
+// VerifyChain check the signing certificate was valid at the time the media
+// was signed and, with Roots, was issued by one of them through the path
+func (verifier *MediaSignatureVerifier) VerifyChain(at time.Time) error {
+	if len(verifier.Path.Certificates) == 0 {
+		return ErrNoSigningCertificate
+	}
+	leaf := verifier.Path.Certificates[0]
+	if verifier.Roots == nil {
+		if at.Before(leaf.NotBefore) || at.After(leaf.NotAfter) {
+			return fmt.Errorf("signing certificate not valid at %s", at.Format(time.RFC3339))
+		}
+		return nil
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range verifier.Path.Certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         verifier.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// Verify check signature is the signature of digest by the signing
+// certificate, see VerifyMediaSignature
+func (verifier *MediaSignatureVerifier) Verify(hash crypto.Hash, digest, signature []byte) error {
+	if len(verifier.Path.Certificates) == 0 {
+		return ErrNoSigningCertificate
+	}
+	return VerifyMediaSignature(verifier.Path.Certificates[0], hash, digest, signature)
+}
+
+// VerifyMediaSignature check signature is the signature of digest, computed
+// with hash, by the key of cert: ASN.1 ECDSA, RSA PKCS #1 v1.5 or PSS, or
+// Ed25519 over the digest. ErrSignatureInvalid is returned when it is not.
+func VerifyMediaSignature(cert *x509.Certificate, hash crypto.Hash, digest, signature []byte) error {
+	if hash.Available() && len(digest) != hash.Size() {
+		return fmt.Errorf("digest of %d bytes for %s", len(digest), hash)
+	}
+	valid := false
+	switch key := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest, signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil ||
+			rsa.VerifyPSS(key, hash, digest, signature, nil) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, digest, signature)
+	default:
+		return fmt.Errorf("unsupported signing key %T", cert.PublicKey)
+	}
+	if !valid {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// MediaDigest return the digest of the parts of the media, e.g. the NAL
+// units covered by a signature, hashed in order with hash
+func MediaDigest(hash crypto.Hash, parts ...[]byte) []byte {
+	h := hash.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
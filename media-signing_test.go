@@ -0,0 +1,133 @@
+package onvif
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* 自签名的媒体签名证书及其私钥 */
+func signingCertificate(t *testing.T, notBefore time.Time) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "camera signing"},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, der
+}
+
+func TestMediaSigning(t *testing.T) {
+	notBefore := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	key, der := signingCertificate(t, notBefore)
+	encoded := base64.StdEncoding.EncodeToString(der)
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tas:GetServiceCapabilitiesResponse><tas:Capabilities><tas:KeystoreCapabilities MaximumNumberOfKeys="8"/>` +
+			`<tas:MediaSigningCapabilities MediaSigningSupported="true"/></tas:Capabilities></tas:GetServiceCapabilitiesResponse>`,
+		"GetAssignedMediaSigningCertificates": `<tas:GetAssignedMediaSigningCertificatesResponse><tas:CertificationPathID> path_1 </tas:CertificationPathID></tas:GetAssignedMediaSigningCertificatesResponse>`,
+		"GetCertificationPath": `<tas:GetCertificationPathResponse><tas:CertificationPath><tas:CertificateID>cert_1</tas:CertificateID>` +
+			`<tas:Alias>signing</tas:Alias></tas:CertificationPath></tas:GetCertificationPathResponse>`,
+		/* 部分设备在base64中插入换行 */
+		"GetCertificate": `<tas:GetCertificateResponse><tas:Certificate><tas:CertificateID>cert_1</tas:CertificateID>` +
+			`<tas:CertificateContent>` + encoded[:64] + "\n" + encoded[64:] + `</tas:CertificateContent></tas:Certificate></tas:GetCertificateResponse>`,
+		"AddMediaSigningCertificateAssignment": `<tas:AddMediaSigningCertificateAssignmentResponse/>`,
+	}, ServiceAdvancedSecurity)
+	ctx := context.Background()
+	if supported, err := dev.MediaSigningSupported(ctx); err != nil || !supported {
+		t.Fatalf("supported %v, %v", supported, err)
+	}
+	paths, err := dev.MediaSigningCertificates(ctx)
+	if err != nil || len(paths) != 1 || paths[0].ID != "path_1" || paths[0].Alias != "signing" || len(paths[0].Certificates) != 1 ||
+		paths[0].Certificates[0].Subject.CommonName != "camera signing" {
+		t.Fatalf("paths %+v, %v", paths, err)
+	}
+	if sent := fake.sent("GetCertificationPath"); len(sent) != 1 || !strings.Contains(sent[0], "<tas:CertificationPathID>path_1</tas:CertificationPathID>") {
+		t.Fatalf("requests %q", sent)
+	}
+	if err := dev.AssignMediaSigningCertificate(ctx, "path_2"); err != nil {
+		t.Fatal(err)
+	}
+	if sent := fake.sent("AddMediaSigningCertificateAssignment"); len(sent) != 1 || !strings.Contains(sent[0], "<tas:CertificationPathID>path_2</tas:CertificationPathID>") {
+		t.Fatalf("requests %q", sent)
+	}
+
+	verifier, err := dev.MediaSignatureVerifier(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	/* 固定的证书只检查有效期 */
+	if err := verifier.VerifyChain(notBefore.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.VerifyChain(notBefore.Add(-time.Hour)); err == nil {
+		t.Fatal("certificate valid before its validity")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(paths[0].Certificates[0])
+	verifier.Roots = roots
+	if err := verifier.VerifyChain(notBefore.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	verifier.Roots = x509.NewCertPool()
+	if err := verifier.VerifyChain(notBefore.Add(time.Hour)); err == nil {
+		t.Fatal("certificate verified without its root")
+	}
+
+	digest := MediaDigest(crypto.SHA256, []byte("\x00\x00\x01\x65"), []byte("frame"))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.Verify(crypto.SHA256, digest, signature); err != nil {
+		t.Fatal(err)
+	}
+	tampered := MediaDigest(crypto.SHA256, []byte("\x00\x00\x01\x65"), []byte("frame!"))
+	if err := verifier.Verify(crypto.SHA256, tampered, signature); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("error %v for a tampered frame", err)
+	}
+	if err := verifier.Verify(crypto.SHA256, digest[:16], signature); err == nil || errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("error %v for a short digest", err)
+	}
+	if err := (&MediaSignatureVerifier{}).Verify(crypto.SHA256, digest, signature); !errors.Is(err, ErrNoSigningCertificate) {
+		t.Fatalf("error %v without certificate", err)
+	}
+}
+
+func TestMediaSigningNotSupported(t *testing.T) {
+	ctx := context.Background()
+	var unsupported *NotSupportedError
+	_, dev := newScriptedDevice(t, nil)
+	if _, err := dev.MediaSigningCertificates(ctx); !errors.As(err, &unsupported) || unsupported.Service != ServiceAdvancedSecurity {
+		t.Fatalf("error %v without advanced security service", err)
+	}
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tas:GetServiceCapabilitiesResponse><tas:Capabilities><tas:MediaSigningCapabilities MediaSigningSupported="false"/></tas:Capabilities></tas:GetServiceCapabilitiesResponse>`,
+	}, ServiceAdvancedSecurity)
+	if err := dev.AssignMediaSigningCertificate(ctx, "path_1"); !errors.As(err, &unsupported) || unsupported.Capability != "MediaSigning" {
+		t.Fatalf("error %v", err)
+	}
+	if len(fake.sent("AddMediaSigningCertificateAssignment")) != 0 {
+		t.Fatal("assignment requested without media signing")
+	}
+}
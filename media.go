@@ -0,0 +1,175 @@
+package onvif
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/media"
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/types/media2"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// StreamProtocol transport of the stream URIs returned by GetStreamURI
+type StreamProtocol string
+
+// Stream protocols
+const (
+	// StreamRTSP unicast RTP over RTSP, the protocol every device supports
+	StreamRTSP StreamProtocol = "RTSP"
+	// StreamRTSPOverHTTP unicast RTP over RTSP tunnelled in HTTP
+	StreamRTSPOverHTTP StreamProtocol = "HTTP"
+	// StreamUDP unicast RTP over UDP
+	StreamUDP StreamProtocol = "UDP"
+	// StreamMulticast multicast RTP over UDP
+	StreamMulticast StreamProtocol = "Multicast"
+)
+
+// streamSetup return the StreamSetup of GetStreamUri for the protocol
+func (protocol StreamProtocol) streamSetup() device.StreamSetup {
+	if protocol == StreamMulticast {
+		return device.StreamSetup{Stream: "RTP-Multicast", Transport: device.Transport{Protocol: "UDP"}}
+	}
+	if protocol == "" {
+		protocol = StreamRTSP
+	}
+	return device.StreamSetup{Stream: "RTP-Unicast", Transport: device.Transport{Protocol: string(protocol)}}
+}
+
+// media2Protocols Protocol of the media2 GetStreamUri of the protocols
+var media2Protocols = map[StreamProtocol]string{
+	StreamRTSP:         "RTSP",
+	StreamRTSPOverHTTP: "RtspOverHttp",
+	StreamUDP:          "RtspUnicast",
+	StreamMulticast:    "RtspMulticast",
+}
+
+// mediaService return the service the media requests of the device are sent
+// to: media, or media2 for a device offering media2 only. The profile tokens
+// of the two services may differ, media is kept whenever it is offered.
+func (dev *Device) mediaService() string {
+	/* 不能用getEndpoint: 模糊匹配会把media2当作media */
+	if dev.hasEndpoint(ServiceMedia) {
+		return ServiceMedia
+	}
+	/* media2只由GetServices报告 */
+	if dev.ensureServices(context.Background()) && dev.hasEndpoint(ServiceMedia2) {
+		return ServiceMedia2
+	}
+	return ServiceMedia
+}
+
+// hasEndpoint report whether the endpoint of key is known, without the fuzzy
+// match of getEndpoint
+func (dev *Device) hasEndpoint(key string) bool {
+	defer dev.readEndpoints()()
+	_, ok := dev.endpoints[key]
+	return ok
+}
+
+// GetProfiles return the media profiles of the device, for a device offering
+// media2 only the token, name and video encoder of its media2 profiles
+func (dev *Device) GetProfiles(ctx context.Context) ([]onvif.Profile, error) {
+	if dev.mediaService() == ServiceMedia2 {
+		return dev.media2Profiles(ctx)
+	}
+	return media.New(dev).GetProfiles(ctx)
+}
+
+// CachedProfiles return the media profiles of the device, read once and
+// cached until InvalidateCache or a configuration change reported by the
+// events of the device pulled by an EventEngine
+func (dev *Device) CachedProfiles(ctx context.Context) ([]onvif.Profile, error) {
+	cache := dev.capabilities
+	if cache == nil {
+		return dev.GetProfiles(ctx)
+	}
+	cache.profilesMutex.Lock()
+	defer cache.profilesMutex.Unlock()
+	if cache.profilesLoaded {
+		return cache.profiles, nil
+	}
+	profiles, err := dev.GetProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cache.profiles, cache.profilesLoaded = profiles, true
+	return profiles, nil
+}
+
+// GetStreamURI return the stream URI of the profile for protocol, StreamRTSP
+// when empty, its host rewritten to the address the device is reached at
+func (dev *Device) GetStreamURI(ctx context.Context, profile string, protocol StreamProtocol) (string, error) {
+	if dev.mediaService() == ServiceMedia2 {
+		if protocol == "" {
+			protocol = StreamRTSP
+		}
+		response := media2.GetStreamUriResponse{}
+		request := media2.GetStreamUri{Protocol: media2Protocols[protocol], ProfileToken: onvif.ReferenceToken(profile)}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			return "", err
+		}
+		return dev.mediaURI(response.Uri, ErrNoStreamURI)
+	}
+	mediaURI, err := media.New(dev).GetStreamUri(ctx, profile, protocol.streamSetup())
+	if err != nil {
+		return "", err
+	}
+	uri := dev.RewriteHost(string(mediaURI.Uri))
+	if uri == "" {
+		return "", ErrNoStreamURI
+	}
+	return uri, nil
+}
+
+// GetSnapshotURI return the JPEG snapshot URI of the profile, its host
+// rewritten to the address the device is reached at
+func (dev *Device) GetSnapshotURI(ctx context.Context, profile string) (string, error) {
+	if dev.mediaService() == ServiceMedia2 {
+		response := media2.GetSnapshotUriResponse{}
+		request := media2.GetSnapshotUri{ProfileToken: onvif.ReferenceToken(profile)}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			return "", err
+		}
+		return dev.mediaURI(response.Uri, ErrNoSnapshotURI)
+	}
+	mediaURI, err := media.New(dev).GetSnapshotUri(ctx, profile)
+	if err != nil {
+		return "", err
+	}
+	uri := dev.RewriteHost(string(mediaURI.Uri))
+	if uri == "" {
+		return "", ErrNoSnapshotURI
+	}
+	return uri, nil
+}
+
+// mediaURI rewrite the host of a URI of a media2 response, missing when empty
+func (dev *Device) mediaURI(uri string, missing error) (string, error) {
+	uri = dev.RewriteHost(strings.TrimSpace(uri))
+	if uri == "" {
+		return "", missing
+	}
+	return uri, nil
+}
+
+// media2Profiles return the media2 profiles as media profiles
+func (dev *Device) media2Profiles(ctx context.Context) ([]onvif.Profile, error) {
+	response := media2.GetProfilesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media2.GetProfiles{Type: []string{"VideoEncoder"}}, &response, ""); err != nil {
+		return nil, err
+	}
+	profiles := make([]onvif.Profile, 0, len(response.Profiles))
+	for _, profile := range response.Profiles {
+		converted := onvif.Profile{Token: profile.Token, Fixed: profile.Fixed, Name: profile.Name}
+		if encoder := profile.Configurations.VideoEncoder; encoder != nil {
+			converted.VideoEncoderConfiguration.Token = encoder.Token
+			converted.VideoEncoderConfiguration.Name = encoder.Name
+			converted.VideoEncoderConfiguration.Encoding = onvif.VideoEncoding(encoder.Encoding)
+			converted.VideoEncoderConfiguration.Resolution = encoder.Resolution
+			converted.VideoEncoderConfiguration.Quality = encoder.Quality
+		}
+		profiles = append(profiles, converted)
+	}
+	return profiles, nil
+}
@@ -0,0 +1,69 @@
+// Package media is the client of the ONVIF media service (ver10): profiles,
+// video sources and encoders, stream and snapshot URIs.
+//
+// The client sends its requests through a core.Caller, a core.Client or an
+// onvif.Device. The URIs are returned as the device answers them, see
+// onvif.Device.GetStreamURI to rewrite their host and fall back to media2.
+package media
+
+import (
+	"context"
+
+	"github.com/PolarisM78/go-onvif/core"
+	"github.com/PolarisM78/go-onvif/types/device"
+	trt "github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Client of the media service of a device
+type Client struct {
+	caller core.Caller
+}
+
+// New return the media service client sending its requests through caller
+func New(caller core.Caller) *Client {
+	return &Client{caller: caller}
+}
+
+// GetServiceCapabilities return the capabilities of the media service
+func (client *Client) GetServiceCapabilities(ctx context.Context) (trt.Capabilities, error) {
+	response := trt.GetServiceCapabilitiesResponse{}
+	err := client.caller.Call(ctx, core.ServiceMedia, trt.GetServiceCapabilities{}, &response)
+	return response.Capabilities, err
+}
+
+// GetProfiles return the media profiles of the device
+func (client *Client) GetProfiles(ctx context.Context) ([]onvif.Profile, error) {
+	response := trt.GetProfilesResponse{}
+	err := client.caller.Call(ctx, core.ServiceMedia, trt.GetProfiles{}, &response)
+	return response.Profiles, err
+}
+
+// GetProfile return the media profile of token
+func (client *Client) GetProfile(ctx context.Context, token string) (onvif.Profile, error) {
+	response := trt.GetProfileResponse{}
+	err := client.caller.Call(ctx, core.ServiceMedia, trt.GetProfile{ProfileToken: onvif.ReferenceToken(token)}, &response)
+	return response.Profile, err
+}
+
+// GetVideoSources return the video sources of the device
+func (client *Client) GetVideoSources(ctx context.Context) ([]onvif.VideoSource, error) {
+	response := trt.GetVideoSourcesResponse{}
+	err := client.caller.Call(ctx, core.ServiceMedia, trt.GetVideoSources{}, &response)
+	return response.VideoSources, err
+}
+
+// GetStreamUri return the stream URI of the profile for setup
+func (client *Client) GetStreamUri(ctx context.Context, profile string, setup device.StreamSetup) (onvif.MediaUri, error) {
+	response := trt.GetStreamUriResponse{}
+	request := trt.GetStreamUri{ProfileToken: onvif.ReferenceToken(profile), StreamSetup: setup}
+	err := client.caller.Call(ctx, core.ServiceMedia, request, &response)
+	return response.MediaUri, err
+}
+
+// GetSnapshotUri return the JPEG snapshot URI of the profile
+func (client *Client) GetSnapshotUri(ctx context.Context, profile string) (onvif.MediaUri, error) {
+	response := trt.GetSnapshotUriResponse{}
+	err := client.caller.Call(ctx, core.ServiceMedia, trt.GetSnapshotUri{ProfileToken: onvif.ReferenceToken(profile)}, &response)
+	return response.MediaUri, err
+}
@@ -0,0 +1,36 @@
+package media
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/core"
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+func TestGetStreamUri(t *testing.T) {
+	var request string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		request = string(body)
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:trt="http://www.onvif.org/ver10/media/wsdl"><s:Body>` +
+			`<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://10.1.1.200/stream1</tt:Uri><tt:Timeout>PT0S</tt:Timeout></trt:MediaUri></trt:GetStreamUriResponse></s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+	client, _ := core.NewClient(core.Params{XAddr: server.URL + "/onvif/device_service"})
+	client.SetEndpoint(core.ServiceMedia, server.URL+"/onvif/media")
+	setup := device.StreamSetup{Stream: "RTP-Unicast", Transport: device.Transport{Protocol: "RTSP"}}
+	uri, err := New(client).GetStreamUri(context.Background(), "profile_1", setup)
+	if err != nil || uri.Uri != "rtsp://10.1.1.200/stream1" {
+		t.Fatalf("uri %+v, %v", uri, err)
+	}
+	for _, want := range []string{"<trt:ProfileToken>profile_1</trt:ProfileToken>", ">RTP-Unicast<", ">RTSP<"} {
+		if !strings.Contains(request, want) {
+			t.Fatalf("request without %q: %s", want, request)
+		}
+	}
+}
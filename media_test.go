@@ -0,0 +1,121 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/media"
+)
+
+/* 只提供media2服务的设备 */
+type media2Device struct {
+	mutex    sync.Mutex
+	requests []string
+}
+
+func (fake *media2Device) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := ioutil.ReadAll(r.Body)
+	request := string(data)
+	fake.mutex.Lock()
+	fake.requests = append(fake.requests, r.URL.Path+" "+request)
+	fake.mutex.Unlock()
+	body := ""
+	switch {
+	case strings.Contains(request, "GetServices"):
+		body = `<tds:GetServicesResponse><tds:Service><tds:Namespace>http://www.onvif.org/ver20/media/wsdl</tds:Namespace>` +
+			`<tds:XAddr>http://` + r.Host + `/onvif/media2</tds:XAddr><tds:Version><tt:Major>19</tt:Major><tt:Minor>6</tt:Minor></tds:Version></tds:Service></tds:GetServicesResponse>`
+	case strings.Contains(request, "GetStreamUri"):
+		body = `<tr2:GetStreamUriResponse><tr2:Uri>rtsp://10.1.1.200/h265</tr2:Uri></tr2:GetStreamUriResponse>`
+	case strings.Contains(request, "GetProfiles"):
+		body = `<tr2:GetProfilesResponse><tr2:Profiles token="p0" fixed="true"><tr2:Name>main</tr2:Name><tr2:Configurations>` +
+			`<tr2:VideoEncoder token="e0"><tt:Name>enc</tt:Name><tt:Encoding>H265</tt:Encoding><tt:Resolution><tt:Width>3840</tt:Width><tt:Height>2160</tt:Height></tt:Resolution></tr2:VideoEncoder>` +
+			`</tr2:Configurations></tr2:Profiles></tr2:GetProfilesResponse>`
+	}
+	w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:tds="http://www.onvif.org/ver10/device/wsdl" xmlns:tr2="http://www.onvif.org/ver20/media/wsdl"><s:Body>` + body + `</s:Body></s:Envelope>`))
+}
+
+func TestMedia2OnlyDevice(t *testing.T) {
+	fake := &media2Device{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://")})
+	dev.endpoints[ServiceDevice] = server.URL + "/onvif/device_service"
+	profiles, err := dev.GetProfiles(context.Background())
+	if err != nil || len(profiles) != 1 || profiles[0].Token != "p0" || profiles[0].VideoEncoderConfiguration.Encoding != "H265" {
+		t.Fatalf("profiles %+v, %v", profiles, err)
+	}
+	uri, err := dev.GetStreamURI(context.Background(), "p0", StreamRTSPOverHTTP)
+	if err != nil || !strings.HasSuffix(uri, "/h265") {
+		t.Fatalf("stream uri %q, %v", uri, err)
+	}
+	if version, ok := dev.ServiceVersion(ServiceMedia2); !ok || !version.AtLeast(19, 6) {
+		t.Fatalf("media2 version %v, %v", version, ok)
+	}
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	last := fake.requests[len(fake.requests)-1]
+	if !strings.HasPrefix(last, "/onvif/media2 ") || !strings.Contains(last, "<tr2:Protocol>RtspOverHttp</tr2:Protocol>") {
+		t.Fatalf("GetStreamUri request %s", last)
+	}
+}
+
+func TestServiceClientThroughDevice(t *testing.T) {
+	dev := cannedDevice(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:trt="http://www.onvif.org/ver10/media/wsdl"><s:Body>` +
+		`<trt:GetProfilesResponse><trt:Profiles token="profile_1" fixed="true"><tt:Name>main</tt:Name></trt:Profiles></trt:GetProfilesResponse></s:Body></s:Envelope>`)
+	profiles, err := media.New(dev).GetProfiles(context.Background())
+	if err != nil || len(profiles) != 1 || profiles[0].Token != "profile_1" {
+		t.Fatalf("profiles %+v, %v", profiles, err)
+	}
+	/* 经Device发送的请求沿用其隔离等策略 */
+	dev.Quarantine("manual")
+	if _, err := media.New(dev).GetProfiles(context.Background()); !errors.Is(err, ErrQuarantined) {
+		t.Fatalf("error %v of a quarantined device", err)
+	}
+}
+
+func TestMediaHelpers(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetProfiles":    `<trt:GetProfilesResponse><trt:Profiles token="main"><tt:Name>main</tt:Name></trt:Profiles></trt:GetProfilesResponse>`,
+		"GetStreamUri":   `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://10.1.1.200:554/main</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`,
+		"GetSnapshotUri": `<trt:GetSnapshotUriResponse><trt:MediaUri><tt:Uri>http://10.1.1.200/snapshot.jpg</tt:Uri></trt:MediaUri></trt:GetSnapshotUriResponse>`,
+	}, ServiceMedia)
+	ctx := context.Background()
+	host, _ := dev.Params.address()
+	profiles, err := dev.GetProfiles(ctx)
+	if err != nil || len(profiles) != 1 || profiles[0].Token != "main" {
+		t.Fatalf("profiles %+v, %v", profiles, err)
+	}
+	/* 设备报告的地址替换为访问设备的地址,端口保留 */
+	uri, err := dev.GetStreamURI(ctx, "main", "")
+	if err != nil || uri != "rtsp://"+host+":554/main" {
+		t.Fatalf("stream uri %q, %v", uri, err)
+	}
+	if _, err := dev.GetStreamURI(ctx, "main", StreamMulticast); err != nil {
+		t.Fatal(err)
+	}
+	requests := fake.sent("GetStreamUri")
+	if len(requests) != 2 || !strings.Contains(requests[0], ">RTP-Unicast</Stream>") || !strings.Contains(requests[0], "<Protocol>RTSP</Protocol>") ||
+		!strings.Contains(requests[1], ">RTP-Multicast</Stream>") || !strings.Contains(requests[1], "<Protocol>UDP</Protocol>") {
+		t.Fatalf("requests %q", requests)
+	}
+	if uri, err := dev.GetSnapshotURI(ctx, "main"); err != nil || uri != "http://"+host+"/snapshot.jpg" {
+		t.Fatalf("snapshot uri %q, %v", uri, err)
+	}
+
+	fake.set("GetStreamUri", `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri></tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`)
+	if _, err := dev.GetStreamURI(ctx, "main", StreamRTSP); !errors.Is(err, ErrNoStreamURI) {
+		t.Fatalf("error %v", err)
+	}
+	/* 故障作为错误返回 */
+	fake.set("GetSnapshotUri", `<s:Fault><s:Code><s:Value>s:Sender</s:Value><s:Subcode><s:Value>ter:InvalidArgVal</s:Value><s:Subcode><s:Value>ter:NoProfile</s:Value></s:Subcode></s:Subcode></s:Code>`+
+		`<s:Reason><s:Text xml:lang="en">no such profile</s:Text></s:Reason></s:Fault>`)
+	if _, err := dev.GetSnapshotURI(ctx, "main"); err == nil || errors.Is(err, ErrNoSnapshotURI) {
+		t.Fatalf("error %v", err)
+	}
+}
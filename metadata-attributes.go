@@ -0,0 +1,274 @@
+package onvif
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// LicensePlate plate read by a license plate recognition analytics
+type LicensePlate struct {
+	Number     string  `json:"number"`
+	Likelihood float64 `json:"likelihood,omitempty"`
+	// Country country code of the plate, e.g. SE or FRA
+	Country string `json:"country,omitempty"`
+	// IssuingEntity state or region issuing the plate
+	IssuingEntity string `json:"issuingEntity,omitempty"`
+	Type          string `json:"type,omitempty"`
+}
+
+// FaceAttributes attributes of a face detected by a face analytics
+type FaceAttributes struct {
+	// Gender Male or Female
+	Gender string `json:"gender,omitempty"`
+	// AgeType Infant, Child, Adult, Middle or Senior
+	AgeType string `json:"ageType,omitempty"`
+	// MinAge and MaxAge estimated age range, 0 when unknown
+	MinAge  int  `json:"minAge,omitempty"`
+	MaxAge  int  `json:"maxAge,omitempty"`
+	Glasses bool `json:"glasses,omitempty"`
+	Hat     bool `json:"hat,omitempty"`
+	Mask    bool `json:"mask,omitempty"`
+}
+
+// Names of the elements and items holding the attributes, lower case, in the
+// tt: schema and the vendor extensions
+var (
+	plateNumberNames   = []string{"platenumber", "licenseplate", "licenseplatenumber", "platetext", "plate", "registration"}
+	plateCountryNames  = []string{"countrycode", "country", "platecountry"}
+	plateIssuerNames   = []string{"issuingentity", "region", "plateregion"}
+	plateTypeNames     = []string{"platetype"}
+	likelihoodNames    = []string{"likelihood", "confidence", "score"}
+	faceGenderNames    = []string{"gender", "sex"}
+	faceAgeTypeNames   = []string{"agetype", "agegroup"}
+	faceAgeNames       = []string{"age"}
+	faceGlassesNames   = []string{"opticals", "glasses", "eyeglasses"}
+	faceHatNames       = []string{"hat", "headwear"}
+	faceMaskNames      = []string{"mask", "facemask"}
+	faceContainerNames = []string{"humanface", "face"}
+)
+
+// xmlLeaf element without child element of a metadata document
+type xmlLeaf struct {
+	// path local names of the ancestors of the element and of the element,
+	// lower case
+	path  []string
+	value string
+	attrs map[string]string
+}
+
+func (leaf xmlLeaf) name() string {
+	return leaf.path[len(leaf.path)-1]
+}
+
+// parent return the local name of the parent of the element
+func (leaf xmlLeaf) parent() string {
+	if len(leaf.path) < 2 {
+		return ""
+	}
+	return leaf.path[len(leaf.path)-2]
+}
+
+func (leaf xmlLeaf) under(names []string) bool {
+	for _, name := range leaf.path[:len(leaf.path)-1] {
+		if containsString(names, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlLeaves list the leaf elements of content, whatever their namespace
+func xmlLeaves(content []byte) []xmlLeaf {
+	var leaves []xmlLeaf
+	var path []string
+	var attrs []map[string]string
+	var text bytes.Buffer
+	leaf := false
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return leaves
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			path = append(path, strings.ToLower(token.Name.Local))
+			values := make(map[string]string, len(token.Attr))
+			for _, attr := range token.Attr {
+				values[strings.ToLower(attr.Name.Local)] = attr.Value
+			}
+			attrs = append(attrs, values)
+			text.Reset()
+			leaf = true
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			if len(path) == 0 {
+				return leaves
+			}
+			if leaf {
+				leaves = append(leaves, xmlLeaf{
+					path:  append([]string(nil), path...),
+					value: strings.TrimSpace(text.String()),
+					attrs: attrs[len(attrs)-1],
+				})
+			}
+			path, attrs = path[:len(path)-1], attrs[:len(attrs)-1]
+			leaf = false
+		}
+	}
+}
+
+func indexString(list []string, value string) int {
+	for i, item := range list {
+		if item == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseLikelihood read a likelihood, given from 0 to 1 or as a percentage
+func parseLikelihood(value string) (float64, bool) {
+	likelihood, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil {
+		return 0, false
+	}
+	if likelihood > 1 {
+		likelihood /= 100
+	}
+	return likelihood, true
+}
+
+// parseWear read whether an accessory is worn, as a boolean or a likelihood
+func parseWear(value string) bool {
+	if worn, err := strconv.ParseBool(value); err == nil {
+		return worn
+	}
+	likelihood, ok := parseLikelihood(value)
+	return ok && likelihood >= 0.5
+}
+
+// plateFromLeaves extract the plate of the leaves, false without plate number
+func plateFromLeaves(leaves []xmlLeaf) (LicensePlate, bool) {
+	plate := LicensePlate{}
+	rank, numberParent := len(plateNumberNames), ""
+	for _, leaf := range leaves {
+		name := leaf.name()
+		switch {
+		case containsString(plateNumberNames, name) && leaf.value != "":
+			/* 多个候选时取名称优先级最高者 */
+			if index := indexString(plateNumberNames, name); index < rank {
+				rank, plate.Number, numberParent = index, leaf.value, leaf.parent()
+				if likelihood, ok := parseLikelihood(leaf.attrs["likelihood"]); ok {
+					plate.Likelihood = likelihood
+				}
+			}
+		case containsString(plateCountryNames, name) && plate.Country == "":
+			plate.Country = leaf.value
+		case containsString(plateIssuerNames, name) && plate.IssuingEntity == "":
+			plate.IssuingEntity = leaf.value
+		case containsString(plateTypeNames, name) && plate.Type == "":
+			plate.Type = leaf.value
+		}
+	}
+	if plate.Number == "" {
+		return plate, false
+	}
+	/* 可信度不在号码属性中时,取同级或号码子元素中的可信度 */
+	for _, leaf := range leaves {
+		if plate.Likelihood != 0 {
+			break
+		}
+		if parent := leaf.parent(); containsString(likelihoodNames, leaf.name()) && (parent == numberParent || containsString(plateNumberNames, parent)) {
+			plate.Likelihood, _ = parseLikelihood(leaf.value)
+		}
+	}
+	return plate, true
+}
+
+// faceFromLeaves extract the face attributes of the leaves, false without
+// any attribute
+func faceFromLeaves(leaves []xmlLeaf) (FaceAttributes, bool) {
+	face := FaceAttributes{}
+	found := false
+	for _, leaf := range leaves {
+		name, parent := leaf.name(), leaf.parent()
+		switch {
+		case containsString(faceGenderNames, name) && leaf.value != "":
+			face.Gender = leaf.value
+		case containsString(faceAgeTypeNames, name) && leaf.value != "":
+			face.AgeType = leaf.value
+		case containsString(faceAgeNames, parent) && (name == "min" || name == "max"):
+			if age, err := strconv.Atoi(leaf.value); err == nil {
+				if name == "min" {
+					face.MinAge = age
+				} else {
+					face.MaxAge = age
+				}
+			}
+		case containsString(faceAgeNames, name):
+			if age, err := strconv.Atoi(leaf.value); err == nil {
+				face.MinAge, face.MaxAge = age, age
+			}
+		case name == "wear" && containsString(faceGlassesNames, parent), containsString(faceGlassesNames, name):
+			face.Glasses = parseWear(leaf.value)
+		case name == "wear" && containsString(faceHatNames, parent), containsString(faceHatNames, name):
+			face.Hat = parseWear(leaf.value)
+		case name == "wear" && containsString(faceMaskNames, parent), containsString(faceMaskNames, name):
+			face.Mask = parseWear(leaf.value)
+		default:
+			continue
+		}
+		found = true
+	}
+	return face, found
+}
+
+// objectAttributes extract the plate and the face of the appearance of an
+// object. Faces are only looked for under a face element, as the names of
+// their attributes are common.
+func objectAttributes(content []byte) (*LicensePlate, *FaceAttributes) {
+	leaves := xmlLeaves(content)
+	var plate *LicensePlate
+	if found, ok := plateFromLeaves(leaves); ok {
+		plate = &found
+	}
+	faceLeaves := leaves[:0:0]
+	for _, leaf := range leaves {
+		if leaf.under(faceContainerNames) {
+			faceLeaves = append(faceLeaves, leaf)
+		}
+	}
+	var face *FaceAttributes
+	if found, ok := faceFromLeaves(faceLeaves); ok {
+		face = &found
+	}
+	return plate, face
+}
+
+// eventLeaves the source and data items of ev as leaves
+func eventLeaves(ev Event) []xmlLeaf {
+	var leaves []xmlLeaf
+	for _, items := range []map[string]string{ev.Source, ev.Data} {
+		for name, value := range items {
+			leaves = append(leaves, xmlLeaf{path: []string{strings.ToLower(name)}, value: value})
+		}
+	}
+	return leaves
+}
+
+// PlateFromEvent extract the plate of a license plate recognition event, from
+// the items the devices name PlateNumber, LicensePlate, Plate... false
+// when ev holds no plate number
+func PlateFromEvent(ev Event) (LicensePlate, bool) {
+	return plateFromLeaves(eventLeaves(ev))
+}
+
+// FaceFromEvent extract the face attributes of a face event, false when ev
+// holds none
+func FaceFromEvent(ev Event) (FaceAttributes, bool) {
+	return faceFromLeaves(eventLeaves(ev))
+}
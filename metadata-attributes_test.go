@@ -0,0 +1,44 @@
+package onvif
+
+import "testing"
+
+func TestMetadataAttributes(t *testing.T) {
+	document := `<tt:MetadataStream xmlns:tt="http://www.onvif.org/ver10/schema"><tt:VideoAnalytics><tt:Frame UtcTime="2024-05-01T10:00:00Z">` +
+		`<tt:Object ObjectId="1"><tt:Appearance><tt:LicensePlateInfo><tt:PlateNumber Likelihood="0.9">ABC123</tt:PlateNumber>` +
+		`<tt:PlateType>Normal</tt:PlateType><tt:CountryCode>SE</tt:CountryCode><tt:IssuingEntity>AB</tt:IssuingEntity></tt:LicensePlateInfo></tt:Appearance></tt:Object>` +
+		`<tt:Object ObjectId="2"><tt:Appearance><tt:HumanFace><tt:Gender>Male</tt:Gender><tt:Age><tt:Min>30</tt:Min><tt:Max>40</tt:Max></tt:Age>` +
+		`<tt:Accessory><tt:Opticals><tt:Wear>true</tt:Wear></tt:Opticals><tt:Hat><tt:Wear>0.2</tt:Wear></tt:Hat></tt:Accessory></tt:HumanFace></tt:Appearance></tt:Object>` +
+		/* 不在人脸元素下的同名属性不视为人脸 */
+		`<tt:Object ObjectId="3"><tt:Appearance><acme:Person xmlns:acme="urn:acme"><acme:Gender>Female</acme:Gender></acme:Person></tt:Appearance></tt:Object>` +
+		`</tt:Frame></tt:VideoAnalytics></tt:MetadataStream>`
+	metadata, err := ParseMetadata("camera", []byte(document))
+	if err != nil || len(metadata.Frames) != 1 || len(metadata.Frames[0].Objects) != 3 {
+		t.Fatalf("metadata %+v, %v", metadata, err)
+	}
+	objects := metadata.Frames[0].Objects
+	if plate := objects[0].Plate; plate == nil || *plate != (LicensePlate{Number: "ABC123", Likelihood: 0.9, Country: "SE", IssuingEntity: "AB", Type: "Normal"}) || objects[0].Face != nil {
+		t.Fatalf("object %+v", objects[0])
+	}
+	if face := objects[1].Face; face == nil || *face != (FaceAttributes{Gender: "Male", MinAge: 30, MaxAge: 40, Glasses: true}) || objects[1].Plate != nil {
+		t.Fatalf("object %+v", objects[1])
+	}
+	if objects[2].Face != nil || objects[2].Plate != nil {
+		t.Fatalf("object %+v", objects[2])
+	}
+
+	/* 厂商事件的条目名与百分比可信度 */
+	ev := Event{Source: map[string]string{"VideoSource": "vs0"}, Data: map[string]string{"PlateText": "XYZ789", "Confidence": "87%", "Country": "FRA"}}
+	if plate, ok := PlateFromEvent(ev); !ok || plate != (LicensePlate{Number: "XYZ789", Likelihood: 0.87, Country: "FRA"}) {
+		t.Fatalf("plate %+v, %v", plate, ok)
+	}
+	if _, ok := FaceFromEvent(ev); ok {
+		t.Fatal("face found in a plate event")
+	}
+	ev = Event{Data: map[string]string{"Gender": "Female", "Age": "25", "Mask": "true"}}
+	if face, ok := FaceFromEvent(ev); !ok || face != (FaceAttributes{Gender: "Female", MinAge: 25, MaxAge: 25, Mask: true}) {
+		t.Fatalf("face %+v, %v", face, ok)
+	}
+	if _, ok := PlateFromEvent(ev); ok {
+		t.Fatal("plate found in a face event")
+	}
+}
@@ -0,0 +1,198 @@
+package onvif
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// RTPPacket RTP packet of a metadata stream, as delivered by an RTSP client
+type RTPPacket struct {
+	SequenceNumber uint16
+	Timestamp      uint32
+	// Marker set on the last packet of a metadata document
+	Marker  bool
+	Payload []byte
+}
+
+// ParseRTPPacket decode the RTP header of data (RFC 3550), the payload
+// shares the memory of data
+func ParseRTPPacket(data []byte) (RTPPacket, error) {
+	if len(data) < 12 || data[0]>>6 != 2 {
+		return RTPPacket{}, errors.New("invalid rtp packet")
+	}
+	packet := RTPPacket{
+		Marker:         data[1]&0x80 != 0,
+		SequenceNumber: binary.BigEndian.Uint16(data[2:4]),
+		Timestamp:      binary.BigEndian.Uint32(data[4:8]),
+	}
+	/* 跳过CSRC列表及扩展头,去掉填充 */
+	offset := 12 + 4*int(data[0]&0x0f)
+	if data[0]&0x10 != 0 {
+		if len(data) < offset+4 {
+			return RTPPacket{}, errors.New("truncated rtp header extension")
+		}
+		offset += 4 + 4*int(binary.BigEndian.Uint16(data[offset+2:offset+4]))
+	}
+	end := len(data)
+	if data[0]&0x20 != 0 && end > 0 {
+		end -= int(data[end-1])
+	}
+	if offset > end {
+		return RTPPacket{}, errors.New("truncated rtp packet")
+	}
+	packet.Payload = data[offset:end]
+	return packet, nil
+}
+
+// MetadataSource RTP packets of the metadata stream of a profile, the
+// integration point of the RTSP client of the application: gortsplib, an
+// ffmpeg pipe or a multicast socket
+type MetadataSource interface {
+	// ReadPacket return the next packet of the stream
+	ReadPacket(ctx context.Context) (RTPPacket, error)
+}
+
+// MetadataSourceFunc adapt a function to MetadataSource
+type MetadataSourceFunc func(ctx context.Context) (RTPPacket, error)
+
+// ReadPacket call fn
+func (fn MetadataSourceFunc) ReadPacket(ctx context.Context) (RTPPacket, error) {
+	return fn(ctx)
+}
+
+// MetadataReader reassemble the packets of a MetadataSource into metadata
+// documents and decode them. A document missing a packet is dropped.
+type MetadataReader struct {
+	Device string
+	Source MetadataSource
+	// MaxSize of a document, 1 MiB when 0; larger documents are dropped
+	MaxSize int
+
+	buffer  []byte
+	next    uint16
+	started bool
+	broken  bool
+}
+
+// NewMetadataReader return a reader of the metadata of device read from
+// source
+func NewMetadataReader(device string, source MetadataSource) *MetadataReader {
+	return &MetadataReader{Device: device, Source: source}
+}
+
+// Next return the next metadata document, decoded, or the error of the
+// source
+func (reader *MetadataReader) Next(ctx context.Context) (Metadata, error) {
+	for {
+		document, err := reader.NextDocument(ctx)
+		if err != nil {
+			return Metadata{}, err
+		}
+		/* 无法解析的文档跳过,不中断流 */
+		if metadata, err := ParseMetadata(reader.Device, document); err == nil {
+			return metadata, nil
+		}
+	}
+}
+
+// NextDocument return the next complete XML document of the stream
+func (reader *MetadataReader) NextDocument(ctx context.Context) ([]byte, error) {
+	maxSize := reader.MaxSize
+	if maxSize <= 0 {
+		maxSize = 1 << 20
+	}
+	for {
+		packet, err := reader.Source.ReadPacket(ctx)
+		if err != nil {
+			return nil, err
+		}
+		/* 序号不连续说明丢包,丢弃当前文档直到下一个marker */
+		if reader.started && packet.SequenceNumber != reader.next {
+			reader.broken = true
+		}
+		reader.started, reader.next = true, packet.SequenceNumber+1
+		if !reader.broken {
+			reader.buffer = append(reader.buffer, packet.Payload...)
+			if len(reader.buffer) > maxSize {
+				reader.broken = true
+			}
+		}
+		if !packet.Marker {
+			continue
+		}
+		document, broken := reader.buffer, reader.broken
+		reader.buffer, reader.broken = nil, false
+		if !broken && len(document) > 0 {
+			return document, nil
+		}
+	}
+}
+
+// UDPMetadataSource MetadataSource reading RTP over UDP, such as the
+// multicast metadata stream of a profile or the unicast port negotiated by
+// an RTSP client
+type UDPMetadataSource struct {
+	Conn net.PacketConn
+
+	buffer []byte
+}
+
+// NewUDPMetadataSource return a source reading the packets received on conn
+func NewUDPMetadataSource(conn net.PacketConn) *UDPMetadataSource {
+	return &UDPMetadataSource{Conn: conn}
+}
+
+// ListenMetadataMulticast join the multicast group address, host:port, on
+// the interface ifi, nil for the default one
+func ListenMetadataMulticast(address string, ifi *net.Interface) (*UDPMetadataSource, error) {
+	group, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	if !group.IP.IsMulticast() {
+		return nil, fmt.Errorf("%s is not a multicast address", address)
+	}
+	conn, err := net.ListenMulticastUDP("udp", ifi, group)
+	if err != nil {
+		return nil, err
+	}
+	return NewUDPMetadataSource(conn), nil
+}
+
+// ReadPacket return the next RTP packet, packets which are not RTP are
+// skipped
+func (source *UDPMetadataSource) ReadPacket(ctx context.Context) (RTPPacket, error) {
+	if source.buffer == nil {
+		source.buffer = make([]byte, 65536)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return RTPPacket{}, err
+		}
+		/* 定期超时以响应ctx取消 */
+		source.Conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := source.Conn.ReadFrom(source.buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			return RTPPacket{}, err
+		}
+		packet, err := ParseRTPPacket(source.buffer[:n])
+		if err != nil {
+			continue
+		}
+		/* 缓冲区复用,负载需复制 */
+		packet.Payload = append([]byte(nil), packet.Payload...)
+		return packet, nil
+	}
+}
+
+// Close close the connection
+func (source *UDPMetadataSource) Close() error {
+	return source.Conn.Close()
+}
@@ -0,0 +1,99 @@
+package onvif
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+const metadataDocument = `<tt:MetadataStream xmlns:tt="http://www.onvif.org/ver10/schema"><tt:VideoAnalytics>` +
+	`<tt:Frame UtcTime="2024-05-01T10:00:00Z" Source="vsc0"><tt:Object ObjectId="7"><tt:Appearance>` +
+	`<tt:Shape><tt:BoundingBox left="-0.5" top="0.5" right="0.5" bottom="-0.5"/></tt:Shape>` +
+	`<tt:Class><tt:Type Likelihood="0.4">Vehicle</tt:Type><tt:Type Likelihood="0.9">Human</tt:Type></tt:Class>` +
+	`</tt:Appearance><tt:Behaviour><tt:Idle/></tt:Behaviour></tt:Object>` +
+	`<tt:ObjectTree><tt:Rename><tt:from ObjectId="3"/><tt:to ObjectId="7"/></tt:Rename><tt:Delete ObjectId="4"/></tt:ObjectTree>` +
+	`</tt:Frame></tt:VideoAnalytics></tt:MetadataStream>`
+
+// rtpPacket encode an RTP packet of the payload, with a CSRC and padding
+func rtpPacket(sequence uint16, marker bool, payload string) []byte {
+	data := make([]byte, 16, 16+len(payload)+2)
+	data[0] = 0x80 | 0x20 | 1
+	if marker {
+		data[1] = 0x80
+	}
+	binary.BigEndian.PutUint16(data[2:4], sequence)
+	binary.BigEndian.PutUint32(data[4:8], 90000)
+	data = append(data, payload...)
+	return append(data, 0, 2)
+}
+
+func TestParseRTPPacket(t *testing.T) {
+	packet, err := ParseRTPPacket(rtpPacket(12, true, "<tt:"))
+	if err != nil || packet.SequenceNumber != 12 || packet.Timestamp != 90000 || !packet.Marker || string(packet.Payload) != "<tt:" {
+		t.Fatalf("packet %+v, %v", packet, err)
+	}
+	for _, data := range [][]byte{[]byte("short"), append([]byte{0x40}, make([]byte, 11)...), {0x90, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}} {
+		if _, err := ParseRTPPacket(data); err == nil {
+			t.Fatalf("packet % x accepted", data)
+		}
+	}
+}
+
+func TestMetadataReader(t *testing.T) {
+	/* 第二个文档丢失一个包,第三个无法解析,均被跳过 */
+	half := len(metadataDocument) / 2
+	var packets [][]byte
+	packets = append(packets, rtpPacket(1, false, metadataDocument[:half]), rtpPacket(2, true, metadataDocument[half:]))
+	packets = append(packets, rtpPacket(3, false, metadataDocument[:half]), rtpPacket(5, true, metadataDocument[half:]))
+	packets = append(packets, rtpPacket(6, false, "<broken"), rtpPacket(7, true, ">"))
+	packets = append(packets, rtpPacket(8, true, metadataDocument))
+	source := MetadataSourceFunc(func(ctx context.Context) (RTPPacket, error) {
+		if len(packets) == 0 {
+			return RTPPacket{}, io.EOF
+		}
+		data := packets[0]
+		packets = packets[1:]
+		return ParseRTPPacket(data)
+	})
+	reader := NewMetadataReader("camera", source)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		metadata, err := reader.Next(ctx)
+		if err != nil || metadata.Device != "camera" || len(metadata.Frames) != 1 {
+			t.Fatalf("metadata %d %+v, %v", i, metadata, err)
+		}
+	}
+	if _, err := reader.Next(ctx); !errors.Is(err, io.EOF) {
+		t.Fatalf("error %v after the last document", err)
+	}
+}
+
+func TestUDPMetadataSource(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := NewUDPMetadataSource(conn)
+	defer source.Close()
+	sender, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	/* 非RTP的数据报被跳过 */
+	sender.Write([]byte("noise"))
+	sender.Write(rtpPacket(9, true, "<doc/>"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	packet, err := source.ReadPacket(ctx)
+	if err != nil || packet.SequenceNumber != 9 || string(packet.Payload) != "<doc/>" {
+		t.Fatalf("packet %+v, %v", packet, err)
+	}
+	if _, err := ListenMetadataMulticast("127.0.0.1:5000", nil); err == nil {
+		t.Fatal("unicast address joined")
+	}
+}
@@ -0,0 +1,119 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Metadata content of one tt:MetadataStream document of the metadata stream
+// of a profile: the objects detected by the analytics and the events
+type Metadata struct {
+	Device string
+	Frames []MetadataFrame
+	Events []Event
+}
+
+// MetadataFrame objects of a video frame
+type MetadataFrame struct {
+	// Time UtcTime of the frame, the reception time when the device sent none
+	Time time.Time
+	// Source video source configuration of the frame, when the stream
+	// carries several
+	Source  string
+	Objects []MetadataObject
+	// Renamed new identifier of the objects renamed in the frame, by former
+	// identifier
+	Renamed map[int]int
+	// Deleted identifiers of the objects which no longer exist
+	Deleted []int
+}
+
+// MetadataObject object detected in a frame, its box in normalized
+// coordinates (-1 to 1, y pointing up)
+type MetadataObject struct {
+	ID          int
+	BoundingBox onvif.Rectangle
+	// Class most likely class of the object, e.g. Human or Vehicle, empty
+	// when the device does not classify
+	Class      string
+	Likelihood float64
+	// Removed the object left the scene, Idle it stopped moving
+	Removed bool
+	Idle    bool
+	// Plate license plate read on the object, nil when none
+	Plate *LicensePlate
+	// Face attributes of the face of the object, nil when none
+	Face *FaceAttributes
+}
+
+// metadataStream decoding form of a tt:MetadataStream
+type metadataStream struct {
+	VideoAnalytics []onvif.VideoAnalyticsStream `xml:"VideoAnalytics"`
+	Event          []struct {
+		NotificationMessage []event.NotificationMessage `xml:"NotificationMessage"`
+	} `xml:"Event"`
+}
+
+// ParseMetadata decode a tt:MetadataStream document received from device
+func ParseMetadata(device string, document []byte) (Metadata, error) {
+	stream := metadataStream{}
+	if err := xml.Unmarshal(document, &stream); err != nil {
+		return Metadata{}, err
+	}
+	metadata := Metadata{Device: device}
+	received := time.Now()
+	for _, analytics := range stream.VideoAnalytics {
+		for _, frame := range analytics.Frame {
+			item := MetadataFrame{Source: frame.Source, Time: received}
+			if t, err := frame.UtcTime.ToTime(); err == nil {
+				item.Time = t
+			}
+			for _, object := range frame.Object {
+				item.Objects = append(item.Objects, newMetadataObject(object))
+			}
+			for _, rename := range frame.ObjectTree.Rename {
+				if item.Renamed == nil {
+					item.Renamed = make(map[int]int)
+				}
+				item.Renamed[rename.From.ObjectId] = rename.To.ObjectId
+			}
+			for _, deleted := range frame.ObjectTree.Delete {
+				item.Deleted = append(item.Deleted, deleted.ObjectId)
+			}
+			metadata.Frames = append(metadata.Frames, item)
+		}
+	}
+	for _, ev := range stream.Event {
+		for _, message := range ev.NotificationMessage {
+			metadata.Events = append(metadata.Events, NewEvent(device, message))
+		}
+	}
+	return metadata, nil
+}
+
+func newMetadataObject(object onvif.Object) MetadataObject {
+	item := MetadataObject{
+		ID:          object.ObjectId,
+		BoundingBox: object.Appearance.Shape.BoundingBox,
+		Removed:     object.Behaviour.Removed != nil,
+		Idle:        object.Behaviour.Idle != nil,
+	}
+	item.Plate, item.Face = objectAttributes(object.Appearance.Content)
+	/* 兼容新旧两种分类格式,取可信度最高者 */
+	class := object.Appearance.Class
+	for _, candidate := range class.ClassCandidate {
+		if candidate.Likelihood >= item.Likelihood {
+			item.Class, item.Likelihood = strings.TrimSpace(candidate.Type), candidate.Likelihood
+		}
+	}
+	for _, candidate := range class.Type {
+		if candidate.Likelihood >= item.Likelihood {
+			item.Class, item.Likelihood = strings.TrimSpace(candidate.Value), candidate.Likelihood
+		}
+	}
+	return item
+}
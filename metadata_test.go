@@ -0,0 +1,29 @@
+package onvif
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+func TestParseMetadata(t *testing.T) {
+	metadata, err := ParseMetadata("camera", []byte(metadataDocument))
+	if err != nil || len(metadata.Frames) != 1 || len(metadata.Events) != 0 {
+		t.Fatalf("metadata %+v, %v", metadata, err)
+	}
+	frame := metadata.Frames[0]
+	if !frame.Time.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) || frame.Source != "vsc0" || frame.Renamed[3] != 7 ||
+		len(frame.Deleted) != 1 || frame.Deleted[0] != 4 || len(frame.Objects) != 1 {
+		t.Fatalf("frame %+v", frame)
+	}
+	/* 取可信度最高的分类 */
+	object := frame.Objects[0]
+	if object.ID != 7 || object.Class != "Human" || object.Likelihood != 0.9 || !object.Idle || object.Removed ||
+		object.BoundingBox != (onvif.Rectangle{Left: -0.5, Top: 0.5, Right: 0.5, Bottom: -0.5}) || object.Plate != nil || object.Face != nil {
+		t.Fatalf("object %+v", object)
+	}
+	if _, err := ParseMetadata("camera", []byte("<tt:MetadataStream")); err == nil {
+		t.Fatal("truncated document parsed")
+	}
+}
@@ -0,0 +1,152 @@
+package onvif
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// MQTTPublisher publish a message to a broker, implemented by mqtt.Client and
+// easily by the wrapper of any other MQTT library
+type MQTTPublisher interface {
+	Publish(topic string, qos byte, retained bool, payload []byte) error
+}
+
+// Default topic templates of MQTTSink
+const (
+	DefaultMQTTTopic      = "onvif/{{.ID}}/events/{{.Topic}}"
+	DefaultMQTTStateTopic = "onvif/{{.ID}}/state/{{.Topic}}{{if .Source}}/{{.Source}}{{end}}"
+)
+
+// MQTTTopicData variables available to the topic templates of MQTTSink,
+// e.g. "{{.Labels.site}}/{{.ID}}/{{.Topic}}"
+type MQTTTopicData struct {
+	// ID registry id of the device, its address when it is not registered
+	ID     string
+	Device string
+	// Topic event topic without namespace prefixes, e.g.
+	// RuleEngine/CellMotionDetector/Motion
+	Topic     string
+	Operation string
+	// Source values of the source items sorted by name and joined by _,
+	// telling apart the instances of a property such as the inputs
+	Source string
+	Labels map[string]string
+}
+
+// MQTTSink publish the events of an EventEngine to an MQTT broker:
+//
+//	engine.Handle(onvif.NewMQTTSink(client).Handle)
+//
+// Property events (Initialized, Changed) are also published retained on
+// StateTopic, so a subscriber gets the current state of every property
+// at once; a Deleted property clears its retained message.
+type MQTTSink struct {
+	Publisher MQTTPublisher
+	// Topic template of the event topics, DefaultMQTTTopic when empty
+	Topic string
+	// StateTopic template of the retained state topics, empty disables them
+	StateTopic string
+	QoS        byte
+	// Registry optional, provides the ID and Labels of the devices
+	Registry *Registry
+	// OnError optional callback for the events which could not be published
+	OnError func(Event, error)
+
+	mutex     sync.Mutex
+	templates map[string]*template.Template
+}
+
+// NewMQTTSink return a sink publishing with the default topics
+func NewMQTTSink(publisher MQTTPublisher) *MQTTSink {
+	return &MQTTSink{Publisher: publisher, Topic: DefaultMQTTTopic, StateTopic: DefaultMQTTStateTopic}
+}
+
+// Handle publish the event, to be registered with EventEngine.Handle
+func (sink *MQTTSink) Handle(ev Event) {
+	data := MQTTTopicData{ID: mqttLevel(ev.Device), Device: ev.Device, Topic: mqttTopicPath(ev.Topic), Operation: ev.Operation, Source: mqttSource(ev.Source)}
+	if sink.Registry != nil {
+		if entry, ok := sink.Registry.Lookup(ev.Device); ok {
+			data.ID = mqttLevel(entry.ID)
+			data.Labels = entry.Labels
+		}
+	}
+	payload, err := json.Marshal(NewEventJSON(ev))
+	if err == nil {
+		err = sink.publish(sink.Topic, DefaultMQTTTopic, data, false, payload)
+	}
+	if err == nil && sink.StateTopic != "" && ev.Operation != "" {
+		/* 属性删除时发送空的保留消息清除状态 */
+		if ev.Operation == "Deleted" {
+			payload = nil
+		}
+		err = sink.publish(sink.StateTopic, DefaultMQTTStateTopic, data, true, payload)
+	}
+	if err != nil && sink.OnError != nil {
+		sink.OnError(ev, err)
+	}
+}
+
+func (sink *MQTTSink) publish(text, fallback string, data MQTTTopicData, retained bool, payload []byte) error {
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := sink.template(text)
+	if err != nil {
+		return err
+	}
+	topic := bytes.Buffer{}
+	if err := tmpl.Execute(&topic, data); err != nil {
+		return err
+	}
+	return sink.Publisher.Publish(topic.String(), sink.QoS, retained, payload)
+}
+
+/* 模板按文本缓存,避免每个事件重复解析 */
+func (sink *MQTTSink) template(text string) (*template.Template, error) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if tmpl, ok := sink.templates[text]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := template.New("topic").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if sink.templates == nil {
+		sink.templates = make(map[string]*template.Template)
+	}
+	sink.templates[text] = tmpl
+	return tmpl, nil
+}
+
+// mqttLevel return value usable as one level of an MQTT topic
+func mqttLevel(value string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(value)
+}
+
+func mqttSource(source map[string]string) string {
+	names := make([]string, 0, len(source))
+	for name := range source {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, mqttLevel(source[name]))
+	}
+	return strings.Join(values, "_")
+}
+
+// mqttTopicPath return the levels of an event topic without their namespace
+// prefix, tns1:RuleEngine/tnsaxis:Motion becomes RuleEngine/Motion
+func mqttTopicPath(topic string) string {
+	levels := strings.Split(topicPath(topic), "/")
+	for i, level := range levels {
+		levels[i] = mqttLevel(level)
+	}
+	return strings.Join(levels, "/")
+}
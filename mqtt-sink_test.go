@@ -0,0 +1,60 @@
+package onvif
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+/* 记录发布的消息 */
+type fakePublisher struct {
+	messages []string
+	payloads [][]byte
+}
+
+func (publisher *fakePublisher) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	publisher.messages = append(publisher.messages, fmt.Sprintf("%s qos=%d retained=%v", topic, qos, retained))
+	publisher.payloads = append(publisher.payloads, payload)
+	return nil
+}
+
+func TestMQTTSink(t *testing.T) {
+	registry := NewRegistry(nil)
+	registry.Add("gate/north", newDevice(DeviceParams{Ipddr: "10.1.1.200"}), map[string]string{"site": "paris"})
+	publisher := &fakePublisher{}
+	sink := NewMQTTSink(publisher)
+	sink.Topic = "{{.Labels.site}}/{{.ID}}/{{.Topic}}"
+	sink.QoS = 1
+	sink.Registry = registry
+	input := Event{
+		Device:    "10.1.1.200",
+		Topic:     "tns1:Device/Trigger/DigitalInput",
+		Operation: "Changed",
+		Source:    map[string]string{"InputToken": "1"},
+		Data:      map[string]string{"LogicalState": "true"},
+	}
+	sink.Handle(input)
+	input.Operation = "Deleted"
+	sink.Handle(input)
+	/* 没有属性操作的事件不发送状态 */
+	sink.Handle(Event{Device: "10.1.1.201", Topic: "tns1:VideoSource/tnsvendor:Tamper"})
+
+	want := []string{
+		"paris/gate_north/Device/Trigger/DigitalInput qos=1 retained=false",
+		"onvif/gate_north/state/Device/Trigger/DigitalInput/1 qos=1 retained=true",
+		"paris/gate_north/Device/Trigger/DigitalInput qos=1 retained=false",
+		"onvif/gate_north/state/Device/Trigger/DigitalInput/1 qos=1 retained=true",
+		"/10.1.1.201/VideoSource/Tamper qos=1 retained=false",
+	}
+	if fmt.Sprint(publisher.messages) != fmt.Sprint(want) {
+		t.Fatalf("messages %q", publisher.messages)
+	}
+	var state map[string]interface{}
+	if err := json.Unmarshal(publisher.payloads[1], &state); err != nil || len(state) == 0 {
+		t.Fatalf("state %s, %v", publisher.payloads[1], err)
+	}
+	/* 删除的属性以空的保留消息清除 */
+	if publisher.payloads[3] != nil {
+		t.Fatalf("payload %s of a deleted property", publisher.payloads[3])
+	}
+}
@@ -0,0 +1,335 @@
+// Package mqtt is a minimal MQTT 3.1.1 client publishing messages with QoS 0
+// or 1, enough to forward device events to a broker without a dependency.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// packet types of MQTT 3.1.1
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPuback     = 4
+	packetPingreq    = 12
+	packetPingresp   = 13
+	packetDisconnect = 14
+)
+
+// ErrClosed returned by Publish after Close
+var ErrClosed = errors.New("mqtt client closed")
+
+// Options of the connection to the broker
+type Options struct {
+	// Address host:port of the broker
+	Address  string
+	ClientID string
+	Username string
+	Password string
+	// KeepAlive interval of the pings, 0 means 60s
+	KeepAlive time.Duration
+	// Timeout of the connection and of the QoS 1 acknowledgements, 0 means 10s
+	Timeout time.Duration
+	// TLSConfig connect with TLS when set
+	TLSConfig *tls.Config
+}
+
+// Client connection to a broker, the connection is established again by
+// Publish when it was lost
+type Client struct {
+	options Options
+
+	mutex    sync.Mutex
+	conn     net.Conn
+	writer   *bufio.Writer
+	packetID uint16
+	acks     map[uint16]chan struct{}
+	closed   bool
+	stop     chan struct{}
+}
+
+// Dial connect to the broker
+func Dial(ctx context.Context, options Options) (*Client, error) {
+	if options.KeepAlive <= 0 {
+		options.KeepAlive = 60 * time.Second
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	client := &Client{options: options}
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if err := client.connect(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+/* 调用方持有mutex */
+func (client *Client) connect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, client.options.Timeout)
+	defer cancel()
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", client.options.Address)
+	if err != nil {
+		return err
+	}
+	if client.options.TLSConfig != nil {
+		tlsConn := tls.Client(conn, client.options.TLSConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	if err := writePacket(writer, packetConnect<<4, client.connectPayload()); err != nil {
+		conn.Close()
+		return err
+	}
+	header, body, err := readPacket(reader)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if header>>4 != packetConnack || len(body) < 2 {
+		conn.Close()
+		return errors.New("mqtt: unexpected answer to connect")
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return fmt.Errorf("mqtt: connection refused, code %d", body[1])
+	}
+	conn.SetDeadline(time.Time{})
+	client.conn = conn
+	client.writer = writer
+	client.acks = make(map[uint16]chan struct{})
+	client.stop = make(chan struct{})
+	go client.read(conn, reader)
+	go client.ping(client.stop)
+	return nil
+}
+
+func (client *Client) connectPayload() []byte {
+	var flags byte = 0x02 // clean session
+	payload := appendString(nil, "MQTT")
+	payload = append(payload, 4)
+	if client.options.Username != "" {
+		flags |= 0x80
+	}
+	if client.options.Password != "" {
+		flags |= 0x40
+	}
+	keepAlive := uint16(client.options.KeepAlive / time.Second)
+	payload = append(payload, flags, byte(keepAlive>>8), byte(keepAlive))
+	payload = appendString(payload, client.options.ClientID)
+	if client.options.Username != "" {
+		payload = appendString(payload, client.options.Username)
+	}
+	if client.options.Password != "" {
+		payload = appendString(payload, client.options.Password)
+	}
+	return payload
+}
+
+// read dispatch the acknowledgements until the connection fails
+func (client *Client) read(conn net.Conn, reader *bufio.Reader) {
+	for {
+		header, body, err := readPacket(reader)
+		if err != nil {
+			client.mutex.Lock()
+			if client.conn == conn {
+				client.drop()
+			}
+			client.mutex.Unlock()
+			return
+		}
+		if header>>4 == packetPuback && len(body) >= 2 {
+			id := uint16(body[0])<<8 | uint16(body[1])
+			client.mutex.Lock()
+			if ack, ok := client.acks[id]; ok {
+				close(ack)
+				delete(client.acks, id)
+			}
+			client.mutex.Unlock()
+		}
+	}
+}
+
+func (client *Client) ping(stop chan struct{}) {
+	ticker := time.NewTicker(client.options.KeepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			client.mutex.Lock()
+			if client.conn != nil {
+				if err := writePacket(client.writer, packetPingreq<<4, nil); err != nil {
+					client.drop()
+				}
+			}
+			client.mutex.Unlock()
+		}
+	}
+}
+
+/* 调用方持有mutex,关闭连接并唤醒等待确认的发布 */
+func (client *Client) drop() {
+	if client.conn == nil {
+		return
+	}
+	client.conn.Close()
+	client.conn = nil
+	close(client.stop)
+	for id, ack := range client.acks {
+		close(ack)
+		delete(client.acks, id)
+	}
+}
+
+// Publish send payload on topic. With QoS 1 the call waits for the broker
+// acknowledgement; QoS 2 is not supported.
+func (client *Client) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	if qos > 1 {
+		return errors.New("mqtt: qos 2 not supported")
+	}
+	client.mutex.Lock()
+	if client.closed {
+		client.mutex.Unlock()
+		return ErrClosed
+	}
+	if client.conn == nil {
+		if err := client.connect(context.Background()); err != nil {
+			client.mutex.Unlock()
+			return err
+		}
+	}
+	header := byte(packetPublish<<4) | qos<<1
+	if retained {
+		header |= 1
+	}
+	body := appendString(nil, topic)
+	var ack chan struct{}
+	var id uint16
+	if qos == 1 {
+		/* 跳过仍在等待确认的报文标识 */
+		for {
+			client.packetID++
+			if _, used := client.acks[client.packetID]; client.packetID != 0 && !used {
+				break
+			}
+		}
+		id = client.packetID
+		body = append(body, byte(id>>8), byte(id))
+		ack = make(chan struct{})
+		client.acks[id] = ack
+	}
+	body = append(body, payload...)
+	client.conn.SetWriteDeadline(time.Now().Add(client.options.Timeout))
+	err := writePacket(client.writer, header, body)
+	if err != nil {
+		client.drop()
+	}
+	client.mutex.Unlock()
+	if err != nil || ack == nil {
+		return err
+	}
+	timer := time.NewTimer(client.options.Timeout)
+	defer timer.Stop()
+	select {
+	case <-ack:
+		client.mutex.Lock()
+		lost := client.conn == nil
+		client.mutex.Unlock()
+		if lost {
+			return errors.New("mqtt: connection lost before acknowledgement")
+		}
+		return nil
+	case <-timer.C:
+		client.mutex.Lock()
+		if client.acks[id] == ack {
+			delete(client.acks, id)
+		}
+		client.mutex.Unlock()
+		return errors.New("mqtt: acknowledgement timeout")
+	}
+}
+
+// Close disconnect from the broker
+func (client *Client) Close() error {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.closed = true
+	if client.conn == nil {
+		return nil
+	}
+	err := writePacket(client.writer, packetDisconnect<<4, nil)
+	client.drop()
+	return err
+}
+
+func appendString(buf []byte, value string) []byte {
+	buf = append(buf, byte(len(value)>>8), byte(len(value)))
+	return append(buf, value...)
+}
+
+func writePacket(writer *bufio.Writer, header byte, body []byte) error {
+	writer.WriteByte(header)
+	/* 剩余长度使用变长编码 */
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		writer.WriteByte(digit)
+		if length == 0 {
+			break
+		}
+	}
+	writer.Write(body)
+	return writer.Flush()
+}
+
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
@@ -0,0 +1,92 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* 最小的代理: 接受连接,记录收到的报文,ack为false时不确认QoS 1发布 */
+func serveBroker(t *testing.T, ack bool) (string, chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	packets := make(chan []byte, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader, writer := bufio.NewReader(conn), bufio.NewWriter(conn)
+		for {
+			header, body, err := readPacket(reader)
+			if err != nil {
+				return
+			}
+			packets <- append([]byte{header}, body...)
+			switch header >> 4 {
+			case packetConnect:
+				writePacket(writer, packetConnack<<4, []byte{0, 0})
+			case packetPublish:
+				if ack && header&0x06 != 0 {
+					/* 报文标识位于主题之后 */
+					offset := 2 + (int(body[0])<<8 | int(body[1]))
+					writePacket(writer, packetPuback<<4, body[offset:offset+2])
+				}
+			}
+		}
+	}()
+	return listener.Addr().String(), packets
+}
+
+func TestPublish(t *testing.T) {
+	address, packets := serveBroker(t, true)
+	client, err := Dial(context.Background(), Options{Address: address, ClientID: "onvif", Username: "user", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	connect := <-packets
+	if connect[0] != packetConnect<<4 || !strings.HasSuffix(string(connect), "\x00\x05onvif\x00\x04user\x00\x06secret") || connect[8]&0xc0 != 0xc0 {
+		t.Fatalf("connect %q", connect)
+	}
+	if err := client.Publish("onvif/gate/state", 1, true, []byte(`{"state":true}`)); err != nil {
+		t.Fatal(err)
+	}
+	publish := <-packets
+	if publish[0] != packetPublish<<4|0x02|0x01 || string(publish[1:]) != "\x00\x10onvif/gate/state\x00\x01"+`{"state":true}` {
+		t.Fatalf("publish %q", publish)
+	}
+	if err := client.Publish("onvif/gate/state", 2, false, nil); err == nil {
+		t.Fatal("qos 2 accepted")
+	}
+	client.Close()
+	if err := client.Publish("onvif/gate/state", 0, false, nil); err != ErrClosed {
+		t.Fatalf("error %v after Close", err)
+	}
+}
+
+func TestPublishAcknowledgementTimeout(t *testing.T) {
+	address, _ := serveBroker(t, false)
+	client, err := Dial(context.Background(), Options{Address: address, Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Publish("onvif/gate/events", 1, false, nil); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("error %v", err)
+	}
+	/* 超时后不再等待该报文的确认 */
+	client.mutex.Lock()
+	pending := len(client.acks)
+	client.mutex.Unlock()
+	if pending != 0 {
+		t.Fatalf("%d acknowledgements pending", pending)
+	}
+}
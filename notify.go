@@ -0,0 +1,264 @@
+package onvif
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/soap"
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+// NotifyListener HTTP consumer of base notification subscriptions: the
+// devices post their Notify messages to it and the events are delivered to
+// Engine, along with the events pulled by the engine. It suits devices
+// without pull points and saves the long polls of large fleets.
+//
+//	listener := onvif.NewNotifyListener(engine, "http://10.1.1.5:8080/onvif/notify")
+//	http.Handle("/onvif/notify/", listener)
+//	go listener.Run(ctx)
+//	go listener.Subscribe(ctx, dev)
+type NotifyListener struct {
+	Engine *EventEngine
+	// URL of the listener as reached by the devices, each subscription is
+	// given its own unguessable path below it
+	URL string
+	// TerminationTime of the subscriptions, renewed once half elapsed, 0
+	// means 60s
+	TerminationTime time.Duration
+	// Filter optional filter of the subscriptions
+	Filter *EventFilter
+	// OnError optional callback for the subscription errors
+	OnError func(dev *Device, err error)
+	// QueueSize notifications acknowledged and waiting for Run to deliver
+	// them, 0 means defaultNotifyQueue. The Backpressure policy of Engine
+	// applies when the queue is full.
+	QueueSize int
+
+	mutex         sync.Mutex
+	subscriptions map[string]*Device
+	queue         chan notification
+	counters      eventCounters
+}
+
+// defaultNotifyQueue notifications queued when QueueSize is 0
+const defaultNotifyQueue = 256
+
+// notification event received from a device, waiting for delivery
+type notification struct {
+	dev *Device
+	ev  Event
+}
+
+// NewNotifyListener return a listener reached at listenerURL and delivering
+// to engine
+func NewNotifyListener(engine *EventEngine, listenerURL string) *NotifyListener {
+	return &NotifyListener{Engine: engine, URL: listenerURL, subscriptions: make(map[string]*Device)}
+}
+
+// consumerURL return the address the device sends the notifications of the
+// subscription to
+func (listener *NotifyListener) consumerURL(token string) string {
+	return strings.TrimSuffix(listener.URL, "/") + "/" + token
+}
+
+// subscriptionToken return a random token identifying a subscription, so
+// that only the subscribed device can post notifications to the listener
+func subscriptionToken() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// notifications return the queue of the notifications to deliver
+func (listener *NotifyListener) notifications() chan notification {
+	listener.mutex.Lock()
+	defer listener.mutex.Unlock()
+	if listener.queue == nil {
+		size := listener.QueueSize
+		if size <= 0 {
+			size = defaultNotifyQueue
+		}
+		listener.queue = make(chan notification, size)
+	}
+	return listener.queue
+}
+
+// Run deliver the notifications received to Engine until ctx is done
+func (listener *NotifyListener) Run(ctx context.Context) error {
+	queue := listener.notifications()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case item := <-queue:
+			if !listener.Engine.deliver(ctx, item.dev, item.ev) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// Stats return the notifications queued for delivery and the ones dropped by
+// the Backpressure policy of Engine
+func (listener *NotifyListener) Stats() ConsumerStats {
+	return listener.counters.stats()
+}
+
+// Subscribe subscribe the listener to the events of dev and renew the
+// subscription until ctx is done, the subscription is then cancelled
+func (listener *NotifyListener) Subscribe(ctx context.Context, dev *Device) error {
+	token, err := subscriptionToken()
+	if err != nil {
+		return err
+	}
+	listener.mutex.Lock()
+	if listener.subscriptions == nil {
+		listener.subscriptions = make(map[string]*Device)
+	}
+	listener.subscriptions[token] = dev
+	listener.mutex.Unlock()
+	defer func() {
+		listener.mutex.Lock()
+		delete(listener.subscriptions, token)
+		listener.mutex.Unlock()
+	}()
+
+	termination := durationOr(listener.TerminationTime, time.Minute)
+	response, err := dev.Subscribe(ctx, listener.consumerURL(token), listener.Filter, termination)
+	if err != nil {
+		return err
+	}
+	reference := response.SubscriptionReference
+	ticker := time.NewTicker(termination / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			/* ctx已结束,使用独立超时取消订阅 */
+			unsubscribeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := dev.Unsubscribe(unsubscribeCtx, reference); err != nil {
+				listener.reportError(dev, err)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if _, err := dev.Renew(ctx, reference, termination); err != nil && ctx.Err() == nil {
+			/* 续订失败时重新订阅 */
+			listener.reportError(dev, err)
+			if response, err = dev.Subscribe(ctx, listener.consumerURL(token), listener.Filter, termination); err != nil {
+				listener.reportError(dev, err)
+				continue
+			}
+			reference = response.SubscriptionReference
+		}
+	}
+}
+
+func (listener *NotifyListener) reportError(dev *Device, err error) {
+	if listener.OnError != nil {
+		listener.OnError(dev, err)
+	}
+}
+
+// ServeHTTP receive the Notify messages of the subscriptions, the messages
+// are acknowledged before they are delivered by Run
+func (listener *NotifyListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	listener.mutex.Lock()
+	dev, ok := listener.subscriptions[token]
+	listener.mutex.Unlock()
+	if !ok {
+		http.Error(w, "unknown subscription", http.StatusNotFound)
+		return
+	}
+	/* 限制大小并检查xml结构,防止恶意请求 */
+	data, err := soap.ReadLimited(r.Body, soap.DefaultLimits)
+	if err == nil {
+		err = soap.CheckXML(data, soap.DefaultLimits)
+	}
+	var body []byte
+	if err == nil {
+		body, err = soap.Body(data)
+	}
+	notify := event.Notify{}
+	if err == nil {
+		err = xml.Unmarshal(body, &notify)
+	}
+	if err != nil {
+		listener.reportError(dev, err)
+		http.Error(w, "invalid notify message", http.StatusBadRequest)
+		return
+	}
+	/* 先应答设备,事件由Run投递,消费者阻塞不影响设备的请求 */
+	w.WriteHeader(http.StatusAccepted)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	for _, message := range notify.NotificationMessage {
+		if !listener.enqueue(r.Context(), notification{dev: dev, ev: NewEvent(dev.Params.Ipddr, message)}) {
+			break
+		}
+	}
+}
+
+// enqueue queue item for Run following the Backpressure policy of Engine,
+// false when ctx ended while blocked
+func (listener *NotifyListener) enqueue(ctx context.Context, item notification) bool {
+	queue := listener.notifications()
+	select {
+	case queue <- item:
+		atomic.AddUint64(&listener.counters.delivered, 1)
+		return true
+	default:
+	}
+	switch listener.Engine.Backpressure {
+	case BackpressureDropNewest:
+		atomic.AddUint64(&listener.counters.dropped, 1)
+		return true
+	case BackpressureDropOldest:
+		select {
+		case <-queue:
+			atomic.AddUint64(&listener.counters.dropped, 1)
+		default:
+		}
+		select {
+		case queue <- item:
+			atomic.AddUint64(&listener.counters.delivered, 1)
+		default:
+			atomic.AddUint64(&listener.counters.dropped, 1)
+		}
+		return true
+	}
+	var timeout <-chan time.Time
+	if listener.Engine.MaxBlock > 0 {
+		timer := time.NewTimer(listener.Engine.MaxBlock)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case queue <- item:
+		atomic.AddUint64(&listener.counters.delivered, 1)
+		return true
+	case <-timeout:
+		atomic.AddUint64(&listener.counters.dropped, 1)
+		return true
+	case <-ctx.Done():
+		atomic.AddUint64(&listener.counters.dropped, 1)
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+package onvif
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const notificationMessage = `<wsnt:NotificationMessage><wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">tns1:VideoSource/MotionAlarm</wsnt:Topic>` +
+	`<wsnt:Message><tt:Message UtcTime="2026-10-16T10:00:00Z" PropertyOperation="Changed"><tt:Source><tt:SimpleItem Name="Source" Value="vs0"/></tt:Source>` +
+	`<tt:Data><tt:SimpleItem Name="State" Value="true"/></tt:Data></tt:Message></wsnt:Message></wsnt:NotificationMessage>`
+
+func notifyEnvelope(messages string) string {
+	return `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2"><s:Body><wsnt:Notify>` +
+		messages + `</wsnt:Notify></s:Body></s:Envelope>`
+}
+
+// postNotify post body to the listener at path, return the status
+func postNotify(listener *NotifyListener, method, path, body string) int {
+	recorder := httptest.NewRecorder()
+	listener.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder.Code
+}
+
+// consumerPath return the path of the consumer address of the Subscribe request
+func consumerPath(request string) string {
+	start := strings.Index(request, "/onvif/notify/")
+	if start < 0 {
+		return ""
+	}
+	return request[start : start+strings.Index(request[start:], "<")]
+}
+
+func TestNotifyListener(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"Renew":       `<wsnt:RenewResponse><wsnt:TerminationTime>2026-10-16T10:01:00Z</wsnt:TerminationTime></wsnt:RenewResponse>`,
+		"Unsubscribe": `<wsnt:UnsubscribeResponse/>`,
+	}, ServiceEvents)
+	fake.set("Subscribe", `<wsnt:SubscribeResponse><wsnt:SubscriptionReference><wsa:Address>`+dev.endpoints[ServiceEvents]+`/subscription</wsa:Address></wsnt:SubscriptionReference></wsnt:SubscribeResponse>`)
+	engine := testEngine(1)
+	var mutex sync.Mutex
+	var events []Event
+	var failures int
+	engine.Handle(func(ev Event) {
+		mutex.Lock()
+		events = append(events, ev)
+		mutex.Unlock()
+	})
+	listener := NewNotifyListener(engine, "http://10.1.1.5:8080/onvif/notify/")
+	listener.TerminationTime = 100 * time.Millisecond
+	listener.OnError = func(dev *Device, err error) {
+		mutex.Lock()
+		failures++
+		mutex.Unlock()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go listener.Run(ctx)
+	subscribeCtx, unsubscribe := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- listener.Subscribe(subscribeCtx, dev) }()
+	eventually(t, "subscription", func() bool { return len(fake.sent("Subscribe")) == 1 })
+	path := consumerPath(fake.sent("Subscribe")[0])
+	if len(path) != len("/onvif/notify/")+32 {
+		t.Fatalf("consumer path %q", path)
+	}
+
+	/* 未知订阅路径与非POST请求被拒绝 */
+	if status := postNotify(listener, http.MethodPost, "/onvif/notify/guess", notifyEnvelope(notificationMessage)); status != http.StatusNotFound {
+		t.Fatalf("status %d for an unknown subscription", status)
+	}
+	if status := postNotify(listener, http.MethodGet, path, ""); status != http.StatusMethodNotAllowed {
+		t.Fatalf("status %d for GET", status)
+	}
+	if status := postNotify(listener, http.MethodPost, path, "<s:Envelope>"); status != http.StatusBadRequest {
+		t.Fatalf("status %d for an invalid message", status)
+	}
+	if status := postNotify(listener, http.MethodPost, path, notifyEnvelope(notificationMessage)); status != http.StatusAccepted {
+		t.Fatalf("status %d", status)
+	}
+	eventually(t, "event delivered", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(events) == 1
+	})
+	mutex.Lock()
+	if events[0].Device != dev.Params.Ipddr || events[0].Topic != "tns1:VideoSource/MotionAlarm" || events[0].Source["Source"] != "vs0" || failures != 1 {
+		t.Fatalf("events %+v, %d failures", events, failures)
+	}
+	mutex.Unlock()
+	if stats := listener.Stats(); stats.Delivered != 1 || stats.Dropped != 0 {
+		t.Fatalf("stats %+v", stats)
+	}
+
+	/* 过半终止时间后续订,续订失败时重新订阅 */
+	eventually(t, "renewal", func() bool { return len(fake.sent("Renew")) > 0 })
+	fake.set("Renew", eventFault("wsrf-rw:ResourceUnknownFault"))
+	eventually(t, "new subscription", func() bool { return len(fake.sent("Subscribe")) > 1 })
+	if subscribed := fake.sent("Subscribe"); consumerPath(subscribed[1]) != path {
+		t.Fatalf("consumer path %q changed", consumerPath(subscribed[1]))
+	}
+
+	unsubscribe()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("subscription ended with %v", err)
+	}
+	if requests := fake.sent("Unsubscribe"); len(requests) != 1 || !strings.HasPrefix(requests[0], "/onvif/"+ServiceEvents+"/subscription ") {
+		t.Fatalf("unsubscribe requests %q", requests)
+	}
+	if status := postNotify(listener, http.MethodPost, path, notifyEnvelope(notificationMessage)); status != http.StatusNotFound {
+		t.Fatalf("status %d after the subscription ended", status)
+	}
+}
+
+func TestNotifyListenerBackpressure(t *testing.T) {
+	engine := testEngine(1)
+	engine.Backpressure = BackpressureDropNewest
+	listener := NewNotifyListener(engine, "http://10.1.1.5:8080/onvif/notify")
+	listener.QueueSize = 1
+	listener.subscriptions["token"] = newDevice(DeviceParams{Ipddr: "192.168.1.10"})
+	/* 未运行Run时队列已满,新通知被丢弃但仍应答设备 */
+	if status := postNotify(listener, http.MethodPost, "/onvif/notify/token", notifyEnvelope(notificationMessage+notificationMessage)); status != http.StatusAccepted {
+		t.Fatalf("status %d", status)
+	}
+	if stats := listener.Stats(); stats.Delivered != 1 || stats.Dropped != 1 {
+		t.Fatalf("stats %+v", stats)
+	}
+}
@@ -0,0 +1,230 @@
+package onvif
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Kinds of TrackEvent
+const (
+	TrackStarted = "started"
+	TrackUpdated = "updated"
+	TrackEnded   = "ended"
+)
+
+// TrackPoint position of a tracked object in a frame
+type TrackPoint struct {
+	Time time.Time
+	Box  onvif.Rectangle
+}
+
+// Track successive positions of one object of the metadata of a device
+type Track struct {
+	Device string
+	Source string
+	// ObjectID identifier of the object, the latest one when it was renamed
+	ObjectID   int
+	Class      string
+	Likelihood float64
+	Started    time.Time
+	Updated    time.Time
+	// Box smoothed bounding box of the object
+	Box onvif.Rectangle
+	// Points raw boxes of the object, the oldest first
+	Points []TrackPoint
+}
+
+// TrackEvent change of a track reported by a Tracker
+type TrackEvent struct {
+	// Kind TrackStarted, TrackUpdated or TrackEnded
+	Kind  string
+	Track Track
+}
+
+// Tracker accumulate the objects of the metadata of devices into tracks:
+//
+//	tracker := onvif.NewTracker()
+//	tracker.Handle(func(ev onvif.TrackEvent) { ... })
+//	for {
+//		metadata, err := reader.Next(ctx)
+//		...
+//		tracker.Update(metadata)
+//	}
+//
+// A track ends when the device removes or deletes its object, or when the
+// object is not seen for Timeout.
+type Tracker struct {
+	// Timeout a track not updated for Timeout ends, 0 means 5s
+	Timeout time.Duration
+	// Smoothing weight of a new box in the smoothed box, from 0 (exclusive)
+	// to 1 for no smoothing; 0 means 0.5
+	Smoothing float64
+	// MaxPoints number of points kept per track, 0 means 100
+	MaxPoints int
+
+	mutex    sync.Mutex
+	handlers []func(TrackEvent)
+	tracks   map[trackKey]*Track
+}
+
+type trackKey struct {
+	device string
+	source string
+	object int
+}
+
+// NewTracker return a tracker without track
+func NewTracker() *Tracker {
+	return &Tracker{tracks: make(map[trackKey]*Track)}
+}
+
+// Handle register a handler called for every change of a track, from the
+// goroutine calling Update or Expire. Handlers must not block.
+func (tracker *Tracker) Handle(handler func(TrackEvent)) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.handlers = append(tracker.handlers, handler)
+}
+
+// Update accumulate the frames of metadata
+func (tracker *Tracker) Update(metadata Metadata) {
+	tracker.mutex.Lock()
+	if tracker.tracks == nil {
+		tracker.tracks = make(map[trackKey]*Track)
+	}
+	var events []TrackEvent
+	for _, frame := range metadata.Frames {
+		events = append(events, tracker.frame(metadata.Device, frame)...)
+		events = append(events, tracker.expire(frame.Time, metadata.Device)...)
+	}
+	handlers := tracker.handlers
+	tracker.mutex.Unlock()
+	emitTrackEvents(handlers, events)
+}
+
+// Expire end the tracks not updated for Timeout at now, for streams which
+// stopped sending frames. now is compared with the UtcTime of the frames,
+// from the clock of the devices.
+func (tracker *Tracker) Expire(now time.Time) {
+	tracker.mutex.Lock()
+	events := tracker.expire(now, "")
+	handlers := tracker.handlers
+	tracker.mutex.Unlock()
+	emitTrackEvents(handlers, events)
+}
+
+// Tracks return a copy of the tracks in progress
+func (tracker *Tracker) Tracks() []Track {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracks := make([]Track, 0, len(tracker.tracks))
+	for _, track := range tracker.tracks {
+		tracks = append(tracks, track.copy())
+	}
+	return tracks
+}
+
+func emitTrackEvents(handlers []func(TrackEvent), events []TrackEvent) {
+	for _, ev := range events {
+		for _, handler := range handlers {
+			handler(ev)
+		}
+	}
+}
+
+func (track *Track) copy() Track {
+	copied := *track
+	copied.Points = append([]TrackPoint(nil), track.Points...)
+	return copied
+}
+
+func (tracker *Tracker) frame(device string, frame MetadataFrame) []TrackEvent {
+	var events []TrackEvent
+	/* 先处理重命名与删除,再合并本帧目标 */
+	for from, to := range frame.Renamed {
+		key := trackKey{device, frame.Source, from}
+		if track, ok := tracker.tracks[key]; ok {
+			delete(tracker.tracks, key)
+			track.ObjectID = to
+			tracker.tracks[trackKey{device, frame.Source, to}] = track
+		}
+	}
+	for _, id := range frame.Deleted {
+		events = tracker.end(events, trackKey{device, frame.Source, id})
+	}
+	for _, object := range frame.Objects {
+		key := trackKey{device, frame.Source, object.ID}
+		if object.Removed {
+			events = tracker.end(events, key)
+			continue
+		}
+		track, ok := tracker.tracks[key]
+		kind := TrackUpdated
+		if !ok {
+			track = &Track{Device: device, Source: frame.Source, ObjectID: object.ID, Started: frame.Time, Box: object.BoundingBox}
+			tracker.tracks[key] = track
+			kind = TrackStarted
+		} else {
+			track.Box = smoothBox(track.Box, object.BoundingBox, tracker.smoothing())
+		}
+		if object.Class != "" {
+			track.Class, track.Likelihood = object.Class, object.Likelihood
+		}
+		track.Updated = frame.Time
+		track.Points = append(track.Points, TrackPoint{Time: frame.Time, Box: object.BoundingBox})
+		if max := tracker.maxPoints(); len(track.Points) > max {
+			track.Points = append(track.Points[:0], track.Points[len(track.Points)-max:]...)
+		}
+		events = append(events, TrackEvent{Kind: kind, Track: track.copy()})
+	}
+	return events
+}
+
+func (tracker *Tracker) end(events []TrackEvent, key trackKey) []TrackEvent {
+	track, ok := tracker.tracks[key]
+	if !ok {
+		return events
+	}
+	delete(tracker.tracks, key)
+	return append(events, TrackEvent{Kind: TrackEnded, Track: track.copy()})
+}
+
+// expire end the tracks of device, every device when empty, not updated
+// for Timeout at now
+func (tracker *Tracker) expire(now time.Time, device string) []TrackEvent {
+	var events []TrackEvent
+	timeout := durationOr(tracker.Timeout, 5*time.Second)
+	for key, track := range tracker.tracks {
+		if (device == "" || key.device == device) && now.Sub(track.Updated) > timeout {
+			events = tracker.end(events, key)
+		}
+	}
+	return events
+}
+
+func (tracker *Tracker) smoothing() float64 {
+	if tracker.Smoothing <= 0 || tracker.Smoothing > 1 {
+		return 0.5
+	}
+	return tracker.Smoothing
+}
+
+func (tracker *Tracker) maxPoints() int {
+	if tracker.MaxPoints <= 0 {
+		return 100
+	}
+	return tracker.MaxPoints
+}
+
+// smoothBox exponential moving average of the boxes
+func smoothBox(previous, box onvif.Rectangle, weight float64) onvif.Rectangle {
+	mix := func(a, b float64) float64 { return a + (b-a)*weight }
+	return onvif.Rectangle{
+		Bottom: mix(previous.Bottom, box.Bottom),
+		Top:    mix(previous.Top, box.Top),
+		Right:  mix(previous.Right, box.Right),
+		Left:   mix(previous.Left, box.Left),
+	}
+}
@@ -0,0 +1,63 @@
+package onvif
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+	tracker.MaxPoints = 2
+	var events []TrackEvent
+	tracker.Handle(func(ev TrackEvent) { events = append(events, ev) })
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	box := func(left float64) onvif.Rectangle {
+		return onvif.Rectangle{Left: left, Right: left + 0.2, Top: 0.2, Bottom: 0}
+	}
+	frame := func(offset time.Duration, objects ...MetadataObject) MetadataFrame {
+		return MetadataFrame{Time: start.Add(offset), Source: "vsc0", Objects: objects}
+	}
+
+	tracker.Update(Metadata{Device: "camera", Frames: []MetadataFrame{
+		frame(0, MetadataObject{ID: 1, BoundingBox: box(0), Class: "Human", Likelihood: 0.8}, MetadataObject{ID: 2, BoundingBox: box(-1)}),
+		frame(time.Second, MetadataObject{ID: 1, BoundingBox: box(0.4)}),
+		frame(2*time.Second, MetadataObject{ID: 1, BoundingBox: box(0.8)}),
+	}})
+	if len(events) != 4 || events[0].Kind != TrackStarted || events[1].Kind != TrackStarted || events[3].Kind != TrackUpdated {
+		t.Fatalf("events %+v", events)
+	}
+	/* 平滑框为指数移动平均,仅保留最近的点 */
+	track := events[3].Track
+	if track.Class != "Human" || track.Box.Left != 0.5 || len(track.Points) != 2 || track.Points[0].Box.Left != 0.4 || !track.Started.Equal(start) {
+		t.Fatalf("track %+v", track)
+	}
+
+	/* 重命名后以新标识继续,删除帧结束轨迹 */
+	events = nil
+	renamed := frame(3*time.Second, MetadataObject{ID: 5, BoundingBox: box(0.8)})
+	renamed.Renamed = map[int]int{1: 5}
+	renamed.Deleted = []int{2}
+	tracker.Update(Metadata{Device: "camera", Frames: []MetadataFrame{renamed}})
+	if len(events) != 2 || events[0].Kind != TrackEnded || events[0].Track.ObjectID != 2 || events[1].Kind != TrackUpdated || events[1].Track.ObjectID != 5 {
+		t.Fatalf("events %+v", events)
+	}
+	if tracks := tracker.Tracks(); len(tracks) != 1 || tracks[0].ObjectID != 5 {
+		t.Fatalf("tracks %+v", tracks)
+	}
+
+	events = nil
+	tracker.Expire(start.Add(5 * time.Second))
+	if len(events) != 0 {
+		t.Fatalf("events %+v before the timeout", events)
+	}
+	tracker.Expire(start.Add(9 * time.Second))
+	if len(events) != 1 || events[0].Kind != TrackEnded || len(tracker.Tracks()) != 0 {
+		t.Fatalf("events %+v after the timeout", events)
+	}
+	tracker.Update(Metadata{Device: "camera", Frames: []MetadataFrame{frame(10*time.Second, MetadataObject{ID: 9, Removed: true})}})
+	if len(events) != 1 {
+		t.Fatalf("events %+v for a removed unknown object", events)
+	}
+}
@@ -0,0 +1,194 @@
+package onvif
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/soap"
+)
+
+// OperationRecord state changing call made to a device, as recorded by an
+// AuditSink
+type OperationRecord struct {
+	Time time.Time `json:"time"`
+	// Caller who made the call, set with WithCaller, the user name of the
+	// device otherwise
+	Caller    string `json:"caller"`
+	Device    string `json:"device"`
+	Operation string `json:"operation"`
+	// ParametersHash HMAC-SHA256 of the XML encoding of the request keyed by
+	// the sink, see AuditKeyer, hex encoded, identifying the parameters
+	// without disclosing them. Passwords and keys are redacted before hashing.
+	ParametersHash string        `json:"parametersHash"`
+	Duration       time.Duration `json:"duration"`
+	// Error error of the call, empty on success
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink receive the state changing calls made to the devices, the calls
+// the operation policy classifies above RoleReadOnly. RecordOperation is
+// called once the call returned, possibly concurrently.
+type AuditSink interface {
+	RecordOperation(OperationRecord)
+}
+
+// AuditKeyer optional interface of an AuditSink giving the HMAC key of the
+// ParametersHash of its records. Without a key the hash is a plain SHA-256,
+// which may be reversed for parameters with few possible values by hashing
+// every candidate.
+type AuditKeyer interface {
+	AuditKey() []byte
+}
+
+// AuditSinkFunc function used as an AuditSink
+type AuditSinkFunc func(OperationRecord)
+
+// RecordOperation implements AuditSink
+func (fn AuditSinkFunc) RecordOperation(record OperationRecord) {
+	fn(record)
+}
+
+// JSONAuditSink write each record as a line of JSON, e.g. to an append-only
+// file
+type JSONAuditSink struct {
+	mutex sync.Mutex
+	w     io.Writer
+	// OnError called with the errors writing the records
+	OnError func(error)
+	// Key HMAC key of the ParametersHash, kept secret with the audit trail
+	Key []byte
+}
+
+// NewJSONAuditSink return a sink writing to w
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{w: w}
+}
+
+// RecordOperation implements AuditSink
+func (sink *JSONAuditSink) RecordOperation(record OperationRecord) {
+	line, err := json.Marshal(record)
+	if err == nil {
+		sink.mutex.Lock()
+		_, err = sink.w.Write(append(line, '\n'))
+		sink.mutex.Unlock()
+	}
+	if err != nil && sink.OnError != nil {
+		sink.OnError(err)
+	}
+}
+
+// AuditKey implements AuditKeyer
+func (sink *JSONAuditSink) AuditKey() []byte {
+	return sink.Key
+}
+
+type callerKey struct{}
+
+// WithCaller return a context naming caller as the author of the calls made
+// with it in the OperationRecords, e.g. the user of a management tool
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// operationAudit record of a call in progress, nil when the call is not
+// audited
+type operationAudit struct {
+	sink   AuditSink
+	record OperationRecord
+}
+
+// auditOperation start the record of a call of method, nil when the device
+// has no AuditSink or the operation does not change the state of the device
+func (dev Device) auditOperation(ctx context.Context, operation string, method interface{}) *operationAudit {
+	sink := dev.Params.AuditSink
+	if sink == nil || OperationRole(operation) <= RoleReadOnly {
+		return nil
+	}
+	caller, _ := ctx.Value(callerKey{}).(string)
+	if caller == "" {
+		caller = dev.Params.Username
+	}
+	record := OperationRecord{Time: time.Now(), Caller: caller, Device: dev.Params.Ipddr, Operation: operation}
+	if raw, ok := method.(rawMessage); ok {
+		record.ParametersHash = parametersHash(sink, raw.body)
+	} else if parameters, err := xml.Marshal(method); err == nil {
+		record.ParametersHash = parametersHash(sink, parameters)
+	}
+	return &operationAudit{sink: sink, record: record}
+}
+
+/* 摘要前清除的密钥,密码等见soap.Scrub */
+var auditSecretRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`(<(?:\w+:)?PSK(?:\s[^>]*)?>)[\s\S]*?(</(?:\w+:)?PSK>)`),
+	regexp.MustCompile(`(<(?:\w+:)?PrivateKey(?:\s[^>]*)?>)[\s\S]*?(</(?:\w+:)?PrivateKey>)`),
+}
+
+// parametersHash hash of the parameters of a request, the secrets redacted,
+// keyed with the key of the sink when it has one
+func parametersHash(sink AuditSink, parameters []byte) string {
+	redacted := soap.Scrub(string(parameters))
+	for _, re := range auditSecretRegexps {
+		redacted = re.ReplaceAllString(redacted, "${1}REDACTED${2}")
+	}
+	var mac hash.Hash
+	if keyer, ok := sink.(AuditKeyer); ok && len(keyer.AuditKey()) > 0 {
+		mac = hmac.New(sha256.New, keyer.AuditKey())
+	} else {
+		mac = sha256.New()
+	}
+	mac.Write([]byte(redacted))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// auditedKey context key of the calls whose caller records the audit and
+// the configuration change itself, once the fault of the response is known
+type auditedKey struct{}
+
+// withAudited mark the calls made with ctx as audited by their caller
+func withAudited(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditedKey{}, true)
+}
+
+// callMethodDo send method to endpoint, the single path of the calls to the
+// devices. The call is recorded with the AuditSink and the cached profiles
+// are dropped after a configuration change, unless the caller does it, see
+// withAudited; the call is taken as failed on an error status, its fault
+// being left in the response.
+func (dev Device) callMethodDo(ctx context.Context, endpoint string, method interface{}, headers ...[]byte) (*http.Response, error) {
+	if audited, _ := ctx.Value(auditedKey{}).(bool); audited {
+		return dev.failoverCall(ctx, endpoint, method, headers...)
+	}
+	operation := operationName(method)
+	audit := dev.auditOperation(ctx, operation, method)
+	resp, err := dev.failoverCall(ctx, endpoint, method, headers...)
+	result := err
+	if err == nil && resp.StatusCode >= http.StatusMultipleChoices {
+		result = fmt.Errorf("response status %s", resp.Status)
+	}
+	audit.done(result)
+	dev.configurationChanged(operation, result)
+	return resp, err
+}
+
+// done send the record of the call to the sink
+func (audit *operationAudit) done(err error) {
+	if audit == nil {
+		return
+	}
+	audit.record.Duration = time.Since(audit.record.Time)
+	if err != nil {
+		audit.record.Error = err.Error()
+	}
+	audit.sink.RecordOperation(audit.record)
+}
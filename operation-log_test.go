@@ -0,0 +1,66 @@
+package onvif
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+func TestParametersHashRedacted(t *testing.T) {
+	user := func(password string) device.SetUser {
+		return device.SetUser{User: onvif.User{Username: "operator", Password: password, UserLevel: "Operator"}}
+	}
+	sink := &JSONAuditSink{}
+	parameters, _ := xml.Marshal(user("1234"))
+	/* 弱密码不能通过穷举明文摘要得到 */
+	sum := sha256.Sum256(parameters)
+	if hash := parametersHash(sink, parameters); hash == hex.EncodeToString(sum[:]) {
+		t.Fatal("hash of the parameters with the password")
+	}
+	other, _ := xml.Marshal(user("5678"))
+	if parametersHash(sink, parameters) != parametersHash(sink, other) {
+		t.Fatal("hash depends on the password")
+	}
+	psk, _ := xml.Marshal(onvif.Dot11SecurityConfiguration{Mode: "PSK", PSK: &onvif.Dot11PSKSet{Key: "00112233"}})
+	if !strings.Contains(string(psk), ">00112233<") {
+		t.Fatalf("security configuration %s", psk)
+	}
+	if parametersHash(sink, psk) != parametersHash(sink, []byte(strings.Replace(string(psk), "00112233", "ffeeddcc", 1))) {
+		t.Fatal("hash depends on the pre-shared key")
+	}
+	/* 不同密钥的摘要不同 */
+	keyed := parametersHash(&JSONAuditSink{Key: []byte("k1")}, parameters)
+	if keyed == parametersHash(sink, parameters) || keyed == parametersHash(&JSONAuditSink{Key: []byte("k2")}, parameters) {
+		t.Fatal("hash not keyed by the sink")
+	}
+}
+
+func TestAuditOperation(t *testing.T) {
+	var mutex sync.Mutex
+	var records []OperationRecord
+	dev := cannedDevice(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><tds:SetUserResponse xmlns:tds="http://www.onvif.org/ver10/device/wsdl"/></s:Body></s:Envelope>`)
+	dev.Params.Username = "admin"
+	dev.Params.AuditSink = AuditSinkFunc(func(record OperationRecord) {
+		mutex.Lock()
+		records = append(records, record)
+		mutex.Unlock()
+	})
+	ctx := WithCaller(context.Background(), "alice")
+	if err := dev.CallMethodInterfaceContext(ctx, device.SetUser{User: onvif.User{Username: "operator", Password: "1234"}}, &device.SetUserResponse{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	/* 只读调用不记录 */
+	dev.CallMethodInterfaceContext(ctx, device.GetUsers{}, &device.GetUsersResponse{}, "")
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(records) != 1 || records[0].Caller != "alice" || records[0].Operation != "SetUser" || records[0].ParametersHash == "" || records[0].Error != "" {
+		t.Fatalf("records %+v", records)
+	}
+}
@@ -0,0 +1,182 @@
+package onvif
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// PasswordPolicy rules of the passwords generated by RotatePasswords
+type PasswordPolicy struct {
+	// Length of the passwords, 0 means 20
+	Length int
+	// Symbols add punctuation to the alphabet, some devices refuse it
+	Symbols bool
+	// AllOrNothing restore the previous password of every rotated device
+	// when one device of the fleet fails
+	AllOrNothing bool
+}
+
+const (
+	passwordLetters = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+	passwordSymbols = "!#%+-.:=?@_"
+)
+
+// GeneratePassword return a random password following policy, it holds at
+// least one upper case letter, one lower case letter and one digit
+func GeneratePassword(policy PasswordPolicy) (string, error) {
+	length := policy.Length
+	if length <= 0 {
+		length = 20
+	}
+	if length < 8 {
+		return "", errors.New("password length must be at least 8")
+	}
+	alphabet := passwordLetters
+	if policy.Symbols {
+		alphabet += passwordSymbols
+	}
+	for {
+		password := make([]byte, length)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+			if err != nil {
+				return "", err
+			}
+			password[i] = alphabet[n.Int64()]
+		}
+		/* 保证包含大小写字母和数字 */
+		var upper, lower, digit bool
+		for _, c := range password {
+			switch {
+			case c >= 'A' && c <= 'Z':
+				upper = true
+			case c >= 'a' && c <= 'z':
+				lower = true
+			case c >= '0' && c <= '9':
+				digit = true
+			}
+		}
+		if upper && lower && digit {
+			return string(password), nil
+		}
+	}
+}
+
+// RotationResult outcome of the password rotation of one device, the
+// mapping written for the credential vault
+type RotationResult struct {
+	Device     string `json:"device"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Rotated    bool   `json:"rotated"`
+	RolledBack bool   `json:"rolledBack,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	previous string
+}
+
+// setPassword change the password of the configured user, keeping its level
+func (dev *Device) setPassword(ctx context.Context, password string) error {
+	users := device.GetUsersResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetUsers{}, &users, ""); err != nil {
+		return err
+	}
+	for _, user := range users.User {
+		if user.Username != dev.Params.Username {
+			continue
+		}
+		request := device.SetUsers{User: []onvif.User{{
+			Username:  user.Username,
+			Password:  password,
+			UserLevel: user.UserLevel,
+		}}}
+		return dev.CallMethodInterfaceContext(ctx, request, &device.SetUsersResponse{}, "")
+	}
+	return fmt.Errorf("user %s not found on device", dev.Params.Username)
+}
+
+// RotatePassword set a new password generated by policy for the configured
+// user. The password is verified by authenticating again, when that fails the
+// previous password is restored. dev uses the new password on success.
+func (dev *Device) RotatePassword(ctx context.Context, policy PasswordPolicy) RotationResult {
+	result := RotationResult{Device: dev.Params.Ipddr, Username: dev.Params.Username, previous: dev.Params.Password}
+	password, err := GeneratePassword(policy)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err := dev.setPassword(ctx, password); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	/* 使用新密码重新认证 */
+	probe := dev.withCredential(UserCredential{Username: dev.Params.Username, Password: password})
+	if err := probe.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &device.GetDeviceInformationResponse{}, ""); err != nil {
+		result.Error = fmt.Sprintf("verify new password: %v", err)
+		/* 设备可能已接受新密码,两个密码都尝试回滚 */
+		if probe.setPassword(ctx, result.previous) == nil || dev.setPassword(ctx, result.previous) == nil {
+			result.RolledBack = true
+		}
+		return result
+	}
+	dev.Params.Password = password
+	result.Password = password
+	result.Rotated = true
+	return result
+}
+
+// rollbackPassword restore the previous password of a rotated device
+func (dev *Device) rollbackPassword(ctx context.Context, result *RotationResult) {
+	if err := dev.setPassword(ctx, result.previous); err != nil {
+		result.Error = fmt.Sprintf("rollback: %v", err)
+		return
+	}
+	dev.Params.Password = result.previous
+	result.Password = ""
+	result.Rotated = false
+	result.RolledBack = true
+	result.Error = "rolled back, another device failed"
+}
+
+// RotatePasswords rotate the password of every device of the fleet, see
+// RotatePassword. With policy.AllOrNothing the rotated devices are restored
+// when any device fails. A PartialError is returned with the results when
+// ctx ended first.
+func (fleet *Fleet) RotatePasswords(ctx context.Context, policy PasswordPolicy) ([]RotationResult, error) {
+	results := make([]RotationResult, len(fleet.Devices))
+	err := fleet.each(ctx, func(i int, dev *Device) {
+		results[i] = dev.RotatePassword(ctx, policy)
+	})
+	if !policy.AllOrNothing {
+		return results, err
+	}
+	failed := false
+	for i := range results {
+		if !results[i].Rotated {
+			failed = true
+		}
+	}
+	if failed {
+		fleet.each(ctx, func(i int, dev *Device) {
+			if results[i].Rotated {
+				dev.rollbackPassword(ctx, &results[i])
+			}
+		})
+	}
+	return results, err
+}
+
+// WriteRotationJSON write the device to credential mapping of the results
+func WriteRotationJSON(w io.Writer, results []RotationResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
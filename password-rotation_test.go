@@ -0,0 +1,61 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	password, err := GeneratePassword(PasswordPolicy{})
+	if err != nil || len(password) != 20 || strings.ContainsAny(password, passwordSymbols+"0O1lI") {
+		t.Fatalf("password %q, %v", password, err)
+	}
+	if password, err := GeneratePassword(PasswordPolicy{Length: 12, Symbols: true}); err != nil || len(password) != 12 {
+		t.Fatalf("password %q, %v", password, err)
+	}
+	if _, err := GeneratePassword(PasswordPolicy{Length: 6}); err == nil {
+		t.Fatal("short password generated")
+	}
+}
+
+func TestRotatePasswordsAllOrNothing(t *testing.T) {
+	users := `<tds:GetUsersResponse><tds:User><tt:Username>operator</tt:Username><tt:UserLevel>Operator</tt:UserLevel></tds:User></tds:GetUsersResponse>`
+	rotated, first := newScriptedDevice(t, map[string]string{
+		"GetUsers":             users,
+		"SetUsers":             `<tds:SetUsersResponse/>`,
+		"GetDeviceInformation": `<tds:GetDeviceInformationResponse/>`,
+	})
+	first.Params.Username, first.Params.Password = "operator", "previous"
+	/* 第二台设备上没有该用户 */
+	_, second := newScriptedDevice(t, map[string]string{"GetUsers": users})
+	second.Params.Username, second.Params.Password = "admin", "previous"
+
+	results, err := (&Fleet{Devices: []*Device{first, second}}).RotatePasswords(context.Background(), PasswordPolicy{AllOrNothing: true})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("results %+v, %v", results, err)
+	}
+	if results[0].Rotated || !results[0].RolledBack || results[0].Password != "" || first.Params.Password != "previous" {
+		t.Fatalf("first device %+v, password %q", results[0], first.Params.Password)
+	}
+	if results[1].Rotated || !strings.Contains(results[1].Error, "user admin not found") {
+		t.Fatalf("second device %+v", results[1])
+	}
+	/* 先设置新密码,再以原级别恢复旧密码 */
+	requests := rotated.sent("SetUsers")
+	if len(requests) != 2 || strings.Contains(requests[0], "<onvif:Password>previous</onvif:Password>") ||
+		!strings.Contains(requests[1], "<onvif:Password>previous</onvif:Password><onvif:UserLevel>Operator</onvif:UserLevel>") {
+		t.Fatalf("requests %q", requests)
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteRotationJSON(&buffer, results); err != nil {
+		t.Fatal(err)
+	}
+	var mapping []map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &mapping); err != nil || len(mapping) != 2 || mapping[0]["rolledBack"] != true || mapping[0]["username"] != "operator" {
+		t.Fatalf("mapping %s, %v", buffer.String(), err)
+	}
+}
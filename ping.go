@@ -0,0 +1,49 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+// PingTimeout timeout of Ping when ctx has no deadline
+const PingTimeout = 3 * time.Second
+
+// PingResult answer of a device to Ping
+type PingResult struct {
+	Latency time.Duration
+	// DeviceTime UTC clock of the device, zero when not reported
+	DeviceTime time.Time
+}
+
+// Ping check the device answers, with an unauthenticated GetSystemDateAndTime
+// as the ONVIF core specification requires it be served before
+// authentication. Devices rejecting the anonymous request are asked again
+// with the credentials.
+func (dev *Device) Ping(ctx context.Context) (PingResult, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, PingTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	response := device.GetSystemDateAndTimeResponse{}
+	err := dev.withCredential(UserCredential{}).CallMethodInterfaceContext(ctx, device.GetSystemDateAndTime{}, &response, "")
+	/* 设备已应答但拒绝匿名请求时,带认证信息重试 */
+	var netErr net.Error
+	if err != nil && dev.Params.Username != "" && ctx.Err() == nil && !errors.As(err, &netErr) {
+		start = time.Now()
+		err = dev.CallMethodInterfaceContext(ctx, device.GetSystemDateAndTime{}, &response, "")
+	}
+	if err != nil {
+		return PingResult{}, err
+	}
+	result := PingResult{Latency: time.Since(start)}
+	if utc := response.SystemDateAndTime.UTCDateTime; utc.Date.Year != 0 {
+		result.DeviceTime = utc.ToTime(time.UTC)
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package onvif
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetSystemDateAndTime": `<tds:GetSystemDateAndTimeResponse><tds:SystemDateAndTime><tt:DateTimeType>NTP</tt:DateTimeType><tt:UTCDateTime>` +
+			`<tt:Time><tt:Hour>10</tt:Hour><tt:Minute>30</tt:Minute><tt:Second>5</tt:Second></tt:Time><tt:Date><tt:Year>2026</tt:Year><tt:Month>10</tt:Month><tt:Day>16</tt:Day></tt:Date>` +
+			`</tt:UTCDateTime></tds:SystemDateAndTime></tds:GetSystemDateAndTimeResponse>`,
+	})
+	dev.Params.Username, dev.Params.Password = "admin", "secret"
+	ctx := context.Background()
+	result, err := dev.Ping(ctx)
+	if err != nil || !result.DeviceTime.Equal(time.Date(2026, 10, 16, 10, 30, 5, 0, time.UTC)) || result.Latency <= 0 {
+		t.Fatalf("result %+v, %v", result, err)
+	}
+	/* 匿名请求不带认证信息 */
+	if sent := fake.sent("GetSystemDateAndTime"); len(sent) != 1 || strings.Contains(sent[0], "UsernameToken") {
+		t.Fatalf("requests %q", sent)
+	}
+
+	/* 设备拒绝匿名请求时带认证信息重试 */
+	fake.set("GetSystemDateAndTime", `<s:Fault><s:Code><s:Value>s:Sender</s:Value><s:Subcode><s:Value>ter:NotAuthorized</s:Value></s:Subcode></s:Code>`+
+		`<s:Reason><s:Text>not authorized</s:Text></s:Reason></s:Fault>`)
+	if _, err := dev.Ping(ctx); err == nil {
+		t.Fatal("fault not returned")
+	}
+	if sent := fake.sent("GetSystemDateAndTime"); len(sent) != 3 || strings.Contains(sent[1], "UsernameToken") || !strings.Contains(sent[2], "UsernameToken") {
+		t.Fatalf("requests %q", sent)
+	}
+
+	/* 网络错误不重试 */
+	var mutex sync.Mutex
+	attempts := 0
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		attempts++
+		mutex.Unlock()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer down.Close()
+	dev.endpoints[ServiceDevice] = down.URL
+	if _, err := dev.Ping(ctx); err == nil {
+		t.Fatal("device down answered")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if attempts != 1 {
+		t.Fatalf("%d attempts", attempts)
+	}
+}
@@ -0,0 +1,195 @@
+package onvif
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Role of the caller of a device, allowing the operations up to its level.
+// The zero Role applies no policy.
+type Role int
+
+// Roles of the operation policy, in increasing order
+const (
+	RoleUnrestricted Role = iota
+	// RoleReadOnly reads the configuration and the state: Get, Find and the
+	// event pulling operations
+	RoleReadOnly
+	// RoleOperator changes the configuration and drives the device: PTZ,
+	// presets, profiles, recordings...
+	RoleOperator
+	// RoleAdmin reboots, resets, upgrades and changes the users, the network
+	// and the security of the device
+	RoleAdmin
+)
+
+func (role Role) String() string {
+	switch role {
+	case RoleUnrestricted:
+		return "unrestricted"
+	case RoleReadOnly:
+		return "read-only"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	}
+	return "unknown"
+}
+
+// ErrForbidden reported by PolicyError
+var ErrForbidden = errors.New("operation forbidden by policy")
+
+// PolicyError returned for an operation the role of the device does not allow
+type PolicyError struct {
+	Operation string
+	Role      Role
+	Required  Role
+}
+
+func (err *PolicyError) Error() string {
+	return fmt.Sprintf("%s requires role %s, caller is %s", err.Operation, err.Required, err.Role)
+}
+
+// Is report the error as ErrForbidden
+func (err *PolicyError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+var (
+	policyMutex sync.RWMutex
+	// operationRoles roles of the operations not following the naming rules
+	// of OperationRole
+	operationRoles = map[string]Role{
+		"PullMessages":                RoleReadOnly,
+		"Seek":                        RoleReadOnly,
+		"Renew":                       RoleReadOnly,
+		"Unsubscribe":                 RoleReadOnly,
+		"Subscribe":                   RoleReadOnly,
+		"CreatePullPointSubscription": RoleReadOnly,
+		"SetSynchronizationPoint":     RoleReadOnly,
+		"EndSearch":                   RoleReadOnly,
+		"ScanAvailableDot11Networks":  RoleReadOnly,
+
+		"SystemReboot":                  RoleAdmin,
+		"SetSystemFactoryDefault":       RoleAdmin,
+		"StartFirmwareUpgrade":          RoleAdmin,
+		"UpgradeSystemFirmware":         RoleAdmin,
+		"StartSystemRestore":            RoleAdmin,
+		"RestoreSystem":                 RoleAdmin,
+		"CreateUsers":                   RoleAdmin,
+		"DeleteUsers":                   RoleAdmin,
+		"SetUser":                       RoleAdmin,
+		"SetRemoteUser":                 RoleAdmin,
+		"GetAccessPolicy":               RoleAdmin,
+		"SetAccessPolicy":               RoleAdmin,
+		"SetSystemDateAndTime":          RoleAdmin,
+		"SetHostname":                   RoleAdmin,
+		"SetHostnameFromDHCP":           RoleAdmin,
+		"SetDNS":                        RoleAdmin,
+		"SetNTP":                        RoleAdmin,
+		"SetDynamicDNS":                 RoleAdmin,
+		"SetNetworkInterfaces":          RoleAdmin,
+		"SetNetworkProtocols":           RoleAdmin,
+		"SetNetworkDefaultGateway":      RoleAdmin,
+		"SetZeroConfiguration":          RoleAdmin,
+		"SetIPAddressFilter":            RoleAdmin,
+		"AddIPAddressFilter":            RoleAdmin,
+		"RemoveIPAddressFilter":         RoleAdmin,
+		"SetDiscoveryMode":              RoleAdmin,
+		"SetRemoteDiscoveryMode":        RoleAdmin,
+		"SetScopes":                     RoleAdmin,
+		"AddScopes":                     RoleAdmin,
+		"RemoveScopes":                  RoleAdmin,
+		"CreateCertificate":             RoleAdmin,
+		"DeleteCertificates":            RoleAdmin,
+		"LoadCertificates":              RoleAdmin,
+		"LoadCACertificates":            RoleAdmin,
+		"LoadCertificateWithPrivateKey": RoleAdmin,
+		"SetCertificatesStatus":         RoleAdmin,
+		"SetClientCertificateMode":      RoleAdmin,
+
+		"AddMediaSigningCertificateAssignment":    RoleAdmin,
+		"RemoveMediaSigningCertificateAssignment": RoleAdmin,
+	}
+)
+
+// RegisterOperationRole set the role required by operation, e.g. for the
+// operations of a vendor service
+func RegisterOperationRole(operation string, role Role) {
+	policyMutex.Lock()
+	defer policyMutex.Unlock()
+	operationRoles[operation] = role
+}
+
+// OperationRole return the role required by operation, the name of its
+// method type: the registered role, else RoleReadOnly for the Get and Find
+// operations and RoleOperator for the others
+func OperationRole(operation string) Role {
+	policyMutex.RLock()
+	role, ok := operationRoles[operation]
+	policyMutex.RUnlock()
+	switch {
+	case ok:
+		return role
+	case strings.HasPrefix(operation, "Get"), strings.HasPrefix(operation, "Find"):
+		return RoleReadOnly
+	}
+	return RoleOperator
+}
+
+// checkPolicy return a PolicyError when the role of the device does not allow
+// method
+func (dev Device) checkPolicy(method interface{}) error {
+	if dev.role == RoleUnrestricted {
+		return nil
+	}
+	operation := operationName(method)
+	if required := OperationRole(operation); required > dev.role {
+		return &PolicyError{Operation: operation, Role: dev.role, Required: required}
+	}
+	return nil
+}
+
+// operationName return the name of the operation of method, the name of its
+// type or the root element of a raw message
+func operationName(method interface{}) string {
+	if raw, ok := method.(rawMessage); ok {
+		return raw.name.Local
+	}
+	methodType := reflect.TypeOf(method)
+	if methodType.Kind() == reflect.Ptr {
+		methodType = methodType.Elem()
+	}
+	return methodType.Name()
+}
+
+// WithRole return a copy of the device whose calls are restricted to the
+// operations allowed to role, e.g. to hand a read-only device to shared
+// tooling. A restricted device can only be restricted further, the copy
+// keeps the lower of the two roles.
+func (dev *Device) WithRole(role Role) *Device {
+	restricted := *dev
+	if dev.role == RoleUnrestricted || (role != RoleUnrestricted && role < dev.role) {
+		restricted.role = role
+	}
+	return &restricted
+}
+
+// Role return the role the calls of the device are restricted to
+func (dev *Device) Role() Role {
+	return dev.role
+}
+
+// WithRole return a fleet of copies of the devices restricted to role, see
+// Device.WithRole
+func (fleet *Fleet) WithRole(role Role) *Fleet {
+	restricted := &Fleet{Devices: make([]*Device, len(fleet.Devices)), Concurrency: fleet.Concurrency}
+	for i, dev := range fleet.Devices {
+		restricted.Devices[i] = dev.WithRole(role)
+	}
+	return restricted
+}
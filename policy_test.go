@@ -0,0 +1,77 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+func TestOperationRole(t *testing.T) {
+	for operation, role := range map[string]Role{
+		"GetProfiles":         RoleReadOnly,
+		"FindRecordings":      RoleReadOnly,
+		"PullMessages":        RoleReadOnly,
+		"ContinuousMove":      RoleOperator,
+		"SetImagingSettings":  RoleOperator,
+		"SystemReboot":        RoleAdmin,
+		"SetNetworkProtocols": RoleAdmin,
+		/* 读取访问策略同样需要管理员 */
+		"GetAccessPolicy": RoleAdmin,
+	} {
+		if got := OperationRole(operation); got != role {
+			t.Errorf("%s requires %s, expected %s", operation, got, role)
+		}
+	}
+	RegisterOperationRole("AcmeWipe", RoleAdmin)
+	defer func() {
+		policyMutex.Lock()
+		delete(operationRoles, "AcmeWipe")
+		policyMutex.Unlock()
+	}()
+	if OperationRole("AcmeWipe") != RoleAdmin {
+		t.Fatal("registered role ignored")
+	}
+}
+
+func TestDeviceRole(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetSystemDateAndTime": `<tds:GetSystemDateAndTimeResponse/>`,
+		"SystemReboot":         `<tds:SystemRebootResponse><tds:Message>rebooting</tds:Message></tds:SystemRebootResponse>`,
+	})
+	ctx := context.Background()
+	readOnly := dev.WithRole(RoleReadOnly)
+	if err := readOnly.CallMethodInterfaceContext(ctx, device.GetSystemDateAndTime{}, &device.GetSystemDateAndTimeResponse{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	err := readOnly.CallMethodInterfaceContext(ctx, device.SystemReboot{}, &device.SystemRebootResponse{}, "")
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || !errors.Is(err, ErrForbidden) || *policyErr != (PolicyError{Operation: "SystemReboot", Role: RoleReadOnly, Required: RoleAdmin}) {
+		t.Fatalf("error %v", err)
+	}
+	if len(fake.sent("SystemReboot")) != 0 {
+		t.Fatal("forbidden operation sent")
+	}
+
+	/* 受限设备只能进一步受限 */
+	if role := readOnly.WithRole(RoleAdmin).Role(); role != RoleReadOnly {
+		t.Fatalf("role %s", role)
+	}
+	if role := dev.WithRole(RoleAdmin).WithRole(RoleOperator).Role(); role != RoleOperator {
+		t.Fatalf("role %s", role)
+	}
+	if role := readOnly.WithRole(RoleUnrestricted).Role(); role != RoleReadOnly {
+		t.Fatalf("role %s", role)
+	}
+	if dev.Role() != RoleUnrestricted {
+		t.Fatal("original device restricted")
+	}
+	if err := dev.WithRole(RoleAdmin).CallMethodInterfaceContext(ctx, device.SystemReboot{}, &device.SystemRebootResponse{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	fleet := (&Fleet{Devices: []*Device{dev}}).WithRole(RoleOperator)
+	if fleet.Devices[0].Role() != RoleOperator || fleet.Devices[0] == dev {
+		t.Fatalf("fleet %+v", fleet.Devices)
+	}
+}
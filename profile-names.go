@@ -0,0 +1,309 @@
+package onvif
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Errors of ProfileNames
+var (
+	ErrUnknownProfileName = errors.New("unknown profile name")
+	ErrProfileNotFound    = errors.New("no media profile matches the profile name")
+)
+
+// ProfileNameRecord stable name of a media profile of a device, with the
+// attributes used to find the profile again when its token changed, e.g.
+// after a factory reset
+type ProfileNameRecord struct {
+	// Device address of the device, as in DeviceParams.Ipddr
+	Device string `json:"device"`
+	Name   string `json:"name"`
+	Token  string `json:"token"`
+
+	ProfileName string `json:"profileName,omitempty"`
+	// Index position of the profile in GetProfiles
+	Index       int    `json:"index"`
+	VideoSource string `json:"videoSource,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+}
+
+// ProfileNameStore persistence hook of ProfileNames
+type ProfileNameStore interface {
+	LoadProfileNames() ([]ProfileNameRecord, error)
+	SaveProfileName(record ProfileNameRecord) error
+	DeleteProfileName(device, name string) error
+}
+
+// ProfileNames map user defined names to the profile tokens of the devices,
+// so the configuration of recorders and analytics referring to the names
+// survives the devices changing their tokens
+type ProfileNames struct {
+	Store ProfileNameStore
+
+	mutex   sync.Mutex
+	records map[string]map[string]ProfileNameRecord
+}
+
+// NewProfileNames return a mapping persisted in store, nil keeps it in memory
+func NewProfileNames(store ProfileNameStore) *ProfileNames {
+	return &ProfileNames{Store: store}
+}
+
+// load read the store once, the caller holds the mutex
+func (names *ProfileNames) load() error {
+	if names.records != nil {
+		return nil
+	}
+	records := make(map[string]map[string]ProfileNameRecord)
+	if names.Store != nil {
+		stored, err := names.Store.LoadProfileNames()
+		if err != nil {
+			return err
+		}
+		for _, record := range stored {
+			if records[record.Device] == nil {
+				records[record.Device] = make(map[string]ProfileNameRecord)
+			}
+			records[record.Device][record.Name] = record
+		}
+	}
+	names.records = records
+	return nil
+}
+
+// save keep and persist record, the caller holds the mutex
+func (names *ProfileNames) save(record ProfileNameRecord) error {
+	if names.records[record.Device] == nil {
+		names.records[record.Device] = make(map[string]ProfileNameRecord)
+	}
+	names.records[record.Device][record.Name] = record
+	if names.Store == nil {
+		return nil
+	}
+	return names.Store.SaveProfileName(record)
+}
+
+// Assign name the profile of token on the device
+func (names *ProfileNames) Assign(ctx context.Context, dev *Device, name, token string) error {
+	profiles := media.GetProfilesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media.GetProfiles{}, &profiles, ""); err != nil {
+		return err
+	}
+	for i, profile := range profiles.Profiles {
+		if string(profile.Token) == token {
+			names.mutex.Lock()
+			defer names.mutex.Unlock()
+			if err := names.load(); err != nil {
+				return err
+			}
+			return names.save(newProfileNameRecord(dev.Params.Ipddr, name, i, profile))
+		}
+	}
+	return ErrProfileNotFound
+}
+
+// Remove forget name on the device
+func (names *ProfileNames) Remove(dev *Device, name string) error {
+	names.mutex.Lock()
+	defer names.mutex.Unlock()
+	if err := names.load(); err != nil {
+		return err
+	}
+	delete(names.records[dev.Params.Ipddr], name)
+	if names.Store == nil {
+		return nil
+	}
+	return names.Store.DeleteProfileName(dev.Params.Ipddr, name)
+}
+
+// Names return the names of the device and their last known tokens
+func (names *ProfileNames) Names(dev *Device) (map[string]string, error) {
+	names.mutex.Lock()
+	defer names.mutex.Unlock()
+	if err := names.load(); err != nil {
+		return nil, err
+	}
+	tokens := make(map[string]string, len(names.records[dev.Params.Ipddr]))
+	for name, record := range names.records[dev.Params.Ipddr] {
+		tokens[name] = record.Token
+	}
+	return tokens, nil
+}
+
+// Resolve return the current token of the profile called name on the device.
+// When the recorded token is gone the profile most alike the recorded one and
+// not claimed by another name is chosen, and the mapping is updated.
+func (names *ProfileNames) Resolve(ctx context.Context, dev *Device, name string) (string, error) {
+	names.mutex.Lock()
+	err := names.load()
+	record, ok := names.records[dev.Params.Ipddr][name]
+	names.mutex.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrUnknownProfileName
+	}
+	profiles := media.GetProfilesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media.GetProfiles{}, &profiles, ""); err != nil {
+		return "", err
+	}
+	for _, profile := range profiles.Profiles {
+		if string(profile.Token) == record.Token {
+			return record.Token, nil
+		}
+	}
+
+	names.mutex.Lock()
+	defer names.mutex.Unlock()
+	/* 其他名称仍有效的token不参与匹配 */
+	claimed := make(map[string]bool)
+	for other, otherRecord := range names.records[dev.Params.Ipddr] {
+		if other != name {
+			claimed[otherRecord.Token] = true
+		}
+	}
+	best, bestScore := -1, 0
+	for i, profile := range profiles.Profiles {
+		if claimed[string(profile.Token)] {
+			continue
+		}
+		if score := record.score(i, profile); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	/* 仅序号相同不足以认定为同一profile */
+	if best < 0 || bestScore < 2 {
+		return "", ErrProfileNotFound
+	}
+	updated := newProfileNameRecord(dev.Params.Ipddr, name, best, profiles.Profiles[best])
+	if err := names.save(updated); err != nil {
+		return "", err
+	}
+	return updated.Token, nil
+}
+
+func newProfileNameRecord(device, name string, index int, profile onvif.Profile) ProfileNameRecord {
+	encoder := profile.VideoEncoderConfiguration
+	return ProfileNameRecord{
+		Device:      device,
+		Name:        name,
+		Token:       string(profile.Token),
+		ProfileName: string(profile.Name),
+		Index:       index,
+		VideoSource: string(profile.VideoSourceConfiguration.SourceToken),
+		Encoding:    string(encoder.Encoding),
+		Width:       int(encoder.Resolution.Width),
+		Height:      int(encoder.Resolution.Height),
+	}
+}
+
+// score likeness of the profile at index to the recorded one
+func (record ProfileNameRecord) score(index int, profile onvif.Profile) int {
+	score := 0
+	if record.ProfileName != "" && string(profile.Name) == record.ProfileName {
+		score += 4
+	}
+	if record.VideoSource != "" && string(profile.VideoSourceConfiguration.SourceToken) == record.VideoSource {
+		score += 2
+	}
+	encoder := profile.VideoEncoderConfiguration
+	if record.Encoding != "" && string(encoder.Encoding) == record.Encoding {
+		score++
+	}
+	if record.Width != 0 && int(encoder.Resolution.Width) == record.Width && int(encoder.Resolution.Height) == record.Height {
+		score++
+	}
+	if index == record.Index {
+		score++
+	}
+	return score
+}
+
+// FileProfileNameStore ProfileNameStore keeping the records in a JSON file
+type FileProfileNameStore struct {
+	Path string
+
+	mutex sync.Mutex
+}
+
+// NewFileProfileNameStore return a store backed by the file at path
+func NewFileProfileNameStore(path string) *FileProfileNameStore {
+	return &FileProfileNameStore{Path: path}
+}
+
+// LoadProfileNames read the records, a missing file holds no record
+func (store *FileProfileNameStore) LoadProfileNames() ([]ProfileNameRecord, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.read()
+}
+
+// SaveProfileName insert or replace the record of the name of the device
+func (store *FileProfileNameStore) SaveProfileName(record ProfileNameRecord) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	records, err := store.read()
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		if records[i].Device == record.Device && records[i].Name == record.Name {
+			records[i] = record
+			return store.write(records)
+		}
+	}
+	return store.write(append(records, record))
+}
+
+// DeleteProfileName remove the record of the name of the device
+func (store *FileProfileNameStore) DeleteProfileName(device, name string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	records, err := store.read()
+	if err != nil {
+		return err
+	}
+	kept := records[:0]
+	for _, record := range records {
+		if record.Device != device || record.Name != name {
+			kept = append(kept, record)
+		}
+	}
+	return store.write(kept)
+}
+
+func (store *FileProfileNameStore) read() ([]ProfileNameRecord, error) {
+	data, err := ioutil.ReadFile(store.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []ProfileNameRecord
+	err = json.Unmarshal(data, &records)
+	return records, err
+}
+
+/* 先写临时文件再重命名,避免写入中断损坏文件 */
+func (store *FileProfileNameStore) write(records []ProfileNameRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := store.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, store.Path)
+}
@@ -0,0 +1,94 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func namedProfiles(main, sub string) string {
+	return `<trt:GetProfilesResponse>` +
+		`<trt:Profiles token="` + main + `"><tt:Name>mainStream</tt:Name><tt:VideoSourceConfiguration token="source_config"><tt:Name>source</tt:Name><tt:SourceToken>source_1</tt:SourceToken></tt:VideoSourceConfiguration>` +
+		`<tt:VideoEncoderConfiguration token="encoder_1"><tt:Name>encoder_1</tt:Name><tt:Encoding>H264</tt:Encoding><tt:Resolution><tt:Width>1920</tt:Width><tt:Height>1080</tt:Height></tt:Resolution></tt:VideoEncoderConfiguration></trt:Profiles>` +
+		`<trt:Profiles token="` + sub + `"><tt:Name>subStream</tt:Name><tt:VideoSourceConfiguration token="source_config"><tt:Name>source</tt:Name><tt:SourceToken>source_1</tt:SourceToken></tt:VideoSourceConfiguration>` +
+		`<tt:VideoEncoderConfiguration token="encoder_2"><tt:Name>encoder_2</tt:Name><tt:Encoding>H264</tt:Encoding><tt:Resolution><tt:Width>640</tt:Width><tt:Height>360</tt:Height></tt:Resolution></tt:VideoEncoderConfiguration></trt:Profiles>` +
+		`</trt:GetProfilesResponse>`
+}
+
+func TestProfileNames(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetProfiles": namedProfiles("main", "sub"),
+	}, ServiceMedia)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	names := NewProfileNames(NewFileProfileNameStore(path))
+	if err := names.Assign(ctx, dev, "entrance", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := names.Assign(ctx, dev, "preview", "sub"); err != nil {
+		t.Fatal(err)
+	}
+	if err := names.Assign(ctx, dev, "other", "missing"); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("error %v assigning an unknown token", err)
+	}
+	if token, err := names.Resolve(ctx, dev, "entrance"); err != nil || token != "main" {
+		t.Fatalf("token %q, %v", token, err)
+	}
+	if _, err := names.Resolve(ctx, dev, "other"); !errors.Is(err, ErrUnknownProfileName) {
+		t.Fatalf("error %v resolving an unknown name", err)
+	}
+
+	/* 恢复出厂设置后token改变,从文件重新加载的映射按属性找回profile */
+	fake.set("GetProfiles", namedProfiles("Profile_1", "Profile_2"))
+	reloaded := NewProfileNames(NewFileProfileNameStore(path))
+	if token, err := reloaded.Resolve(ctx, dev, "preview"); err != nil || token != "Profile_2" {
+		t.Fatalf("token %q, %v", token, err)
+	}
+	if token, err := reloaded.Resolve(ctx, dev, "entrance"); err != nil || token != "Profile_1" {
+		t.Fatalf("token %q, %v", token, err)
+	}
+	stored, err := NewProfileNames(NewFileProfileNameStore(path)).Names(dev)
+	if err != nil || len(stored) != 2 || stored["entrance"] != "Profile_1" || stored["preview"] != "Profile_2" {
+		t.Fatalf("stored names %v, %v", stored, err)
+	}
+
+	/* 只有序号相同的profile不被认定为同一个 */
+	fake.set("GetProfiles", `<trt:GetProfilesResponse><trt:Profiles token="other"><tt:Name>other</tt:Name></trt:Profiles></trt:GetProfilesResponse>`)
+	if _, err := reloaded.Resolve(ctx, dev, "entrance"); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("error %v", err)
+	}
+
+	if err := reloaded.Remove(dev, "preview"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = NewProfileNames(NewFileProfileNameStore(path)).Names(dev)
+	if err != nil || len(stored) != 1 || stored["entrance"] != "Profile_1" {
+		t.Fatalf("stored names %v, %v", stored, err)
+	}
+}
+
+func TestProfileNamesClaimedTokens(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetProfiles": namedProfiles("main", "sub"),
+	}, ServiceMedia)
+	ctx := context.Background()
+	names := NewProfileNames(nil)
+	if err := names.Assign(ctx, dev, "entrance", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := names.Assign(ctx, dev, "preview", "sub"); err != nil {
+		t.Fatal(err)
+	}
+	/* 仍有效的token属于其他名称,不能被重新分配 */
+	fake.set("GetProfiles", namedProfiles("Profile_1", "sub"))
+	if token, err := names.Resolve(ctx, dev, "entrance"); err != nil || token != "Profile_1" {
+		t.Fatalf("token %q, %v", token, err)
+	}
+	fake.set("GetProfiles", `<trt:GetProfilesResponse>`+
+		`<trt:Profiles token="sub"><tt:Name>mainStream</tt:Name><tt:VideoSourceConfiguration token="source_config"><tt:Name>source</tt:Name><tt:SourceToken>source_1</tt:SourceToken></tt:VideoSourceConfiguration></trt:Profiles>`+
+		`</trt:GetProfilesResponse>`)
+	if _, err := names.Resolve(ctx, dev, "entrance"); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("error %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ScheduleEntry configuration variant applied to a fleet from a time of day
+type ScheduleEntry struct {
+	Name string
+	// At time of day "15:04" from which the variant is in force
+	At string
+	// Weekdays the entry applies to, empty means every day
+	Weekdays []time.Weekday
+	Template ConfigTemplate
+	Fleet    *Fleet
+}
+
+// ProfileScheduler switch device settings between stored configuration
+// variants at configured times of day
+type ProfileScheduler struct {
+	Entries []ScheduleEntry
+	// Location time zone of the entry times, nil means time.Local
+	Location *time.Location
+	// OnApply optional callback with the reports of every switch
+	OnApply func(entry ScheduleEntry, reports []ApplyReport)
+}
+
+func parseTimeOfDay(at string) (time.Time, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time of day %q: %w", at, err)
+	}
+	return t, nil
+}
+
+// startOn return the time the entry starts on day, at the wall clock time of
+// At even when daylight saving time changes between midnight and then
+func (entry ScheduleEntry) startOn(day time.Time) (time.Time, error) {
+	at, err := parseTimeOfDay(entry.At)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, day.Location()), nil
+}
+
+func (entry ScheduleEntry) onDay(day time.Weekday) bool {
+	if len(entry.Weekdays) == 0 {
+		return true
+	}
+	for _, weekday := range entry.Weekdays {
+		if weekday == day {
+			return true
+		}
+	}
+	return false
+}
+
+// validate check every entry before the scheduler starts
+func (scheduler *ProfileScheduler) validate() error {
+	if len(scheduler.Entries) == 0 {
+		return errors.New("profile scheduler has no entry")
+	}
+	for _, entry := range scheduler.Entries {
+		if _, err := parseTimeOfDay(entry.At); err != nil {
+			return err
+		}
+		if entry.Fleet == nil {
+			return fmt.Errorf("schedule entry %q has no fleet", entry.Name)
+		}
+	}
+	return nil
+}
+
+func (scheduler *ProfileScheduler) location() *time.Location {
+	if scheduler.Location != nil {
+		return scheduler.Location
+	}
+	return time.Local
+}
+
+// Active return the entry in force at t, i.e. the one that started last.
+// The previous days are searched so an evening entry stays active overnight.
+func (scheduler *ProfileScheduler) Active(t time.Time) (ScheduleEntry, bool) {
+	t = t.In(scheduler.location())
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for back := 0; back < 8; back++ {
+		day := midnight.AddDate(0, 0, -back)
+		found := false
+		var best ScheduleEntry
+		var bestStart time.Time
+		for _, entry := range scheduler.Entries {
+			start, err := entry.startOn(day)
+			if err != nil || !entry.onDay(day.Weekday()) {
+				continue
+			}
+			if start.After(t) {
+				continue
+			}
+			if !found || start.After(bestStart) {
+				found, best, bestStart = true, entry, start
+			}
+		}
+		if found {
+			return best, true
+		}
+	}
+	return ScheduleEntry{}, false
+}
+
+// next return the first entry starting strictly after t and its start time
+func (scheduler *ProfileScheduler) next(t time.Time) (ScheduleEntry, time.Time, bool) {
+	t = t.In(scheduler.location())
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for ahead := 0; ahead < 8; ahead++ {
+		day := midnight.AddDate(0, 0, ahead)
+		found := false
+		var best ScheduleEntry
+		var bestStart time.Time
+		for _, entry := range scheduler.Entries {
+			start, err := entry.startOn(day)
+			if err != nil || !entry.onDay(day.Weekday()) {
+				continue
+			}
+			if !start.After(t) {
+				continue
+			}
+			if !found || start.Before(bestStart) {
+				found, best, bestStart = true, entry, start
+			}
+		}
+		if found {
+			return best, bestStart, true
+		}
+	}
+	return ScheduleEntry{}, time.Time{}, false
+}
+
+func (scheduler *ProfileScheduler) apply(ctx context.Context, entry ScheduleEntry) {
+	/* ctx结束时Run随即返回,已完成的部分照常上报 */
+	reports, _ := entry.Fleet.Apply(ctx, entry.Template)
+	if scheduler.OnApply != nil {
+		scheduler.OnApply(entry, reports)
+	}
+}
+
+// Run apply the variant currently in force, then switch variants at their
+// configured times until ctx is done
+func (scheduler *ProfileScheduler) Run(ctx context.Context) error {
+	if err := scheduler.validate(); err != nil {
+		return err
+	}
+	if entry, ok := scheduler.Active(time.Now()); ok {
+		scheduler.apply(ctx, entry)
+	}
+	for {
+		entry, start, ok := scheduler.next(time.Now())
+		if !ok {
+			return errors.New("profile scheduler has no upcoming entry")
+		}
+		timer := time.NewTimer(time.Until(start))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		scheduler.apply(ctx, entry)
+	}
+}
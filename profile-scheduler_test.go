@@ -0,0 +1,59 @@
+package onvif
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProfileSchedulerDaylightSaving(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	scheduler := &ProfileScheduler{
+		Location: paris,
+		Entries: []ScheduleEntry{
+			{Name: "day", At: "08:00", Fleet: &Fleet{}},
+			{Name: "night", At: "22:00", Fleet: &Fleet{}},
+		},
+	}
+	/* 2026-03-29 02:00夏令时开始,当天只有23小时 */
+	for _, day := range []int{29, 30} {
+		if entry, ok := scheduler.Active(time.Date(2026, 3, day, 8, 30, 0, 0, paris)); !ok || entry.Name != "day" {
+			t.Fatalf("march %d 08:30: %q active", day, entry.Name)
+		}
+		entry, start, ok := scheduler.next(time.Date(2026, 3, day, 7, 0, 0, 0, paris))
+		if want := time.Date(2026, 3, day, 8, 0, 0, 0, paris); !ok || entry.Name != "day" || !start.Equal(want) {
+			t.Fatalf("march %d: next %q at %s, want %s", day, entry.Name, start, want)
+		}
+	}
+	/* 2026-10-25 03:00夏令时结束,当天有25小时 */
+	if entry, ok := scheduler.Active(time.Date(2026, 10, 25, 21, 30, 0, 0, paris)); !ok || entry.Name != "day" {
+		t.Fatalf("october 25 21:30: %q active", entry.Name)
+	}
+	entry, start, ok := scheduler.next(time.Date(2026, 10, 25, 12, 0, 0, 0, paris))
+	if want := time.Date(2026, 10, 25, 22, 0, 0, 0, paris); !ok || entry.Name != "night" || !start.Equal(want) {
+		t.Fatalf("october 25: next %q at %s, want %s", entry.Name, start, want)
+	}
+}
+
+func TestProfileSchedulerWeekdays(t *testing.T) {
+	scheduler := &ProfileScheduler{
+		Location: time.UTC,
+		Entries: []ScheduleEntry{
+			{Name: "open", At: "09:00", Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, Fleet: &Fleet{}},
+			{Name: "closed", At: "18:00", Fleet: &Fleet{}},
+		},
+	}
+	/* 2026-10-17是星期六,周五晚上的条目持续到周一 */
+	if entry, ok := scheduler.Active(time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)); !ok || entry.Name != "closed" {
+		t.Fatalf("saturday: %q active", entry.Name)
+	}
+	entry, start, ok := scheduler.next(time.Date(2026, 10, 17, 19, 0, 0, 0, time.UTC))
+	if !ok || entry.Name != "closed" || start.Day() != 18 {
+		t.Fatalf("saturday evening: next %q at %s", entry.Name, start)
+	}
+	if err := (&ProfileScheduler{Entries: []ScheduleEntry{{At: "25:00", Fleet: &Fleet{}}}}).validate(); err == nil {
+		t.Fatal("invalid time of day accepted")
+	}
+}
@@ -0,0 +1,247 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/media"
+	"github.com/PolarisM78/go-onvif/types/ptz"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// PTZUnit unit of the positions given to and returned by the PTZ position API
+type PTZUnit int
+
+// PTZ position units
+const (
+	// PTZGeneric generic absolute position space of the device, pan and tilt
+	// usually -1 to 1, zoom 0 to 1
+	PTZGeneric PTZUnit = iota
+	// PTZPercent 0 to 100 percent of the range of each axis
+	PTZPercent
+	// PTZDegrees pan and tilt in degrees and zoom as a magnification ratio,
+	// mapped linearly from the generic space by a PTZCalibration
+	PTZDegrees
+)
+
+func (unit PTZUnit) String() string {
+	switch unit {
+	case PTZGeneric:
+		return "generic"
+	case PTZPercent:
+		return "percent"
+	case PTZDegrees:
+		return "degrees"
+	}
+	return fmt.Sprintf("PTZUnit(%d)", int(unit))
+}
+
+// PTZPosition absolute pan, tilt and zoom position in a unit
+type PTZPosition struct {
+	Pan  float64
+	Tilt float64
+	Zoom float64
+	Unit PTZUnit
+}
+
+// PTZCalibration physical range covered by the generic position space of a
+// device, e.g. pan -180 to 180 degrees, tilt -90 to 0 degrees, zoom 1 to 30x
+type PTZCalibration struct {
+	Pan  onvif.FloatRange
+	Tilt onvif.FloatRange
+	Zoom onvif.FloatRange
+}
+
+// ptzSpace generic ranges of the absolute position spaces of a PTZ node
+type ptzSpace struct {
+	pan, tilt, zoom onvif.FloatRange
+}
+
+// ErrPositionMismatch returned when the device did not reach the commanded
+// position
+var ErrPositionMismatch = errors.New("ptz position not reached")
+
+// PositionMismatchError ErrPositionMismatch with the commanded and the actual
+// positions, in the unit of the command
+type PositionMismatchError struct {
+	Commanded PTZPosition
+	Actual    PTZPosition
+	Tolerance float64
+}
+
+func (err *PositionMismatchError) Error() string {
+	return fmt.Sprintf("ptz position not reached: commanded pan %g tilt %g zoom %g, actual pan %g tilt %g zoom %g (%s, tolerance %g)",
+		err.Commanded.Pan, err.Commanded.Tilt, err.Commanded.Zoom, err.Actual.Pan, err.Actual.Tilt, err.Actual.Zoom, err.Commanded.Unit, err.Tolerance)
+}
+
+// Is report the error as ErrPositionMismatch
+func (err *PositionMismatchError) Is(target error) bool {
+	return target == ErrPositionMismatch
+}
+
+// PTZStatus return the position and move status of the PTZ node of the profile
+func (dev *Device) PTZStatus(ctx context.Context, profile string) (onvif.PTZStatus, error) {
+	if err := dev.requirePTZ(); err != nil {
+		return onvif.PTZStatus{}, err
+	}
+	response := ptz.GetStatusResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, ptz.GetStatus{ProfileToken: onvif.ReferenceToken(profile)}, &response, ""); err != nil {
+		return onvif.PTZStatus{}, err
+	}
+	return response.PTZStatus, nil
+}
+
+// waitPTZIdle poll the status of the PTZ node of the profile until its
+// movement is finished. Devices reporting no move status are considered idle
+// once their position holds between two reads.
+func (dev *Device) waitPTZIdle(ctx context.Context, profile string, poll time.Duration) error {
+	var previous *onvif.PTZVector
+	for {
+		status, err := dev.PTZStatus(ctx, profile)
+		if err != nil {
+			return err
+		}
+		panTilt, zoom := string(status.MoveStatus.PanTilt.Status), string(status.MoveStatus.Zoom.Status)
+		if panTilt != "" || zoom != "" {
+			if !strings.EqualFold(panTilt, string(onvif.MoveStateMoving)) && !strings.EqualFold(zoom, string(onvif.MoveStateMoving)) {
+				return nil
+			}
+		} else if previous != nil && *previous == status.Position {
+			return nil
+		}
+		previous = &status.Position
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("movement not finished: %w", ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
+
+// ptzSpace return the generic ranges of the PTZ node of the profile, the
+// ranges of the specification when the device reports none
+func (dev *Device) ptzSpace(ctx context.Context, profile string) (ptzSpace, error) {
+	space := ptzSpace{pan: onvif.FloatRange{Min: -1, Max: 1}, tilt: onvif.FloatRange{Min: -1, Max: 1}, zoom: onvif.FloatRange{Min: 0, Max: 1}}
+	response := media.GetProfileResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, media.GetProfile{ProfileToken: onvif.ReferenceToken(profile)}, &response, ""); err != nil {
+		return space, err
+	}
+	node := ptz.GetNodeResponse{}
+	request := ptz.GetNode{NodeToken: response.Profile.PTZConfiguration.NodeToken}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &node, ""); err != nil {
+		return space, err
+	}
+	spaces := node.PTZNode.SupportedPTZSpaces
+	if r := spaces.AbsolutePanTiltPositionSpace; r.XRange.Max > r.XRange.Min && r.YRange.Max > r.YRange.Min {
+		space.pan, space.tilt = r.XRange, r.YRange
+	}
+	if r := spaces.AbsoluteZoomPositionSpace; r.XRange.Max > r.XRange.Min {
+		space.zoom = r.XRange
+	}
+	return space, nil
+}
+
+// rescale map value from the range from to the range to
+func rescale(value float64, from, to onvif.FloatRange) float64 {
+	return to.Min + (value-from.Min)*(to.Max-to.Min)/(from.Max-from.Min)
+}
+
+// unitRanges return the ranges of the axes in unit
+func unitRanges(unit PTZUnit, space ptzSpace, calibration *PTZCalibration) (ptzSpace, error) {
+	switch unit {
+	case PTZGeneric:
+		return space, nil
+	case PTZPercent:
+		percent := onvif.FloatRange{Min: 0, Max: 100}
+		return ptzSpace{pan: percent, tilt: percent, zoom: percent}, nil
+	case PTZDegrees:
+		if calibration == nil || calibration.Pan.Max == calibration.Pan.Min || calibration.Tilt.Max == calibration.Tilt.Min || calibration.Zoom.Max == calibration.Zoom.Min {
+			return ptzSpace{}, errors.New("degrees need a calibration of every axis")
+		}
+		return ptzSpace{pan: calibration.Pan, tilt: calibration.Tilt, zoom: calibration.Zoom}, nil
+	}
+	return ptzSpace{}, fmt.Errorf("unknown ptz unit %d", int(unit))
+}
+
+// PTZPosition return the current position of the PTZ node of the profile in
+// unit; calibration is required for PTZDegrees only
+func (dev *Device) PTZPosition(ctx context.Context, profile string, unit PTZUnit, calibration *PTZCalibration) (PTZPosition, error) {
+	space, err := dev.ptzSpace(ctx, profile)
+	if err != nil {
+		return PTZPosition{}, err
+	}
+	ranges, err := unitRanges(unit, space, calibration)
+	if err != nil {
+		return PTZPosition{}, err
+	}
+	status, err := dev.PTZStatus(ctx, profile)
+	if err != nil {
+		return PTZPosition{}, err
+	}
+	return PTZPosition{
+		Pan:  rescale(status.Position.PanTilt.X, space.pan, ranges.pan),
+		Tilt: rescale(status.Position.PanTilt.Y, space.tilt, ranges.tilt),
+		Zoom: rescale(status.Position.Zoom.X, space.zoom, ranges.zoom),
+		Unit: unit,
+	}, nil
+}
+
+// AbsoluteMove move the PTZ node of the profile to position, converted to
+// the generic space of the device
+func (dev *Device) AbsoluteMove(ctx context.Context, profile string, position PTZPosition, calibration *PTZCalibration) error {
+	space, err := dev.ptzSpace(ctx, profile)
+	if err != nil {
+		return err
+	}
+	return dev.absoluteMove(ctx, profile, position, space, calibration)
+}
+
+func (dev *Device) absoluteMove(ctx context.Context, profile string, position PTZPosition, space ptzSpace, calibration *PTZCalibration) error {
+	ranges, err := unitRanges(position.Unit, space, calibration)
+	if err != nil {
+		return err
+	}
+	request := ptz.AbsoluteMove{
+		ProfileToken: onvif.ReferenceToken(profile),
+		Position: onvif.PTZVector{
+			PanTilt: onvif.Vector2D{X: rescale(position.Pan, ranges.pan, space.pan), Y: rescale(position.Tilt, ranges.tilt, space.tilt)},
+			Zoom:    onvif.Vector1D{X: rescale(position.Zoom, ranges.zoom, space.zoom)},
+		},
+	}
+	return dev.CallMethodInterfaceContext(ctx, request, &ptz.AbsoluteMoveResponse{}, "")
+}
+
+// MoveAndVerify move to position, wait for the end of the movement and read
+// the position back. A position off by more than tolerance on any axis, in
+// the unit of position, is returned with a PositionMismatchError; pan in
+// degrees is compared modulo 360.
+func (dev *Device) MoveAndVerify(ctx context.Context, profile string, position PTZPosition, calibration *PTZCalibration, tolerance float64) (PTZPosition, error) {
+	space, err := dev.ptzSpace(ctx, profile)
+	if err != nil {
+		return PTZPosition{}, err
+	}
+	if err := dev.absoluteMove(ctx, profile, position, space, calibration); err != nil {
+		return PTZPosition{}, err
+	}
+	if err := dev.waitPTZIdle(ctx, profile, 250*time.Millisecond); err != nil {
+		return PTZPosition{}, err
+	}
+	actual, err := dev.PTZPosition(ctx, profile, position.Unit, calibration)
+	if err != nil {
+		return PTZPosition{}, err
+	}
+	panDiff := math.Abs(actual.Pan - position.Pan)
+	if position.Unit == PTZDegrees {
+		/* 水平角按360度取模比较,-180与180为同一位置 */
+		panDiff = math.Mod(panDiff, 360)
+		panDiff = math.Min(panDiff, 360-panDiff)
+	}
+	if panDiff > tolerance || math.Abs(actual.Tilt-position.Tilt) > tolerance || math.Abs(actual.Zoom-position.Zoom) > tolerance {
+		return actual, &PositionMismatchError{Commanded: position, Actual: actual, Tolerance: tolerance}
+	}
+	return actual, nil
+}
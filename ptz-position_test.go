@@ -0,0 +1,46 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+func TestPTZPosition(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetProfile":   `<trt:GetProfileResponse><trt:Profile token="main"><tt:Name>main</tt:Name><tt:PTZConfiguration token="ptz"><tt:NodeToken>node</tt:NodeToken></tt:PTZConfiguration></trt:Profile></trt:GetProfileResponse>`,
+		"GetNode":      `<tptz:GetNodeResponse><tptz:PTZNode token="node"/></tptz:GetNodeResponse>`,
+		"AbsoluteMove": `<tptz:AbsoluteMoveResponse/>`,
+		"GetStatus": `<tptz:GetStatusResponse><tptz:PTZStatus><tt:Position><tt:PanTilt x="0.5" y="-0.5"/><tt:Zoom x="0.25"/></tt:Position>` +
+			`<tt:MoveStatus><tt:PanTilt>IDLE</tt:PanTilt><tt:Zoom>IDLE</tt:Zoom></tt:MoveStatus></tptz:PTZStatus></tptz:GetStatusResponse>`,
+	}, ServiceMedia, ServicePTZ)
+	ctx := context.Background()
+	calibration := &PTZCalibration{Pan: onvif.FloatRange{Min: -180, Max: 180}, Tilt: onvif.FloatRange{Min: -90, Max: 0}, Zoom: onvif.FloatRange{Min: 1, Max: 30}}
+	/* 设备未报告位置空间时使用规范的通用范围 */
+	if position, err := dev.PTZPosition(ctx, "main", PTZPercent, nil); err != nil || position != (PTZPosition{Pan: 75, Tilt: 25, Zoom: 25, Unit: PTZPercent}) {
+		t.Fatalf("position %+v, %v", position, err)
+	}
+	if position, err := dev.PTZPosition(ctx, "main", PTZDegrees, calibration); err != nil || position != (PTZPosition{Pan: 90, Tilt: -67.5, Zoom: 8.25, Unit: PTZDegrees}) {
+		t.Fatalf("position %+v, %v", position, err)
+	}
+	if _, err := dev.PTZPosition(ctx, "main", PTZDegrees, nil); err == nil {
+		t.Fatal("degrees without a calibration")
+	}
+
+	/* 水平角按360度取模比较 */
+	if _, err := dev.MoveAndVerify(ctx, "main", PTZPosition{Pan: -270, Tilt: -67.5, Zoom: 8.25, Unit: PTZDegrees}, calibration, 0.01); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := dev.MoveAndVerify(ctx, "main", PTZPosition{Pan: 50, Tilt: 25, Zoom: 25, Unit: PTZPercent}, nil, 1)
+	var mismatch *PositionMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrPositionMismatch) || actual.Pan != 75 || mismatch.Commanded.Pan != 50 || mismatch.Tolerance != 1 {
+		t.Fatalf("position %+v, %v", actual, err)
+	}
+	moves := fake.sent("AbsoluteMove")
+	if len(moves) != 2 || !strings.Contains(moves[1], `x="0" y="-0.5"`) || !strings.Contains(moves[1], `x="0.25"`) {
+		t.Fatalf("requests %q", moves)
+	}
+}
@@ -0,0 +1,217 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PolarisM78/go-onvif/types/ptz"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ErrPresetNotFound returned when no preset of the profile matches a name
+var ErrPresetNotFound = errors.New("preset not found")
+
+/* Latin-1字母去掉重音后的ASCII形式 */
+var presetFolding = func() map[rune]string {
+	folding := map[rune]string{'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'ø': "o", 'Ø': "O"}
+	for letter, accented := range map[string]string{
+		"a": "àáâãäå", "A": "ÀÁÂÃÄÅ", "c": "ç", "C": "Ç", "e": "èéêë", "E": "ÈÉÊË",
+		"i": "ìíîï", "I": "ÌÍÎÏ", "n": "ñ", "N": "Ñ", "o": "òóôõö", "O": "ÒÓÔÕÖ",
+		"u": "ùúûü", "U": "ÙÚÛÜ", "y": "ýÿ", "Y": "Ý",
+	} {
+		for _, r := range accented {
+			folding[r] = letter
+		}
+	}
+	return folding
+}()
+
+// NormalizePresetName return the name as the device with the quirks stores
+// it: white space collapsed, accents folded and other characters replaced by
+// _ on ASCII only devices, spaces replaced by _ when not accepted, truncated
+// to the longest name accepted
+func NormalizePresetName(name string, quirks Quirks) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if quirks.PresetNameASCII {
+		builder := strings.Builder{}
+		for _, r := range name {
+			switch folded, ok := presetFolding[r]; {
+			case r < utf8.RuneSelf:
+				builder.WriteRune(r)
+			case ok:
+				builder.WriteString(folded)
+			default:
+				builder.WriteByte('_')
+			}
+		}
+		name = builder.String()
+	}
+	if quirks.PresetNameNoSpaces {
+		name = strings.Replace(name, " ", "_", -1)
+	}
+	if quirks.PresetNameMaxLength > 0 && len(name) > quirks.PresetNameMaxLength {
+		/* 按字符边界截断 */
+		end := quirks.PresetNameMaxLength
+		for end > 0 && !utf8.RuneStart(name[end]) {
+			end--
+		}
+		name = strings.TrimRight(name[:end], " _")
+	}
+	return name
+}
+
+// presetKey return the form under which preset names are compared: folded to
+// lower case ASCII letters and digits, so "Front Door", "front_door" and
+// "FRONT-DOOR" are the same preset
+func presetKey(name string) string {
+	builder := strings.Builder{}
+	for _, r := range name {
+		if folded, ok := presetFolding[r]; ok {
+			builder.WriteString(strings.ToLower(folded))
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return builder.String()
+}
+
+func (dev *Device) requirePTZ() error {
+	if _, err := dev.getEndpoint("ptz"); err != nil {
+		return &NotSupportedError{Service: "ptz"}
+	}
+	return nil
+}
+
+func (dev *Device) getPresets(ctx context.Context, profile string) ([]onvif.PTZPreset, error) {
+	if err := dev.requirePTZ(); err != nil {
+		return nil, err
+	}
+	response := ptz.GetPresetsResponse{}
+	request := ptz.GetPresets{ProfileToken: onvif.ReferenceToken(profile)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return nil, err
+	}
+	return response.Preset, nil
+}
+
+// Presets return the PTZ presets of the profile, without the free slots
+// listed by the devices with the PresetSlots quirk
+func (dev *Device) Presets(ctx context.Context, profile string) ([]onvif.PTZPreset, error) {
+	quirks, err := dev.Quirks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	presets, err := dev.getPresets(ctx, profile)
+	if err != nil || !quirks.PresetSlots {
+		return presets, err
+	}
+	used := presets[:0]
+	for _, preset := range presets {
+		if preset.Name != "" {
+			used = append(used, preset)
+		}
+	}
+	return used, nil
+}
+
+// matchPreset return the preset named name, the name being compared as
+// written, as the device stores it and by presetKey; a preset token is
+// accepted as well
+func matchPreset(presets []onvif.PTZPreset, name string, quirks Quirks) (onvif.PTZPreset, bool) {
+	for _, preset := range presets {
+		if string(preset.Name) == name {
+			return preset, true
+		}
+	}
+	keys := []string{presetKey(name), presetKey(NormalizePresetName(name, quirks))}
+	for _, preset := range presets {
+		if preset.Name != "" && containsString(keys, presetKey(string(preset.Name))) {
+			return preset, true
+		}
+	}
+	for _, preset := range presets {
+		if string(preset.Token) == name {
+			return preset, true
+		}
+	}
+	return onvif.PTZPreset{}, false
+}
+
+// FindPreset return the preset of the profile named name, whatever the vendor
+// did to the name when storing it
+func (dev *Device) FindPreset(ctx context.Context, profile, name string) (onvif.PTZPreset, error) {
+	quirks, err := dev.Quirks(ctx)
+	if err != nil {
+		return onvif.PTZPreset{}, err
+	}
+	presets, err := dev.getPresets(ctx, profile)
+	if err != nil {
+		return onvif.PTZPreset{}, err
+	}
+	preset, ok := matchPreset(presets, name, quirks)
+	if !ok {
+		return onvif.PTZPreset{}, fmt.Errorf("%w: %q", ErrPresetNotFound, name)
+	}
+	return preset, nil
+}
+
+// SavePreset store the current position as the preset named name and return
+// its token. An existing preset with the name is overwritten; the name is
+// normalized for the device with NormalizePresetName.
+func (dev *Device) SavePreset(ctx context.Context, profile, name string) (string, error) {
+	quirks, err := dev.Quirks(ctx)
+	if err != nil {
+		return "", err
+	}
+	presets, err := dev.getPresets(ctx, profile)
+	if err != nil {
+		return "", err
+	}
+	request := ptz.SetPreset{ProfileToken: onvif.ReferenceToken(profile), PresetName: xsd.String(NormalizePresetName(name, quirks))}
+	if preset, ok := matchPreset(presets, name, quirks); ok {
+		request.PresetToken = preset.Token
+	} else if quirks.PresetSlots {
+		/* 设备预置了全部编号,新预置位占用第一个空闲编号 */
+		for _, preset := range presets {
+			if preset.Name == "" {
+				request.PresetToken = preset.Token
+				break
+			}
+		}
+		if request.PresetToken == "" {
+			return "", errors.New("no free preset slot")
+		}
+	}
+	response := ptz.SetPresetResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return "", err
+	}
+	if response.PresetToken == "" {
+		return string(request.PresetToken), nil
+	}
+	return string(response.PresetToken), nil
+}
+
+// GotoPresetByName move to the preset named name, speed is optional
+func (dev *Device) GotoPresetByName(ctx context.Context, profile, name string, speed *onvif.PTZSpeed) error {
+	preset, err := dev.FindPreset(ctx, profile, name)
+	if err != nil {
+		return err
+	}
+	return dev.GotoPreset(ctx, profile, string(preset.Token), speed)
+}
+
+// RemovePresetByName remove the preset named name
+func (dev *Device) RemovePresetByName(ctx context.Context, profile, name string) error {
+	preset, err := dev.FindPreset(ctx, profile, name)
+	if err != nil {
+		return err
+	}
+	request := ptz.RemovePreset{ProfileToken: onvif.ReferenceToken(profile), PresetToken: preset.Token}
+	return dev.CallMethodInterfaceContext(ctx, request, &ptz.RemovePresetResponse{}, "")
+}
@@ -0,0 +1,181 @@
+package onvif
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/ptz"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Sequence ordered PTZ, imaging and media steps run on a profile of a device,
+// for guard tours and evidence capture:
+//
+//	result, err := onvif.NewSequence(dev, "Profile_1").
+//		Goto("Front Door").WaitIdle().Dwell(10 * time.Second).Zoom(0.2).WaitIdle().Snapshot().
+//		Run(ctx)
+//
+// The steps run in order, the first failing step ends the sequence with a
+// SequenceError.
+type Sequence struct {
+	Device  *Device
+	Profile string
+	// IdleTimeout bound of a WaitIdle step, 0 means 30s
+	IdleTimeout time.Duration
+	// PollInterval between two status reads of a WaitIdle step, 0 means 250ms
+	PollInterval time.Duration
+
+	steps []sequenceStep
+}
+
+type sequenceStep struct {
+	name string
+	run  func(ctx context.Context, result *SequenceResult) error
+}
+
+// SequenceResult outcome of the steps run
+type SequenceResult struct {
+	// Steps number of steps completed
+	Steps int
+	// Snapshots taken by the Snapshot steps, in order
+	Snapshots []Snapshot
+}
+
+// SequenceError failure of a step of a Sequence
+type SequenceError struct {
+	// Step index of the failing step
+	Step int
+	Name string
+	Err  error
+}
+
+func (err *SequenceError) Error() string {
+	return fmt.Sprintf("sequence step %d %s: %v", err.Step, err.Name, err.Err)
+}
+
+// Unwrap return the error of the step
+func (err *SequenceError) Unwrap() error {
+	return err.Err
+}
+
+// NewSequence return an empty sequence on the profile of dev
+func NewSequence(dev *Device, profile string) *Sequence {
+	return &Sequence{Device: dev, Profile: profile}
+}
+
+func (seq *Sequence) add(name string, run func(ctx context.Context, result *SequenceResult) error) *Sequence {
+	seq.steps = append(seq.steps, sequenceStep{name: name, run: run})
+	return seq
+}
+
+// Goto move to the preset named preset, see GotoPresetByName
+func (seq *Sequence) Goto(preset string) *Sequence {
+	return seq.add("goto "+preset, func(ctx context.Context, result *SequenceResult) error {
+		return seq.Device.GotoPresetByName(ctx, seq.Profile, preset, nil)
+	})
+}
+
+// Home move to the home position
+func (seq *Sequence) Home() *Sequence {
+	return seq.add("home", func(ctx context.Context, result *SequenceResult) error {
+		request := ptz.GotoHomePosition{ProfileToken: onvif.ReferenceToken(seq.Profile)}
+		return seq.Device.CallMethodInterfaceContext(ctx, request, &ptz.GotoHomePositionResponse{}, "")
+	})
+}
+
+// Move pan and tilt relatively, in the generic translation space of the
+// device (-1 to 1)
+func (seq *Sequence) Move(pan, tilt float64) *Sequence {
+	return seq.add(fmt.Sprintf("move %+g,%+g", pan, tilt), func(ctx context.Context, result *SequenceResult) error {
+		return seq.relativeMove(ctx, onvif.PTZVector{PanTilt: onvif.Vector2D{X: pan, Y: tilt}})
+	})
+}
+
+// Zoom zoom relatively, in the generic translation space of the device (-1
+// to 1), e.g. Zoom(+0.2)
+func (seq *Sequence) Zoom(delta float64) *Sequence {
+	return seq.add(fmt.Sprintf("zoom %+g", delta), func(ctx context.Context, result *SequenceResult) error {
+		return seq.relativeMove(ctx, onvif.PTZVector{Zoom: onvif.Vector1D{X: delta}})
+	})
+}
+
+func (seq *Sequence) relativeMove(ctx context.Context, translation onvif.PTZVector) error {
+	request := ptz.RelativeMove{ProfileToken: onvif.ReferenceToken(seq.Profile), Translation: translation}
+	return seq.Device.CallMethodInterfaceContext(ctx, request, &ptz.RelativeMoveResponse{}, "")
+}
+
+// Stop stop any pan, tilt or zoom movement
+func (seq *Sequence) Stop() *Sequence {
+	return seq.add("stop", func(ctx context.Context, result *SequenceResult) error {
+		request := ptz.Stop{ProfileToken: onvif.ReferenceToken(seq.Profile), PanTilt: xsd.Boolean(true), Zoom: xsd.Boolean(true)}
+		return seq.Device.CallMethodInterfaceContext(ctx, request, &ptz.StopResponse{}, "")
+	})
+}
+
+// WaitIdle wait for the end of the movement in progress
+func (seq *Sequence) WaitIdle() *Sequence {
+	return seq.add("wait idle", func(ctx context.Context, result *SequenceResult) error {
+		ctx, cancel := context.WithTimeout(ctx, durationOr(seq.IdleTimeout, 30*time.Second))
+		defer cancel()
+		return seq.Device.waitPTZIdle(ctx, seq.Profile, durationOr(seq.PollInterval, 250*time.Millisecond))
+	})
+}
+
+// Dwell wait for d
+func (seq *Sequence) Dwell(d time.Duration) *Sequence {
+	return seq.add("dwell "+d.String(), func(ctx context.Context, result *SequenceResult) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	})
+}
+
+// ImagingPreset apply the imaging preset to the video source, see
+// SetImagingPreset
+func (seq *Sequence) ImagingPreset(videoSource, preset string) *Sequence {
+	return seq.add("imaging preset "+preset, func(ctx context.Context, result *SequenceResult) error {
+		return seq.Device.SetImagingPreset(ctx, videoSource, preset)
+	})
+}
+
+// Snapshot take a snapshot of the profile, added to SequenceResult.Snapshots
+func (seq *Sequence) Snapshot() *Sequence {
+	return seq.add("snapshot", func(ctx context.Context, result *SequenceResult) error {
+		snapshot, err := seq.Device.Snapshot(ctx, seq.Profile)
+		if err != nil {
+			return err
+		}
+		result.Snapshots = append(result.Snapshots, snapshot)
+		return nil
+	})
+}
+
+// Do add a custom step
+func (seq *Sequence) Do(name string, fn func(ctx context.Context, dev *Device) error) *Sequence {
+	return seq.add(name, func(ctx context.Context, result *SequenceResult) error {
+		return fn(ctx, seq.Device)
+	})
+}
+
+// Run run the steps in order. The result holds what the steps completed
+// before a failure.
+func (seq *Sequence) Run(ctx context.Context) (SequenceResult, error) {
+	result := SequenceResult{}
+	for i, step := range seq.steps {
+		if err := ctx.Err(); err != nil {
+			return result, &SequenceError{Step: i, Name: step.name, Err: err}
+		}
+		if err := step.run(ctx, &result); err != nil {
+			return result, &SequenceError{Step: i, Name: step.name, Err: err}
+		}
+		result.Steps++
+	}
+	return result, nil
+}
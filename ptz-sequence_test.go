@@ -0,0 +1,51 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSequence(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GotoHomePosition": `<tptz:GotoHomePositionResponse/>`,
+		"RelativeMove":     `<tptz:RelativeMoveResponse/>`,
+		"Stop":             `<tptz:StopResponse/>`,
+		"GetStatus":        `<tptz:GetStatusResponse><tptz:PTZStatus><tt:MoveStatus><tt:PanTilt>IDLE</tt:PanTilt><tt:Zoom>IDLE</tt:Zoom></tt:MoveStatus></tptz:PTZStatus></tptz:GetStatusResponse>`,
+	}, ServicePTZ)
+	ctx := context.Background()
+	var order []string
+	record := func(name string) func(ctx context.Context, dev *Device) error {
+		return func(ctx context.Context, dev *Device) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	result, err := NewSequence(dev, "main").Home().Do("home done", record("home")).
+		Zoom(0.2).WaitIdle().Dwell(time.Millisecond).Do("zoom done", record("zoom")).Run(ctx)
+	if err != nil || result.Steps != 6 || strings.Join(order, ",") != "home,zoom" {
+		t.Fatalf("result %+v, %v, order %v", result, err, order)
+	}
+	moves := fake.sent("RelativeMove")
+	if len(moves) != 1 || !strings.Contains(moves[0], `x="0.2"`) || len(fake.sent("GetStatus")) != 1 {
+		t.Fatalf("requests %q", moves)
+	}
+
+	/* 失败的步骤结束序列,后续步骤不执行 */
+	failure := errors.New("failure")
+	result, err = NewSequence(dev, "main").Home().Do("fail", func(ctx context.Context, dev *Device) error { return failure }).Stop().Run(ctx)
+	var sequenceErr *SequenceError
+	if !errors.As(err, &sequenceErr) || sequenceErr.Step != 1 || sequenceErr.Name != "fail" || !errors.Is(err, failure) || result.Steps != 1 {
+		t.Fatalf("result %+v, %v", result, err)
+	}
+	if len(fake.sent("Stop")) != 0 {
+		t.Fatal("step run after the failure")
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := NewSequence(dev, "main").Dwell(time.Hour).Run(cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package onvif
+
+import (
+	"context"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/ptz"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ContinuousMove move the PTZ unit of the profile at velocity, in the
+// generic velocity spaces from -1 to 1, until Stop or for timeout, the
+// default timeout of the device when 0
+//
+// Deprecated: use ptz.New(dev).ContinuousMove.
+func (dev *Device) ContinuousMove(ctx context.Context, profile string, velocity onvif.PTZSpeed, timeout time.Duration) error {
+	if err := dev.requirePTZ(); err != nil {
+		return err
+	}
+	return ptz.New(dev).ContinuousMove(ctx, profile, velocity, timeout)
+}
+
+// Stop stop the pan and tilt and the zoom movements of the profile, as
+// selected
+//
+// Deprecated: use ptz.New(dev).Stop.
+func (dev *Device) Stop(ctx context.Context, profile string, panTilt, zoom bool) error {
+	if err := dev.requirePTZ(); err != nil {
+		return err
+	}
+	return ptz.New(dev).Stop(ctx, profile, panTilt, zoom)
+}
+
+// GotoPreset move the profile to the preset of token, speed is optional
+//
+// Deprecated: use ptz.New(dev).GotoPreset.
+func (dev *Device) GotoPreset(ctx context.Context, profile, preset string, speed *onvif.PTZSpeed) error {
+	if err := dev.requirePTZ(); err != nil {
+		return err
+	}
+	return ptz.New(dev).GotoPreset(ctx, profile, preset, speed)
+}
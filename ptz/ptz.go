@@ -0,0 +1,117 @@
+// Package ptz is the client of the ONVIF PTZ service: moves, presets and
+// status of the PTZ unit of a media profile.
+//
+// The client sends its requests through a core.Caller, a core.Client or an
+// onvif.Device:
+//
+//	err := ptz.New(dev).GotoPreset(ctx, "profile_1", "1", nil)
+package ptz
+
+import (
+	"context"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/core"
+	tptz "github.com/PolarisM78/go-onvif/types/ptz"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Client of the PTZ service of a device
+type Client struct {
+	caller core.Caller
+}
+
+// New return the PTZ service client sending its requests through caller
+func New(caller core.Caller) *Client {
+	return &Client{caller: caller}
+}
+
+// GetServiceCapabilities return the capabilities of the PTZ service
+func (client *Client) GetServiceCapabilities(ctx context.Context) (tptz.Capabilities, error) {
+	response := tptz.GetServiceCapabilitiesResponse{}
+	err := client.caller.Call(ctx, core.ServicePTZ, tptz.GetServiceCapabilities{}, &response)
+	return response.Capabilities, err
+}
+
+// GetNode return the PTZ node of token
+func (client *Client) GetNode(ctx context.Context, token string) (onvif.PTZNode, error) {
+	response := tptz.GetNodeResponse{}
+	err := client.caller.Call(ctx, core.ServicePTZ, tptz.GetNode{NodeToken: onvif.ReferenceToken(token)}, &response)
+	return response.PTZNode, err
+}
+
+// GetStatus return the position and move status of the PTZ unit of the
+// profile
+func (client *Client) GetStatus(ctx context.Context, profile string) (onvif.PTZStatus, error) {
+	response := tptz.GetStatusResponse{}
+	err := client.caller.Call(ctx, core.ServicePTZ, tptz.GetStatus{ProfileToken: onvif.ReferenceToken(profile)}, &response)
+	return response.PTZStatus, err
+}
+
+// ContinuousMove move the PTZ unit of the profile at velocity, in the
+// generic velocity spaces from -1 to 1, until Stop or for timeout, the
+// default timeout of the device when 0
+func (client *Client) ContinuousMove(ctx context.Context, profile string, velocity onvif.PTZSpeed, timeout time.Duration) error {
+	request := tptz.ContinuousMove{ProfileToken: onvif.ReferenceToken(profile), Velocity: velocity}
+	if timeout > 0 {
+		duration := xsd.DurationOf(timeout)
+		request.Timeout = &duration
+	}
+	return client.caller.Call(ctx, core.ServicePTZ, request, &tptz.ContinuousMoveResponse{})
+}
+
+// RelativeMove move the PTZ unit of the profile by translation, speed is
+// optional
+func (client *Client) RelativeMove(ctx context.Context, profile string, translation onvif.PTZVector, speed *onvif.PTZSpeed) error {
+	request := tptz.RelativeMove{ProfileToken: onvif.ReferenceToken(profile), Translation: translation, Speed: speed}
+	return client.caller.Call(ctx, core.ServicePTZ, request, &tptz.RelativeMoveResponse{})
+}
+
+// AbsoluteMove move the PTZ unit of the profile to position, speed is
+// optional
+func (client *Client) AbsoluteMove(ctx context.Context, profile string, position onvif.PTZVector, speed *onvif.PTZSpeed) error {
+	request := tptz.AbsoluteMove{ProfileToken: onvif.ReferenceToken(profile), Position: position, Speed: speed}
+	return client.caller.Call(ctx, core.ServicePTZ, request, &tptz.AbsoluteMoveResponse{})
+}
+
+// Stop stop the pan and tilt and the zoom movements of the profile, as
+// selected
+func (client *Client) Stop(ctx context.Context, profile string, panTilt, zoom bool) error {
+	request := tptz.Stop{ProfileToken: onvif.ReferenceToken(profile), PanTilt: xsd.Boolean(panTilt), Zoom: xsd.Boolean(zoom)}
+	return client.caller.Call(ctx, core.ServicePTZ, request, &tptz.StopResponse{})
+}
+
+// GetPresets return the presets of the profile
+func (client *Client) GetPresets(ctx context.Context, profile string) ([]onvif.PTZPreset, error) {
+	response := tptz.GetPresetsResponse{}
+	err := client.caller.Call(ctx, core.ServicePTZ, tptz.GetPresets{ProfileToken: onvif.ReferenceToken(profile)}, &response)
+	return response.Preset, err
+}
+
+// SetPreset save the current position of the profile as the preset of
+// token, a new preset when token is empty, and return its token
+func (client *Client) SetPreset(ctx context.Context, profile, name, token string) (string, error) {
+	request := tptz.SetPreset{ProfileToken: onvif.ReferenceToken(profile), PresetName: xsd.String(name), PresetToken: onvif.ReferenceToken(token)}
+	response := tptz.SetPresetResponse{}
+	err := client.caller.Call(ctx, core.ServicePTZ, request, &response)
+	return string(response.PresetToken), err
+}
+
+// RemovePreset remove the preset of token from the profile
+func (client *Client) RemovePreset(ctx context.Context, profile, token string) error {
+	request := tptz.RemovePreset{ProfileToken: onvif.ReferenceToken(profile), PresetToken: onvif.ReferenceToken(token)}
+	return client.caller.Call(ctx, core.ServicePTZ, request, &tptz.RemovePresetResponse{})
+}
+
+// GotoPreset move the profile to the preset of token, speed is optional
+func (client *Client) GotoPreset(ctx context.Context, profile, preset string, speed *onvif.PTZSpeed) error {
+	request := tptz.GotoPreset{ProfileToken: onvif.ReferenceToken(profile), PresetToken: onvif.ReferenceToken(preset), Speed: speed}
+	return client.caller.Call(ctx, core.ServicePTZ, request, &tptz.GotoPresetResponse{})
+}
+
+// GotoHomePosition move the profile to its home position, speed is optional
+func (client *Client) GotoHomePosition(ctx context.Context, profile string, speed *onvif.PTZSpeed) error {
+	request := tptz.GotoHomePosition{ProfileToken: onvif.ReferenceToken(profile), Speed: speed}
+	return client.caller.Call(ctx, core.ServicePTZ, request, &tptz.GotoHomePositionResponse{})
+}
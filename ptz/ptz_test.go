@@ -0,0 +1,38 @@
+package ptz
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/core"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+func TestContinuousMove(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, string(body))
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl"><s:Body><tptz:ContinuousMoveResponse/></s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+	client, _ := core.NewClient(core.Params{XAddr: server.URL + "/onvif/device_service"})
+	client.SetEndpoint(core.ServicePTZ, server.URL+"/onvif/ptz")
+	velocity := onvif.PTZSpeed{PanTilt: onvif.Vector2D{X: 0.5, Y: -0.5}}
+	service := New(client)
+	if err := service.ContinuousMove(context.Background(), "profile_1", velocity, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	/* 超时为0时不发送Timeout,使用设备的默认值 */
+	if err := service.ContinuousMove(context.Background(), "profile_1", velocity, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(requests[0], "<tptz:Timeout>PT2S</tptz:Timeout>") || strings.Contains(requests[1], "Timeout") {
+		t.Fatalf("requests %q", requests)
+	}
+}
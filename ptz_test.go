@@ -0,0 +1,52 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+func TestPTZHelpers(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"ContinuousMove": `<tptz:ContinuousMoveResponse/>`,
+		"Stop":           `<tptz:StopResponse/>`,
+		"GotoPreset":     `<tptz:GotoPresetResponse/>`,
+	}, ServicePTZ)
+	ctx := context.Background()
+	velocity := onvif.PTZSpeed{PanTilt: onvif.Vector2D{X: 0.5, Y: -0.5}}
+	if err := dev.ContinuousMove(ctx, "main", velocity, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if requests := fake.sent("ContinuousMove"); len(requests) != 1 || !strings.Contains(requests[0], "<tptz:ProfileToken>main</tptz:ProfileToken>") ||
+		!strings.Contains(requests[0], `x="0.5"`) || !strings.Contains(requests[0], "<tptz:Timeout>PT2S</tptz:Timeout>") {
+		t.Fatalf("requests %q", requests)
+	}
+	if err := dev.Stop(ctx, "main", true, false); err != nil {
+		t.Fatal(err)
+	}
+	if requests := fake.sent("Stop"); len(requests) != 1 || !strings.Contains(requests[0], "<tptz:PanTilt>true</tptz:PanTilt>") || !strings.Contains(requests[0], "<tptz:Zoom>false</tptz:Zoom>") {
+		t.Fatalf("requests %q", requests)
+	}
+	if err := dev.GotoPreset(ctx, "main", "preset_1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if requests := fake.sent("GotoPreset"); len(requests) != 1 || !strings.Contains(requests[0], "<tptz:PresetToken>preset_1</tptz:PresetToken>") || strings.Contains(requests[0], "Speed") {
+		t.Fatalf("requests %q", requests)
+	}
+	fake.set("GotoPreset", `<s:Fault><s:Code><s:Value>s:Sender</s:Value><s:Subcode><s:Value>ter:InvalidArgVal</s:Value><s:Subcode><s:Value>ter:NoToken</s:Value></s:Subcode></s:Subcode></s:Code>`+
+		`<s:Reason><s:Text xml:lang="en">no such preset</s:Text></s:Reason></s:Fault>`)
+	if err := dev.GotoPreset(ctx, "main", "preset_9", nil); err == nil {
+		t.Fatal("fault returned no error")
+	}
+
+	/* 没有PTZ服务的设备不发送请求 */
+	_, camera := newScriptedDevice(t, map[string]string{}, ServiceMedia)
+	var unsupported *NotSupportedError
+	if err := camera.ContinuousMove(ctx, "main", velocity, 0); !errors.As(err, &unsupported) || unsupported.Service != "ptz" {
+		t.Fatalf("error %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package onvif
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HealthQuarantined status of a device quarantined, see Device.Quarantine
+const HealthQuarantined = "quarantined"
+
+// ErrQuarantined returned by the calls to a quarantined device
+var ErrQuarantined = errors.New("device quarantined")
+
+// QuarantineError ErrQuarantined carrying why and when the device was
+// quarantined
+type QuarantineError struct {
+	Device string
+	Reason string
+	Since  time.Time
+}
+
+func (err *QuarantineError) Error() string {
+	return fmt.Sprintf("device %s quarantined since %s: %s", err.Device, err.Since.Format(time.RFC3339), err.Reason)
+}
+
+// Is report the error as ErrQuarantined
+func (err *QuarantineError) Is(target error) bool {
+	return target == ErrQuarantined
+}
+
+// quarantineState quarantine of a device, shared by the copies of the Device
+type quarantineState struct {
+	mutex  sync.Mutex
+	active bool
+	reason string
+	since  time.Time
+}
+
+// Quarantine isolate a misbehaving device until Release: its calls fail at
+// once with a QuarantineError without reaching the device, the health
+// monitor and the snapshot poller skip it and the event engine drops its
+// subscription, recreated at its next retry after the release. It reports
+// false when the device was already quarantined, the first reason is then
+// kept. The HealthMonitor and the EventEngine quarantine the devices
+// faulting or flooding events, see QuarantineAfter and FloodLimit.
+func (dev *Device) Quarantine(reason string) bool {
+	state := dev.quarantine
+	if state == nil {
+		return false
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if state.active {
+		return false
+	}
+	state.active, state.reason, state.since = true, reason, time.Now()
+	return true
+}
+
+// Release end the quarantine of the device, it reports false when the
+// device was not quarantined
+func (dev *Device) Release() bool {
+	state := dev.quarantine
+	if state == nil {
+		return false
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	released := state.active
+	state.active, state.reason, state.since = false, "", time.Time{}
+	return released
+}
+
+// Quarantined return the QuarantineError of the calls to the device while it
+// is quarantined, nil otherwise
+func (dev *Device) Quarantined() *QuarantineError {
+	state := dev.quarantine
+	if state == nil {
+		return nil
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if !state.active {
+		return nil
+	}
+	return &QuarantineError{Device: dev.Params.Ipddr, Reason: state.reason, Since: state.since}
+}
+
+// checkQuarantine return the error of the calls to a quarantined device
+func (dev *Device) checkQuarantine() error {
+	if err := dev.Quarantined(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Quarantined return the quarantined devices of the fleet
+func (fleet *Fleet) Quarantined() []*Device {
+	var quarantined []*Device
+	for _, dev := range fleet.Devices {
+		if dev.Quarantined() != nil {
+			quarantined = append(quarantined, dev)
+		}
+	}
+	return quarantined
+}
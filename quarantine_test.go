@@ -0,0 +1,127 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+func TestQuarantineFailsFast(t *testing.T) {
+	fake := &healthDevice{}
+	dev := newHealthDevice(t, fake)
+	if !dev.Quarantine("faulting") || dev.Quarantine("again") {
+		t.Fatal("quarantine not reported once")
+	}
+	err := dev.CallMethodInterfaceContext(context.Background(), device.GetSystemDateAndTime{}, &device.GetSystemDateAndTimeResponse{}, "")
+	var quarantine *QuarantineError
+	if !errors.Is(err, ErrQuarantined) || !errors.As(err, &quarantine) || quarantine.Reason != "faulting" || fake.count() != 0 {
+		t.Fatalf("error %v, %d requests", err, fake.count())
+	}
+	/* 设备的副本共享隔离状态 */
+	copied := *dev
+	if copied.Quarantined() == nil {
+		t.Fatal("copy of the device not quarantined")
+	}
+	if !dev.Release() || dev.Release() {
+		t.Fatal("release not reported once")
+	}
+	if err := dev.CallMethodInterfaceContext(context.Background(), device.GetSystemDateAndTime{}, &device.GetSystemDateAndTimeResponse{}, ""); err != nil || fake.count() != 1 {
+		t.Fatalf("error %v, %d requests after release", err, fake.count())
+	}
+}
+
+func TestHealthMonitorQuarantine(t *testing.T) {
+	/* 设备可达但持续应答fault */
+	fake := &healthDevice{fault: true}
+	dev := newHealthDevice(t, fake)
+	monitor := NewHealthMonitor(&Fleet{Devices: []*Device{dev}})
+	monitor.QuarantineAfter = 2
+	ctx := context.Background()
+	monitor.Check(ctx)
+	if health := monitor.Check(ctx)[0]; health.Status != HealthQuarantined || dev.Quarantined() == nil {
+		t.Fatalf("status %s after %d faults", health.Status, fake.count())
+	}
+	/* 隔离期间不探测 */
+	monitor.Check(ctx)
+	if fake.count() != 2 {
+		t.Fatalf("%d probes of a quarantined device", fake.count())
+	}
+	fake.set(false, false)
+	dev.Release()
+	if health := monitor.Check(ctx)[0]; health.Status != HealthOnline {
+		t.Fatalf("status %s after release", health.Status)
+	}
+	/* 不可达的设备不隔离 */
+	fake.set(true, false)
+	for i := 0; i < 3; i++ {
+		monitor.Check(ctx)
+	}
+	if dev.Quarantined() != nil {
+		t.Fatal("unreachable device quarantined")
+	}
+}
+
+func TestEventEngineQuarantine(t *testing.T) {
+	fake := &eventDevice{}
+	dev := newEventDevice(t, fake)
+	engine := testEngine(1)
+	engine.Add(dev)
+	runEngine(t, engine)
+	eventually(t, "a pull", func() bool { return fake.count(&fake.pulls) > 0 })
+	dev.Quarantine("manual")
+	/* 隔离期间按退避重试,但不再拉取 */
+	pulls := fake.count(&fake.pulls)
+	time.Sleep(50 * time.Millisecond)
+	stopped := fake.count(&fake.pulls)
+	if stopped > pulls+1 {
+		t.Fatalf("%d pulls of the quarantined device", stopped-pulls)
+	}
+	/* 解除隔离后重新订阅 */
+	dev.Release()
+	eventually(t, "a new subscription", func() bool { return fake.count(&fake.created) == 2 })
+	eventually(t, "a pull of the new subscription", func() bool { return fake.count(&fake.pulls) > stopped })
+}
+
+func TestEventEngineFloodQuarantine(t *testing.T) {
+	fake := &eventDevice{messages: 5}
+	dev := newEventDevice(t, fake)
+	engine := testEngine(1)
+	engine.FloodLimit = 3
+	var mutex sync.Mutex
+	var quarantined error
+	engine.OnError = func(dev *Device, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if errors.Is(err, ErrQuarantined) && quarantined == nil {
+			quarantined = err
+		}
+	}
+	var received int
+	engine.Handle(func(Event) {
+		mutex.Lock()
+		received++
+		mutex.Unlock()
+	})
+	engine.Add(dev)
+	runEngine(t, engine)
+	eventually(t, "the flood quarantine", func() bool { return dev.Quarantined() != nil })
+	if reason := dev.Quarantined().Reason; !strings.HasPrefix(reason, "event flood") {
+		t.Fatalf("quarantine reason %q", reason)
+	}
+	eventually(t, "the quarantine error", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return quarantined != nil
+	})
+	/* 洪泛的事件被丢弃 */
+	mutex.Lock()
+	defer mutex.Unlock()
+	if received != 0 {
+		t.Fatalf("%d flooding events delivered", received)
+	}
+}
@@ -0,0 +1,90 @@
+package onvif
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+// Quirks vendor specific behaviours the package works around
+type Quirks struct {
+	// PresetNameMaxLength longest preset name in bytes the device stores, 0
+	// means unlimited; longer names are truncated by the device
+	PresetNameMaxLength int
+	// PresetNameASCII the device rejects or mangles preset names out of ASCII
+	PresetNameASCII bool
+	// PresetNameNoSpaces the device rejects spaces in preset names
+	PresetNameNoSpaces bool
+	// PresetSlots GetPresets lists every preset slot, the unnamed ones being
+	// free, and SetPreset needs the token of a slot to create a preset
+	PresetSlots bool
+	// MotionTopic topic, without namespace prefix, the device sends its
+	// motion events under, preferred by MotionWatchAnyTopic
+	MotionTopic string
+	// NoSnapshotURI the snapshot URI advertised by the device serves no
+	// usable image, SnapshotOrFrame grabs a frame of the stream instead
+	NoSnapshotURI bool
+}
+
+var (
+	quirksMutex sync.RWMutex
+	// quirkRegistry quirks by lower case manufacturer name
+	quirkRegistry = map[string]Quirks{
+		"axis":      {PresetNameMaxLength: 31, PresetNameASCII: true},
+		"hikvision": {PresetNameMaxLength: 32, PresetSlots: true, MotionTopic: TopicCellMotion},
+		"hanwha":    {PresetNameMaxLength: 12, PresetNameASCII: true, PresetNameNoSpaces: true, PresetSlots: true},
+		"samsung":   {PresetNameMaxLength: 12, PresetNameASCII: true, PresetNameNoSpaces: true, PresetSlots: true},
+	}
+)
+
+// RegisterQuirks set the quirks of the devices whose manufacturer name
+// contains manufacturer, case insensitively
+func RegisterQuirks(manufacturer string, quirks Quirks) {
+	quirksMutex.Lock()
+	defer quirksMutex.Unlock()
+	quirkRegistry[strings.ToLower(manufacturer)] = quirks
+}
+
+// QuirksFor return the quirks registered for the manufacturer, e.g.
+// "Hanwha Techwin" or "HIKVISION"
+func QuirksFor(manufacturer string) Quirks {
+	manufacturer = strings.ToLower(manufacturer)
+	quirksMutex.RLock()
+	defer quirksMutex.RUnlock()
+	/* 取最长的匹配,避免较短的名称覆盖更具体的条目 */
+	quirks, matched := Quirks{}, ""
+	for name, entry := range quirkRegistry {
+		if strings.Contains(manufacturer, name) && len(name) > len(matched) {
+			quirks, matched = entry, name
+		}
+	}
+	return quirks
+}
+
+// Quirks return the quirks of the device, by the manufacturer reported by
+// GetDeviceInformation. The manufacturer is cached on success.
+func (dev *Device) Quirks(ctx context.Context) (Quirks, error) {
+	cache := dev.capabilities
+	if cache != nil {
+		cache.mutex.Lock()
+		loaded, manufacturer := cache.manufacturerLoaded, cache.manufacturer
+		cache.mutex.Unlock()
+		if loaded {
+			return QuirksFor(manufacturer), nil
+		}
+	}
+	/* 请求期间不持有锁,并发调用最多各请求一次 */
+	info := device.GetDeviceInformationResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetDeviceInformation{}, &info, ""); err != nil {
+		return Quirks{}, err
+	}
+	if cache != nil {
+		cache.mutex.Lock()
+		cache.manufacturer = info.Manufacturer
+		cache.manufacturerLoaded = true
+		cache.mutex.Unlock()
+	}
+	return QuirksFor(info.Manufacturer), nil
+}
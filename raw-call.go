@@ -0,0 +1,124 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"text/template"
+
+	"github.com/PolarisM78/go-onvif/core"
+	"github.com/PolarisM78/go-onvif/soap"
+)
+
+// ErrNoRawOperation returned by CallRaw when the template renders no element
+var ErrNoRawOperation = errors.New("raw message has no operation element")
+
+// rawMessage body of a CallRaw, sent verbatim
+type rawMessage struct {
+	// name namespace and local name of the root element of body
+	name xml.Name
+	body []byte
+}
+
+// CallRaw call an operation without generated types, such as the vendor
+// extensions: xmlTemplate is the content of the SOAP Body, executed as a
+// text/template with vars, whose values are XML escaped. service is the
+// endpoint key of the service, e.g. media or deviceio, or the URL of the
+// service. The message goes through the policy, audit and call observers of
+// the typed calls, is signed with WS-Security and carries the wsa:Action
+// derived from the operation element. The content of the response Body is
+// returned, a SOAP fault as an error.
+//
+//	body, err := dev.CallRaw(ctx, "device", `<acme:SetLedMode xmlns:acme="http://acme.com/onvif">`+
+//		`<acme:Mode>{{.Mode}}</acme:Mode></acme:SetLedMode>`, map[string]string{"Mode": "Off"})
+func (dev Device) CallRaw(ctx context.Context, service, xmlTemplate string, vars map[string]string) ([]byte, error) {
+	message, err := renderRawMessage(xmlTemplate, vars)
+	if err != nil {
+		return nil, err
+	}
+	retBytes, err := dev.callRaw(ctx, service, message)
+	if err != nil {
+		return nil, err
+	}
+	return soap.Body(retBytes)
+}
+
+// callRaw send the raw message to service, see CallRaw, and return the whole
+// response, checked not to be a fault
+func (dev Device) callRaw(ctx context.Context, service string, message rawMessage) ([]byte, error) {
+	endpoint := service
+	if !strings.Contains(service, "://") {
+		var err error
+		if endpoint, err = dev.getEndpoint(strings.ToLower(service)); err != nil {
+			return nil, err
+		}
+	}
+	operation := message.name.Local
+	audit := dev.auditOperation(ctx, operation, message)
+	recorder := newCallRecorder(ctx, operation, endpoint)
+	headers := soap.AddressingHeaders(rawAction(message.name), "")
+	retBytes, err := dev.callMethodRead(withAudited(recorder.trace(ctx)), recorder, endpoint, message, headers)
+	if err == nil {
+		_, err = core.ResponseBody(retBytes)
+	}
+	recorder.done(err)
+	audit.done(err)
+	dev.configurationChanged(operation, err)
+	if err != nil {
+		return nil, err
+	}
+	return retBytes, nil
+}
+
+// renderRawMessage execute the template and find its operation element
+func renderRawMessage(xmlTemplate string, vars map[string]string) (rawMessage, error) {
+	tmpl, err := template.New("raw").Option("missingkey=error").Parse(xmlTemplate)
+	if err != nil {
+		return rawMessage{}, err
+	}
+	/* 变量值按xml转义,防止注入额外元素 */
+	escaped := make(map[string]string, len(vars))
+	for key, value := range vars {
+		buf := bytes.Buffer{}
+		xml.EscapeText(&buf, []byte(value))
+		escaped[key] = buf.String()
+	}
+	buf := bytes.Buffer{}
+	if err := tmpl.Execute(&buf, escaped); err != nil {
+		return rawMessage{}, err
+	}
+	return parseRawMessage(buf.Bytes())
+}
+
+// parseRawMessage return the raw message of body, named after its operation
+// element
+func parseRawMessage(body []byte) (rawMessage, error) {
+	message := rawMessage{body: body}
+	decoder := xml.NewDecoder(bytes.NewReader(message.body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return rawMessage{}, ErrNoRawOperation
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			message.name = start.Name
+			break
+		}
+	}
+	/* 前缀在信封上声明时解析为相应命名空间 */
+	if namespace, ok := Xlmns[message.name.Space]; ok {
+		message.name.Space = namespace
+	}
+	return message, nil
+}
+
+// rawAction return the wsa:Action of the operation, its namespace followed
+// by its name as in the ONVIF WSDLs, none without namespace
+func rawAction(name xml.Name) string {
+	if name.Space == "" {
+		return ""
+	}
+	return strings.TrimSuffix(name.Space, "/") + "/" + name.Local
+}
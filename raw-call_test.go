@@ -0,0 +1,70 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCallRaw(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"SetLedMode":           `<acme:SetLedModeResponse xmlns:acme="http://acme.com/onvif"><acme:Mode>Off</acme:Mode></acme:SetLedModeResponse>`,
+		"GetDeviceInformation": `<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer></tds:GetDeviceInformationResponse>`,
+	})
+	var mutex sync.Mutex
+	var records []OperationRecord
+	dev.Params.AuditSink = AuditSinkFunc(func(record OperationRecord) {
+		mutex.Lock()
+		records = append(records, record)
+		mutex.Unlock()
+	})
+	ctx := context.Background()
+	ledMode := `<acme:SetLedMode xmlns:acme="http://acme.com/onvif"><acme:Mode>{{.Mode}}</acme:Mode></acme:SetLedMode>`
+	body, err := dev.CallRaw(ctx, "device", ledMode, map[string]string{"Mode": "Off</acme:Mode><acme:Reset/>"})
+	if err != nil || !strings.Contains(string(body), "<acme:Mode>Off</acme:Mode>") {
+		t.Fatalf("body %s, %v", body, err)
+	}
+	/* 变量值被转义,无法注入元素 */
+	requests := fake.sent("SetLedMode")
+	if len(requests) != 1 || !strings.Contains(requests[0], "<acme:Mode>Off&lt;/acme:Mode&gt;&lt;acme:Reset/&gt;</acme:Mode>") ||
+		!strings.Contains(requests[0], ">http://acme.com/onvif/SetLedMode</a:Action>") {
+		t.Fatalf("requests %q", requests)
+	}
+	mutex.Lock()
+	if len(records) != 1 || records[0].Operation != "SetLedMode" || records[0].ParametersHash == "" {
+		t.Fatalf("records %+v", records)
+	}
+	mutex.Unlock()
+
+	/* 信封上声明的前缀解析为相应命名空间 */
+	if _, err := dev.CallRaw(ctx, dev.endpoints[ServiceDevice], `<tds:GetDeviceInformation/>`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if requests := fake.sent("GetDeviceInformation"); len(requests) != 1 || !strings.Contains(requests[0], ">http://www.onvif.org/ver10/device/wsdl/GetDeviceInformation</a:Action>") {
+		t.Fatalf("requests %q", requests)
+	}
+
+	if _, err := dev.CallRaw(ctx, "device", `<acme:Reboot xmlns:acme="http://acme.com/onvif"/>`, nil); err == nil {
+		t.Fatal("fault returned no error")
+	}
+	if _, err := dev.CallRaw(ctx, "device", ledMode, nil); err == nil {
+		t.Fatal("missing variable accepted")
+	}
+	if _, err := dev.CallRaw(ctx, "device", "{{.Mode}}", map[string]string{"Mode": "Off"}); !errors.Is(err, ErrNoRawOperation) {
+		t.Fatalf("error %v without operation element", err)
+	}
+	if _, err := dev.CallRaw(ctx, "deviceio", ledMode, map[string]string{"Mode": "Off"}); err == nil {
+		t.Fatal("unknown service accepted")
+	}
+
+	/* 受限设备的原始调用同样受策略约束 */
+	var denied *PolicyError
+	if _, err := dev.WithRole(RoleReadOnly).CallRaw(ctx, "device", ledMode, map[string]string{"Mode": "Off"}); !errors.As(err, &denied) || denied.Operation != "SetLedMode" {
+		t.Fatalf("error %v", err)
+	}
+	if len(fake.sent("SetLedMode")) != 1 {
+		t.Fatal("denied operation sent")
+	}
+}
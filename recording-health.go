@@ -0,0 +1,150 @@
+package onvif
+
+import (
+	"strings"
+	"time"
+)
+
+// Topics of the recording and storage events watched by a HealthMonitor
+const (
+	TopicRecordingJobState         = "RecordingConfig/JobState"
+	TopicRecordingJobConfiguration = "RecordingConfig/RecordingJobConfiguration"
+	TopicStorageFailure            = "Device/HardwareFailure/StorageFailure"
+)
+
+// HealthDegraded status of a device answering its probes while a storage or
+// recording alarm is in progress
+const HealthDegraded = "degraded"
+
+// Kinds of HealthAlarm
+const (
+	AlarmStorage   = "storage"
+	AlarmRecording = "recording"
+)
+
+// HealthAlarm storage failure or recording failure reported by a device
+type HealthAlarm struct {
+	// Kind AlarmStorage or AlarmRecording
+	Kind string
+	// Source token of the storage or of the recording job
+	Source string
+	// Detail e.g. "storage failed" or "recording job state Error"
+	Detail string
+	Since  time.Time
+}
+
+func onlineStatus(alarms []HealthAlarm) string {
+	if len(alarms) > 0 {
+		return HealthDegraded
+	}
+	return HealthOnline
+}
+
+// healthEvent change carried by a recording or storage event: an alarm
+// raised or cleared, and the state of a recording job
+type healthEvent struct {
+	// alarm the event tells whether the alarm is raised, not the case of
+	// the configuration changes
+	alarm  *HealthAlarm
+	raised bool
+	job    string
+	state  string
+}
+
+// parseHealthEvent return the storage or recording change carried by ev
+func parseHealthEvent(ev Event) (healthEvent, bool) {
+	switch path := topicPath(ev.Topic); {
+	case path == TopicStorageFailure || (strings.Contains(path, "Storage") && strings.Contains(path, "Fail")):
+		failed, ok := logicalState(itemValue(ev.Data, "Failed", "State", "Failure"))
+		if !ok {
+			/* 非属性类的故障事件本身即表示故障 */
+			failed = ev.Operation == ""
+		}
+		source := itemValue(ev.Source, "Token", "StorageToken", "Storage", "Disk")
+		return healthEvent{alarm: &HealthAlarm{Kind: AlarmStorage, Source: source, Detail: "storage failed", Since: ev.Time}, raised: failed}, true
+	case path == TopicRecordingJobState:
+		job, state := itemValue(ev.Source, "RecordingJobToken"), itemValue(ev.Data, "State")
+		alarm := &HealthAlarm{Kind: AlarmRecording, Source: job, Detail: "recording job state " + state, Since: ev.Time}
+		return healthEvent{alarm: alarm, raised: state == "Error" || state == "PartiallyActive", job: job, state: state}, job != ""
+	case path == TopicRecordingJobConfiguration:
+		job := itemValue(ev.Source, "RecordingJobToken")
+		mode := ""
+		for _, leaf := range xmlLeaves([]byte(ev.Elements["Configuration"])) {
+			if leaf.name() == "mode" {
+				mode = leaf.value
+			}
+		}
+		if job == "" || mode == "" {
+			return healthEvent{}, false
+		}
+		/* 配置变更不产生告警,只记录作业模式 */
+		return healthEvent{job: job, state: mode}, true
+	}
+	return healthEvent{}, false
+}
+
+// WatchEvents surface the storage failures and the recording job failures
+// reported by the events of engine into the health of the devices: a device
+// with an alarm in progress is HealthDegraded. OnChange is called when an
+// alarm is raised or cleared.
+func (monitor *HealthMonitor) WatchEvents(engine *EventEngine) {
+	engine.Handle(monitor.handleEvent)
+}
+
+func (monitor *HealthMonitor) handleEvent(ev Event) {
+	change, ok := parseHealthEvent(ev)
+	if !ok || ev.Operation == "Deleted" {
+		return
+	}
+	var dev *Device
+	for _, candidate := range monitor.fleet().Devices {
+		if candidate.Params.Ipddr == ev.Device {
+			dev = candidate
+			break
+		}
+	}
+	if dev == nil {
+		return
+	}
+	monitor.restore(dev)
+	monitor.mutex.Lock()
+	health, _ := monitor.current(dev)
+	health.Device = dev
+	if change.job != "" {
+		jobs := make(map[string]string, len(health.RecordingJobs)+1)
+		for job, state := range health.RecordingJobs {
+			jobs[job] = state
+		}
+		jobs[change.job] = change.state
+		health.RecordingJobs = jobs
+	}
+	changed := false
+	if change.alarm != nil {
+		index := -1
+		for i, alarm := range health.Alarms {
+			if alarm.Kind == change.alarm.Kind && alarm.Source == change.alarm.Source {
+				index = i
+			}
+		}
+		/* 告警列表可能已被Status返回,修改时复制 */
+		alarms := append([]HealthAlarm(nil), health.Alarms...)
+		switch {
+		case change.raised && index < 0:
+			alarms = append(alarms, *change.alarm)
+			changed = true
+		case !change.raised && index >= 0:
+			alarms = append(alarms[:index], alarms[index+1:]...)
+			changed = true
+		}
+		health.Alarms = alarms
+	}
+	if health.Status != "" && health.Status != HealthOffline {
+		health.Status = onlineStatus(health.Alarms)
+	}
+	monitor.health[dev] = health
+	monitor.mutex.Unlock()
+	monitor.persist(health)
+	if changed && monitor.OnChange != nil {
+		monitor.OnChange(health)
+	}
+}
@@ -0,0 +1,46 @@
+package onvif
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHealthMonitorEvents(t *testing.T) {
+	dev := newHealthDevice(t, &healthDevice{})
+	monitor := NewHealthMonitor(&Fleet{Devices: []*Device{dev}})
+	var changes []int
+	monitor.OnChange = func(health DeviceHealth) { changes = append(changes, len(health.Alarms)) }
+	monitor.WatchEvents(testEngine(1))
+	ctx := context.Background()
+	if status := monitor.Check(ctx); status[0].Status != HealthOnline {
+		t.Fatalf("status %+v", status[0])
+	}
+	changes = nil
+	address := dev.Params.Ipddr
+
+	/* 非属性类的故障事件本身即表示故障,探测不清除告警 */
+	monitor.handleEvent(Event{Device: address, Topic: "tns1:Device/HardwareFailure/StorageFailure", Source: map[string]string{"Token": "sd0"}})
+	monitor.handleEvent(Event{Device: address, Topic: "tns1:RecordingConfig/JobState", Operation: "Changed",
+		Source: map[string]string{"RecordingJobToken": "job1"}, Data: map[string]string{"State": "Error"}})
+	status := monitor.Check(ctx)[0]
+	if status.Status != HealthDegraded || len(status.Alarms) != 2 || status.Alarms[0] != (HealthAlarm{Kind: AlarmStorage, Source: "sd0", Detail: "storage failed"}) ||
+		status.Alarms[1].Detail != "recording job state Error" || status.RecordingJobs["job1"] != "Error" {
+		t.Fatalf("status %+v", status)
+	}
+
+	monitor.handleEvent(Event{Device: address, Topic: "tns1:RecordingConfig/JobState", Operation: "Changed",
+		Source: map[string]string{"RecordingJobToken": "job1"}, Data: map[string]string{"State": "Active"}})
+	monitor.handleEvent(Event{Device: address, Topic: "tns1:Device/HardwareFailure/StorageFailure", Operation: "Changed",
+		Source: map[string]string{"Token": "sd0"}, Data: map[string]string{"Failed": "false"}})
+	/* 配置变更只记录作业模式 */
+	monitor.handleEvent(Event{Device: address, Topic: "tns1:RecordingConfig/RecordingJobConfiguration", Operation: "Changed",
+		Source: map[string]string{"RecordingJobToken": "job2"}, Elements: map[string]string{"Configuration": `<tt:RecordingToken>rec</tt:RecordingToken><tt:Mode>Idle</tt:Mode>`}})
+	monitor.handleEvent(Event{Device: "other", Topic: "tns1:Device/HardwareFailure/StorageFailure"})
+	status = monitor.Status()[0]
+	if status.Status != HealthOnline || len(status.Alarms) != 0 || status.RecordingJobs["job1"] != "Active" || status.RecordingJobs["job2"] != "Idle" {
+		t.Fatalf("status %+v", status)
+	}
+	if len(changes) != 4 || changes[0] != 1 || changes[1] != 2 || changes[2] != 1 || changes[3] != 0 {
+		t.Fatalf("changes %v", changes)
+	}
+}
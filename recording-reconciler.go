@@ -0,0 +1,205 @@
+package onvif
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/recording"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ReconcilerCaller caller of the repairs of a RecordingReconciler in the
+// OperationRecords of the devices
+const ReconcilerCaller = "recording-reconciler"
+
+// ReconcileAction repair of a recording job by a RecordingReconciler
+type ReconcileAction struct {
+	Time   time.Time
+	Device string
+	Job    string
+	// State reported by GetRecordingJobState, Mode the mode set
+	State string
+	Mode  string
+	// Err error of the repair, nil when the mode was set
+	Err error
+}
+
+// RecordingReconciler keep the recording jobs of the devices in their
+// desired mode: the state of the jobs is compared to the desired mode every
+// Interval, and at once when the device reports a job state change, and the
+// jobs which drifted, e.g. turned Idle by a reboot, are set back. The repairs
+// are recorded by the AuditSink of the devices with ReconcilerCaller.
+//
+//	reconciler := onvif.NewRecordingReconciler(engine)
+//	reconciler.Desire(dev, "RecordingJob_1", recording.ModeActive)
+//	go reconciler.Run(ctx)
+type RecordingReconciler struct {
+	// Interval between two comparisons, 0 means 1m
+	Interval time.Duration
+	// OnAction called with every repair
+	OnAction func(ReconcileAction)
+	// OnError called with the errors reading the state of a job
+	OnError func(dev *Device, job string, err error)
+
+	mutex   sync.Mutex
+	targets map[string]*reconcileTarget
+	wake    chan string
+}
+
+type reconcileTarget struct {
+	dev *Device
+	// jobs desired mode of the jobs
+	jobs map[string]string
+}
+
+// NewRecordingReconciler return a reconciler also driven by the job state
+// events of engine, engine may be nil to compare every Interval only
+func NewRecordingReconciler(engine *EventEngine) *RecordingReconciler {
+	reconciler := &RecordingReconciler{targets: make(map[string]*reconcileTarget), wake: make(chan string, 16)}
+	if engine != nil {
+		engine.Handle(reconciler.handle)
+	}
+	return reconciler
+}
+
+// Desire keep the job of dev in mode, recording.ModeActive or
+// recording.ModeIdle
+func (reconciler *RecordingReconciler) Desire(dev *Device, job, mode string) {
+	reconciler.mutex.Lock()
+	defer reconciler.mutex.Unlock()
+	if reconciler.targets == nil {
+		reconciler.targets = make(map[string]*reconcileTarget)
+	}
+	target, ok := reconciler.targets[dev.Params.Ipddr]
+	if !ok {
+		target = &reconcileTarget{dev: dev, jobs: make(map[string]string)}
+		reconciler.targets[dev.Params.Ipddr] = target
+	}
+	target.jobs[job] = mode
+}
+
+// Forget stop reconciling the job of dev
+func (reconciler *RecordingReconciler) Forget(dev *Device, job string) {
+	reconciler.mutex.Lock()
+	defer reconciler.mutex.Unlock()
+	if target, ok := reconciler.targets[dev.Params.Ipddr]; ok {
+		delete(target.jobs, job)
+		if len(target.jobs) == 0 {
+			delete(reconciler.targets, dev.Params.Ipddr)
+		}
+	}
+}
+
+// handle wake the reconciliation of a device reporting a job state other
+// than the desired one
+func (reconciler *RecordingReconciler) handle(ev Event) {
+	change, ok := parseHealthEvent(ev)
+	if !ok || change.state == "" {
+		return
+	}
+	reconciler.mutex.Lock()
+	target, found := reconciler.targets[ev.Device]
+	mode := ""
+	if found {
+		mode = target.jobs[change.job]
+	}
+	reconciler.mutex.Unlock()
+	if mode == "" || !jobDrifted(mode, change.state) {
+		return
+	}
+	/* 处理函数不能阻塞,队列满时等待周期比对 */
+	select {
+	case reconciler.wake <- ev.Device:
+	default:
+	}
+}
+
+// jobDrifted report whether a job in state must be set to mode
+func jobDrifted(mode, state string) bool {
+	if mode == recording.ModeActive {
+		return state == recording.ModeIdle
+	}
+	return state == recording.ModeActive || state == "PartiallyActive"
+}
+
+// Reconcile compare the jobs of every device to their desired mode once and
+// repair the drifted ones
+func (reconciler *RecordingReconciler) Reconcile(ctx context.Context) []ReconcileAction {
+	reconciler.mutex.Lock()
+	fleet := &Fleet{}
+	for _, target := range reconciler.targets {
+		fleet.Devices = append(fleet.Devices, target.dev)
+	}
+	reconciler.mutex.Unlock()
+	var mutex sync.Mutex
+	var actions []ReconcileAction
+	fleet.each(ctx, func(i int, dev *Device) {
+		deviceActions := reconciler.reconcileDevice(ctx, dev)
+		mutex.Lock()
+		actions = append(actions, deviceActions...)
+		mutex.Unlock()
+	})
+	return actions
+}
+
+// reconcileDevice compare and repair the jobs of dev
+func (reconciler *RecordingReconciler) reconcileDevice(ctx context.Context, dev *Device) []ReconcileAction {
+	reconciler.mutex.Lock()
+	target, ok := reconciler.targets[dev.Params.Ipddr]
+	jobs := make(map[string]string)
+	if ok {
+		for job, mode := range target.jobs {
+			jobs[job] = mode
+		}
+	}
+	reconciler.mutex.Unlock()
+
+	ctx = WithCaller(ctx, ReconcilerCaller)
+	var actions []ReconcileAction
+	for job, mode := range jobs {
+		response := recording.GetRecordingJobStateResponse{}
+		request := recording.GetRecordingJobState{JobToken: onvif.ReferenceToken(job)}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			if reconciler.OnError != nil {
+				reconciler.OnError(dev, job, err)
+			}
+			continue
+		}
+		state := response.State.State
+		if !jobDrifted(mode, state) {
+			continue
+		}
+		action := ReconcileAction{Time: time.Now(), Device: dev.Params.Ipddr, Job: job, State: state, Mode: mode}
+		action.Err = dev.SetRecordingJobMode(ctx, job, mode)
+		if reconciler.OnAction != nil {
+			reconciler.OnAction(action)
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// Run reconcile every Interval, and the devices reporting a drift as soon as
+// their event is received, until ctx is done
+func (reconciler *RecordingReconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(durationOr(reconciler.Interval, time.Minute))
+	defer ticker.Stop()
+	reconciler.Reconcile(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			reconciler.Reconcile(ctx)
+		case address := <-reconciler.wake:
+			/* 事件触发只比对相应设备 */
+			reconciler.mutex.Lock()
+			target, ok := reconciler.targets[address]
+			reconciler.mutex.Unlock()
+			if ok {
+				reconciler.reconcileDevice(ctx, target.dev)
+			}
+		}
+	}
+}
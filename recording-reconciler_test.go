@@ -0,0 +1,104 @@
+package onvif
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/recording"
+)
+
+func jobState(state string) string {
+	return `<trc:GetRecordingJobStateResponse><trc:State><tt:RecordingToken>rec</tt:RecordingToken><tt:State>` + state + `</tt:State></trc:State></trc:GetRecordingJobStateResponse>`
+}
+
+func TestRecordingReconciler(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetRecordingJobState": jobState("Idle"),
+		"SetRecordingJobMode":  `<trc:SetRecordingJobModeResponse/>`,
+	}, ServiceRecording)
+	var mutex sync.Mutex
+	var records []OperationRecord
+	dev.Params.AuditSink = AuditSinkFunc(func(record OperationRecord) {
+		mutex.Lock()
+		records = append(records, record)
+		mutex.Unlock()
+	})
+	reconciler := NewRecordingReconciler(nil)
+	var failed []string
+	reconciler.OnError = func(dev *Device, job string, err error) { failed = append(failed, job) }
+	reconciler.Desire(dev, "job1", recording.ModeActive)
+	ctx := context.Background()
+
+	/* 重启后作业变为Idle,恢复为期望的模式并记录审计 */
+	actions := reconciler.Reconcile(ctx)
+	if len(actions) != 1 || actions[0].Job != "job1" || actions[0].State != "Idle" || actions[0].Mode != recording.ModeActive || actions[0].Err != nil {
+		t.Fatalf("actions %+v", actions)
+	}
+	if requests := fake.sent("SetRecordingJobMode"); len(requests) != 1 || !strings.Contains(requests[0], "<trc:Mode>Active</trc:Mode>") {
+		t.Fatalf("requests %q", requests)
+	}
+	mutex.Lock()
+	if len(records) != 1 || records[0].Caller != ReconcilerCaller || records[0].Operation != "SetRecordingJobMode" {
+		t.Fatalf("records %+v", records)
+	}
+	mutex.Unlock()
+
+	fake.set("GetRecordingJobState", jobState("Active"))
+	if actions := reconciler.Reconcile(ctx); len(actions) != 0 {
+		t.Fatalf("actions %+v", actions)
+	}
+	/* 期望Idle时部分运行也需要修复 */
+	reconciler.Desire(dev, "job1", recording.ModeIdle)
+	fake.set("GetRecordingJobState", jobState("PartiallyActive"))
+	if actions := reconciler.Reconcile(ctx); len(actions) != 1 || actions[0].Mode != recording.ModeIdle {
+		t.Fatalf("actions %+v", actions)
+	}
+
+	fake.set("GetRecordingJobState", `<s:Fault><s:Code><s:Value>s:Receiver</s:Value></s:Code><s:Reason><s:Text xml:lang="en">failed</s:Text></s:Reason></s:Fault>`)
+	if actions := reconciler.Reconcile(ctx); len(actions) != 0 || len(failed) != 1 || failed[0] != "job1" {
+		t.Fatalf("actions %+v, failed %v", actions, failed)
+	}
+
+	reconciler.Forget(dev, "job1")
+	fake.set("GetRecordingJobState", jobState("Active"))
+	if actions := reconciler.Reconcile(ctx); len(actions) != 0 || len(fake.sent("SetRecordingJobMode")) != 2 {
+		t.Fatalf("actions %+v after forgetting the job", actions)
+	}
+}
+
+func TestRecordingReconcilerEvents(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetRecordingJobState": jobState("Active"),
+		"SetRecordingJobMode":  `<trc:SetRecordingJobModeResponse/>`,
+	}, ServiceRecording)
+	reconciler := NewRecordingReconciler(testEngine(1))
+	reconciler.Interval = time.Hour
+	reconciler.Desire(dev, "job1", recording.ModeActive)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- reconciler.Run(ctx) }()
+	eventually(t, "first comparison", func() bool { return len(fake.sent("GetRecordingJobState")) == 1 })
+
+	/* 状态与期望一致或来自其他作业的事件不触发比对 */
+	address := dev.Params.Ipddr
+	reconciler.handle(Event{Device: address, Topic: "tns1:RecordingConfig/JobState", Operation: "Changed",
+		Source: map[string]string{"RecordingJobToken": "job1"}, Data: map[string]string{"State": "Active"}})
+	reconciler.handle(Event{Device: address, Topic: "tns1:RecordingConfig/JobState", Operation: "Changed",
+		Source: map[string]string{"RecordingJobToken": "job2"}, Data: map[string]string{"State": "Idle"}})
+	time.Sleep(50 * time.Millisecond)
+	if requests := fake.sent("GetRecordingJobState"); len(requests) != 1 {
+		t.Fatalf("%d comparisons", len(requests))
+	}
+
+	fake.set("GetRecordingJobState", jobState("Idle"))
+	reconciler.handle(Event{Device: address, Topic: "tns1:RecordingConfig/JobState", Operation: "Changed",
+		Source: map[string]string{"RecordingJobToken": "job1"}, Data: map[string]string{"State": "Idle"}})
+	eventually(t, "job repaired", func() bool { return len(fake.sent("SetRecordingJobMode")) == 1 })
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("run ended with %v", err)
+	}
+}
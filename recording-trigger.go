@@ -0,0 +1,204 @@
+package onvif
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/recording"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// RecordingTriggerTopics topics starting a recording by default: motion and
+// line crossing
+var RecordingTriggerTopics = []string{
+	"RuleEngine/CellMotionDetector/Motion",
+	"RuleEngine/MotionRegionDetector/Motion",
+	"RuleEngine/LineDetector/Crossed",
+	"VideoSource/MotionAlarm",
+}
+
+// RecordingTrigger event triggered edge recording of Profile G devices: the
+// recording jobs of a device are set Active when one of its events passes
+// Filter, and back Idle once no event passed for Duration.
+//
+//	trigger := onvif.NewRecordingTrigger(engine)
+//	trigger.Add(dev)
+type RecordingTrigger struct {
+	// Filter events starting a recording, the active states of
+	// RecordingTriggerTopics when nil
+	Filter func(Event) bool
+	// Duration of the recording after the last event, 0 means 30s
+	Duration time.Duration
+	// OnError called with the errors of the mode changes of a job
+	OnError func(dev *Device, job string, err error)
+
+	mutex   sync.Mutex
+	targets map[string]*recordingTarget
+}
+
+type recordingTarget struct {
+	dev  *Device
+	jobs []string
+	// active recording requested, until the deadline
+	active   bool
+	deadline time.Time
+	timer    *time.Timer
+
+	// calls serializes the mode changes, applied is the mode last set
+	calls   sync.Mutex
+	applied string
+}
+
+// NewRecordingTrigger return a trigger driven by the events of engine
+func NewRecordingTrigger(engine *EventEngine) *RecordingTrigger {
+	trigger := &RecordingTrigger{targets: make(map[string]*recordingTarget)}
+	engine.Handle(trigger.handle)
+	return trigger
+}
+
+// Add drive the recording jobs of dev, every job of the device when none is
+// given. The events of dev must be pulled by the engine of the trigger.
+func (trigger *RecordingTrigger) Add(dev *Device, jobs ...string) {
+	trigger.mutex.Lock()
+	defer trigger.mutex.Unlock()
+	if trigger.targets == nil {
+		trigger.targets = make(map[string]*recordingTarget)
+	}
+	trigger.targets[dev.Params.Ipddr] = &recordingTarget{dev: dev, jobs: jobs}
+}
+
+// defaultRecordingFilter keep the events of RecordingTriggerTopics which do
+// not report the end of a detection
+func defaultRecordingFilter(ev Event) bool {
+	if !TopicFilter(RecordingTriggerTopics...)(ev) {
+		return false
+	}
+	active, ok := logicalState(itemValue(ev.Data, "IsMotion", "State", "LogicalState", "IsInside"))
+	return !ok || active
+}
+
+func (trigger *RecordingTrigger) handle(ev Event) {
+	filter := trigger.Filter
+	if filter == nil {
+		filter = defaultRecordingFilter
+	}
+	if !filter(ev) {
+		return
+	}
+	duration := durationOr(trigger.Duration, 30*time.Second)
+	trigger.mutex.Lock()
+	target, ok := trigger.targets[ev.Device]
+	if !ok {
+		trigger.mutex.Unlock()
+		return
+	}
+	target.deadline = time.Now().Add(duration)
+	started := !target.active
+	target.active = true
+	if target.timer == nil {
+		target.timer = time.AfterFunc(duration, func() { trigger.expire(target) })
+	}
+	trigger.mutex.Unlock()
+	/* 处理函数不能阻塞,模式切换在独立goroutine中进行 */
+	if started {
+		go trigger.apply(target)
+	}
+}
+
+// expire end the recording once the deadline passed, or wait for the
+// deadline pushed by the later events
+func (trigger *RecordingTrigger) expire(target *recordingTarget) {
+	trigger.mutex.Lock()
+	if target.timer == nil {
+		/* 已被Stop取消 */
+		trigger.mutex.Unlock()
+		return
+	}
+	if remaining := time.Until(target.deadline); remaining > 0 {
+		target.timer.Reset(remaining)
+		trigger.mutex.Unlock()
+		return
+	}
+	target.active, target.timer = false, nil
+	trigger.mutex.Unlock()
+	trigger.apply(target)
+}
+
+// apply set the jobs of target to the requested mode
+func (trigger *RecordingTrigger) apply(target *recordingTarget) {
+	target.calls.Lock()
+	defer target.calls.Unlock()
+	trigger.mutex.Lock()
+	mode := recording.ModeIdle
+	if target.active {
+		mode = recording.ModeActive
+	}
+	trigger.mutex.Unlock()
+	if mode == target.applied || (target.applied == "" && mode == recording.ModeIdle) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if len(target.jobs) == 0 {
+		jobs, err := target.dev.RecordingJobs(ctx)
+		if err != nil {
+			trigger.fail(target.dev, "", err)
+			return
+		}
+		for _, job := range jobs {
+			target.jobs = append(target.jobs, string(job.JobToken))
+		}
+	}
+	for _, job := range target.jobs {
+		if err := target.dev.SetRecordingJobMode(ctx, job, mode); err != nil {
+			trigger.fail(target.dev, job, err)
+		}
+	}
+	target.applied = mode
+}
+
+func (trigger *RecordingTrigger) fail(dev *Device, job string, err error) {
+	if trigger.OnError != nil {
+		trigger.OnError(dev, job, err)
+	}
+}
+
+// Stop cancel the pending recordings and set their jobs back Idle
+func (trigger *RecordingTrigger) Stop() {
+	trigger.mutex.Lock()
+	var active []*recordingTarget
+	for _, target := range trigger.targets {
+		if target.timer != nil {
+			target.timer.Stop()
+			target.timer = nil
+		}
+		if target.active {
+			target.active = false
+			active = append(active, target)
+		}
+	}
+	trigger.mutex.Unlock()
+	for _, target := range active {
+		trigger.apply(target)
+	}
+}
+
+// RecordingJobs return the recording jobs of the device
+func (dev *Device) RecordingJobs(ctx context.Context) ([]recording.JobItem, error) {
+	if _, err := dev.getEndpoint("recording"); err != nil {
+		return nil, &NotSupportedError{Service: "recording"}
+	}
+	response := recording.GetRecordingJobsResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, recording.GetRecordingJobs{}, &response, ""); err != nil {
+		return nil, err
+	}
+	return response.JobItem, nil
+}
+
+// SetRecordingJobMode set the mode of the recording job, recording.ModeIdle
+// or recording.ModeActive
+func (dev *Device) SetRecordingJobMode(ctx context.Context, job, mode string) error {
+	request := recording.SetRecordingJobMode{JobToken: onvif.ReferenceToken(job), Mode: mode}
+	return dev.CallMethodInterfaceContext(ctx, request, &recording.SetRecordingJobModeResponse{}, "")
+}
@@ -0,0 +1,62 @@
+package onvif
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordingTrigger(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetRecordingJobs": `<trc:GetRecordingJobsResponse><trc:JobItem><tt:JobToken>job1</tt:JobToken></trc:JobItem>` +
+			`<trc:JobItem><tt:JobToken>job2</tt:JobToken></trc:JobItem></trc:GetRecordingJobsResponse>`,
+		"SetRecordingJobMode": `<trc:SetRecordingJobModeResponse/>`,
+	}, ServiceRecording)
+	modes := func() []string {
+		var modes []string
+		for _, request := range fake.sent("SetRecordingJobMode") {
+			for _, mode := range []string{"Active", "Idle"} {
+				if strings.Contains(request, "<trc:Mode>"+mode+"</trc:Mode>") {
+					modes = append(modes, mode)
+				}
+			}
+		}
+		return modes
+	}
+	trigger := NewRecordingTrigger(testEngine(1))
+	trigger.Duration = 200 * time.Millisecond
+	trigger.Add(dev)
+	motion := func(state string) Event {
+		return Event{Device: dev.Params.Ipddr, Topic: "tns1:VideoSource/MotionAlarm", Data: map[string]string{"State": state}}
+	}
+
+	/* 检测结束的事件不触发录像 */
+	trigger.handle(motion("false"))
+	trigger.handle(Event{Device: "other", Topic: "tns1:VideoSource/MotionAlarm"})
+	time.Sleep(20 * time.Millisecond)
+	if sent := fake.sent("SetRecordingJobMode"); len(sent) != 0 {
+		t.Fatalf("requests %q", sent)
+	}
+	trigger.handle(motion("true"))
+	eventually(t, "the jobs set active", func() bool { return len(modes()) == 2 })
+	/* 录像期间的事件延长截止时间 */
+	time.Sleep(120 * time.Millisecond)
+	trigger.handle(motion("true"))
+	time.Sleep(120 * time.Millisecond)
+	if got := strings.Join(modes(), ","); got != "Active,Active" {
+		t.Fatalf("modes %s before the extended deadline", got)
+	}
+	eventually(t, "the jobs set idle", func() bool { return len(modes()) == 4 })
+	if got := strings.Join(modes(), ","); got != "Active,Active,Idle,Idle" || len(fake.sent("GetRecordingJobs")) != 1 {
+		t.Fatalf("modes %s", got)
+	}
+
+	/* Stop取消等待并恢复Idle */
+	trigger.Duration = time.Hour
+	trigger.handle(motion("true"))
+	eventually(t, "the jobs set active again", func() bool { return len(modes()) == 6 })
+	trigger.Stop()
+	if got := strings.Join(modes()[4:], ","); got != "Active,Active,Idle,Idle" {
+		t.Fatalf("modes %s after Stop", got)
+	}
+}
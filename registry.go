@@ -0,0 +1,386 @@
+package onvif
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RegistryEntry device of a Registry with its labels and groups
+type RegistryEntry struct {
+	ID     string
+	Device *Device
+	// Labels e.g. site=paris, building=b2, orientation=north
+	Labels map[string]string
+	Groups []string
+}
+
+// HasLabels report whether the entry carries every label of selector
+func (entry RegistryEntry) HasLabels(selector map[string]string) bool {
+	for key, value := range selector {
+		if entry.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// RegistryRecord persisted form of a RegistryEntry
+type RegistryRecord struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+	// Addresses secondary addresses, see DeviceParams.Addresses
+	Addresses  []string          `json:"addresses,omitempty"`
+	ServiceURL string            `json:"serviceUrl,omitempty"`
+	Username   string            `json:"username,omitempty"`
+	Password   string            `json:"password,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Groups     []string          `json:"groups,omitempty"`
+}
+
+// RegistryStore persistence hook of a Registry, every change of an entry is
+// saved as a whole record
+type RegistryStore interface {
+	LoadDevices() ([]RegistryRecord, error)
+	SaveDevice(record RegistryRecord) error
+	DeleteDevice(id string) error
+}
+
+// ErrUnknownDevice returned for an id not in the registry
+var ErrUnknownDevice = errors.New("unknown device")
+
+// Registry devices indexed by id, with labels and groups to select the
+// fleet an operation runs on
+type Registry struct {
+	// Store optional persistence of the entries
+	Store RegistryStore
+
+	mutex   sync.RWMutex
+	entries map[string]*RegistryEntry
+}
+
+// NewRegistry return an empty registry persisted in store, store may be nil
+func NewRegistry(store RegistryStore) *Registry {
+	return &Registry{Store: store, entries: make(map[string]*RegistryEntry)}
+}
+
+// Load connect the devices saved in the store. Devices unreachable are still
+// registered, so they are reported offline instead of being forgotten.
+func (registry *Registry) Load(ctx context.Context) error {
+	if registry.Store == nil {
+		return nil
+	}
+	records, err := registry.Store.LoadDevices()
+	if err != nil {
+		return err
+	}
+	entries := make([]*RegistryEntry, len(records))
+	fleet := &Fleet{Devices: make([]*Device, len(records))}
+	for i, record := range records {
+		params := DeviceParams{Ipddr: record.Address, ServiceURL: record.ServiceURL, Username: record.Username, Password: record.Password, Addresses: record.Addresses}
+		fleet.Devices[i] = newOfflineDevice(params)
+		entries[i] = &RegistryEntry{ID: record.ID, Device: fleet.Devices[i], Labels: copyLabels(record.Labels), Groups: record.Groups}
+	}
+	/* 并发连接设备 */
+	fleet.each(ctx, func(i int, dev *Device) {
+		if connected, err := NewDevice(dev.Params); err == nil {
+			entries[i].Device = connected
+		}
+	})
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if registry.entries == nil {
+		registry.entries = make(map[string]*RegistryEntry)
+	}
+	for _, entry := range entries {
+		registry.entries[entry.ID] = entry
+	}
+	return ctx.Err()
+}
+
+// Add register dev under id, replacing the device registered with that id
+func (registry *Registry) Add(id string, dev *Device, labels map[string]string, groups ...string) error {
+	entry := &RegistryEntry{ID: id, Device: dev, Labels: copyLabels(labels), Groups: append([]string(nil), groups...)}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if registry.entries == nil {
+		registry.entries = make(map[string]*RegistryEntry)
+	}
+	registry.entries[id] = entry
+	return registry.save(entry)
+}
+
+// Remove unregister the device
+func (registry *Registry) Remove(id string) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if _, ok := registry.entries[id]; !ok {
+		return ErrUnknownDevice
+	}
+	delete(registry.entries, id)
+	if registry.Store != nil {
+		return registry.Store.DeleteDevice(id)
+	}
+	return nil
+}
+
+// Get return the entry of the device
+func (registry *Registry) Get(id string) (RegistryEntry, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	entry, ok := registry.entries[id]
+	if !ok {
+		return RegistryEntry{}, false
+	}
+	return entry.copy(), true
+}
+
+// Device return the device registered under id
+func (registry *Registry) Device(id string) (*Device, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	entry, ok := registry.entries[id]
+	if !ok {
+		return nil, false
+	}
+	return entry.Device, true
+}
+
+// Lookup return the entry of the device with the address, as found in
+// Event.Device
+func (registry *Registry) Lookup(address string) (RegistryEntry, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	for _, entry := range registry.entries {
+		if entry.Device != nil && entry.Device.Params.Ipddr == address {
+			return entry.copy(), true
+		}
+	}
+	return RegistryEntry{}, false
+}
+
+// SetLabel set a label of the device, an empty value removes the label
+func (registry *Registry) SetLabel(id, key, value string) error {
+	return registry.update(id, func(entry *RegistryEntry) {
+		if value == "" {
+			delete(entry.Labels, key)
+			return
+		}
+		if entry.Labels == nil {
+			entry.Labels = make(map[string]string)
+		}
+		entry.Labels[key] = value
+	})
+}
+
+// AddToGroups add the device to the groups
+func (registry *Registry) AddToGroups(id string, groups ...string) error {
+	return registry.update(id, func(entry *RegistryEntry) {
+		for _, group := range groups {
+			if !containsString(entry.Groups, group) {
+				entry.Groups = append(entry.Groups, group)
+			}
+		}
+	})
+}
+
+// RemoveFromGroups remove the device from the groups
+func (registry *Registry) RemoveFromGroups(id string, groups ...string) error {
+	return registry.update(id, func(entry *RegistryEntry) {
+		kept := entry.Groups[:0]
+		for _, group := range entry.Groups {
+			if !containsString(groups, group) {
+				kept = append(kept, group)
+			}
+		}
+		entry.Groups = kept
+	})
+}
+
+// Entries return every entry, sorted by id
+func (registry *Registry) Entries() []RegistryEntry {
+	return registry.filter(func(*RegistryEntry) bool { return true })
+}
+
+// Select return the entries carrying every label of selector
+func (registry *Registry) Select(selector map[string]string) []RegistryEntry {
+	return registry.filter(func(entry *RegistryEntry) bool { return entry.HasLabels(selector) })
+}
+
+// Members return the entries of the group
+func (registry *Registry) Members(group string) []RegistryEntry {
+	return registry.filter(func(entry *RegistryEntry) bool { return containsString(entry.Groups, group) })
+}
+
+// Fleet return the fleet of the devices carrying every label of selector, an
+// empty selector selects every device
+func (registry *Registry) Fleet(selector map[string]string) *Fleet {
+	return entriesFleet(registry.Select(selector))
+}
+
+// Group return the fleet of the devices of the group
+func (registry *Registry) Group(group string) *Fleet {
+	return entriesFleet(registry.Members(group))
+}
+
+// ParseSelector parse a label selector written key=value,key=value
+func ParseSelector(text string) (map[string]string, error) {
+	selector := make(map[string]string)
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		index := strings.Index(part, "=")
+		if index <= 0 {
+			return nil, fmt.Errorf("invalid selector %q, expected key=value", part)
+		}
+		selector[strings.TrimSpace(part[:index])] = strings.TrimSpace(part[index+1:])
+	}
+	return selector, nil
+}
+
+func (registry *Registry) update(id string, fn func(entry *RegistryEntry)) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	entry, ok := registry.entries[id]
+	if !ok {
+		return ErrUnknownDevice
+	}
+	fn(entry)
+	return registry.save(entry)
+}
+
+func (registry *Registry) save(entry *RegistryEntry) error {
+	if registry.Store == nil {
+		return nil
+	}
+	record := RegistryRecord{ID: entry.ID, Labels: entry.Labels, Groups: entry.Groups}
+	if entry.Device != nil {
+		record.Address = entry.Device.Params.Ipddr
+		record.Addresses = entry.Device.Params.Addresses
+		record.ServiceURL = entry.Device.Params.ServiceURL
+		record.Username = entry.Device.Params.Username
+		record.Password = entry.Device.Params.Password
+	}
+	return registry.Store.SaveDevice(record)
+}
+
+func (registry *Registry) filter(keep func(*RegistryEntry) bool) []RegistryEntry {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	entries := make([]RegistryEntry, 0, len(registry.entries))
+	for _, entry := range registry.entries {
+		if keep(entry) {
+			entries = append(entries, entry.copy())
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries
+}
+
+func (entry *RegistryEntry) copy() RegistryEntry {
+	return RegistryEntry{ID: entry.ID, Device: entry.Device, Labels: copyLabels(entry.Labels), Groups: append([]string(nil), entry.Groups...)}
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	copied := make(map[string]string, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+	return copied
+}
+
+func entriesFleet(entries []RegistryEntry) *Fleet {
+	fleet := &Fleet{Devices: make([]*Device, 0, len(entries))}
+	for _, entry := range entries {
+		fleet.Devices = append(fleet.Devices, entry.Device)
+	}
+	return fleet
+}
+
+// FileRegistryStore RegistryStore keeping the records in a JSON file. The
+// file holds the device passwords and is written with mode 0600.
+type FileRegistryStore struct {
+	Path string
+
+	mutex sync.Mutex
+}
+
+// NewFileRegistryStore return a store backed by the file at path
+func NewFileRegistryStore(path string) *FileRegistryStore {
+	return &FileRegistryStore{Path: path}
+}
+
+// LoadDevices read the records, a missing file holds no record
+func (store *FileRegistryStore) LoadDevices() ([]RegistryRecord, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.read()
+}
+
+// SaveDevice insert or replace the record
+func (store *FileRegistryStore) SaveDevice(record RegistryRecord) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	records, err := store.read()
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		if records[i].ID == record.ID {
+			records[i] = record
+			return store.write(records)
+		}
+	}
+	return store.write(append(records, record))
+}
+
+// DeleteDevice remove the record
+func (store *FileRegistryStore) DeleteDevice(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	records, err := store.read()
+	if err != nil {
+		return err
+	}
+	kept := records[:0]
+	for _, record := range records {
+		if record.ID != id {
+			kept = append(kept, record)
+		}
+	}
+	return store.write(kept)
+}
+
+func (store *FileRegistryStore) read() ([]RegistryRecord, error) {
+	data, err := ioutil.ReadFile(store.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []RegistryRecord
+	err = json.Unmarshal(data, &records)
+	return records, err
+}
+
+/* 先写临时文件再重命名,避免写入中断损坏文件 */
+func (store *FileRegistryStore) write(records []RegistryRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := store.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, store.Path)
+}
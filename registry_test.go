@@ -0,0 +1,74 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.json")
+	registry := NewRegistry(NewFileRegistryStore(path))
+	for _, device := range []struct {
+		id, address string
+		labels      map[string]string
+		groups      []string
+	}{
+		{"gate", "127.0.0.1:1", map[string]string{"site": "paris", "orientation": "north"}, []string{"perimeter"}},
+		{"hall", "127.0.0.1:2", map[string]string{"site": "paris"}, nil},
+		{"dock", "127.0.0.1:3", map[string]string{"site": "lyon"}, []string{"perimeter"}},
+	} {
+		dev := newDevice(DeviceParams{Ipddr: device.address, Username: "admin", Password: "secret"})
+		if err := registry.Add(device.id, dev, device.labels, device.groups...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	selector, err := ParseSelector("site=paris, orientation=north")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := registry.Select(selector); len(entries) != 1 || entries[0].ID != "gate" {
+		t.Fatalf("selected %+v", entries)
+	}
+	if fleet := registry.Fleet(map[string]string{"site": "paris"}); len(fleet.Devices) != 2 || fleet.Devices[0].Params.Ipddr != "127.0.0.1:1" {
+		t.Fatalf("fleet %+v", fleet.Devices)
+	}
+	if err := registry.SetLabel("hall", "site", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.RemoveFromGroups("gate", "perimeter"); err != nil {
+		t.Fatal(err)
+	}
+	if members := registry.Members("perimeter"); len(members) != 1 || members[0].ID != "dock" {
+		t.Fatalf("members %+v", members)
+	}
+	if entry, ok := registry.Lookup("127.0.0.1:3"); !ok || entry.ID != "dock" {
+		t.Fatalf("lookup %+v, %v", entry, ok)
+	}
+	if err := registry.Remove("dock"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.SetLabel("dock", "site", "lyon"); !errors.Is(err, ErrUnknownDevice) {
+		t.Fatalf("error %v for a removed device", err)
+	}
+	if _, err := ParseSelector("site"); err == nil {
+		t.Fatal("selector without value accepted")
+	}
+
+	/* 文件含密码,仅所有者可读 */
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("file %v, %v", info, err)
+	}
+	/* 无法连接的设备仍然登记 */
+	loaded := NewRegistry(NewFileRegistryStore(path))
+	if err := loaded.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	entries := loaded.Entries()
+	if len(entries) != 2 || entries[0].ID != "gate" || entries[0].Labels["orientation"] != "north" || len(entries[0].Groups) != 0 ||
+		entries[1].ID != "hall" || len(entries[1].Labels) != 0 || entries[1].Device.Params.Password != "secret" {
+		t.Fatalf("loaded %+v", entries)
+	}
+}
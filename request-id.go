@@ -0,0 +1,36 @@
+package onvif
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestIDHeader header carrying the request ID of the calls, see
+// WithRequestID
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID return a context sending id in the X-Request-ID header of
+// every request made with it, to correlate the logs of the client, of the
+// proxies and of the devices
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID return the request ID of ctx, empty when none was set
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// setRequestHeaders set the User-Agent of the device and the request ID of
+// the context on req
+func (dev Device) setRequestHeaders(ctx context.Context, req *http.Request) {
+	if dev.Params.UserAgent != "" {
+		req.Header.Set("User-Agent", dev.Params.UserAgent)
+	}
+	if id := RequestID(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+}
@@ -0,0 +1,62 @@
+package onvif
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	fake := &scriptedDevice{answers: map[string]string{
+		"GetSystemDateAndTime": `<tds:GetSystemDateAndTimeResponse/>`,
+	}}
+	var mutex sync.Mutex
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		headers = append(headers, r.Header)
+		mutex.Unlock()
+		if r.Method == http.MethodGet {
+			w.Write([]byte("log"))
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://"), UserAgent: "vms/2.1"})
+	dev.endpoints[ServiceDevice] = server.URL + "/onvif/device_service"
+
+	ctx := WithRequestID(context.Background(), "request-7")
+	if RequestID(ctx) != "request-7" || RequestID(context.Background()) != "" {
+		t.Fatal("request id of the context")
+	}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetSystemDateAndTime{}, &device.GetSystemDateAndTimeResponse{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	/* 下载等直接请求同样带上请求头 */
+	if _, err := dev.Download(ctx, server.URL+"/log.txt", ioutil.Discard, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.CallMethodInterfaceContext(context.Background(), device.GetSystemDateAndTime{}, &device.GetSystemDateAndTimeResponse{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(headers) != 3 {
+		t.Fatalf("%d requests", len(headers))
+	}
+	for i, header := range headers {
+		if header.Get("User-Agent") != "vms/2.1" {
+			t.Errorf("request %d User-Agent %q", i, header.Get("User-Agent"))
+		}
+	}
+	if headers[0].Get(RequestIDHeader) != "request-7" || headers[1].Get(RequestIDHeader) != "request-7" || headers[2].Get(RequestIDHeader) != "" {
+		t.Fatalf("request ids %q %q %q", headers[0].Get(RequestIDHeader), headers[1].Get(RequestIDHeader), headers[2].Get(RequestIDHeader))
+	}
+}
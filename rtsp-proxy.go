@@ -0,0 +1,558 @@
+package onvif
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rtspProxy local RTSP server relaying the connection of a player to the
+// camera and authenticating its requests, so that the player, e.g. ffmpeg,
+// is given an address without the credentials: a command line is readable
+// by every local user. Only the first connection is accepted.
+type rtspProxy struct {
+	listener net.Listener
+	target   *url.URL
+	username string
+	password string
+	// tunnel RTSP is tunnelled in HTTP to the camera
+	tunnel bool
+
+	mutex  sync.Mutex
+	conns  []net.Conn
+	closed bool
+	err    error
+	done   chan struct{}
+}
+
+// startRTSPProxy listen on the loopback for the player of the stream at
+// target, which holds no credentials
+func startRTSPProxy(target *url.URL, username, password string, tunnel bool) (*rtspProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	/* 复制地址,调用方之后的修改不影响代理 */
+	copied := *target
+	proxy := &rtspProxy{listener: listener, target: &copied, username: username, password: password, tunnel: tunnel, done: make(chan struct{})}
+	go proxy.serve()
+	return proxy, nil
+}
+
+// URL of the stream on the proxy
+func (proxy *rtspProxy) URL() string {
+	return "rtsp://" + proxy.listener.Addr().String() + proxy.target.RequestURI()
+}
+
+// Close stop the proxy and its connections, the error is the one of the
+// connection to the camera
+func (proxy *rtspProxy) Close() error {
+	proxy.listener.Close()
+	proxy.mutex.Lock()
+	proxy.closed = true
+	for _, conn := range proxy.conns {
+		conn.Close()
+	}
+	proxy.mutex.Unlock()
+	<-proxy.done
+	return proxy.err
+}
+
+// track close conn with the proxy, false when the proxy is already closed
+func (proxy *rtspProxy) track(conn net.Conn) bool {
+	proxy.mutex.Lock()
+	defer proxy.mutex.Unlock()
+	if proxy.closed {
+		conn.Close()
+		return false
+	}
+	proxy.conns = append(proxy.conns, conn)
+	return true
+}
+
+func (proxy *rtspProxy) serve() {
+	defer close(proxy.done)
+	client, err := proxy.listener.Accept()
+	/* 只接受一个连接,本机其他用户无法借用已认证的会话 */
+	proxy.listener.Close()
+	if err != nil || !proxy.track(client) {
+		return
+	}
+	defer client.Close()
+	upstream, err := proxy.connect()
+	if err != nil {
+		proxy.err = err
+		return
+	}
+	defer upstream.close()
+	session := &rtspSession{proxy: proxy, upstream: upstream, client: client, pending: make(map[string]*rtspMessage), retried: make(map[string]bool)}
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		session.responses()
+		client.Close()
+	}()
+	session.requests(bufio.NewReader(client))
+	upstream.close()
+	<-finished
+}
+
+// rtspUpstream connection to the camera, two HTTP connections for a tunnel
+type rtspUpstream struct {
+	reader *bufio.Reader
+	writer io.Writer
+	conns  []net.Conn
+}
+
+func (upstream *rtspUpstream) close() {
+	for _, conn := range upstream.conns {
+		conn.Close()
+	}
+}
+
+// dial connect to the camera, TLS for rtsps and https
+func (proxy *rtspProxy) dial() (net.Conn, error) {
+	port := proxy.target.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443", "rtsps": "322"}[strings.ToLower(proxy.target.Scheme)]
+	}
+	if port == "" {
+		port = "554"
+	}
+	address := net.JoinHostPort(proxy.target.Hostname(), port)
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	switch strings.ToLower(proxy.target.Scheme) {
+	case "rtsps", "https":
+		/* 与ffmpeg默认不校验证书(tls_verify=0)保持一致 */
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+	default:
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !proxy.track(conn) {
+		return nil, errors.New("rtsp proxy closed")
+	}
+	return conn, nil
+}
+
+func (proxy *rtspProxy) connect() (*rtspUpstream, error) {
+	if proxy.tunnel {
+		return proxy.connectTunnel()
+	}
+	conn, err := proxy.dial()
+	if err != nil {
+		return nil, err
+	}
+	return &rtspUpstream{reader: bufio.NewReader(conn), writer: conn, conns: []net.Conn{conn}}, nil
+}
+
+// connectTunnel open the GET connection the camera answers on and the POST
+// connection the requests are sent on, base64 encoded
+func (proxy *rtspProxy) connectTunnel() (*rtspUpstream, error) {
+	cookie := getCnonce()
+	uri := proxy.target.RequestURI()
+	authorization := ""
+	var get net.Conn
+	var reader *bufio.Reader
+	for attempt := 0; ; attempt++ {
+		conn, err := proxy.dial()
+		if err != nil {
+			return nil, err
+		}
+		request := "GET " + uri + " HTTP/1.0\r\nHost: " + proxy.target.Host + "\r\nAccept: application/x-rtsp-tunnelled\r\n" +
+			"x-sessioncookie: " + cookie + "\r\nPragma: no-cache\r\nCache-Control: no-cache\r\nUser-Agent: go-onvif\r\n"
+		if authorization != "" {
+			request += "Authorization: " + authorization + "\r\n"
+		}
+		if _, err := io.WriteString(conn, request+"\r\n"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		reader = bufio.NewReader(conn)
+		response, err := readRTSPHeader(reader)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		status := messageStatus(response.start)
+		if status == 200 {
+			get = conn
+			break
+		}
+		conn.Close()
+		challenge := preferredChallenge(response.values("WWW-Authenticate"))
+		if status != 401 || attempt > 0 || challenge == "" {
+			return nil, fmt.Errorf("rtsp over http tunnel: %s", response.start)
+		}
+		authorization = rtspAuthorization(challenge, "GET", uri, proxy.username, proxy.password, 1)
+	}
+	post, err := proxy.dial()
+	if err != nil {
+		get.Close()
+		return nil, err
+	}
+	request := "POST " + uri + " HTTP/1.0\r\nHost: " + proxy.target.Host + "\r\nContent-Type: application/x-rtsp-tunnelled\r\n" +
+		"x-sessioncookie: " + cookie + "\r\nContent-Length: 32767\r\nExpires: Sun, 9 Jan 1972 00:00:00 GMT\r\nPragma: no-cache\r\n" +
+		"Cache-Control: no-cache\r\nUser-Agent: go-onvif\r\n"
+	if authorization != "" {
+		request += "Authorization: " + authorization + "\r\n"
+	}
+	if _, err := io.WriteString(post, request+"\r\n"); err != nil {
+		get.Close()
+		post.Close()
+		return nil, err
+	}
+	return &rtspUpstream{reader: reader, writer: tunnelWriter{post}, conns: []net.Conn{get, post}}, nil
+}
+
+// tunnelWriter writer of the POST connection of a tunnel, each message being
+// encoded on its own
+type tunnelWriter struct {
+	w io.Writer
+}
+
+func (writer tunnelWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(writer.w, base64.StdEncoding.EncodeToString(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// rtspSession relay of the connection of the player
+type rtspSession struct {
+	proxy    *rtspProxy
+	upstream *rtspUpstream
+	client   net.Conn
+
+	writeMutex sync.Mutex
+	mutex      sync.Mutex
+	// pending requests waiting for their response, by CSeq
+	pending   map[string]*rtspMessage
+	retried   map[string]bool
+	challenge string
+	nonces    int
+}
+
+// requests relay the requests and interleaved frames of the player
+func (session *rtspSession) requests(r *bufio.Reader) error {
+	local, base := "rtsp://"+session.proxy.listener.Addr().String(), session.proxy.target.Scheme+"://"+session.proxy.target.Host
+	for {
+		frame, request, err := readRTSP(r)
+		if err != nil {
+			return err
+		}
+		if frame != nil {
+			if err := session.send(frame); err != nil {
+				return err
+			}
+			continue
+		}
+		/* 将代理地址换回设备地址,摘要认证的uri须与请求行一致 */
+		if fields := strings.Fields(request.start); len(fields) == 3 && strings.HasPrefix(fields[1], local) {
+			request.start = fields[0] + " " + base + fields[1][len(local):] + " " + fields[2]
+		}
+		request.del("Authorization")
+		session.mutex.Lock()
+		session.pending[request.get("CSeq")] = request
+		session.mutex.Unlock()
+		if err := session.sendRequest(request); err != nil {
+			return err
+		}
+	}
+}
+
+// sendRequest send request with the answer to the last challenge of the
+// camera
+func (session *rtspSession) sendRequest(request *rtspMessage) error {
+	session.mutex.Lock()
+	if session.challenge != "" {
+		session.nonces++
+		fields := strings.Fields(request.start)
+		if len(fields) == 3 {
+			request.set("Authorization", rtspAuthorization(session.challenge, fields[0], fields[1], session.proxy.username, session.proxy.password, session.nonces))
+		}
+	}
+	data := request.bytes()
+	session.mutex.Unlock()
+	return session.send(data)
+}
+
+func (session *rtspSession) send(data []byte) error {
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
+	_, err := session.upstream.writer.Write(data)
+	return err
+}
+
+// responses relay the responses and frames of the camera, answering its
+// authentication challenges once per request
+func (session *rtspSession) responses() error {
+	for {
+		frame, response, err := readRTSP(session.upstream.reader)
+		if err != nil {
+			return err
+		}
+		if frame == nil {
+			if strings.HasPrefix(response.start, "RTSP/") {
+				retry, request := session.challenged(response)
+				if retry {
+					if err := session.sendRequest(request); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			frame = response.bytes()
+		}
+		if _, err := session.client.Write(frame); err != nil {
+			return err
+		}
+	}
+}
+
+// challenged tell whether the request of response must be sent again with
+// the credentials, the response being a new challenge
+func (session *rtspSession) challenged(response *rtspMessage) (bool, *rtspMessage) {
+	cseq := response.get("CSeq")
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	request := session.pending[cseq]
+	delete(session.pending, cseq)
+	challenge := preferredChallenge(response.values("WWW-Authenticate"))
+	if request == nil || messageStatus(response.start) != 401 || challenge == "" || session.retried[cseq] {
+		return false, nil
+	}
+	session.challenge = challenge
+	session.retried[cseq] = true
+	session.pending[cseq] = request
+	return true, request
+}
+
+// rtspMessage RTSP request or response, or HTTP header of a tunnel
+type rtspMessage struct {
+	start  string
+	header []string
+	body   []byte
+}
+
+// maxRTSPHeader header lines and maxRTSPBody body bytes of a message
+const (
+	maxRTSPHeader = 128
+	maxRTSPBody   = 1 << 20
+)
+
+// readRTSP read the next interleaved frame or message of r
+func readRTSP(r *bufio.Reader) ([]byte, *rtspMessage, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first[0] != '$' {
+		message, err := readRTSPMessage(r)
+		return nil, message, err
+	}
+	/* $ + 通道 + 2字节长度 */
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, err
+	}
+	frame := make([]byte, 4+int(header[2])<<8|int(header[3]))
+	copy(frame, header)
+	_, err = io.ReadFull(r, frame[4:])
+	return frame, nil, err
+}
+
+func readRTSPMessage(r *bufio.Reader) (*rtspMessage, error) {
+	message, err := readRTSPHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if length := message.get("Content-Length"); length != "" {
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 0 || n > maxRTSPBody {
+			return nil, fmt.Errorf("invalid content length %q", length)
+		}
+		message.body = make([]byte, n)
+		if _, err := io.ReadFull(r, message.body); err != nil {
+			return nil, err
+		}
+	}
+	return message, nil
+}
+
+// readRTSPHeader read the start line and the header of a message
+func readRTSPHeader(r *bufio.Reader) (*rtspMessage, error) {
+	message := &rtspMessage{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case message.start == "" && line == "":
+		case message.start == "":
+			message.start = line
+		case line == "":
+			return message, nil
+		case len(message.header) == maxRTSPHeader:
+			return nil, errors.New("rtsp header too large")
+		default:
+			message.header = append(message.header, line)
+		}
+	}
+}
+
+// messageStatus status code of a response line, 0 for a request
+func messageStatus(start string) int {
+	fields := strings.Fields(start)
+	if len(fields) < 2 || !strings.Contains(fields[0], "/") {
+		return 0
+	}
+	status, _ := strconv.Atoi(fields[1])
+	return status
+}
+
+// headerField split a header line into its name and value
+func headerField(line string) (string, string) {
+	index := strings.IndexByte(line, ':')
+	if index < 0 {
+		return line, ""
+	}
+	return strings.TrimSpace(line[:index]), strings.TrimSpace(line[index+1:])
+}
+
+func (message *rtspMessage) values(name string) []string {
+	var values []string
+	for _, line := range message.header {
+		if key, value := headerField(line); strings.EqualFold(key, name) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (message *rtspMessage) get(name string) string {
+	if values := message.values(name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (message *rtspMessage) del(name string) {
+	header := message.header[:0]
+	for _, line := range message.header {
+		if key, _ := headerField(line); !strings.EqualFold(key, name) {
+			header = append(header, line)
+		}
+	}
+	message.header = header
+}
+
+func (message *rtspMessage) set(name, value string) {
+	message.del(name)
+	message.header = append(message.header, name+": "+value)
+}
+
+func (message *rtspMessage) bytes() []byte {
+	data := []byte(message.start + "\r\n")
+	for _, line := range message.header {
+		data = append(data, line+"\r\n"...)
+	}
+	data = append(data, "\r\n"...)
+	return append(data, message.body...)
+}
+
+// preferredChallenge the Digest challenge among the WWW-Authenticate values,
+// the Basic one when there is none
+func preferredChallenge(values []string) string {
+	challenge := ""
+	for _, value := range values {
+		scheme, _ := parseChallenge(value)
+		if strings.EqualFold(scheme, "Digest") {
+			return value
+		}
+		if strings.EqualFold(scheme, "Basic") {
+			challenge = value
+		}
+	}
+	return challenge
+}
+
+// parseChallenge return the scheme and the parameters of a WWW-Authenticate
+// value
+func parseChallenge(challenge string) (string, map[string]string) {
+	challenge = strings.TrimSpace(challenge)
+	scheme, rest := challenge, ""
+	if index := strings.IndexByte(challenge, ' '); index >= 0 {
+		scheme, rest = challenge[:index], challenge[index+1:]
+	}
+	params := make(map[string]string)
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		index := strings.IndexByte(rest, '=')
+		if index < 0 {
+			break
+		}
+		key, value := strings.ToLower(strings.TrimSpace(rest[:index])), ""
+		rest = strings.TrimLeft(rest[index+1:], " ")
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if end := strings.IndexByte(rest, ','); end >= 0 {
+			value, rest = strings.TrimSpace(rest[:end]), rest[end:]
+		} else {
+			value, rest = strings.TrimSpace(rest), ""
+		}
+		params[key] = value
+	}
+	return scheme, params
+}
+
+// rtspAuthorization return the Authorization value answering the Basic or
+// Digest challenge for the request, nc counting the requests of the session
+func rtspAuthorization(challenge, method, uri, username, password string, nc int) string {
+	scheme, params := parseChallenge(challenge)
+	if strings.EqualFold(scheme, "Basic") {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	}
+	ha1 := getMD5(username + ":" + params["realm"] + ":" + password)
+	ha2 := getMD5(method + ":" + uri)
+	authorization := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s"`, username, params["realm"], params["nonce"], uri)
+	qop := false
+	for _, option := range strings.Split(params["qop"], ",") {
+		qop = qop || strings.TrimSpace(option) == "auth"
+	}
+	if qop {
+		cnonce, count := getCnonce(), fmt.Sprintf("%08x", nc)
+		response := getMD5(ha1 + ":" + params["nonce"] + ":" + count + ":" + cnonce + ":auth:" + ha2)
+		authorization += fmt.Sprintf(`, qop=auth, nc=%s, cnonce="%s", response="%s"`, count, cnonce, response)
+	} else {
+		/* RTSP设备通常不带qop,按RFC 2069计算 */
+		authorization += fmt.Sprintf(`, response="%s"`, getMD5(ha1+":"+params["nonce"]+":"+ha2))
+	}
+	if params["opaque"] != "" {
+		authorization += fmt.Sprintf(`, opaque="%s"`, params["opaque"])
+	}
+	if params["algorithm"] != "" {
+		authorization += ", algorithm=" + params["algorithm"]
+	}
+	return authorization
+}
@@ -0,0 +1,336 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/schedule"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// WeeklyPeriod period repeated every week on the given weekdays. Start and
+// End are device local "15:04" times, End "24:00" is the next midnight.
+type WeeklyPeriod struct {
+	Weekdays []time.Weekday
+	Start    string
+	End      string
+}
+
+// WeeklySchedule standard schedule of the schedule service, converted to
+// and from the iCalendar document the service requires
+type WeeklySchedule struct {
+	Summary string
+	Periods []WeeklyPeriod
+}
+
+// SpecialDays days of a special day group, e.g. public holidays
+type SpecialDays struct {
+	Summary string
+	Dates   []time.Time
+}
+
+const (
+	icalDateTime = "20060102T150405"
+	icalDate     = "20060102"
+)
+
+var icalWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+/* 以1970年1月4日(星期日)为基准日期,按星期偏移得到事件的起始日期 */
+var icalReference = time.Date(1970, time.January, 4, 0, 0, 0, 0, time.UTC)
+
+// parseClock parse a "15:04" time of day, "24:00" is accepted
+func parseClock(value string) (time.Duration, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// ICalendar return the iCalendar document of the schedule, one VEVENT with a
+// weekly RRULE per period
+func (week WeeklySchedule) ICalendar() (string, error) {
+	var builder strings.Builder
+	builder.WriteString("BEGIN:VCALENDAR\r\n")
+	for _, period := range week.Periods {
+		if len(period.Weekdays) == 0 {
+			return "", errors.New("weekly period without weekday")
+		}
+		start, err := parseClock(period.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := parseClock(period.End)
+		if err != nil {
+			return "", err
+		}
+		if end <= start {
+			return "", fmt.Errorf("period %s-%s ends before it starts", period.Start, period.End)
+		}
+		days := append([]time.Weekday(nil), period.Weekdays...)
+		sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+		day := icalReference.AddDate(0, 0, int(days[0]))
+		builder.WriteString("BEGIN:VEVENT\r\n")
+		if week.Summary != "" {
+			builder.WriteString("SUMMARY:" + week.Summary + "\r\n")
+		}
+		builder.WriteString("DTSTART:" + day.Add(start).Format(icalDateTime) + "\r\n")
+		builder.WriteString("DTEND:" + day.Add(end).Format(icalDateTime) + "\r\n")
+		if len(days) == 7 {
+			builder.WriteString("RRULE:FREQ=DAILY\r\n")
+		} else {
+			names := make([]string, len(days))
+			for i, weekday := range days {
+				names[i] = icalWeekdays[weekday]
+			}
+			builder.WriteString("RRULE:FREQ=WEEKLY;BYDAY=" + strings.Join(names, ",") + "\r\n")
+		}
+		builder.WriteString("END:VEVENT\r\n")
+	}
+	builder.WriteString("END:VCALENDAR\r\n")
+	return builder.String(), nil
+}
+
+// ICalendar return the iCalendar document of the special days, one all day
+// VEVENT per date
+func (days SpecialDays) ICalendar() string {
+	var builder strings.Builder
+	builder.WriteString("BEGIN:VCALENDAR\r\n")
+	for _, date := range days.Dates {
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		builder.WriteString("BEGIN:VEVENT\r\n")
+		if days.Summary != "" {
+			builder.WriteString("SUMMARY:" + days.Summary + "\r\n")
+		}
+		builder.WriteString("DTSTART:" + day.Format(icalDateTime) + "\r\n")
+		builder.WriteString("DTEND:" + day.AddDate(0, 0, 1).Format(icalDateTime) + "\r\n")
+		builder.WriteString("END:VEVENT\r\n")
+	}
+	builder.WriteString("END:VCALENDAR\r\n")
+	return builder.String()
+}
+
+// icalEvent properties of a VEVENT, keyed by name without parameters
+type icalEvent map[string]string
+
+// parseICalendar return the VEVENTs of an iCalendar document
+func parseICalendar(document string) ([]icalEvent, error) {
+	/* 展开折叠行 */
+	document = strings.ReplaceAll(document, "\r\n", "\n")
+	document = strings.ReplaceAll(document, "\n ", "")
+	document = strings.ReplaceAll(document, "\n\t", "")
+	var events []icalEvent
+	var current icalEvent
+	for _, line := range strings.Split(document, "\n") {
+		line = strings.TrimSpace(line)
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		name, value := strings.ToUpper(line[:colon]), line[colon+1:]
+		if semicolon := strings.Index(name, ";"); semicolon >= 0 {
+			name = name[:semicolon]
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = icalEvent{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current == nil {
+				return nil, errors.New("icalendar END:VEVENT without BEGIN")
+			}
+			events = append(events, current)
+			current = nil
+		case current != nil:
+			current[name] = value
+		}
+	}
+	if current != nil {
+		return nil, errors.New("icalendar VEVENT not terminated")
+	}
+	return events, nil
+}
+
+func parseICalTime(value string) (time.Time, error) {
+	value = strings.TrimSuffix(value, "Z")
+	for _, layout := range []string{icalDateTime, icalDate} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid icalendar time %q", value)
+}
+
+// icalRule return the parts of an RRULE
+func icalRule(rule string) map[string]string {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rule, ";") {
+		if index := strings.Index(part, "="); index > 0 {
+			parts[strings.ToUpper(part[:index])] = strings.ToUpper(part[index+1:])
+		}
+	}
+	return parts
+}
+
+func clockString(t, day time.Time) string {
+	if t.Sub(day) >= 24*time.Hour {
+		return "24:00"
+	}
+	return t.Format("15:04")
+}
+
+// ParseWeeklySchedule convert the iCalendar document of a standard schedule
+// back to a WeeklySchedule, only DAILY and WEEKLY rules are understood
+func ParseWeeklySchedule(document string) (WeeklySchedule, error) {
+	events, err := parseICalendar(document)
+	if err != nil {
+		return WeeklySchedule{}, err
+	}
+	week := WeeklySchedule{}
+	for _, ev := range events {
+		start, err := parseICalTime(ev["DTSTART"])
+		if err != nil {
+			return week, err
+		}
+		end, err := parseICalTime(ev["DTEND"])
+		if err != nil {
+			return week, err
+		}
+		if week.Summary == "" {
+			week.Summary = ev["SUMMARY"]
+		}
+		rule := icalRule(ev["RRULE"])
+		period := WeeklyPeriod{Start: start.Format("15:04"), End: clockString(end, start.Truncate(24*time.Hour))}
+		switch rule["FREQ"] {
+		case "DAILY":
+			period.Weekdays = []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+		case "WEEKLY":
+			if rule["BYDAY"] == "" {
+				period.Weekdays = []time.Weekday{start.Weekday()}
+			}
+			for _, name := range strings.Split(rule["BYDAY"], ",") {
+				for weekday, icalName := range icalWeekdays {
+					if name == icalName {
+						period.Weekdays = append(period.Weekdays, time.Weekday(weekday))
+					}
+				}
+			}
+		default:
+			return week, fmt.Errorf("unsupported icalendar rule %q", ev["RRULE"])
+		}
+		week.Periods = append(week.Periods, period)
+	}
+	return week, nil
+}
+
+// ParseSpecialDays convert the iCalendar document of a special day group
+// back to its dates, events spanning several days are expanded
+func ParseSpecialDays(document string) (SpecialDays, error) {
+	events, err := parseICalendar(document)
+	if err != nil {
+		return SpecialDays{}, err
+	}
+	days := SpecialDays{}
+	for _, ev := range events {
+		start, err := parseICalTime(ev["DTSTART"])
+		if err != nil {
+			return days, err
+		}
+		end := start.AddDate(0, 0, 1)
+		if ev["DTEND"] != "" {
+			if end, err = parseICalTime(ev["DTEND"]); err != nil {
+				return days, err
+			}
+		}
+		if days.Summary == "" {
+			days.Summary = ev["SUMMARY"]
+		}
+		for day := start.Truncate(24 * time.Hour); day.Before(end); day = day.AddDate(0, 0, 1) {
+			days.Dates = append(days.Dates, day)
+		}
+	}
+	return days, nil
+}
+
+// ScheduleCapabilities return the capabilities of the schedule service, a
+// NotSupportedError is returned when the device has none
+func (dev *Device) ScheduleCapabilities(ctx context.Context) (schedule.Capabilities, error) {
+	if _, err := dev.getEndpoint("schedule"); err != nil {
+		return schedule.Capabilities{}, &NotSupportedError{Service: "schedule"}
+	}
+	response := schedule.GetServiceCapabilitiesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, schedule.GetServiceCapabilities{}, &response, ""); err != nil {
+		return schedule.Capabilities{}, err
+	}
+	return response.Capabilities, nil
+}
+
+// CreateWeeklySchedule create a schedule from week and return its token
+func (dev *Device) CreateWeeklySchedule(ctx context.Context, name string, week WeeklySchedule) (string, error) {
+	document, err := week.ICalendar()
+	if err != nil {
+		return "", err
+	}
+	request := schedule.CreateSchedule{Schedule: schedule.Schedule{Name: onvif.Name(name), Standard: xsd.String(document)}}
+	response := schedule.CreateScheduleResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return "", err
+	}
+	return string(response.Token), nil
+}
+
+// CreateSpecialDayGroup create a special day group and return its token
+func (dev *Device) CreateSpecialDayGroup(ctx context.Context, name string, days SpecialDays) (string, error) {
+	capabilities, err := dev.ScheduleCapabilities(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !capabilities.SpecialDaysSupported {
+		return "", &NotSupportedError{Service: "schedule", Capability: "SpecialDays"}
+	}
+	if capabilities.MaxDaysInSpecialDayGroup > 0 && len(days.Dates) > capabilities.MaxDaysInSpecialDayGroup {
+		return "", fmt.Errorf("%d special days exceed the maximum of %d", len(days.Dates), capabilities.MaxDaysInSpecialDayGroup)
+	}
+	request := schedule.CreateSpecialDayGroup{
+		SpecialDayGroup: schedule.SpecialDayGroup{Name: onvif.Name(name), Days: xsd.String(days.ICalendar())},
+	}
+	response := schedule.CreateSpecialDayGroupResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return "", err
+	}
+	return string(response.Token), nil
+}
+
+// WeeklySchedules return every schedule of the device with its standard
+// schedule parsed, schedules using rules ParseWeeklySchedule does not
+// understand are returned with an empty WeeklySchedule
+func (dev *Device) WeeklySchedules(ctx context.Context) (map[string]WeeklySchedule, error) {
+	capabilities, err := dev.ScheduleCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schedules := make(map[string]WeeklySchedule)
+	request := schedule.GetScheduleList{Limit: capabilities.MaxLimit}
+	for {
+		response := schedule.GetScheduleListResponse{}
+		if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+			return schedules, err
+		}
+		for _, info := range response.Schedule {
+			week, _ := ParseWeeklySchedule(string(info.Standard))
+			schedules[string(info.Token)] = week
+		}
+		if response.NextStartReference == "" || response.NextStartReference == request.StartReference {
+			return schedules, nil
+		}
+		request.StartReference = response.NextStartReference
+	}
+}
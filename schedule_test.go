@@ -0,0 +1,64 @@
+package onvif
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWeeklyScheduleICalendar(t *testing.T) {
+	workdays := []time.Weekday{time.Friday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday}
+	everyDay := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	week := WeeklySchedule{Summary: "office", Periods: []WeeklyPeriod{
+		{Weekdays: workdays, Start: "08:00", End: "18:00"},
+		{Weekdays: everyDay, Start: "22:00", End: "24:00"},
+	}}
+	document, err := week.ICalendar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:office\r\nDTSTART:19700105T080000\r\nDTEND:19700105T180000\r\nRRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:office\r\nDTSTART:19700104T220000\r\nDTEND:19700105T000000\r\nRRULE:FREQ=DAILY\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if document != want {
+		t.Fatalf("icalendar\n%s\nwant\n%s", document, want)
+	}
+	parsed, err := ParseWeeklySchedule(document)
+	/* 星期按顺序返回 */
+	week.Periods[0].Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	if err != nil || !reflect.DeepEqual(parsed, week) {
+		t.Fatalf("parsed %+v, %v", parsed, err)
+	}
+
+	for _, period := range []WeeklyPeriod{
+		{Weekdays: workdays, Start: "18:00", End: "08:00"},
+		{Weekdays: workdays, Start: "08:00", End: "24:30"},
+		{Start: "08:00", End: "18:00"},
+	} {
+		if _, err := (WeeklySchedule{Periods: []WeeklyPeriod{period}}).ICalendar(); err == nil {
+			t.Fatalf("period %+v accepted", period)
+		}
+	}
+	if _, err := ParseWeeklySchedule("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:19700105T080000\r\nDTEND:19700105T180000\r\nRRULE:FREQ=MONTHLY\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"); err == nil {
+		t.Fatal("monthly rule accepted")
+	}
+}
+
+func TestSpecialDaysICalendar(t *testing.T) {
+	christmas := time.Date(2026, time.December, 25, 0, 0, 0, 0, time.UTC)
+	days := SpecialDays{Summary: "holidays", Dates: []time.Time{christmas}}
+	document := days.ICalendar()
+	if !strings.Contains(document, "DTSTART:20261225T000000\r\nDTEND:20261226T000000\r\n") {
+		t.Fatalf("icalendar %q", document)
+	}
+	if parsed, err := ParseSpecialDays(document); err != nil || !reflect.DeepEqual(parsed, days) {
+		t.Fatalf("parsed %+v, %v", parsed, err)
+	}
+	/* 跨多天的全天事件展开为每一天,折叠行被展开 */
+	parsed, err := ParseSpecialDays("BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:new\n  year\nDTSTART;VALUE=DATE:20261231\nDTEND;VALUE=DATE:20270102\nEND:VEVENT\nEND:VCALENDAR\n")
+	if err != nil || parsed.Summary != "new year" || len(parsed.Dates) != 2 || !parsed.Dates[1].Equal(time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("parsed %+v, %v", parsed, err)
+	}
+}
@@ -0,0 +1,123 @@
+package onvif
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Prefixes of the asset scopes written by WriteAssetScopes
+const (
+	LocationScopePrefix = "onvif://www.onvif.org/location/"
+	NameScopePrefix     = "onvif://www.onvif.org/name/"
+)
+
+// ScopeMapping labels of the registry written as the scopes of the devices
+type ScopeMapping struct {
+	// Location labels written as location scopes, e.g. site=paris gives
+	// onvif://www.onvif.org/location/site/paris, the missing labels are
+	// skipped
+	Location []string
+	// Name label written as the name scope, the id of the entry when empty
+	// or missing
+	Name string
+}
+
+// Scopes return the asset scopes of entry
+func (mapping ScopeMapping) Scopes(entry RegistryEntry) []string {
+	var scopes []string
+	for _, label := range mapping.Location {
+		if value := entry.Labels[label]; value != "" {
+			scopes = append(scopes, LocationScopePrefix+url.PathEscape(label)+"/"+url.PathEscape(value))
+		}
+	}
+	name := entry.Labels[mapping.Name]
+	if mapping.Name == "" || name == "" {
+		name = entry.ID
+	}
+	if name != "" {
+		scopes = append(scopes, NameScopePrefix+url.PathEscape(name))
+	}
+	return scopes
+}
+
+// ScopeResult outcome of the scopes written to a device
+type ScopeResult struct {
+	ID     string
+	Device string
+	Scopes []string
+	// Changed the device held other asset scopes and was updated
+	Changed bool
+	Err     error
+}
+
+// WriteAssetScopes replace the configurable location and name scopes of the
+// device with scopes, the other configurable scopes are kept and the fixed
+// ones cannot change. It reports whether the device was updated.
+func (dev *Device) WriteAssetScopes(ctx context.Context, scopes []string) (bool, error) {
+	response := device.GetScopesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetScopes{}, &response, ""); err != nil {
+		return false, err
+	}
+	var kept, current []string
+	for _, scope := range response.Scopes {
+		if scope.ScopeDef != onvif.ScopeDefinitionConfigurable {
+			continue
+		}
+		item := strings.TrimSpace(string(scope.ScopeItem))
+		if strings.HasPrefix(item, LocationScopePrefix) || strings.HasPrefix(item, NameScopePrefix) {
+			current = append(current, item)
+		} else {
+			kept = append(kept, item)
+		}
+	}
+	if sameScopes(current, scopes) {
+		return false, nil
+	}
+	/* SetScopes替换全部可配置scope,须带上其他可配置scope */
+	request := device.SetScopes{}
+	for _, scope := range append(kept, scopes...) {
+		request.Scopes = append(request.Scopes, xsd.AnyURI(scope))
+	}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &device.SetScopesResponse{}, ""); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sameScopes report whether a and b hold the same scopes, in any order
+func sameScopes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteScopes write the asset scopes of mapping to the devices carrying
+// every label of selector, so that the discovery reports the location and
+// the name the devices are labelled with. A PartialError is returned with
+// the results when ctx ended first.
+func (registry *Registry) WriteScopes(ctx context.Context, selector map[string]string, mapping ScopeMapping) ([]ScopeResult, error) {
+	entries := registry.Select(selector)
+	results := make([]ScopeResult, len(entries))
+	err := entriesFleet(entries).each(ctx, func(i int, dev *Device) {
+		result := ScopeResult{ID: entries[i].ID, Device: dev.Params.Ipddr, Scopes: mapping.Scopes(entries[i])}
+		result.Changed, result.Err = dev.WriteAssetScopes(ctx, result.Scopes)
+		results[i] = result
+	})
+	return results, err
+}
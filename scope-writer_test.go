@@ -0,0 +1,63 @@
+package onvif
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func scopesResponse(items ...string) string {
+	body := `<tds:GetScopesResponse><tds:Scopes><tt:ScopeDef>Fixed</tt:ScopeDef><tt:ScopeItem>onvif://www.onvif.org/type/video_encoder</tt:ScopeItem></tds:Scopes>`
+	for _, item := range items {
+		body += `<tds:Scopes><tt:ScopeDef>Configurable</tt:ScopeDef><tt:ScopeItem>` + item + `</tt:ScopeItem></tds:Scopes>`
+	}
+	return body + `</tds:GetScopesResponse>`
+}
+
+func TestScopeMapping(t *testing.T) {
+	mapping := ScopeMapping{Location: []string{"site", "building", "floor"}, Name: "label"}
+	scopes := mapping.Scopes(RegistryEntry{ID: "gate", Labels: map[string]string{"site": "paris", "building": "b 2", "label": "Main gate"}})
+	if strings.Join(scopes, " ") != "onvif://www.onvif.org/location/site/paris onvif://www.onvif.org/location/building/b%202 onvif://www.onvif.org/name/Main%20gate" {
+		t.Fatalf("scopes %q", scopes)
+	}
+	/* 缺少名称标签时使用条目id */
+	if scopes := mapping.Scopes(RegistryEntry{ID: "dock"}); len(scopes) != 1 || scopes[0] != NameScopePrefix+"dock" {
+		t.Fatalf("scopes %q", scopes)
+	}
+}
+
+func TestWriteScopes(t *testing.T) {
+	gate, gateDevice := newScriptedDevice(t, map[string]string{
+		"GetScopes": scopesResponse("onvif://www.onvif.org/location/site/lyon", "onvif://www.onvif.org/name/gate", "onvif://www.onvif.org/hardware/custom"),
+		"SetScopes": `<tds:SetScopesResponse/>`,
+	})
+	dock, dockDevice := newScriptedDevice(t, map[string]string{
+		"GetScopes": scopesResponse("onvif://www.onvif.org/name/dock", "onvif://www.onvif.org/location/site/paris"),
+	})
+	registry := NewRegistry(nil)
+	if err := registry.Add("gate", gateDevice, map[string]string{"site": "paris"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Add("dock", dockDevice, map[string]string{"site": "paris"}); err != nil {
+		t.Fatal(err)
+	}
+	results, err := registry.WriteScopes(context.Background(), map[string]string{"site": "paris"}, ScopeMapping{Location: []string{"site"}})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("results %+v, %v", results, err)
+	}
+	for _, result := range results {
+		if result.Err != nil || result.Changed != (result.ID == "gate") || len(result.Scopes) != 2 {
+			t.Fatalf("result %+v", result)
+		}
+	}
+	/* 其他可配置scope保留,固定scope不写入 */
+	requests := gate.sent("SetScopes")
+	if len(requests) != 1 || strings.Count(requests[0], "<tds:Scopes>") != 3 || !strings.Contains(requests[0], "<tds:Scopes>onvif://www.onvif.org/hardware/custom</tds:Scopes>") ||
+		!strings.Contains(requests[0], "<tds:Scopes>onvif://www.onvif.org/location/site/paris</tds:Scopes>") || !strings.Contains(requests[0], "<tds:Scopes>onvif://www.onvif.org/name/gate</tds:Scopes>") {
+		t.Fatalf("requests %q", requests)
+	}
+	/* scope已一致的设备不更新 */
+	if len(dock.sent("SetScopes")) != 0 {
+		t.Fatal("unchanged scopes written")
+	}
+}
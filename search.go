@@ -0,0 +1,80 @@
+package onvif
+
+import (
+	"context"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/search"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// defaultSearchPage results requested at once by SearchRecordings
+const defaultSearchPage = 100
+
+// RecordingSearch criteria of SearchRecordings
+type RecordingSearch struct {
+	// Recordings tokens of the recordings searched, every recording when empty
+	Recordings []string
+	// Filter XPath filter of the recording information, e.g.
+	// boolean(//Track[TrackType = "Video"])
+	Filter string
+	// MaxMatches largest number of recordings found, 0 for no limit
+	MaxMatches int
+	// PageSize results requested at once, 0 means defaultSearchPage
+	PageSize int
+}
+
+// SearchRecordings return the recordings of the search service matching the
+// criteria, read in pages as the device finds them
+func (dev *Device) SearchRecordings(ctx context.Context, criteria RecordingSearch) ([]search.RecordingInformation, error) {
+	var recordings []search.RecordingInformation
+	err := dev.recordingSearchPages(ctx, criteria, func(page []search.RecordingInformation) bool {
+		recordings = append(recordings, page...)
+		return true
+	})
+	return recordings, err
+}
+
+// recordingSearchPages call page with the pages of results of the search
+// until it returns false or the search completes, the search being ended on
+// the device in every case
+func (dev *Device) recordingSearchPages(ctx context.Context, criteria RecordingSearch, page func([]search.RecordingInformation) bool) error {
+	if _, err := dev.getEndpoint(ServiceSearch); err != nil {
+		return &NotSupportedError{Service: ServiceSearch}
+	}
+	request := search.FindRecordings{
+		Scope:         search.SearchScope{RecordingInformationFilter: criteria.Filter},
+		MaxMatches:    criteria.MaxMatches,
+		KeepAliveTime: "PT30S",
+	}
+	for _, token := range criteria.Recordings {
+		request.Scope.IncludedRecordings = append(request.Scope.IncludedRecordings, onvif.ReferenceToken(token))
+	}
+	found := search.FindRecordingsResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &found, ""); err != nil {
+		return err
+	}
+	defer func() {
+		/* ctx结束后仍需释放设备上的搜索会话 */
+		endCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		dev.CallMethodInterfaceContext(endCtx, search.EndSearch{SearchToken: found.SearchToken}, &search.EndSearchResponse{}, "")
+	}()
+	pageSize := criteria.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultSearchPage
+	}
+	results := search.GetRecordingSearchResults{SearchToken: found.SearchToken, MaxResults: pageSize, WaitTime: "PT5S"}
+	for {
+		response := search.GetRecordingSearchResultsResponse{}
+		if err := dev.CallMethodInterfaceContext(ctx, results, &response, ""); err != nil {
+			return err
+		}
+		if len(response.ResultList.RecordingInformation) != 0 && !page(response.ResultList.RecordingInformation) {
+			return nil
+		}
+		if response.ResultList.SearchState == search.StateCompleted {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const searchResults = `<tse:GetRecordingSearchResultsResponse><tse:ResultList><tt:SearchState>Completed</tt:SearchState>` +
+	`<tt:RecordingInformation><tt:RecordingToken>rec_1</tt:RecordingToken><tt:Source><tt:Name>lobby</tt:Name></tt:Source>` +
+	`<tt:Track><tt:TrackToken>video</tt:TrackToken><tt:TrackType>Video</tt:TrackType></tt:Track><tt:RecordingStatus>Recording</tt:RecordingStatus></tt:RecordingInformation>` +
+	`<tt:RecordingInformation><tt:RecordingToken>rec_2</tt:RecordingToken><tt:RecordingStatus>Stopped</tt:RecordingStatus></tt:RecordingInformation>` +
+	`</tse:ResultList></tse:GetRecordingSearchResultsResponse>`
+
+func TestSearchRecordings(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"FindRecordings":            `<tse:FindRecordingsResponse><tse:SearchToken>search_1</tse:SearchToken></tse:FindRecordingsResponse>`,
+		"GetRecordingSearchResults": searchResults,
+		"EndSearch":                 `<tse:EndSearchResponse><tse:Endpoint>2026-10-16T10:00:00Z</tse:Endpoint></tse:EndSearchResponse>`,
+	}, ServiceSearch)
+	ctx := context.Background()
+	recordings, err := dev.SearchRecordings(ctx, RecordingSearch{Recordings: []string{"rec_1", "rec_2"}, Filter: `boolean(//Track[TrackType = "Video"])`, PageSize: 10})
+	if err != nil || len(recordings) != 2 || recordings[0].RecordingToken != "rec_1" || recordings[0].Source.Name != "lobby" ||
+		len(recordings[0].Track) != 1 || recordings[0].Track[0].TrackType != "Video" || recordings[1].RecordingStatus != "Stopped" {
+		t.Fatalf("recordings %+v, %v", recordings, err)
+	}
+	found := fake.sent("FindRecordings")
+	if len(found) != 1 || !strings.Contains(found[0], "<tt:IncludedRecordings>rec_2</tt:IncludedRecordings>") ||
+		!strings.Contains(found[0], "<tt:RecordingInformationFilter>boolean(//Track[TrackType = &#34;Video&#34;])</tt:RecordingInformationFilter>") ||
+		strings.Contains(found[0], "MaxMatches") {
+		t.Fatalf("requests %q", found)
+	}
+	if results := fake.sent("GetRecordingSearchResults"); len(results) != 1 || !strings.Contains(results[0], "<tse:SearchToken>search_1</tse:SearchToken>") ||
+		!strings.Contains(results[0], "<tse:MaxResults>10</tse:MaxResults>") {
+		t.Fatalf("requests %q", results)
+	}
+	/* 搜索结束后释放设备上的搜索会话,出错时也一样 */
+	fake.set("GetRecordingSearchResults", eventFault("ter:InvalidArgVal"))
+	if _, err := dev.SearchRecordings(ctx, RecordingSearch{}); err == nil {
+		t.Fatal("search fault ignored")
+	}
+	if ended := fake.sent("EndSearch"); len(ended) != 2 || !strings.Contains(ended[1], "<tse:SearchToken>search_1</tse:SearchToken>") {
+		t.Fatalf("requests %q", ended)
+	}
+
+	_, dev = newScriptedDevice(t, nil)
+	var unsupported *NotSupportedError
+	if _, err := dev.SearchRecordings(ctx, RecordingSearch{}); !errors.As(err, &unsupported) || unsupported.Service != ServiceSearch {
+		t.Fatalf("error %v without search service", err)
+	}
+}
@@ -0,0 +1,253 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/types/imaging"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ErrUnknownSensor returned by Device.Sensor for a channel the device does
+// not have
+var ErrUnknownSensor = errors.New("unknown sensor")
+
+// Sensor one sensor of a multi-sensor camera, a logical sub-device limited
+// to the profiles of one video source. Multi-sensor heads, e.g. the Hanwha
+// and Dahua panoramic cameras, expose their sensors as the video sources
+// VideoSource_1 to VideoSource_4 of a single device.
+type Sensor struct {
+	Device *Device
+	// Channel 1 based number of the sensor
+	Channel int
+	// VideoSource token of the video source of the sensor, the imaging
+	// settings apply to it
+	VideoSource string
+	// Profiles tokens of the media profiles of the sensor
+	Profiles []string
+	// PTZProfile first profile of the sensor with a PTZ configuration, empty
+	// when the sensor cannot move
+	PTZProfile string
+}
+
+// Sensors return the sensors of the device, one per video source used by
+// its profiles, by channel
+func (dev *Device) Sensors(ctx context.Context) ([]Sensor, error) {
+	profiles, err := dev.GetProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var sensors []Sensor
+	index := make(map[string]int)
+	for _, profile := range profiles {
+		source := string(profile.VideoSourceConfiguration.SourceToken)
+		if source == "" {
+			continue
+		}
+		i, ok := index[source]
+		if !ok {
+			i = len(sensors)
+			index[source] = i
+			sensors = append(sensors, Sensor{Device: dev, VideoSource: source})
+		}
+		sensors[i].Profiles = append(sensors[i].Profiles, string(profile.Token))
+		if sensors[i].PTZProfile == "" && profile.PTZConfiguration.NodeToken != "" {
+			sensors[i].PTZProfile = string(profile.Token)
+		}
+	}
+	numberSensors(sensors)
+	return sensors, nil
+}
+
+// numberSensors set the channels from the numbers ending the video source
+// tokens, counted from 1 when the device counts from 0, e.g. 000 to 003, and
+// in order of the profiles when a token has no number
+func numberSensors(sensors []Sensor) {
+	numbers := make([]int, len(sensors))
+	first := -1
+	for i := range sensors {
+		token := sensors[i].VideoSource
+		end := len(token)
+		for end > 0 && token[end-1] >= '0' && token[end-1] <= '9' {
+			end--
+		}
+		number, err := strconv.Atoi(token[end:])
+		if err != nil {
+			for j := range sensors {
+				sensors[j].Channel = j + 1
+			}
+			return
+		}
+		numbers[i] = number
+		if first < 0 || number < first {
+			first = number
+		}
+	}
+	for i := range sensors {
+		sensors[i].Channel = numbers[i]
+		if first == 0 {
+			sensors[i].Channel++
+		}
+	}
+	sort.SliceStable(sensors, func(i, j int) bool { return sensors[i].Channel < sensors[j].Channel })
+}
+
+// Sensor return the sensor of the channel, counted from 1
+func (dev *Device) Sensor(ctx context.Context, channel int) (Sensor, error) {
+	sensors, err := dev.Sensors(ctx)
+	if err != nil {
+		return Sensor{}, err
+	}
+	for _, sensor := range sensors {
+		if sensor.Channel == channel {
+			return sensor, nil
+		}
+	}
+	return Sensor{}, ErrUnknownSensor
+}
+
+// URIChannel return the channel query parameter of uri, as found in the
+// stream URIs and the service addresses of multi-channel devices, e.g.
+// rtsp://10.1.1.20/cam/realmonitor?channel=2&subtype=0, 0 when it has none
+func URIChannel(uri string) int {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return 0
+	}
+	for key, values := range u.Query() {
+		if strings.EqualFold(key, "channel") && len(values) > 0 {
+			if channel, err := strconv.Atoi(values[0]); err == nil {
+				return channel
+			}
+		}
+	}
+	return 0
+}
+
+// WithURIChannel return uri with its channel query parameter set to channel,
+// uri is unchanged when it has no channel parameter
+func WithURIChannel(uri string, channel int) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	query := u.Query()
+	for key := range query {
+		if strings.EqualFold(key, "channel") {
+			query.Set(key, strconv.Itoa(channel))
+			u.RawQuery = query.Encode()
+			return u.String()
+		}
+	}
+	return uri
+}
+
+// profile return the main profile of the sensor
+func (sensor Sensor) profile() (string, error) {
+	if len(sensor.Profiles) == 0 {
+		return "", ErrNoProfile
+	}
+	return sensor.Profiles[0], nil
+}
+
+// GetProfiles return the media profiles of the sensor
+func (sensor Sensor) GetProfiles(ctx context.Context) ([]onvif.Profile, error) {
+	profiles, err := sensor.Device.GetProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var own []onvif.Profile
+	for _, profile := range profiles {
+		if string(profile.VideoSourceConfiguration.SourceToken) == sensor.VideoSource {
+			own = append(own, profile)
+		}
+	}
+	return own, nil
+}
+
+// GetStreamURI return the stream URI of the main profile of the sensor. The
+// devices answering the URI of the first channel for every profile are
+// worked around by setting the channel parameter of the URI.
+func (sensor Sensor) GetStreamURI(ctx context.Context, protocol StreamProtocol) (string, error) {
+	profile, err := sensor.profile()
+	if err != nil {
+		return "", err
+	}
+	uri, err := sensor.Device.GetStreamURI(ctx, profile, protocol)
+	if err != nil {
+		return "", err
+	}
+	return WithURIChannel(uri, sensor.Channel), nil
+}
+
+// GetSnapshotURI return the snapshot URI of the main profile of the sensor,
+// its channel parameter set like GetStreamURI
+func (sensor Sensor) GetSnapshotURI(ctx context.Context) (string, error) {
+	profile, err := sensor.profile()
+	if err != nil {
+		return "", err
+	}
+	uri, err := sensor.Device.GetSnapshotURI(ctx, profile)
+	if err != nil {
+		return "", err
+	}
+	return WithURIChannel(uri, sensor.Channel), nil
+}
+
+// ImagingOptions return the imaging options of the video source of the
+// sensor
+func (sensor Sensor) ImagingOptions(ctx context.Context) (onvif.ImagingOptions20, error) {
+	return sensor.Device.ImagingOptions(ctx, sensor.VideoSource)
+}
+
+// ImagingPresets return the imaging presets of the video source of the
+// sensor
+func (sensor Sensor) ImagingPresets(ctx context.Context) ([]imaging.ImagingPreset, error) {
+	return sensor.Device.ImagingPresets(ctx, sensor.VideoSource)
+}
+
+// SetImagingPreset apply an imaging preset to the video source of the sensor
+func (sensor Sensor) SetImagingPreset(ctx context.Context, preset string) error {
+	return sensor.Device.SetImagingPreset(ctx, sensor.VideoSource, preset)
+}
+
+// ptzProfile return the PTZ profile of the sensor
+func (sensor Sensor) ptzProfile() (string, error) {
+	if sensor.PTZProfile == "" {
+		return "", &NotSupportedError{Service: "ptz", Capability: "sensor " + strconv.Itoa(sensor.Channel)}
+	}
+	return sensor.PTZProfile, nil
+}
+
+// ContinuousMove move the sensor, see Device.ContinuousMove
+func (sensor Sensor) ContinuousMove(ctx context.Context, velocity onvif.PTZSpeed, timeout time.Duration) error {
+	profile, err := sensor.ptzProfile()
+	if err != nil {
+		return err
+	}
+	return sensor.Device.ContinuousMove(ctx, profile, velocity, timeout)
+}
+
+// Stop stop the movements of the sensor, see Device.Stop
+func (sensor Sensor) Stop(ctx context.Context, panTilt, zoom bool) error {
+	profile, err := sensor.ptzProfile()
+	if err != nil {
+		return err
+	}
+	return sensor.Device.Stop(ctx, profile, panTilt, zoom)
+}
+
+// GotoPreset move the sensor to a preset of its PTZ profile, see
+// Device.GotoPreset
+func (sensor Sensor) GotoPreset(ctx context.Context, preset string, speed *onvif.PTZSpeed) error {
+	profile, err := sensor.ptzProfile()
+	if err != nil {
+		return err
+	}
+	return sensor.Device.GotoPreset(ctx, profile, preset, speed)
+}
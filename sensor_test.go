@@ -0,0 +1,112 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+func sensorProfile(token, source, ptz string) string {
+	profile := `<trt:Profiles token="` + token + `"><tt:Name>` + token + `</tt:Name>`
+	if source != "" {
+		profile += `<tt:VideoSourceConfiguration token="config_` + source + `"><tt:Name>` + source + `</tt:Name><tt:SourceToken>` + source + `</tt:SourceToken></tt:VideoSourceConfiguration>`
+	}
+	if ptz != "" {
+		profile += `<tt:PTZConfiguration token="ptz"><tt:Name>ptz</tt:Name><tt:NodeToken>` + ptz + `</tt:NodeToken></tt:PTZConfiguration>`
+	}
+	return profile + `</trt:Profiles>`
+}
+
+func TestSensors(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetProfiles": `<trt:GetProfilesResponse>` + sensorProfile("second_main", "VideoSource_2", "") + sensorProfile("first_main", "VideoSource_1", "") +
+			sensorProfile("first_ptz", "VideoSource_1", "node_1") + sensorProfile("audio", "", "") + `</trt:GetProfilesResponse>`,
+		"GetStreamUri":   `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://10.1.1.20/cam/realmonitor?channel=1&amp;subtype=0</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`,
+		"ContinuousMove": `<tptz:ContinuousMoveResponse/>`,
+	}, ServiceMedia, ServicePTZ)
+	ctx := context.Background()
+	sensors, err := dev.Sensors(ctx)
+	if err != nil || len(sensors) != 2 {
+		t.Fatalf("sensors %+v, %v", sensors, err)
+	}
+	/* 按视频源token的编号排序 */
+	first, second := sensors[0], sensors[1]
+	if first.Channel != 1 || first.VideoSource != "VideoSource_1" || strings.Join(first.Profiles, ",") != "first_main,first_ptz" || first.PTZProfile != "first_ptz" ||
+		second.Channel != 2 || strings.Join(second.Profiles, ",") != "second_main" || second.PTZProfile != "" {
+		t.Fatalf("sensors %+v", sensors)
+	}
+	if _, err := dev.Sensor(ctx, 3); !errors.Is(err, ErrUnknownSensor) {
+		t.Fatalf("error %v", err)
+	}
+
+	/* 各通道都应答第一通道的URI时修正channel参数 */
+	sensor, err := dev.Sensor(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := sensor.GetStreamURI(ctx, StreamRTSP)
+	if err != nil || URIChannel(uri) != 2 || !strings.Contains(uri, "subtype=0") {
+		t.Fatalf("stream uri %q, %v", uri, err)
+	}
+	if requests := fake.sent("GetStreamUri"); len(requests) != 1 || !strings.Contains(requests[0], "<trt:ProfileToken>second_main</trt:ProfileToken>") {
+		t.Fatalf("requests %q", requests)
+	}
+	if profiles, err := sensor.GetProfiles(ctx); err != nil || len(profiles) != 1 || profiles[0].Token != "second_main" {
+		t.Fatalf("profiles %+v, %v", profiles, err)
+	}
+
+	var unsupported *NotSupportedError
+	if err := sensor.ContinuousMove(ctx, onvif.PTZSpeed{}, 0); !errors.As(err, &unsupported) {
+		t.Fatalf("error %v moving a fixed sensor", err)
+	}
+	if err := first.ContinuousMove(ctx, onvif.PTZSpeed{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if requests := fake.sent("ContinuousMove"); len(requests) != 1 || !strings.Contains(requests[0], "<tptz:ProfileToken>first_ptz</tptz:ProfileToken>") {
+		t.Fatalf("requests %q", requests)
+	}
+	if _, err := (Sensor{Device: dev, Channel: 3}).GetSnapshotURI(ctx); !errors.Is(err, ErrNoProfile) {
+		t.Fatalf("error %v for a sensor without profile", err)
+	}
+}
+
+func TestNumberSensors(t *testing.T) {
+	for _, test := range []struct {
+		sources  []string
+		channels []int
+	}{
+		{[]string{"VideoSource_2", "VideoSource_1"}, []int{1, 2}},
+		{[]string{"000", "001", "002"}, []int{1, 2, 3}},
+		{[]string{"main", "VideoSource_2"}, []int{1, 2}},
+	} {
+		sensors := make([]Sensor, len(test.sources))
+		for i, source := range test.sources {
+			sensors[i].VideoSource = source
+		}
+		numberSensors(sensors)
+		for i, sensor := range sensors {
+			if sensor.Channel != test.channels[i] {
+				t.Fatalf("%v numbered %+v", test.sources, sensors)
+			}
+		}
+	}
+}
+
+func TestURIChannel(t *testing.T) {
+	if channel := URIChannel("rtsp://10.1.1.20/cam/realmonitor?Channel=3&subtype=1"); channel != 3 {
+		t.Fatalf("channel %d", channel)
+	}
+	if channel := URIChannel("rtsp://10.1.1.20/stream1"); channel != 0 {
+		t.Fatalf("channel %d", channel)
+	}
+	if uri := WithURIChannel("rtsp://10.1.1.20/cam/realmonitor?channel=1", 4); uri != "rtsp://10.1.1.20/cam/realmonitor?channel=4" {
+		t.Fatalf("uri %q", uri)
+	}
+	/* 没有channel参数的URI不变 */
+	if uri := WithURIChannel("rtsp://10.1.1.20/stream1?a=b", 4); uri != "rtsp://10.1.1.20/stream1?a=b" {
+		t.Fatalf("uri %q", uri)
+	}
+}
@@ -0,0 +1,78 @@
+package onvif
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ServiceVersion version of a service advertised by GetServices. Since ONVIF
+// 2.6 the version is the release of the specification, e.g. 16.12.
+type ServiceVersion struct {
+	Major int
+	Minor int
+}
+
+func (version ServiceVersion) String() string {
+	return fmt.Sprintf("%d.%d", version.Major, version.Minor)
+}
+
+// AtLeast report whether the version is major.minor or later
+func (version ServiceVersion) AtLeast(major, minor int) bool {
+	return version.Major > major || (version.Major == major && version.Minor >= minor)
+}
+
+// ServiceVersion return the version of service, the endpoint key such as
+// "media", "media2" or "ptz", false when the device did not advertise it
+func (dev *Device) ServiceVersion(service string) (ServiceVersion, bool) {
+	dev.ensureServices(context.Background())
+	defer dev.readEndpoints()()
+	version, ok := dev.versions[strings.ToLower(service)]
+	return version, ok
+}
+
+// operationVersion service version introducing an operation
+type operationVersion struct {
+	service string
+	version ServiceVersion
+}
+
+var (
+	versionMutex sync.RWMutex
+	// operationVersions operations missing from the first versions of their
+	// service
+	operationVersions = map[string]operationVersion{
+		"GeoMove": {service: "ptz", version: ServiceVersion{Major: 16, Minor: 12}},
+	}
+)
+
+// RegisterOperationVersion declare that operation, the name of its method
+// type, requires version major.minor of service. Calls to devices advertising
+// an older version fail with a NotSupportedError without being sent.
+func RegisterOperationVersion(operation, service string, major, minor int) {
+	versionMutex.Lock()
+	defer versionMutex.Unlock()
+	operationVersions[operation] = operationVersion{service: strings.ToLower(service), version: ServiceVersion{Major: major, Minor: minor}}
+}
+
+// checkVersion return a NotSupportedError when the device advertises a
+// version of the service of method older than the one introducing it, the
+// call is allowed when the version is unknown
+func (dev Device) checkVersion(method interface{}) error {
+	operation := operationName(method)
+	versionMutex.RLock()
+	required, ok := operationVersions[operation]
+	versionMutex.RUnlock()
+	if !ok {
+		return nil
+	}
+	dev.ensureServices(context.Background())
+	unlock := dev.readEndpoints()
+	version, known := dev.versions[required.service]
+	unlock()
+	if known && !version.AtLeast(required.version.Major, required.version.Minor) {
+		return &NotSupportedError{Service: required.service, Capability: operation}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/PolarisM78/go-onvif/core"
+	"github.com/PolarisM78/go-onvif/types/device"
+
+	"github.com/beevik/etree"
+)
+
+// Endpoint keys of the services, the lower case name of the package holding
+// the types of the service
+const (
+	ServiceDevice        = core.ServiceDevice
+	ServiceMedia         = core.ServiceMedia
+	ServiceMedia2        = core.ServiceMedia2
+	ServiceEvents        = core.ServiceEvents
+	ServicePTZ           = core.ServicePTZ
+	ServiceImaging       = core.ServiceImaging
+	ServiceAnalytics     = core.ServiceAnalytics
+	ServiceDeviceIO      = core.ServiceDeviceIO
+	ServiceRecording     = core.ServiceRecording
+	ServiceSearch        = core.ServiceSearch
+	ServiceReplay        = core.ServiceReplay
+	ServiceAccessControl = core.ServiceAccessControl
+	ServiceCredential    = core.ServiceCredential
+	ServiceSchedule      = core.ServiceSchedule
+	// ServiceAdvancedSecurity keystore, certificates and media signing
+	ServiceAdvancedSecurity = core.ServiceAdvancedSecurity
+)
+
+// ServiceEndpoint service offered by a device
+type ServiceEndpoint struct {
+	// Key endpoint key of the service, e.g. ServiceEvents
+	Key       string
+	Namespace string
+	XAddr     string
+	// Version advertised by GetServices, zero when the device does not
+	// advertise it
+	Version ServiceVersion
+	// Capabilities the service capabilities of the service were read, see
+	// Device.ServiceCapabilities
+	Capabilities bool
+}
+
+// ServiceEndpoints services of a device by endpoint key
+type ServiceEndpoints map[string]ServiceEndpoint
+
+// Lookup return the service of key, e.g. ServiceEvents, case insensitively
+func (endpoints ServiceEndpoints) Lookup(key string) (ServiceEndpoint, bool) {
+	endpoint, ok := endpoints[strings.ToLower(key)]
+	return endpoint, ok
+}
+
+// ByNamespace return the service of the WSDL namespace, e.g.
+// http://www.onvif.org/ver20/media/wsdl
+func (endpoints ServiceEndpoints) ByNamespace(namespace string) (ServiceEndpoint, bool) {
+	for _, endpoint := range endpoints {
+		if endpoint.Namespace != "" && endpoint.Namespace == namespace {
+			return endpoint, true
+		}
+	}
+	return ServiceEndpoint{}, false
+}
+
+// Sorted return the services in key order
+func (endpoints ServiceEndpoints) Sorted() []ServiceEndpoint {
+	sorted := make([]ServiceEndpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		sorted = append(sorted, endpoint)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+	return sorted
+}
+
+// Services return the services of the device with their namespace, address
+// and version. The endpoint keys are normalized, e.g. the Events or EVENTS
+// element of GetCapabilities is ServiceEvents.
+func (dev *Device) Services() ServiceEndpoints {
+	dev.ensureServices(context.Background())
+	namespaces := make(map[string]string, len(serviceKeys)+len(capabilityServiceKeys))
+	for _, keys := range []map[string]string{serviceKeys, capabilityServiceKeys} {
+		for namespace, key := range keys {
+			namespaces[key] = namespace
+		}
+	}
+	var capabilities *ServiceCapabilities
+	if cache := dev.capabilities; cache != nil {
+		cache.mutex.Lock()
+		if cache.loaded {
+			capabilities = &cache.capabilities
+		}
+		cache.mutex.Unlock()
+	}
+	defer dev.readEndpoints()()
+	endpoints := make(ServiceEndpoints, len(dev.endpoints))
+	for key, xaddr := range dev.endpoints {
+		endpoints[key] = ServiceEndpoint{
+			Key:          key,
+			Namespace:    namespaces[key],
+			XAddr:        xaddr,
+			Version:      dev.versions[key],
+			Capabilities: capabilities != nil && capabilities.has(key),
+		}
+	}
+	return endpoints
+}
+
+// serviceKeys endpoint key of the services only advertised by GetServices,
+// the key is the name of the package holding the types of the service
+var serviceKeys = map[string]string{
+	"http://www.onvif.org/ver10/accesscontrol/wsdl":    ServiceAccessControl,
+	"http://www.onvif.org/ver10/credential/wsdl":       ServiceCredential,
+	"http://www.onvif.org/ver10/schedule/wsdl":         ServiceSchedule,
+	"http://www.onvif.org/ver10/recording/wsdl":        ServiceRecording,
+	"http://www.onvif.org/ver10/search/wsdl":           ServiceSearch,
+	"http://www.onvif.org/ver10/replay/wsdl":           ServiceReplay,
+	"http://www.onvif.org/ver20/media/wsdl":            ServiceMedia2,
+	"http://www.onvif.org/ver10/advancedsecurity/wsdl": ServiceAdvancedSecurity,
+}
+
+// capabilityServiceKeys endpoint key of the services reported by
+// GetCapabilities, used to record their versions
+var capabilityServiceKeys = map[string]string{
+	"http://www.onvif.org/ver10/device/wsdl":    ServiceDevice,
+	"http://www.onvif.org/ver10/media/wsdl":     ServiceMedia,
+	"http://www.onvif.org/ver10/events/wsdl":    ServiceEvents,
+	"http://www.onvif.org/ver20/ptz/wsdl":       ServicePTZ,
+	"http://www.onvif.org/ver20/imaging/wsdl":   ServiceImaging,
+	"http://www.onvif.org/ver20/analytics/wsdl": ServiceAnalytics,
+	"http://www.onvif.org/ver10/deviceIO/wsdl":  ServiceDeviceIO,
+}
+
+// loadServices register the endpoints of the services listed by GetServices
+// which GetCapabilities does not report and the versions of every service,
+// devices without GetServices are left unchanged and false is returned. The
+// endpoints already known are replaced when overwrite is set.
+func (dev Device) loadServices(ctx context.Context, overwrite bool) bool {
+	response := device.GetServicesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetServices{}, &response, ""); err != nil {
+		return false
+	}
+	for _, service := range response.Service {
+		/* GetCapabilities报告的服务只记录版本 */
+		key, reported := capabilityServiceKeys[string(service.Namespace)]
+		if !reported {
+			var ok bool
+			if key, ok = serviceKeys[string(service.Namespace)]; !ok || service.XAddr == "" {
+				continue
+			}
+		}
+		unlock := dev.writeEndpoints()
+		dev.versions[key] = ServiceVersion{Major: service.Version.Major, Minor: service.Version.Minor}
+		_, found := dev.endpoints[key]
+		unlock()
+		if !reported && (overwrite || !found) {
+			dev.addEndpoint(key, string(service.XAddr))
+		}
+	}
+	return true
+}
+
+// ensureServices request GetServices on first use, once per device. The
+// device service alone is used to request it, the other endpoints are not
+// looked up with the services mutex held.
+func (dev Device) ensureServices(ctx context.Context) bool {
+	cache := dev.capabilities
+	if cache == nil {
+		return false
+	}
+	cache.servicesMutex.Lock()
+	defer cache.servicesMutex.Unlock()
+	if cache.servicesLoaded {
+		return true
+	}
+	cache.servicesLoaded = true
+	return dev.loadServices(ctx, false)
+}
+
+// RefreshServices read the services of the device again, GetCapabilities
+// then GetServices, updating the endpoints and versions of the services, e.g.
+// after the ports or the protocols of the device were changed. Services no
+// longer advertised are kept. The cached capabilities are dropped.
+func (dev *Device) RefreshServices(ctx context.Context) error {
+	body, err := dev.CallRaw(ctx, ServiceDevice, `<tds:GetCapabilities xmlns:tds="`+Xlmns["tds"]+`">`+
+		`<tds:Category>All</tds:Category></tds:GetCapabilities>`, nil)
+	if err != nil {
+		return err
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(body); err != nil {
+		return err
+	}
+	capabilities := doc.FindElement("./GetCapabilitiesResponse/Capabilities")
+	if capabilities == nil {
+		return errors.New("no capabilities in GetCapabilities response")
+	}
+	for _, xaddr := range capabilityXAddrs(capabilities) {
+		/* 设备服务地址即当前使用的地址,保持不变 */
+		if key := strings.ToLower(xaddr.Parent().Tag); key != ServiceDevice {
+			dev.addEndpoint(key, xaddr.Text())
+		}
+	}
+	if cache := dev.capabilities; cache != nil {
+		cache.servicesMutex.Lock()
+		cache.servicesLoaded = true
+		cache.servicesMutex.Unlock()
+	}
+	dev.loadServices(ctx, true)
+	dev.InvalidateCache()
+	return nil
+}
+
+// capabilityXAddrs XAddr elements of the services of the Capabilities of
+// GetCapabilities, those of its Extension included, e.g. DeviceIO
+func capabilityXAddrs(capabilities *etree.Element) []*etree.Element {
+	return append(capabilities.FindElements("./*/XAddr"), capabilities.FindElements("./Extension/*/XAddr")...)
+}
@@ -0,0 +1,146 @@
+package onvif
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+/* 以GetCapabilities应答服务地址的设备,DeviceIO位于Extension中,GetServices返回错误 */
+type capabilitiesDevice struct {
+	mutex sync.Mutex
+	// deviceIO path of the DeviceIO service
+	deviceIO string
+}
+
+func (fake *capabilitiesDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if !strings.Contains(string(body), "GetCapabilities") {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><s:Fault/></s:Body></s:Envelope>`))
+		return
+	}
+	xaddr := "http://" + r.Host
+	w.Write([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema" xmlns:tds="http://www.onvif.org/ver10/device/wsdl">` +
+		`<s:Body><tds:GetCapabilitiesResponse><tds:Capabilities>` +
+		`<tt:Device><tt:XAddr>` + xaddr + `/onvif/device_service</tt:XAddr></tt:Device>` +
+		`<tt:Media><tt:XAddr>` + xaddr + `/onvif/Media</tt:XAddr></tt:Media>` +
+		`<tt:Extension><tt:DeviceIO><tt:XAddr>` + xaddr + fake.deviceIO + `</tt:XAddr></tt:DeviceIO></tt:Extension>` +
+		`</tds:Capabilities></tds:GetCapabilitiesResponse></s:Body></s:Envelope>`))
+}
+
+func (fake *capabilitiesDevice) setDeviceIO(path string) {
+	fake.mutex.Lock()
+	fake.deviceIO = path
+	fake.mutex.Unlock()
+}
+
+func newCapabilitiesDevice(t *testing.T) (*capabilitiesDevice, *Device) {
+	fake := &capabilitiesDevice{deviceIO: "/onvif/DeviceIO"}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	dev := newDevice(DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://")})
+	dev.endpoints[ServiceDevice] = server.URL + "/onvif/device_service"
+	/* 不再读取GetServices */
+	dev.capabilities.servicesLoaded = true
+	return fake, dev
+}
+
+func TestRefreshServicesExtension(t *testing.T) {
+	fake, dev := newCapabilitiesDevice(t)
+	if err := dev.RefreshServices(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fake.setDeviceIO("/onvif/IO")
+	if err := dev.RefreshServices(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if xaddr, _ := dev.getEndpoint(ServiceDeviceIO); !strings.HasSuffix(xaddr, "/onvif/IO") {
+		t.Fatalf("deviceio endpoint %q", xaddr)
+	}
+}
+
+func TestGetServicesCopy(t *testing.T) {
+	fake, dev := newCapabilitiesDevice(t)
+	if err := dev.RefreshServices(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	services := dev.GetServices()
+	services[ServiceMedia] = "http://changed/"
+	fake.setDeviceIO("/onvif/IO")
+	if err := dev.RefreshServices(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if xaddr, _ := dev.getEndpoint(ServiceMedia); !strings.HasSuffix(xaddr, "/onvif/Media") {
+		t.Fatalf("media endpoint %q changed through GetServices", xaddr)
+	}
+	if strings.HasSuffix(services[ServiceDeviceIO], "/onvif/IO") {
+		t.Fatal("GetServices copy changed by RefreshServices")
+	}
+}
+
+func TestConfigurationEventRefreshesServices(t *testing.T) {
+	fake, dev := newCapabilitiesDevice(t)
+	dev.capabilities.loaded = true
+	engine := NewEventEngine(1)
+	/* 未调用WatchConfiguration,引擎仍需处理配置变更 */
+	fake.setDeviceIO("/onvif/IO")
+	engine.Inject(context.Background(), dev, Event{Topic: "tns1:Device/Configuration", Operation: "Changed"})
+	if xaddr, _ := dev.getEndpoint(ServiceDeviceIO); !strings.HasSuffix(xaddr, "/onvif/IO") {
+		t.Fatalf("deviceio endpoint %q", xaddr)
+	}
+	if dev.capabilities.loaded {
+		t.Fatal("cached capabilities kept after a configuration change")
+	}
+}
+
+func TestServices(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServices": `<tds:GetServicesResponse>` +
+			`<tds:Service><tds:Namespace>http://www.onvif.org/ver10/media/wsdl</tds:Namespace><tds:XAddr>http://10.0.0.1/onvif/Media</tds:XAddr><tds:Version><tt:Major>2</tt:Major><tt:Minor>60</tt:Minor></tds:Version></tds:Service>` +
+			`<tds:Service><tds:Namespace>http://www.onvif.org/ver20/media/wsdl</tds:Namespace><tds:XAddr>http://10.0.0.1/onvif/Media2</tds:XAddr><tds:Version><tt:Major>19</tt:Major><tt:Minor>6</tt:Minor></tds:Version></tds:Service>` +
+			`</tds:GetServicesResponse>`,
+	}, ServiceMedia)
+	/* 首次调用时读取GetServices,已知的media地址不被替换 */
+	dev.capabilities.servicesLoaded = false
+	services := dev.Services()
+	if len(fake.sent("GetServices")) != 1 {
+		t.Fatalf("requests %q", fake.sent("GetServices"))
+	}
+	media, ok := services.Lookup("Media")
+	if !ok || media.Key != ServiceMedia || media.Namespace != "http://www.onvif.org/ver10/media/wsdl" || !strings.HasSuffix(media.XAddr, "/onvif/media") ||
+		media.Version.String() != "2.60" || media.Capabilities {
+		t.Fatalf("media %+v, %v", media, ok)
+	}
+	media2, ok := services.ByNamespace("http://www.onvif.org/ver20/media/wsdl")
+	if !ok || media2.Key != ServiceMedia2 || !strings.HasSuffix(media2.XAddr, "/onvif/Media2") || !media2.Version.AtLeast(19, 6) {
+		t.Fatalf("media2 %+v, %v", media2, ok)
+	}
+	if _, ok := services.Lookup(ServiceEvents); ok {
+		t.Fatal("events service not offered by the device")
+	}
+	var keys []string
+	for _, service := range services.Sorted() {
+		keys = append(keys, service.Key)
+	}
+	if strings.Join(keys, ",") != "device,media,media2" {
+		t.Fatalf("services %v", keys)
+	}
+	/* 读取过服务能力的服务被标记 */
+	fake.set("GetServiceCapabilities", `<trt:GetServiceCapabilitiesResponse><trt:Capabilities SnapshotUri="true"/></trt:GetServiceCapabilitiesResponse>`)
+	if _, err := dev.ServiceCapabilities(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if media, _ := dev.Services().Lookup(ServiceMedia); !media.Capabilities {
+		t.Fatalf("media %+v", media)
+	}
+	if media2, _ := dev.Services().Lookup(ServiceMedia2); media2.Capabilities {
+		t.Fatalf("media2 %+v", media2)
+	}
+}
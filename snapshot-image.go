@@ -0,0 +1,144 @@
+package onvif
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // some devices serve PNG snapshots
+)
+
+// Decode decode the snapshot image
+func (snapshot Snapshot) Decode() (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(snapshot.Image))
+	return img, err
+}
+
+// Thumbnail return the snapshot scaled down to fit in maxWidth x maxHeight,
+// keeping its aspect ratio; a dimension of 0 is not constrained. A snapshot
+// already small enough is returned at its size.
+func (snapshot Snapshot) Thumbnail(maxWidth, maxHeight int) (image.Image, error) {
+	img, err := snapshot.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return Resize(img, maxWidth, maxHeight), nil
+}
+
+// ThumbnailJPEG return the thumbnail encoded as JPEG, quality between 1 and
+// 100, 0 meaning the encoder default. The encoded image holds no metadata.
+func (snapshot Snapshot) ThumbnailJPEG(maxWidth, maxHeight, quality int) ([]byte, error) {
+	img, err := snapshot.Thumbnail(maxWidth, maxHeight)
+	if err != nil {
+		return nil, err
+	}
+	buffer := bytes.Buffer{}
+	var options *jpeg.Options
+	if quality > 0 {
+		options = &jpeg.Options{Quality: quality}
+	}
+	if err := jpeg.Encode(&buffer, img, options); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Resize scale img down to fit in maxWidth x maxHeight by averaging the
+// source pixels covered by each destination pixel
+func Resize(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && height > maxHeight && float64(maxHeight)/float64(height) < scale {
+		scale = float64(maxHeight) / float64(height)
+	}
+	if scale == 1 || width == 0 || height == 0 {
+		return img
+	}
+	dstWidth, dstHeight := int(float64(width)*scale+0.5), int(float64(height)*scale+0.5)
+	if dstWidth < 1 {
+		dstWidth = 1
+	}
+	if dstHeight < 1 {
+		dstHeight = 1
+	}
+	src, ok := img.(*image.RGBA)
+	if !ok || src.Bounds().Min != (image.Point{}) {
+		src = image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0, y1 := y*height/dstHeight, (y+1)*height/dstHeight
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dstWidth; x++ {
+			x0, x1 := x*width/dstWidth, (x+1)*width/dstWidth
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / count)
+			}
+		}
+	}
+	return dst
+}
+
+// StripMetadata return the JPEG snapshot without its EXIF, XMP, IPTC and
+// comment segments, which can carry the device name, position or time. The
+// image data is copied as is, without decoding it again.
+func (snapshot Snapshot) StripMetadata() ([]byte, error) {
+	return stripJPEGMetadata(snapshot.Image)
+}
+
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, errors.New("not a JPEG image")
+	}
+	stripped := make([]byte, 0, len(data))
+	stripped = append(stripped, 0xff, 0xd8)
+	offset := 2
+	for {
+		if offset+4 > len(data) || data[offset] != 0xff {
+			return nil, errors.New("malformed JPEG segment")
+		}
+		marker := data[offset+1]
+		/* 填充字节 */
+		if marker == 0xff {
+			offset++
+			continue
+		}
+		/* SOS之后为压缩数据,原样保留 */
+		if marker == 0xda {
+			return append(stripped, data[offset:]...), nil
+		}
+		length := int(data[offset+2])<<8 | int(data[offset+3])
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, errors.New("malformed JPEG segment")
+		}
+		/* 去掉APP1(EXIF/XMP),APP13(IPTC)及COM段,其余段影响解码需保留 */
+		if marker != 0xe1 && marker != 0xed && marker != 0xfe {
+			stripped = append(stripped, data[offset:end]...)
+		}
+		offset = end
+	}
+}
@@ -0,0 +1,55 @@
+package onvif
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestResize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	img.Set(0, 0, color.RGBA{R: 200, A: 255})
+	img.Set(1, 1, color.RGBA{R: 100, A: 255})
+	/* 宽度限制更严,保持宽高比,每个目标像素为覆盖的源像素平均值 */
+	resized := Resize(img, 2, 2)
+	if resized.Bounds() != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("bounds %v", resized.Bounds())
+	}
+	if r, _, _, a := resized.At(0, 0).RGBA(); r>>8 != 75 || a>>8 != 127 {
+		t.Fatalf("pixel %v", resized.At(0, 0))
+	}
+	if Resize(img, 8, 0) != image.Image(img) {
+		t.Fatal("small image resized")
+	}
+}
+
+func TestSnapshotThumbnailAndMetadata(t *testing.T) {
+	buffer := bytes.Buffer{}
+	if err := jpeg.Encode(&buffer, image.NewGray(image.Rect(0, 0, 64, 32)), nil); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buffer.Bytes()
+	/* 在SOI之后插入EXIF与注释段 */
+	exif := append([]byte{0xff, 0xe1, 0x00, 0x0e}, "Exif\x00\x00camera"...)
+	comment := append([]byte{0xff, 0xfe, 0x00, 0x08}, "site42"...)
+	data := append(append(append([]byte{}, encoded[:2]...), append(exif, comment...)...), encoded[2:]...)
+	snapshot := Snapshot{Image: data}
+
+	stripped, err := snapshot.StripMetadata()
+	if err != nil || !bytes.Equal(stripped, encoded) {
+		t.Fatalf("stripped %d bytes of %d, %v", len(stripped), len(encoded), err)
+	}
+	thumbnail, err := snapshot.ThumbnailJPEG(16, 16, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := Snapshot{Image: thumbnail}.Decode()
+	if err != nil || img.Bounds() != image.Rect(0, 0, 16, 8) {
+		t.Fatalf("thumbnail %v, %v", img.Bounds(), err)
+	}
+	if _, err := (Snapshot{Image: []byte("<html/>")}).StripMetadata(); err == nil {
+		t.Fatal("metadata stripped from a non JPEG")
+	}
+}
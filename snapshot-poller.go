@@ -0,0 +1,183 @@
+package onvif
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// SnapshotState last snapshot of a device taken by a SnapshotPoller
+type SnapshotState struct {
+	// Device address of the device, as in DeviceParams.Ipddr
+	Device  string `json:"device"`
+	Profile string `json:"profile"`
+	// Time of the last successful snapshot
+	Time time.Time `json:"time"`
+	// Digest SHA-256 of the last image, lower case hex
+	Digest string `json:"digest,omitempty"`
+	// LastAttempt time of the last snapshot, Error its error
+	LastAttempt time.Time `json:"lastAttempt"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// SnapshotPoller take a snapshot of a profile of every device every Interval,
+// e.g. to feed the thumbnails of a video wall. With a Store the state of the
+// devices is kept, so a restarted poller waits until the snapshots are due
+// and still recognises the unchanged images.
+//
+//	poller := onvif.NewSnapshotPoller(fleet)
+//	poller.OnSnapshot = func(dev *onvif.Device, snapshot onvif.Snapshot) { ... }
+//	go poller.Run(ctx)
+type SnapshotPoller struct {
+	Fleet *Fleet
+	// Registry when set, the devices registered at each round are polled
+	// instead of Fleet
+	Registry *Registry
+	// Profile token of the profile, the first profile of each device when
+	// empty
+	Profile string
+	// Interval between two snapshots of a device, 0 means 1m
+	Interval time.Duration
+	// Timeout of one snapshot, 0 means 10s
+	Timeout time.Duration
+	// SkipUnchanged do not report an image identical to the previous one
+	SkipUnchanged bool
+	// Store optional persistence of the state of the devices
+	Store KVStore
+	// OnSnapshot called with every new snapshot
+	OnSnapshot func(dev *Device, snapshot Snapshot)
+	// OnError optional callback for the errors of the snapshots and of Store
+	OnError func(dev *Device, err error)
+
+	mutex sync.Mutex
+	state map[string]SnapshotState
+}
+
+// NewSnapshotPoller return a poller of the devices of the fleet
+func NewSnapshotPoller(fleet *Fleet) *SnapshotPoller {
+	return &SnapshotPoller{Fleet: fleet, state: make(map[string]SnapshotState)}
+}
+
+// State return the last snapshot state of dev
+func (poller *SnapshotPoller) State(dev *Device) (SnapshotState, bool) {
+	poller.mutex.Lock()
+	defer poller.mutex.Unlock()
+	return poller.current(dev)
+}
+
+// current return the state of dev, read from Store when the device was never
+// polled by this poller; the caller holds the mutex
+func (poller *SnapshotPoller) current(dev *Device) (SnapshotState, bool) {
+	if poller.state == nil {
+		poller.state = make(map[string]SnapshotState)
+	}
+	if state, ok := poller.state[dev.Params.Ipddr]; ok || poller.Store == nil {
+		return state, ok
+	}
+	state := SnapshotState{}
+	found, err := getJSON(poller.Store, SnapshotBucket, dev.Params.Ipddr, &state)
+	if err != nil {
+		poller.reportError(dev, err)
+	}
+	if !found || err != nil {
+		return SnapshotState{}, false
+	}
+	poller.state[dev.Params.Ipddr] = state
+	return state, true
+}
+
+func (poller *SnapshotPoller) fleet() *Fleet {
+	if poller.Registry != nil {
+		return poller.Registry.Fleet(nil)
+	}
+	return poller.Fleet
+}
+
+// Poll snapshot the devices once, the ones whose next snapshot is not due
+// included
+func (poller *SnapshotPoller) Poll(ctx context.Context) {
+	poller.round(ctx, time.Time{})
+}
+
+// round snapshot the devices due at now, every device when now is zero
+func (poller *SnapshotPoller) round(ctx context.Context, now time.Time) {
+	interval := durationOr(poller.Interval, time.Minute)
+	poller.fleet().each(ctx, func(i int, dev *Device) {
+		poller.mutex.Lock()
+		state, _ := poller.current(dev)
+		poller.mutex.Unlock()
+		/* 留半个间隔的余量,快照耗时不致使设备跳过一轮 */
+		if !now.IsZero() && now.Before(state.LastAttempt.Add(interval/2)) || dev.Quarantined() != nil {
+			return
+		}
+		poller.poll(ctx, dev, state)
+	})
+}
+
+func (poller *SnapshotPoller) poll(ctx context.Context, dev *Device, state SnapshotState) {
+	snapshotCtx, cancel := context.WithTimeout(ctx, durationOr(poller.Timeout, 10*time.Second))
+	defer cancel()
+	state.Device = dev.Params.Ipddr
+	state.LastAttempt = time.Now()
+	profile := poller.Profile
+	var err error
+	if profile == "" {
+		var token onvif.ReferenceToken
+		token, err = firstProfile(snapshotCtx, dev)
+		profile = string(token)
+	}
+	snapshot := Snapshot{}
+	if err == nil {
+		snapshot, err = dev.Snapshot(snapshotCtx, profile)
+	}
+	changed := false
+	if err != nil {
+		state.Error = err.Error()
+		poller.reportError(dev, err)
+	} else {
+		sum := sha256.Sum256(snapshot.Image)
+		digest := hex.EncodeToString(sum[:])
+		changed = digest != state.Digest || profile != state.Profile
+		state.Profile, state.Digest, state.Time, state.Error = profile, digest, state.LastAttempt, ""
+	}
+
+	poller.mutex.Lock()
+	if poller.state == nil {
+		poller.state = make(map[string]SnapshotState)
+	}
+	poller.state[dev.Params.Ipddr] = state
+	poller.mutex.Unlock()
+	if poller.Store != nil {
+		if err := putJSON(poller.Store, SnapshotBucket, dev.Params.Ipddr, state); err != nil {
+			poller.reportError(dev, err)
+		}
+	}
+	if err == nil && poller.OnSnapshot != nil && (changed || !poller.SkipUnchanged) {
+		poller.OnSnapshot(dev, snapshot)
+	}
+}
+
+// Run snapshot the devices every Interval until ctx is done
+func (poller *SnapshotPoller) Run(ctx context.Context) error {
+	interval := durationOr(poller.Interval, time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		poller.round(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (poller *SnapshotPoller) reportError(dev *Device, err error) {
+	if poller.OnError != nil {
+		poller.OnError(dev, err)
+	}
+}
@@ -0,0 +1,172 @@
+package onvif
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Snapshot sources
+const (
+	SnapshotFromURI  = "snapshot-uri"
+	SnapshotFromRTSP = "rtsp"
+)
+
+// Snapshot still image of a profile
+type Snapshot struct {
+	Image       []byte
+	ContentType string
+	// Source SnapshotFromURI or SnapshotFromRTSP
+	Source string
+	// URI of the snapshot or of the stream the frame was grabbed from
+	URI string
+}
+
+// FrameGrabber grab a single frame of an RTSP stream as a JPEG image. The
+// package holds no video decoder, FFmpegGrabber runs ffmpeg and any other
+// decoder can be plugged in.
+type FrameGrabber interface {
+	GrabFrame(ctx context.Context, streamURI, username, password string) ([]byte, error)
+}
+
+// FrameGrabberFunc adapt a function to the FrameGrabber interface
+type FrameGrabberFunc func(ctx context.Context, streamURI, username, password string) ([]byte, error)
+
+// GrabFrame call fn
+func (fn FrameGrabberFunc) GrabFrame(ctx context.Context, streamURI, username, password string) ([]byte, error) {
+	return fn(ctx, streamURI, username, password)
+}
+
+// FFmpegGrabber FrameGrabber running the ffmpeg command
+type FFmpegGrabber struct {
+	// Path of the ffmpeg binary, "ffmpeg" from PATH when empty
+	Path string
+	// Transport RTSP lower transport, "tcp" when empty
+	Transport string
+}
+
+// GrabFrame decode the first frame of the stream
+func (grabber FFmpegGrabber) GrabFrame(ctx context.Context, streamURI, username, password string) ([]byte, error) {
+	transport := grabber.Transport
+	if transport == "" {
+		transport = "tcp"
+	}
+	stdout := bytes.Buffer{}
+	output := []string{"-frames:v", "1", "-f", "image2", "-c:v", "mjpeg", "pipe:1"}
+	if err := runFFmpeg(ctx, grabber.Path, streamURI, username, password, transport, output, &stdout); err != nil {
+		return nil, err
+	}
+	if stdout.Len() == 0 {
+		return nil, errors.New("ffmpeg: no frame decoded")
+	}
+	return stdout.Bytes(), nil
+}
+
+// Snapshot return a still image of the profile from its snapshot URI. When
+// the device has no snapshot URI or serves no image there, a frame of the
+// RTSP stream is grabbed instead if DeviceParams.FrameGrabber is set.
+func (dev *Device) Snapshot(ctx context.Context, profile string) (Snapshot, error) {
+	snapshot, err := dev.uriSnapshot(ctx, profile)
+	if err == nil || dev.Params.FrameGrabber == nil {
+		return snapshot, err
+	}
+	/* 快照地址不可用,从RTSP流中截取一帧 */
+	snapshot, rtspErr := dev.rtspSnapshot(ctx, profile)
+	if rtspErr != nil {
+		return Snapshot{}, fmt.Errorf("snapshot uri: %v, rtsp fallback: %w", err, rtspErr)
+	}
+	return snapshot, nil
+}
+
+func (dev *Device) uriSnapshot(ctx context.Context, profile string) (Snapshot, error) {
+	uri, err := dev.GetSnapshotURI(ctx, profile)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	image, contentType, err := dev.fetchImage(ctx, uri)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{Image: image, ContentType: contentType, Source: SnapshotFromURI, URI: uri}, nil
+}
+
+func (dev *Device) rtspSnapshot(ctx context.Context, profile string) (Snapshot, error) {
+	uri, err := dev.GetStreamURI(ctx, profile, StreamRTSP)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	image, err := dev.Params.FrameGrabber.GrabFrame(ctx, uri, dev.Params.Username, dev.Params.Password)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{Image: image, ContentType: http.DetectContentType(image), Source: SnapshotFromRTSP, URI: uri}, nil
+}
+
+// fetchImage download the image at uri, answering a basic or digest
+// authentication challenge with the device credentials
+func (dev *Device) fetchImage(ctx context.Context, uri string) ([]byte, string, error) {
+	resp, err := dev.authorizedGet(ctx, uri)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("snapshot status %s", resp.Status)
+	}
+	image, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	/* 部分设备返回错误页面或空内容,按内容判断是否为图片 */
+	contentType := http.DetectContentType(image)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("snapshot is not an image, got %s", contentType)
+	}
+	return image, contentType, nil
+}
+
+// authorizedGet get uri, answering a basic or digest authentication
+// challenge with the device credentials
+func (dev *Device) authorizedGet(ctx context.Context, uri string) (*http.Response, error) {
+	if err := dev.checkQuarantine(); err != nil {
+		return nil, err
+	}
+	get := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, err
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		dev.setRequestHeaders(ctx, req)
+		return dev.httpClient.Do(req)
+	}
+	resp, err := get("")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && dev.Params.Username != "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		authorization := ""
+		if strings.HasPrefix(strings.ToLower(challenge), "digest") {
+			parts := digestParts(resp)
+			parts["uri"] = resp.Request.URL.RequestURI()
+			parts["method"] = http.MethodGet
+			parts["username"] = dev.Params.Username
+			parts["password"] = dev.Params.Password
+			authorization = getDigestAuthrization(parts)
+		} else {
+			req := &http.Request{Header: http.Header{}}
+			req.SetBasicAuth(dev.Params.Username, dev.Params.Password)
+			authorization = req.Header.Get("Authorization")
+		}
+		return get(authorization)
+	}
+	return resp, nil
+}
@@ -0,0 +1,57 @@
+package onvif
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+/* JPEG文件头,足以被识别为图片 */
+var jpegImage = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")
+
+func TestSnapshot(t *testing.T) {
+	var broken int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="camera"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if atomic.LoadInt32(&broken) != 0 {
+			w.Write([]byte("<html><body>no image</body></html>"))
+			return
+		}
+		w.Write(jpegImage)
+	}))
+	defer server.Close()
+	_, port := DeviceParams{Ipddr: strings.TrimPrefix(server.URL, "http://")}.address()
+	/* 设备报告的地址被替换为访问设备的地址 */
+	_, dev := newScriptedDevice(t, map[string]string{
+		"GetSnapshotUri": `<trt:GetSnapshotUriResponse><trt:MediaUri><tt:Uri>http://10.0.0.1:` + port + `/snapshot.jpg</tt:Uri></trt:MediaUri></trt:GetSnapshotUriResponse>`,
+		"GetStreamUri":   `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://10.0.0.1:554/main</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`,
+	}, ServiceMedia)
+	dev.Params.Username, dev.Params.Password = "admin", "secret"
+	ctx := context.Background()
+	snapshot, err := dev.Snapshot(ctx, "main")
+	if err != nil || snapshot.Source != SnapshotFromURI || snapshot.ContentType != "image/jpeg" || snapshot.URI != "http://127.0.0.1:"+port+"/snapshot.jpg" {
+		t.Fatalf("snapshot %+v, %v", snapshot, err)
+	}
+
+	atomic.StoreInt32(&broken, 1)
+	if _, err := dev.Snapshot(ctx, "main"); err == nil || !strings.Contains(err.Error(), "not an image") {
+		t.Fatalf("error %v without frame grabber", err)
+	}
+	/* 快照地址返回的不是图片时从RTSP流截取 */
+	var grabbed string
+	dev.Params.FrameGrabber = FrameGrabberFunc(func(ctx context.Context, streamURI, username, password string) ([]byte, error) {
+		grabbed = streamURI + " " + username + " " + password
+		return jpegImage, nil
+	})
+	snapshot, err = dev.Snapshot(ctx, "main")
+	if err != nil || snapshot.Source != SnapshotFromRTSP || snapshot.ContentType != "image/jpeg" || grabbed != "rtsp://127.0.0.1:554/main admin secret" {
+		t.Fatalf("snapshot %+v, %v, grabbed %q", snapshot, err, grabbed)
+	}
+}
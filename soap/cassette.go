@@ -0,0 +1,261 @@
+package soap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Interaction one recorded HTTP exchange with a device
+type Interaction struct {
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	Operation      string      `json:"operation"`
+	RequestBody    string      `json:"requestBody"`
+	StatusCode     int         `json:"statusCode"`
+	ResponseHeader http.Header `json:"responseHeader"`
+	ResponseBody   string      `json:"responseBody"`
+}
+
+// Cassette list of interactions recorded from a real device
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+/* 录制时需要清除的敏感信息 */
+var scrubRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`(<(?:\w+:)?Password[^>]*>)[^<]*(</(?:\w+:)?Password>)`),
+	regexp.MustCompile(`(<(?:\w+:)?Passphrase[^>]*>)[^<]*(</(?:\w+:)?Passphrase>)`),
+	regexp.MustCompile(`(<(?:\w+:)?Nonce[^>]*>)[^<]*(</(?:\w+:)?Nonce>)`),
+	regexp.MustCompile(`(<(?:\w+:)?Created[^>]*>)[^<]*(</(?:\w+:)?Created>)`),
+	regexp.MustCompile(`(<(?:\w+:)?Username[^>]*>)[^<]*(</(?:\w+:)?Username>)`),
+}
+
+// Scrub remove credentials from a SOAP message
+func Scrub(message string) string {
+	for _, re := range scrubRegexps {
+		message = re.ReplaceAllString(message, "${1}REDACTED${2}")
+	}
+	return message
+}
+
+var operationRegexp = regexp.MustCompile(`<(?:\w+:)?Body[^>]*>\s*<(?:\w+:)?(\w+)`)
+
+// Operation return the name of the first element of the SOAP body
+func Operation(message string) string {
+	if match := operationRegexp.FindStringSubmatch(message); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// LoadCassette read a cassette written by Recorder.Save
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cassette := &Cassette{}
+	if err := json.Unmarshal(data, cassette); err != nil {
+		return nil, err
+	}
+	return cassette, nil
+}
+
+// Save write the cassette as JSON
+func (cassette *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, os.FileMode(0644))
+}
+
+// decodeBody return body decoded following the Content-Encoding of header,
+// gzip being the only encoding of SOAP messages
+func decodeBody(header http.Header, body []byte) ([]byte, error) {
+	if !strings.EqualFold(header.Get("Content-Encoding"), "gzip") {
+		return body, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// Recorder http.RoundTripper recording every exchange with secrets scrubbed.
+// Compressed messages are recorded decompressed, the secrets being scrubbed
+// from their text.
+type Recorder struct {
+	// Transport used for the real requests, nil means http.DefaultTransport
+	Transport http.RoundTripper
+
+	mutex    sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder return a recorder sending requests through transport
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport}
+}
+
+// RoundTrip implements http.RoundTripper
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := recorder.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		if requestBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+	}
+	/* 压缩的报文解压后才能清除敏感信息,无法解压时不发送也不录制 */
+	requestText, err := decodeBody(req.Header, requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("recording request: %w", err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+	responseText, err := decodeBody(resp.Header, responseBody)
+	if err != nil {
+		return nil, fmt.Errorf("recording response: %w", err)
+	}
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	header.Del("Www-Authenticate")
+	/* 录制的是解压后的应答 */
+	if strings.EqualFold(header.Get("Content-Encoding"), "gzip") {
+		header.Del("Content-Encoding")
+		header.Del("Content-Length")
+	}
+	recorder.mutex.Lock()
+	recorder.cassette.Interactions = append(recorder.cassette.Interactions, Interaction{
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		Operation:      Operation(string(requestText)),
+		RequestBody:    Scrub(string(requestText)),
+		StatusCode:     resp.StatusCode,
+		ResponseHeader: header,
+		ResponseBody:   Scrub(string(responseText)),
+	})
+	recorder.mutex.Unlock()
+	return resp, nil
+}
+
+// Cassette return a copy of the recorded interactions
+func (recorder *Recorder) Cassette() *Cassette {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	cassette := &Cassette{Interactions: make([]Interaction, len(recorder.cassette.Interactions))}
+	copy(cassette.Interactions, recorder.cassette.Interactions)
+	return cassette
+}
+
+// Save write the recorded interactions to path
+func (recorder *Recorder) Save(path string) error {
+	return recorder.Cassette().Save(path)
+}
+
+// ErrNoInteraction returned by Replayer when no recorded interaction matches
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// Replayer http.RoundTripper answering requests from a cassette, interactions
+// are matched on URL path and SOAP operation and replayed in recorded order
+type Replayer struct {
+	mutex    sync.Mutex
+	cassette *Cassette
+	played   map[string]int
+}
+
+// NewReplayer return a replayer for the cassette
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{cassette: cassette, played: make(map[string]int)}
+}
+
+// RoundTrip implements http.RoundTripper
+func (replayer *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		if requestBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	requestText, err := decodeBody(req.Header, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	operation := Operation(string(requestText))
+	key := req.URL.Path + "#" + operation
+
+	replayer.mutex.Lock()
+	defer replayer.mutex.Unlock()
+	/* 按录制顺序回放,用完后重复最后一条 */
+	var matches []Interaction
+	for _, interaction := range replayer.cassette.Interactions {
+		if interaction.Operation != operation || interaction.Method != req.Method {
+			continue
+		}
+		if path, err := urlPath(interaction.URL); err != nil || path != req.URL.Path {
+			continue
+		}
+		matches = append(matches, interaction)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.URL.Path, operation)
+	}
+	index := replayer.played[key]
+	if index >= len(matches) {
+		index = len(matches) - 1
+	}
+	replayer.played[key]++
+	interaction := matches[index]
+	header := interaction.ResponseHeader.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+		StatusCode:    interaction.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewBufferString(interaction.ResponseBody)),
+		ContentLength: int64(len(interaction.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+func urlPath(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return u.Path, nil
+}
@@ -0,0 +1,134 @@
+package soap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, text string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(text))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const (
+	cassetteRequest  = `<s:Envelope><s:Header><wsse:Security><wsse:UsernameToken><wsse:Username>admin</wsse:Username><wsse:Password>digest</wsse:Password></wsse:UsernameToken></wsse:Security></s:Header><s:Body><tds:GetUsers/></s:Body></s:Envelope>`
+	cassetteResponse = `<s:Envelope><s:Body><tds:GetUsersResponse><tds:User><tt:Username>operator</tt:Username><tt:Password>secret</tt:Password></tds:User></tds:GetUsersResponse></s:Body></s:Envelope>`
+)
+
+func TestRecorderGzip(t *testing.T) {
+	/* 请求和应答均以gzip压缩 */
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped(t, cassetteResponse))
+	}))
+	defer server.Close()
+	recorder := NewRecorder(nil)
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/onvif/device_service", bytes.NewReader(gzipped(t, cassetteRequest)))
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := recorder.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	/* 调用方收到的仍是原始的压缩应答 */
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadAll(reader); string(body) != cassetteResponse {
+		t.Fatalf("response %q", body)
+	}
+	interaction := recorder.Cassette().Interactions[0]
+	for _, text := range []string{interaction.RequestBody, interaction.ResponseBody} {
+		if strings.Contains(text, "admin") || strings.Contains(text, "digest") || strings.Contains(text, "secret") || !strings.Contains(text, "REDACTED") {
+			t.Fatalf("recorded %q", text)
+		}
+	}
+	if interaction.Operation != "GetUsers" || interaction.ResponseHeader.Get("Content-Encoding") != "" {
+		t.Fatalf("operation %q, header %v", interaction.Operation, interaction.ResponseHeader)
+	}
+
+	/* 回放解压后的应答,压缩的请求同样可以匹配 */
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/onvif/device_service", bytes.NewReader(gzipped(t, cassetteRequest)))
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = NewReplayer(recorder.Cassette()).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); !strings.Contains(string(body), "<tds:GetUsersResponse>") {
+		t.Fatalf("replayed %q", body)
+	}
+}
+
+func TestRecorderInvalidGzip(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(cassetteRequest))
+	req.Header.Set("Content-Encoding", "gzip")
+	if _, err := NewRecorder(nil).RoundTrip(req); err == nil || requests != 0 {
+		t.Fatalf("error %v, %d requests sent", err, requests)
+	}
+}
+
+func TestCassetteReplay(t *testing.T) {
+	/* 设备依次应答不同的内容 */
+	answers := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		answers++
+		w.Write([]byte(`<s:Envelope><s:Body><tds:GetUsersResponse><tds:User><tt:UserLevel>level` + string(rune('0'+answers)) + `</tt:UserLevel></tds:User></tds:GetUsersResponse></s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewRecorder(nil)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(server.URL+"/onvif/device_service", "application/soap+xml", strings.NewReader(cassetteRequest))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	path := t.TempDir() + "/cassette.json"
+	if err := client.Transport.(*Recorder).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	cassette, err := LoadCassette(path)
+	if err != nil || len(cassette.Interactions) != 2 {
+		t.Fatalf("cassette %+v, %v", cassette, err)
+	}
+	server.Close()
+
+	/* 离线按录制顺序回放,用完后重复最后一条 */
+	client = &http.Client{Transport: NewReplayer(cassette)}
+	for _, level := range []string{"level1", "level2", "level2"} {
+		resp, err := client.Post("http://10.1.1.200/onvif/device_service", "application/soap+xml", strings.NewReader(cassetteRequest))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), level) {
+			t.Fatalf("replayed %q, want %s", body, level)
+		}
+	}
+	/* 路径或操作不同的请求没有匹配 */
+	if _, err := client.Post("http://10.1.1.200/onvif/media", "application/soap+xml", strings.NewReader(cassetteRequest)); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("error %v for another path", err)
+	}
+	other := strings.Replace(cassetteRequest, "GetUsers", "GetScopes", 1)
+	if _, err := client.Post("http://10.1.1.200/onvif/device_service", "application/soap+xml", strings.NewReader(other)); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("error %v for another operation", err)
+	}
+}
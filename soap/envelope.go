@@ -0,0 +1,319 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/* 构建请求时复用的缓冲区 */
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// GetBuffer return an empty buffer from the pool
+func GetBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// PutBuffer give a buffer back to the pool, buf must not be used afterwards
+func PutBuffer(buf *bytes.Buffer) {
+	/* 过大的缓冲区不回收,避免一次大请求长期占用内存 */
+	if buf.Cap() > 1<<20 {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+const (
+	envelopeStart = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://www.w3.org/2005/08/addressing" xmlns:soap-enc="http://www.w3.org/2003/05/soap-encoding"`
+	bodyStart = `<s:Body xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema">`
+)
+
+// WriteEnvelope write a complete SOAP envelope to buf in a single pass.
+// namespaces are declared on the Envelope in sorted order, headers and body
+// must be marshaled XML fragments.
+func WriteEnvelope(buf *bytes.Buffer, namespaces map[string]string, headers [][]byte, body []byte) {
+	buf.WriteString(envelopeStart)
+	for _, key := range sortedKeys(namespaces) {
+		buf.WriteString(` xmlns:`)
+		buf.WriteString(key)
+		buf.WriteString(`="`)
+		xml.EscapeText(buf, []byte(namespaces[key]))
+		buf.WriteString(`"`)
+	}
+	buf.WriteString(`><s:Header>`)
+	for _, header := range headers {
+		buf.Write(header)
+	}
+	buf.WriteString(`</s:Header>`)
+	buf.WriteString(bodyStart)
+	buf.Write(body)
+	buf.WriteString(`</s:Body></s:Envelope>`)
+}
+
+// MarshalTo append the XML encoding of v to buf
+func MarshalTo(buf *bytes.Buffer, v interface{}) error {
+	return xml.NewEncoder(buf).Encode(v)
+}
+
+// BuildEnvelope marshal method, the optional WS-Security header and the extra
+// header blocks into a pooled buffer. The caller owns the returned buffer and
+// should release it with PutBuffer once the message has been sent.
+func BuildEnvelope(namespaces map[string]string, method interface{}, username, password string, extra ...[]byte) (*bytes.Buffer, error) {
+	var security *Security
+	if username != "" && password != "" {
+		auth, err := NewSecurity(username, password)
+		if err != nil {
+			return nil, err
+		}
+		security = &auth
+	}
+	return BuildEnvelopeSecurity(namespaces, method, security, extra...)
+}
+
+// BuildEnvelopeSecurity marshal method like BuildEnvelope with the given
+// WS-Security header, none when security is nil
+func BuildEnvelopeSecurity(namespaces map[string]string, method interface{}, security *Security, extra ...[]byte) (*bytes.Buffer, error) {
+	body := GetBuffer()
+	defer PutBuffer(body)
+	if err := MarshalTo(body, method); err != nil {
+		return nil, err
+	}
+	return BuildEnvelopeRaw(namespaces, body.Bytes(), security, extra...), nil
+}
+
+// BuildEnvelopeRaw build the envelope of the marshaled body like
+// BuildEnvelopeSecurity
+func BuildEnvelopeRaw(namespaces map[string]string, body []byte, security *Security, extra ...[]byte) *bytes.Buffer {
+	var headers [][]byte
+	if security != nil {
+		header := GetBuffer()
+		defer PutBuffer(header)
+		/* Security只含字符串字段,编码不会失败 */
+		MarshalTo(header, security)
+		headers = append(headers, header.Bytes())
+	}
+	headers = append(headers, extra...)
+	buf := GetBuffer()
+	WriteEnvelope(buf, namespaces, headers, body)
+	return buf
+}
+
+// BufferRequest request body over a pooled buffer. The transport may read a
+// body after the response arrived and, through GetBody, read it again for a
+// redirect or a retry, so the buffer goes back to the pool only once every
+// body handed out is closed and Release was called by the owner, e.g. when
+// the response body is closed.
+type BufferRequest struct {
+	mutex sync.Mutex
+	buf   *bytes.Buffer
+	refs  int
+}
+
+// NewBufferRequest return the request body of buf, holding the reference of
+// the owner released by Release
+func NewBufferRequest(buf *bytes.Buffer) *BufferRequest {
+	return &BufferRequest{buf: buf, refs: 1}
+}
+
+// Body return a new reader of the buffer, released when closed
+func (request *BufferRequest) Body() io.ReadCloser {
+	request.mutex.Lock()
+	defer request.mutex.Unlock()
+	request.refs++
+	return &bufferBody{Reader: bytes.NewReader(request.buf.Bytes()), request: request}
+}
+
+// GetBody is Body for http.Request.GetBody
+func (request *BufferRequest) GetBody() (io.ReadCloser, error) {
+	return request.Body(), nil
+}
+
+// Release drop the reference of the owner
+func (request *BufferRequest) Release() {
+	request.release()
+}
+
+func (request *BufferRequest) release() {
+	request.mutex.Lock()
+	defer request.mutex.Unlock()
+	if request.refs--; request.refs == 0 {
+		PutBuffer(request.buf)
+		request.buf = nil
+	}
+}
+
+// bufferBody reader of a BufferRequest
+type bufferBody struct {
+	*bytes.Reader
+	once    sync.Once
+	request *BufferRequest
+}
+
+func (body *bufferBody) Close() error {
+	body.once.Do(body.request.release)
+	return nil
+}
+
+// Namespaces of the SOAP 1.1 and SOAP 1.2 envelopes
+const (
+	NamespaceSOAP11 = "http://schemas.xmlsoap.org/soap/envelope/"
+	NamespaceSOAP12 = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// ErrNoBody returned by Body when the message has no SOAP body
+var ErrNoBody = errors.New("message has no soap body")
+
+// Body return the content of the body of a SOAP message. The body is found
+// by the namespace of its element, whatever the prefix the device binds to
+// it: env:, s:, soap:, SOAP-ENV: or a default namespace. A prefix the message
+// does not declare is accepted too, as some devices omit the declaration.
+func Body(message []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(message))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, ErrNoBody
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			/* Body只作为Envelope的直接子元素查找 */
+			if depth == 2 && token.Name.Local == "Body" && envelopeNamespace(token.Name.Space) {
+				var body struct {
+					Content []byte `xml:",innerxml"`
+				}
+				if err := decoder.DecodeElement(&body, &token); err != nil {
+					return nil, err
+				}
+				return body.Content, nil
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// envelopeNamespace report whether space is a SOAP envelope namespace, or a
+// prefix left undeclared by the message
+func envelopeNamespace(space string) bool {
+	return space == NamespaceSOAP12 || space == NamespaceSOAP11 || !strings.Contains(space, "/")
+}
+
+// Elements return the elements of the message whose local name is local,
+// whatever their namespace, outermost first. Each element is encoded on its
+// own with the namespaces of its content declared, so it can be sent in
+// another message, as is or renamed by ElementsAs.
+func Elements(message []byte, local string) ([][]byte, error) {
+	return ElementsAs(message, local, xml.Name{})
+}
+
+// ElementsAs return the elements of Elements renamed as, e.g. the OSDs of a
+// GetOSDs response renamed as the OSD of a SetOSD request; a zero name keeps
+// the names. The prefixes in scope are declared again on each element, QName
+// values such as Type="tt:CellMotionDetector" keep their namespace.
+func ElementsAs(message []byte, local string, as xml.Name) ([][]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(message))
+	var elements [][]byte
+	var buf *bytes.Buffer
+	var encoder *xml.Encoder
+	depth := 0
+	/* 各层元素声明的前缀 */
+	var scopes []map[string]string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return elements, nil
+		}
+		if err != nil {
+			return elements, err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			prefixes := make(map[string]string)
+			for _, attr := range element.Attr {
+				if attr.Name.Space == "xmlns" {
+					prefixes[attr.Name.Local] = attr.Value
+				}
+			}
+			scopes = append(scopes, prefixes)
+			if encoder == nil && element.Name.Local != local {
+				continue
+			}
+			if encoder == nil {
+				buf = new(bytes.Buffer)
+				encoder = xml.NewEncoder(buf)
+				if as.Local != "" {
+					element.Name = as
+				}
+				/* 根元素声明外层所有仍有效的前缀 */
+				prefixes = make(map[string]string)
+				for _, scope := range scopes {
+					for prefix, space := range scope {
+						prefixes[prefix] = space
+					}
+				}
+			}
+			depth++
+			/* 元素名的命名空间由编码器重新声明,前缀原样声明 */
+			attrs := element.Attr[:0:0]
+			for _, attr := range element.Attr {
+				if attr.Name.Space != "xmlns" && !(attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					attrs = append(attrs, attr)
+				}
+			}
+			for _, prefix := range sortedKeys(prefixes) {
+				attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: prefixes[prefix]})
+			}
+			element.Attr = attrs
+			token = element
+		case xml.EndElement:
+			scopes = scopes[:len(scopes)-1]
+			if encoder == nil {
+				continue
+			}
+			depth--
+			if depth == 0 && as.Local != "" {
+				element.Name = as
+			}
+			token = element
+		case xml.CharData, xml.Comment:
+			if encoder == nil {
+				continue
+			}
+		default:
+			continue
+		}
+		if err := encoder.EncodeToken(token); err != nil {
+			return elements, err
+		}
+		if depth == 0 {
+			if err := encoder.Flush(); err != nil {
+				return elements, err
+			}
+			elements = append(elements, buf.Bytes())
+			encoder = nil
+		}
+	}
+}
+
+// sortedKeys keys of m in order, namespaces are declared in a stable order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,140 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"testing"
+)
+
+var benchNamespaces = map[string]string{
+	"tt":   "http://www.onvif.org/ver10/schema",
+	"tptz": "http://www.onvif.org/ver20/ptz/wsdl",
+}
+
+type benchVector struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+}
+
+/* 连续移动请求,模拟高频ptz控制 */
+type benchContinuousMove struct {
+	XMLName      xml.Name    `xml:"tptz:ContinuousMove"`
+	ProfileToken string      `xml:"tptz:ProfileToken"`
+	PanTilt      benchVector `xml:"tptz:Velocity>tt:PanTilt"`
+}
+
+var benchMove = benchContinuousMove{ProfileToken: "Profile_1", PanTilt: benchVector{X: 0.5, Y: -0.5}}
+
+func TestBufferRequestGetBody(t *testing.T) {
+	buf := GetBuffer()
+	buf.WriteString("<Envelope/>")
+	request := NewBufferRequest(buf)
+	first := request.Body()
+	first.Close()
+	/* 传输层关闭首个请求体后,重试/重定向仍需读取同样内容 */
+	again, _ := request.GetBody()
+	first.Close()
+	content, _ := ioutil.ReadAll(again)
+	if string(content) != "<Envelope/>" {
+		t.Fatalf("body read again = %q", content)
+	}
+	again.Close()
+	if request.buf == nil {
+		t.Fatal("buffer released before the owner released it")
+	}
+	request.Release()
+	if request.buf != nil {
+		t.Fatal("buffer not released")
+	}
+}
+
+func BenchmarkBuildEnvelope(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, err := BuildEnvelope(benchNamespaces, benchMove, "admin", "password")
+		if err != nil {
+			b.Fatal(err)
+		}
+		PutBuffer(buf)
+	}
+}
+
+func BenchmarkBuildEnvelopeRaw(b *testing.B) {
+	body, _ := xml.Marshal(benchMove)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PutBuffer(BuildEnvelopeRaw(benchNamespaces, body, nil))
+	}
+}
+
+func BenchmarkBufferRequest(b *testing.B) {
+	content := bytes.Repeat([]byte("<a/>"), 256)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := GetBuffer()
+		buf.Write(content)
+		request := NewBufferRequest(buf)
+		body := request.Body()
+		ioutil.ReadAll(body)
+		body.Close()
+		request.Release()
+	}
+}
+
+func TestElementsAsPrefixes(t *testing.T) {
+	message := `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tt="http://www.onvif.org/ver10/schema"><s:Body>
+<tan:GetRulesResponse xmlns:tan="http://www.onvif.org/ver20/analytics/wsdl"><tan:Rule Name="r1" Type="tt:CellMotionDetector"><tt:Parameters xmlns:axt="http://www.axis.com/2014/axt"><tt:SimpleItem Name="Kind" Value="axt:Human"/></tt:Parameters></tan:Rule></tan:GetRulesResponse>
+</s:Body></s:Envelope>`
+	elements, err := ElementsAs([]byte(message), "Rule", xml.Name{Space: "http://www.onvif.org/ver20/analytics/wsdl", Local: "Rule"})
+	if err != nil || len(elements) != 1 {
+		t.Fatalf("%d elements, %v", len(elements), err)
+	}
+	/* 取出的元素单独解析时,属性值中的前缀仍须有绑定 */
+	decoder := xml.NewDecoder(bytes.NewReader(elements[0]))
+	bound := make(map[string]string)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			for _, attr := range start.Attr {
+				if attr.Name.Space == "xmlns" {
+					bound[attr.Name.Local] = attr.Value
+				}
+			}
+		}
+	}
+	if bound["tt"] != "http://www.onvif.org/ver10/schema" || bound["axt"] != "http://www.axis.com/2014/axt" {
+		t.Fatalf("prefixes %v in %s", bound, elements[0])
+	}
+}
+
+func TestBody(t *testing.T) {
+	for _, message := range []string{
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><r/></s:Body></s:Envelope>`,
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Header/><soap:Body><r/></soap:Body></soap:Envelope>`,
+		`<SOAP-ENV:Envelope xmlns:SOAP-ENV="http://www.w3.org/2003/05/soap-envelope"><SOAP-ENV:Body><r/></SOAP-ENV:Body></SOAP-ENV:Envelope>`,
+		`<Envelope xmlns="http://www.w3.org/2003/05/soap-envelope"><Body><r/></Body></Envelope>`,
+		/* 未声明的前缀同样接受 */
+		`<env:Envelope><env:Body><r/></env:Body></env:Envelope>`,
+		/* 头部中同名的元素不是Body */
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Header><x:Body xmlns:x="urn:x"><h/></x:Body></s:Header><s:Body><r/></s:Body></s:Envelope>`,
+	} {
+		body, err := Body([]byte(message))
+		if err != nil || string(body) != "<r/>" {
+			t.Fatalf("body %q, %v of %s", body, err, message)
+		}
+	}
+	for _, message := range []string{
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Header/></s:Envelope>`,
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><x:Body xmlns:x="http://example.com/x"><r/></x:Body></s:Envelope>`,
+		`not xml`,
+	} {
+		if body, err := Body([]byte(message)); err != ErrNoBody {
+			t.Fatalf("body %q, %v of %s", body, err, message)
+		}
+	}
+}
@@ -176,17 +176,21 @@ func buildSoapRoot() *etree.Document {
 	return doc
 }
 
-//AddWSSecurity Header for soapMessage
-func (msg *SoapMessage) AddWSSecurity(username, password string) {
+//AddWSSecurity Header for soapMessage, the message is left unchanged on error
+func (msg *SoapMessage) AddWSSecurity(username, password string) error {
 	/* Getting an WS-Security struct representation */
-	auth := NewSecurity(username, password)
+	auth, err := NewSecurity(username, password)
+	if err != nil {
+		return err
+	}
 	/* Adding WS-Security namespaces to root element of SOAP message */
 	soapReq, err := xml.Marshal(auth)
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
 	/*Adding WS-Security struct to SOAP header*/
 	msg.AddStringHeaderContent(string(soapReq))
+	return nil
 }
 
 //AddAction Header handling for soapMessage
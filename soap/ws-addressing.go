@@ -0,0 +1,94 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+)
+
+const addressingNamespace = "http://www.w3.org/2005/08/addressing"
+
+// AddressingHeaders return the wsa:Action and wsa:To header blocks of a
+// message sent to an endpoint reference, empty values are left out
+func AddressingHeaders(action, to string) [][]byte {
+	var headers [][]byte
+	if action != "" {
+		headers = append(headers, addressingHeader("Action", action))
+	}
+	if to != "" {
+		headers = append(headers, addressingHeader("To", to))
+	}
+	return headers
+}
+
+func addressingHeader(name, value string) []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString(`<a:` + name + ` s:mustUnderstand="1">`)
+	xml.EscapeText(&buf, []byte(value))
+	buf.WriteString(`</a:` + name + `>`)
+	return buf.Bytes()
+}
+
+// ReferenceParameterHeader return the header block echoing one reference
+// parameter of an endpoint reference, marked with wsa:IsReferenceParameter.
+// name.Space is the namespace of the element when it was resolved by the
+// decoder, otherwise the original prefix which is then written unchanged.
+// inner is copied verbatim.
+func ReferenceParameterHeader(name xml.Name, attrs []xml.Attr, inner string) []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString(`<`)
+	tag := name.Local
+	switch {
+	case isNamespaceURI(name.Space):
+		tag = "rp:" + name.Local
+		buf.WriteString(tag)
+		writeAttr(&buf, "xmlns:rp", name.Space)
+	case name.Space != "":
+		tag = name.Space + ":" + name.Local
+		buf.WriteString(tag)
+	default:
+		buf.WriteString(tag)
+	}
+	/* 保留原有属性和命名空间声明,带命名空间的属性使用新的前缀 */
+	for i, attr := range attrs {
+		switch {
+		case attr.Name.Space == "xmlns":
+			if attr.Name.Local == "rp" {
+				continue
+			}
+			writeAttr(&buf, "xmlns:"+attr.Name.Local, attr.Value)
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			writeAttr(&buf, "xmlns", attr.Value)
+		case attr.Name.Space == addressingNamespace && attr.Name.Local == "IsReferenceParameter":
+		case isNamespaceURI(attr.Name.Space):
+			prefix := "rp" + strconv.Itoa(i)
+			writeAttr(&buf, "xmlns:"+prefix, attr.Name.Space)
+			writeAttr(&buf, prefix+":"+attr.Name.Local, attr.Value)
+		case attr.Name.Space != "":
+			writeAttr(&buf, attr.Name.Space+":"+attr.Name.Local, attr.Value)
+		default:
+			writeAttr(&buf, attr.Name.Local, attr.Value)
+		}
+	}
+	writeAttr(&buf, "a:IsReferenceParameter", "true")
+	buf.WriteString(`>`)
+	buf.WriteString(inner)
+	buf.WriteString(`</` + tag + `>`)
+	return buf.Bytes()
+}
+
+func writeAttr(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(` ` + name + `="`)
+	xml.EscapeText(buf, []byte(value))
+	buf.WriteString(`"`)
+}
+
+/* 解码器未能解析前缀时Space为原前缀 */
+func isNamespaceURI(space string) bool {
+	for _, c := range space {
+		if c == ':' || c == '/' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+/* 与types/events中的引用参数相同的解码方式 */
+type referenceParameter struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   string     `xml:",innerxml"`
+}
+
+type referenceParameters struct {
+	Parameters []referenceParameter `xml:",any"`
+}
+
+func TestReferenceParameterHeader(t *testing.T) {
+	reference := `<wsa:ReferenceParameters xmlns:wsa="http://www.w3.org/2005/08/addressing" xmlns:dom0="http://www.example.com/subscription" xmlns:vnd="http://www.example.com/vendor">` +
+		`<dom0:SubscriptionId vnd:kind="pull &amp; push">7</dom0:SubscriptionId><Plain><vnd:Nested>1</vnd:Nested></Plain></wsa:ReferenceParameters>`
+	parameters := referenceParameters{}
+	if err := xml.Unmarshal([]byte(reference), &parameters); err != nil || len(parameters.Parameters) != 2 {
+		t.Fatalf("parameters %+v, %v", parameters, err)
+	}
+	headers := AddressingHeaders("http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/RenewRequest", "http://10.1.1.200/subscription?id=1&x=2")
+	for _, parameter := range parameters.Parameters {
+		headers = append(headers, ReferenceParameterHeader(parameter.XMLName, parameter.Attrs, parameter.Inner))
+	}
+	buf := &bytes.Buffer{}
+	WriteEnvelope(buf, map[string]string{"vnd": "http://www.example.com/vendor"}, headers, []byte(`<Renew/>`))
+	/* 报文格式正确,参数保留命名空间与属性并标记为引用参数 */
+	envelope := struct {
+		Header struct {
+			To         string               `xml:"http://www.w3.org/2005/08/addressing To"`
+			Parameters []referenceParameter `xml:",any"`
+		}
+	}{}
+	if err := xml.Unmarshal(buf.Bytes(), &envelope); err != nil {
+		t.Fatalf("%v: %s", err, buf)
+	}
+	if envelope.Header.To != "http://10.1.1.200/subscription?id=1&x=2" {
+		t.Fatalf("to %q", envelope.Header.To)
+	}
+	var id referenceParameter
+	for _, parameter := range envelope.Header.Parameters {
+		if parameter.XMLName.Local == "SubscriptionId" {
+			id = parameter
+		}
+	}
+	if id.XMLName.Space != "http://www.example.com/subscription" || id.Inner != "7" {
+		t.Fatalf("subscription id %+v in %s", id, buf)
+	}
+	attrs := map[string]string{}
+	for _, attr := range id.Attrs {
+		attrs[attr.Name.Space+" "+attr.Name.Local] = attr.Value
+	}
+	if attrs["http://www.example.com/vendor kind"] != "pull & push" || attrs[addressingNamespace+" IsReferenceParameter"] != "true" {
+		t.Fatalf("attributes %v", attrs)
+	}
+	if !strings.Contains(buf.String(), `<Plain a:IsReferenceParameter="true"><vnd:Nested>1</vnd:Nested></Plain>`) {
+		t.Fatalf("plain parameter not echoed: %s", buf)
+	}
+}
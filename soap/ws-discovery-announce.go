@@ -0,0 +1,151 @@
+package soap
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/gofrs/uuid"
+	"golang.org/x/net/ipv4"
+)
+
+// WS-Discovery announcement actions
+const (
+	ActionHello = "http://schemas.xmlsoap.org/ws/2005/04/discovery/Hello"
+	ActionBye   = "http://schemas.xmlsoap.org/ws/2005/04/discovery/Bye"
+)
+
+// discoveryAddressing WS-Addressing namespace of WS-Discovery 2005/04, bound
+// to its own prefix since the envelope binds a to WS-Addressing 1.0
+const discoveryAddressing = "http://schemas.xmlsoap.org/ws/2004/08/addressing"
+
+// Announcer send the WS-Discovery Hello and Bye announcements of an emulated
+// device, so clients listening for announcements see it come and go
+type Announcer struct {
+	// Address endpoint reference of the device, stable across restarts
+	Address string
+	// Types e.g. dn:NetworkVideoTransmitter
+	Types []string
+	// Scopes e.g. onvif://www.onvif.org/name/emulator
+	Scopes []string
+	// XAddrs device service URLs
+	XAddrs          []string
+	MetadataVersion int
+	// Interface name of the multicast interface, empty for the system default
+	Interface string
+
+	mutex         sync.Mutex
+	instanceID    int64
+	messageNumber int
+}
+
+// NewAnnouncer return an announcer of a NetworkVideoTransmitter with a random
+// endpoint reference
+func NewAnnouncer(xaddrs ...string) *Announcer {
+	return &Announcer{
+		Address:         "urn:uuid:" + uuid.Must(uuid.NewV4()).String(),
+		Types:           []string{"dn:NetworkVideoTransmitter"},
+		XAddrs:          xaddrs,
+		MetadataVersion: 1,
+	}
+}
+
+// Hello announce the device on the network
+func (announcer *Announcer) Hello() error {
+	return sendMulticast(announcer.message(ActionHello).String(), announcer.Interface)
+}
+
+// Bye announce the device leaves the network
+func (announcer *Announcer) Bye() error {
+	return sendMulticast(announcer.message(ActionBye).String(), announcer.Interface)
+}
+
+// Run send Hello, wait for ctx to be done and send Bye
+func (announcer *Announcer) Run(ctx context.Context) error {
+	if err := announcer.Hello(); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return announcer.Bye()
+}
+
+func (announcer *Announcer) message(action string) SoapMessage {
+	/* AppSequence让客户端识别重启和乱序的通告 */
+	announcer.mutex.Lock()
+	if announcer.instanceID == 0 {
+		announcer.instanceID = time.Now().Unix()
+	}
+	announcer.messageNumber++
+	instanceID, messageNumber := announcer.instanceID, announcer.messageNumber
+	announcer.mutex.Unlock()
+
+	message := NewEmptySOAP()
+	message.AddRootNamespaces(map[string]string{
+		"wsa": discoveryAddressing,
+		"d":   "http://schemas.xmlsoap.org/ws/2005/04/discovery",
+	})
+	var header []*etree.Element
+	header = append(header, textElement("wsa:Action", action))
+	header = append(header, textElement("wsa:MessageID", "uuid:"+uuid.Must(uuid.NewV4()).String()))
+	header = append(header, textElement("wsa:To", "urn:schemas-xmlsoap-org:ws:2005:04:discovery"))
+	sequence := etree.NewElement("d:AppSequence")
+	sequence.CreateAttr("InstanceId", strconv.FormatInt(instanceID, 10))
+	sequence.CreateAttr("MessageNumber", strconv.Itoa(messageNumber))
+	header = append(header, sequence)
+	message.AddHeaderContents(header)
+
+	tag := "d:Hello"
+	if action == ActionBye {
+		tag = "d:Bye"
+	}
+	body := etree.NewElement(tag)
+	body.CreateElement("wsa:EndpointReference").AddChild(textElement("wsa:Address", announcer.Address))
+	if len(announcer.Types) != 0 {
+		types := textElement("d:Types", strings.Join(announcer.Types, " "))
+		types.CreateAttr("xmlns:dn", "http://www.onvif.org/ver10/network/wsdl")
+		body.AddChild(types)
+	}
+	if len(announcer.Scopes) != 0 {
+		body.AddChild(textElement("d:Scopes", strings.Join(announcer.Scopes, " ")))
+	}
+	if len(announcer.XAddrs) != 0 {
+		body.AddChild(textElement("d:XAddrs", strings.Join(announcer.XAddrs, " ")))
+	}
+	body.AddChild(textElement("d:MetadataVersion", strconv.Itoa(announcer.MetadataVersion)))
+	message.AddBodyContent(body)
+	return message
+}
+
+func textElement(tag, text string) *etree.Element {
+	element := etree.NewElement(tag)
+	element.SetText(text)
+	return element
+}
+
+// sendMulticast send msg to the WS-Discovery multicast group without waiting
+// for answers
+func sendMulticast(msg string, interfaceName string) error {
+	c, err := net.ListenPacket("udp4", "0.0.0.0:0")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	p := ipv4.NewPacketConn(c)
+	if interfaceName != "" {
+		iface, err := net.InterfaceByName(interfaceName)
+		if err != nil {
+			return err
+		}
+		if err := p.SetMulticastInterface(iface); err != nil {
+			return err
+		}
+	}
+	p.SetMulticastTTL(2)
+	dst := &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 3702}
+	_, err = p.WriteTo([]byte(msg), nil, dst)
+	return err
+}
@@ -0,0 +1,58 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+type announcedReference struct {
+	Address string `xml:"http://schemas.xmlsoap.org/ws/2004/08/addressing Address"`
+}
+
+type announcedDevice struct {
+	XMLName           xml.Name
+	EndpointReference announcedReference `xml:"http://schemas.xmlsoap.org/ws/2004/08/addressing EndpointReference"`
+	Types             string             `xml:"http://schemas.xmlsoap.org/ws/2005/04/discovery Types"`
+	Scopes            string             `xml:"http://schemas.xmlsoap.org/ws/2005/04/discovery Scopes"`
+	XAddrs            string             `xml:"http://schemas.xmlsoap.org/ws/2005/04/discovery XAddrs"`
+}
+
+type announcement struct {
+	Header struct {
+		Action      string `xml:"http://schemas.xmlsoap.org/ws/2004/08/addressing Action"`
+		AppSequence struct {
+			InstanceID    int64 `xml:"InstanceId,attr"`
+			MessageNumber int   `xml:"MessageNumber,attr"`
+		} `xml:"http://schemas.xmlsoap.org/ws/2005/04/discovery AppSequence"`
+	} `xml:"http://www.w3.org/2003/05/soap-envelope Header"`
+	Body struct {
+		Device announcedDevice `xml:",any"`
+	} `xml:"http://www.w3.org/2003/05/soap-envelope Body"`
+}
+
+func TestAnnouncerMessages(t *testing.T) {
+	announcer := NewAnnouncer("http://10.1.1.200/onvif/device_service")
+	announcer.Scopes = []string{"onvif://www.onvif.org/name/emulator", "onvif://www.onvif.org/location/lab"}
+	var hello, bye announcement
+	if err := xml.Unmarshal([]byte(announcer.message(ActionHello).String()), &hello); err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal([]byte(announcer.message(ActionBye).String()), &bye); err != nil {
+		t.Fatal(err)
+	}
+	/* 地址元素须在WS-Discovery使用的2004/08寻址命名空间中 */
+	device := hello.Body.Device
+	if hello.Header.Action != ActionHello || device.XMLName.Local != "Hello" || device.EndpointReference.Address != announcer.Address ||
+		device.Types != "dn:NetworkVideoTransmitter" || device.Scopes != "onvif://www.onvif.org/name/emulator onvif://www.onvif.org/location/lab" ||
+		device.XAddrs != "http://10.1.1.200/onvif/device_service" {
+		t.Fatalf("hello %+v", hello)
+	}
+	if bye.Header.Action != ActionBye || bye.Body.Device.XMLName.Local != "Bye" || bye.Body.Device.EndpointReference.Address != announcer.Address {
+		t.Fatalf("bye %+v", bye)
+	}
+	/* 同一实例的消息编号递增 */
+	if hello.Header.AppSequence.InstanceID == 0 || bye.Header.AppSequence.InstanceID != hello.Header.AppSequence.InstanceID ||
+		hello.Header.AppSequence.MessageNumber != 1 || bye.Header.AppSequence.MessageNumber != 2 {
+		t.Fatalf("sequences %+v, %+v", hello.Header.AppSequence, bye.Header.AppSequence)
+	}
+}
@@ -1,8 +1,9 @@
 package soap
 
 import (
+	"context"
+	"encoding/xml"
 	"errors"
-	"fmt"
 	"log"
 	"net"
 	"os"
@@ -68,6 +69,7 @@ func buildProbeMessage(uuidV4 string, scopes, types []string, nmsp map[string]st
 
 	if len(scopes) != 0 {
 		scopesTag := etree.NewElement("d:Scopes")
+		scopesTag.CreateAttr("xmlns:d", "http://schemas.xmlsoap.org/ws/2005/04/discovery")
 		var scopesString string
 		for _, j := range scopes {
 			scopesString += j
@@ -83,10 +85,38 @@ func buildProbeMessage(uuidV4 string, scopes, types []string, nmsp map[string]st
 	return probeMessage
 }
 
-//SendProbe to device
+// ProbeWindow time the answers to a probe are collected for
+const ProbeWindow = time.Second
+
+//SendProbe to device, the errors are logged, see SendProbeContext
 func SendProbe(interfaceName string, scopes, types []string, namespaces map[string]string) []string {
+	answers, err := SendProbeContext(context.Background(), interfaceName, scopes, types, namespaces)
+	if err != nil {
+		log.Println(err)
+	}
+	return answers
+}
+
+// SendProbeContext send a probe on the network of interfaceName and collect
+// the answers for ProbeWindow. When ctx ends first the answers received so far
+// are returned with the error of ctx.
+func SendProbeContext(ctx context.Context, interfaceName string, scopes, types []string, namespaces map[string]string) ([]string, error) {
+	var result []string
+	err := ListenProbe(ctx, interfaceName, scopes, types, namespaces, ProbeWindow, func(answer []byte) {
+		result = append(result, string(answer))
+	})
+	return result, err
+}
+
+// ListenProbe send a probe on the network of interfaceName and call answer
+// with every answer as soon as it is received, for window, ProbeWindow when
+// 0. When ctx ends first the error of ctx is returned.
+func ListenProbe(ctx context.Context, interfaceName string, scopes, types []string, namespaces map[string]string, window time.Duration, answer func([]byte)) error {
 	// Creating UUID Version 4
-	uuidV4 := uuid.Must(uuid.NewV4())
+	uuidV4, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
 	probeSOAP := buildProbeMessage(uuidV4.String(), scopes, types, namespaces)
 	//probeSOAP = `<?xml version="1.0" encoding="UTF-8"?>
 	//<Envelope xmlns="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing">
@@ -102,55 +132,124 @@ func SendProbe(interfaceName string, scopes, types []string, namespaces map[stri
 	//</Body>
 	//</Envelope>`
 
-	return sendUDPMulticast(probeSOAP.String(), interfaceName)
-
+	if window <= 0 {
+		window = ProbeWindow
+	}
+	return sendUDPMulticast(ctx, probeSOAP.String(), interfaceName, window, answer)
 }
 
-func sendUDPMulticast(msg string, interfaceName string) []string {
-	var result []string
+func sendUDPMulticast(ctx context.Context, msg string, interfaceName string, window time.Duration, answer func([]byte)) error {
 	data := []byte(msg)
 	iface, err := net.InterfaceByName(interfaceName)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 	group := net.IPv4(239, 255, 255, 250)
 
-	c, err := net.ListenPacket("udp4", "0.0.0.0:1024")
+	/* 使用临时端口,多个网卡可同时探测 */
+	c, err := net.ListenPacket("udp4", "0.0.0.0:0")
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 	defer c.Close()
 
 	p := ipv4.NewPacketConn(c)
 	if err := p.JoinGroup(iface, &net.UDPAddr{IP: group}); err != nil {
-		fmt.Println(err)
+		return err
 	}
 
 	dst := &net.UDPAddr{IP: group, Port: 3702}
-	for _, ifi := range []*net.Interface{iface} {
-		if err := p.SetMulticastInterface(ifi); err != nil {
-			fmt.Println(err)
-		}
-		p.SetMulticastTTL(2)
-		if _, err := p.WriteTo(data, nil, dst); err != nil {
-			fmt.Println(err)
-		}
+	if err := p.SetMulticastInterface(iface); err != nil {
+		return err
+	}
+	p.SetMulticastTTL(2)
+	if _, err := p.WriteTo(data, nil, dst); err != nil {
+		return err
 	}
 
-	if err := p.SetReadDeadline(time.Now().Add(time.Second * 1)); err != nil {
-		log.Fatal(err)
+	deadline, cut := time.Now().Add(window), false
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline, cut = ctxDeadline, true
+	}
+	if err := p.SetReadDeadline(deadline); err != nil {
+		return err
 	}
+	/* ctx被取消时立即结束接收 */
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
 
 	for {
 		b := make([]byte, bufSize)
 		n, _, _, err := p.ReadFrom(b)
 		if err != nil {
 			if !errors.Is(err, os.ErrDeadlineExceeded) {
-				fmt.Println(err)
+				return err
 			}
 			break
 		}
-		result = append(result, string(b[0:n]))
+		answer(b[0:n])
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cut {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// ProbeMatch one device answer to a WS-Discovery probe
+type ProbeMatch struct {
+	// Address endpoint reference of the device, usually urn:uuid:...
+	Address         string
+	Types           []string
+	Scopes          []string
+	XAddrs          []string
+	MetadataVersion int
+}
+
+type probeMatchesEnvelope struct {
+	Body struct {
+		ProbeMatches struct {
+			ProbeMatch []struct {
+				EndpointReference struct {
+					Address string `xml:"Address"`
+				} `xml:"EndpointReference"`
+				Types           string `xml:"Types"`
+				Scopes          string `xml:"Scopes"`
+				XAddrs          string `xml:"XAddrs"`
+				MetadataVersion int    `xml:"MetadataVersion"`
+			} `xml:"ProbeMatch"`
+		} `xml:"ProbeMatches"`
+	} `xml:"Body"`
+}
+
+// ParseProbeMatches decode a ProbeMatches message, the message is checked
+// against DefaultLimits first since any host on the network can answer
+func ParseProbeMatches(data []byte) ([]ProbeMatch, error) {
+	if err := CheckXML(data, DefaultLimits); err != nil {
+		return nil, err
+	}
+	envelope := probeMatchesEnvelope{}
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	matches := make([]ProbeMatch, 0, len(envelope.Body.ProbeMatches.ProbeMatch))
+	for _, match := range envelope.Body.ProbeMatches.ProbeMatch {
+		matches = append(matches, ProbeMatch{
+			Address:         strings.TrimSpace(match.EndpointReference.Address),
+			Types:           strings.Fields(match.Types),
+			Scopes:          strings.Fields(match.Scopes),
+			XAddrs:          strings.Fields(match.XAddrs),
+			MetadataVersion: match.MetadataVersion,
+		})
 	}
-	return result
+	return matches, nil
 }
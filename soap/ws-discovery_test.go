@@ -0,0 +1,57 @@
+//go:build go1.18
+
+package soap
+
+import (
+	"strings"
+	"testing"
+)
+
+const probeMatchesMessage = `<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:wsadis="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:dn="http://www.onvif.org/ver10/network/wsdl">
+<env:Body><d:ProbeMatches><d:ProbeMatch><wsadis:EndpointReference><wsadis:Address> urn:uuid:5f5a69c2-e0ae-504f-829b-00256b1d2b6a </wsadis:Address></wsadis:EndpointReference>
+<d:Types>dn:NetworkVideoTransmitter tds:Device</d:Types>
+<d:Scopes>onvif://www.onvif.org/type/video_encoder onvif://www.onvif.org/name/HIKVISION</d:Scopes>
+<d:XAddrs>http://10.1.1.200/onvif/device_service</d:XAddrs><d:MetadataVersion>10</d:MetadataVersion></d:ProbeMatch></d:ProbeMatches></env:Body></env:Envelope>`
+
+func TestParseProbeMatches(t *testing.T) {
+	matches, err := ParseProbeMatches([]byte(probeMatchesMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Address != "urn:uuid:5f5a69c2-e0ae-504f-829b-00256b1d2b6a" ||
+		len(matches[0].Scopes) != 2 || matches[0].XAddrs[0] != "http://10.1.1.200/onvif/device_service" || matches[0].MetadataVersion != 10 {
+		t.Fatalf("%+v", matches)
+	}
+}
+
+func FuzzParseProbeMatches(f *testing.F) {
+	f.Add([]byte(probeMatchesMessage))
+	/* 任何主机都可应答探测,超大、深层嵌套与实体声明的报文须被拒绝 */
+	f.Add([]byte(strings.Replace(probeMatchesMessage, "<d:Types>", "<d:Types>"+strings.Repeat(" ", int(DefaultLimits.MaxSize)), 1)))
+	f.Add([]byte(strings.Repeat("<d:ProbeMatches>", DefaultLimits.MaxDepth+1)))
+	f.Add([]byte(strings.Replace(probeMatchesMessage, "<env:Envelope", "<!DOCTYPE e [<!ENTITY x \"x\">]><env:Envelope", 1)))
+	f.Add([]byte(strings.Replace(probeMatchesMessage, "10.1.1.200", "&x;", 1)))
+	f.Add([]byte(billionLaughs))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		matches, err := ParseProbeMatches(data)
+		if err != nil {
+			return
+		}
+		if err := CheckXML(data, DefaultLimits); err != nil {
+			t.Fatalf("parsed a message rejected by CheckXML: %v", err)
+		}
+		for _, match := range matches {
+			if match.Address != strings.TrimSpace(match.Address) {
+				t.Fatalf("address %q not trimmed", match.Address)
+			}
+			for _, field := range [][]string{match.Types, match.Scopes, match.XAddrs} {
+				for _, value := range field {
+					if value == "" || strings.ContainsAny(value, " \t\r\n") {
+						t.Fatalf("list value %q", value)
+					}
+				}
+			}
+		}
+	})
+}
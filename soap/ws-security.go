@@ -1,12 +1,13 @@
 package soap
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/xml"
+	"io"
+	"sync"
 	"time"
-
-	"github.com/elgs/gostrgen"
 )
 
 /*************************
@@ -20,8 +21,15 @@ const (
 //Security type :XMLName xml.Name `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 type Security struct {
 	//XMLName xml.Name  `xml:"wsse:Security"`
-	XMLName xml.Name `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
-	Auth    wsAuth
+	XMLName   xml.Name `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+	Timestamp *timestamp
+	Auth      wsAuth
+}
+
+type timestamp struct {
+	XMLName xml.Name `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Timestamp"`
+	Created string   `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+	Expires string   `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Expires"`
 }
 
 type password struct {
@@ -55,13 +63,55 @@ type wsAuth struct {
    </Security>
 */
 
-//NewSecurity get a new security
-func NewSecurity(username, passwd string) Security {
-	/** Generating Nonce sequence **/
-	charsToGenerate := 32
-	charSet := gostrgen.Lower | gostrgen.Digit
+// NonceSize length in bytes of the random nonces, 16 as WS-Security
+// recommends
+const NonceSize = 16
+
+// recentNonces nonces sent lately, a nonce is never sent twice within the
+// window devices keep to detect replays
+var recentNonces = struct {
+	sync.Mutex
+	seen  map[string]bool
+	order []string
+	next  int
+}{seen: make(map[string]bool)}
+
+const recentNonceCount = 4096
+
+// newNonce return a random nonce not sent lately, base64 encoded
+func newNonce() (string, error) {
+	raw := make([]byte, NonceSize)
+	recentNonces.Lock()
+	defer recentNonces.Unlock()
+	for {
+		if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+			return "", err
+		}
+		value := base64.StdEncoding.EncodeToString(raw)
+		if recentNonces.seen[value] {
+			continue
+		}
+		/* 环形记录最近使用的nonce */
+		if len(recentNonces.order) < recentNonceCount {
+			recentNonces.order = append(recentNonces.order, value)
+		} else {
+			delete(recentNonces.seen, recentNonces.order[recentNonces.next])
+			recentNonces.order[recentNonces.next] = value
+			recentNonces.next = (recentNonces.next + 1) % recentNonceCount
+		}
+		recentNonces.seen[value] = true
+		return value, nil
+	}
+}
 
-	nonceSeq, _ := gostrgen.RandGen(charsToGenerate, charSet, "", "")
+//NewSecurity get a new security, a UsernameToken with a password digest over
+//a fresh random nonce and the current time, the error is the one of the
+//random source
+func NewSecurity(username, passwd string) (Security, error) {
+	nonceSeq, err := newNonce()
+	if err != nil {
+		return Security{}, err
+	}
 	created := time.Now().UTC().Format(time.RFC3339Nano)
 	auth := Security{
 		Auth: wsAuth{
@@ -77,8 +127,22 @@ func NewSecurity(username, passwd string) Security {
 			Created: created,
 		},
 	}
+	return auth, nil
+}
 
-	return auth
+// AddTimestamp add a wsu:Timestamp to the header, created with the
+// UsernameToken and expiring after ttl, some devices reject the UsernameToken
+// without it
+func (security *Security) AddTimestamp(ttl time.Duration) {
+	/* 与UsernameToken的Created保持一致,部分设备会比较两者 */
+	created, err := time.Parse(time.RFC3339Nano, security.Auth.Created)
+	if err != nil {
+		created = time.Now().UTC()
+	}
+	security.Timestamp = &timestamp{
+		Created: created.Format(time.RFC3339Nano),
+		Expires: created.Add(ttl).Format(time.RFC3339Nano),
+	}
 }
 
 //Digest = B64ENCODE( SHA1( B64DECODE( Nonce ) + Date + Password ) )
@@ -0,0 +1,74 @@
+package soap
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+/* ONVIF Application Programmer's Guide 6.1.1.3 的示例 */
+func TestPasswordDigest(t *testing.T) {
+	digest := generateToken("user", "LKqI6G/AikKCQrN0zqZFlg==", "2010-09-16T07:50:45Z", "userpassword")
+	if digest != "tuOSpGlFlIXsozq4HFNeeGeFLEI=" {
+		t.Fatalf("digest = %s", digest)
+	}
+}
+
+func TestNonceUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 2*recentNonceCount; i++ {
+		auth, err := NewSecurity("admin", "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := base64.StdEncoding.DecodeString(auth.Auth.Nonce.Nonce)
+		if err != nil || len(raw) != NonceSize {
+			t.Fatalf("nonce %q: %d bytes, %v", auth.Auth.Nonce.Nonce, len(raw), err)
+		}
+		if seen[auth.Auth.Nonce.Nonce] {
+			t.Fatalf("nonce %s sent twice", auth.Auth.Nonce.Nonce)
+		}
+		seen[auth.Auth.Nonce.Nonce] = true
+		if auth.Auth.Password.Password != generateToken("admin", auth.Auth.Nonce.Nonce, auth.Auth.Created, "password") {
+			t.Fatal("digest does not match the nonce and created time sent")
+		}
+	}
+}
+
+func TestTimestampCreated(t *testing.T) {
+	auth, err := NewSecurity("admin", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.AddTimestamp(time.Minute)
+	output, err := xml.Marshal(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	/* 解析序列化后的报文,检查设备实际收到的值 */
+	sent := Security{}
+	if err := xml.Unmarshal(output, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent.Timestamp == nil {
+		t.Fatalf("no wsu:Timestamp in %s", output)
+	}
+	if sent.Timestamp.Created != sent.Auth.Created {
+		t.Fatalf("wsu:Timestamp/Created %s, UsernameToken/Created %s", sent.Timestamp.Created, sent.Auth.Created)
+	}
+	created, err := time.Parse(time.RFC3339Nano, sent.Timestamp.Created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, err := time.Parse(time.RFC3339Nano, sent.Timestamp.Expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expires.Sub(created) != time.Minute {
+		t.Fatalf("expires %s after created", expires.Sub(created))
+	}
+	if created.Location() != time.UTC || time.Since(created) > time.Minute {
+		t.Fatalf("created %s is not the current UTC time", sent.Timestamp.Created)
+	}
+}
@@ -0,0 +1,90 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Limits bounds applied to XML received from the network, a rogue responder
+// on the discovery group or a compromised device can otherwise send
+// gigantic envelopes, deep nesting or entity expansion bombs.
+type Limits struct {
+	// MaxSize maximum size of a message in bytes
+	MaxSize int64
+	// MaxDepth maximum element nesting depth
+	MaxDepth int
+	// MaxElements maximum number of elements in a message
+	MaxElements int
+}
+
+// DefaultLimits limits used for every response parsed by the package
+var DefaultLimits = Limits{
+	MaxSize:     8 << 20,
+	MaxDepth:    64,
+	MaxElements: 200000,
+}
+
+// Errors returned when a message exceeds the limits
+var (
+	ErrMessageTooLarge = errors.New("xml message exceeds maximum size")
+	ErrTooDeep         = errors.New("xml message exceeds maximum element depth")
+	ErrTooManyElements = errors.New("xml message exceeds maximum element count")
+	ErrDTDNotAllowed   = errors.New("xml message contains a DTD or entity declaration")
+)
+
+// ReadLimited read r up to limits.MaxSize bytes, ErrMessageTooLarge is
+// returned when r holds more
+func ReadLimited(r io.Reader, limits Limits) ([]byte, error) {
+	if limits.MaxSize <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, limits.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limits.MaxSize {
+		return nil, ErrMessageTooLarge
+	}
+	return data, nil
+}
+
+// CheckXML validate data against the limits before it is handed to a parser.
+// Any DOCTYPE is rejected, SOAP 1.2 forbids them and they are the only way
+// to declare expandable entities.
+func CheckXML(data []byte, limits Limits) error {
+	if limits.MaxSize > 0 && int64(len(data)) > limits.MaxSize {
+		return ErrMessageTooLarge
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+	depth := 0
+	elements := 0
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid xml message: %w", err)
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+			elements++
+			if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+				return ErrTooDeep
+			}
+			if limits.MaxElements > 0 && elements > limits.MaxElements {
+				return ErrTooManyElements
+			}
+		case xml.EndElement:
+			depth--
+		case xml.Directive:
+			return ErrDTDNotAllowed
+		}
+	}
+}
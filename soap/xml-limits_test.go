@@ -0,0 +1,107 @@
+//go:build go1.18
+
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+/* 模糊测试使用较小的限制,便于生成超限输入 */
+var fuzzLimits = Limits{MaxSize: 4096, MaxDepth: 16, MaxElements: 128}
+
+const billionLaughs = `<?xml version="1.0"?>
+<!DOCTYPE lolz [
+<!ENTITY lol "lol">
+<!ENTITY lol1 "&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;">
+<!ENTITY lol2 "&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;">
+]>
+<lolz>&lol2;</lolz>`
+
+const externalEntity = `<?xml version="1.0"?>
+<!DOCTYPE e [<!ENTITY xxe SYSTEM "file:///etc/passwd">]>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body>&xxe;</s:Body></s:Envelope>`
+
+// xmlSeeds oversized, deeply nested and entity-laden inputs
+func xmlSeeds() []string {
+	return []string{
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body/></s:Envelope>`,
+		`<a>` + strings.Repeat("x", int(fuzzLimits.MaxSize)) + `</a>`,
+		strings.Repeat("<a>", 2*fuzzLimits.MaxDepth) + strings.Repeat("</a>", 2*fuzzLimits.MaxDepth),
+		`<a>` + strings.Repeat("<b/>", 2*fuzzLimits.MaxElements) + `</a>`,
+		billionLaughs,
+		externalEntity,
+		`<a>&amp;&lt;&#x41;&#65;</a>`,
+		`<a><![CDATA[<!DOCTYPE x>]]></a>`,
+		`<a><b></a>`,
+	}
+}
+
+// xmlShape depth and element count of data found by a namespace aware
+// decode, independently of CheckXML
+func xmlShape(data []byte) (depth, elements int, directive bool, err error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	level := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return depth, elements, directive, nil
+		}
+		if err != nil {
+			return depth, elements, directive, err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			level++
+			elements++
+			if level > depth {
+				depth = level
+			}
+		case xml.EndElement:
+			level--
+		case xml.Directive:
+			directive = true
+		}
+	}
+}
+
+func TestCheckXMLSeeds(t *testing.T) {
+	rejected := map[string]error{
+		xmlSeeds()[1]:  ErrMessageTooLarge,
+		xmlSeeds()[2]:  ErrTooDeep,
+		xmlSeeds()[3]:  ErrTooManyElements,
+		billionLaughs:  ErrDTDNotAllowed,
+		externalEntity: ErrDTDNotAllowed,
+	}
+	for data, want := range rejected {
+		if err := CheckXML([]byte(data), fuzzLimits); !errors.Is(err, want) {
+			t.Errorf("%.40q: %v, want %v", data, err, want)
+		}
+	}
+}
+
+func FuzzCheckXML(f *testing.F) {
+	for _, seed := range xmlSeeds() {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if CheckXML(data, fuzzLimits) != nil {
+			return
+		}
+		if int64(len(data)) > fuzzLimits.MaxSize {
+			t.Fatalf("accepted %d bytes", len(data))
+		}
+		depth, elements, directive, err := xmlShape(data)
+		if err != nil {
+			/* 非严格命名空间等差异不在此检查 */
+			return
+		}
+		if depth > fuzzLimits.MaxDepth || elements > fuzzLimits.MaxElements || directive {
+			t.Fatalf("accepted depth %d, %d elements, directive %v", depth, elements, directive)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package onvif
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// StreamRelay re-stream an RTSP stream to a writer in a format a browser or
+// an HTTP client plays, such as MPEG-TS or fragmented MP4
+type StreamRelay interface {
+	// ContentType of the relayed stream, e.g. video/mp2t
+	ContentType() string
+	// Relay copy the stream to w until ctx is done or the stream ends
+	Relay(ctx context.Context, streamURI, username, password string, w io.Writer) error
+}
+
+// FFmpegRelay StreamRelay running ffmpeg, the video is copied without
+// transcoding
+type FFmpegRelay struct {
+	// Path of the ffmpeg binary, "ffmpeg" from PATH when empty
+	Path string
+	// Transport RTSP lower transport, "tcp" when empty
+	Transport string
+	// Format output format, mpegts (default), mp4 (fragmented) or mpjpeg
+	// (multipart JPEG, transcoded)
+	Format string
+}
+
+// ffmpegFormats output arguments and content type of the formats of FFmpegRelay
+var ffmpegFormats = map[string]struct {
+	args        []string
+	contentType string
+}{
+	"mpegts": {[]string{"-c", "copy", "-f", "mpegts"}, "video/mp2t"},
+	"mp4":    {[]string{"-c", "copy", "-movflags", "frag_keyframe+empty_moov+default_base_moof", "-f", "mp4"}, "video/mp4"},
+	"mpjpeg": {[]string{"-an", "-c:v", "mjpeg", "-q:v", "5", "-f", "mpjpeg", "-boundary_tag", "frame"}, "multipart/x-mixed-replace;boundary=frame"},
+}
+
+func (relay FFmpegRelay) format() string {
+	if relay.Format == "" {
+		return "mpegts"
+	}
+	return relay.Format
+}
+
+// ContentType of the output format
+func (relay FFmpegRelay) ContentType() string {
+	return ffmpegFormats[relay.format()].contentType
+}
+
+// Relay run ffmpeg until ctx is done
+func (relay FFmpegRelay) Relay(ctx context.Context, streamURI, username, password string, w io.Writer) error {
+	format, ok := ffmpegFormats[relay.format()]
+	if !ok {
+		return fmt.Errorf("unknown relay format %q", relay.Format)
+	}
+	transport := relay.Transport
+	if transport == "" {
+		transport = "tcp"
+	}
+	output := append(append([]string{}, format.args...), "pipe:1")
+	if err := runFFmpeg(ctx, relay.Path, streamURI, username, password, transport, output, w); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
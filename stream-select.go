@@ -0,0 +1,277 @@
+package onvif
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoWorkingStream returned by StreamSelector.Select when no transport of
+// the stream could be probed
+var ErrNoWorkingStream = errors.New("no working stream transport")
+
+// StreamProber check a stream URI is playable over the transport of
+// protocol, the selector timing the call. RTSPProber checks the RTSP control
+// connection, FFmpegProber receives a frame.
+type StreamProber interface {
+	ProbeStream(ctx context.Context, streamURI string, protocol StreamProtocol, username, password string) error
+}
+
+// StreamProberFunc adapt a function to the StreamProber interface
+type StreamProberFunc func(ctx context.Context, streamURI string, protocol StreamProtocol, username, password string) error
+
+// ProbeStream call fn
+func (fn StreamProberFunc) ProbeStream(ctx context.Context, streamURI string, protocol StreamProtocol, username, password string) error {
+	return fn(ctx, streamURI, protocol, username, password)
+}
+
+// RTSPProber StreamProber sending an RTSP OPTIONS request, tunnelled in HTTP
+// for StreamRTSPOverHTTP, and waiting for the answer of the server. It
+// measures the round trip of the control connection without credentials; it
+// does not check the media reaches the client, the UDP ports being set up by
+// the player only.
+type RTSPProber struct {
+	// Dial connect to the server, net.Dialer when nil
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// ProbeStream send OPTIONS and read the status line of the answer, any
+// RTSP status, 401 included, proving the server answers. The UDP transports
+// cannot be told from RTSP over TCP that way and are reported not supported.
+func (prober RTSPProber) ProbeStream(ctx context.Context, streamURI string, protocol StreamProtocol, username, password string) error {
+	if protocol == StreamUDP || protocol == StreamMulticast {
+		return fmt.Errorf("rtsp prober cannot check the %s transport: %w", protocol, ErrNotSupported)
+	}
+	u, err := url.Parse(streamURI)
+	if err != nil {
+		return err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "554"
+		if strings.EqualFold(u.Scheme, "http") {
+			port = "80"
+		}
+	}
+	dial := prober.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dial(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+	/* 去掉地址中的用户信息 */
+	u.User = nil
+	request, status := "OPTIONS "+u.String()+" RTSP/1.0\r\nCSeq: 1\r\nUser-Agent: go-onvif\r\n\r\n", "RTSP/1."
+	if protocol == StreamRTSPOverHTTP || strings.EqualFold(u.Scheme, "http") {
+		/* RTSP over HTTP: 以GET建立下行通道,服务器应答HTTP 200 */
+		request = "GET " + u.RequestURI() + " HTTP/1.0\r\nHost: " + u.Host + "\r\nAccept: application/x-rtsp-tunnelled\r\n" +
+			"x-sessioncookie: " + getCnonce() + "\r\nUser-Agent: go-onvif\r\n\r\n"
+		status = "HTTP/1."
+	}
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, status) {
+		return fmt.Errorf("unexpected answer %q", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+// FFmpegProber StreamProber running ffmpeg until the first frame of the
+// stream is received over the transport of the protocol
+type FFmpegProber struct {
+	// Path of the ffmpeg binary, "ffmpeg" from PATH when empty
+	Path string
+}
+
+// ffmpegTransports RTSP lower transport of ffmpeg for the stream protocols
+var ffmpegTransports = map[StreamProtocol]string{
+	StreamUDP:          "udp",
+	StreamRTSP:         "tcp",
+	StreamRTSPOverHTTP: "http",
+	StreamMulticast:    "udp_multicast",
+}
+
+// ProbeStream receive the first frame of the stream
+func (prober FFmpegProber) ProbeStream(ctx context.Context, streamURI string, protocol StreamProtocol, username, password string) error {
+	transport, ok := ffmpegTransports[protocol]
+	if !ok {
+		transport = "tcp"
+	}
+	return runFFmpeg(ctx, prober.Path, streamURI, username, password, transport, []string{"-frames:v", "1", "-f", "null", "-"}, nil)
+}
+
+// StreamProbe result of the probe of one transport
+type StreamProbe struct {
+	Protocol StreamProtocol
+	URI      string
+	Latency  time.Duration
+	Err      error
+}
+
+// StreamSelection transport picked by StreamSelector.Select
+type StreamSelection struct {
+	Profile  string
+	Protocol StreamProtocol
+	URI      string
+	Latency  time.Duration
+	// Network context the selection was made and cached in
+	Network string
+	// Probes of every transport, in the order they were tried
+	Probes []StreamProbe
+	// Cached the selection was taken from the cache, without probing
+	Cached bool
+}
+
+// StreamSelector pick the transport of the stream of a profile with the
+// lowest latency among the ones which work, e.g. RTSP over TCP on a flaky
+// Wi-Fi where UDP loses packets and UDP on a wired LAN. The selection is
+// cached per device, profile and network context, so that a laptop moving
+// from Wi-Fi to a wired LAN probes again.
+type StreamSelector struct {
+	// Prober RTSPProber when nil
+	Prober StreamProber
+	// Protocols transports probed, in order of preference on equal latency,
+	// StreamRTSP and StreamRTSPOverHTTP when empty. StreamUDP needs a prober
+	// receiving the media such as FFmpegProber.
+	Protocols []StreamProtocol
+	// ProbeTimeout longest probe of a transport, 0 means 5s
+	ProbeTimeout time.Duration
+	// TTL time a selection is kept, 0 means 10 minutes
+	TTL time.Duration
+	// NetworkContext return the network context of the device, the local
+	// address the device is reached from when nil
+	NetworkContext func(dev *Device) string
+
+	mutex sync.Mutex
+	cache map[string]cachedSelection
+}
+
+type cachedSelection struct {
+	selection StreamSelection
+	expires   time.Time
+}
+
+// defaultStreamProtocols transports probed by default, in order of
+// preference; RTSPProber only checks the control connection, UDP would always
+// win over TCP with the same probe
+var defaultStreamProtocols = []StreamProtocol{StreamRTSP, StreamRTSPOverHTTP}
+
+// Select return the working transport of the stream of the profile, the
+// first one when empty, with the lowest latency. A transport whose probe is
+// within a millisecond of the best is preferred when it comes first in
+// Protocols. ErrNoWorkingStream is returned, with the probes, when none
+// works.
+func (selector *StreamSelector) Select(ctx context.Context, dev *Device, profile string) (StreamSelection, error) {
+	if profile == "" {
+		token, err := firstProfile(ctx, dev)
+		if err != nil {
+			return StreamSelection{}, err
+		}
+		profile = string(token)
+	}
+	network := selector.network(dev)
+	key := dev.Params.Ipddr + "|" + profile + "|" + network
+	selector.mutex.Lock()
+	if cached, ok := selector.cache[key]; ok && time.Now().Before(cached.expires) {
+		selector.mutex.Unlock()
+		cached.selection.Cached = true
+		return cached.selection, nil
+	}
+	selector.mutex.Unlock()
+
+	prober := selector.Prober
+	if prober == nil {
+		prober = RTSPProber{Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dev.dialTCP(ctx, address)
+		}}
+	}
+	protocols := selector.Protocols
+	if len(protocols) == 0 {
+		protocols = defaultStreamProtocols
+	}
+	selection := StreamSelection{Profile: profile, Network: network}
+	best := -1
+	for _, protocol := range protocols {
+		probe := StreamProbe{Protocol: protocol}
+		probe.URI, probe.Err = dev.GetStreamURI(ctx, profile, protocol)
+		if probe.Err == nil {
+			probeCtx, cancel := context.WithTimeout(ctx, durationOr(selector.ProbeTimeout, 5*time.Second))
+			start := time.Now()
+			probe.Err = prober.ProbeStream(probeCtx, probe.URI, protocol, dev.Params.Username, dev.Params.Password)
+			probe.Latency = time.Since(start)
+			cancel()
+		}
+		if ctx.Err() != nil {
+			return selection, ctx.Err()
+		}
+		selection.Probes = append(selection.Probes, probe)
+		/* 延迟相差不足1ms时保留靠前的协议 */
+		if probe.Err == nil && (best < 0 || probe.Latency+time.Millisecond < selection.Probes[best].Latency) {
+			best = len(selection.Probes) - 1
+		}
+	}
+	if best < 0 {
+		return selection, fmt.Errorf("%w for profile %s: %v", ErrNoWorkingStream, profile, selection.Probes[len(selection.Probes)-1].Err)
+	}
+	selection.Protocol = selection.Probes[best].Protocol
+	selection.URI = selection.Probes[best].URI
+	selection.Latency = selection.Probes[best].Latency
+
+	selector.mutex.Lock()
+	if selector.cache == nil {
+		selector.cache = make(map[string]cachedSelection)
+	}
+	selector.cache[key] = cachedSelection{selection: selection, expires: time.Now().Add(durationOr(selector.TTL, 10*time.Minute))}
+	selector.mutex.Unlock()
+	return selection, nil
+}
+
+// Invalidate drop the cached selections, e.g. when the network changed in a
+// way the network context does not tell
+func (selector *StreamSelector) Invalidate() {
+	selector.mutex.Lock()
+	defer selector.mutex.Unlock()
+	selector.cache = nil
+}
+
+// network return the network context of dev
+func (selector *StreamSelector) network(dev *Device) string {
+	if selector.NetworkContext != nil {
+		return selector.NetworkContext(dev)
+	}
+	return localAddress(dev)
+}
+
+// localAddress return the local address the device is reached from, empty
+// when there is no route to the device. No packet is sent.
+func localAddress(dev *Device) string {
+	host, _ := dev.Params.address()
+	conn, err := net.Dial("udp", net.JoinHostPort(host, "9"))
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	if address, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return address.IP.String()
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package onvif
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+// SubscriptionRecord persisted pull point subscription of a device
+type SubscriptionRecord struct {
+	// Device address of the device, as in DeviceParams.Ipddr
+	Device    string                      `json:"device"`
+	Reference event.EndpointReferenceType `json:"reference"`
+	// Expires termination time of the subscription when it was last renewed
+	Expires time.Time `json:"expires"`
+}
+
+// SubscriptionStore persistence hook of the subscriptions of an EventEngine,
+// so a restarted process resumes them instead of creating new ones
+type SubscriptionStore interface {
+	LoadSubscriptions() ([]SubscriptionRecord, error)
+	SaveSubscription(record SubscriptionRecord) error
+	DeleteSubscription(device string) error
+}
+
+// FileSubscriptionStore SubscriptionStore keeping the records in a JSON file
+type FileSubscriptionStore struct {
+	Path string
+
+	mutex sync.Mutex
+}
+
+// NewFileSubscriptionStore return a store backed by the file at path
+func NewFileSubscriptionStore(path string) *FileSubscriptionStore {
+	return &FileSubscriptionStore{Path: path}
+}
+
+// LoadSubscriptions read the records, a missing file holds no record
+func (store *FileSubscriptionStore) LoadSubscriptions() ([]SubscriptionRecord, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.read()
+}
+
+// SaveSubscription insert or replace the record of the device
+func (store *FileSubscriptionStore) SaveSubscription(record SubscriptionRecord) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	records, err := store.read()
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		if records[i].Device == record.Device {
+			records[i] = record
+			return store.write(records)
+		}
+	}
+	return store.write(append(records, record))
+}
+
+// DeleteSubscription remove the record of the device
+func (store *FileSubscriptionStore) DeleteSubscription(device string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	records, err := store.read()
+	if err != nil {
+		return err
+	}
+	kept := records[:0]
+	for _, record := range records {
+		if record.Device != device {
+			kept = append(kept, record)
+		}
+	}
+	return store.write(kept)
+}
+
+func (store *FileSubscriptionStore) read() ([]SubscriptionRecord, error) {
+	data, err := ioutil.ReadFile(store.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []SubscriptionRecord
+	err = json.Unmarshal(data, &records)
+	return records, err
+}
+
+/* 先写临时文件再重命名,避免写入中断损坏文件 */
+func (store *FileSubscriptionStore) write(records []SubscriptionRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := store.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, store.Path)
+}
@@ -0,0 +1,98 @@
+package onvif
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+func TestFileSubscriptionStore(t *testing.T) {
+	store := NewFileSubscriptionStore(filepath.Join(t.TempDir(), "subscriptions.json"))
+	if records, err := store.LoadSubscriptions(); err != nil || len(records) != 0 {
+		t.Fatalf("records %v, %v of a missing file", records, err)
+	}
+	expires := time.Date(2026, 10, 16, 10, 1, 0, 0, time.UTC)
+	for _, record := range []SubscriptionRecord{
+		{Device: "10.1.1.200", Reference: event.EndpointReferenceType{Address: "http://10.1.1.200/subscription/1"}, Expires: expires},
+		{Device: "10.1.1.201", Reference: event.EndpointReferenceType{Address: "http://10.1.1.201/subscription/1"}, Expires: expires},
+		{Device: "10.1.1.200", Reference: event.EndpointReferenceType{Address: "http://10.1.1.200/subscription/2"}, Expires: expires},
+	} {
+		if err := store.SaveSubscription(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.DeleteSubscription("10.1.1.201"); err != nil {
+		t.Fatal(err)
+	}
+	records, err := store.LoadSubscriptions()
+	if err != nil || len(records) != 1 || records[0].Reference.Address != "http://10.1.1.200/subscription/2" || !records[0].Expires.Equal(expires) {
+		t.Fatalf("records %+v, %v", records, err)
+	}
+}
+
+func TestEventEngineResume(t *testing.T) {
+	fake := &eventDevice{}
+	dev := newEventDevice(t, fake)
+	store := NewFileSubscriptionStore(filepath.Join(t.TempDir(), "subscriptions.json"))
+	engine := testEngine(1)
+	engine.Store = store
+	engine.Add(dev)
+	stop := runEngine(t, engine)
+	eventually(t, "a pull", func() bool { return fake.count(&fake.pulls) > 0 })
+	stop()
+	/* 带Store时退出不取消订阅 */
+	if open := fake.openSubscriptions(); open != 1 {
+		t.Fatalf("%d subscriptions open after Run", open)
+	}
+
+	/* 重启后续订并设置同步点,不新建订阅 */
+	engine = testEngine(1)
+	engine.Store = store
+	engine.Add(dev)
+	stop = runEngine(t, engine)
+	/* 上次运行中被取消的拉取可能晚到,计数从同步点之后开始 */
+	eventually(t, "the synchronization point", func() bool { return fake.count(&fake.synced) > 0 })
+	pulls := fake.count(&fake.pulls)
+	eventually(t, "a pull of the resumed subscription", func() bool { return fake.count(&fake.pulls) > pulls })
+	stop()
+	if created, renewed, synced := fake.count(&fake.created), fake.count(&fake.renewed), fake.count(&fake.synced); created != 1 || renewed == 0 || synced != 1 {
+		t.Fatalf("%d subscriptions created, %d renewed, %d synchronized", created, renewed, synced)
+	}
+
+	/* 设备不再认识保存的订阅时重新订阅 */
+	fake.mutex.Lock()
+	fake.open = nil
+	pulls = fake.pulls
+	fake.mutex.Unlock()
+	engine = testEngine(1)
+	engine.Store = store
+	engine.Add(dev)
+	stop = runEngine(t, engine)
+	eventually(t, "a pull of a new subscription", func() bool { return fake.count(&fake.pulls) > pulls })
+	if created := fake.count(&fake.created); created != 2 {
+		t.Fatalf("%d subscriptions created", created)
+	}
+	stop()
+	records, err := store.LoadSubscriptions()
+	if err != nil || len(records) != 1 || string(records[0].Reference.Address) != "http://"+dev.Params.Ipddr+"/subscription/2" {
+		t.Fatalf("records %+v, %v", records, err)
+	}
+}
+
+func TestEventEngineRemoveDeletesRecord(t *testing.T) {
+	fake := &eventDevice{}
+	dev := newEventDevice(t, fake)
+	store := NewFileSubscriptionStore(filepath.Join(t.TempDir(), "subscriptions.json"))
+	engine := testEngine(1)
+	engine.Store = store
+	engine.Add(dev)
+	runEngine(t, engine)
+	eventually(t, "a pull", func() bool { return fake.count(&fake.pulls) > 0 })
+	engine.Remove(dev)
+	eventually(t, "the subscription cancelled", func() bool { return fake.openSubscriptions() == 0 })
+	if records, err := store.LoadSubscriptions(); err != nil || len(records) != 0 {
+		t.Fatalf("records %+v, %v after Remove", records, err)
+	}
+}
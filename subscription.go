@@ -0,0 +1,70 @@
+package onvif
+
+import (
+	"context"
+	"time"
+
+	"github.com/PolarisM78/go-onvif/events"
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+// WS-Addressing actions of the messages sent to a subscription manager, see
+// the events package
+const (
+	ActionPullMessages = events.ActionPullMessages
+	ActionRenew        = events.ActionRenew
+	ActionUnsubscribe  = events.ActionUnsubscribe
+
+	ActionSetSynchronizationPoint = events.ActionSetSynchronizationPoint
+)
+
+// ErrNoSubscriptionAddress returned when a subscription reference holds no address
+var ErrNoSubscriptionAddress = events.ErrNoSubscriptionAddress
+
+// CreatePullPointSubscription create a pull point on the device, filter and
+// termination are optional
+//
+// Deprecated: use events.New(dev).CreatePullPointSubscription with
+// filter.FilterType().
+func (dev *Device) CreatePullPointSubscription(ctx context.Context, filter *EventFilter, termination time.Duration) (event.CreatePullPointSubscriptionResponse, error) {
+	return events.New(dev).CreatePullPointSubscription(ctx, filter.FilterType(), termination)
+}
+
+// Subscribe create a base notification subscription sending the events of
+// the device to consumer, the address of a NotifyListener, filter and
+// termination are optional
+//
+// Deprecated: use events.New(dev).Subscribe with filter.FilterType().
+func (dev *Device) Subscribe(ctx context.Context, consumer string, filter *EventFilter, termination time.Duration) (event.SubscribeResponse, error) {
+	return events.New(dev).Subscribe(ctx, consumer, filter.FilterType(), termination)
+}
+
+// PullMessages pull the messages of the subscription, the device holds the
+// request up to timeout when no message is pending
+//
+// Deprecated: use events.New(dev).PullMessages.
+func (dev *Device) PullMessages(ctx context.Context, reference event.EndpointReferenceType, timeout time.Duration, limit int) (event.PullMessagesResponse, error) {
+	return events.New(dev).PullMessages(ctx, reference, timeout, limit)
+}
+
+// Renew extend the subscription by termination
+//
+// Deprecated: use events.New(dev).Renew.
+func (dev *Device) Renew(ctx context.Context, reference event.EndpointReferenceType, termination time.Duration) (event.RenewResponse, error) {
+	return events.New(dev).Renew(ctx, reference, termination)
+}
+
+// Unsubscribe cancel the subscription on the subscription manager
+//
+// Deprecated: use events.New(dev).Unsubscribe.
+func (dev *Device) Unsubscribe(ctx context.Context, reference event.EndpointReferenceType) error {
+	return events.New(dev).Unsubscribe(ctx, reference)
+}
+
+// SetSynchronizationPoint ask the subscription to send the current state of
+// every property again, as Initialized messages
+//
+// Deprecated: use events.New(dev).SetSynchronizationPoint.
+func (dev *Device) SetSynchronizationPoint(ctx context.Context, reference event.EndpointReferenceType) error {
+	return events.New(dev).SetSynchronizationPoint(ctx, reference)
+}
@@ -0,0 +1,42 @@
+package onvif
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	event "github.com/PolarisM78/go-onvif/types/events"
+)
+
+func TestSubscriptionManagerAddress(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"Renew":       `<wsnt:RenewResponse><wsnt:TerminationTime>2026-10-16T10:01:00Z</wsnt:TerminationTime><wsnt:CurrentTime>2026-10-16T10:00:00Z</wsnt:CurrentTime></wsnt:RenewResponse>`,
+		"Unsubscribe": `<wsnt:UnsubscribeResponse/>`,
+	}, ServiceEvents)
+	reference := event.EndpointReferenceType{}
+	if err := xml.Unmarshal([]byte(`<SubscriptionReference xmlns:wsa="http://www.w3.org/2005/08/addressing"><wsa:Address>http://`+dev.Params.Ipddr+`/subscription/9</wsa:Address>`+
+		`<wsa:ReferenceParameters><dom0:SubscriptionId xmlns:dom0="http://www.example.com/subscription">9</dom0:SubscriptionId></wsa:ReferenceParameters></SubscriptionReference>`), &reference); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := dev.Renew(ctx, reference, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Unsubscribe(ctx, reference); err != nil {
+		t.Fatal(err)
+	}
+	/* 发往订阅管理器地址而非events服务地址,并回显引用参数 */
+	for operation, action := range map[string]string{"Renew": ActionRenew, "Unsubscribe": ActionUnsubscribe} {
+		requests := fake.sent(operation)
+		if len(requests) != 1 || !strings.HasPrefix(requests[0], "/subscription/9 ") || !strings.Contains(requests[0], action) ||
+			!strings.Contains(requests[0], `a:IsReferenceParameter="true">9</rp:SubscriptionId>`) {
+			t.Fatalf("%s requests %q", operation, requests)
+		}
+	}
+	if err := dev.Unsubscribe(ctx, event.EndpointReferenceType{}); !errors.Is(err, ErrNoSubscriptionAddress) {
+		t.Fatalf("error %v without address", err)
+	}
+}
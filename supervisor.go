@@ -0,0 +1,193 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrShutdownTimeout reported for a component still running ShutdownTimeout
+// after it was stopped
+var ErrShutdownTimeout = errors.New("component did not stop in time")
+
+// Component long running part of an application running until ctx ends, e.g.
+// an EventEngine, a HealthMonitor, a SnapshotPoller or a Gateway through
+// ComponentFunc
+type Component interface {
+	Run(ctx context.Context) error
+}
+
+// ComponentFunc adapt a function to the Component interface
+//
+//	supervisor.Add("gateway", onvif.ComponentFunc(func(ctx context.Context) error {
+//		return gateway.ListenAndServe(ctx, ":8080")
+//	}))
+type ComponentFunc func(ctx context.Context) error
+
+// Run call fn
+func (fn ComponentFunc) Run(ctx context.Context) error {
+	return fn(ctx)
+}
+
+// ComponentError failure of a component of a Supervisor
+type ComponentError struct {
+	Name string
+	Err  error
+}
+
+func (err *ComponentError) Error() string {
+	return fmt.Sprintf("%s: %v", err.Name, err.Err)
+}
+
+// Unwrap return the error of the component
+func (err *ComponentError) Unwrap() error {
+	return err.Err
+}
+
+// SupervisorError failures of the components of a Supervisor, in the order
+// they happened
+type SupervisorError struct {
+	Errors []*ComponentError
+}
+
+func (err *SupervisorError) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, componentErr := range err.Errors {
+		messages[i] = componentErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap return the first failure, the one which stopped the supervisor
+func (err *SupervisorError) Unwrap() error {
+	return err.Errors[0]
+}
+
+// Supervisor run components together, errgroup style: they are started in
+// the order they were added, and when ctx ends or one of them fails they are
+// stopped in the reverse order, each one being waited for before the
+// previous one is stopped, so a component is stopped before the ones it
+// depends on, e.g. a gateway before the event engine feeding it. The
+// failures of every component are collected in a single SupervisorError.
+//
+//	supervisor := &onvif.Supervisor{}
+//	supervisor.Add("events", engine)
+//	supervisor.Add("health", monitor)
+//	supervisor.Add("snapshots", poller)
+//	err := supervisor.Run(ctx)
+type Supervisor struct {
+	// ShutdownTimeout wait for each component to return once stopped, 0
+	// means 30s; a component still running then is reported with
+	// ErrShutdownTimeout and left behind
+	ShutdownTimeout time.Duration
+
+	mutex      sync.Mutex
+	components []supervisedComponent
+}
+
+type supervisedComponent struct {
+	name      string
+	component Component
+}
+
+// componentExit return of the Run of a component
+type componentExit struct {
+	index int
+	err   error
+}
+
+// Add add a component to be run by Run, after the ones already added
+func (supervisor *Supervisor) Add(name string, component Component) {
+	supervisor.mutex.Lock()
+	defer supervisor.mutex.Unlock()
+	supervisor.components = append(supervisor.components, supervisedComponent{name: name, component: component})
+}
+
+// Run run the components until ctx ends, every component has returned or one
+// of them fails, then stop the ones still running. A SupervisorError is
+// returned when a component failed, the error of ctx when it ended, nil when
+// the components all returned on their own.
+func (supervisor *Supervisor) Run(ctx context.Context) error {
+	supervisor.mutex.Lock()
+	components := append([]supervisedComponent(nil), supervisor.components...)
+	supervisor.mutex.Unlock()
+
+	exits := make(chan componentExit, len(components))
+	cancels := make([]context.CancelFunc, len(components))
+	exited := make([]bool, len(components))
+	/* 组件的ctx不随ctx取消,以便按相反顺序逐个停止 */
+	base := detachedContext{ctx}
+	for i, supervised := range components {
+		componentCtx, cancel := context.WithCancel(base)
+		cancels[i] = cancel
+		go func(i int, component Component) {
+			exits <- componentExit{index: i, err: component.Run(componentCtx)}
+		}(i, supervised.component)
+	}
+
+	var failures []*ComponentError
+	record := func(exit componentExit, stopped bool) {
+		exited[exit.index] = true
+		if exit.err == nil || stopped && errors.Is(exit.err, context.Canceled) {
+			return
+		}
+		failures = append(failures, &ComponentError{Name: components[exit.index].name, Err: exit.err})
+	}
+	running := len(components)
+	for running > 0 && len(failures) == 0 && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case exit := <-exits:
+			running--
+			record(exit, false)
+		}
+	}
+
+	timeout := durationOr(supervisor.ShutdownTimeout, 30*time.Second)
+	for i := len(components) - 1; i >= 0; i-- {
+		cancels[i]()
+		if exited[i] {
+			continue
+		}
+		timer := time.NewTimer(timeout)
+		for !exited[i] {
+			select {
+			case exit := <-exits:
+				record(exit, true)
+			case <-timer.C:
+				exited[i] = true
+				failures = append(failures, &ComponentError{Name: components[i].name, Err: ErrShutdownTimeout})
+			}
+		}
+		timer.Stop()
+	}
+	if len(failures) > 0 {
+		return &SupervisorError{Errors: failures}
+	}
+	return ctx.Err()
+}
+
+// detachedContext context carrying the values of its parent but not its
+// cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
@@ -0,0 +1,105 @@
+package accesscontrol
+
+import (
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Capabilities of the access control service
+type Capabilities struct {
+	MaxLimit                       int  `xml:"MaxLimit,attr"`
+	MaxAccessPoints                int  `xml:"MaxAccessPoints,attr"`
+	MaxAreas                       int  `xml:"MaxAreas,attr"`
+	ClientSuppliedTokenSupported   bool `xml:"ClientSuppliedTokenSupported,attr"`
+	AccessPointManagementSupported bool `xml:"AccessPointManagementSupported,attr"`
+	AreaManagementSupported        bool `xml:"AreaManagementSupported,attr"`
+}
+
+// AreaInfo physical area of the access control system, e.g. a building zone
+type AreaInfo struct {
+	Token       onvif.ReferenceToken `xml:"token,attr"`
+	Name        onvif.Name           `xml:"Name"`
+	Description xsd.String           `xml:"Description"`
+}
+
+// Area AreaInfo as accepted by the area management operations
+type Area struct {
+	Token       onvif.ReferenceToken `xml:"token,attr,omitempty"`
+	Name        onvif.Name           `xml:"tac:Name"`
+	Description xsd.String           `xml:"tac:Description,omitempty"`
+}
+
+// AccessPointInfo access point between two areas
+type AccessPointInfo struct {
+	Token       onvif.ReferenceToken `xml:"token,attr"`
+	Name        onvif.Name           `xml:"Name"`
+	Description xsd.String           `xml:"Description"`
+	AreaFrom    onvif.ReferenceToken `xml:"AreaFrom"`
+	AreaTo      onvif.ReferenceToken `xml:"AreaTo"`
+	EntityType  xsd.QName            `xml:"EntityType"`
+	Entity      onvif.ReferenceToken `xml:"Entity"`
+}
+
+type GetServiceCapabilities struct {
+	XMLName string `xml:"tac:GetServiceCapabilities"`
+}
+
+type GetServiceCapabilitiesResponse struct {
+	Capabilities Capabilities
+}
+
+type GetAreaInfoList struct {
+	XMLName        string     `xml:"tac:GetAreaInfoList"`
+	Limit          int        `xml:"tac:Limit,omitempty"`
+	StartReference xsd.String `xml:"tac:StartReference,omitempty"`
+}
+
+type GetAreaInfoListResponse struct {
+	NextStartReference xsd.String `xml:"NextStartReference"`
+	AreaInfo           []AreaInfo `xml:"AreaInfo"`
+}
+
+type GetAreaInfo struct {
+	XMLName string                 `xml:"tac:GetAreaInfo"`
+	Token   []onvif.ReferenceToken `xml:"tac:Token"`
+}
+
+type GetAreaInfoResponse struct {
+	AreaInfo []AreaInfo `xml:"AreaInfo"`
+}
+
+type CreateArea struct {
+	XMLName string `xml:"tac:CreateArea"`
+	Area    Area   `xml:"tac:Area"`
+}
+
+type CreateAreaResponse struct {
+	Token onvif.ReferenceToken `xml:"Token"`
+}
+
+type ModifyArea struct {
+	XMLName string `xml:"tac:ModifyArea"`
+	Area    Area   `xml:"tac:Area"`
+}
+
+type ModifyAreaResponse struct {
+}
+
+type DeleteArea struct {
+	XMLName string               `xml:"tac:DeleteArea"`
+	Token   onvif.ReferenceToken `xml:"tac:Token"`
+}
+
+type DeleteAreaResponse struct {
+}
+
+type GetAccessPointInfoList struct {
+	XMLName        string     `xml:"tac:GetAccessPointInfoList"`
+	Limit          int        `xml:"tac:Limit,omitempty"`
+	StartReference xsd.String `xml:"tac:StartReference,omitempty"`
+}
+
+type GetAccessPointInfoListResponse struct {
+	NextStartReference xsd.String        `xml:"NextStartReference"`
+	AccessPointInfo    []AccessPointInfo `xml:"AccessPointInfo"`
+}
@@ -13,7 +13,7 @@ type GetSupportedRules struct {
 type CreateRules struct {
 	XMLName            string               `xml:"tan:CreateRules"`
 	ConfigurationToken onvif.ReferenceToken `xml:"tan:ConfigurationToken"`
-	Rule               onvif.Config         `xml:"tan:Rule"`
+	Rule               []Config             `xml:"tan:Rule"`
 }
 
 type DeleteRules struct {
@@ -22,6 +22,22 @@ type DeleteRules struct {
 	RuleName           xsd.String           `xml:"tan:RuleName"`
 }
 
+// Config rule or analytics module sent to the device, with its parameters
+type Config struct {
+	Name       string     `xml:"Name,attr"`
+	Type       xsd.QName  `xml:"Type,attr"`
+	Parameters Parameters `xml:"tt:Parameters"`
+}
+
+type Parameters struct {
+	SimpleItem []SimpleItem `xml:"tt:SimpleItem"`
+}
+
+type SimpleItem struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr"`
+}
+
 type GetRules struct {
 	XMLName            string               `xml:"tan:GetRules"`
 	ConfigurationToken onvif.ReferenceToken `xml:"tan:ConfigurationToken"`
@@ -29,14 +45,14 @@ type GetRules struct {
 
 type GetRuleOptions struct {
 	XMLName            string               `xml:"tan:GetRuleOptions"`
-	RuleType           xsd.QName            `xml:"tan:RuleType"`
+	RuleType           xsd.QName            `xml:"tan:RuleType,omitempty"`
 	ConfigurationToken onvif.ReferenceToken `xml:"tan:ConfigurationToken"`
 }
 
 type ModifyRules struct {
 	XMLName            string               `xml:"tan:ModifyRules"`
 	ConfigurationToken onvif.ReferenceToken `xml:"tan:ConfigurationToken"`
-	Rule               onvif.Config         `xml:"tan:Rule"`
+	Rule               []Config             `xml:"tan:Rule"`
 }
 
 type GetServiceCapabilities struct {
@@ -50,7 +66,7 @@ type GetSupportedAnalyticsModules struct {
 
 type GetAnalyticsModuleOptions struct {
 	XMLName            string               `xml:"tan:GetAnalyticsModuleOptions"`
-	Type               xsd.QName            `xml:"tan:Type"`
+	Type               xsd.QName            `xml:"tan:Type,omitempty"`
 	ConfigurationToken onvif.ReferenceToken `xml:"tan:ConfigurationToken"`
 }
 
@@ -76,3 +92,43 @@ type ModifyAnalyticsModules struct {
 	ConfigurationToken onvif.ReferenceToken `xml:"tan:ConfigurationToken"`
 	AnalyticsModule    onvif.Config         `xml:"tan:AnalyticsModule"`
 }
+
+type GetSupportedRulesResponse struct {
+	SupportedRules onvif.SupportedRules `xml:"SupportedRules"`
+}
+
+type GetRuleOptionsResponse struct {
+	RuleOptions []onvif.ConfigOptions `xml:"RuleOptions"`
+}
+
+type GetSupportedAnalyticsModulesResponse struct {
+	SupportedAnalyticsModules onvif.SupportedAnalyticsModules `xml:"SupportedAnalyticsModules"`
+}
+
+type GetAnalyticsModuleOptionsResponse struct {
+	Options []onvif.ConfigOptions `xml:"Options"`
+}
+
+type CreateRulesResponse struct {
+}
+
+type ModifyRulesResponse struct {
+}
+
+type DeleteRulesResponse struct {
+}
+
+// Capabilities of the analytics service
+type Capabilities struct {
+	RuleSupport                        bool   `xml:"RuleSupport,attr"`
+	AnalyticsModuleSupport             bool   `xml:"AnalyticsModuleSupport,attr"`
+	CellBasedSceneDescriptionSupported bool   `xml:"CellBasedSceneDescriptionSupported,attr"`
+	RuleOptionsSupported               bool   `xml:"RuleOptionsSupported,attr"`
+	AnalyticsModuleOptionsSupported    bool   `xml:"AnalyticsModuleOptionsSupported,attr"`
+	SupportedMetadata                  bool   `xml:"SupportedMetadata,attr"`
+	ImageSendingType                   string `xml:"ImageSendingType,attr"`
+}
+
+type GetServiceCapabilitiesResponse struct {
+	Capabilities Capabilities
+}
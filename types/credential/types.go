@@ -0,0 +1,143 @@
+package credential
+
+import (
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Capabilities of the credential service
+type Capabilities struct {
+	MaxLimit                                 int      `xml:"MaxLimit,attr"`
+	CredentialValiditySupported              bool     `xml:"CredentialValiditySupported,attr"`
+	CredentialAccessProfileValiditySupported bool     `xml:"CredentialAccessProfileValiditySupported,attr"`
+	ValiditySupportsTimeValue                bool     `xml:"ValiditySupportsTimeValue,attr"`
+	MaxCredentials                           int      `xml:"MaxCredentials,attr"`
+	MaxAccessProfilesPerCredential           int      `xml:"MaxAccessProfilesPerCredential,attr"`
+	ClientSuppliedTokenSupported             bool     `xml:"ClientSuppliedTokenSupported,attr"`
+	SupportedIdentifierType                  []string `xml:"SupportedIdentifierType"`
+}
+
+// IdentifierType recognition type (pt:Card, pt:PIN...) and value format
+type IdentifierType struct {
+	Name       xsd.String `xml:"tcr:Name"`
+	FormatType xsd.String `xml:"tcr:FormatType"`
+}
+
+// CredentialIdentifier value presented to a reader, Value is hex encoded
+type CredentialIdentifier struct {
+	Type                       IdentifierType `xml:"tcr:Type"`
+	ExemptedFromAuthentication xsd.Boolean    `xml:"tcr:ExemptedFromAuthentication"`
+	Value                      xsd.String     `xml:"tcr:Value"`
+}
+
+// CredentialAccessProfile access profile granted to a credential
+type CredentialAccessProfile struct {
+	AccessProfileToken onvif.ReferenceToken `xml:"tcr:AccessProfileToken"`
+	ValidFrom          xsd.String           `xml:"tcr:ValidFrom,omitempty"`
+	ValidTo            xsd.String           `xml:"tcr:ValidTo,omitempty"`
+}
+
+// Credential as sent to CreateCredential and ModifyCredential
+type Credential struct {
+	Token                     onvif.ReferenceToken      `xml:"token,attr,omitempty"`
+	Description               xsd.String                `xml:"tcr:Description,omitempty"`
+	CredentialHolderReference xsd.String                `xml:"tcr:CredentialHolderReference"`
+	ValidFrom                 xsd.String                `xml:"tcr:ValidFrom,omitempty"`
+	ValidTo                   xsd.String                `xml:"tcr:ValidTo,omitempty"`
+	CredentialIdentifier      []CredentialIdentifier    `xml:"tcr:CredentialIdentifier"`
+	CredentialAccessProfile   []CredentialAccessProfile `xml:"tcr:CredentialAccessProfile,omitempty"`
+}
+
+// CredentialInfo credential as returned by the device, only parsed from responses
+type CredentialInfo struct {
+	Token                     onvif.ReferenceToken `xml:"token,attr"`
+	Description               xsd.String           `xml:"Description"`
+	CredentialHolderReference xsd.String           `xml:"CredentialHolderReference"`
+	ValidFrom                 xsd.String           `xml:"ValidFrom"`
+	ValidTo                   xsd.String           `xml:"ValidTo"`
+}
+
+// CredentialState enabled state of a credential
+type CredentialState struct {
+	Enabled xsd.Boolean `xml:"tcr:Enabled"`
+	Reason  xsd.String  `xml:"tcr:Reason,omitempty"`
+}
+
+// CredentialStateInfo state of a credential, only parsed from responses
+type CredentialStateInfo struct {
+	Enabled xsd.Boolean `xml:"Enabled"`
+	Reason  xsd.String  `xml:"Reason"`
+}
+
+type GetServiceCapabilities struct {
+	XMLName string `xml:"tcr:GetServiceCapabilities"`
+}
+
+type GetServiceCapabilitiesResponse struct {
+	Capabilities Capabilities
+}
+
+type GetCredentialInfoList struct {
+	XMLName        string     `xml:"tcr:GetCredentialInfoList"`
+	Limit          int        `xml:"tcr:Limit,omitempty"`
+	StartReference xsd.String `xml:"tcr:StartReference,omitempty"`
+}
+
+type GetCredentialInfoListResponse struct {
+	NextStartReference xsd.String       `xml:"NextStartReference"`
+	CredentialInfo     []CredentialInfo `xml:"CredentialInfo"`
+}
+
+type CreateCredential struct {
+	XMLName    string          `xml:"tcr:CreateCredential"`
+	Credential Credential      `xml:"tcr:Credential"`
+	State      CredentialState `xml:"tcr:State"`
+}
+
+type CreateCredentialResponse struct {
+	Token onvif.ReferenceToken `xml:"Token"`
+}
+
+type SetCredentialIdentifier struct {
+	XMLName              string               `xml:"tcr:SetCredentialIdentifier"`
+	CredentialToken      onvif.ReferenceToken `xml:"tcr:CredentialToken"`
+	CredentialIdentifier CredentialIdentifier `xml:"tcr:CredentialIdentifier"`
+}
+
+type SetCredentialIdentifierResponse struct {
+}
+
+type GetCredentialState struct {
+	XMLName string               `xml:"tcr:GetCredentialState"`
+	Token   onvif.ReferenceToken `xml:"tcr:Token"`
+}
+
+type GetCredentialStateResponse struct {
+	State CredentialStateInfo `xml:"State"`
+}
+
+type EnableCredential struct {
+	XMLName string               `xml:"tcr:EnableCredential"`
+	Token   onvif.ReferenceToken `xml:"tcr:Token"`
+	Reason  xsd.String           `xml:"tcr:Reason,omitempty"`
+}
+
+type EnableCredentialResponse struct {
+}
+
+type DisableCredential struct {
+	XMLName string               `xml:"tcr:DisableCredential"`
+	Token   onvif.ReferenceToken `xml:"tcr:Token"`
+	Reason  xsd.String           `xml:"tcr:Reason,omitempty"`
+}
+
+type DisableCredentialResponse struct {
+}
+
+type DeleteCredential struct {
+	XMLName string               `xml:"tcr:DeleteCredential"`
+	Token   onvif.ReferenceToken `xml:"tcr:Token"`
+}
+
+type DeleteCredentialResponse struct {
+}
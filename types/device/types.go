@@ -2,6 +2,7 @@ package device
 
 import (
 	"encoding/xml"
+	"time"
 
 	"github.com/PolarisM78/go-onvif/xsd"
 	"github.com/PolarisM78/go-onvif/xsd/onvif"
@@ -110,7 +111,7 @@ type GetServices struct {
 }
 
 type GetServicesResponse struct {
-	Service Service
+	Service []Service `xml:"Service"`
 }
 
 type GetServiceCapabilities struct {
@@ -134,12 +135,14 @@ type GetDeviceInformationResponse struct {
 	HardwareId      string   `xml:"HardwareId"`
 }
 
+// SetSystemDateAndTime TimeZone and UTCDateTime are optional, UTCDateTime
+// must be omitted in NTP mode
 type SetSystemDateAndTime struct {
 	XMLName         string      `xml:"tds:SetSystemDateAndTime"`
 	DateTimeType    string      `xml:"tds:DateTimeType"`
 	DaylightSavings xsd.Boolean `xml:"tds:DaylightSavings"`
-	TimeZone        TimeZone    `xml:"tds:TimeZone"`
-	UTCDateTime     DateTime    `xml:"tds:UTCDateTime"`
+	TimeZone        *TimeZone   `xml:"tds:TimeZone,omitempty"`
+	UTCDateTime     *DateTime   `xml:"tds:UTCDateTime,omitempty"`
 }
 
 type SetSystemDateAndTimeResponse struct {
@@ -217,13 +220,12 @@ type GetScopes struct {
 }
 
 type GetScopesResponse struct {
-	Scopes onvif.Scope
+	Scopes []onvif.Scope
 }
 
-// TODO: one or more scopes
 type SetScopes struct {
-	XMLName string     `xml:"tds:SetScopes"`
-	Scopes  xsd.AnyURI `xml:"tds:Scopes"`
+	XMLName string       `xml:"tds:SetScopes"`
+	Scopes  []xsd.AnyURI `xml:"tds:Scopes"`
 }
 
 type SetScopesResponse struct {
@@ -325,7 +327,7 @@ type GetUsers struct {
 }
 
 type GetUsersResponse struct {
-	User onvif.User
+	User []onvif.User `xml:"User"`
 }
 
 // TODO: List of users
@@ -354,6 +356,14 @@ type SetUser struct {
 type SetUserResponse struct {
 }
 
+type SetUsers struct {
+	XMLName string       `xml:"tds:SetUsers"`
+	User    []onvif.User `xml:"tds:User"`
+}
+
+type SetUsersResponse struct {
+}
+
 type GetWsdlUrl struct {
 	XMLName string `xml:"tds:GetWsdlUrl"`
 }
@@ -454,7 +464,7 @@ type GetNetworkInterfaces struct {
 }
 
 type GetNetworkInterfacesResponse struct {
-	NetworkInterfaces onvif.NetworkInterface
+	NetworkInterfaces []onvif.NetworkInterface
 }
 
 type SetNetworkInterfaces struct {
@@ -560,7 +570,14 @@ type GetAccessPolicy struct {
 }
 
 type GetAccessPolicyResponse struct {
-	PolicyFile onvif.BinaryData
+	XMLName    xml.Name   `xml:"GetAccessPolicyResponse"`
+	PolicyFile PolicyFile `xml:"PolicyFile"`
+}
+
+// PolicyFile access policy file of a GetAccessPolicyResponse, Data being base64
+type PolicyFile struct {
+	ContentType string `xml:"contentType,attr"`
+	Data        string `xml:"Data"`
 }
 
 type SetAccessPolicy struct {
@@ -787,7 +804,7 @@ type ScanAvailableDot11Networks struct {
 }
 
 type ScanAvailableDot11NetworksResponse struct {
-	Networks onvif.Dot11AvailableNetworks
+	Networks []onvif.Dot11AvailableNetworks
 }
 
 type GetSystemUris struct {
@@ -929,6 +946,55 @@ type TimeZone struct {
 	TZ string `xml:"onvif:TZ"`
 }
 
+// UnmarshalXML decode DateTime from a response, the prefixed tags above are only
+// usable for requests
+func (dateTime *DateTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	value := struct {
+		Time struct {
+			Hour   int `xml:"Hour"`
+			Minute int `xml:"Minute"`
+			Second int `xml:"Second"`
+		} `xml:"Time"`
+		Date struct {
+			Year  int `xml:"Year"`
+			Month int `xml:"Month"`
+			Day   int `xml:"Day"`
+		} `xml:"Date"`
+	}{}
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	dateTime.Time = Time(value.Time)
+	dateTime.Date = Date(value.Date)
+	return nil
+}
+
+// UnmarshalXML decode TimeZone from a response
+func (timeZone *TimeZone) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	value := struct {
+		TZ string `xml:"TZ"`
+	}{}
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	timeZone.TZ = value.TZ
+	return nil
+}
+
+// ToTime convert the DateTime in the given location
+func (dateTime DateTime) ToTime(loc *time.Location) time.Time {
+	return time.Date(dateTime.Date.Year, time.Month(dateTime.Date.Month), dateTime.Date.Day,
+		dateTime.Time.Hour, dateTime.Time.Minute, dateTime.Time.Second, 0, loc)
+}
+
+// NewDateTime build a DateTime from t, the caller chooses the location of t
+func NewDateTime(t time.Time) DateTime {
+	return DateTime{
+		Time: Time{Hour: t.Hour(), Minute: t.Minute(), Second: t.Second()},
+		Date: Date{Year: t.Year(), Month: int(t.Month()), Day: t.Day()},
+	}
+}
+
 type SystemDateTime struct {
 	DateTimeType    string
 	DaylightSavings bool
@@ -21,29 +21,43 @@ type SubscriptionPolicy struct { //tev http://www.onvif.org/ver10/events/wsdl
 
 // Subscribe action for subscribe event topic
 type Subscribe struct { //http://docs.oasis-open.org/wsn/b-2.xsd
-	XMLName                struct{}              `xml:"wsnt:Subscribe"`
-	ConsumerReference      EndpointReferenceType `xml:"wsnt:ConsumerReference"`
-	Filter                 FilterType            `xml:"wsnt:Filter"`
-	SubscriptionPolicy     SubscriptionPolicy    `xml:"wsnt:SubscriptionPolicy"`
-	InitialTerminationTime TerminationTime       `xml:"wsnt:InitialTerminationTime"`
+	XMLName           struct{}              `xml:"wsnt:Subscribe"`
+	ConsumerReference ConsumerReferenceType `xml:"wsnt:ConsumerReference"`
+	// Filter optional topic and message content filter, omitted when nil
+	Filter             *FilterType         `xml:"wsnt:Filter,omitempty"`
+	SubscriptionPolicy *SubscriptionPolicy `xml:"wsnt:SubscriptionPolicy,omitempty"`
+	// InitialTerminationTime as xs:duration (PT60S) or xs:dateTime, omitted when empty
+	InitialTerminationTime xsd.String `xml:"wsnt:InitialTerminationTime,omitempty"`
+}
+
+// ConsumerReferenceType address the notifications of a subscription are sent to
+type ConsumerReferenceType struct {
+	Address xsd.AnyURI `xml:"wsa:Address"`
+}
+
+// Notify message sent by the device to the consumer of a subscription
+type Notify struct { //http://docs.oasis-open.org/wsn/b-2.xsd
+	NotificationMessage []NotificationMessage `xml:"NotificationMessage"`
 }
 
 // SubscribeResponse message for subscribe event topic
 type SubscribeResponse struct { //http://docs.oasis-open.org/wsn/b-2.xsd
-	ConsumerReference EndpointReferenceType `xml:"ConsumerReference"`
-	CurrentTime       CurrentTime           `xml:"CurrentTime"`
-	TerminationTime   TerminationTime       `xml:"TerminationTime"`
+	SubscriptionReference EndpointReferenceType `xml:"SubscriptionReference"`
+	CurrentTime           CurrentTime           `xml:"CurrentTime"`
+	TerminationTime       TerminationTime       `xml:"TerminationTime"`
 }
 
 // Renew action for refresh event topic subscription
 type Renew struct { //http://docs.oasis-open.org/wsn/b-2.xsd
-	TerminationTime AbsoluteOrRelativeTimeType `xml:"wsnt:TerminationTime"`
+	XMLName string `xml:"wsnt:Renew"`
+	// TerminationTime as xs:duration (PT60S) or xs:dateTime
+	TerminationTime xsd.String `xml:"wsnt:TerminationTime"`
 }
 
 // RenewResponse for Renew action
 type RenewResponse struct { //http://docs.oasis-open.org/wsn/b-2.xsd
-	TerminationTime TerminationTime `xml:"wsnt:TerminationTime"`
-	CurrentTime     CurrentTime     `xml:"wsnt:CurrentTime"`
+	TerminationTime TerminationTime `xml:"TerminationTime"`
+	CurrentTime     CurrentTime     `xml:"CurrentTime"`
 }
 
 // Unsubscribe action for Unsubscribe event topic
@@ -59,8 +73,10 @@ type UnsubscribeResponse struct { //http://docs.oasis-open.org/wsn/b-2.xsd
 // BUG(r) Bad AbsoluteOrRelativeTimeType type
 type CreatePullPointSubscription struct {
 	XMLName string `xml:"tev:CreatePullPointSubscription"`
-	// Filter                 FilterType                 `xml:"tev:Filter"`
-	// InitialTerminationTime AbsoluteOrRelativeTimeType `xml:"wsnt:InitialTerminationTime"`
+	// Filter optional topic and message content filter, omitted when nil
+	Filter *FilterType `xml:"tev:Filter,omitempty"`
+	// InitialTerminationTime as xs:duration (PT60S) or xs:dateTime, omitted when empty
+	InitialTerminationTime xsd.String `xml:"tev:InitialTerminationTime,omitempty"`
 	// SubscriptionPolicy     SubscriptionPolicy         `xml:"wsnt:sSubscriptionPolicy"`
 }
 
@@ -98,9 +114,9 @@ type PullMessages struct { //xmlns="http://www.onvif.org/ver10/events/wsdl"
 
 // PullMessagesResponse response type
 type PullMessagesResponse struct {
-	CurrentTime         CurrentTime         `xml:"CurrentTime"`
-	TerminationTime     TerminationTime     `xml:"TerminationTime"`
-	NotificationMessage NotificationMessage `xml:"NotificationMessage"`
+	CurrentTime         CurrentTime           `xml:"CurrentTime"`
+	TerminationTime     TerminationTime       `xml:"TerminationTime"`
+	NotificationMessage []NotificationMessage `xml:"NotificationMessage"`
 }
 
 // PullMessagesFaultResponse response type
@@ -1,6 +1,9 @@
 package event
 
 import (
+	"encoding/xml"
+	"time"
+
 	"github.com/PolarisM78/go-onvif/xsd"
 )
 
@@ -11,6 +14,17 @@ type Address xsd.String
 type CurrentTime xsd.DateTime //wsnt http://docs.oasis-open.org/wsn/b-2.xsd
 // TerminationTime alias
 type TerminationTime xsd.DateTime //wsnt http://docs.oasis-open.org/wsn/b-2.xsd
+
+// ToTime convert the current time of the device
+func (t CurrentTime) ToTime() (time.Time, error) {
+	return xsd.DateTime(t).ToTime()
+}
+
+// ToTime convert the termination time of the subscription
+func (t TerminationTime) ToTime() (time.Time, error) {
+	return xsd.DateTime(t).ToTime()
+}
+
 // FixedTopicSet alias
 type FixedTopicSet xsd.Boolean //wsnt http://docs.oasis-open.org/wsn/b-2.xsd
 
@@ -48,12 +62,41 @@ type FilterType struct {
 	MessageContent  QueryExpressionType `xml:"wsnt:MessageContent"`
 }
 
+// MarshalXML omit the expressions left empty, devices treat an empty
+// expression as matching nothing
+func (filter FilterType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if filter.TopicExpression.TopicKinds != "" {
+		topic := xml.StartElement{Name: xml.Name{Local: "wsnt:TopicExpression"}}
+		if err := e.EncodeElement(filter.TopicExpression, topic); err != nil {
+			return err
+		}
+	}
+	if filter.MessageContent.MessageKind != "" {
+		content := xml.StartElement{Name: xml.Name{Local: "wsnt:MessageContent"}}
+		if err := e.EncodeElement(filter.MessageContent, content); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
 // EndpointReference alais
 type EndpointReference EndpointReferenceType
 
 // ReferenceParametersType in ws-addr
 type ReferenceParametersType struct { //wsa https://www.w3.org/2005/08/addressing/ws-addr.xsd
-	//Here can be anyAttribute
+	Parameters []ReferenceParameter `xml:",any"`
+}
+
+// ReferenceParameter one element of the reference parameters, it must be
+// echoed as a SOAP header in every message sent to the endpoint
+type ReferenceParameter struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   string     `xml:",innerxml"`
 }
 
 // Metadata in ws-addr
@@ -91,7 +134,39 @@ type NotificationMessageHolderType struct {
 	SubscriptionReference SubscriptionReference //wsnt http://docs.oasis-open.org/wsn/b-2.xsd
 	Topic                 Topic                 `xml:"Topic"`
 	ProducerReference     ProducerReference     `xml:"ProducerReference"`
-	Message               Message               `xml:"Message"`
+	Message               MessageHolder         `xml:"Message"`
+}
+
+// MessageHolder wsnt:Message wrapping the onvif tt:Message
+type MessageHolder struct {
+	Message MessageDescription `xml:"Message"`
+}
+
+// MessageDescription tt:Message content of a notification
+type MessageDescription struct { //tt http://www.onvif.org/ver10/schema
+	UtcTime           xsd.DateTime `xml:"UtcTime,attr"`
+	PropertyOperation xsd.String   `xml:"PropertyOperation,attr"`
+	Source            ItemList     `xml:"Source"`
+	Key               ItemList     `xml:"Key"`
+	Data              ItemList     `xml:"Data"`
+}
+
+// ItemList tt:ItemList of a message
+type ItemList struct { //tt http://www.onvif.org/ver10/schema
+	SimpleItem  []SimpleItem  `xml:"SimpleItem"`
+	ElementItem []ElementItem `xml:"ElementItem"`
+}
+
+// SimpleItem name/value pair of a message
+type SimpleItem struct {
+	Name  xsd.String        `xml:"Name,attr"`
+	Value xsd.AnySimpleType `xml:"Value,attr"`
+}
+
+// ElementItem complex value of a message, the content is kept as raw xml
+type ElementItem struct {
+	Name  xsd.String `xml:"Name,attr"`
+	Inner string     `xml:",innerxml"`
 }
 
 // NotificationMessage Alias
@@ -62,3 +62,80 @@ type SetCurrentPreset struct {
 	VideoSourceToken onvif.ReferenceToken `xml:"timg:VideoSourceToken"`
 	PresetToken      onvif.ReferenceToken `xml:"timg:PresetToken"`
 }
+
+// Capabilities of the imaging service
+type Capabilities struct {
+	ImageStabilization bool `xml:"ImageStabilization,attr"`
+	Presets            bool `xml:"Presets,attr"`
+	AdaptablePreset    bool `xml:"AdaptablePreset,attr"`
+}
+
+type GetServiceCapabilitiesResponse struct {
+	Capabilities Capabilities
+}
+
+// ImagingPreset predefined imaging settings of a video source
+type ImagingPreset struct {
+	Token onvif.ReferenceToken `xml:"token,attr"`
+	Type  string               `xml:"type,attr"`
+	Name  onvif.Name           `xml:"Name"`
+}
+
+type GetPresetsResponse struct {
+	Preset []ImagingPreset `xml:"Preset"`
+}
+
+type GetCurrentPresetResponse struct {
+	Preset ImagingPreset `xml:"Preset"`
+}
+
+type SetCurrentPresetResponse struct {
+}
+
+type GetOptionsResponse struct {
+	ImagingOptions onvif.ImagingOptions20 `xml:"ImagingOptions"`
+}
+
+type GetMoveOptionsResponse struct {
+	MoveOptions onvif.MoveOptions20 `xml:"MoveOptions"`
+}
+
+type SetImagingSettingsResponse struct {
+}
+
+// SetImagingSettingsExtension SetImagingSettings carrying only the extension
+// elements, every other setting of the video source is left unchanged
+type SetImagingSettingsExtension struct {
+	XMLName          string                  `xml:"timg:SetImagingSettings"`
+	VideoSourceToken onvif.ReferenceToken    `xml:"timg:VideoSourceToken"`
+	ImagingSettings  ImagingExtensionSetting `xml:"timg:ImagingSettings"`
+	ForcePersistence xsd.Boolean             `xml:"timg:ForcePersistence"`
+}
+
+type SetImagingSettingsExtensionResponse struct {
+}
+
+// ImagingExtensionSetting ImagingSettings20 reduced to its extension, nil
+// members are omitted
+type ImagingExtensionSetting struct {
+	Extension ImagingExtension `xml:"onvif:Extension"`
+}
+
+type ImagingExtension struct {
+	ImageStabilization *ModeLevel         `xml:"onvif:ImageStabilization,omitempty"`
+	Extension          *ImagingExtension2 `xml:"onvif:Extension,omitempty"`
+}
+
+type ImagingExtension2 struct {
+	Extension *ImagingExtension3 `xml:"onvif:Extension,omitempty"`
+}
+
+type ImagingExtension3 struct {
+	Defogging *ModeLevel `xml:"onvif:Defogging,omitempty"`
+}
+
+// ModeLevel mode and optional level of ImageStabilization and Defogging
+type ModeLevel struct {
+	Mode  string   `xml:"onvif:Mode"`
+	Level *float64 `xml:"onvif:Level,omitempty"`
+}
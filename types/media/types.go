@@ -396,7 +396,7 @@ type GetCompatibleVideoEncoderConfigurations struct {
 }
 
 type GetCompatibleVideoEncoderConfigurationsResponse struct {
-	Configurations onvif.VideoEncoderConfiguration
+	Configurations []onvif.VideoEncoderConfiguration `xml:"Configurations"`
 }
 
 type GetCompatibleVideoSourceConfigurations struct {
@@ -414,7 +414,7 @@ type GetCompatibleAudioEncoderConfigurations struct {
 }
 
 type GetCompatibleAudioEncoderConfigurationsResponse struct {
-	Configurations onvif.AudioEncoderConfiguration
+	Configurations []onvif.AudioEncoderConfiguration `xml:"Configurations"`
 }
 
 type GetCompatibleAudioSourceConfigurations struct {
@@ -546,8 +546,8 @@ type GetVideoSourceConfigurationOptionsResponse struct {
 
 type GetVideoEncoderConfigurationOptions struct {
 	XMLName            string               `xml:"trt:GetVideoEncoderConfigurationOptions"`
-	ProfileToken       onvif.ReferenceToken `xml:"trt:ProfileToken"`
-	ConfigurationToken onvif.ReferenceToken `xml:"trt:ConfigurationToken"`
+	ProfileToken       onvif.ReferenceToken `xml:"trt:ProfileToken,omitempty"`
+	ConfigurationToken onvif.ReferenceToken `xml:"trt:ConfigurationToken,omitempty"`
 }
 
 type GetVideoEncoderConfigurationOptionsResponse struct {
@@ -665,7 +665,7 @@ type GetVideoSourceModes struct {
 }
 
 type GetVideoSourceModesResponse struct {
-	VideoSourceModes onvif.VideoSourceMode
+	VideoSourceModes []onvif.VideoSourceMode
 }
 
 type SetVideoSourceMode struct {
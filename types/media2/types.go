@@ -0,0 +1,98 @@
+package media2
+
+import (
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Media2 service types, only the operations the package uses on top of the
+// media service
+
+type GetVideoSourceModes struct {
+	XMLName          string               `xml:"tr2:GetVideoSourceModes"`
+	VideoSourceToken onvif.ReferenceToken `xml:"tr2:VideoSourceToken"`
+}
+
+type GetVideoSourceModesResponse struct {
+	VideoSourceModes []onvif.VideoSourceMode
+}
+
+type SetVideoSourceMode struct {
+	XMLName              string               `xml:"tr2:SetVideoSourceMode"`
+	VideoSourceToken     onvif.ReferenceToken `xml:"tr2:VideoSourceToken"`
+	VideoSourceModeToken onvif.ReferenceToken `xml:"tr2:VideoSourceModeToken"`
+}
+
+type SetVideoSourceModeResponse struct {
+	Reboot bool
+}
+
+type GetProfiles struct {
+	XMLName string               `xml:"tr2:GetProfiles"`
+	Token   onvif.ReferenceToken `xml:"tr2:Token,omitempty"`
+	Type    []string             `xml:"tr2:Type"`
+}
+
+type GetProfilesResponse struct {
+	Profiles []MediaProfile
+}
+
+type MediaProfile struct {
+	Token          onvif.ReferenceToken `xml:"token,attr"`
+	Fixed          bool                 `xml:"fixed,attr"`
+	Name           onvif.Name
+	Configurations ConfigurationSet
+}
+
+// ConfigurationSet configurations of a media2 profile, only the video
+// encoder is decoded
+type ConfigurationSet struct {
+	VideoEncoder *VideoEncoder2Configuration
+}
+
+type VideoEncoder2Configuration struct {
+	Token      onvif.ReferenceToken `xml:"token,attr"`
+	Name       onvif.Name
+	Encoding   string
+	Resolution onvif.VideoResolution
+	Quality    float64
+}
+
+type GetStreamUri struct {
+	XMLName      string               `xml:"tr2:GetStreamUri"`
+	Protocol     string               `xml:"tr2:Protocol"`
+	ProfileToken onvif.ReferenceToken `xml:"tr2:ProfileToken"`
+}
+
+type GetStreamUriResponse struct {
+	Uri string
+}
+
+type GetVideoEncoderConfigurationOptions struct {
+	XMLName            string               `xml:"tr2:GetVideoEncoderConfigurationOptions"`
+	ConfigurationToken onvif.ReferenceToken `xml:"tr2:ConfigurationToken,omitempty"`
+	ProfileToken       onvif.ReferenceToken `xml:"tr2:ProfileToken,omitempty"`
+}
+
+type GetVideoEncoderConfigurationOptionsResponse struct {
+	Options []VideoEncoder2ConfigurationOptions
+}
+
+// VideoEncoder2ConfigurationOptions options of one encoding, Encoding being
+// a media subtype name such as JPEG, H264 or H265
+type VideoEncoder2ConfigurationOptions struct {
+	GovLengthRange           string `xml:"GovLengthRange,attr"`
+	FrameRatesSupported      string `xml:"FrameRatesSupported,attr"`
+	ProfilesSupported        string `xml:"ProfilesSupported,attr"`
+	ConstantBitRateSupported bool   `xml:"ConstantBitRateSupported,attr"`
+	Encoding                 string
+	ResolutionsAvailable     []onvif.VideoResolution
+}
+
+type GetSnapshotUri struct {
+	XMLName      string               `xml:"tr2:GetSnapshotUri"`
+	ProfileToken onvif.ReferenceToken `xml:"tr2:ProfileToken"`
+}
+
+type GetSnapshotUriResponse struct {
+	Uri string
+}
@@ -91,14 +91,14 @@ type GetPresets struct {
 }
 
 type GetPresetsResponse struct {
-	Preset onvif.PTZPreset
+	Preset []onvif.PTZPreset
 }
 
 type SetPreset struct {
 	XMLName      string               `xml:"tptz:SetPreset"`
 	ProfileToken onvif.ReferenceToken `xml:"tptz:ProfileToken"`
-	PresetName   xsd.String           `xml:"tptz:PresetName"`
-	PresetToken  onvif.ReferenceToken `xml:"tptz:PresetToken"`
+	PresetName   xsd.String           `xml:"tptz:PresetName,omitempty"`
+	PresetToken  onvif.ReferenceToken `xml:"tptz:PresetToken,omitempty"`
 }
 
 type SetPresetResponse struct {
@@ -118,7 +118,7 @@ type GotoPreset struct {
 	XMLName      string               `xml:"tptz:GotoPreset"`
 	ProfileToken onvif.ReferenceToken `xml:"tptz:ProfileToken"`
 	PresetToken  onvif.ReferenceToken `xml:"tptz:PresetToken"`
-	Speed        onvif.PTZSpeed       `xml:"tptz:Speed"`
+	Speed        *onvif.PTZSpeed      `xml:"tptz:Speed,omitempty"`
 }
 
 type GotoPresetResponse struct {
@@ -127,7 +127,7 @@ type GotoPresetResponse struct {
 type GotoHomePosition struct {
 	XMLName      string               `xml:"tptz:GotoHomePosition"`
 	ProfileToken onvif.ReferenceToken `xml:"tptz:ProfileToken"`
-	Speed        onvif.PTZSpeed       `xml:"tptz:Speed"`
+	Speed        *onvif.PTZSpeed      `xml:"tptz:Speed,omitempty"`
 }
 
 type GotoHomePositionResponse struct {
@@ -145,7 +145,7 @@ type ContinuousMove struct {
 	XMLName      string               `xml:"tptz:ContinuousMove"`
 	ProfileToken onvif.ReferenceToken `xml:"tptz:ProfileToken"`
 	Velocity     onvif.PTZSpeed       `xml:"tptz:Velocity"`
-	Timeout      xsd.Duration         `xml:"tptz:Timeout"`
+	Timeout      *xsd.Duration        `xml:"tptz:Timeout,omitempty"`
 }
 
 type ContinuousMove3 struct {
@@ -168,7 +168,7 @@ type RelativeMove struct {
 	XMLName      string               `xml:"tptz:RelativeMove"`
 	ProfileToken onvif.ReferenceToken `xml:"tptz:ProfileToken"`
 	Translation  onvif.PTZVector      `xml:"tptz:Translation"`
-	Speed        onvif.PTZSpeed       `xml:"tptz:Speed"`
+	Speed        *onvif.PTZSpeed      `xml:"tptz:Speed,omitempty"`
 }
 
 type RelativeMoveResponse struct {
@@ -187,7 +187,7 @@ type AbsoluteMove struct {
 	XMLName      string               `xml:"tptz:AbsoluteMove"`
 	ProfileToken onvif.ReferenceToken `xml:"tptz:ProfileToken"`
 	Position     onvif.PTZVector      `xml:"tptz:Position"`
-	Speed        onvif.PTZSpeed       `xml:"tptz:Speed"`
+	Speed        *onvif.PTZSpeed      `xml:"tptz:Speed,omitempty"`
 }
 
 type AbsoluteMoveResponse struct {
@@ -287,5 +287,5 @@ type GetCompatibleConfigurations struct {
 }
 
 type GetCompatibleConfigurationsResponse struct {
-	PTZConfiguration onvif.PTZConfiguration
+	PTZConfiguration []onvif.PTZConfiguration `xml:"PTZConfiguration"`
 }
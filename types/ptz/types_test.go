@@ -0,0 +1,69 @@
+package ptz
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+func TestGetPresetsResponseUnmarshal(t *testing.T) {
+	data := `<tptz:GetPresetsResponse xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl" xmlns:tt="http://www.onvif.org/ver10/schema">
+<tptz:Preset token="1"><tt:Name>door</tt:Name></tptz:Preset>
+<tptz:Preset token="2"><tt:Name>gate</tt:Name></tptz:Preset>
+<tptz:Preset token="3"></tptz:Preset>
+</tptz:GetPresetsResponse>`
+	response := GetPresetsResponse{}
+	if err := xml.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Preset) != 3 || response.Preset[0].Token != "1" || response.Preset[1].Name != "gate" || response.Preset[2].Name != "" {
+		t.Fatalf("%+v", response.Preset)
+	}
+}
+
+func TestGotoPresetMarshal(t *testing.T) {
+	request := GotoPreset{ProfileToken: "Profile_1", PresetToken: "2"}
+	output, err := xml.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	/* 未指定速度时不发送Speed,由设备使用默认速度 */
+	if strings.Contains(string(output), "Speed") {
+		t.Fatalf("speed sent without being set: %s", output)
+	}
+	request.Speed = &onvif.PTZSpeed{PanTilt: onvif.Vector2D{X: 0.5, Y: -0.5}}
+	if output, err = xml.Marshal(request); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), `<tptz:Speed><PanTilt x="0.5" y="-0.5"></PanTilt>`) {
+		t.Fatalf("speed not sent: %s", output)
+	}
+}
+
+func TestGetPresetsResponseRoundTrip(t *testing.T) {
+	response := GetPresetsResponse{Preset: []onvif.PTZPreset{{Token: "1", Name: "door"}, {Token: "2", Name: "gate"}}}
+	output, err := xml.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := GetPresetsResponse{}
+	if err := xml.Unmarshal(output, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Preset) != 2 || decoded.Preset[0] != response.Preset[0] || decoded.Preset[1] != response.Preset[1] {
+		t.Fatalf("%s decoded as %+v", output, decoded)
+	}
+}
+
+func TestSetPresetMarshal(t *testing.T) {
+	output, err := xml.Marshal(SetPreset{ProfileToken: "Profile_1", PresetName: "door"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	/* 新建预置位时不能发送空的PresetToken */
+	if strings.Contains(string(output), "PresetToken") || !strings.Contains(string(output), "<tptz:PresetName>door</tptz:PresetName>") {
+		t.Fatalf("%s", output)
+	}
+}
@@ -0,0 +1,81 @@
+package recording
+
+import (
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Modes of a recording job
+const (
+	ModeIdle   = "Idle"
+	ModeActive = "Active"
+)
+
+type GetRecordingJobs struct {
+	XMLName string `xml:"trc:GetRecordingJobs"`
+}
+
+type GetRecordingJobsResponse struct {
+	JobItem []JobItem `xml:"JobItem"`
+}
+
+// JobItem recording job of GetRecordingJobs
+type JobItem struct {
+	JobToken         onvif.ReferenceToken `xml:"JobToken"`
+	JobConfiguration JobConfiguration     `xml:"JobConfiguration"`
+}
+
+// JobConfiguration configuration of a recording job, Mode is Idle or Active
+type JobConfiguration struct {
+	ScheduleToken  string               `xml:"ScheduleToken"`
+	RecordingToken onvif.ReferenceToken `xml:"RecordingToken"`
+	Mode           string               `xml:"Mode"`
+	Priority       int                  `xml:"Priority"`
+}
+
+type SetRecordingJobMode struct {
+	XMLName  string               `xml:"trc:SetRecordingJobMode"`
+	JobToken onvif.ReferenceToken `xml:"trc:JobToken"`
+	Mode     string               `xml:"trc:Mode"`
+}
+
+type SetRecordingJobModeResponse struct {
+}
+
+type GetRecordingJobState struct {
+	XMLName  string               `xml:"trc:GetRecordingJobState"`
+	JobToken onvif.ReferenceToken `xml:"trc:JobToken"`
+}
+
+type GetRecordingJobStateResponse struct {
+	State JobState `xml:"State"`
+}
+
+// JobState state of a recording job, State is Idle, PartiallyActive, Active
+// or Error
+type JobState struct {
+	RecordingToken onvif.ReferenceToken `xml:"RecordingToken"`
+	State          string               `xml:"State"`
+}
+
+type GetServiceCapabilities struct {
+	XMLName string `xml:"trc:GetServiceCapabilities"`
+}
+
+// Capabilities of the recording service, Encoding lists the supported
+// encodings separated by spaces
+type Capabilities struct {
+	DynamicRecordings          bool    `xml:"DynamicRecordings,attr"`
+	DynamicTracks              bool    `xml:"DynamicTracks,attr"`
+	Encoding                   string  `xml:"Encoding,attr"`
+	MaxRate                    float64 `xml:"MaxRate,attr"`
+	MaxTotalRate               float64 `xml:"MaxTotalRate,attr"`
+	MaxRecordings              float64 `xml:"MaxRecordings,attr"`
+	MaxRecordingJobs           int     `xml:"MaxRecordingJobs,attr"`
+	Options                    bool    `xml:"Options,attr"`
+	MetadataRecording          bool    `xml:"MetadataRecording,attr"`
+	SupportedExportFileFormats string  `xml:"SupportedExportFileFormats,attr"`
+}
+
+type GetServiceCapabilitiesResponse struct {
+	Capabilities Capabilities
+}
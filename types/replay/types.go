@@ -0,0 +1,18 @@
+package replay
+
+type GetServiceCapabilities struct {
+	XMLName string `xml:"trp:GetServiceCapabilities"`
+}
+
+// Capabilities of the replay service, SessionTimeoutRange holds the minimum
+// and maximum session timeouts in seconds separated by a space
+type Capabilities struct {
+	ReversePlayback     bool   `xml:"ReversePlayback,attr"`
+	SessionTimeoutRange string `xml:"SessionTimeoutRange,attr"`
+	RTP_RTSP_TCP        bool   `xml:"RTP_RTSP_TCP,attr"`
+	RTSPWebSocketUri    string `xml:"RTSPWebSocketUri,attr"`
+}
+
+type GetServiceCapabilitiesResponse struct {
+	Capabilities Capabilities
+}
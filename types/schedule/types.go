@@ -0,0 +1,112 @@
+package schedule
+
+import (
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// Capabilities of the schedule service
+type Capabilities struct {
+	MaxLimit                     int  `xml:"MaxLimit,attr"`
+	MaxSchedules                 int  `xml:"MaxSchedules,attr"`
+	MaxTimePeriodsPerDay         int  `xml:"MaxTimePeriodsPerDay,attr"`
+	MaxSpecialDayGroups          int  `xml:"MaxSpecialDayGroups,attr"`
+	MaxDaysInSpecialDayGroup     int  `xml:"MaxDaysInSpecialDayGroup,attr"`
+	MaxSpecialDaysSchedules      int  `xml:"MaxSpecialDaysSchedules,attr"`
+	ExtendedRecurrenceSupported  bool `xml:"ExtendedRecurrenceSupported,attr"`
+	SpecialDaysSupported         bool `xml:"SpecialDaysSupported,attr"`
+	StateReportingSupported      bool `xml:"StateReportingSupported,attr"`
+	ClientSuppliedTokenSupported bool `xml:"ClientSuppliedTokenSupported,attr"`
+}
+
+// TimePeriod time range of a special day, Until is exclusive
+type TimePeriod struct {
+	From  xsd.String `xml:"tsc:From"`
+	Until xsd.String `xml:"tsc:Until,omitempty"`
+}
+
+// SpecialDaysSchedule periods applied instead of the standard schedule on
+// the days of a special day group
+type SpecialDaysSchedule struct {
+	GroupToken onvif.ReferenceToken `xml:"tsc:GroupToken"`
+	TimeRange  []TimePeriod         `xml:"tsc:TimeRange,omitempty"`
+}
+
+// Schedule as sent to CreateSchedule and ModifySchedule, Standard is an
+// iCalendar document
+type Schedule struct {
+	Token       onvif.ReferenceToken  `xml:"token,attr,omitempty"`
+	Name        onvif.Name            `xml:"tsc:Name"`
+	Description xsd.String            `xml:"tsc:Description,omitempty"`
+	Standard    xsd.String            `xml:"tsc:Standard"`
+	SpecialDays []SpecialDaysSchedule `xml:"tsc:SpecialDays,omitempty"`
+}
+
+// ScheduleInfo schedule as returned by the device, only parsed from responses
+type ScheduleInfo struct {
+	Token       onvif.ReferenceToken `xml:"token,attr"`
+	Name        onvif.Name           `xml:"Name"`
+	Description xsd.String           `xml:"Description"`
+	Standard    xsd.String           `xml:"Standard"`
+}
+
+// SpecialDayGroup group of days described by an iCalendar document
+type SpecialDayGroup struct {
+	Token       onvif.ReferenceToken `xml:"token,attr,omitempty"`
+	Name        onvif.Name           `xml:"tsc:Name"`
+	Description xsd.String           `xml:"tsc:Description,omitempty"`
+	Days        xsd.String           `xml:"tsc:Days"`
+}
+
+type GetServiceCapabilities struct {
+	XMLName string `xml:"tsc:GetServiceCapabilities"`
+}
+
+type GetServiceCapabilitiesResponse struct {
+	Capabilities Capabilities
+}
+
+type GetScheduleList struct {
+	XMLName        string     `xml:"tsc:GetScheduleList"`
+	Limit          int        `xml:"tsc:Limit,omitempty"`
+	StartReference xsd.String `xml:"tsc:StartReference,omitempty"`
+}
+
+type GetScheduleListResponse struct {
+	NextStartReference xsd.String     `xml:"NextStartReference"`
+	Schedule           []ScheduleInfo `xml:"Schedule"`
+}
+
+type CreateSchedule struct {
+	XMLName  string   `xml:"tsc:CreateSchedule"`
+	Schedule Schedule `xml:"tsc:Schedule"`
+}
+
+type CreateScheduleResponse struct {
+	Token onvif.ReferenceToken `xml:"Token"`
+}
+
+type ModifySchedule struct {
+	XMLName  string   `xml:"tsc:ModifySchedule"`
+	Schedule Schedule `xml:"tsc:Schedule"`
+}
+
+type ModifyScheduleResponse struct {
+}
+
+type DeleteSchedule struct {
+	XMLName string               `xml:"tsc:DeleteSchedule"`
+	Token   onvif.ReferenceToken `xml:"tsc:Token"`
+}
+
+type DeleteScheduleResponse struct {
+}
+
+type CreateSpecialDayGroup struct {
+	XMLName         string          `xml:"tsc:CreateSpecialDayGroup"`
+	SpecialDayGroup SpecialDayGroup `xml:"tsc:SpecialDayGroup"`
+}
+
+type CreateSpecialDayGroupResponse struct {
+	Token onvif.ReferenceToken `xml:"Token"`
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+type GetServiceCapabilities struct {
+	XMLName string `xml:"tse:GetServiceCapabilities"`
+}
+
+// Capabilities of the search service
+type Capabilities struct {
+	MetadataSearch     bool `xml:"MetadataSearch,attr"`
+	GeneralStartEvents bool `xml:"GeneralStartEvents,attr"`
+}
+
+type GetServiceCapabilitiesResponse struct {
+	Capabilities Capabilities
+}
+
+// Search states of GetRecordingSearchResults
+const (
+	StateQueued    = "Queued"
+	StateSearching = "Searching"
+	StateCompleted = "Completed"
+	StateUnknown   = "Unknown"
+)
+
+// SearchScope scope of a search, RecordingInformationFilter an XPath filter
+// of the recordings, every recording when empty
+type SearchScope struct {
+	IncludedRecordings         []onvif.ReferenceToken `xml:"tt:IncludedRecordings,omitempty"`
+	RecordingInformationFilter string                 `xml:"tt:RecordingInformationFilter,omitempty"`
+}
+
+type FindRecordings struct {
+	XMLName       string      `xml:"tse:FindRecordings"`
+	Scope         SearchScope `xml:"tse:Scope"`
+	MaxMatches    int         `xml:"tse:MaxMatches,omitempty"`
+	KeepAliveTime string      `xml:"tse:KeepAliveTime"`
+}
+
+type FindRecordingsResponse struct {
+	SearchToken string `xml:"SearchToken"`
+}
+
+type GetRecordingSearchResults struct {
+	XMLName     string `xml:"tse:GetRecordingSearchResults"`
+	SearchToken string `xml:"tse:SearchToken"`
+	MinResults  int    `xml:"tse:MinResults,omitempty"`
+	MaxResults  int    `xml:"tse:MaxResults,omitempty"`
+	WaitTime    string `xml:"tse:WaitTime,omitempty"`
+}
+
+type GetRecordingSearchResultsResponse struct {
+	ResultList FindRecordingResultList `xml:"ResultList"`
+}
+
+// FindRecordingResultList results of a recording search, SearchState is one
+// of the search states
+type FindRecordingResultList struct {
+	SearchState          string                 `xml:"SearchState"`
+	RecordingInformation []RecordingInformation `xml:"RecordingInformation"`
+}
+
+// RecordingInformation recording found by a search, the times in the
+// xs:dateTime form sent by the device
+type RecordingInformation struct {
+	RecordingToken    onvif.ReferenceToken       `xml:"RecordingToken"`
+	Source            RecordingSourceInformation `xml:"Source"`
+	EarliestRecording string                     `xml:"EarliestRecording"`
+	LatestRecording   string                     `xml:"LatestRecording"`
+	Content           string                     `xml:"Content"`
+	Track             []TrackInformation         `xml:"Track"`
+	RecordingStatus   string                     `xml:"RecordingStatus"`
+}
+
+// RecordingSourceInformation source of a recording
+type RecordingSourceInformation struct {
+	SourceId    string `xml:"SourceId"`
+	Name        string `xml:"Name"`
+	Location    string `xml:"Location"`
+	Description string `xml:"Description"`
+	Address     string `xml:"Address"`
+}
+
+// TrackInformation track of a recording, TrackType is Video, Audio, Metadata
+// or Extended
+type TrackInformation struct {
+	TrackToken  onvif.ReferenceToken `xml:"TrackToken"`
+	TrackType   string               `xml:"TrackType"`
+	Description string               `xml:"Description"`
+	DataFrom    string               `xml:"DataFrom"`
+	DataTo      string               `xml:"DataTo"`
+}
+
+type EndSearch struct {
+	XMLName     string `xml:"tse:EndSearch"`
+	SearchToken string `xml:"tse:SearchToken"`
+}
+
+type EndSearchResponse struct {
+	Endpoint string `xml:"Endpoint"`
+}
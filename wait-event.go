@@ -0,0 +1,118 @@
+package onvif
+
+import (
+	"context"
+	"time"
+)
+
+func (dev *Device) attachEngine(engine *EventEngine) {
+	if dev.capabilities == nil {
+		return
+	}
+	dev.capabilities.engineMutex.Lock()
+	dev.capabilities.engine = engine
+	dev.capabilities.engineMutex.Unlock()
+}
+
+func (dev *Device) detachEngine(engine *EventEngine) {
+	if dev.capabilities == nil {
+		return
+	}
+	dev.capabilities.engineMutex.Lock()
+	if dev.capabilities.engine == engine {
+		dev.capabilities.engine = nil
+	}
+	dev.capabilities.engineMutex.Unlock()
+}
+
+// runningEngine return the running engine pulling the events of the device
+func (dev *Device) runningEngine() *EventEngine {
+	if dev.capabilities == nil {
+		return nil
+	}
+	dev.capabilities.engineMutex.Lock()
+	engine := dev.capabilities.engine
+	dev.capabilities.engineMutex.Unlock()
+	if engine == nil {
+		return nil
+	}
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	if !engine.running {
+		return nil
+	}
+	return engine
+}
+
+// WaitForEvent block until the device sends an event under topic, given
+// without namespace prefix as in TopicFilter, for which predicate holds, nil
+// accepting any event. The events are taken from the running EventEngine
+// pulling the device if any, otherwise a pull point subscription is created
+// for the wait and cancelled afterwards. ctx bounds the wait.
+//
+//	ev, err := dev.WaitForEvent(ctx, "RuleEngine/CellMotionDetector/Motion", func(ev onvif.Event) bool {
+//		return ev.Data["IsMotion"] == "true"
+//	})
+func (dev *Device) WaitForEvent(ctx context.Context, topic string, predicate func(Event) bool) (Event, error) {
+	if engine := dev.runningEngine(); engine != nil {
+		return engine.WaitForEvent(ctx, dev, topic, predicate)
+	}
+	matchTopic := TopicFilter(topic)
+	match := func(ev Event) bool {
+		return matchTopic(ev) && (predicate == nil || predicate(ev))
+	}
+
+	termination := time.Minute
+	response, err := dev.CreatePullPointSubscription(ctx, nil, termination)
+	if err != nil {
+		return Event{}, err
+	}
+	reference := response.SubscriptionReference
+	defer func() {
+		/* ctx可能已结束,取消订阅使用独立超时 */
+		cancelCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		dev.Unsubscribe(cancelCtx, reference)
+	}()
+	renewed := time.Now()
+	for {
+		if time.Since(renewed) > termination/2 {
+			if _, err := dev.Renew(ctx, reference, termination); err != nil {
+				return Event{}, err
+			}
+			renewed = time.Now()
+		}
+		messages, err := dev.PullMessages(ctx, reference, 5*time.Second, 32)
+		if err != nil {
+			if ctx.Err() != nil {
+				return Event{}, ctx.Err()
+			}
+			return Event{}, err
+		}
+		for _, message := range messages.NotificationMessage {
+			if ev := NewEvent(dev.Params.Ipddr, message); match(ev) {
+				return ev, nil
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return Event{}, err
+		}
+	}
+}
+
+// WaitForEvent block until dev sends an event under topic for which
+// predicate holds, reusing the subscription of the engine on the device; see
+// Device.WaitForEvent
+func (engine *EventEngine) WaitForEvent(ctx context.Context, dev *Device, topic string, predicate func(Event) bool) (Event, error) {
+	matchTopic := TopicFilter(topic)
+	events, cancel := engine.Subscribe(dev, func(ev Event) bool {
+		return matchTopic(ev) && (predicate == nil || predicate(ev))
+	}, 1)
+	defer cancel()
+	select {
+	case ev := <-events:
+		return ev, nil
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
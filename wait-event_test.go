@@ -0,0 +1,43 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitForEvent(t *testing.T) {
+	fake := &eventDevice{messages: 1}
+	dev := newEventDevice(t, fake)
+	ctx := context.Background()
+	ev, err := dev.WaitForEvent(ctx, "VideoSource/MotionAlarm", func(ev Event) bool { return ev.Data["Sequence"] == "3" })
+	if err != nil || ev.Data["Sequence"] != "3" || ev.Source["Source"] != "vs0" {
+		t.Fatalf("event %+v, %v", ev, err)
+	}
+	/* 等待结束后取消为等待建立的订阅 */
+	if open := fake.openSubscriptions(); open != 0 {
+		t.Fatalf("%d subscriptions left open", open)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := dev.WaitForEvent(timeout, "RuleEngine", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error %v", err)
+	}
+	if open := fake.openSubscriptions(); open != 0 {
+		t.Fatalf("%d subscriptions left open after the deadline", open)
+	}
+
+	/* 引擎运行时复用其订阅 */
+	created := fake.count(&fake.created) + 1
+	engine := testEngine(1)
+	engine.Add(dev)
+	runEngine(t, engine)
+	eventually(t, "the engine subscription", func() bool { return fake.count(&fake.created) == created })
+	if _, err := dev.WaitForEvent(ctx, "VideoSource", nil); err != nil {
+		t.Fatal(err)
+	}
+	if fake.count(&fake.created) != created {
+		t.Fatalf("subscription created next to the engine: %d then %d", created, fake.count(&fake.created))
+	}
+}
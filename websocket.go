@@ -0,0 +1,144 @@
+package onvif
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes of RFC 6455
+const (
+	websocketText  = 0x1
+	websocketClose = 0x8
+	websocketPing  = 0x9
+	websocketPong  = 0xa
+)
+
+// websocketGUID appended to the key of the client to compute the accept key
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// websocketConn server side of a WebSocket connection, enough to push text
+// messages to a browser
+type websocketConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	mutex  sync.Mutex
+	writer *bufio.Writer
+}
+
+// isWebSocket report whether r asks to upgrade to WebSocket
+func isWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// upgradeWebSocket answer the handshake and take over the connection
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("unsupported websocket handshake")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection can not be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &websocketConn{conn: conn, reader: rw.Reader, writer: rw.Writer}, nil
+}
+
+// WriteText send a text message
+func (ws *websocketConn) WriteText(message []byte) error {
+	return ws.writeFrame(websocketText, message)
+}
+
+func (ws *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ws.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	/* 服务端发送的帧不加掩码 */
+	ws.writer.WriteByte(0x80 | opcode)
+	switch length := len(payload); {
+	case length < 126:
+		ws.writer.WriteByte(byte(length))
+	case length <= 0xffff:
+		ws.writer.WriteByte(126)
+		binary.Write(ws.writer, binary.BigEndian, uint16(length))
+	default:
+		ws.writer.WriteByte(127)
+		binary.Write(ws.writer, binary.BigEndian, uint64(length))
+	}
+	ws.writer.Write(payload)
+	return ws.writer.Flush()
+}
+
+// readLoop answer the control frames of the client and discard its
+// messages, it returns when the client closes the connection
+func (ws *websocketConn) readLoop() {
+	for {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(ws.reader, header); err != nil {
+			return
+		}
+		opcode, length := header[0]&0x0f, uint64(header[1]&0x7f)
+		switch length {
+		case 126:
+			var extended uint16
+			if binary.Read(ws.reader, binary.BigEndian, &extended) != nil {
+				return
+			}
+			length = uint64(extended)
+		case 127:
+			if binary.Read(ws.reader, binary.BigEndian, &length) != nil {
+				return
+			}
+		}
+		/* 客户端消息只用于保活,过大的帧视为异常 */
+		if length > 1<<16 {
+			return
+		}
+		mask := make([]byte, 4)
+		if header[1]&0x80 != 0 {
+			if _, err := io.ReadFull(ws.reader, mask); err != nil {
+				return
+			}
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(ws.reader, payload); err != nil {
+			return
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+		switch opcode {
+		case websocketPing:
+			ws.writeFrame(websocketPong, payload)
+		case websocketClose:
+			ws.writeFrame(websocketClose, payload)
+			return
+		}
+	}
+}
+
+// Close close the connection
+func (ws *websocketConn) Close() error {
+	return ws.conn.Close()
+}
@@ -0,0 +1,139 @@
+package onvif
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PolarisM78/go-onvif/types/device"
+	"github.com/PolarisM78/go-onvif/xsd"
+	"github.com/PolarisM78/go-onvif/xsd/onvif"
+)
+
+// ifTypeIEEE80211 IANA interface type of the wireless interfaces
+const ifTypeIEEE80211 = 71
+
+// SSIDOf return the SSID called name, encoded in hexadecimal as ONVIF
+// requires
+func SSIDOf(name string) onvif.Dot11SSIDType {
+	return onvif.Dot11SSIDType(strings.ToUpper(hex.EncodeToString([]byte(name))))
+}
+
+// SSIDName return the name of an SSID reported by a device; some devices
+// report the name instead of its hexadecimal encoding, it is then returned
+// unchanged
+func SSIDName(ssid onvif.Dot11SSIDType) string {
+	name, err := hex.DecodeString(string(ssid))
+	if err != nil || !utf8.Valid(name) {
+		return string(ssid)
+	}
+	return string(name)
+}
+
+// Dot11PSKConfiguration return the configuration joining the infrastructure
+// network ssid secured by WPA-PSK with passphrase
+func Dot11PSKConfiguration(ssid, passphrase string) onvif.Dot11Configuration {
+	return onvif.Dot11Configuration{
+		SSID:  SSIDOf(ssid),
+		Mode:  onvif.Dot11StationModeInfrastructure,
+		Alias: onvif.Name(ssid),
+		Security: onvif.Dot11SecurityConfiguration{
+			Mode:      onvif.Dot11SecurityModePSK,
+			Algorithm: onvif.Dot11CipherAny,
+			PSK:       &onvif.Dot11PSKSet{Passphrase: onvif.Dot11PSKPassphrase(passphrase)},
+		},
+	}
+}
+
+// requireDot11 fail with a NotSupportedError when the device does not
+// advertise the wireless configuration
+func (dev *Device) requireDot11(ctx context.Context) error {
+	capabilities, err := dev.ServiceCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	if capabilities.Device == nil || !bool(capabilities.Device.Network.Dot11Configuration) {
+		return &NotSupportedError{Service: "device", Capability: "Dot11Configuration"}
+	}
+	return nil
+}
+
+// Dot11Capabilities return the wireless capabilities of the device, a
+// NotSupportedError is returned when it has no wireless configuration
+func (dev *Device) Dot11Capabilities(ctx context.Context) (onvif.Dot11Capabilities, error) {
+	if err := dev.requireDot11(ctx); err != nil {
+		return onvif.Dot11Capabilities{}, err
+	}
+	response := device.GetDot11CapabilitiesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetDot11Capabilities{}, &response, ""); err != nil {
+		return onvif.Dot11Capabilities{}, err
+	}
+	return response.Capabilities, nil
+}
+
+// WirelessInterfaces return the wireless network interfaces of the device
+func (dev *Device) WirelessInterfaces(ctx context.Context) ([]onvif.NetworkInterface, error) {
+	response := device.GetNetworkInterfacesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, device.GetNetworkInterfaces{}, &response, ""); err != nil {
+		return nil, err
+	}
+	var wireless []onvif.NetworkInterface
+	for _, networkInterface := range response.NetworkInterfaces {
+		extension := networkInterface.Extension
+		if extension.InterfaceType == ifTypeIEEE80211 || len(extension.Dot11) > 0 {
+			wireless = append(wireless, networkInterface)
+		}
+	}
+	return wireless, nil
+}
+
+// Dot11Status return the status of the wireless interface, e.g. the network
+// it is associated to and the signal strength
+func (dev *Device) Dot11Status(ctx context.Context, interfaceToken string) (onvif.Dot11Status, error) {
+	response := device.GetDot11StatusResponse{}
+	request := device.GetDot11Status{InterfaceToken: onvif.ReferenceToken(interfaceToken)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return onvif.Dot11Status{}, err
+	}
+	return response.Status, nil
+}
+
+// ScanDot11Networks return the wireless networks seen by the interface, a
+// NotSupportedError is returned when the device cannot scan
+func (dev *Device) ScanDot11Networks(ctx context.Context, interfaceToken string) ([]onvif.Dot11AvailableNetworks, error) {
+	capabilities, err := dev.Dot11Capabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !bool(capabilities.ScanAvailableNetworks) {
+		return nil, &NotSupportedError{Service: "device", Capability: "ScanAvailableNetworks"}
+	}
+	response := device.ScanAvailableDot11NetworksResponse{}
+	request := device.ScanAvailableDot11Networks{InterfaceToken: onvif.ReferenceToken(interfaceToken)}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return nil, err
+	}
+	return response.Networks, nil
+}
+
+// SetDot11Configurations replace the wireless configurations of the
+// interface, in order of priority, leaving its other settings unchanged. It
+// reports whether the device must be rebooted for them to apply.
+//
+//	reboot, err := dev.SetDot11Configurations(ctx, "wlan0", onvif.Dot11PSKConfiguration("site-cameras", passphrase))
+func (dev *Device) SetDot11Configurations(ctx context.Context, interfaceToken string, configurations ...onvif.Dot11Configuration) (bool, error) {
+	enabled := xsd.Boolean(true)
+	request := device.SetNetworkInterfaces{
+		InterfaceToken: onvif.ReferenceToken(interfaceToken),
+		NetworkInterface: onvif.NetworkInterfaceSetConfiguration{
+			Enabled:   &enabled,
+			Extension: &onvif.NetworkInterfaceSetConfigurationExtension{Dot11: configurations},
+		},
+	}
+	response := device.SetNetworkInterfacesResponse{}
+	if err := dev.CallMethodInterfaceContext(ctx, request, &response, ""); err != nil {
+		return false, err
+	}
+	return bool(response.RebootNeeded), nil
+}
@@ -0,0 +1,77 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSSID(t *testing.T) {
+	if ssid := SSIDOf("site-cameras"); ssid != "736974652D63616D65726173" {
+		t.Fatalf("ssid %q", ssid)
+	}
+	if name := SSIDName("736974652D63616D65726173"); name != "site-cameras" {
+		t.Fatalf("name %q", name)
+	}
+	/* 部分设备直接报告名称 */
+	if name := SSIDName("Office WiFi"); name != "Office WiFi" {
+		t.Fatalf("name %q", name)
+	}
+}
+
+func TestWireless(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tds:GetServiceCapabilitiesResponse><tds:Capabilities><tds:Network Dot11Configuration="true"/></tds:Capabilities></tds:GetServiceCapabilitiesResponse>`,
+		"GetDot11Capabilities": `<tds:GetDot11CapabilitiesResponse><tds:Capabilities><tt:TKIP>false</tt:TKIP><tt:ScanAvailableNetworks>true</tt:ScanAvailableNetworks>` +
+			`<tt:MultipleConfiguration>true</tt:MultipleConfiguration><tt:AdHocStationMode>false</tt:AdHocStationMode><tt:WEP>false</tt:WEP></tds:Capabilities></tds:GetDot11CapabilitiesResponse>`,
+		"GetNetworkInterfaces": `<tds:GetNetworkInterfacesResponse>` +
+			`<tds:NetworkInterfaces token="eth0"><tt:Enabled>true</tt:Enabled><tt:Extension><tt:InterfaceType>6</tt:InterfaceType></tt:Extension></tds:NetworkInterfaces>` +
+			`<tds:NetworkInterfaces token="wlan0"><tt:Enabled>true</tt:Enabled><tt:Extension><tt:InterfaceType>71</tt:InterfaceType>` +
+			`<tt:Dot11><tt:SSID>4F6666696365</tt:SSID><tt:Mode>Infrastructure</tt:Mode><tt:Alias>Office</tt:Alias><tt:Security><tt:Mode>PSK</tt:Mode></tt:Security></tt:Dot11></tt:Extension></tds:NetworkInterfaces>` +
+			`</tds:GetNetworkInterfacesResponse>`,
+		"GetDot11Status": `<tds:GetDot11StatusResponse><tds:Status><tt:SSID>4F6666696365</tt:SSID><tt:BSSID>00:11:22:33:44:55</tt:BSSID>` +
+			`<tt:SignalStrength>Good</tt:SignalStrength><tt:ActiveConfigAlias>Office</tt:ActiveConfigAlias></tds:Status></tds:GetDot11StatusResponse>`,
+		"ScanAvailableDot11Networks": `<tds:ScanAvailableDot11NetworksResponse><tds:Networks><tt:SSID>4F6666696365</tt:SSID><tt:SignalStrength>Good</tt:SignalStrength></tds:Networks>` +
+			`<tds:Networks><tt:SSID>Guest</tt:SSID><tt:SignalStrength>Bad</tt:SignalStrength></tds:Networks></tds:ScanAvailableDot11NetworksResponse>`,
+		"SetNetworkInterfaces": `<tds:SetNetworkInterfacesResponse><tds:RebootNeeded>true</tds:RebootNeeded></tds:SetNetworkInterfacesResponse>`,
+	})
+	ctx := context.Background()
+	interfaces, err := dev.WirelessInterfaces(ctx)
+	if err != nil || len(interfaces) != 1 || interfaces[0].Token != "wlan0" || len(interfaces[0].Extension.Dot11) != 1 || SSIDName(interfaces[0].Extension.Dot11[0].SSID) != "Office" ||
+		interfaces[0].Extension.Dot11[0].Security.Mode != "PSK" {
+		t.Fatalf("interfaces %+v, %v", interfaces, err)
+	}
+	status, err := dev.Dot11Status(ctx, "wlan0")
+	if err != nil || SSIDName(status.SSID) != "Office" || status.SignalStrength != "Good" || status.ActiveConfigAlias != "Office" {
+		t.Fatalf("status %+v, %v", status, err)
+	}
+	networks, err := dev.ScanDot11Networks(ctx, "wlan0")
+	if err != nil || len(networks) != 2 || SSIDName(networks[0].SSID) != "Office" || SSIDName(networks[1].SSID) != "Guest" {
+		t.Fatalf("networks %+v, %v", networks, err)
+	}
+
+	reboot, err := dev.SetDot11Configurations(ctx, "wlan0", Dot11PSKConfiguration("site-cameras", "secret passphrase"))
+	if err != nil || !reboot {
+		t.Fatalf("reboot %v, %v", reboot, err)
+	}
+	requests := fake.sent("SetNetworkInterfaces")
+	if len(requests) != 1 || !strings.Contains(requests[0], "<tds:InterfaceToken>wlan0</tds:InterfaceToken>") ||
+		!strings.Contains(requests[0], "<onvif:SSID>736974652D63616D65726173</onvif:SSID>") || !strings.Contains(requests[0], "<onvif:Passphrase>secret passphrase</onvif:Passphrase>") ||
+		!strings.Contains(requests[0], "<onvif:Mode>PSK</onvif:Mode>") {
+		t.Fatalf("requests %q", requests)
+	}
+}
+
+func TestWirelessNotSupported(t *testing.T) {
+	fake, dev := newScriptedDevice(t, map[string]string{
+		"GetServiceCapabilities": `<tds:GetServiceCapabilitiesResponse><tds:Capabilities><tds:Network Dot11Configuration="false"/></tds:Capabilities></tds:GetServiceCapabilitiesResponse>`,
+	})
+	var unsupported *NotSupportedError
+	if _, err := dev.ScanDot11Networks(context.Background(), "wlan0"); !errors.As(err, &unsupported) || unsupported.Capability != "Dot11Configuration" {
+		t.Fatalf("error %v", err)
+	}
+	if len(fake.sent("GetDot11Capabilities")) != 0 || len(fake.sent("ScanAvailableDot11Networks")) != 0 {
+		t.Fatal("wireless operation sent")
+	}
+}
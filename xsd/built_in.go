@@ -175,7 +175,7 @@ func (tp Decimal) NewDecimal(data string) Decimal {
 	More info: https://www.w3.org/TR/xmlschema-2/#duration
 
 	TODO: process restrictions
-	TODO: Look at time.Duration go type
+	See ParseDuration and FormatDuration for time.Duration
 */
 
 /*
@@ -226,7 +226,7 @@ type DateTime AnySimpleType
 	Construct an instance of xsd dateTime type
 */
 func (tp DateTime) NewDateTime(time time.Time) DateTime {
-	return DateTime(time.Format("2002-10-10T12:00:00-05:00"))
+	return DateTime(FormatDateTime(time))
 }
 
 /*
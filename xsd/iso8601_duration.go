@@ -3,20 +3,22 @@ package xsd
 import (
 	"errors"
 	"regexp"
+	"strings"
 )
 
-//Duration of iso8601
+// Duration of iso8601
 type Duration struct {
-	years  string //= number of years
-	months string //= number of months
-	days   string //= number of days
+	negative bool
+	years    string //= number of years
+	months   string //= number of months
+	days     string //= number of days
 	// Time section
 	hours   string //= the number of hours
 	minutes string //= the number of minutes
 	seconds string //= the number of seconds
 }
 
-//NewDuration return duration
+// NewDuration return duration
 func NewDuration(years, months, days, hours, minutes, seconds string) (*Duration, error) {
 	// Pattern for Years, Months, Days, Hours and Minutes components
 	pattern1 := "^$|[0-9]+"
@@ -68,9 +70,12 @@ func NewDuration(years, months, days, hours, minutes, seconds string) (*Duration
 	return &Duration{years: years, months: months, hours: hours, days: days, minutes: minutes, seconds: seconds}, nil
 }
 
-//ISO8601Duration to string
+// ISO8601Duration to string
 func (duration Duration) ISO8601Duration() string {
 	var result string
+	if duration.negative {
+		result += "-"
+	}
 	result += "P" // time duration designator
 	//years
 	if duration.years != "" {
@@ -83,7 +88,7 @@ func (duration Duration) ISO8601Duration() string {
 		result += duration.days + "D"
 	}
 
-	if duration.hours != "" && duration.minutes != "" && duration.seconds != "" {
+	if duration.hours != "" || duration.minutes != "" || duration.seconds != "" {
 		result += "T"
 		if duration.hours != "" {
 			result += duration.hours + "H"
@@ -96,7 +101,7 @@ func (duration Duration) ISO8601Duration() string {
 		}
 	}
 
-	if len(result) == 1 {
+	if strings.HasSuffix(result, "P") {
 		result += "T0S"
 	}
 
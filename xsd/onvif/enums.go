@@ -0,0 +1,1334 @@
+// Code generated by gen_enums.go; DO NOT EDIT.
+
+package onvif
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Values of OSDType
+const (
+	OSDTypeText     OSDType = "Text"
+	OSDTypeImage    OSDType = "Image"
+	OSDTypeExtended OSDType = "Extended"
+)
+
+// OSDTypeValues values of OSDType, in the order of the schema
+var OSDTypeValues = []OSDType{OSDTypeText, OSDTypeImage, OSDTypeExtended}
+
+func (value OSDType) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value OSDType) Valid() bool {
+	switch value {
+	case OSDTypeText, OSDTypeImage, OSDTypeExtended:
+		return true
+	}
+	return false
+}
+
+// ParseOSDType return the value of OSDType named text, compared case
+// insensitively as devices differ in case
+func ParseOSDType(text string) (OSDType, error) {
+	for _, value := range OSDTypeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid OSDType %q", text)
+}
+
+// Values of BacklightCompensationMode
+const (
+	BacklightCompensationModeOff BacklightCompensationMode = "OFF"
+	BacklightCompensationModeOn  BacklightCompensationMode = "ON"
+)
+
+// BacklightCompensationModeValues values of BacklightCompensationMode, in the order of the schema
+var BacklightCompensationModeValues = []BacklightCompensationMode{BacklightCompensationModeOff, BacklightCompensationModeOn}
+
+func (value BacklightCompensationMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value BacklightCompensationMode) Valid() bool {
+	switch value {
+	case BacklightCompensationModeOff, BacklightCompensationModeOn:
+		return true
+	}
+	return false
+}
+
+// ParseBacklightCompensationMode return the value of BacklightCompensationMode named text, compared case
+// insensitively as devices differ in case
+func ParseBacklightCompensationMode(text string) (BacklightCompensationMode, error) {
+	for _, value := range BacklightCompensationModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid BacklightCompensationMode %q", text)
+}
+
+// Values of ExposureMode
+const (
+	ExposureModeAuto   ExposureMode = "AUTO"
+	ExposureModeManual ExposureMode = "MANUAL"
+)
+
+// ExposureModeValues values of ExposureMode, in the order of the schema
+var ExposureModeValues = []ExposureMode{ExposureModeAuto, ExposureModeManual}
+
+func (value ExposureMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value ExposureMode) Valid() bool {
+	switch value {
+	case ExposureModeAuto, ExposureModeManual:
+		return true
+	}
+	return false
+}
+
+// ParseExposureMode return the value of ExposureMode named text, compared case
+// insensitively as devices differ in case
+func ParseExposureMode(text string) (ExposureMode, error) {
+	for _, value := range ExposureModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid ExposureMode %q", text)
+}
+
+// Values of ExposurePriority
+const (
+	ExposurePriorityLowNoise  ExposurePriority = "LowNoise"
+	ExposurePriorityFrameRate ExposurePriority = "FrameRate"
+)
+
+// ExposurePriorityValues values of ExposurePriority, in the order of the schema
+var ExposurePriorityValues = []ExposurePriority{ExposurePriorityLowNoise, ExposurePriorityFrameRate}
+
+func (value ExposurePriority) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value ExposurePriority) Valid() bool {
+	switch value {
+	case ExposurePriorityLowNoise, ExposurePriorityFrameRate:
+		return true
+	}
+	return false
+}
+
+// ParseExposurePriority return the value of ExposurePriority named text, compared case
+// insensitively as devices differ in case
+func ParseExposurePriority(text string) (ExposurePriority, error) {
+	for _, value := range ExposurePriorityValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid ExposurePriority %q", text)
+}
+
+// Values of AutoFocusMode
+const (
+	AutoFocusModeAuto   AutoFocusMode = "AUTO"
+	AutoFocusModeManual AutoFocusMode = "MANUAL"
+)
+
+// AutoFocusModeValues values of AutoFocusMode, in the order of the schema
+var AutoFocusModeValues = []AutoFocusMode{AutoFocusModeAuto, AutoFocusModeManual}
+
+func (value AutoFocusMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value AutoFocusMode) Valid() bool {
+	switch value {
+	case AutoFocusModeAuto, AutoFocusModeManual:
+		return true
+	}
+	return false
+}
+
+// ParseAutoFocusMode return the value of AutoFocusMode named text, compared case
+// insensitively as devices differ in case
+func ParseAutoFocusMode(text string) (AutoFocusMode, error) {
+	for _, value := range AutoFocusModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid AutoFocusMode %q", text)
+}
+
+// Values of IrCutFilterMode
+const (
+	IrCutFilterModeOn   IrCutFilterMode = "ON"
+	IrCutFilterModeOff  IrCutFilterMode = "OFF"
+	IrCutFilterModeAuto IrCutFilterMode = "AUTO"
+)
+
+// IrCutFilterModeValues values of IrCutFilterMode, in the order of the schema
+var IrCutFilterModeValues = []IrCutFilterMode{IrCutFilterModeOn, IrCutFilterModeOff, IrCutFilterModeAuto}
+
+func (value IrCutFilterMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value IrCutFilterMode) Valid() bool {
+	switch value {
+	case IrCutFilterModeOn, IrCutFilterModeOff, IrCutFilterModeAuto:
+		return true
+	}
+	return false
+}
+
+// ParseIrCutFilterMode return the value of IrCutFilterMode named text, compared case
+// insensitively as devices differ in case
+func ParseIrCutFilterMode(text string) (IrCutFilterMode, error) {
+	for _, value := range IrCutFilterModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid IrCutFilterMode %q", text)
+}
+
+// Values of WideDynamicMode
+const (
+	WideDynamicModeOff WideDynamicMode = "OFF"
+	WideDynamicModeOn  WideDynamicMode = "ON"
+)
+
+// WideDynamicModeValues values of WideDynamicMode, in the order of the schema
+var WideDynamicModeValues = []WideDynamicMode{WideDynamicModeOff, WideDynamicModeOn}
+
+func (value WideDynamicMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value WideDynamicMode) Valid() bool {
+	switch value {
+	case WideDynamicModeOff, WideDynamicModeOn:
+		return true
+	}
+	return false
+}
+
+// ParseWideDynamicMode return the value of WideDynamicMode named text, compared case
+// insensitively as devices differ in case
+func ParseWideDynamicMode(text string) (WideDynamicMode, error) {
+	for _, value := range WideDynamicModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid WideDynamicMode %q", text)
+}
+
+// Values of WhiteBalanceMode
+const (
+	WhiteBalanceModeAuto   WhiteBalanceMode = "AUTO"
+	WhiteBalanceModeManual WhiteBalanceMode = "MANUAL"
+)
+
+// WhiteBalanceModeValues values of WhiteBalanceMode, in the order of the schema
+var WhiteBalanceModeValues = []WhiteBalanceMode{WhiteBalanceModeAuto, WhiteBalanceModeManual}
+
+func (value WhiteBalanceMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value WhiteBalanceMode) Valid() bool {
+	switch value {
+	case WhiteBalanceModeAuto, WhiteBalanceModeManual:
+		return true
+	}
+	return false
+}
+
+// ParseWhiteBalanceMode return the value of WhiteBalanceMode named text, compared case
+// insensitively as devices differ in case
+func ParseWhiteBalanceMode(text string) (WhiteBalanceMode, error) {
+	for _, value := range WhiteBalanceModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid WhiteBalanceMode %q", text)
+}
+
+// Values of ImageStabilizationMode
+const (
+	ImageStabilizationModeOff      ImageStabilizationMode = "OFF"
+	ImageStabilizationModeOn       ImageStabilizationMode = "ON"
+	ImageStabilizationModeAuto     ImageStabilizationMode = "AUTO"
+	ImageStabilizationModeExtended ImageStabilizationMode = "Extended"
+)
+
+// ImageStabilizationModeValues values of ImageStabilizationMode, in the order of the schema
+var ImageStabilizationModeValues = []ImageStabilizationMode{ImageStabilizationModeOff, ImageStabilizationModeOn, ImageStabilizationModeAuto, ImageStabilizationModeExtended}
+
+func (value ImageStabilizationMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value ImageStabilizationMode) Valid() bool {
+	switch value {
+	case ImageStabilizationModeOff, ImageStabilizationModeOn, ImageStabilizationModeAuto, ImageStabilizationModeExtended:
+		return true
+	}
+	return false
+}
+
+// ParseImageStabilizationMode return the value of ImageStabilizationMode named text, compared case
+// insensitively as devices differ in case
+func ParseImageStabilizationMode(text string) (ImageStabilizationMode, error) {
+	for _, value := range ImageStabilizationModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid ImageStabilizationMode %q", text)
+}
+
+// Values of RotateMode
+const (
+	RotateModeOff  RotateMode = "OFF"
+	RotateModeOn   RotateMode = "ON"
+	RotateModeAuto RotateMode = "AUTO"
+)
+
+// RotateModeValues values of RotateMode, in the order of the schema
+var RotateModeValues = []RotateMode{RotateModeOff, RotateModeOn, RotateModeAuto}
+
+func (value RotateMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value RotateMode) Valid() bool {
+	switch value {
+	case RotateModeOff, RotateModeOn, RotateModeAuto:
+		return true
+	}
+	return false
+}
+
+// ParseRotateMode return the value of RotateMode named text, compared case
+// insensitively as devices differ in case
+func ParseRotateMode(text string) (RotateMode, error) {
+	for _, value := range RotateModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid RotateMode %q", text)
+}
+
+// Values of SceneOrientationMode
+const (
+	SceneOrientationModeManual SceneOrientationMode = "MANUAL"
+	SceneOrientationModeAuto   SceneOrientationMode = "AUTO"
+)
+
+// SceneOrientationModeValues values of SceneOrientationMode, in the order of the schema
+var SceneOrientationModeValues = []SceneOrientationMode{SceneOrientationModeManual, SceneOrientationModeAuto}
+
+func (value SceneOrientationMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value SceneOrientationMode) Valid() bool {
+	switch value {
+	case SceneOrientationModeManual, SceneOrientationModeAuto:
+		return true
+	}
+	return false
+}
+
+// ParseSceneOrientationMode return the value of SceneOrientationMode named text, compared case
+// insensitively as devices differ in case
+func ParseSceneOrientationMode(text string) (SceneOrientationMode, error) {
+	for _, value := range SceneOrientationModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid SceneOrientationMode %q", text)
+}
+
+// Values of VideoEncoding
+const (
+	VideoEncodingJPEG  VideoEncoding = "JPEG"
+	VideoEncodingMPEG4 VideoEncoding = "MPEG4"
+	VideoEncodingH264  VideoEncoding = "H264"
+)
+
+// VideoEncodingValues values of VideoEncoding, in the order of the schema
+var VideoEncodingValues = []VideoEncoding{VideoEncodingJPEG, VideoEncodingMPEG4, VideoEncodingH264}
+
+func (value VideoEncoding) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value VideoEncoding) Valid() bool {
+	switch value {
+	case VideoEncodingJPEG, VideoEncodingMPEG4, VideoEncodingH264:
+		return true
+	}
+	return false
+}
+
+// ParseVideoEncoding return the value of VideoEncoding named text, compared case
+// insensitively as devices differ in case
+func ParseVideoEncoding(text string) (VideoEncoding, error) {
+	for _, value := range VideoEncodingValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid VideoEncoding %q", text)
+}
+
+// Values of Mpeg4Profile
+const (
+	Mpeg4ProfileSP  Mpeg4Profile = "SP"
+	Mpeg4ProfileASP Mpeg4Profile = "ASP"
+)
+
+// Mpeg4ProfileValues values of Mpeg4Profile, in the order of the schema
+var Mpeg4ProfileValues = []Mpeg4Profile{Mpeg4ProfileSP, Mpeg4ProfileASP}
+
+func (value Mpeg4Profile) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value Mpeg4Profile) Valid() bool {
+	switch value {
+	case Mpeg4ProfileSP, Mpeg4ProfileASP:
+		return true
+	}
+	return false
+}
+
+// ParseMpeg4Profile return the value of Mpeg4Profile named text, compared case
+// insensitively as devices differ in case
+func ParseMpeg4Profile(text string) (Mpeg4Profile, error) {
+	for _, value := range Mpeg4ProfileValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid Mpeg4Profile %q", text)
+}
+
+// Values of H264Profile
+const (
+	H264ProfileBaseline H264Profile = "Baseline"
+	H264ProfileMain     H264Profile = "Main"
+	H264ProfileExtended H264Profile = "Extended"
+	H264ProfileHigh     H264Profile = "High"
+)
+
+// H264ProfileValues values of H264Profile, in the order of the schema
+var H264ProfileValues = []H264Profile{H264ProfileBaseline, H264ProfileMain, H264ProfileExtended, H264ProfileHigh}
+
+func (value H264Profile) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value H264Profile) Valid() bool {
+	switch value {
+	case H264ProfileBaseline, H264ProfileMain, H264ProfileExtended, H264ProfileHigh:
+		return true
+	}
+	return false
+}
+
+// ParseH264Profile return the value of H264Profile named text, compared case
+// insensitively as devices differ in case
+func ParseH264Profile(text string) (H264Profile, error) {
+	for _, value := range H264ProfileValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid H264Profile %q", text)
+}
+
+// Values of IPType
+const (
+	IPTypeIPv4 IPType = "IPv4"
+	IPTypeIPv6 IPType = "IPv6"
+)
+
+// IPTypeValues values of IPType, in the order of the schema
+var IPTypeValues = []IPType{IPTypeIPv4, IPTypeIPv6}
+
+func (value IPType) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value IPType) Valid() bool {
+	switch value {
+	case IPTypeIPv4, IPTypeIPv6:
+		return true
+	}
+	return false
+}
+
+// ParseIPType return the value of IPType named text, compared case
+// insensitively as devices differ in case
+func ParseIPType(text string) (IPType, error) {
+	for _, value := range IPTypeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid IPType %q", text)
+}
+
+// Values of AudioEncoding
+const (
+	AudioEncodingG711 AudioEncoding = "G711"
+	AudioEncodingG726 AudioEncoding = "G726"
+	AudioEncodingAAC  AudioEncoding = "AAC"
+)
+
+// AudioEncodingValues values of AudioEncoding, in the order of the schema
+var AudioEncodingValues = []AudioEncoding{AudioEncodingG711, AudioEncodingG726, AudioEncodingAAC}
+
+func (value AudioEncoding) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value AudioEncoding) Valid() bool {
+	switch value {
+	case AudioEncodingG711, AudioEncodingG726, AudioEncodingAAC:
+		return true
+	}
+	return false
+}
+
+// ParseAudioEncoding return the value of AudioEncoding named text, compared case
+// insensitively as devices differ in case
+func ParseAudioEncoding(text string) (AudioEncoding, error) {
+	for _, value := range AudioEncodingValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid AudioEncoding %q", text)
+}
+
+// Values of EFlipMode
+const (
+	EFlipModeOff      EFlipMode = "OFF"
+	EFlipModeOn       EFlipMode = "ON"
+	EFlipModeExtended EFlipMode = "Extended"
+)
+
+// EFlipModeValues values of EFlipMode, in the order of the schema
+var EFlipModeValues = []EFlipMode{EFlipModeOff, EFlipModeOn, EFlipModeExtended}
+
+func (value EFlipMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value EFlipMode) Valid() bool {
+	switch value {
+	case EFlipModeOff, EFlipModeOn, EFlipModeExtended:
+		return true
+	}
+	return false
+}
+
+// ParseEFlipMode return the value of EFlipMode named text, compared case
+// insensitively as devices differ in case
+func ParseEFlipMode(text string) (EFlipMode, error) {
+	for _, value := range EFlipModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid EFlipMode %q", text)
+}
+
+// Values of ReverseMode
+const (
+	ReverseModeOff      ReverseMode = "OFF"
+	ReverseModeOn       ReverseMode = "ON"
+	ReverseModeAuto     ReverseMode = "AUTO"
+	ReverseModeExtended ReverseMode = "Extended"
+)
+
+// ReverseModeValues values of ReverseMode, in the order of the schema
+var ReverseModeValues = []ReverseMode{ReverseModeOff, ReverseModeOn, ReverseModeAuto, ReverseModeExtended}
+
+func (value ReverseMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value ReverseMode) Valid() bool {
+	switch value {
+	case ReverseModeOff, ReverseModeOn, ReverseModeAuto, ReverseModeExtended:
+		return true
+	}
+	return false
+}
+
+// ParseReverseMode return the value of ReverseMode named text, compared case
+// insensitively as devices differ in case
+func ParseReverseMode(text string) (ReverseMode, error) {
+	for _, value := range ReverseModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid ReverseMode %q", text)
+}
+
+// Values of MoveState
+const (
+	MoveStateIdle    MoveState = "IDLE"
+	MoveStateMoving  MoveState = "MOVING"
+	MoveStateUnknown MoveState = "UNKNOWN"
+)
+
+// MoveStateValues values of MoveState, in the order of the schema
+var MoveStateValues = []MoveState{MoveStateIdle, MoveStateMoving, MoveStateUnknown}
+
+func (value MoveState) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value MoveState) Valid() bool {
+	switch value {
+	case MoveStateIdle, MoveStateMoving, MoveStateUnknown:
+		return true
+	}
+	return false
+}
+
+// ParseMoveState return the value of MoveState named text, compared case
+// insensitively as devices differ in case
+func ParseMoveState(text string) (MoveState, error) {
+	for _, value := range MoveStateValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid MoveState %q", text)
+}
+
+// Values of PTZPresetTourOperation
+const (
+	PTZPresetTourOperationStart    PTZPresetTourOperation = "Start"
+	PTZPresetTourOperationStop     PTZPresetTourOperation = "Stop"
+	PTZPresetTourOperationPause    PTZPresetTourOperation = "Pause"
+	PTZPresetTourOperationExtended PTZPresetTourOperation = "Extended"
+)
+
+// PTZPresetTourOperationValues values of PTZPresetTourOperation, in the order of the schema
+var PTZPresetTourOperationValues = []PTZPresetTourOperation{PTZPresetTourOperationStart, PTZPresetTourOperationStop, PTZPresetTourOperationPause, PTZPresetTourOperationExtended}
+
+func (value PTZPresetTourOperation) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value PTZPresetTourOperation) Valid() bool {
+	switch value {
+	case PTZPresetTourOperationStart, PTZPresetTourOperationStop, PTZPresetTourOperationPause, PTZPresetTourOperationExtended:
+		return true
+	}
+	return false
+}
+
+// ParsePTZPresetTourOperation return the value of PTZPresetTourOperation named text, compared case
+// insensitively as devices differ in case
+func ParsePTZPresetTourOperation(text string) (PTZPresetTourOperation, error) {
+	for _, value := range PTZPresetTourOperationValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid PTZPresetTourOperation %q", text)
+}
+
+// Values of PTZPresetTourState
+const (
+	PTZPresetTourStateIdle     PTZPresetTourState = "Idle"
+	PTZPresetTourStateTouring  PTZPresetTourState = "Touring"
+	PTZPresetTourStatePaused   PTZPresetTourState = "Paused"
+	PTZPresetTourStateExtended PTZPresetTourState = "Extended"
+)
+
+// PTZPresetTourStateValues values of PTZPresetTourState, in the order of the schema
+var PTZPresetTourStateValues = []PTZPresetTourState{PTZPresetTourStateIdle, PTZPresetTourStateTouring, PTZPresetTourStatePaused, PTZPresetTourStateExtended}
+
+func (value PTZPresetTourState) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value PTZPresetTourState) Valid() bool {
+	switch value {
+	case PTZPresetTourStateIdle, PTZPresetTourStateTouring, PTZPresetTourStatePaused, PTZPresetTourStateExtended:
+		return true
+	}
+	return false
+}
+
+// ParsePTZPresetTourState return the value of PTZPresetTourState named text, compared case
+// insensitively as devices differ in case
+func ParsePTZPresetTourState(text string) (PTZPresetTourState, error) {
+	for _, value := range PTZPresetTourStateValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid PTZPresetTourState %q", text)
+}
+
+// Values of PTZPresetTourDirection
+const (
+	PTZPresetTourDirectionForward  PTZPresetTourDirection = "Forward"
+	PTZPresetTourDirectionBackward PTZPresetTourDirection = "Backward"
+	PTZPresetTourDirectionExtended PTZPresetTourDirection = "Extended"
+)
+
+// PTZPresetTourDirectionValues values of PTZPresetTourDirection, in the order of the schema
+var PTZPresetTourDirectionValues = []PTZPresetTourDirection{PTZPresetTourDirectionForward, PTZPresetTourDirectionBackward, PTZPresetTourDirectionExtended}
+
+func (value PTZPresetTourDirection) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value PTZPresetTourDirection) Valid() bool {
+	switch value {
+	case PTZPresetTourDirectionForward, PTZPresetTourDirectionBackward, PTZPresetTourDirectionExtended:
+		return true
+	}
+	return false
+}
+
+// ParsePTZPresetTourDirection return the value of PTZPresetTourDirection named text, compared case
+// insensitively as devices differ in case
+func ParsePTZPresetTourDirection(text string) (PTZPresetTourDirection, error) {
+	for _, value := range PTZPresetTourDirectionValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid PTZPresetTourDirection %q", text)
+}
+
+// Values of FactoryDefaultType
+const (
+	FactoryDefaultTypeHard FactoryDefaultType = "Hard"
+	FactoryDefaultTypeSoft FactoryDefaultType = "Soft"
+)
+
+// FactoryDefaultTypeValues values of FactoryDefaultType, in the order of the schema
+var FactoryDefaultTypeValues = []FactoryDefaultType{FactoryDefaultTypeHard, FactoryDefaultTypeSoft}
+
+func (value FactoryDefaultType) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value FactoryDefaultType) Valid() bool {
+	switch value {
+	case FactoryDefaultTypeHard, FactoryDefaultTypeSoft:
+		return true
+	}
+	return false
+}
+
+// ParseFactoryDefaultType return the value of FactoryDefaultType named text, compared case
+// insensitively as devices differ in case
+func ParseFactoryDefaultType(text string) (FactoryDefaultType, error) {
+	for _, value := range FactoryDefaultTypeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid FactoryDefaultType %q", text)
+}
+
+// Values of SystemLogType
+const (
+	SystemLogTypeSystem SystemLogType = "System"
+	SystemLogTypeAccess SystemLogType = "Access"
+)
+
+// SystemLogTypeValues values of SystemLogType, in the order of the schema
+var SystemLogTypeValues = []SystemLogType{SystemLogTypeSystem, SystemLogTypeAccess}
+
+func (value SystemLogType) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value SystemLogType) Valid() bool {
+	switch value {
+	case SystemLogTypeSystem, SystemLogTypeAccess:
+		return true
+	}
+	return false
+}
+
+// ParseSystemLogType return the value of SystemLogType named text, compared case
+// insensitively as devices differ in case
+func ParseSystemLogType(text string) (SystemLogType, error) {
+	for _, value := range SystemLogTypeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid SystemLogType %q", text)
+}
+
+// Values of ScopeDefinition
+const (
+	ScopeDefinitionFixed        ScopeDefinition = "Fixed"
+	ScopeDefinitionConfigurable ScopeDefinition = "Configurable"
+)
+
+// ScopeDefinitionValues values of ScopeDefinition, in the order of the schema
+var ScopeDefinitionValues = []ScopeDefinition{ScopeDefinitionFixed, ScopeDefinitionConfigurable}
+
+func (value ScopeDefinition) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value ScopeDefinition) Valid() bool {
+	switch value {
+	case ScopeDefinitionFixed, ScopeDefinitionConfigurable:
+		return true
+	}
+	return false
+}
+
+// ParseScopeDefinition return the value of ScopeDefinition named text, compared case
+// insensitively as devices differ in case
+func ParseScopeDefinition(text string) (ScopeDefinition, error) {
+	for _, value := range ScopeDefinitionValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid ScopeDefinition %q", text)
+}
+
+// Values of DiscoveryMode
+const (
+	DiscoveryModeDiscoverable    DiscoveryMode = "Discoverable"
+	DiscoveryModeNonDiscoverable DiscoveryMode = "NonDiscoverable"
+)
+
+// DiscoveryModeValues values of DiscoveryMode, in the order of the schema
+var DiscoveryModeValues = []DiscoveryMode{DiscoveryModeDiscoverable, DiscoveryModeNonDiscoverable}
+
+func (value DiscoveryMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value DiscoveryMode) Valid() bool {
+	switch value {
+	case DiscoveryModeDiscoverable, DiscoveryModeNonDiscoverable:
+		return true
+	}
+	return false
+}
+
+// ParseDiscoveryMode return the value of DiscoveryMode named text, compared case
+// insensitively as devices differ in case
+func ParseDiscoveryMode(text string) (DiscoveryMode, error) {
+	for _, value := range DiscoveryModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid DiscoveryMode %q", text)
+}
+
+// Values of NetworkHostType
+const (
+	NetworkHostTypeIPv4 NetworkHostType = "IPv4"
+	NetworkHostTypeIPv6 NetworkHostType = "IPv6"
+	NetworkHostTypeDNS  NetworkHostType = "DNS"
+)
+
+// NetworkHostTypeValues values of NetworkHostType, in the order of the schema
+var NetworkHostTypeValues = []NetworkHostType{NetworkHostTypeIPv4, NetworkHostTypeIPv6, NetworkHostTypeDNS}
+
+func (value NetworkHostType) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value NetworkHostType) Valid() bool {
+	switch value {
+	case NetworkHostTypeIPv4, NetworkHostTypeIPv6, NetworkHostTypeDNS:
+		return true
+	}
+	return false
+}
+
+// ParseNetworkHostType return the value of NetworkHostType named text, compared case
+// insensitively as devices differ in case
+func ParseNetworkHostType(text string) (NetworkHostType, error) {
+	for _, value := range NetworkHostTypeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid NetworkHostType %q", text)
+}
+
+// Values of UserLevel
+const (
+	UserLevelAdministrator UserLevel = "Administrator"
+	UserLevelOperator      UserLevel = "Operator"
+	UserLevelUser          UserLevel = "User"
+	UserLevelAnonymous     UserLevel = "Anonymous"
+	UserLevelExtended      UserLevel = "Extended"
+)
+
+// UserLevelValues values of UserLevel, in the order of the schema
+var UserLevelValues = []UserLevel{UserLevelAdministrator, UserLevelOperator, UserLevelUser, UserLevelAnonymous, UserLevelExtended}
+
+func (value UserLevel) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value UserLevel) Valid() bool {
+	switch value {
+	case UserLevelAdministrator, UserLevelOperator, UserLevelUser, UserLevelAnonymous, UserLevelExtended:
+		return true
+	}
+	return false
+}
+
+// ParseUserLevel return the value of UserLevel named text, compared case
+// insensitively as devices differ in case
+func ParseUserLevel(text string) (UserLevel, error) {
+	for _, value := range UserLevelValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid UserLevel %q", text)
+}
+
+// Values of CapabilityCategory
+const (
+	CapabilityCategoryAll       CapabilityCategory = "All"
+	CapabilityCategoryAnalytics CapabilityCategory = "Analytics"
+	CapabilityCategoryDevice    CapabilityCategory = "Device"
+	CapabilityCategoryEvents    CapabilityCategory = "Events"
+	CapabilityCategoryImaging   CapabilityCategory = "Imaging"
+	CapabilityCategoryMedia     CapabilityCategory = "Media"
+	CapabilityCategoryPTZ       CapabilityCategory = "PTZ"
+)
+
+// CapabilityCategoryValues values of CapabilityCategory, in the order of the schema
+var CapabilityCategoryValues = []CapabilityCategory{CapabilityCategoryAll, CapabilityCategoryAnalytics, CapabilityCategoryDevice, CapabilityCategoryEvents, CapabilityCategoryImaging, CapabilityCategoryMedia, CapabilityCategoryPTZ}
+
+func (value CapabilityCategory) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value CapabilityCategory) Valid() bool {
+	switch value {
+	case CapabilityCategoryAll, CapabilityCategoryAnalytics, CapabilityCategoryDevice, CapabilityCategoryEvents, CapabilityCategoryImaging, CapabilityCategoryMedia, CapabilityCategoryPTZ:
+		return true
+	}
+	return false
+}
+
+// ParseCapabilityCategory return the value of CapabilityCategory named text, compared case
+// insensitively as devices differ in case
+func ParseCapabilityCategory(text string) (CapabilityCategory, error) {
+	for _, value := range CapabilityCategoryValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid CapabilityCategory %q", text)
+}
+
+// Values of DynamicDNSType
+const (
+	DynamicDNSTypeNoUpdate      DynamicDNSType = "NoUpdate"
+	DynamicDNSTypeClientUpdates DynamicDNSType = "ClientUpdates"
+	DynamicDNSTypeServerUpdates DynamicDNSType = "ServerUpdates"
+)
+
+// DynamicDNSTypeValues values of DynamicDNSType, in the order of the schema
+var DynamicDNSTypeValues = []DynamicDNSType{DynamicDNSTypeNoUpdate, DynamicDNSTypeClientUpdates, DynamicDNSTypeServerUpdates}
+
+func (value DynamicDNSType) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value DynamicDNSType) Valid() bool {
+	switch value {
+	case DynamicDNSTypeNoUpdate, DynamicDNSTypeClientUpdates, DynamicDNSTypeServerUpdates:
+		return true
+	}
+	return false
+}
+
+// ParseDynamicDNSType return the value of DynamicDNSType named text, compared case
+// insensitively as devices differ in case
+func ParseDynamicDNSType(text string) (DynamicDNSType, error) {
+	for _, value := range DynamicDNSTypeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid DynamicDNSType %q", text)
+}
+
+// Values of Dot11Cipher
+const (
+	Dot11CipherCCMP     Dot11Cipher = "CCMP"
+	Dot11CipherTKIP     Dot11Cipher = "TKIP"
+	Dot11CipherAny      Dot11Cipher = "Any"
+	Dot11CipherExtended Dot11Cipher = "Extended"
+)
+
+// Dot11CipherValues values of Dot11Cipher, in the order of the schema
+var Dot11CipherValues = []Dot11Cipher{Dot11CipherCCMP, Dot11CipherTKIP, Dot11CipherAny, Dot11CipherExtended}
+
+func (value Dot11Cipher) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value Dot11Cipher) Valid() bool {
+	switch value {
+	case Dot11CipherCCMP, Dot11CipherTKIP, Dot11CipherAny, Dot11CipherExtended:
+		return true
+	}
+	return false
+}
+
+// ParseDot11Cipher return the value of Dot11Cipher named text, compared case
+// insensitively as devices differ in case
+func ParseDot11Cipher(text string) (Dot11Cipher, error) {
+	for _, value := range Dot11CipherValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid Dot11Cipher %q", text)
+}
+
+// Values of Dot11SecurityMode
+const (
+	Dot11SecurityModeNone     Dot11SecurityMode = "None"
+	Dot11SecurityModeWEP      Dot11SecurityMode = "WEP"
+	Dot11SecurityModePSK      Dot11SecurityMode = "PSK"
+	Dot11SecurityModeDot1X    Dot11SecurityMode = "Dot1X"
+	Dot11SecurityModeExtended Dot11SecurityMode = "Extended"
+)
+
+// Dot11SecurityModeValues values of Dot11SecurityMode, in the order of the schema
+var Dot11SecurityModeValues = []Dot11SecurityMode{Dot11SecurityModeNone, Dot11SecurityModeWEP, Dot11SecurityModePSK, Dot11SecurityModeDot1X, Dot11SecurityModeExtended}
+
+func (value Dot11SecurityMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value Dot11SecurityMode) Valid() bool {
+	switch value {
+	case Dot11SecurityModeNone, Dot11SecurityModeWEP, Dot11SecurityModePSK, Dot11SecurityModeDot1X, Dot11SecurityModeExtended:
+		return true
+	}
+	return false
+}
+
+// ParseDot11SecurityMode return the value of Dot11SecurityMode named text, compared case
+// insensitively as devices differ in case
+func ParseDot11SecurityMode(text string) (Dot11SecurityMode, error) {
+	for _, value := range Dot11SecurityModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid Dot11SecurityMode %q", text)
+}
+
+// Values of Dot11StationMode
+const (
+	Dot11StationModeAdHoc          Dot11StationMode = "Ad-hoc"
+	Dot11StationModeInfrastructure Dot11StationMode = "Infrastructure"
+	Dot11StationModeExtended       Dot11StationMode = "Extended"
+)
+
+// Dot11StationModeValues values of Dot11StationMode, in the order of the schema
+var Dot11StationModeValues = []Dot11StationMode{Dot11StationModeAdHoc, Dot11StationModeInfrastructure, Dot11StationModeExtended}
+
+func (value Dot11StationMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value Dot11StationMode) Valid() bool {
+	switch value {
+	case Dot11StationModeAdHoc, Dot11StationModeInfrastructure, Dot11StationModeExtended:
+		return true
+	}
+	return false
+}
+
+// ParseDot11StationMode return the value of Dot11StationMode named text, compared case
+// insensitively as devices differ in case
+func ParseDot11StationMode(text string) (Dot11StationMode, error) {
+	for _, value := range Dot11StationModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid Dot11StationMode %q", text)
+}
+
+// Values of Dot11SignalStrength
+const (
+	Dot11SignalStrengthNone     Dot11SignalStrength = "None"
+	Dot11SignalStrengthVeryBad  Dot11SignalStrength = "Very Bad"
+	Dot11SignalStrengthBad      Dot11SignalStrength = "Bad"
+	Dot11SignalStrengthGood     Dot11SignalStrength = "Good"
+	Dot11SignalStrengthVeryGood Dot11SignalStrength = "Very Good"
+	Dot11SignalStrengthExtended Dot11SignalStrength = "Extended"
+)
+
+// Dot11SignalStrengthValues values of Dot11SignalStrength, in the order of the schema
+var Dot11SignalStrengthValues = []Dot11SignalStrength{Dot11SignalStrengthNone, Dot11SignalStrengthVeryBad, Dot11SignalStrengthBad, Dot11SignalStrengthGood, Dot11SignalStrengthVeryGood, Dot11SignalStrengthExtended}
+
+func (value Dot11SignalStrength) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value Dot11SignalStrength) Valid() bool {
+	switch value {
+	case Dot11SignalStrengthNone, Dot11SignalStrengthVeryBad, Dot11SignalStrengthBad, Dot11SignalStrengthGood, Dot11SignalStrengthVeryGood, Dot11SignalStrengthExtended:
+		return true
+	}
+	return false
+}
+
+// ParseDot11SignalStrength return the value of Dot11SignalStrength named text, compared case
+// insensitively as devices differ in case
+func ParseDot11SignalStrength(text string) (Dot11SignalStrength, error) {
+	for _, value := range Dot11SignalStrengthValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid Dot11SignalStrength %q", text)
+}
+
+// Values of NetworkProtocolType
+const (
+	NetworkProtocolTypeHTTP  NetworkProtocolType = "HTTP"
+	NetworkProtocolTypeHTTPS NetworkProtocolType = "HTTPS"
+	NetworkProtocolTypeRTSP  NetworkProtocolType = "RTSP"
+)
+
+// NetworkProtocolTypeValues values of NetworkProtocolType, in the order of the schema
+var NetworkProtocolTypeValues = []NetworkProtocolType{NetworkProtocolTypeHTTP, NetworkProtocolTypeHTTPS, NetworkProtocolTypeRTSP}
+
+func (value NetworkProtocolType) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value NetworkProtocolType) Valid() bool {
+	switch value {
+	case NetworkProtocolTypeHTTP, NetworkProtocolTypeHTTPS, NetworkProtocolTypeRTSP:
+		return true
+	}
+	return false
+}
+
+// ParseNetworkProtocolType return the value of NetworkProtocolType named text, compared case
+// insensitively as devices differ in case
+func ParseNetworkProtocolType(text string) (NetworkProtocolType, error) {
+	for _, value := range NetworkProtocolTypeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid NetworkProtocolType %q", text)
+}
+
+// Values of IPAddressFilterType
+const (
+	IPAddressFilterTypeAllow IPAddressFilterType = "Allow"
+	IPAddressFilterTypeDeny  IPAddressFilterType = "Deny"
+)
+
+// IPAddressFilterTypeValues values of IPAddressFilterType, in the order of the schema
+var IPAddressFilterTypeValues = []IPAddressFilterType{IPAddressFilterTypeAllow, IPAddressFilterTypeDeny}
+
+func (value IPAddressFilterType) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value IPAddressFilterType) Valid() bool {
+	switch value {
+	case IPAddressFilterTypeAllow, IPAddressFilterTypeDeny:
+		return true
+	}
+	return false
+}
+
+// ParseIPAddressFilterType return the value of IPAddressFilterType named text, compared case
+// insensitively as devices differ in case
+func ParseIPAddressFilterType(text string) (IPAddressFilterType, error) {
+	for _, value := range IPAddressFilterTypeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid IPAddressFilterType %q", text)
+}
+
+// Values of RelayIdleState
+const (
+	RelayIdleStateClosed RelayIdleState = "closed"
+	RelayIdleStateOpen   RelayIdleState = "open"
+)
+
+// RelayIdleStateValues values of RelayIdleState, in the order of the schema
+var RelayIdleStateValues = []RelayIdleState{RelayIdleStateClosed, RelayIdleStateOpen}
+
+func (value RelayIdleState) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value RelayIdleState) Valid() bool {
+	switch value {
+	case RelayIdleStateClosed, RelayIdleStateOpen:
+		return true
+	}
+	return false
+}
+
+// ParseRelayIdleState return the value of RelayIdleState named text, compared case
+// insensitively as devices differ in case
+func ParseRelayIdleState(text string) (RelayIdleState, error) {
+	for _, value := range RelayIdleStateValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid RelayIdleState %q", text)
+}
+
+// Values of RelayMode
+const (
+	RelayModeMonostable RelayMode = "Monostable"
+	RelayModeBistable   RelayMode = "Bistable"
+)
+
+// RelayModeValues values of RelayMode, in the order of the schema
+var RelayModeValues = []RelayMode{RelayModeMonostable, RelayModeBistable}
+
+func (value RelayMode) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value RelayMode) Valid() bool {
+	switch value {
+	case RelayModeMonostable, RelayModeBistable:
+		return true
+	}
+	return false
+}
+
+// ParseRelayMode return the value of RelayMode named text, compared case
+// insensitively as devices differ in case
+func ParseRelayMode(text string) (RelayMode, error) {
+	for _, value := range RelayModeValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid RelayMode %q", text)
+}
+
+// Values of RelayLogicalState
+const (
+	RelayLogicalStateActive   RelayLogicalState = "active"
+	RelayLogicalStateInactive RelayLogicalState = "inactive"
+)
+
+// RelayLogicalStateValues values of RelayLogicalState, in the order of the schema
+var RelayLogicalStateValues = []RelayLogicalState{RelayLogicalStateActive, RelayLogicalStateInactive}
+
+func (value RelayLogicalState) String() string {
+	return string(value)
+}
+
+// Valid report whether value is a value of the enumeration
+func (value RelayLogicalState) Valid() bool {
+	switch value {
+	case RelayLogicalStateActive, RelayLogicalStateInactive:
+		return true
+	}
+	return false
+}
+
+// ParseRelayLogicalState return the value of RelayLogicalState named text, compared case
+// insensitively as devices differ in case
+func ParseRelayLogicalState(text string) (RelayLogicalState, error) {
+	for _, value := range RelayLogicalStateValues {
+		if strings.EqualFold(string(value), text) {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("invalid RelayLogicalState %q", text)
+}
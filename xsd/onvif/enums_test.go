@@ -0,0 +1,35 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestEnums(t *testing.T) {
+	if !IrCutFilterModeAuto.Valid() || IrCutFilterMode("Automatic").Valid() || IrCutFilterMode("").Valid() {
+		t.Fatal("IrCutFilterMode validation")
+	}
+	/* 设备的大小写不一致,解析时忽略大小写 */
+	if value, err := ParseMoveState("idle"); err != nil || value != MoveStateIdle || value.String() != "IDLE" {
+		t.Fatalf("move state %q, %v", value, err)
+	}
+	if value, err := ParseDot11StationMode("AD-HOC"); err != nil || value != Dot11StationModeAdHoc {
+		t.Fatalf("station mode %q, %v", value, err)
+	}
+	if _, err := ParseH264Profile("High10"); err == nil {
+		t.Fatal("unknown profile parsed")
+	}
+	if len(FactoryDefaultTypeValues) != 2 || FactoryDefaultTypeValues[0] != FactoryDefaultTypeHard || FactoryDefaultTypeValues[1] != FactoryDefaultTypeSoft {
+		t.Fatalf("values %v", FactoryDefaultTypeValues)
+	}
+
+	/* 枚举类型按原字符串编解码 */
+	var status struct {
+		PanTilt MoveStatus
+		Signal  Dot11SignalStrength
+	}
+	if err := xml.Unmarshal([]byte(`<Status><PanTilt>MOVING</PanTilt><Signal>Very Good</Signal></Status>`), &status); err != nil ||
+		status.PanTilt.Status != MoveStateMoving || status.Signal != Dot11SignalStrengthVeryGood {
+		t.Fatalf("status %+v, %v", status, err)
+	}
+}
@@ -0,0 +1,131 @@
+//go:build ignore
+// +build ignore
+
+// gen_enums generate enums.go, the constants and the validation of the ONVIF
+// enumerations, from the table below. Run go generate in xsd/onvif.
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+	"unicode"
+)
+
+// enums values of each enumeration, in the order of the schema
+var enums = []struct {
+	name   string
+	values []string
+}{
+	{"OSDType", []string{"Text", "Image", "Extended"}},
+	{"BacklightCompensationMode", []string{"OFF", "ON"}},
+	{"ExposureMode", []string{"AUTO", "MANUAL"}},
+	{"ExposurePriority", []string{"LowNoise", "FrameRate"}},
+	{"AutoFocusMode", []string{"AUTO", "MANUAL"}},
+	{"IrCutFilterMode", []string{"ON", "OFF", "AUTO"}},
+	{"WideDynamicMode", []string{"OFF", "ON"}},
+	{"WhiteBalanceMode", []string{"AUTO", "MANUAL"}},
+	{"ImageStabilizationMode", []string{"OFF", "ON", "AUTO", "Extended"}},
+	{"RotateMode", []string{"OFF", "ON", "AUTO"}},
+	{"SceneOrientationMode", []string{"MANUAL", "AUTO"}},
+	{"VideoEncoding", []string{"JPEG", "MPEG4", "H264"}},
+	{"Mpeg4Profile", []string{"SP", "ASP"}},
+	{"H264Profile", []string{"Baseline", "Main", "Extended", "High"}},
+	{"IPType", []string{"IPv4", "IPv6"}},
+	{"AudioEncoding", []string{"G711", "G726", "AAC"}},
+	{"EFlipMode", []string{"OFF", "ON", "Extended"}},
+	{"ReverseMode", []string{"OFF", "ON", "AUTO", "Extended"}},
+	{"MoveState", []string{"IDLE", "MOVING", "UNKNOWN"}},
+	{"PTZPresetTourOperation", []string{"Start", "Stop", "Pause", "Extended"}},
+	{"PTZPresetTourState", []string{"Idle", "Touring", "Paused", "Extended"}},
+	{"PTZPresetTourDirection", []string{"Forward", "Backward", "Extended"}},
+	{"FactoryDefaultType", []string{"Hard", "Soft"}},
+	{"SystemLogType", []string{"System", "Access"}},
+	{"ScopeDefinition", []string{"Fixed", "Configurable"}},
+	{"DiscoveryMode", []string{"Discoverable", "NonDiscoverable"}},
+	{"NetworkHostType", []string{"IPv4", "IPv6", "DNS"}},
+	{"UserLevel", []string{"Administrator", "Operator", "User", "Anonymous", "Extended"}},
+	{"CapabilityCategory", []string{"All", "Analytics", "Device", "Events", "Imaging", "Media", "PTZ"}},
+	{"DynamicDNSType", []string{"NoUpdate", "ClientUpdates", "ServerUpdates"}},
+	{"Dot11Cipher", []string{"CCMP", "TKIP", "Any", "Extended"}},
+	{"Dot11SecurityMode", []string{"None", "WEP", "PSK", "Dot1X", "Extended"}},
+	{"Dot11StationMode", []string{"Ad-hoc", "Infrastructure", "Extended"}},
+	{"Dot11SignalStrength", []string{"None", "Very Bad", "Bad", "Good", "Very Good", "Extended"}},
+	{"NetworkProtocolType", []string{"HTTP", "HTTPS", "RTSP"}},
+	{"IPAddressFilterType", []string{"Allow", "Deny"}},
+	{"RelayIdleState", []string{"closed", "open"}},
+	{"RelayMode", []string{"Monostable", "Bistable"}},
+	{"RelayLogicalState", []string{"active", "inactive"}},
+}
+
+// words upper case values written as words in the constant names, the
+// other upper case values being acronyms such as JPEG or HTTPS
+var words = map[string]bool{"OFF": true, "ON": true, "AUTO": true, "MANUAL": true, "IDLE": true, "MOVING": true, "UNKNOWN": true}
+
+// constantName name of the constant of value, e.g. IrCutFilterModeAuto,
+// VideoEncodingJPEG or Dot11StationModeAdHoc
+func constantName(enum, value string) string {
+	if words[value] {
+		value = value[:1] + strings.ToLower(value[1:])
+	}
+	var name strings.Builder
+	name.WriteString(enum)
+	upper := true
+	for _, r := range value {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			upper = true
+		case upper:
+			name.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			name.WriteRune(r)
+		}
+	}
+	return name.String()
+}
+
+func main() {
+	var out bytes.Buffer
+	out.WriteString("// Code generated by gen_enums.go; DO NOT EDIT.\n\npackage onvif\n\n")
+	out.WriteString("import (\n\t\"fmt\"\n\t\"strings\"\n)\n\n")
+	for _, enum := range enums {
+		out.WriteString("// Values of " + enum.name + "\nconst (\n")
+		for _, value := range enum.values {
+			out.WriteString("\t" + constantName(enum.name, value) + " " + enum.name + " = \"" + value + "\"\n")
+		}
+		out.WriteString(")\n\n")
+		out.WriteString("// " + enum.name + "Values values of " + enum.name + ", in the order of the schema\n")
+		out.WriteString("var " + enum.name + "Values = []" + enum.name + "{")
+		for i, value := range enum.values {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(constantName(enum.name, value))
+		}
+		out.WriteString("}\n\n")
+		out.WriteString("func (value " + enum.name + ") String() string {\n\treturn string(value)\n}\n\n")
+		out.WriteString("// Valid report whether value is a value of the enumeration\n")
+		out.WriteString("func (value " + enum.name + ") Valid() bool {\n\tswitch value {\n\tcase ")
+		for i, value := range enum.values {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(constantName(enum.name, value))
+		}
+		out.WriteString(":\n\t\treturn true\n\t}\n\treturn false\n}\n\n")
+		out.WriteString("// Parse" + enum.name + " return the value of " + enum.name + " named text, compared case\n// insensitively as devices differ in case\n")
+		out.WriteString("func Parse" + enum.name + "(text string) (" + enum.name + ", error) {\n")
+		out.WriteString("\tfor _, value := range " + enum.name + "Values {\n\t\tif strings.EqualFold(string(value), text) {\n\t\t\treturn value, nil\n\t\t}\n\t}\n")
+		out.WriteString("\treturn \"\", fmt.Errorf(\"invalid " + enum.name + " %q\", text)\n}\n\n")
+	}
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("enums.go", source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,12 +1,15 @@
 package onvif
 
 import (
+	"encoding/xml"
+
 	"github.com/PolarisM78/go-onvif/xsd"
 )
 
-// BUG(r): Enum types implemented as simple string
+// BUG(r): Enum types implemented as simple string, see enums.go for their values
+
+//go:generate go run gen_enums.go
 
-//TODO: enumerations
 //TODO: type <typeName> struct {Any string} convert to type <typeName> AnyType
 //TODO: process restrictions
 
@@ -14,7 +17,6 @@ import (
 //todo что делать с xs:any = Any
 //todo IntList и ему подобные. Проверить нужен ли слайс. Изменить на slice
 //todo посмотреть можно ли заменить StreamType и ему подобные типы на вмтроенные типы
-//todo оттестировать тип VideoSourceMode из-за Description-а
 
 //todo в документации описать, что Capabilities повторяеся у каждого сервиса, поэтому у каждого свой Capabilities (MediaCapabilities)
 //todo AuxiliaryData и другие simpleTypes, как реализовать рестрикшн
@@ -57,7 +59,7 @@ type FloatRange struct {
 }
 
 type OSDConfiguration struct {
-	DeviceEntity                  `xml:"token,attr"`
+	DeviceEntity
 	VideoSourceConfigurationToken OSDReference              `xml:"onvif:VideoSourceConfigurationToken"`
 	Type                          OSDType                   `xml:"onvif:Type"`
 	Position                      OSDPosConfiguration       `xml:"onvif:Position"`
@@ -315,9 +317,9 @@ type ToneCompensation struct {
 type ToneCompensationExtension xsd.AnyType
 
 type Defogging struct {
-	Mode      string
-	Level     float64
-	Extension DefoggingExtension
+	Mode      string             `xml:"onvif:Mode"`
+	Level     float64            `xml:"onvif:Level"`
+	Extension DefoggingExtension `xml:"onvif:Extension"`
 }
 
 type DefoggingExtension xsd.AnyType
@@ -328,6 +330,95 @@ type NoiseReduction struct {
 
 type ImagingSettingsExtension204 xsd.AnyType
 
+// ImagingOptions20 value ranges of the imaging settings of a video source,
+// only parsed from responses
+type ImagingOptions20 struct {
+	BacklightCompensation BacklightCompensationOptions20 `xml:"BacklightCompensation"`
+	Brightness            FloatRange                     `xml:"Brightness"`
+	ColorSaturation       FloatRange                     `xml:"ColorSaturation"`
+	Contrast              FloatRange                     `xml:"Contrast"`
+	Focus                 FocusOptions20                 `xml:"Focus"`
+	IrCutFilterModes      []IrCutFilterMode              `xml:"IrCutFilterModes"`
+	Sharpness             FloatRange                     `xml:"Sharpness"`
+	WideDynamicRange      WideDynamicRangeOptions        `xml:"WideDynamicRange"`
+	Extension             ImagingOptions20Extension      `xml:"Extension"`
+}
+
+type FocusOptions20 struct {
+	AutoFocusModes []AutoFocusMode `xml:"AutoFocusModes"`
+	DefaultSpeed   FloatRange      `xml:"DefaultSpeed"`
+	NearLimit      FloatRange      `xml:"NearLimit"`
+	FarLimit       FloatRange      `xml:"FarLimit"`
+}
+
+type WideDynamicRangeOptions struct {
+	Mode  []WideDynamicMode `xml:"Mode"`
+	Level FloatRange        `xml:"Level"`
+}
+
+// MoveOptions20 focus moves supported by a video source, a nil member is not
+// supported
+type MoveOptions20 struct {
+	Absolute   *AbsoluteFocusOptions   `xml:"Absolute"`
+	Relative   *RelativeFocusOptions20 `xml:"Relative"`
+	Continuous *ContinuousFocusOptions `xml:"Continuous"`
+}
+
+type AbsoluteFocusOptions struct {
+	Position FloatRange `xml:"Position"`
+	Speed    FloatRange `xml:"Speed"`
+}
+
+type RelativeFocusOptions20 struct {
+	Distance FloatRange `xml:"Distance"`
+	Speed    FloatRange `xml:"Speed"`
+}
+
+type ContinuousFocusOptions struct {
+	Speed FloatRange `xml:"Speed"`
+}
+
+type BacklightCompensationOptions20 struct {
+	Mode  []BacklightCompensationMode `xml:"Mode"`
+	Level FloatRange                  `xml:"Level"`
+}
+
+type ImagingOptions20Extension struct {
+	ImageStabilization ImageStabilizationOptions  `xml:"ImageStabilization"`
+	Extension          ImagingOptions20Extension2 `xml:"Extension"`
+}
+
+type ImageStabilizationOptions struct {
+	Mode  []ImageStabilizationMode `xml:"Mode"`
+	Level FloatRange               `xml:"Level"`
+}
+
+type ImagingOptions20Extension2 struct {
+	Extension ImagingOptions20Extension3 `xml:"Extension"`
+}
+
+type ImagingOptions20Extension3 struct {
+	ToneCompensationOptions ToneCompensationOptions `xml:"ToneCompensationOptions"`
+	DefoggingOptions        DefoggingOptions        `xml:"DefoggingOptions"`
+	NoiseReductionOptions   NoiseReductionOptions   `xml:"NoiseReductionOptions"`
+}
+
+type ToneCompensationOptions struct {
+	Mode  []string `xml:"Mode"`
+	Level bool     `xml:"Level"`
+}
+
+// DefoggingOptions supported defog modes, Level reports whether the level
+// (normalized between 0 and 1) can be set
+type DefoggingOptions struct {
+	Mode  []string `xml:"Mode"`
+	Level bool     `xml:"Level"`
+}
+
+type NoiseReductionOptions struct {
+	Level bool `xml:"Level"`
+}
+
 type VideoSourceExtension2 xsd.AnyType
 
 type AudioSource struct {
@@ -553,7 +644,7 @@ type PTZSpeed struct {
 type Vector2D struct {
 	X     float64    `xml:"x,attr"`
 	Y     float64    `xml:"y,attr"`
-	Space xsd.AnyURI `xml:"space,attr"`
+	Space xsd.AnyURI `xml:"space,attr,omitempty"`
 }
 
 type Vector2D2 struct {
@@ -562,7 +653,7 @@ type Vector2D2 struct {
 }
 type Vector1D struct {
 	X     float64    `xml:"x,attr"`
-	Space xsd.AnyURI `xml:"space,attr"`
+	Space xsd.AnyURI `xml:"space,attr,omitempty"`
 }
 type Vector1D2 struct {
 	X float64 `xml:"x,attr"`
@@ -824,18 +915,11 @@ type VideoSourceMode struct {
 	Enabled       bool           `xml:"Enabled,attr"`
 	MaxFramerate  float64
 	MaxResolution VideoResolution
-	Encodings     EncodingTypes
-	Reboot        bool
-	Description   Description
-	Extension     VideoSourceModeExtension
-}
-
-type EncodingTypes struct {
-	EncodingTypes []string
-}
-
-type Description struct {
+	// Encodings space separated list of the encodings of the mode
+	Encodings   string
+	Reboot      bool
 	Description string
+	Extension   VideoSourceModeExtension
 }
 
 type VideoSourceModeExtension xsd.AnyType
@@ -1007,6 +1091,21 @@ type PTZVector struct {
 	Zoom    Vector1D `xml:"onvif:Zoom"`
 }
 
+// UnmarshalXML decode PTZVector from a response, the prefixed tags above are
+// only usable for requests
+func (vector *PTZVector) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	value := struct {
+		PanTilt Vector2D `xml:"PanTilt"`
+		Zoom    Vector1D `xml:"Zoom"`
+	}{}
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	vector.PanTilt = value.PanTilt
+	vector.Zoom = value.Zoom
+	return nil
+}
+
 type PTZStatus struct {
 	Position   PTZVector
 	MoveStatus PTZMoveStatus
@@ -1019,10 +1118,14 @@ type PTZMoveStatus struct {
 	Zoom    MoveStatus
 }
 
+// MoveStatus move status of the pan tilt or zoom, given as the text of the
+// element
 type MoveStatus struct {
-	Status string
+	Status MoveState `xml:",chardata"`
 }
 
+type MoveState xsd.String
+
 type GeoLocation struct {
 	Lon       xsd.Double `xml:"lon,attr"`
 	Lat       xsd.Double `xml:"lat,attr"`
@@ -1179,7 +1282,24 @@ type User struct {
 	Username  string        `xml:"onvif:Username"`
 	Password  string        `xml:"onvif:Password"`
 	UserLevel UserLevel     `xml:"onvif:UserLevel"`
-	Extension UserExtension `xml:"onvif:Extension"`
+	Extension UserExtension `xml:"onvif:Extension,omitempty"`
+}
+
+// UnmarshalXML decode User from a response, the prefixed tags above are only
+// usable for requests
+func (user *User) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	value := struct {
+		Username  string    `xml:"Username"`
+		Password  string    `xml:"Password"`
+		UserLevel UserLevel `xml:"UserLevel"`
+	}{}
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	user.Username = value.Username
+	user.Password = value.Password
+	user.UserLevel = value.UserLevel
+	return nil
 }
 
 type UserLevel xsd.String
@@ -1465,34 +1585,89 @@ type NetworkInterfaceConnectionSetting struct {
 }
 
 type NetworkInterfaceExtension struct {
-	InterfaceType int                `xml:"InterfaceType"`
-	Dot3          string             `xml:"Dot3"`
-	Dot11         Dot11Configuration `xml:"Dot11"`
-	Extension     string             `xml:"Extension"`
+	InterfaceType int                  `xml:"InterfaceType"`
+	Dot3          []string             `xml:"Dot3"`
+	Dot11         []Dot11Configuration `xml:"Dot11"`
+	Extension     string               `xml:"Extension"`
 }
 
 type Dot11Configuration struct {
-	SSID     Dot11SSIDType                  `xml:"SSID"`
-	Mode     Dot11StationMode               `xml:"Mode"`
-	Alias    Name                           `xml:"Alias"`
-	Priority NetworkInterfaceConfigPriority `xml:"Priority"`
-	Security Dot11SecurityConfiguration     `xml:"Security"`
+	SSID     Dot11SSIDType                  `xml:"onvif:SSID"`
+	Mode     Dot11StationMode               `xml:"onvif:Mode"`
+	Alias    Name                           `xml:"onvif:Alias"`
+	Priority NetworkInterfaceConfigPriority `xml:"onvif:Priority,omitempty"`
+	Security Dot11SecurityConfiguration     `xml:"onvif:Security"`
+}
+
+// UnmarshalXML decode Dot11Configuration from a response, the prefixed tags
+// above are only usable for requests
+func (configuration *Dot11Configuration) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	value := struct {
+		SSID     Dot11SSIDType                  `xml:"SSID"`
+		Mode     Dot11StationMode               `xml:"Mode"`
+		Alias    Name                           `xml:"Alias"`
+		Priority NetworkInterfaceConfigPriority `xml:"Priority"`
+		Security Dot11SecurityConfiguration     `xml:"Security"`
+	}{}
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	configuration.SSID = value.SSID
+	configuration.Mode = value.Mode
+	configuration.Alias = value.Alias
+	configuration.Priority = value.Priority
+	configuration.Security = value.Security
+	return nil
 }
 
 type Dot11SecurityConfiguration struct {
 	Mode      Dot11SecurityMode                   `xml:"onvif:Mode"`
-	Algorithm Dot11Cipher                         `xml:"onvif:Algorithm"`
-	PSK       Dot11PSKSet                         `xml:"onvif:PSK"`
-	Dot1X     ReferenceToken                      `xml:"onvif:Dot1X"`
-	Extension Dot11SecurityConfigurationExtension `xml:"onvif:Extension"`
+	Algorithm Dot11Cipher                         `xml:"onvif:Algorithm,omitempty"`
+	PSK       *Dot11PSKSet                        `xml:"onvif:PSK,omitempty"`
+	Dot1X     ReferenceToken                      `xml:"onvif:Dot1X,omitempty"`
+	Extension Dot11SecurityConfigurationExtension `xml:"onvif:Extension,omitempty"`
+}
+
+// UnmarshalXML decode Dot11SecurityConfiguration from a response, the
+// prefixed tags above are only usable for requests
+func (security *Dot11SecurityConfiguration) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	value := struct {
+		Mode      Dot11SecurityMode `xml:"Mode"`
+		Algorithm Dot11Cipher       `xml:"Algorithm"`
+		PSK       *Dot11PSKSet      `xml:"PSK"`
+		Dot1X     ReferenceToken    `xml:"Dot1X"`
+	}{}
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	security.Mode = value.Mode
+	security.Algorithm = value.Algorithm
+	security.PSK = value.PSK
+	security.Dot1X = value.Dot1X
+	return nil
 }
 
 type Dot11SecurityConfigurationExtension xsd.AnyType
 
 type Dot11PSKSet struct {
-	Key        Dot11PSK             `xml:"onvif:Key"`
-	Passphrase Dot11PSKPassphrase   `xml:"onvif:Passphrase"`
-	Extension  Dot11PSKSetExtension `xml:"onvif:Extension"`
+	Key        Dot11PSK             `xml:"onvif:Key,omitempty"`
+	Passphrase Dot11PSKPassphrase   `xml:"onvif:Passphrase,omitempty"`
+	Extension  Dot11PSKSetExtension `xml:"onvif:Extension,omitempty"`
+}
+
+// UnmarshalXML decode Dot11PSKSet from a response, the prefixed tags above
+// are only usable for requests
+func (set *Dot11PSKSet) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	value := struct {
+		Key        Dot11PSK           `xml:"Key"`
+		Passphrase Dot11PSKPassphrase `xml:"Passphrase"`
+	}{}
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	set.Key = value.Key
+	set.Passphrase = value.Passphrase
+	return nil
 }
 
 type Dot11PSKSetExtension xsd.AnyType
@@ -1556,19 +1731,21 @@ type PrefixedIPv4Address struct {
 	PrefixLength xsd.Int     `xml:"PrefixLength"`
 }
 
+// NetworkInterfaceSetConfiguration settings of SetNetworkInterfaces, the
+// settings left nil are not changed
 type NetworkInterfaceSetConfiguration struct {
-	Enabled   xsd.Boolean                               `xml:"onvif:Enabled"`
-	Link      NetworkInterfaceConnectionSetting         `xml:"onvif:Link"`
-	MTU       xsd.Int                                   `xml:"onvif:MTU"`
-	IPv4      IPv4NetworkInterfaceSetConfiguration      `xml:"onvif:IPv4"`
-	IPv6      IPv6NetworkInterfaceSetConfiguration      `xml:"onvif:IPv6"`
-	Extension NetworkInterfaceSetConfigurationExtension `xml:"onvif:Extension"`
+	Enabled   *xsd.Boolean                               `xml:"onvif:Enabled,omitempty"`
+	Link      *NetworkInterfaceConnectionSetting         `xml:"onvif:Link,omitempty"`
+	MTU       *xsd.Int                                   `xml:"onvif:MTU,omitempty"`
+	IPv4      *IPv4NetworkInterfaceSetConfiguration      `xml:"onvif:IPv4,omitempty"`
+	IPv6      *IPv6NetworkInterfaceSetConfiguration      `xml:"onvif:IPv6,omitempty"`
+	Extension *NetworkInterfaceSetConfigurationExtension `xml:"onvif:Extension,omitempty"`
 }
 
 type NetworkInterfaceSetConfigurationExtension struct {
-	Dot3      Dot3Configuration                          `xml:"onvif:Dot3"`
-	Dot11     Dot11Configuration                         `xml:"onvif:Dot11"`
-	Extension NetworkInterfaceSetConfigurationExtension2 `xml:"onvif:Extension"`
+	Dot3      []Dot3Configuration                        `xml:"onvif:Dot3,omitempty"`
+	Dot11     []Dot11Configuration                       `xml:"onvif:Dot11,omitempty"`
+	Extension NetworkInterfaceSetConfigurationExtension2 `xml:"onvif:Extension,omitempty"`
 }
 
 type NetworkInterfaceSetConfigurationExtension2 xsd.AnyType
@@ -1583,7 +1760,7 @@ type IPv6NetworkInterfaceSetConfiguration struct {
 type IPv4NetworkInterfaceSetConfiguration struct {
 	Enabled xsd.Boolean         `xml:"onvif:Enabled"`
 	Manual  PrefixedIPv4Address `xml:"onvif:Manual"`
-	DHCP    xsd.Boolean         `xml:"onif:DHCP"`
+	DHCP    xsd.Boolean         `xml:"onvif:DHCP"`
 }
 
 type NetworkProtocol struct {
@@ -1632,7 +1809,7 @@ type IPAddressFilterType xsd.String
 
 // TODO: attribite <xs:attribute ref="xmime:contentType" use="optional"/>
 type BinaryData struct {
-	X    ContentType      `xml:"xmime:contentType,attr"`
+	X    ContentType      `xml:"xmime:contentType,attr,omitempty"`
 	Data xsd.Base64Binary `xml:"onvif:Data"`
 }
 
@@ -1745,7 +1922,7 @@ type Dot11SignalStrength xsd.String
 type Dot11AvailableNetworks struct {
 	SSID                  Dot11SSIDType
 	BSSID                 xsd.String
-	AuthAndMangementSuite Dot11AuthAndMangementSuite
+	AuthAndMangementSuite []Dot11AuthAndMangementSuite
 	PairCipher            Dot11Cipher
 	GroupCipher           Dot11Cipher
 	SignalStrength        Dot11SignalStrength
@@ -1758,7 +1935,7 @@ type Dot11AvailableNetworksExtension xsd.AnyType
 type Dot11AuthAndMangementSuite xsd.String
 
 type SystemLogUriList struct {
-	SystemLog SystemLogUri
+	SystemLog []SystemLogUri
 }
 
 type SystemLogUri struct {
@@ -1816,3 +1993,114 @@ type AbsoluteFocus struct {
 	Position xsd.Float `xml:"onvif:Position"`
 	Speed    xsd.Float `xml:"onvif:Speed"`
 }
+
+// Metadata stream, tt:MetadataStream of the RTP metadata of a profile
+
+type VideoAnalyticsStream struct {
+	Frame []Frame `xml:"Frame"`
+}
+
+type Frame struct {
+	UtcTime    xsd.DateTime `xml:"UtcTime,attr"`
+	Source     string       `xml:"Source,attr"`
+	Object     []Object     `xml:"Object"`
+	ObjectTree ObjectTree   `xml:"ObjectTree"`
+}
+
+// ObjectTree changes of the identity of the objects of a frame
+type ObjectTree struct {
+	Rename []Rename   `xml:"Rename"`
+	Delete []ObjectId `xml:"Delete"`
+}
+
+type Rename struct {
+	From ObjectId `xml:"from"`
+	To   ObjectId `xml:"to"`
+}
+
+type ObjectId struct {
+	ObjectId int `xml:"ObjectId,attr"`
+}
+
+type Object struct {
+	ObjectId   int        `xml:"ObjectId,attr"`
+	Parent     int        `xml:"Parent,attr"`
+	Appearance Appearance `xml:"Appearance"`
+	Behaviour  Behaviour  `xml:"Behaviour"`
+}
+
+type Appearance struct {
+	Shape ShapeDescriptor `xml:"Shape"`
+	Class ClassDescriptor `xml:"Class"`
+	// Content raw content, holding the descriptors of the Profile M schema
+	// (LicensePlateInfo, HumanFace...) and of the vendor extensions
+	Content []byte `xml:",innerxml"`
+}
+
+type ShapeDescriptor struct {
+	BoundingBox     Rectangle `xml:"BoundingBox"`
+	CenterOfGravity Vector    `xml:"CenterOfGravity"`
+}
+
+// ClassDescriptor class of an object, as ClassCandidate before ONVIF 2.6 or
+// as Type afterwards
+type ClassDescriptor struct {
+	ClassCandidate []ClassCandidate   `xml:"ClassCandidate"`
+	Type           []StringLikelihood `xml:"Type"`
+}
+
+type ClassCandidate struct {
+	Type       string  `xml:"Type"`
+	Likelihood float64 `xml:"Likelihood"`
+}
+
+type StringLikelihood struct {
+	Likelihood float64 `xml:"Likelihood,attr"`
+	Value      string  `xml:",chardata"`
+}
+
+type Behaviour struct {
+	Removed *struct{} `xml:"Removed"`
+	Idle    *struct{} `xml:"Idle"`
+}
+
+// Analytics modules and rules descriptions
+
+type SupportedAnalyticsModules struct {
+	AnalyticsModuleContentSchemaLocation []xsd.AnyURI        `xml:"AnalyticsModuleContentSchemaLocation"`
+	AnalyticsModuleDescription           []ConfigDescription `xml:"AnalyticsModuleDescription"`
+}
+
+type SupportedRules struct {
+	RuleContentSchemaLocation []xsd.AnyURI        `xml:"RuleContentSchemaLocation"`
+	RuleDescription           []ConfigDescription `xml:"RuleDescription"`
+}
+
+type ConfigDescription struct {
+	Name         xsd.QName           `xml:"Name,attr"`
+	Fixed        bool                `xml:"fixed,attr"`
+	MaxInstances int                 `xml:"maxInstances,attr"`
+	Parameters   ItemListDescription `xml:"Parameters"`
+}
+
+type ItemListDescription struct {
+	SimpleItemDescription  []ItemDescription `xml:"SimpleItemDescription"`
+	ElementItemDescription []ItemDescription `xml:"ElementItemDescription"`
+}
+
+type ItemDescription struct {
+	Name string    `xml:"Name,attr"`
+	Type xsd.QName `xml:"Type,attr"`
+}
+
+// ConfigOptions allowed values of a parameter of a rule or an analytics
+// module, held by Content as IntRange, FloatRange, StringList...
+type ConfigOptions struct {
+	Name            string    `xml:"Name,attr"`
+	Type            xsd.QName `xml:"Type,attr"`
+	RuleType        xsd.QName `xml:"RuleType,attr"`
+	AnalyticsModule xsd.QName `xml:"AnalyticsModule,attr"`
+	MinOccurs       int       `xml:"minOccurs,attr"`
+	MaxOccurs       int       `xml:"maxOccurs,attr"`
+	Content         []byte    `xml:",innerxml"`
+}
@@ -0,0 +1,137 @@
+package xsd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationPattern = regexp.MustCompile(`^(-)?P(?:([0-9]+)Y)?(?:([0-9]+)M)?(?:([0-9]+)D)?(?:T(?:([0-9]+)H)?(?:([0-9]+)M)?(?:([0-9]+(?:\.[0-9]+)?)S)?)?$`)
+
+// durationParts return the sign and the components of an xs:duration, from
+// years to seconds
+func durationParts(value string) (bool, []string, error) {
+	value = strings.TrimSpace(value)
+	match := durationPattern.FindStringSubmatch(value)
+	/* P与T后至少要有一个分量 */
+	if match == nil || strings.HasSuffix(value, "P") || strings.HasSuffix(value, "T") {
+		return false, nil, fmt.Errorf("invalid xs:duration %q", value)
+	}
+	return match[1] == "-", match[2:], nil
+}
+
+// ParseDuration parse an xs:duration such as PT60S or P1DT2H30M, a year is
+// counted as 365 days and a month as 30 days
+func ParseDuration(value string) (time.Duration, error) {
+	negative, parts, err := durationParts(value)
+	if err != nil {
+		return 0, err
+	}
+	units := []time.Duration{365 * 24 * time.Hour, 30 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute}
+	var d time.Duration
+	for i, unit := range units {
+		if parts[i] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(parts[i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid xs:duration %q", value)
+		}
+		d += time.Duration(n) * unit
+	}
+	if parts[5] != "" {
+		seconds, err := strconv.ParseFloat(parts[5], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid xs:duration %q", value)
+		}
+		d += time.Duration(seconds * float64(time.Second))
+	}
+	if negative {
+		d = -d
+	}
+	return d, nil
+}
+
+// FormatDuration format d as an xs:duration in seconds, e.g. PT60S or PT0.5S,
+// the form every device accepts
+func FormatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	if d%time.Second == 0 {
+		return fmt.Sprintf("%sPT%dS", sign, int64(d/time.Second))
+	}
+	return sign + "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+}
+
+// DurationOf return the Duration of d, in seconds
+func DurationOf(d time.Duration) Duration {
+	duration := Duration{negative: d < 0}
+	if d < 0 {
+		d = -d
+	}
+	duration.seconds = strings.TrimSuffix(strings.TrimPrefix(FormatDuration(d), "PT"), "S")
+	return duration
+}
+
+// IsZero report whether the duration is unset
+func (duration Duration) IsZero() bool {
+	return duration == Duration{}
+}
+
+// ToDuration convert the duration, see ParseDuration
+func (duration Duration) ToDuration() (time.Duration, error) {
+	return ParseDuration(duration.ISO8601Duration())
+}
+
+// MarshalText encode the duration, nothing when it is unset
+func (duration Duration) MarshalText() ([]byte, error) {
+	if duration.IsZero() {
+		return nil, nil
+	}
+	return []byte(duration.ISO8601Duration()), nil
+}
+
+// UnmarshalText decode an xs:duration, an empty element leaves it unset
+func (duration *Duration) UnmarshalText(text []byte) error {
+	if len(strings.TrimSpace(string(text))) == 0 {
+		*duration = Duration{}
+		return nil
+	}
+	negative, parts, err := durationParts(string(text))
+	if err != nil {
+		return err
+	}
+	*duration = Duration{negative: negative, years: parts[0], months: parts[1], days: parts[2],
+		hours: parts[3], minutes: parts[4], seconds: parts[5]}
+	return nil
+}
+
+// dateTimeLayouts layouts of the xs:dateTime sent by devices, some omit the
+// zone or the fraction of the seconds
+var dateTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"}
+
+// ParseDateTime parse an xs:dateTime, a value without zone is taken as UTC as
+// ONVIF requires
+func ParseDateTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid xs:dateTime %q", value)
+}
+
+// FormatDateTime format t as an xs:dateTime in UTC, e.g. 2022-02-10T03:00:00Z
+func FormatDateTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ToTime convert the dateTime, see ParseDateTime
+func (tp DateTime) ToTime() (time.Time, error) {
+	return ParseDateTime(string(tp))
+}